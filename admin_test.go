@@ -0,0 +1,77 @@
+package ha_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestAdminServer(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.porch_motion", "off")
+	app := newApp(t, server)
+
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("porch lights").
+			On(ha.StateChanged("binary_sensor.porch_motion").To("on")).
+			When(ha.StateIs("binary_sensor.porch_motion", "on")).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn("light.porch")
+			}).
+			MustBuild(),
+	))
+	require.NoError(t, app.WithAdminServer("127.0.0.1:0"))
+	base := "http://" + app.AdminAddr()
+	assert.Error(t, app.WithAdminServer("127.0.0.1:0"), "one admin server per app")
+
+	status := func(method, path string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, base+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, status("GET", "/healthz"))
+	assert.Equal(t, http.StatusServiceUnavailable, status("GET", "/readyz"), "not started yet")
+	assert.Equal(t, http.StatusServiceUnavailable, status("POST", "/automations/porch%20lights/run"))
+
+	start(t, app)
+	assert.Equal(t, http.StatusOK, status("GET", "/readyz"))
+
+	resp, err := http.Get(base + "/automations")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var dump struct {
+		Automations []struct {
+			Name    string  `json:"name"`
+			LastRun *string `json:"last_run"`
+		} `json:"automations"`
+		Listeners []struct {
+			Entities []string `json:"entities"`
+		} `json:"listeners"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&dump))
+	require.Len(t, dump.Automations, 1)
+	assert.Equal(t, "porch lights", dump.Automations[0].Name)
+	assert.Nil(t, dump.Automations[0].LastRun, "never run is left out")
+	assert.Equal(t, []string{"binary_sensor.porch_motion"}, dump.Listeners[0].Entities)
+
+	// Run by hand, skipping the condition that the motion sensor is on.
+	assert.Equal(t, http.StatusAccepted, status("POST", "/automations/"+url.PathEscape("porch lights")+"/run"))
+	server.WaitForCalls(1)
+	server.AssertServiceCalled("light", "turn_on", "light.porch")
+
+	assert.Equal(t, http.StatusNotFound, status("POST", "/automations/garage/run"))
+	assert.Equal(t, http.StatusMethodNotAllowed, status("GET", "/automations/garage/run"))
+}
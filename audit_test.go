@@ -0,0 +1,85 @@
+package ha_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
+)
+
+func auditedApp(t *testing.T, server *hatest.Server, opts types.AuditOptions) *ha.App {
+	t.Helper()
+
+	app, err := ha.NewApp(types.NewAppRequest{URL: server.URL(), HAAuthToken: hatest.Token, Audit: opts})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = app.Close() })
+	return app
+}
+
+func TestAuditSensorShowsTheLastCall(t *testing.T) {
+	server := hatest.New(t)
+	app := auditedApp(t, server, types.AuditOptions{Sensor: types.DefaultAuditSensor})
+
+	require.NoError(t, app.Services().Light.TurnOn("light.kitchen"))
+
+	state, attrs, ok := server.State(types.DefaultAuditSensor)
+	require.True(t, ok)
+	assert.Equal(t, "light.turn_on", state)
+	assert.Equal(t, "light", attrs["domain"])
+	assert.Equal(t, "turn_on", attrs["service"])
+	assert.Equal(t, "light.kitchen", attrs["target"])
+	assert.NotEmpty(t, attrs["ts"])
+}
+
+func TestAuditLogbookRecordsEachCall(t *testing.T) {
+	server := hatest.New(t)
+	app := auditedApp(t, server, types.AuditOptions{Logbook: true})
+
+	require.NoError(t, app.Services().Switch.TurnOff("switch.fan"))
+
+	calls := server.WaitForCalls(2)
+	assert.Equal(t, "switch", calls[0].Domain)
+	assert.Equal(t, "logbook", calls[1].Domain)
+	assert.Equal(t, "log", calls[1].Service)
+	assert.Equal(t, "called switch.turn_off on switch.fan", calls[1].ServiceData["message"])
+	assert.Equal(t, "switch.fan", calls[1].ServiceData["entity_id"])
+
+	// The logbook entry is not itself logged.
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, server.Calls(), 2)
+}
+
+// An area or device is not an entity the logbook can file an entry under, so
+// it is only named in the message.
+func TestAuditLogbookFilesUnderEntitiesOnly(t *testing.T) {
+	server := hatest.New(t)
+	app := auditedApp(t, server, types.AuditOptions{Logbook: true})
+
+	require.NoError(t, app.Services().Targeting(services.InArea("kitchen")).Light.TurnOn(""))
+	require.NoError(t, app.Services().Targeting(services.InArea("hall")).Light.TurnOn("light.hall, light.landing"))
+
+	calls := server.WaitForCalls(4)
+	assert.Equal(t, "called light.turn_on on area kitchen", calls[1].ServiceData["message"])
+	assert.NotContains(t, calls[1].ServiceData, "entity_id")
+	assert.Equal(t, "light.hall", calls[3].ServiceData["entity_id"])
+	assert.Contains(t, calls[3].ServiceData["message"], "area hall")
+}
+
+func TestNoAuditByDefault(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	require.NoError(t, app.Services().Light.TurnOn("light.kitchen"))
+	server.WaitForCalls(1)
+	time.Sleep(50 * time.Millisecond)
+
+	_, _, ok := server.State(types.DefaultAuditSensor)
+	assert.False(t, ok)
+	assert.Len(t, server.Calls(), 1)
+}
@@ -0,0 +1,57 @@
+package ha_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestVerifyAuthReportsTheUser(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	info, err := app.VerifyAuth()
+	require.NoError(t, err)
+	assert.Equal(t, "hatest", info.UserName)
+	assert.True(t, info.IsAdmin)
+	assert.True(t, info.ExpiresAt.IsZero(), "the test token carries no expiry")
+}
+
+// A proxy that drops the Authorization header on plain requests leaves the
+// websocket working and every REST call refused, which used to surface only as
+// a failed state read somewhere down the line.
+func TestVerifyAuthCatchesAProxyDroppingTheToken(t *testing.T) {
+	server := hatest.New(t)
+	target, err := url.Parse(server.URL())
+	require.NoError(t, err)
+	forward := httputil.NewSingleHostReverseProxy(target)
+	// Its own transport, so its idle connections can be closed and are not
+	// left behind for the leak checks.
+	transport := &http.Transport{}
+	forward.Transport = transport
+	t.Cleanup(transport.CloseIdleConnections)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/websocket" {
+			r.Header.Del("Authorization")
+		}
+		forward.ServeHTTP(w, r)
+	}))
+	t.Cleanup(proxy.Close)
+
+	app, err := ha.NewApp(types.NewAppRequest{URL: proxy.URL, HAAuthToken: hatest.Token})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = app.Close() })
+
+	_, err = app.VerifyAuth()
+	require.ErrorIs(t, err, ha.ErrAuthFailed)
+	assert.ErrorContains(t, err, "Authorization header")
+}
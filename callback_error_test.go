@@ -0,0 +1,126 @@
+package ha_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+// failures collects what OnCallbackError is told.
+type failures struct {
+	mu   sync.Mutex
+	errs []error
+	meta []ha.AutomationMeta
+	seen chan struct{}
+}
+
+func watchFailures(app *ha.App) *failures {
+	f := &failures{seen: make(chan struct{}, 16)}
+	app.OnCallbackError(func(err error, meta ha.AutomationMeta) {
+		f.mu.Lock()
+		f.errs = append(f.errs, err)
+		f.meta = append(f.meta, meta)
+		f.mu.Unlock()
+		f.seen <- struct{}{}
+	})
+	return f
+}
+
+func (f *failures) wait(t *testing.T) (error, ha.AutomationMeta) {
+	t.Helper()
+	select {
+	case <-f.seen:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnCallbackError was never called")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.errs[len(f.errs)-1], f.meta[len(f.meta)-1]
+}
+
+func TestPanickingActionIsRecoveredAndReported(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.motion", "off")
+	app := newApp(t, server)
+	failed := watchFailures(app)
+
+	runs := make(chan struct{}, 2)
+	require.NoError(t, app.RegisterAutomations(ha.NewAutomation("hall light").
+		On(ha.StateChanged("binary_sensor.motion").To("on")).
+		Mode(ha.ModeQueued).
+		Do(func(context.Context, ha.Run) error {
+			runs <- struct{}{}
+			var lights map[string]string
+			lights["hall"] = "on"
+			return nil
+		}).
+		MustBuild()))
+	start(t, app)
+
+	server.ChangeState("binary_sensor.motion", "on")
+	err, meta := failed.wait(t)
+	var p *ha.PanicError
+	require.ErrorAs(t, err, &p)
+	assert.Contains(t, string(p.Stack), "callback_error_test.go", "the stack points at the panic")
+	assert.Equal(t, "hall light", meta.Name)
+	assert.NotEmpty(t, meta.ID)
+	assert.Equal(t, "binary_sensor.motion", meta.Event.EntityID)
+
+	// The app carries on, and the automation runs at its next trigger.
+	server.ChangeState("binary_sensor.motion", "off")
+	server.ChangeState("binary_sensor.motion", "on")
+	for range 2 {
+		select {
+		case <-runs:
+		case <-time.After(2 * time.Second):
+			t.Fatal("the automation did not run again after panicking")
+		}
+	}
+	failed.wait(t)
+}
+
+func TestFailingActionIsReported(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+	failed := watchFailures(app)
+
+	offline := errors.New("printer offline")
+	require.NoError(t, app.RegisterAutomations(ha.NewAutomation("print label").
+		On(ha.EventFired("label_requested")).
+		Do(func(context.Context, ha.Run) error { return offline }).
+		MustBuild()))
+	start(t, app)
+
+	server.Fire("label_requested", nil)
+	err, meta := failed.wait(t)
+	assert.ErrorIs(t, err, offline)
+	assert.Equal(t, "print label", meta.Name)
+	assert.Equal(t, "label_requested", meta.Event.Type)
+}
+
+func TestPanickingAfterFuncAndHookAreRecovered(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+	failed := watchFailures(app)
+
+	app.OnReady(func(context.Context) error { panic("not ready") })
+	start(t, app)
+	err, meta := failed.wait(t)
+	var p *ha.PanicError
+	require.ErrorAs(t, err, &p)
+	assert.Equal(t, "not ready", p.Value)
+	assert.Equal(t, "OnReady hook 1", meta.Name)
+
+	app.AfterFunc(time.Millisecond, func() { panic(errors.New("timer went off")) })
+	err, meta = failed.wait(t)
+	assert.EqualError(t, err, "panic: timer went off")
+	assert.Equal(t, "AfterFunc", meta.Name)
+}
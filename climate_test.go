@@ -0,0 +1,29 @@
+package ha_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestSetTemperatureIsCheckedAgainstTheEntity(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("climate.hall", "heat", map[string]any{"min_temp": 7.0, "max_temp": 35.0})
+	app := newApp(t, server)
+	start(t, app)
+
+	err := app.Services().Climate.SetTemperature("climate.hall", types.SetTemperatureRequest{Temperature: types.Ptr(float32(40))})
+	assert.ErrorIs(t, err, types.ErrInvalidTemperature)
+	assert.Empty(t, server.Calls(), "refused before it was sent")
+
+	require.NoError(t, app.Services().Climate.SetTemperature("climate.hall", types.SetTemperatureRequest{
+		Temperature: types.Ptr(float32(68)),
+		Unit:        types.Fahrenheit,
+	}))
+	calls := server.WaitForCalls(1)
+	assert.EqualValues(t, 20, calls[0].ServiceData["temperature"], "converted to the installation's Celsius")
+}
@@ -0,0 +1,83 @@
+package ha_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+// cloudServer answers cloud/status with whatever the test last set.
+func cloudServer(t *testing.T) (*hatest.Server, func(map[string]any)) {
+	var mu sync.Mutex
+	status := map[string]any{}
+
+	server := hatest.New(t)
+	server.HandleCommand("cloud/status", func(map[string]any) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return status, nil
+	})
+	return server, func(s map[string]any) {
+		mu.Lock()
+		defer mu.Unlock()
+		status = s
+	}
+}
+
+func TestCloudStatus(t *testing.T) {
+	server, set := cloudServer(t)
+	set(map[string]any{"logged_in": true, "cloud": "connected", "remote_enabled": true, "remote_connected": true})
+	app := newApp(t, server)
+
+	status, err := app.CloudStatus()
+	require.NoError(t, err)
+	assert.True(t, status.Connected())
+	assert.True(t, status.RemoteConnected)
+}
+
+// Signed out, Home Assistant omits the connection fields altogether.
+func TestCloudStatusSignedOut(t *testing.T) {
+	server, set := cloudServer(t)
+	set(map[string]any{"logged_in": false})
+	app := newApp(t, server)
+
+	status, err := app.CloudStatus()
+	require.NoError(t, err)
+	assert.False(t, status.Connected())
+	assert.Equal(t, ha.CloudDisconnected, status.Cloud)
+}
+
+func TestOnCloudConnectionChange(t *testing.T) {
+	server, set := cloudServer(t)
+	set(map[string]any{"logged_in": true, "cloud": "connected", "remote_connected": true})
+	server.SetState("binary_sensor.remote_ui", "on", nil)
+	app := newApp(t, server)
+
+	changes := make(chan ha.CloudStatus, 4)
+	require.NoError(t, app.OnCloudConnectionChange(func(s ha.CloudStatus) { changes <- s }))
+	start(t, app)
+
+	// The status at startup is where things stand, not a change.
+	select {
+	case s := <-changes:
+		t.Fatalf("reported %+v without a change", s)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	set(map[string]any{"logged_in": true, "cloud": "connected", "remote_connected": false})
+	server.ChangeState("binary_sensor.remote_ui", "off", nil)
+
+	select {
+	case s := <-changes:
+		assert.False(t, s.RemoteConnected)
+		assert.True(t, s.Connected())
+	case <-time.After(2 * time.Second):
+		t.Fatal("the remote UI dropping was never reported")
+	}
+}
@@ -1,4 +1,5 @@
 // Package main provides the generate command for generating Home Assistant entity constants
+// and typed service wrappers
 package main
 
 import (
@@ -35,6 +36,38 @@ type Domain struct {
 type Entity struct {
 	FieldName string
 	EntityID  string
+	IDType    string
+
+	// Comment is the entity's friendly name and device class, for the line
+	// its field is declared on.
+	Comment string
+
+	// Info is what the Info map holds about the entity.
+	Info EntityInfo
+}
+
+// EntityInfo is what the generated package records about an entity beyond its
+// id, mirrored by the type of the same name it declares.
+type EntityInfo struct {
+	FriendlyName string
+	DeviceClass  string
+	Area         string
+}
+
+// Area is one area's entities, as the template emits them. Entities from
+// every domain share the struct, so their fields are prefixed with it.
+type Area struct {
+	Name     string
+	Entities []Entity
+}
+
+// areaRef is the area an entity is in, as the registries tell it. ID is what
+// Home Assistant made of the name when the area was created, already reduced
+// to lower case ASCII, so it names the area in Go however the name is
+// written.
+type areaRef struct {
+	ID   string
+	Name string
 }
 
 func toFieldName(entityID string) string {
@@ -81,7 +114,7 @@ package entities
 {{- $idType := .IDType }}
 type {{ .Name }}Domain struct {
 	{{- range .Entities }}
-	{{ .FieldName }} services.{{ $idType }}
+	{{ .FieldName }} services.{{ $idType }}{{ if .Comment }} // {{ .Comment }}{{ end }}
 	{{- end }}
 }
 
@@ -91,6 +124,55 @@ var {{ .Name }} = {{ .Name }}Domain{
 	{{- end }}
 }
 {{ end }}
+{{- if .Areas }}
+// AreaIndex holds every area's entities.
+type AreaIndex struct {
+	{{- range .Areas }}
+	{{ .Name }} {{ .Name }}Area
+	{{- end }}
+}
+
+// Areas holds every area's entities, by area and then by domain and name.
+var Areas = AreaIndex{
+	{{- range .Areas }}
+	{{ .Name }}: {{ .Name }}Area{
+		{{- range .Entities }}
+		{{ .FieldName }}: "{{ .EntityID }}",
+		{{- end }}
+	},
+	{{- end }}
+}
+{{ range .Areas }}
+type {{ .Name }}Area struct {
+	{{- range .Entities }}
+	{{ .FieldName }} services.{{ .IDType }}{{ if .Comment }} // {{ .Comment }}{{ end }}
+	{{- end }}
+}
+{{ end }}
+{{- end }}
+{{- if .Domains }}
+// EntityInfo is what Home Assistant said of an entity when this was
+// generated.
+type EntityInfo struct {
+	FriendlyName string
+	DeviceClass  string
+	Area         string
+}
+
+// Info holds every entity's EntityInfo, by id.
+var Info = map[string]EntityInfo{
+	{{- range .Domains }}
+	{{- range .Entities }}
+	"{{ .EntityID }}": {
+		{{- with .Info }}
+		{{- if .FriendlyName }}FriendlyName: {{ printf "%q" .FriendlyName }}, {{ end }}
+		{{- if .DeviceClass }}DeviceClass: {{ printf "%q" .DeviceClass }}, {{ end }}
+		{{- if .Area }}Area: {{ printf "%q" .Area }}{{ end }}
+		{{- end }}},
+	{{- end }}
+	{{- end }}
+}
+{{- end }}
 `))
 
 // includes reports whether a domain survives the include and exclude lists. An
@@ -107,15 +189,23 @@ func includes(domain string, include, exclude []string) bool {
 // separate from generate so the transformation, which produces code users
 // compile against, can be tested without a Home Assistant connection.
 //
+// areas holds the area of each entity that has one, by entity id. Those are
+// grouped by area as well as by domain, and every entity's friendly name,
+// device class and area are recorded in Info and on its fields' lines.
+//
 // The output is run through go/format, which both tidies it and rejects any
 // result that is not valid Go, so a template or identifier mistake fails here
 // rather than in the user's build.
-func render(entities []ha.EntityState, include, exclude []string) ([]byte, error) {
+func render(entities []ha.EntityState, areas map[string]areaRef, include, exclude []string) ([]byte, error) {
 	domainMap := make(map[string]*Domain)
+	areaMap := make(map[string]*Area)
 	// seen guards against two entity ids camel-casing to the same field, which
 	// would emit a struct with a duplicate field and not compile. light.a_b and
-	// light.a__b both become AB.
+	// light.a__b both become AB. An area's struct holds every domain, so it
+	// is guarded the same way by its fields' prefixed names.
 	seen := make(map[string]map[string]string)
+	seenInArea := make(map[string]map[string]string)
+	areaIDs := make(map[string]string)
 
 	for _, entity := range entities {
 		if entity.State == "unavailable" {
@@ -155,25 +245,70 @@ func render(entities []ha.EntityState, include, exclude []string) ([]byte, error
 			domainMap[domain] = &Domain{Name: domainName(domain), IDType: idType}
 		}
 
-		domainMap[domain].Entities = append(domainMap[domain].Entities, Entity{
+		area := areas[entity.EntityID]
+		e := Entity{
 			FieldName: field,
 			EntityID:  entity.EntityID,
-		})
+			IDType:    domainMap[domain].IDType,
+			Info: EntityInfo{
+				FriendlyName: attribute(entity, "friendly_name"),
+				DeviceClass:  attribute(entity, "device_class"),
+				Area:         area.Name,
+			},
+		}
+		e.Comment = comment(e.Info)
+		domainMap[domain].Entities = append(domainMap[domain].Entities, e)
+
+		if area.ID == "" {
+			continue
+		}
+		name := identifier(area.ID)
+		if name == "" {
+			return nil, fmt.Errorf("area %q has no usable name", area.ID)
+		}
+		if prior, ok := areaIDs[name]; !ok {
+			areaIDs[name] = area.ID
+			areaMap[name] = &Area{Name: name}
+			seenInArea[name] = make(map[string]string)
+		} else if prior != area.ID {
+			return nil, fmt.Errorf("areas %q and %q both map to %s", prior, area.ID, name)
+		}
+		e.FieldName = domainName(domain) + field
+		if prior, clash := seenInArea[name][e.FieldName]; clash {
+			return nil, fmt.Errorf("entities %q and %q both map to field %sArea.%s",
+				prior, entity.EntityID, name, e.FieldName)
+		}
+		seenInArea[name][e.FieldName] = entity.EntityID
+		areaMap[name].Entities = append(areaMap[name].Entities, e)
 	}
 
+	byField := func(a, b Entity) int { return strings.Compare(a.FieldName, b.FieldName) }
 	domains := make([]Domain, 0, len(domainMap))
 	for _, domain := range domainMap {
-		domain.Entities = slices.SortedFunc(slices.Values(domain.Entities), func(a, b Entity) int {
-			return strings.Compare(a.FieldName, b.FieldName)
-		})
+		domain.Entities = slices.SortedFunc(slices.Values(domain.Entities), byField)
 		domains = append(domains, *domain)
 	}
 	// Map iteration is randomised, so without this the generated file changed
 	// byte for byte on every run and showed up in every diff.
 	slices.SortFunc(domains, func(a, b Domain) int { return strings.Compare(a.Name, b.Name) })
 
+	grouped := make([]Area, 0, len(areaMap))
+	for _, area := range areaMap {
+		area.Entities = slices.SortedFunc(slices.Values(area.Entities), byField)
+		grouped = append(grouped, *area)
+	}
+	slices.SortFunc(grouped, func(a, b Area) int { return strings.Compare(a.Name, b.Name) })
+
+	if err := checkNames(domains, grouped); err != nil {
+		return nil, err
+	}
+
 	var buf bytes.Buffer
-	if err := entitiesTemplate.Execute(&buf, struct{ Domains []Domain }{domains}); err != nil {
+	data := struct {
+		Domains []Domain
+		Areas   []Area
+	}{domains, grouped}
+	if err := entitiesTemplate.Execute(&buf, data); err != nil {
 		return nil, fmt.Errorf("executing template: %w", err)
 	}
 
@@ -184,11 +319,61 @@ func render(entities []ha.EntityState, include, exclude []string) ([]byte, error
 	return formatted, nil
 }
 
+// checkNames makes sure no two of the package's top-level names collide: a
+// domain called info would declare Info twice, and one called kitchen_area the
+// same type as the kitchen's area.
+func checkNames(domains []Domain, areas []Area) error {
+	names := map[string]string{"EntityInfo": "the package", "Info": "the package"}
+	if len(areas) > 0 {
+		names["AreaIndex"], names["Areas"] = "the package", "the package"
+	}
+	claim := func(name, by string) error {
+		if prior, clash := names[name]; clash {
+			return fmt.Errorf("%s and %s both map to %s", prior, by, name)
+		}
+		names[name] = by
+		return nil
+	}
+	for _, d := range domains {
+		if err := claim(d.Name, "domain "+d.Name); err != nil {
+			return err
+		}
+		if err := claim(d.Name+"Domain", "domain "+d.Name); err != nil {
+			return err
+		}
+	}
+	for _, a := range areas {
+		if err := claim(a.Name+"Area", "area "+a.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attribute is one of an entity's string attributes, or "" if it has none.
+func attribute(entity ha.EntityState, key string) string {
+	s, _ := entity.Attributes[key].(string)
+	return docLine(s)
+}
+
+// comment describes an entity on its field's line, e.g. "Kitchen Ceiling
+// (motion)".
+func comment(info EntityInfo) string {
+	switch {
+	case info.DeviceClass == "":
+		return info.FriendlyName
+	case info.FriendlyName == "":
+		return "(" + info.DeviceClass + ")"
+	}
+	return info.FriendlyName + " (" + info.DeviceClass + ")"
+}
+
 // domainName is the exported Go identifier for a domain, e.g. input_boolean ->
 // InputBoolean.
 func domainName(domain string) string { return toCamelCase(domain) }
 
-// generate writes entities/entities.go from the entities the app can see.
+// generate writes entities/entities.go from the entities the app can see, and
+// haservices/services.go from the services Home Assistant exposes.
 func generate(config Config) error {
 	app, err := ha.NewApp(types.NewAppRequest{
 		URL:         config.URL,
@@ -204,7 +389,12 @@ func generate(config Config) error {
 		return fmt.Errorf("failed to list entities: %w", err)
 	}
 
-	out, err := render(entities, config.IncludeDomains, config.ExcludeDomains)
+	areas, err := entityAreas(app)
+	if err != nil {
+		return err
+	}
+
+	out, err := render(entities, areas, config.IncludeDomains, config.ExcludeDomains)
 	if err != nil {
 		return err
 	}
@@ -216,11 +406,46 @@ func generate(config Config) error {
 		return fmt.Errorf("failed to write entities.go: %w", err)
 	}
 
+	registry, err := app.Command(map[string]any{"type": "get_services"})
+	if err != nil {
+		return fmt.Errorf("failed to list services: %w", err)
+	}
+	out, err = renderServices(registry, config.IncludeDomains, config.ExcludeDomains)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("haservices", 0755); err != nil {
+		return fmt.Errorf("failed to create haservices directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join("haservices", "services.go"), out, 0644); err != nil {
+		return fmt.Errorf("failed to write services.go: %w", err)
+	}
+
 	return nil
 }
 
+// entityAreas asks Home Assistant's registries which area each entity is in,
+// its own or, without one, its device's.
+func entityAreas(app *ha.App) (map[string]areaRef, error) {
+	areaList, err := app.Registry().Areas()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list areas: %w", err)
+	}
+	areas := make(map[string]areaRef)
+	for _, a := range areaList {
+		ids, err := app.Registry().EntitiesInArea(a.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the entities in %s: %w", a.Name, err)
+		}
+		for _, id := range ids {
+			areas[id] = areaRef{ID: a.ID, Name: a.Name}
+		}
+	}
+	return areas, nil
+}
+
 func main() {
-	println("Generating entities.go...")
+	println("Generating entities.go and services.go...")
 	configFile := flag.String("config", "gen.yaml", "Path to config file")
 	flag.Parse()
 
@@ -256,5 +481,5 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Println("Generated entities/entities.go")
+	fmt.Println("Generated entities/entities.go and haservices/services.go")
 }
@@ -71,7 +71,7 @@ func TestRenderProducesTypedConstants(t *testing.T) {
 		entity("light.kitchen", "on"),
 		entity("light.hall", "off"),
 		entity("switch.fan", "on"),
-	}, nil, nil)
+	}, nil, nil, nil)
 	require.NoError(t, err)
 	parseGenerated(t, out)
 
@@ -84,7 +84,7 @@ func TestRenderProducesTypedConstants(t *testing.T) {
 }
 
 func TestRenderUnknownDomainFallsBackToEntityID(t *testing.T) {
-	out, err := render([]ha.EntityState{entity("weather.home", "sunny")}, nil, nil)
+	out, err := render([]ha.EntityState{entity("weather.home", "sunny")}, nil, nil, nil)
 	require.NoError(t, err)
 	parseGenerated(t, out)
 	assert.Contains(t, string(out), "Home services.EntityID")
@@ -95,7 +95,7 @@ func TestRenderSkipsUnavailableAndMalformed(t *testing.T) {
 		entity("light.kitchen", "unavailable"),
 		entity("light.hall", "on"),
 		entity("no_domain", "on"),
-	}, nil, nil)
+	}, nil, nil, nil)
 	require.NoError(t, err)
 	parseGenerated(t, out)
 
@@ -112,13 +112,13 @@ func TestRenderAppliesIncludeAndExclude(t *testing.T) {
 		entity("climate.hvac", "cool"),
 	}
 
-	included, err := render(entities, []string{"light"}, nil)
+	included, err := render(entities, nil, []string{"light"}, nil)
 	require.NoError(t, err)
 	assert.Contains(t, string(included), "light.kitchen")
 	assert.NotContains(t, string(included), "switch.fan")
 	assert.NotContains(t, string(included), "climate.hvac")
 
-	excluded, err := render(entities, nil, []string{"switch"})
+	excluded, err := render(entities, nil, nil, []string{"switch"})
 	require.NoError(t, err)
 	assert.Contains(t, string(excluded), "light.kitchen")
 	assert.NotContains(t, string(excluded), "switch.fan")
@@ -134,10 +134,10 @@ func TestRenderIsDeterministic(t *testing.T) {
 		entity("climate.hvac", "cool"),
 	}
 
-	first, err := render(entities, nil, nil)
+	first, err := render(entities, nil, nil, nil)
 	require.NoError(t, err)
 	for i := 0; i < 20; i++ {
-		again, err := render(entities, nil, nil)
+		again, err := render(entities, nil, nil, nil)
 		require.NoError(t, err)
 		require.Equal(t, first, again, "render output changed between runs")
 	}
@@ -154,14 +154,14 @@ func TestRenderRejectsFieldCollision(t *testing.T) {
 	_, err := render([]ha.EntityState{
 		entity("light.a_b", "on"),
 		entity("light.a__b", "on"),
-	}, nil, nil)
+	}, nil, nil, nil)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "light.a_b")
 	assert.Contains(t, err.Error(), "light.a__b")
 }
 
 func TestRenderEmptyInputIsValidEmptyPackage(t *testing.T) {
-	out, err := render(nil, nil, nil)
+	out, err := render(nil, nil, nil, nil)
 	require.NoError(t, err)
 	parseGenerated(t, out)
 
@@ -170,3 +170,48 @@ func TestRenderEmptyInputIsValidEmptyPackage(t *testing.T) {
 	// No domains means no import, or it would be unused and not compile.
 	assert.NotContains(t, s, "import")
 }
+
+func TestRenderGroupsByAreaAndRecordsMetadata(t *testing.T) {
+	motion := entity("binary_sensor.kitchen_motion", "off")
+	motion.Attributes = map[string]any{"friendly_name": "Kitchen\nMotion", "device_class": "motion"}
+	ceiling := entity("light.ceiling", "on")
+	ceiling.Attributes = map[string]any{"friendly_name": "Ceiling"}
+
+	out, err := render([]ha.EntityState{motion, ceiling, entity("switch.fan", "on")}, map[string]areaRef{
+		"binary_sensor.kitchen_motion": {ID: "kitchen", Name: "Kitchen"},
+		"light.ceiling":                {ID: "kitchen", Name: "Kitchen"},
+	}, nil, nil)
+	require.NoError(t, err)
+	parseGenerated(t, out)
+
+	s := string(out)
+	assert.Contains(t, s, "KitchenMotion services.EntityID // Kitchen Motion (motion)")
+	assert.Contains(t, s, "Ceiling services.LightID // Ceiling")
+	assert.Contains(t, s, "Kitchen KitchenArea")
+	assert.Contains(t, s, "type KitchenArea struct {")
+	assert.Contains(t, s, `LightCeiling:              "light.ceiling",`)
+	assert.Contains(t, s, `BinarySensorKitchenMotion: "binary_sensor.kitchen_motion",`)
+	assert.Contains(t, s, `"light.ceiling":                {FriendlyName: "Ceiling", Area: "Kitchen"},`)
+	assert.Contains(t, s, `"switch.fan":                   {},`)
+	assert.NotContains(t, s, "SwitchFan", "an entity without an area is only under its domain")
+}
+
+func TestRenderWithoutAreasDeclaresNoIndex(t *testing.T) {
+	out, err := render([]ha.EntityState{entity("light.hall", "on")}, nil, nil, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "AreaIndex")
+	assert.Contains(t, string(out), "var Info = map[string]EntityInfo{")
+}
+
+func TestRenderRejectsNameCollisions(t *testing.T) {
+	// A domain called info would declare Info twice.
+	_, err := render([]ha.EntityState{entity("info.x", "on")}, nil, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Info")
+
+	// So would a kitchen_area domain and the kitchen's area.
+	_, err = render([]ha.EntityState{entity("kitchen_area.x", "on"), entity("light.y", "on")},
+		map[string]areaRef{"light.y": {ID: "kitchen", Name: "Kitchen"}}, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "KitchenArea")
+}
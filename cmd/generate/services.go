@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/Xevion/go-ha/services"
+)
+
+// serviceSpec is one service as get_services describes it.
+type serviceSpec struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Fields      map[string]fieldSpec `json:"fields"`
+
+	// Target is present, if empty, on services that act on entities, areas
+	// or devices.
+	Target json.RawMessage `json:"target"`
+}
+
+// fieldSpec is one field of a service's data. A section groups fields in the
+// frontend and carries them in Fields, rather than being one itself.
+type fieldSpec struct {
+	Description string                     `json:"description"`
+	Required    bool                       `json:"required"`
+	Selector    map[string]json.RawMessage `json:"selector"`
+	Fields      map[string]fieldSpec       `json:"fields"`
+}
+
+// ServiceDomain is one domain's services, as the services template emits
+// them.
+type ServiceDomain struct {
+	Name     string
+	Domain   string
+	IDType   string
+	Services []ServiceMethod
+}
+
+type ServiceMethod struct {
+	Method  string
+	Service string
+	Doc     string
+
+	// Targeted services take an entity id; DataType is empty for services
+	// without fields.
+	Targeted bool
+	DataType string
+	Fields   []ServiceField
+}
+
+type ServiceField struct {
+	FieldName string
+	Key       string
+	Type      string
+	Doc       string
+	Required  bool
+
+	// Pointer marks an optional field whose type has no nil of its own to
+	// leave it unset with.
+	Pointer bool
+}
+
+var servicesTemplate = template.Must(template.New("services").Parse(`// Code generated by go generate; DO NOT EDIT.
+
+// Package haservices calls every service the Home Assistant it was generated
+// from exposes, custom integrations' included.
+package haservices
+
+{{ if .Domains }}
+import (
+	"context"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/services"
+)
+
+// Services holds every domain's services.
+type Services struct {
+	{{- range .Domains }}
+	{{ .Name }} {{ .Name }}Services
+	{{- end }}
+}
+
+// New builds the services around s. Every call waits for Home Assistant to
+// confirm it, up to the app's ServiceTimeout.
+func New(s *ha.Service) Services {
+	return Services{
+		{{- range .Domains }}
+		{{ .Name }}: {{ .Name }}Services{s},
+		{{- end }}
+	}
+}
+
+// Ptr returns a pointer to v, for the optional fields of service data.
+func Ptr[T any](v T) *T { return &v }
+{{ range .Domains }}
+{{- $domain := . }}
+// {{ .Name }}Services calls the {{ .Domain }} domain's services.
+type {{ .Name }}Services struct{ s *ha.Service }
+{{ range .Services }}
+// {{ .Method }} calls {{ $domain.Domain }}.{{ .Service }}.{{ if .Doc }} {{ .Doc }}{{ end }}
+func (d {{ $domain.Name }}Services) {{ .Method }}(ctx context.Context
+	{{- if .Targeted }}, entityID services.{{ $domain.IDType }}{{ end }}
+	{{- if .DataType }}, data {{ .DataType }}{{ end }}) (services.ServiceResult, error) {
+	return d.s.CallWithResult(ctx, "{{ $domain.Domain }}", "{{ .Service }}",
+		{{- if .Targeted }} services.EntityID(entityID){{ else }} ""{{ end }},
+		{{- if .DataType }} data.serviceData(){{ else }} nil{{ end }})
+}
+{{ if .DataType }}
+// {{ .DataType }} is the data {{ $domain.Domain }}.{{ .Service }} takes. Optional
+// fields left nil are not sent.
+type {{ .DataType }} struct {
+	{{- range .Fields }}
+	{{ if .Doc }}// {{ .Doc }}
+	{{ end }}{{ .FieldName }} {{ if .Pointer }}*{{ end }}{{ .Type }}
+	{{- end }}
+}
+
+func (d {{ .DataType }}) serviceData() map[string]any {
+	data := map[string]any{}
+	{{- range .Fields }}
+	{{- if .Required }}
+	data["{{ .Key }}"] = d.{{ .FieldName }}
+	{{- else }}
+	if d.{{ .FieldName }} != nil {
+		data["{{ .Key }}"] = {{ if .Pointer }}*{{ end }}d.{{ .FieldName }}
+	}
+	{{- end }}
+	{{- end }}
+	return data
+}
+{{ end }}
+{{- end }}
+{{- end }}
+{{- end }}
+`))
+
+// renderServices turns get_services' answer into the source of the
+// haservices package: a method per service, taking an entity id if the
+// service has a target and a struct of its fields if it has any. Domains are
+// filtered as entities are.
+//
+// Like render, it sorts everything it emits, so the output is the same from
+// one run to the next, and checks it with go/format.
+func renderServices(raw json.RawMessage, include, exclude []string) ([]byte, error) {
+	var registry map[string]map[string]serviceSpec
+	if err := json.Unmarshal(raw, &registry); err != nil {
+		return nil, fmt.Errorf("decoding services: %w", err)
+	}
+
+	// Every type goes into one package, so a domain's name and a service's
+	// data type must not collide with any other.
+	types := map[string]string{"Services": "the package", "Ptr": "the package"}
+	claim := func(name, by string) error {
+		if prior, clash := types[name]; clash {
+			return fmt.Errorf("%s and %s both map to %s", prior, by, name)
+		}
+		types[name] = by
+		return nil
+	}
+
+	var domains []ServiceDomain
+	for _, domain := range slices.Sorted(maps.Keys(registry)) {
+		if !includes(domain, include, exclude) {
+			continue
+		}
+		d := ServiceDomain{Name: identifier(domain), Domain: domain, IDType: "EntityID"}
+		if d.Name == "" {
+			return nil, fmt.Errorf("domain %q has no usable name", domain)
+		}
+		if err := claim(d.Name+"Services", "domain "+domain); err != nil {
+			return nil, err
+		}
+		if idType, ok := services.DomainIDTypes[domain]; ok {
+			d.IDType = idType
+		}
+
+		methods := map[string]string{}
+		specs := registry[domain]
+		for _, service := range slices.Sorted(maps.Keys(specs)) {
+			spec := specs[service]
+			m := ServiceMethod{
+				Method:   identifier(service),
+				Service:  service,
+				Doc:      docLine(spec.Description),
+				Targeted: spec.Target != nil,
+			}
+			if m.Method == "" {
+				return nil, fmt.Errorf("service %s.%s has no usable name", domain, service)
+			}
+			if prior, clash := methods[m.Method]; clash {
+				return nil, fmt.Errorf("services %s.%s and %s.%s both map to %s.%s",
+					domain, prior, domain, service, d.Name, m.Method)
+			}
+			methods[m.Method] = service
+
+			fields, err := serviceFields(domain, service, spec.Fields)
+			if err != nil {
+				return nil, err
+			}
+			if len(fields) > 0 {
+				m.DataType = d.Name + m.Method + "Data"
+				m.Fields = fields
+				if err := claim(m.DataType, "service "+domain+"."+service); err != nil {
+					return nil, err
+				}
+			}
+			d.Services = append(d.Services, m)
+		}
+		domains = append(domains, d)
+	}
+
+	var buf bytes.Buffer
+	if err := servicesTemplate.Execute(&buf, struct{ Domains []ServiceDomain }{domains}); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated source is not valid Go: %w", err)
+	}
+	return formatted, nil
+}
+
+// serviceFields lists a service's fields sorted by name, the fields of its
+// sections included.
+func serviceFields(domain, service string, specs map[string]fieldSpec) ([]ServiceField, error) {
+	flat := map[string]fieldSpec{}
+	for key, spec := range specs {
+		if spec.Fields != nil {
+			maps.Copy(flat, spec.Fields)
+			continue
+		}
+		flat[key] = spec
+	}
+
+	var fields []ServiceField
+	seen := map[string]string{}
+	for _, key := range slices.Sorted(maps.Keys(flat)) {
+		spec := flat[key]
+		f := ServiceField{
+			FieldName: identifier(key),
+			Key:       key,
+			Type:      fieldType(spec.Selector),
+			Doc:       docLine(spec.Description),
+			Required:  spec.Required,
+		}
+		f.Pointer = !f.Required && f.Type != "any" && !strings.HasPrefix(f.Type, "[]")
+		if f.FieldName == "" {
+			return nil, fmt.Errorf("field %q of %s.%s has no usable name", key, domain, service)
+		}
+		if prior, clash := seen[f.FieldName]; clash {
+			return nil, fmt.Errorf("fields %q and %q of %s.%s both map to %s", prior, key, domain, service, f.FieldName)
+		}
+		seen[f.FieldName] = key
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// fieldType is the Go type for a field, going by its selector. A selector
+// this does not know, or a field without one, takes any value.
+func fieldType(selector map[string]json.RawMessage) string {
+	for kind, raw := range selector {
+		var opts struct {
+			Multiple bool `json:"multiple"`
+		}
+		_ = json.Unmarshal(raw, &opts)
+
+		var t string
+		switch kind {
+		case "boolean":
+			t = "bool"
+		case "number", "color_temp":
+			t = "float64"
+		case "text", "select", "entity", "device", "area", "floor", "label",
+			"time", "date", "datetime", "icon", "theme", "conversation_agent", "template":
+			t = "string"
+		case "color_rgb":
+			return "[3]int"
+		default:
+			return "any"
+		}
+		if opts.Multiple {
+			return "[]" + t
+		}
+		return t
+	}
+	return "any"
+}
+
+// identifier is the exported Go identifier for a name Home Assistant gives,
+// whatever characters it holds beyond the usual lower case and underscores.
+func identifier(name string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, name)
+	return toCamelCase(strings.Trim(cleaned, "_"))
+}
+
+// docLine is a description fit for a one-line comment.
+func docLine(description string) string {
+	return strings.Join(strings.Fields(description), " ")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registry is a cut-down get_services answer: a built-in domain with a
+// target, a section and several selector kinds, and a custom integration's
+// service with neither target nor fields.
+const registry = `{
+	"light": {
+		"turn_on": {
+			"name": "Turn on",
+			"description": "Turns on one or more lights\nand adjusts their properties.",
+			"target": {"entity": [{"domain": ["light"]}]},
+			"fields": {
+				"transition": {"selector": {"number": {"min": 0, "max": 300}}},
+				"rgb_color": {"selector": {"color_rgb": {}}},
+				"advanced_fields": {
+					"collapsed": true,
+					"fields": {
+						"flash": {"selector": {"select": {"options": ["long", "short"]}}},
+						"effect": {"description": "Light effect.", "selector": {"text": null}}
+					}
+				}
+			}
+		},
+		"turn_off": {"target": {}, "fields": {}}
+	},
+	"pool_pump": {
+		"run_cycle": {"description": "Runs a cleaning cycle.", "fields": {
+			"minutes": {"required": true, "selector": {"number": {}}},
+			"zones": {"selector": {"select": {"multiple": true, "options": ["a", "b"]}}},
+			"schedule": {"selector": {"object": {}}}
+		}},
+		"stop": {}
+	}
+}`
+
+func TestRenderServicesProducesTypedWrappers(t *testing.T) {
+	out, err := renderServices([]byte(registry), nil, nil)
+	require.NoError(t, err)
+	parseGenerated(t, out)
+
+	s := string(out)
+	assert.Contains(t, s, "package haservices")
+	assert.Contains(t, s, "Light    LightServices")
+	assert.Contains(t, s, "PoolPump PoolPumpServices")
+
+	// A targeted service takes the domain's id type; one with fields, a struct.
+	assert.Contains(t, s, "func (d LightServices) TurnOn(ctx context.Context, entityID services.LightID, data LightTurnOnData)")
+	assert.Contains(t, s, "// TurnOn calls light.turn_on. Turns on one or more lights and adjusts their properties.")
+	assert.Contains(t, s, "func (d LightServices) TurnOff(ctx context.Context, entityID services.LightID)")
+	assert.Contains(t, s, "func (d PoolPumpServices) Stop(ctx context.Context)")
+
+	// Sections are flattened, and each selector picks the field's type.
+	assert.Contains(t, s, "Transition *float64")
+	assert.Contains(t, s, "RgbColor   *[3]int")
+	assert.Contains(t, s, "Flash      *string")
+	assert.Contains(t, s, "Effect     *string")
+	assert.Contains(t, s, "Minutes  float64", "a required field is not optional")
+	assert.Contains(t, s, "Zones    []string", "a nil slice is already unset")
+	assert.Contains(t, s, "Schedule any")
+	assert.NotContains(t, s, "AdvancedFields")
+
+	assert.Contains(t, s, `data["minutes"] = d.Minutes`)
+	assert.Contains(t, s, `data["transition"] = *d.Transition`)
+	assert.Contains(t, s, `data["zones"] = d.Zones`)
+}
+
+func TestRenderServicesAppliesIncludeAndExclude(t *testing.T) {
+	out, err := renderServices([]byte(registry), nil, []string{"light"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "LightServices")
+	assert.Contains(t, string(out), "PoolPumpServices")
+}
+
+func TestRenderServicesIsDeterministic(t *testing.T) {
+	first, err := renderServices([]byte(registry), nil, nil)
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		again, err := renderServices([]byte(registry), nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, first, again, "renderServices output changed between runs")
+	}
+	s := string(first)
+	assert.Less(t, strings.Index(s, "func (d LightServices) TurnOff"), strings.Index(s, "func (d LightServices) TurnOn"))
+}
+
+func TestRenderServicesRejectsCollisions(t *testing.T) {
+	_, err := renderServices([]byte(`{"light": {"turn_on": {}, "turn-on": {}}}`), nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TurnOn")
+
+	_, err = renderServices([]byte(`{"x": {"s": {"fields": {"a_b": {}, "a-b": {}}}}}`), nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AB")
+}
+
+func TestRenderServicesEmptyRegistryIsValidEmptyPackage(t *testing.T) {
+	out, err := renderServices([]byte(`{}`), nil, nil)
+	require.NoError(t, err)
+	parseGenerated(t, out)
+	assert.NotContains(t, string(out), "import")
+}
@@ -0,0 +1,237 @@
+// Package color converts between the colour models Home Assistant's lights
+// accept, with the same arithmetic Home Assistant uses, so a colour worked out
+// here lands on the bulb as the colour Home Assistant would have picked.
+//
+// Each model carries the service data for light.turn_on:
+//
+//	run.Services.Light.SetColor("light.lounge", color.Kelvin(2700))
+//	run.Services.Light.SetColor("light.desk", color.MustNamed("tomato"))
+package color
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrUnknownColor reports a colour name that is not one of the CSS colours.
+var ErrUnknownColor = errors.New("unknown colour name")
+
+// Color is a colour light.turn_on can take.
+type Color interface {
+	// ServiceData is the light.turn_on data setting this colour, keyed as
+	// Home Assistant expects: rgb_color, hs_color, xy_color or
+	// color_temp_kelvin.
+	ServiceData() map[string]any
+}
+
+// RGB is a colour as red, green and blue, each 0 to 255.
+type RGB [3]uint8
+
+// HS is a colour as hue, 0 to 360 degrees, and saturation, 0 to 100 percent,
+// the scale Home Assistant's hs_color uses.
+type HS struct {
+	Hue, Saturation float64
+}
+
+// XY is a colour as CIE 1931 chromaticity, the model Hue and Zigbee bulbs use
+// natively.
+type XY struct {
+	X, Y float64
+}
+
+// Kelvin is a white colour temperature. Warm white is around 2700, daylight
+// around 6500.
+type Kelvin int
+
+// Mireds is a colour temperature as the reciprocal megakelvin Home Assistant
+// used before it moved to kelvin, and some integrations still report.
+type Mireds int
+
+func (c RGB) ServiceData() map[string]any {
+	return map[string]any{"rgb_color": []int{int(c[0]), int(c[1]), int(c[2])}}
+}
+
+func (c HS) ServiceData() map[string]any {
+	return map[string]any{"hs_color": []float64{c.Hue, c.Saturation}}
+}
+
+func (c XY) ServiceData() map[string]any {
+	return map[string]any{"xy_color": []float64{c.X, c.Y}}
+}
+
+func (k Kelvin) ServiceData() map[string]any {
+	return map[string]any{"color_temp_kelvin": int(k)}
+}
+
+func (m Mireds) ServiceData() map[string]any {
+	return m.Kelvin().ServiceData()
+}
+
+func (c RGB) String() string { return fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2]) }
+
+// Mireds converts to mireds, rounding down as Home Assistant does.
+func (k Kelvin) Mireds() Mireds {
+	if k <= 0 {
+		return 0
+	}
+	return Mireds(1_000_000 / int(k))
+}
+
+// Kelvin converts to kelvin, rounding down as Home Assistant does.
+func (m Mireds) Kelvin() Kelvin {
+	if m <= 0 {
+		return 0
+	}
+	return Kelvin(1_000_000 / int(m))
+}
+
+// HS converts to hue and saturation, dropping brightness, which light.turn_on
+// takes separately.
+func (c RGB) HS() HS {
+	r, g, b := float64(c[0])/255, float64(c[1])/255, float64(c[2])/255
+	hi := max(r, g, b)
+	lo := min(r, g, b)
+	if hi == 0 || hi == lo {
+		return HS{}
+	}
+
+	delta := hi - lo
+	var h float64
+	switch hi {
+	case r:
+		h = (g - b) / delta
+	case g:
+		h = 2 + (b-r)/delta
+	default:
+		h = 4 + (r-g)/delta
+	}
+	h = math.Mod(h/6+1, 1)
+	return HS{Hue: round3(h * 360), Saturation: round3(delta / hi * 100)}
+}
+
+// RGB converts to red, green and blue at full brightness.
+func (c HS) RGB() RGB {
+	h := math.Mod(c.Hue, 360) / 360
+	if h < 0 {
+		h++
+	}
+	s := clamp(c.Saturation/100, 0, 1)
+	if s == 0 {
+		return RGB{255, 255, 255}
+	}
+
+	i := math.Floor(h * 6)
+	f := h*6 - i
+	p, q, t := 1-s, 1-s*f, 1-s*(1-f)
+	var r, g, b float64
+	switch int(i) % 6 {
+	case 0:
+		r, g, b = 1, t, p
+	case 1:
+		r, g, b = q, 1, p
+	case 2:
+		r, g, b = p, 1, t
+	case 3:
+		r, g, b = p, q, 1
+	case 4:
+		r, g, b = t, p, 1
+	default:
+		r, g, b = 1, p, q
+	}
+	return RGB{byte255(r), byte255(g), byte255(b)}
+}
+
+// XY converts to CIE 1931 chromaticity with Home Assistant's wide gamut
+// matrix. Black has no chromaticity and converts to 0,0.
+func (c RGB) XY() XY {
+	r, g, b := linear(c[0]), linear(c[1]), linear(c[2])
+	x := r*0.664511 + g*0.154324 + b*0.162028
+	y := r*0.283881 + g*0.668433 + b*0.047685
+	z := r*0.000088 + g*0.072310 + b*0.986039
+	sum := x + y + z
+	if sum == 0 {
+		return XY{}
+	}
+	return XY{X: round3(x / sum), Y: round3(y / sum)}
+}
+
+// RGB converts to red, green and blue at full brightness, scaled so the
+// brightest channel is 255.
+func (c XY) RGB() RGB {
+	y := c.Y
+	if y == 0 {
+		y = 1e-11
+	}
+	bigY := 1.0
+	bigX := bigY / y * c.X
+	bigZ := bigY / y * (1 - c.X - y)
+
+	r := bigX*1.656492 - bigY*0.354851 - bigZ*0.255038
+	g := -bigX*0.707196 + bigY*1.655397 + bigZ*0.036152
+	b := bigX*0.051713 - bigY*0.121364 + bigZ*1.011530
+
+	r, g, b = max(gamma(r), 0), max(gamma(g), 0), max(gamma(b), 0)
+	if hi := max(r, g, b); hi > 1 {
+		r, g, b = r/hi, g/hi, b/hi
+	}
+	return RGB{uint8(r * 255), uint8(g * 255), uint8(b * 255)}
+}
+
+// RGB approximates a colour temperature in red, green and blue, for lights
+// that take colour but not temperature. Temperatures outside 1000 to 40000
+// are clamped into it.
+func (k Kelvin) RGB() RGB {
+	t := clamp(float64(k), 1000, 40000) / 100
+
+	red := 255.0
+	if t > 66 {
+		red = 329.698727446 * math.Pow(t-60, -0.1332047592)
+	}
+
+	var green float64
+	if t <= 66 {
+		green = 99.4708025861*math.Log(t) - 161.1195681661
+	} else {
+		green = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	}
+
+	var blue float64
+	switch {
+	case t >= 66:
+		blue = 255
+	case t <= 19:
+		blue = 0
+	default:
+		blue = 138.5177312231*math.Log(t-10) - 305.0447927307
+	}
+
+	return RGB{
+		uint8(math.Round(clamp(red, 0, 255))),
+		uint8(math.Round(clamp(green, 0, 255))),
+		uint8(math.Round(clamp(blue, 0, 255))),
+	}
+}
+
+// linear undoes sRGB gamma correction on one channel.
+func linear(v uint8) float64 {
+	f := float64(v) / 255
+	if f > 0.04045 {
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	return f / 12.92
+}
+
+// gamma applies sRGB gamma correction to one linear channel.
+func gamma(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+func byte255(v float64) uint8 { return uint8(math.Round(clamp(v, 0, 1) * 255)) }
+
+func round3(v float64) float64 { return math.Round(v*1000) / 1000 }
+
+func clamp(v, lo, hi float64) float64 { return math.Min(math.Max(v, lo), hi) }
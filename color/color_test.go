@@ -0,0 +1,68 @@
+package color
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The expected values are Home Assistant's own, from its color util tests, so
+// a drift from its arithmetic shows up here.
+func TestRGBToXY(t *testing.T) {
+	assert.Equal(t, XY{0.701, 0.299}, RGB{255, 0, 0}.XY())
+	assert.Equal(t, XY{0.172, 0.747}, RGB{0, 255, 0}.XY())
+	assert.Equal(t, XY{0.136, 0.04}, RGB{0, 0, 255}.XY())
+	assert.Equal(t, XY{0.323, 0.329}, RGB{255, 255, 255}.XY())
+	assert.Equal(t, XY{}, RGB{0, 0, 0}.XY())
+}
+
+func TestXYToRGB(t *testing.T) {
+	assert.Equal(t, RGB{255, 0, 60}, XY{1, 0}.RGB())
+	assert.Equal(t, RGB{0, 255, 0}, XY{0, 1}.RGB())
+	assert.Equal(t, RGB{0, 63, 255}, XY{0, 0}.RGB())
+}
+
+func TestRGBAndHS(t *testing.T) {
+	assert.Equal(t, HS{0, 100}, RGB{255, 0, 0}.HS())
+	assert.Equal(t, HS{120, 100}, RGB{0, 255, 0}.HS())
+	assert.Equal(t, HS{38.824, 100}, RGB{255, 165, 0}.HS())
+	assert.Equal(t, HS{}, RGB{128, 128, 128}.HS(), "a grey has no hue")
+
+	assert.Equal(t, RGB{0, 0, 255}, HS{240, 100}.RGB())
+	assert.Equal(t, RGB{255, 255, 255}, HS{0, 0}.RGB())
+	assert.Equal(t, RGB{255, 128, 128}, HS{360, 50}.RGB(), "360 degrees is red again")
+}
+
+func TestColorTemperature(t *testing.T) {
+	assert.Equal(t, Mireds(370), Kelvin(2700).Mireds())
+	assert.Equal(t, Kelvin(6535), Mireds(153).Kelvin())
+	assert.Equal(t, Mireds(0), Kelvin(0).Mireds())
+
+	assert.Equal(t, RGB{255, 254, 250}, Kelvin(6500).RGB())
+	assert.Equal(t, RGB{255, 167, 87}, Kelvin(2700).RGB())
+	assert.Equal(t, Kelvin(500).RGB(), Kelvin(1000).RGB(), "clamped to 1000K")
+}
+
+func TestServiceData(t *testing.T) {
+	assert.Equal(t, map[string]any{"rgb_color": []int{255, 99, 71}}, RGB{255, 99, 71}.ServiceData())
+	assert.Equal(t, map[string]any{"hs_color": []float64{30, 50}}, HS{30, 50}.ServiceData())
+	assert.Equal(t, map[string]any{"xy_color": []float64{0.3, 0.3}}, XY{0.3, 0.3}.ServiceData())
+	assert.Equal(t, map[string]any{"color_temp_kelvin": 2700}, Kelvin(2700).ServiceData())
+	assert.Equal(t, map[string]any{"color_temp_kelvin": 2702}, Mireds(370).ServiceData())
+}
+
+func TestNamed(t *testing.T) {
+	c, err := Named("tomato")
+	require.NoError(t, err)
+	assert.Equal(t, RGB{255, 99, 71}, c)
+	assert.Equal(t, "#ff6347", c.String())
+
+	c, err = Named("Dark Orange")
+	require.NoError(t, err)
+	assert.Equal(t, RGB{255, 140, 0}, c)
+
+	_, err = Named("octarine")
+	assert.ErrorIs(t, err, ErrUnknownColor)
+	assert.Panics(t, func() { MustNamed("octarine") })
+}
@@ -0,0 +1,116 @@
+package ha_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestCommandReturnsTheResult(t *testing.T) {
+	server := hatest.New(t)
+	server.HandleCommand("config/automation/list", func(msg map[string]any) (any, error) {
+		return []map[string]any{{"id": "porch"}}, nil
+	})
+	app := newApp(t, server)
+
+	result, err := app.Command(map[string]any{"type": "config/automation/list"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"id":"porch"}]`, string(result))
+}
+
+// A struct works as well as a map, so a command can be typed by its caller.
+func TestCommandAcceptsAStruct(t *testing.T) {
+	server := hatest.New(t)
+	server.HandleCommand("lovelace/config", func(msg map[string]any) (any, error) {
+		return map[string]any{"url_path": msg["url_path"]}, nil
+	})
+	app := newApp(t, server)
+
+	result, err := app.Command(struct {
+		Type    string `json:"type"`
+		URLPath string `json:"url_path"`
+	}{Type: "lovelace/config", URLPath: "kitchen"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"url_path":"kitchen"}`, string(result))
+}
+
+func TestCommandReportsARefusal(t *testing.T) {
+	server := hatest.New(t)
+	server.HandleCommand("config/broken", func(map[string]any) (any, error) {
+		return nil, errors.New("nope")
+	})
+	app := newApp(t, server)
+
+	_, err := app.Command(map[string]any{"type": "config/broken"})
+	assert.ErrorContains(t, err, "nope")
+}
+
+// The registry holds its lock across a command, so one that is never
+// answered must not hold it for ever.
+func TestCommandGivesUpAfterTheServiceTimeout(t *testing.T) {
+	server := hatest.New(t)
+	release := make(chan struct{})
+	server.HandleCommand("config/stuck", func(map[string]any) (any, error) {
+		<-release
+		return nil, nil
+	})
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.ServiceTimeout = 50 * time.Millisecond })
+	t.Cleanup(func() { close(release) })
+
+	start := time.Now()
+	_, err := app.Command(map[string]any{"type": "config/stuck"})
+	assert.ErrorContains(t, err, "no answer within 50ms")
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestCommandNeedsAType(t *testing.T) {
+	app := newApp(t, hatest.New(t))
+
+	_, err := app.Command(map[string]any{"url_path": "kitchen"})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+
+	_, err = app.Command([]string{"not", "an", "object"})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
+
+func TestSubscribeDeliversUntilCancelled(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	got := make(chan json.RawMessage, 4)
+	sub, err := app.Subscribe(map[string]any{"type": "subscribe_events", "event_type": "doorbell"},
+		func(event json.RawMessage) { got <- event })
+	require.NoError(t, err)
+
+	server.Fire("doorbell", map[string]any{"button": "front"})
+
+	select {
+	case event := <-got:
+		var body struct {
+			EventType string         `json:"event_type"`
+			Data      map[string]any `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(event, &body))
+		assert.Equal(t, "doorbell", body.EventType)
+		assert.Equal(t, "front", body.Data["button"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("the event was never delivered")
+	}
+
+	require.NoError(t, sub.Cancel())
+	server.Fire("doorbell", map[string]any{"button": "back"})
+
+	select {
+	case event := <-got:
+		t.Fatalf("delivered after cancelling: %s", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
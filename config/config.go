@@ -0,0 +1,541 @@
+// Package config loads simple automations from YAML, for setups where the easy
+// rules live in a file and only the complicated ones are written in Go.
+//
+// A file holds a list of automations, each a set of triggers, optional
+// conditions and a list of service calls:
+//
+//	automations:
+//	  - name: porch light at dusk
+//	    triggers:
+//	      - sun: sunset
+//	        offset: -15m
+//	    conditions:
+//	      - state: input_boolean.away
+//	        is: "off"
+//	    actions:
+//	      - service: light.turn_on
+//	        target: light.porch
+//	        data: {brightness_pct: 60}
+//
+// Everything a file can express is also available through the builder, and
+// both kinds of automation run side by side on one App. Unknown keys are
+// rejected, so a misspelt field fails the load instead of being ignored.
+// JSON, being YAML, is accepted too.
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/services"
+)
+
+// ErrInvalidConfig reports a file that does not describe valid automations.
+var ErrInvalidConfig = errors.New("invalid automation config")
+
+// File is the document a config file holds.
+type File struct {
+	Automations []Automation `yaml:"automations"`
+}
+
+// Automation is one rule as written in a file.
+type Automation struct {
+	Name       string      `yaml:"name"`
+	Triggers   []Trigger   `yaml:"triggers"`
+	Conditions []Condition `yaml:"conditions"`
+
+	// Mode is single, restart, queued or parallel. Defaults to single.
+	Mode string `yaml:"mode"`
+
+	// Throttle is the minimum gap between runs, such as 5m.
+	Throttle Duration `yaml:"throttle"`
+
+	Actions []Action `yaml:"actions"`
+}
+
+// Trigger is one way an automation can fire. Exactly one of State, Event, At,
+// Every, Cron, Sun and Startup is set.
+type Trigger struct {
+	// State fires when any of these entities changes state, narrowed by From,
+	// To and For.
+	State Strings  `yaml:"state"`
+	From  string   `yaml:"from"`
+	To    string   `yaml:"to"`
+	For   Duration `yaml:"for"`
+
+	// Event fires on Home Assistant events of these types.
+	Event Strings `yaml:"event"`
+
+	// At fires daily at a time of day, written HH:MM.
+	At string `yaml:"at"`
+
+	// Every fires on a fixed interval.
+	Every Duration `yaml:"every"`
+
+	// Cron fires on a cron expression.
+	Cron string `yaml:"cron"`
+
+	// Sun fires at sunrise, sunset, dawn, dusk, noon, nautical_dawn,
+	// nautical_dusk, astronomical_dawn or astronomical_dusk, moved by Offset.
+	Sun    string   `yaml:"sun"`
+	Offset Duration `yaml:"offset"`
+
+	// Startup fires once, when the app starts.
+	Startup bool `yaml:"startup"`
+}
+
+// Condition decides whether a fired automation runs. Exactly one of its
+// families is set: State with Is or IsNot, After and Before, Sun, Weekdays, or
+// one of the combinators All, Any and Not.
+type Condition struct {
+	State string  `yaml:"state"`
+	Is    Strings `yaml:"is"`
+	IsNot string  `yaml:"is_not"`
+
+	// After and Before bound the time of day, written HH:MM. With both set the
+	// window may cross midnight.
+	After  string `yaml:"after"`
+	Before string `yaml:"before"`
+
+	// Sun is up or down.
+	Sun string `yaml:"sun"`
+
+	// Weekdays lists days by name, such as [sat, sun].
+	Weekdays []string `yaml:"weekdays"`
+
+	All []Condition `yaml:"all"`
+	Any []Condition `yaml:"any"`
+	Not *Condition  `yaml:"not"`
+}
+
+// Action is one step of an automation: a service call, or a pause before the
+// next step.
+type Action struct {
+	// Service is the call to make, written domain.service.
+	Service string         `yaml:"service"`
+	Target  Strings        `yaml:"target"`
+	Data    map[string]any `yaml:"data"`
+
+	// Delay pauses before the next step.
+	Delay Duration `yaml:"delay"`
+}
+
+// Duration is a time.Duration written the way time.ParseDuration reads it,
+// such as 90s or 1h30m.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Strings accepts either a single string or a list of them, since most fields
+// that take several entities usually name one.
+type Strings []string
+
+func (s *Strings) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = Strings{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// Load reads a config file and registers its automations on the app.
+func Load(app *ha.App, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	automations, err := Parse(app, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return app.RegisterAutomations(automations...)
+}
+
+// Parse builds the automations a config document describes without
+// registering them, so they can be inspected or registered alongside others.
+// The app is what their service calls are made through.
+func Parse(app *ha.App, data []byte) ([]ha.Automation, error) {
+	file, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	automations := make([]ha.Automation, 0, len(file.Automations))
+	for i, spec := range file.Automations {
+		a, err := spec.build(app)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", spec.label(i), err)
+		}
+		automations = append(automations, a)
+	}
+	return automations, nil
+}
+
+func decode(data []byte) (File, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var file File
+	if err := decoder.Decode(&file); err != nil {
+		return File{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return file, nil
+}
+
+// label names the i'th automation of a file for an error about it.
+func (spec Automation) label(i int) string {
+	if spec.Name == "" {
+		return fmt.Sprintf("automation %d", i+1)
+	}
+	return spec.Name
+}
+
+func (spec Automation) build(app *ha.App) (ha.Automation, error) {
+	if spec.Name == "" {
+		return ha.Automation{}, fmt.Errorf("%w: an automation needs a name", ErrInvalidConfig)
+	}
+	if len(spec.Actions) == 0 {
+		return ha.Automation{}, fmt.Errorf("%w: no actions", ErrInvalidConfig)
+	}
+
+	b := ha.NewAutomation(spec.Name)
+
+	triggers := make([]ha.Trigger, 0, len(spec.Triggers))
+	for _, t := range spec.Triggers {
+		trig, err := t.build()
+		if err != nil {
+			return ha.Automation{}, err
+		}
+		triggers = append(triggers, trig)
+	}
+	b = b.On(triggers...)
+
+	if len(spec.Conditions) > 0 {
+		conditions, err := buildConditions(spec.Conditions)
+		if err != nil {
+			return ha.Automation{}, err
+		}
+		b = b.When(ha.All(conditions...))
+	}
+
+	if spec.Mode != "" {
+		mode, err := parseMode(spec.Mode)
+		if err != nil {
+			return ha.Automation{}, err
+		}
+		b = b.Mode(mode)
+	}
+	if spec.Throttle > 0 {
+		b = b.Throttle(time.Duration(spec.Throttle))
+	}
+
+	steps := make([]step, 0, len(spec.Actions))
+	for _, a := range spec.Actions {
+		s, err := a.build(app)
+		if err != nil {
+			return ha.Automation{}, err
+		}
+		steps = append(steps, s)
+	}
+
+	// The builder validates what is left: times of day, empty trigger lists
+	// and the rest are refused with the same errors as in Go.
+	return b.Do(func(ctx context.Context, _ ha.Run) error {
+		for _, s := range steps {
+			if err := s(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Build()
+}
+
+func (t Trigger) build() (ha.Trigger, error) {
+	var (
+		built ha.Trigger
+		set   int
+	)
+	if len(t.State) > 0 {
+		set++
+		trig := ha.StateChanged(t.State...)
+		if t.From != "" {
+			trig = trig.From(t.From)
+		}
+		if t.To != "" {
+			trig = trig.To(t.To)
+		}
+		if t.For > 0 {
+			trig = trig.For(time.Duration(t.For))
+		}
+		built = trig
+	} else if t.From != "" || t.To != "" || t.For != 0 {
+		return nil, fmt.Errorf("%w: from, to and for narrow a state trigger", ErrInvalidConfig)
+	}
+	if len(t.Event) > 0 {
+		set++
+		built = ha.EventFired(t.Event...)
+	}
+	if t.At != "" {
+		set++
+		at, err := parseClock(t.At)
+		if err != nil {
+			return nil, err
+		}
+		built = ha.Daily(at)
+	}
+	if t.Every > 0 {
+		set++
+		built = ha.Every(time.Duration(t.Every))
+	}
+	if t.Cron != "" {
+		set++
+		built = ha.Cron(t.Cron)
+	}
+	if t.Sun != "" {
+		set++
+		offset := time.Duration(t.Offset)
+		switch strings.ToLower(t.Sun) {
+		case "sunrise":
+			built = ha.Sunrise(offset)
+		case "sunset":
+			built = ha.Sunset(offset)
+		case "dawn":
+			built = ha.Dawn(offset)
+		case "dusk":
+			built = ha.Dusk(offset)
+		case "noon":
+			built = ha.SolarNoon(offset)
+		case "nautical_dawn":
+			built = ha.NauticalDawn(offset)
+		case "nautical_dusk":
+			built = ha.NauticalDusk(offset)
+		case "astronomical_dawn":
+			built = ha.AstronomicalDawn(offset)
+		case "astronomical_dusk":
+			built = ha.AstronomicalDusk(offset)
+		default:
+			return nil, fmt.Errorf("%w: unknown sun event %q", ErrInvalidConfig, t.Sun)
+		}
+	} else if t.Offset != 0 {
+		return nil, fmt.Errorf("%w: offset applies only to a sun trigger", ErrInvalidConfig)
+	}
+	if t.Startup {
+		set++
+		built = ha.AtStartup()
+	}
+
+	if set != 1 {
+		return nil, fmt.Errorf("%w: a trigger needs exactly one kind, found %d", ErrInvalidConfig, set)
+	}
+	return built, nil
+}
+
+func buildConditions(specs []Condition) ([]ha.Condition, error) {
+	out := make([]ha.Condition, 0, len(specs))
+	for _, spec := range specs {
+		c, err := spec.build()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (c Condition) build() (ha.Condition, error) {
+	var (
+		built ha.Condition
+		set   int
+	)
+	if c.State != "" {
+		set++
+		switch {
+		case len(c.Is) > 0 && c.IsNot != "":
+			return nil, fmt.Errorf("%w: %s has both is and is_not", ErrInvalidConfig, c.State)
+		case len(c.Is) == 1:
+			built = ha.StateIs(c.State, c.Is[0])
+		case len(c.Is) > 1:
+			built = ha.StateIsOneOf(c.State, c.Is...)
+		case c.IsNot != "":
+			built = ha.StateIsNot(c.State, c.IsNot)
+		default:
+			return nil, fmt.Errorf("%w: %s needs is or is_not", ErrInvalidConfig, c.State)
+		}
+	}
+	if c.After != "" || c.Before != "" {
+		set++
+		cond, err := timeCondition(c.After, c.Before)
+		if err != nil {
+			return nil, err
+		}
+		built = cond
+	}
+	if c.Sun != "" {
+		set++
+		switch strings.ToLower(c.Sun) {
+		case "up":
+			built = ha.SunIsUp()
+		case "down":
+			built = ha.SunIsDown()
+		default:
+			return nil, fmt.Errorf("%w: sun is up or down, not %q", ErrInvalidConfig, c.Sun)
+		}
+	}
+	if len(c.Weekdays) > 0 {
+		set++
+		days := make([]time.Weekday, 0, len(c.Weekdays))
+		for _, name := range c.Weekdays {
+			day, err := parseWeekday(name)
+			if err != nil {
+				return nil, err
+			}
+			days = append(days, day)
+		}
+		built = ha.OnWeekdays(days...)
+	}
+	if len(c.All) > 0 {
+		set++
+		inner, err := buildConditions(c.All)
+		if err != nil {
+			return nil, err
+		}
+		built = ha.All(inner...)
+	}
+	if len(c.Any) > 0 {
+		set++
+		inner, err := buildConditions(c.Any)
+		if err != nil {
+			return nil, err
+		}
+		built = ha.Any(inner...)
+	}
+	if c.Not != nil {
+		set++
+		inner, err := c.Not.build()
+		if err != nil {
+			return nil, err
+		}
+		built = ha.Not(inner)
+	}
+
+	if set != 1 {
+		return nil, fmt.Errorf("%w: a condition needs exactly one kind, found %d", ErrInvalidConfig, set)
+	}
+	return built, nil
+}
+
+func timeCondition(after, before string) (ha.Condition, error) {
+	var start, end ha.ClockTime
+	var err error
+	if after != "" {
+		if start, err = parseClock(after); err != nil {
+			return nil, err
+		}
+	}
+	if before != "" {
+		if end, err = parseClock(before); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case after != "" && before != "":
+		return ha.TimeBetween(start, end), nil
+	case after != "":
+		return ha.AfterTime(start), nil
+	default:
+		return ha.BeforeTime(end), nil
+	}
+}
+
+// step is one compiled action.
+type step func(ctx context.Context) error
+
+func (a Action) build(app *ha.App) (step, error) {
+	if a.Delay > 0 {
+		if a.Service != "" {
+			return nil, fmt.Errorf("%w: a delay is its own step", ErrInvalidConfig)
+		}
+		d := time.Duration(a.Delay)
+		return func(ctx context.Context) error {
+			// On the app's clock, as the automation's own timing is.
+			elapsed := make(chan struct{})
+			stop := app.AfterFunc(d, func() { close(elapsed) })
+			defer stop()
+			select {
+			case <-elapsed:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, nil
+	}
+
+	domain, service, ok := strings.Cut(a.Service, ".")
+	if !ok || domain == "" || service == "" {
+		return nil, fmt.Errorf("%w: service %q is not domain.service", ErrInvalidConfig, a.Service)
+	}
+	target := services.EntityID(strings.Join(a.Target, ", "))
+
+	// Through the app's services, like a call written in Go, so a read-only or
+	// dry-run app, the audit trail and the service timeout all apply.
+	return func(ctx context.Context) error {
+		_, err := app.Services().CallWithResult(ctx, domain, service, target, a.Data)
+		return err
+	}, nil
+}
+
+func parseMode(s string) (ha.Mode, error) {
+	switch strings.ToLower(s) {
+	case "single":
+		return ha.ModeSingle, nil
+	case "restart":
+		return ha.ModeRestart, nil
+	case "queued":
+		return ha.ModeQueued, nil
+	case "parallel":
+		return ha.ModeParallel, nil
+	}
+	return 0, fmt.Errorf("%w: unknown mode %q", ErrInvalidConfig, s)
+}
+
+// parseClock reads an HH:MM time of day. The range is checked when the
+// automation is built, as it is for one written in Go.
+func parseClock(s string) (ha.ClockTime, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(s, "%d:%d", &hour, &minute); err != nil {
+		return ha.ClockTime{}, fmt.Errorf("%w: time %q is not HH:MM", ErrInvalidConfig, s)
+	}
+	return ha.TimeOfDay(hour, minute), nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(name)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown weekday %q", ErrInvalidConfig, name)
+}
@@ -0,0 +1,184 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/config"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+const motionLight = `
+automations:
+  - name: hall light on motion
+    triggers:
+      - state: binary_sensor.hall_motion
+        to: "on"
+    conditions:
+      - state: input_boolean.guests
+        is: "off"
+      - not:
+          sun: up
+    mode: restart
+    actions:
+      - service: light.turn_on
+        target: light.hall
+        data: {brightness_pct: 40}
+`
+
+func TestLoadedAutomationCallsItsService(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	server.SetState("input_boolean.guests", "off")
+	server.SetState(ha.SunEntityID, "below_horizon")
+	app := hatest.NewApp(t, server)
+
+	path := filepath.Join(t.TempDir(), "automations.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(motionLight), 0o600))
+	require.NoError(t, config.Load(app, path))
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+
+	calls := server.WaitForCalls(1)
+	assert.Equal(t, "light", calls[0].Domain)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Equal(t, "light.hall", calls[0].EntityID)
+	assert.EqualValues(t, 40, calls[0].ServiceData["brightness_pct"])
+}
+
+func TestConditionsFromAFileStillGate(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	server.SetState("input_boolean.guests", "on")
+	server.SetState(ha.SunEntityID, "below_horizon")
+	app := hatest.NewApp(t, server)
+
+	automations, err := config.Parse(app, []byte(motionLight))
+	require.NoError(t, err)
+	require.NoError(t, app.RegisterAutomations(automations...))
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+}
+
+// A file's calls go through the app's services like any other, so a dry run
+// keeps them from Home Assistant.
+func TestDryRunAppSendsNothing(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	server.SetState("input_boolean.guests", "off")
+	server.SetState(ha.SunEntityID, "below_horizon")
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.DryRun = true })
+
+	automations, err := config.Parse(app, []byte(motionLight))
+	require.NoError(t, err)
+	require.NoError(t, app.RegisterAutomations(automations...))
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+
+	require.Eventually(t, func() bool { return len(app.DryRunCalls()) == 1 }, 2*time.Second, 10*time.Millisecond)
+	call := app.DryRunCalls()[0]
+	assert.Equal(t, "light", call.Domain)
+	assert.Equal(t, "turn_on", call.Service)
+	assert.Equal(t, "light.hall", call.Target)
+	assert.Empty(t, server.Calls())
+}
+
+func TestDelayWaitsOnTheAppClock(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	clock := hatest.NewClock(time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC))
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.Clock = clock })
+
+	automations, err := config.Parse(app, []byte(`
+automations:
+  - name: hall light off later
+    triggers: [{state: binary_sensor.hall_motion, to: "off"}]
+    actions:
+      - delay: 10m
+      - service: light.turn_off
+        target: [light.hall, light.landing]`))
+	require.NoError(t, err)
+	require.NoError(t, app.RegisterAutomations(automations...))
+	hatest.StartApp(t, app)
+	require.True(t, clock.WaitForSleepers(2))
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	server.ChangeState("binary_sensor.hall_motion", "off")
+	require.True(t, clock.WaitForSleepers(3), "the delay waits on the clock")
+	assert.Empty(t, server.Calls())
+
+	clock.Advance(10 * time.Minute)
+	calls := server.WaitForCalls(1)
+	assert.Equal(t, "turn_off", calls[0].Service)
+	assert.Equal(t, "light.hall, light.landing", calls[0].EntityID)
+}
+
+func TestParseRejectsBadConfig(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+
+	cases := map[string]string{
+		"unknown key": `
+automations:
+  - name: typo
+    trigers: []
+    actions: [{service: light.turn_on}]`,
+		"two kinds in one trigger": `
+automations:
+  - name: both
+    triggers: [{every: 5m, cron: "* * * * *"}]
+    actions: [{service: light.turn_on}]`,
+		"service without a domain": `
+automations:
+  - name: bare
+    triggers: [{every: 5m}]
+    actions: [{service: turn_on}]`,
+		"narrowing without a state": `
+automations:
+  - name: loose
+    triggers: [{every: 5m, to: "on"}]
+    actions: [{service: light.turn_on}]`,
+		"unknown mode": `
+automations:
+  - name: odd
+    triggers: [{every: 5m}]
+    mode: sometimes
+    actions: [{service: light.turn_on}]`,
+		"bad duration": `
+automations:
+  - name: odd
+    triggers: [{every: soon}]
+    actions: [{service: light.turn_on}]`,
+	}
+	for name, doc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := config.Parse(app, []byte(doc))
+			assert.ErrorIs(t, err, config.ErrInvalidConfig)
+		})
+	}
+}
+
+// What the builder already checks is left to it, so a file fails exactly as the
+// same automation written in Go would.
+func TestParseDefersToTheBuilder(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+
+	_, err := config.Parse(app, []byte(`
+automations:
+  - name: late
+    triggers: [{at: "25:00"}]
+    actions: [{service: light.turn_off}]`))
+	assert.ErrorIs(t, err, ha.ErrInvalidAutomation)
+	assert.ErrorIs(t, err, ha.ErrInvalidTimeOfDay)
+}
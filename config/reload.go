@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	ha "github.com/Xevion/go-ha"
+)
+
+// Reloader keeps an app's automations in step with a config file edited while
+// the app runs. A reload touches only the automations whose definitions
+// changed, so the rest keep their throttle windows, their waits and any run
+// under way, as though nothing had happened.
+type Reloader struct {
+	app  *ha.App
+	path string
+
+	mu sync.Mutex
+
+	// loaded holds what the file last described, by automation name, and
+	// what was built and registered for it.
+	loaded map[string]loaded
+}
+
+type loaded struct {
+	spec       Automation
+	automation ha.Automation
+}
+
+// NewReloader loads the file's automations onto the app, as Load does, and
+// returns what reloads them. A reload matches automations by name, so names
+// must be unique within the file.
+func NewReloader(app *ha.App, path string) (*Reloader, error) {
+	r := &Reloader{app: app, path: path, loaded: map[string]loaded{}}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the file again and brings the app in line with it. An
+// automation whose definition is unchanged is left registered as it is, one
+// that changed is replaced, one no longer in the file is unregistered and one
+// new to it is registered. A file that does not load leaves every automation
+// as it was. One that cannot be registered is reported, and tried again on
+// the next reload.
+func (r *Reloader) Reload() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	file, err := decode(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.path, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Everything is built before anything is unregistered, so a mistake
+	// anywhere in the file costs nothing already running.
+	next := make(map[string]loaded, len(file.Automations))
+	kept := map[string]bool{}
+	var added []loaded
+	for i, spec := range file.Automations {
+		if _, dup := next[spec.Name]; dup {
+			return fmt.Errorf("%s: %s: %w: the name is used twice, and a reload matches automations by name",
+				r.path, spec.label(i), ErrInvalidConfig)
+		}
+		if prev, ok := r.loaded[spec.Name]; ok && reflect.DeepEqual(prev.spec, spec) {
+			next[spec.Name] = prev
+			kept[spec.Name] = true
+			continue
+		}
+		a, err := spec.build(r.app)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", r.path, spec.label(i), err)
+		}
+		next[spec.Name] = loaded{spec: spec, automation: a}
+		added = append(added, next[spec.Name])
+	}
+
+	var retired []ha.Automation
+	for name, prev := range r.loaded {
+		if !kept[name] {
+			retired = append(retired, prev.automation)
+		}
+	}
+
+	var errs []error
+	if err := r.app.UnregisterAutomations(retired...); err != nil {
+		errs = append(errs, err)
+	}
+	for _, l := range added {
+		if err := r.app.RegisterAutomations(l.automation); err != nil {
+			// Whatever part of it did register goes again, and forgetting it
+			// has the next reload try it afresh.
+			_ = r.app.UnregisterAutomations(l.automation)
+			delete(next, l.spec.Name)
+			errs = append(errs, err)
+		}
+	}
+	r.loaded = next
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", r.path, err)
+	}
+	return nil
+}
+
+// ReloadOnSignal reloads the file each time the process receives SIGHUP, as a
+// daemon conventionally does, until ctx is done. A reload that fails is
+// logged through the app's logger and changes nothing it could not, so a
+// mistake saved to the file can be fixed and signalled again.
+func (r *Reloader) ReloadOnSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := r.Reload(); err != nil {
+				r.app.Logger().Error("Reloading automations failed", "path", r.path, "error", err)
+				continue
+			}
+			r.app.Logger().Info("Reloaded automations", "path", r.path)
+		}
+	}
+}
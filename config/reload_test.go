@@ -0,0 +1,93 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Xevion/go-ha/config"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+const throttledHall = `
+  - name: hall light
+    triggers:
+      - state: binary_sensor.hall_motion
+        to: "on"
+    throttle: 1h
+    actions:
+      - service: light.turn_on
+        target: light.hall
+`
+
+func porch(target string) string {
+	return `
+  - name: porch light
+    triggers:
+      - state: binary_sensor.porch_motion
+        to: "on"
+    actions:
+      - service: light.turn_on
+        target: ` + target + `
+`
+}
+
+func TestReloadReplacesOnlyWhatChanged(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	server.SetState("binary_sensor.porch_motion", "off")
+	app := hatest.NewApp(t, server)
+
+	path := filepath.Join(t.TempDir(), "automations.yaml")
+	write := func(doc string) { require.NoError(t, os.WriteFile(path, []byte(doc), 0o600)) }
+	motion := func(id string) {
+		server.ChangeState(id, "on")
+		server.ChangeState(id, "off")
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	write("automations:" + throttledHall + porch("light.porch"))
+	r, err := config.NewReloader(app, path)
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	motion("binary_sensor.hall_motion")
+	server.AssertServiceCalled("light", "turn_on", "light.hall")
+	server.ResetCalls()
+
+	write("automations:" + throttledHall + porch("light.porch_wall"))
+	require.NoError(t, r.Reload())
+
+	motion("binary_sensor.porch_motion")
+	server.AssertServiceCalled("light", "turn_on", "light.porch_wall")
+	assert.True(t, server.AssertServiceNotCalled("light", "turn_on", "light.porch"), "the old porch automation is gone")
+
+	motion("binary_sensor.hall_motion")
+	assert.True(t, server.AssertServiceNotCalled("light", "turn_on", "light.hall"), "the unchanged hall automation kept its throttle")
+	server.ResetCalls()
+
+	// A file that does not load changes nothing.
+	write("automations:" + throttledHall + "  - name: broken\n")
+	require.ErrorIs(t, r.Reload(), config.ErrInvalidConfig)
+	motion("binary_sensor.porch_motion")
+	server.AssertServiceCalled("light", "turn_on", "light.porch_wall")
+	server.ResetCalls()
+
+	write("automations:" + throttledHall)
+	require.NoError(t, r.Reload())
+	motion("binary_sensor.porch_motion")
+	assert.Empty(t, server.Calls(), "the porch automation was removed from the file")
+}
+
+func TestReloaderRefusesDuplicateNames(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+	path := filepath.Join(t.TempDir(), "automations.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("automations:"+throttledHall+throttledHall), 0o600))
+
+	_, err := config.NewReloader(app, path)
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+}
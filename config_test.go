@@ -0,0 +1,53 @@
+package ha_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestConfigIsReadFromHomeAssistant(t *testing.T) {
+	server := hatest.New(t)
+	server.SetConfig("latitude", 44.9778)
+	server.SetConfig("longitude", -93.265)
+	server.SetConfig("time_zone", "America/Chicago")
+	server.SetConfig("unit_system", map[string]any{"temperature": "°F", "length": "mi"})
+	app := newApp(t, server)
+
+	config, err := app.Config()
+	require.NoError(t, err)
+	assert.Equal(t, 44.9778, config.Latitude)
+	assert.Equal(t, -93.265, config.Longitude)
+	assert.EqualValues(t, 11, config.Elevation)
+	assert.Equal(t, "America/Chicago", config.TimeZone)
+	assert.Equal(t, types.Fahrenheit, config.UnitSystem.Temperature)
+	assert.Equal(t, "mi", config.UnitSystem.Length)
+	assert.Equal(t, "2026.7.0", config.Version)
+}
+
+// With no zone.home to read, the deeper twilights are computed from where the
+// configuration puts the home.
+func TestUnpublishedSunTimesUseTheConfiguredLocation(t *testing.T) {
+	server := hatest.New(t)
+	clock := hatest.NewClock(time.Date(2026, 6, 21, 12, 0, 0, 0, time.UTC))
+	app := newAppWithClock(t, server, clock)
+
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("stargazing").
+			On(ha.NauticalDusk()).
+			Do(func(context.Context, ha.Run) error { return nil }).
+			MustBuild(),
+	))
+
+	schedules := app.Inspect().Schedules
+	require.Len(t, schedules, 1)
+	// London's, as hatest configures the home.
+	assert.WithinDuration(t, time.Date(2026, 6, 21, 22, 25, 0, 0, time.UTC), schedules[0].NextRun, 5*time.Minute)
+}
@@ -0,0 +1,62 @@
+package ha_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+// Each fixture under testdata/protocol is the same conversation recorded from a
+// different Home Assistant version: authenticate, subscribe to state_changed,
+// read the configuration, turn a light on and see it change, then call a
+// service that does not exist.
+// The app has to hold the same conversation with every one of them.
+func TestProtocolConformance(t *testing.T) {
+	fixtures, err := hatest.LoadFixtures("testdata/protocol/*.json")
+	require.NoError(t, err)
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			replay := hatest.NewReplay(t, f)
+			app, err := ha.NewApp(types.NewAppRequest{URL: replay.URL(), HAAuthToken: hatest.Token})
+			require.NoError(t, err, "authenticating against %s", f.HAVersion)
+			t.Cleanup(func() { _ = app.Close() })
+
+			require.Eventually(t, func() bool {
+				es, ok := app.CachedState("light.kitchen")
+				return ok && es.State == "off"
+			}, 2*time.Second, 10*time.Millisecond, "the REST snapshot was not loaded")
+
+			config, err := app.Config()
+			require.NoError(t, err)
+			assert.Equal(t, "Europe/Amsterdam", config.TimeZone)
+			assert.Equal(t, types.Celsius, config.UnitSystem.Temperature)
+			assert.Equal(t, f.HAVersion, config.Version)
+
+			waiting := app.Services().WithResult()
+			require.NoError(t, waiting.Light.TurnOn("light.kitchen"))
+
+			require.Eventually(t, func() bool {
+				es, ok := app.CachedState("light.kitchen")
+				return ok && es.State == "on"
+			}, 2*time.Second, 10*time.Millisecond, "state_changed was not applied")
+			es, _ := app.CachedState("light.kitchen")
+			assert.EqualValues(t, 255, es.Attributes["brightness"])
+			assert.Equal(t, 345678000, es.LastChanged.Nanosecond())
+
+			_, err = waiting.CallWithResult(t.Context(), "light", "explode", "light.kitchen", nil)
+			assert.ErrorIs(t, err, ha.ErrCallFailed)
+			assert.ErrorContains(t, err, "light.explode not found")
+
+			require.NoError(t, replay.Wait(2*time.Second))
+			assert.Empty(t, replay.Unexpected())
+			assert.Zero(t, app.ConnectionStats().Undecodable)
+		})
+	}
+}
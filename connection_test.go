@@ -0,0 +1,34 @@
+package ha_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+// With the read deadline too far off to notice, an unanswered ping is what
+// drops a connection gone dead behind a NAT.
+func TestPingTimeoutDropsAConnectionThatStopsAnswering(t *testing.T) {
+	server := hatest.New(t)
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) {
+		r.Connection = types.ConnectionOptions{
+			PingInterval: 50 * time.Millisecond,
+			PingTimeout:  100 * time.Millisecond,
+			ReadTimeout:  time.Hour,
+		}
+	})
+	hatest.StartApp(t, app)
+	require.Equal(t, 1, server.Connections())
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, server.Connections(), "answered pings keep the connection")
+
+	server.WithholdPongs(true)
+	require.Eventually(t, func() bool { return server.Connections() > 1 }, 5*time.Second, 10*time.Millisecond,
+		"the unanswered ping should have dropped the connection")
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// adminShutdownTimeout bounds how long the admin server gives a request in
+// flight when the app shuts down.
+const adminShutdownTimeout = 5 * time.Second
+
+// WithAdminServer serves AdminHandler on addr, such as ":9090", for as long as
+// the app runs. The address is bound here, so a port already taken is
+// reported now rather than lost on a goroutine.
+//
+// The endpoints are unauthenticated and can run automations, so bind them to
+// an address only trusted callers can reach.
+func (app *App) WithAdminServer(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("admin server: %w", err)
+	}
+	srv := &http.Server{Handler: app.AdminHandler(), ReadHeaderTimeout: 10 * time.Second}
+
+	app.registryMu.Lock()
+	if app.admin != nil {
+		app.registryMu.Unlock()
+		_ = listener.Close()
+		return fmt.Errorf("%w: admin server is already serving on %s", ErrInvalidArgs, app.admin.addr)
+	}
+	app.admin = &adminServer{srv: srv, addr: listener.Addr().String()}
+	app.registryMu.Unlock()
+
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			app.log.Error("Admin server stopped", "addr", addr, "error", err)
+		}
+	}()
+	return nil
+}
+
+// adminServer is the server WithAdminServer started.
+type adminServer struct {
+	srv  *http.Server
+	addr string
+}
+
+// AdminAddr is the address the admin server is listening on, with the port
+// filled in when WithAdminServer was given ":0". It is empty without one.
+func (app *App) AdminAddr() string {
+	app.registryMu.RLock()
+	defer app.registryMu.RUnlock()
+	if app.admin == nil {
+		return ""
+	}
+	return app.admin.addr
+}
+
+// stopAdmin shuts the admin server down as the app does.
+func (app *App) stopAdmin() {
+	app.registryMu.RLock()
+	admin := app.admin
+	app.registryMu.RUnlock()
+	if admin == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+	defer cancel()
+	if err := admin.srv.Shutdown(ctx); err != nil {
+		app.log.Warn("Admin server did not shut down cleanly", "error", err)
+	}
+}
+
+// AdminHandler serves endpoints for looking into a running deployment, for
+// mounting on a server of your own. WithAdminServer serves it on its own.
+//
+//	GET  /healthz                   200 while the app is alive
+//	GET  /readyz                    200 once it is running with state loaded
+//	GET  /automations               what Inspect reports, as JSON
+//	POST /automations/{name}/run    RunAutomation, with the name path-escaped
+func (app *App) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", app.serveHealth)
+	mux.HandleFunc("GET /readyz", app.serveReady)
+	mux.HandleFunc("GET /automations", app.serveAutomations)
+	mux.HandleFunc("POST /automations/{name}/run", app.serveRun)
+	return mux
+}
+
+// serveHealth answers liveness: the app is alive until it is closed or its
+// connection is abandoned.
+func (app *App) serveHealth(w http.ResponseWriter, _ *http.Request) {
+	select {
+	case <-app.ctx.Done():
+		http.Error(w, "stopped", http.StatusServiceUnavailable)
+	case <-app.client.Done():
+		http.Error(w, "connection abandoned", http.StatusServiceUnavailable)
+	default:
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+// serveReady answers readiness: started, and holding a current snapshot of
+// entity state, which it loses while the connection is down.
+func (app *App) serveReady(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case !app.started.Load():
+		http.Error(w, "not started", http.StatusServiceUnavailable)
+	case !app.state.cache.ready():
+		http.Error(w, "state not loaded", http.StatusServiceUnavailable)
+	default:
+		app.serveHealth(w, r)
+	}
+}
+
+func (app *App) serveAutomations(w http.ResponseWriter, _ *http.Request) {
+	type automation struct {
+		Name            string     `json:"name"`
+		Mode            string     `json:"mode"`
+		Running         bool       `json:"running"`
+		LastRun         *time.Time `json:"last_run,omitempty"`
+		LastError       string     `json:"last_error,omitempty"`
+		LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+		ConditionErrors uint64     `json:"condition_errors"`
+	}
+	type schedule struct {
+		Automation string     `json:"automation"`
+		Trigger    string     `json:"trigger"`
+		NextRun    *time.Time `json:"next_run"`
+	}
+	type listener struct {
+		Automation string   `json:"automation"`
+		Trigger    string   `json:"trigger"`
+		EventTypes []string `json:"event_types"`
+		Entities   []string `json:"entities,omitempty"`
+	}
+	// A zero time is "never", which reads better absent than as year one.
+	at := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+
+	in := app.Inspect()
+	out := struct {
+		Automations []automation `json:"automations"`
+		Schedules   []schedule   `json:"schedules"`
+		Listeners   []listener   `json:"listeners"`
+	}{[]automation{}, []schedule{}, []listener{}}
+
+	for _, a := range in.Automations {
+		view := automation{
+			Name:            a.Name,
+			Mode:            a.Mode.String(),
+			Running:         a.Running,
+			LastRun:         at(a.LastRun),
+			LastErrorAt:     at(a.LastErrorAt),
+			ConditionErrors: a.ConditionErrors,
+		}
+		if a.LastError != nil {
+			view.LastError = a.LastError.Error()
+		}
+		out.Automations = append(out.Automations, view)
+	}
+	for _, s := range in.Schedules {
+		out.Schedules = append(out.Schedules, schedule{s.Automation, s.Trigger, at(s.NextRun)})
+	}
+	for _, l := range in.Listeners {
+		out.Listeners = append(out.Listeners, listener{l.Automation, l.Trigger, l.EventTypes, l.Entities})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (app *App) serveRun(w http.ResponseWriter, r *http.Request) {
+	err := app.RunAutomation(r.PathValue("name"))
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusAccepted)
+	case errors.Is(err, ErrUnknownAutomation):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrAutomationBusy):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	}
+}
@@ -3,21 +3,26 @@ package core
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/Xevion/go-ha/internal"
 	"github.com/Xevion/go-ha/internal/connect"
+	"github.com/Xevion/go-ha/services"
 	"github.com/Xevion/go-ha/types"
 )
 
 var (
 	// ErrInvalidArgs reports a malformed NewAppRequest.
-	ErrInvalidArgs = errors.New("invalid arguments provided")
+	ErrInvalidArgs = types.ErrInvalidArgs
 
 	// ErrConnectionAbandoned reports that the client gave up re-establishing
 	// the connection, so Start returned without being asked to.
@@ -37,9 +42,19 @@ type App struct {
 	httpClient *internal.HttpClient
 	clock      Clock
 
+	// log receives everything the app logs, as NewAppRequest's Logger asks.
+	log *slog.Logger
+
+	// token is the access token the app connected with, kept for VerifyAuth
+	// to read its expiry from.
+	token string
+
 	service *Service
 	state   *state
 
+	// undecodable takes every message and event that could not be decoded.
+	undecodable *deadLetters
+
 	schedules *scheduler
 	intervals *scheduler
 
@@ -51,16 +66,86 @@ type App struct {
 	// automations maps an event type to the automations waiting on it.
 	automations map[string][]binding
 
+	// subscribed holds the event types asked of Home Assistant, besides
+	// state_changed, so each is subscribed to once whoever wants it.
+	subscribed map[string]struct{}
+
+	// waiters maps an event type to the calls blocked in WaitForEvent or
+	// WaitUntil on it.
+	waiters map[string]map[*waiter]struct{}
+
 	// runners holds every registered automation's runner, deduplicated because
 	// an automation with several triggers registers once per trigger. Shutdown
-	// waits on these so a run in flight finishes its service calls.
-	runners map[*runner]struct{}
+	// waits on these so a run in flight finishes its service calls. Each maps
+	// to its automation's name, for reporting.
+	runners map[*runner]string
+
+	// named maps each automation's name to it, for RunAutomation.
+	named map[string]Automation
+
+	// config is Home Assistant's configuration, fetched once connected.
+	config *homeConfig
+
+	// tasks maps the name of each automation registered with
+	// RegisterTaskHandlers to it, for ExecuteTask.
+	tasks map[string]Automation
 
 	// rescheduled wakes the schedule loop when a dynamic trigger's time moves.
 	// A refreshed sun time can be earlier than the one the loop is sleeping
 	// on, and it would otherwise wake too late to fire it.
 	rescheduled chan struct{}
 
+	// startupStagger spaces out the startup runs when Start begins.
+	startupStagger time.Duration
+
+	// serviceTimeout bounds how long a call waits for its answer.
+	serviceTimeout time.Duration
+
+	// readOnly refuses service calls and state writes, as NewAppRequest's
+	// ReadOnly asks.
+	readOnly bool
+
+	// dryRun skips service calls and state writes as though they succeeded,
+	// as NewAppRequest's DryRun asks, and keeps what it skipped.
+	dryRun *dryRunSender
+
+	// publisher mirrors the schedule onto a calendar, when PublishSchedules
+	// has asked for it. Guarded by registryMu.
+	publisher *schedulePublisher
+
+	// heartbeat is the sensor WithHeartbeat keeps alive, if any. Guarded by
+	// registryMu.
+	heartbeat *heartbeat
+
+	// admin is the server WithAdminServer started, if any. Guarded by
+	// registryMu.
+	admin *adminServer
+
+	// strictEntities refuses automations naming entities Home Assistant does
+	// not have, as NewAppRequest.CheckEntities asks.
+	strictEntities bool
+
+	// stats counts the events received, for EventStats.
+	stats eventCounter
+
+	// registry is Home Assistant's area, device and entity registries, as
+	// Registry returns them.
+	registry *Registry
+
+	// lifecycle holds the OnStart, OnReady and OnStop hooks.
+	lifecycle lifecycle
+
+	// callbackErrors holds the OnCallbackError hooks.
+	callbackErrors callbackErrors
+
+	// closing makes shutdown happen once, however many callers ask for it
+	// and whatever their deadlines.
+	closing struct {
+		once sync.Once
+		done chan struct{}
+		err  error
+	}
+
 	// loops tracks the schedule and interval goroutines. They admit runs of
 	// their own, so shutdown has to join them before waiting on any runner: a
 	// WaitGroup may not be raised from zero while a Wait on it is in flight.
@@ -77,14 +162,21 @@ type App struct {
 }
 
 // NewApp establishes the WebSocket connection and returns an object you can use to register schedules and listeners.
+//
+// Running as a Home Assistant add-on, URL and HAAuthToken may both be left
+// empty: they are taken from the add-on's environment.
 func NewApp(request types.NewAppRequest) (*App, error) {
+	request = supervisorDefaults(request)
 	if request.URL == "" || request.HAAuthToken == "" {
 		return nil, fmt.Errorf("%w: URL and HAAuthToken are both required", ErrInvalidArgs)
 	}
+	if request.ReadOnly && request.DryRun {
+		return nil, fmt.Errorf("%w: ReadOnly refuses the calls DryRun reports as made; set one of them", ErrInvalidArgs)
+	}
 
-	baseURL, err := url.Parse(request.URL)
+	baseURL, err := parseURL(request.URL)
 	if err != nil {
-		return nil, fmt.Errorf("parsing URL %q: %w", request.URL, err)
+		return nil, err
 	}
 
 	ctx, ctxCancel := context.WithCancel(context.Background())
@@ -95,47 +187,148 @@ func NewApp(request types.NewAppRequest) (*App, error) {
 	if request.Clock != nil {
 		clock = request.Clock
 	}
+	if request.Timezone != "" {
+		clock, err = inZone(clock, request.Timezone, httpClient)
+		if err != nil {
+			ctxCancel()
+			return nil, err
+		}
+	}
+
+	logger := request.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
 
-	state := newState(httpClient)
+	state := newState(httpClient, clock, logger)
+	undecodable := &deadLetters{hook: request.OnUndecodable, log: logger}
+	// Filled in once the client it is read through exists, and first read
+	// once connected.
+	config := &homeConfig{}
+	var stream *entityStream
+	if request.StreamEntities {
+		stream = &entityStream{state: state}
+	}
 
 	client, err := connect.NewClient(baseURL, request.HAAuthToken, connect.Options{
 		QueueSize:    request.Connection.QueueSize,
 		Workers:      request.Connection.Workers,
 		PingInterval: request.Connection.PingInterval,
+		PingTimeout:  request.Connection.PingTimeout,
+		WriteTimeout: request.Connection.WriteTimeout,
+		ReadTimeout:  request.Connection.ReadTimeout,
+		Logger:       logger,
 		// Every connection starts with a fresh snapshot. Anything that changed
 		// while the stream was down was never delivered.
+		//
+		// A stream of entities brings its own snapshot, as its first message.
 		OnConnected: func() {
+			// Read ahead of the snapshot, so the home's location is known
+			// by the time the app is ready. Config tries again for a caller
+			// who needs it after a failure here.
+			if _, err := config.get(); err != nil {
+				logger.Warn("Failed to read Home Assistant's config", "error", err)
+			}
+			if stream != nil {
+				return
+			}
 			if err := state.seed(); err != nil {
-				slog.Error("Failed to load entity states", "error", err)
+				logger.Error("Failed to load entity states", "error", err)
 			}
 		},
 		// Applied in wire order on the reader, so a condition a worker evaluates
 		// sees every event up to and including the one that triggered it. Done
 		// on the worker instead, it would race the update of an entity changed
 		// just before the trigger.
+		//
+		// Every event passes through here exactly once, so this is also where
+		// one that cannot be decoded is caught and counted. The dispatchers
+		// pass it over rather than report it again.
 		OnEvent: func(m connect.Message) {
-			state.applyEvent(m.Raw)
+			if err := state.applyEvent(m.Raw); err != nil {
+				undecodable.record(m.Raw, err)
+			}
 		},
+		OnUndecodable: undecodable.record,
 	})
 	if err != nil {
 		ctxCancel()
 		return nil, err
 	}
 
+	timeout := request.ServiceTimeout
+	if timeout <= 0 {
+		timeout = defaultServiceTimeout
+	}
+	var sender services.Sender = client
+	var waiting services.ResultSender = resultSender{client: client, ctx: ctx, timeout: timeout}
+	if request.Audit != (types.AuditOptions{}) {
+		sender = auditSender{next: client, state: state, clock: clock, opts: request.Audit, log: logger}
+		waiting = auditSender{next: waiting, state: state, clock: clock, opts: request.Audit, log: logger}
+	}
+	if request.ReadOnly {
+		// In place of the senders rather than in front of them, so nothing
+		// beneath, the audit trail included, can write either.
+		sender, waiting = readOnlySender{log: logger}, readOnlySender{log: logger}
+	}
+	// Past any read-only or dry-run sender: reading the configuration writes
+	// nothing.
+	config.sender = resultSender{client: client, ctx: ctx, timeout: timeout}
+
+	var dryRun *dryRunSender
+	if request.DryRun {
+		// In place of the senders too, for the same reason.
+		dryRun = &dryRunSender{log: logger, clock: clock}
+		sender, waiting = dryRun, dryRun
+	}
+
+	// A dry run's calls are never made, so they are not kept for an app
+	// that might make them.
+	deferredFile := request.DeferredCallsFile
+	if request.DryRun {
+		deferredFile = ""
+	}
+	deferred, err := newDeferredCalls(sender, clock, logger, deferredFile, request.ReadOnly)
+	if err != nil {
+		ctxCancel()
+		return nil, err
+	}
+
 	app := &App{
 		client:      client,
 		ctx:         ctx,
 		ctxCancel:   ctxCancel,
 		httpClient:  httpClient,
+		token:       request.HAAuthToken,
 		clock:       clock,
-		service:     newService(client),
+		log:         logger,
+		service:     newService(sender, waiting, &climateLimits{state: state, config: config}, state, logger, ctx.Done()),
 		state:       state,
+		undecodable: undecodable,
 		schedules:   newScheduler(clock),
 		intervals:   newScheduler(clock),
 		automations: map[string][]binding{},
-		runners:     map[*runner]struct{}{},
+		subscribed:  map[string]struct{}{},
+		waiters:     map[string]map[*waiter]struct{}{},
+		runners:     map[*runner]string{},
 		rescheduled: make(chan struct{}, 1),
+
+		startupStagger: request.StartupStagger,
+		serviceTimeout: timeout,
+		readOnly:       request.ReadOnly,
+		dryRun:         dryRun,
+		strictEntities: request.CheckEntities,
+		config:         config,
 	}
+	// Carried by every run's context, so a callback can wait on the app
+	// without being handed it.
+	app.ctx = context.WithValue(ctx, appContextKey{}, app)
+	app.service.Template = &Template{app: app, timeout: timeout}
+	app.service.overrides = &overrides{app: app}
+	app.service.deferred = deferred
+	app.registry = &Registry{app: app}
+	app.schedules.log, app.intervals.log = logger, logger
+	undecodable.report = app.reportCallbackError
 
 	// Subscribing before connecting, so the replay that runs on every
 	// connection establishes it before the snapshot is taken. Taking the
@@ -147,6 +340,12 @@ func NewApp(request types.NewAppRequest) (*App, error) {
 		ctxCancel()
 		return nil, err
 	}
+	if stream != nil {
+		if err := client.Subscribe(stream.subscription(undecodable), nil); err != nil {
+			ctxCancel()
+			return nil, err
+		}
+	}
 
 	if err := client.Connect(ctx); err != nil {
 		ctxCancel()
@@ -156,6 +355,33 @@ func NewApp(request types.NewAppRequest) (*App, error) {
 	return app, nil
 }
 
+// parseURL reads the instance's address. It has to name a scheme and a host:
+// "homeassistant.local:8123" parses, as a URL whose scheme is the hostname, and
+// would only fail later and obscurely, on the first request.
+//
+// A websocket address is accepted for the REST side too, since both are served
+// from the same place, and a bare host and port is the shape of the IpAddress
+// and Port fields the URL replaced, so the error says how to write it now.
+func parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%w: URL %q needs a scheme and a host, such as "+
+			"\"http://192.168.1.5:8123\"; where IpAddress, Port and Secure were "+
+			"used, write them as one URL, with https for Secure", ErrInvalidArgs, raw)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("%w: URL %q has scheme %q, not http or https", ErrInvalidArgs, raw, u.Scheme)
+	}
+	return u, nil
+}
+
 // refreshSunSchedules re-derives sun-backed schedules when Home Assistant
 // republishes their times, which it does as each solar event passes.
 func (app *App) refreshSunSchedules(raw []byte) {
@@ -190,6 +416,14 @@ func (app *App) onEvent(msg connect.Message) {
 	app.dispatchEvent(msg.Raw)
 }
 
+// onAnyEvent is onEvent for the subscription to every event.
+func (app *App) onAnyEvent(msg connect.Message) {
+	if !app.started.Load() {
+		return
+	}
+	app.dispatchToAll(msg.Raw)
+}
+
 // onStateChanged refreshes sun schedules and, once the app has started, runs
 // the automations watching the entity. The cache is already current: the event
 // was applied on the reader, in wire order, before it reached this worker.
@@ -203,9 +437,64 @@ func (app *App) onStateChanged(msg connect.Message) {
 	}
 }
 
-// Close performs a clean shutdown: it stops the background goroutines, closes
-// the connection, and waits for both to finish.
+// Close performs a clean shutdown: it runs the OnStop hooks, stops the
+// background goroutines, closes the connection, and waits for both to finish,
+// including every automation run still in flight. It waits as long as that
+// takes; Shutdown bounds the wait.
 func (app *App) Close() error {
+	return app.Shutdown(context.Background())
+}
+
+// Shutdown is Close with a deadline. Runs in flight have their contexts
+// cancelled and are waited for, as are For durations and trailing throttles
+// being stopped and the schedule loops winding down. The OnStop hooks are
+// bounded by ctx rather than by Close's ten seconds.
+//
+// If ctx ends first, Shutdown returns its error, naming the automations still
+// running, and the shutdown carries on in the background. Calling Close or
+// Shutdown again waits for that same shutdown rather than starting another.
+func (app *App) Shutdown(ctx context.Context) error {
+	app.closing.once.Do(func() {
+		app.closing.done = make(chan struct{})
+		go func() {
+			defer close(app.closing.done)
+			app.closing.err = app.shutdown(ctx)
+		}()
+	})
+
+	select {
+	case <-app.closing.done:
+		return app.closing.err
+	case <-ctx.Done():
+		if running := app.runningAutomations(); len(running) > 0 {
+			return fmt.Errorf("shutting down: %w, still running: %s", ctx.Err(), strings.Join(running, ", "))
+		}
+		return fmt.Errorf("shutting down: %w", ctx.Err())
+	}
+}
+
+// runningAutomations names the automations with a run in flight or queued.
+func (app *App) runningAutomations() []string {
+	app.registryMu.RLock()
+	defer app.registryMu.RUnlock()
+
+	var names []string
+	for r, name := range app.runners {
+		if r.running() {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// shutdown does the work of Close, once.
+func (app *App) shutdown(ctx context.Context) error {
+	// Before cancelling, while the HTTP client can still reach Home Assistant.
+	app.runStopHooks(ctx)
+	app.stopHeartbeat()
+	app.stopAdmin()
+
 	if app.ctxCancel != nil {
 		app.ctxCancel()
 	}
@@ -227,15 +516,30 @@ func (app *App) Close() error {
 	for r := range app.runners {
 		runners = append(runners, r)
 	}
-	// Same reasoning as the listener timers: a trigger waiting out a For
-	// duration would otherwise fire into a closed connection.
+	var pending []*pendingRuns
 	for _, bindings := range app.automations {
 		for _, b := range bindings {
-			b.pending.stop()
+			pending = append(pending, b.pending)
 		}
 	}
+	if app.publisher != nil {
+		pending = append(pending, app.publisher.pending)
+	}
 	app.registryMu.RUnlock()
 
+	// Same reasoning as the listener timers: a trigger waiting out a For
+	// duration, or a trailing throttle, would otherwise fire into a closed
+	// connection. Stopped outside the lock, because stopping waits for a
+	// callback already under way, and that callback may need the registry.
+	for _, p := range pending {
+		p.stop()
+	}
+	for _, r := range runners {
+		r.stop()
+	}
+
+	app.lifecycle.running.Wait()
+
 	// The schedule and interval loops admit runs of their own, so they have to
 	// be quiescent before any runner is waited on. Otherwise a loop that has
 	// already passed its cancellation check admits a run behind the pass, and
@@ -256,7 +560,9 @@ func (app *App) Close() error {
 // reconnection. It returns the reason it stopped: nil for a clean shutdown,
 // ErrConnectionAbandoned when the connection could not be recovered.
 //
-// Calling it twice, or after Close, is a no-op returning ErrNotRunning.
+// The OnStart hooks run first, and an error from one is returned before
+// anything else starts. Calling it twice, or after Close, is a no-op returning
+// ErrNotRunning.
 func (app *App) Start() error {
 	if !app.starting.CompareAndSwap(false, true) {
 		return ErrNotRunning
@@ -264,38 +570,49 @@ func (app *App) Start() error {
 	if app.ctx.Err() != nil {
 		return ErrNotRunning
 	}
+	if err := app.runStartHooks(); err != nil {
+		return err
+	}
 
 	app.registryMu.RLock()
 	eventTypes := len(app.automations)
 	app.registryMu.RUnlock()
 
-	slog.Info("Starting",
+	app.log.Info("Starting",
 		"version", internal.Version,
 		"schedules", app.schedules.len(),
 		"intervals", app.intervals.len(),
 		"event_types", eventTypes,
 	)
 
+	// Timed from now, not from registration, so the first run is not already
+	// overdue and the rest are spaced from the moment they can actually go.
+	if app.startupStagger > 0 {
+		app.schedules.stagger(app.clock.Now(), app.startupStagger)
+	}
+
 	// Separate channels: a wake meant for the schedules loop would otherwise be
 	// consumed by the intervals loop, which has no dynamic triggers to re-read,
 	// and the schedule that actually moved would sleep through it.
-	app.loops.Add(2)
+	app.loops.Add(3)
 	go func() { defer app.loops.Done(); app.schedules.run(app.ctx, app.rescheduled, "schedules") }()
 	go func() { defer app.loops.Done(); app.intervals.run(app.ctx, nil, "intervals") }()
+	go func() { defer app.loops.Done(); app.service.deferred.run(app.ctx) }()
 
 	// Opening the gate last, so nothing fires before the loops are up.
 	app.started.Store(true)
+	app.awaitReady()
 
 	select {
 	case <-app.ctx.Done():
-		slog.Info("Context cancelled, stopping")
+		app.log.Info("Context cancelled, stopping")
 		return nil
 	case <-app.client.Done():
 		// The client gave up reconnecting, so blocking on our own context
 		// would leave the app alive but permanently deaf. Cancelling also
 		// stops the schedule and interval loops, which would otherwise keep
 		// firing callbacks whose service calls have nowhere to go.
-		slog.Error("Connection abandoned, stopping")
+		app.log.Error("Connection abandoned, stopping")
 		app.ctxCancel()
 		return ErrConnectionAbandoned
 	}
@@ -308,3 +625,88 @@ func (app *App) Services() *Service {
 func (app *App) State() StateReader {
 	return app.state
 }
+
+// CachedState reads an entity from the local cache alone, never the network,
+// so it answers at once and keeps answering while Home Assistant is
+// unreachable. ok is false for an entity the cache does not hold, including
+// every entity before the first snapshot has landed.
+func (app *App) CachedState(entityId string) (es EntityState, ok bool) {
+	return app.state.cache.get(entityId)
+}
+
+// FreshState reads an entity straight from Home Assistant, bypassing the
+// cache, for the rare caller that cannot accept the cache lagging the event
+// stream by the time an event takes to arrive.
+func (app *App) FreshState(entityId string) (EntityState, error) {
+	resp, err := app.httpClient.GetState(entityId)
+	if err != nil {
+		return EntityState{}, err
+	}
+	var es EntityState
+	if err := json.Unmarshal(resp, &es); err != nil {
+		return EntityState{}, fmt.Errorf("decoding %s: %w", entityId, err)
+	}
+	return es, nil
+}
+
+// SetState publishes a state for an entity, creating it if Home Assistant does
+// not know it. It is for virtual sensors the app maintains itself; an entity
+// owned by an integration is overwritten only until that integration next
+// reports.
+func (app *App) SetState(entityId, value string, attributes map[string]any) error {
+	if app.readOnly {
+		app.log.Info("Read-only, not setting state", "entity_id", entityId, "state", value)
+		return fmt.Errorf("%w: refused setting %s", ErrReadOnly, entityId)
+	}
+	if app.dryRun != nil {
+		app.dryRun.setState(entityId, value, attributes)
+		return nil
+	}
+	return app.state.set(entityId, value, attributes)
+}
+
+// Clock is the time source the app's automations read, for helpers that need
+// to measure against the same clock.
+func (app *App) Clock() Clock {
+	return app.clock
+}
+
+// AfterFunc calls fn on its own goroutine once d has passed on the app's
+// clock, for helpers that time things against the same clock as the
+// automations. It is never called once the app has shut down. stop abandons
+// the call, reporting whether it did so before fn was started.
+func (app *App) AfterFunc(d time.Duration, fn func()) (stop func() bool) {
+	const (
+		pending = iota
+		fired
+		stopped
+	)
+	var state atomic.Int32
+	abandon := make(chan struct{})
+
+	c, stopTimer := clockTimer(app.clock, d)
+	go func() {
+		defer stopTimer()
+		select {
+		case <-c:
+			if app.ctx.Err() == nil && state.CompareAndSwap(pending, fired) {
+				app.protect(AutomationMeta{Name: "AfterFunc"}, fn)
+			}
+		case <-abandon:
+		case <-app.ctx.Done():
+		}
+	}()
+
+	return func() bool {
+		if !state.CompareAndSwap(pending, stopped) {
+			return false
+		}
+		close(abandon)
+		return true
+	}
+}
+
+// Logger is where the app logs, for helpers that should log alongside it.
+func (app *App) Logger() *slog.Logger {
+	return app.log
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"log/slog"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -28,11 +29,14 @@ func TestCloseWaitsOutScheduleTriggeredRuns(t *testing.T) {
 			ctx:         ctx,
 			ctxCancel:   cancel,
 			clock:       clock,
+			log:         slog.Default(),
 			state:       stateWith(),
 			schedules:   newScheduler(clock),
 			intervals:   newScheduler(clock),
 			automations: map[string][]binding{},
-			runners:     map[*runner]struct{}{},
+			subscribed:  map[string]struct{}{},
+			waiters:     map[string]map[*waiter]struct{}{},
+			runners:     map[*runner]string{},
 			rescheduled: make(chan struct{}, 1),
 		}
 
@@ -69,11 +73,14 @@ func TestStartRefusesToRunTwice(t *testing.T) {
 	app := &App{
 		ctx: ctx, ctxCancel: cancel,
 		clock:       clock,
+		log:         slog.Default(),
 		state:       stateWith(),
 		schedules:   newScheduler(clock),
 		intervals:   newScheduler(clock),
 		automations: map[string][]binding{},
-		runners:     map[*runner]struct{}{},
+		subscribed:  map[string]struct{}{},
+		waiters:     map[string]map[*waiter]struct{}{},
+		runners:     map[*runner]string{},
 		rescheduled: make(chan struct{}, 1),
 	}
 
@@ -88,11 +95,14 @@ func TestStartRefusesAfterClose(t *testing.T) {
 	app := &App{
 		ctx: ctx, ctxCancel: cancel,
 		clock:       clock,
+		log:         slog.Default(),
 		state:       stateWith(),
 		schedules:   newScheduler(clock),
 		intervals:   newScheduler(clock),
 		automations: map[string][]binding{},
-		runners:     map[*runner]struct{}{},
+		subscribed:  map[string]struct{}{},
+		waiters:     map[string]map[*waiter]struct{}{},
+		runners:     map[*runner]string{},
 		rescheduled: make(chan struct{}, 1),
 	}
 	require.NoError(t, app.Close())
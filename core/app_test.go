@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -171,3 +172,29 @@ func TestAppContextCancellation(t *testing.T) {
 		t.Errorf("Close() returned error: %v", err)
 	}
 }
+
+func TestParseURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"http://192.168.1.5:8123":        "http://192.168.1.5:8123",
+		"https://ha.example.com":         "https://ha.example.com",
+		"ws://homeassistant.local:8123":  "http://homeassistant.local:8123",
+		"wss://ha.example.com/something": "https://ha.example.com/something",
+	} {
+		u, err := parseURL(raw)
+		if err != nil {
+			t.Errorf("parseURL(%q) returned error: %v", raw, err)
+			continue
+		}
+		if u.String() != want {
+			t.Errorf("parseURL(%q) = %q, want %q", raw, u, want)
+		}
+	}
+
+	// The shapes the old IpAddress and Port fields suggest, written without a
+	// scheme, are refused with directions rather than failing on first use.
+	for _, raw := range []string{"192.168.1.5:8123", "homeassistant.local:8123", "ftp://ha.example.com", ""} {
+		if _, err := parseURL(raw); !errors.Is(err, ErrInvalidArgs) {
+			t.Errorf("parseURL(%q) = %v, want ErrInvalidArgs", raw, err)
+		}
+	}
+}
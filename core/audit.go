@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
+)
+
+// auditName is how the app signs its logbook entries.
+const auditName = "go-ha"
+
+// auditSender records each service call it forwards, as AuditOptions asks.
+//
+// Recording happens after the call is sent and never fails it: an audit trail
+// that could stop the lights turning on would be worse than none.
+type auditSender struct {
+	next  services.Sender
+	state *state
+	clock Clock
+	opts  types.AuditOptions
+	log   *slog.Logger
+}
+
+func (a auditSender) Send(req types.Request) error {
+	if err := a.next.Send(req); err != nil {
+		return err
+	}
+	a.record(req)
+	return nil
+}
+
+// SendForResult forwards a call that waits for its answer, recording it once
+// Home Assistant has accepted it.
+func (a auditSender) SendForResult(ctx context.Context, req types.Request) (json.RawMessage, error) {
+	waiting, ok := a.next.(services.ResultSender)
+	if !ok {
+		return nil, errors.New("audited sender cannot wait for results")
+	}
+	result, err := waiting.SendForResult(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	a.record(req)
+	return result, nil
+}
+
+// record writes a call to wherever the audit options ask.
+func (a auditSender) record(req types.Request) {
+	call, ok := req.(*services.BaseServiceRequest)
+	// Logging the logbook's own entries would record every entry twice, and
+	// then the entry about that, without end.
+	if !ok || call.Domain == "logbook" {
+		return
+	}
+
+	target := call.Target.String()
+	action := call.Domain + "." + call.Service
+
+	if a.opts.Sensor != "" {
+		attrs := map[string]any{
+			"domain":  call.Domain,
+			"service": call.Service,
+			"target":  target,
+			"ts":      a.clock.Now().Format(time.RFC3339),
+		}
+		if err := a.state.set(a.opts.Sensor, action, attrs); err != nil {
+			a.log.Warn("Failed to record a service call", "sensor", a.opts.Sensor, "error", err)
+		}
+	}
+
+	if a.opts.Logbook {
+		message := "called " + action
+		if target != "" {
+			message = fmt.Sprintf("called %s on %s", action, target)
+		}
+		entry := services.NewBaseServiceRequest("")
+		entry.Domain = "logbook"
+		entry.Service = "log"
+		entry.ServiceData = map[string]any{"name": auditName, "message": message}
+		// The logbook files an entry under one entity. Areas and devices are
+		// not entities, so they are left to the message.
+		if call.Target != nil && call.Target.EntityId != "" {
+			first, _, _ := strings.Cut(call.Target.EntityId, ",")
+			entry.ServiceData["entity_id"] = strings.TrimSpace(first)
+		}
+		if err := a.next.Send(&entry); err != nil {
+			a.log.Warn("Failed to write a logbook entry", "error", err)
+		}
+	}
+}
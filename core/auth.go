@@ -0,0 +1,69 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Xevion/go-ha/internal"
+	"github.com/Xevion/go-ha/internal/connect"
+)
+
+// tokenExpiryWarning is how close to expiring a token has to be for
+// VerifyAuth to warn about it.
+const tokenExpiryWarning = 7 * 24 * time.Hour
+
+// AuthInfo is what VerifyAuth learned about the app's access token.
+type AuthInfo struct {
+	UserID   string
+	UserName string
+	IsOwner  bool
+	IsAdmin  bool
+
+	// ExpiresAt is when the token stops working, read from the token itself.
+	// Long-lived tokens last ten years.
+	ExpiresAt time.Time
+}
+
+// VerifyAuth checks the access token against both of Home Assistant's APIs
+// and reports whose it is, so a deployment can find out before Start that its
+// token belongs to the wrong user, lacks admin rights some services need, or
+// is about to expire.
+//
+// The websocket has already accepted the token by the time an App exists, so
+// a refusal here comes from the REST API, most often because a proxy in front
+// of Home Assistant strips the Authorization header. The error wraps
+// ErrAuthFailed and says so.
+func (app *App) VerifyAuth() (AuthInfo, error) {
+	if err := app.httpClient.CheckAPI(); err != nil {
+		if errors.Is(err, internal.ErrUnauthorized) {
+			return AuthInfo{}, fmt.Errorf("%w: the REST API refused the token the websocket accepted; "+
+				"check that any proxy in front of Home Assistant passes the Authorization header on", connect.ErrAuthFailed)
+		}
+		return AuthInfo{}, err
+	}
+
+	raw, err := app.Command(map[string]any{"type": "auth/current_user"})
+	if err != nil {
+		return AuthInfo{}, fmt.Errorf("reading the token's user: %w", err)
+	}
+	var user struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		IsOwner bool   `json:"is_owner"`
+		IsAdmin bool   `json:"is_admin"`
+	}
+	if err := json.Unmarshal(raw, &user); err != nil {
+		return AuthInfo{}, fmt.Errorf("decoding the token's user: %w", err)
+	}
+
+	info := AuthInfo{UserID: user.ID, UserName: user.Name, IsOwner: user.IsOwner, IsAdmin: user.IsAdmin}
+	if claims, ok := internal.ParseTokenClaims(app.token); ok {
+		info.ExpiresAt = claims.ExpiresAt
+	}
+	if !info.ExpiresAt.IsZero() && info.ExpiresAt.Sub(app.clock.Now()) < tokenExpiryWarning {
+		app.log.Warn("Access token expires soon", "user", info.UserName, "expires_at", info.ExpiresAt)
+	}
+	return info, nil
+}
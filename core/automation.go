@@ -2,16 +2,28 @@ package core
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/Xevion/go-ha/internal"
 )
 
-// ErrInvalidAutomation reports an automation that cannot be built.
-var ErrInvalidAutomation = errors.New("invalid automation")
+var (
+	// ErrInvalidAutomation reports an automation that cannot be built.
+	ErrInvalidAutomation = errors.New("invalid automation")
+
+	// ErrUnknownAutomation reports a name no registered automation has.
+	ErrUnknownAutomation = errors.New("unknown automation")
+
+	// ErrAutomationBusy reports a run by hand that the automation's mode or
+	// throttle refused.
+	ErrAutomationBusy = errors.New("automation did not run")
+)
 
 // Run is the context an action is given when it fires.
 type Run struct {
@@ -46,6 +58,47 @@ const (
 	RunAnyway
 )
 
+// RetryThenSkip re-evaluates an unevaluable condition up to attempts more
+// times, backing off between them, and skips the run only if it never settles.
+// It suits conditions that read an entity across a flaky integration, where
+// the next try a second later usually succeeds.
+//
+// The wait does not hold up other automations. A newer trigger for the same
+// entity replaces a retry still waiting, since it re-evaluates anyway.
+func RetryThenSkip(attempts int) ConditionErrorPolicy {
+	if attempts < 1 {
+		// Out of range on purpose, so Build refuses it rather than it quietly
+		// becoming SkipRun.
+		return invalidConditionErrorPolicy
+	}
+	return -ConditionErrorPolicy(attempts)
+}
+
+// invalidConditionErrorPolicy is no policy at all. Retry policies are stored
+// as the negated attempt count, which leaves the positive range past RunAnyway
+// free to mark a bad one.
+const invalidConditionErrorPolicy ConditionErrorPolicy = RunAnyway + 1
+
+// retries is how many re-evaluations the policy allows.
+func (p ConditionErrorPolicy) retries() int {
+	if p < 0 {
+		return int(-p)
+	}
+	return 0
+}
+
+func (p ConditionErrorPolicy) String() string {
+	switch {
+	case p == SkipRun:
+		return "skip"
+	case p == RunAnyway:
+		return "run anyway"
+	case p < 0:
+		return fmt.Sprintf("retry %d then skip", p.retries())
+	}
+	return fmt.Sprintf("ConditionErrorPolicy(%d)", int(p))
+}
+
 // Automation is a trigger, a condition, a policy and an action, which together
 // describe one rule. Build one with NewAutomation.
 type Automation struct {
@@ -56,6 +109,9 @@ type Automation struct {
 	action           Action
 	onConditionError ConditionErrorPolicy
 
+	// executor runs the action. Nil runs it in process.
+	executor Executor
+
 	// runtime is allocated by Build, never by the builder stages. Every stage
 	// returns a copy, so allocating earlier would hand one runner to every
 	// automation branched off a shared prefix.
@@ -64,8 +120,39 @@ type Automation struct {
 
 func (a Automation) Name() string { return a.name }
 
+// String describes the automation for a log line: its name, what fires it and
+// its mode, as in "hallway light: sunset or every 10m (restart)".
 func (a Automation) String() string {
-	return fmt.Sprintf("%s (%d trigger(s), %s)", a.name, len(a.triggers), a.policy.Mode)
+	labels := make([]string, len(a.triggers))
+	for i, t := range a.triggers {
+		labels[i] = triggerLabel(t)
+	}
+	return fmt.Sprintf("%s: %s (%s)", a.name, strings.Join(labels, " or "), a.policy.Mode)
+}
+
+// ID identifies the automation by its name and what fires it, as a short hex
+// string. Unlike the name alone it tells apart two automations that share one,
+// and unlike the automation's address it comes out the same on every run of
+// the program, so it can key metrics or anything persisted between runs. Two
+// automations with the same name and the same triggers share an ID.
+func (a Automation) ID() string {
+	h := sha256.New()
+	h.Write([]byte(a.name))
+	for _, t := range a.triggers {
+		h.Write([]byte{0})
+		h.Write([]byte(triggerLabel(t)))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// triggerLabel describes a trigger by what it says it is, or failing that by
+// its type. Printing the value itself would show the addresses of whatever
+// functions and pointers it holds, which differ from one run to the next.
+func triggerLabel(t Trigger) string {
+	if s, ok := t.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", t)
 }
 
 // validator is implemented by triggers and conditions that can be constructed
@@ -120,6 +207,15 @@ func (b AutomationBuilder) Throttle(d time.Duration) AutomationBuilder {
 	return b
 }
 
+// ThrottleTrailing makes Throttle run the last trigger it dropped once the
+// window closes, rather than discarding it. A burst of sensor updates then
+// produces one run at its start and one with its final value, instead of
+// leaving the automation acting on whatever arrived first.
+func (b AutomationBuilder) ThrottleTrailing() AutomationBuilder {
+	b.a.policy.Trailing = true
+	return b
+}
+
 // Limit caps in-flight runs under ModeParallel and waiting runs under
 // ModeQueued.
 func (b AutomationBuilder) Limit(n int) AutomationBuilder {
@@ -152,6 +248,12 @@ func (b AutomationBuilder) Build() (Automation, error) {
 	if b.a.action == nil {
 		errs = append(errs, errors.New("automation needs an action, set with Do"))
 	}
+	if b.a.policy.Trailing && b.a.policy.Throttle <= 0 {
+		errs = append(errs, errors.New("ThrottleTrailing needs a window, set with Throttle"))
+	}
+	if b.a.onConditionError > RunAnyway {
+		errs = append(errs, errors.New("RetryThenSkip needs at least one attempt"))
+	}
 
 	for _, t := range b.a.triggers {
 		if v, ok := t.(validator); ok {
@@ -167,7 +269,14 @@ func (b AutomationBuilder) Build() (Automation, error) {
 	}
 
 	built := b.a
+	built.triggers = slices.Clone(built.triggers)
+	for i, t := range built.triggers {
+		if at, ok := t.(advancingTrigger); ok {
+			built.triggers[i] = at.fresh()
+		}
+	}
 	built.runtime = newRunner(built.policy, internal.RealClock{})
+	built.runtime.id = built.ID()
 	return built, nil
 }
 
@@ -219,24 +328,114 @@ func concat[T any](a, b []T) []T {
 // fire evaluates the conditions and, if they hold, runs the action under the
 // policy. It reports whether the action was admitted.
 func (a Automation) fire(ctx context.Context, ec EvalContext, deps Run, key string) bool {
+	return a.attempt(ctx, ec, deps, key, 0)
+}
+
+// attempt is fire, numbered so a retried evaluation knows how many tries it has
+// left.
+func (a Automation) attempt(ctx context.Context, ec EvalContext, deps Run, key string, try int) bool {
 	if a.condition != nil {
-		ok, err := a.condition.Eval(ctx, ec)
+		ok, err := a.eval(ctx, ec)
+		var p *PanicError
+		if errors.As(err, &p) {
+			// A condition that panics is a bug, not a read that may succeed
+			// on a retry, so the error policy does not apply to it.
+			a.runtime.conditionErrors.Add(1)
+			a.runtime.logger().Error("Automation condition panicked", failureAttrs(err)...)
+			a.runtime.reportFailure(err, deps.Event)
+			return false
+		}
 		if err != nil {
-			if a.onConditionError == SkipRun {
-				slog.Warn("Skipping automation, condition could not be evaluated",
-					"automation", a.name, "error", err)
+			if !a.conditionFailed(ctx, ec, deps, key, try, err) {
 				return false
 			}
-			slog.Warn("Running automation despite an unevaluable condition",
-				"automation", a.name, "error", err)
 		} else if !ok {
 			return false
 		}
 	}
 
-	return a.runtime.run(ctx, key, func(runCtx context.Context) {
-		if err := a.action(runCtx, deps); err != nil {
-			slog.Error("Automation action failed", "automation", a.name, "error", err)
-		}
-	})
+	return a.runtime.run(ctx, key, func(runCtx context.Context) { a.act(runCtx, deps) })
+}
+
+// eval evaluates the automation's condition, returning a panic in it as a
+// *PanicError.
+func (a Automation) eval(ctx context.Context, ec EvalContext) (ok bool, err error) {
+	defer recoverPanic(&err)
+	return a.condition.Eval(ctx, ec)
+}
+
+// act runs the action, through the automation's executor if it has one,
+// recording, logging and reporting a failure. A panic in the action is
+// recovered, so it costs the run rather than the process.
+func (a Automation) act(ctx context.Context, deps Run) {
+	err := a.execute(ctx, deps)
+	if err == nil {
+		return
+	}
+	a.runtime.failed(err)
+	msg := "Automation action failed"
+	var p *PanicError
+	if errors.As(err, &p) {
+		msg = "Automation action panicked"
+	}
+	a.runtime.logger().Error(msg, failureAttrs(err)...)
+	a.runtime.reportFailure(err, deps.Event)
+}
+
+// execute runs the action. The action is guarded where it runs, which for an
+// executor may be another goroutine, and the executor itself here.
+func (a Automation) execute(ctx context.Context, deps Run) (err error) {
+	defer recoverPanic(&err)
+	run := func(ctx context.Context) (err error) {
+		defer recoverPanic(&err)
+		return a.action(ctx, deps)
+	}
+	if a.executor != nil {
+		return a.executor.Execute(ctx, Task{Automation: a.name, Event: deps.Event, run: run})
+	}
+	return run(ctx)
+}
+
+// conditionFailed records an unevaluable condition and applies the
+// automation's policy to it, reporting whether to run regardless.
+func (a Automation) conditionFailed(ctx context.Context, ec EvalContext, deps Run, key string, try int, err error) bool {
+	a.runtime.conditionErrors.Add(1)
+
+	log := a.runtime.logger()
+	attrs := []any{"error", err}
+	var read *EntityReadError
+	if errors.As(err, &read) {
+		attrs = append(attrs, "entity", read.EntityID)
+	}
+
+	switch {
+	case a.onConditionError == RunAnyway:
+		log.Warn("Running automation despite an unevaluable condition", attrs...)
+		return true
+
+	case try < a.onConditionError.retries():
+		delay := conditionRetryDelay(try)
+		log.Warn("Condition could not be evaluated, retrying",
+			append(attrs, "attempt", try+1, "retry_in", delay)...)
+		a.runtime.retries.arm(key, delay, func() {
+			a.attempt(ctx, ec, deps, key, try+1)
+		})
+		return false
+	}
+
+	if try > 0 {
+		attrs = append(attrs, "attempts", try+1)
+	}
+	log.Warn("Skipping automation, condition could not be evaluated", attrs...)
+	return false
+}
+
+// conditionRetryDelay backs off between re-evaluations: one second, then two,
+// then four, up to half a minute.
+func conditionRetryDelay(try int) time.Duration {
+	const ceiling = 30 * time.Second
+	if try >= 5 {
+		return ceiling
+	}
+	return min(time.Second<<try, ceiling)
 }
@@ -1,12 +1,14 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
+	"slices"
 	"time"
 
 	"github.com/Xevion/go-ha/internal/connect"
+	"github.com/Xevion/go-ha/internal/scheduling"
 )
 
 // binding pairs an automation with the one trigger of its several that a given
@@ -25,6 +27,13 @@ type binding struct {
 // which reports absence with a nil pointer rather than a bool.
 type schedulerAdapter struct {
 	trigger ScheduleTrigger
+
+	// automation names the automation the trigger belongs to, for listing
+	// what is scheduled.
+	automation string
+
+	// runner is the automation's runtime, which unregistering it matches on.
+	runner *runner
 }
 
 func (a schedulerAdapter) NextTime(now time.Time) *time.Time {
@@ -59,14 +68,30 @@ func (app *App) RegisterAutomations(automations ...Automation) error {
 				ErrInvalidAutomation, a.name))
 			continue
 		}
+		if app.strictEntities {
+			if err := app.checkEntities(a); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
 
 		// Build has no App to read a clock from, so it starts on the real one.
 		// Registration is where the automation joins an app, and its throttle
 		// has to measure against the same clock its conditions read.
 		a.runtime.withClock(app.clock)
+		a.runtime.withLogger(app.log.With("automation", a.name, "automation_id", a.runtime.id))
+		a.runtime.withReporter(func(err error, ev Event) {
+			app.reportCallbackError(err, AutomationMeta{Name: a.name, ID: a.runtime.id, Event: ev})
+		})
 
 		app.registryMu.Lock()
-		app.runners[a.runtime] = struct{}{}
+		app.runners[a.runtime] = a.name
+		if app.named == nil {
+			app.named = map[string]Automation{}
+		}
+		if _, taken := app.named[a.name]; !taken {
+			app.named[a.name] = a
+		}
 		app.registryMu.Unlock()
 
 		for _, t := range a.triggers {
@@ -98,17 +123,94 @@ func (app *App) RegisterAutomations(automations ...Automation) error {
 	return errors.Join(errs...)
 }
 
+// UnregisterAutomations removes automations registered earlier, leaving the
+// rest untouched. Their schedules are dropped, their triggers no longer fire
+// them, and whatever they were waiting out, a For, a throttle's trailing run or
+// a condition retry, is abandoned. A run under way is left to finish. One that
+// is not registered is reported with ErrUnknownAutomation, and the others are
+// removed regardless.
+//
+// An automation is matched by what Build made of it, not by its name, so a
+// second automation under the same name stays registered, though RunAutomation
+// no longer finds it by that name.
+func (app *App) UnregisterAutomations(automations ...Automation) error {
+	var errs []error
+	var retired []*runner
+
+	app.registryMu.Lock()
+	for _, a := range automations {
+		if _, ok := app.runners[a.runtime]; a.runtime == nil || !ok {
+			errs = append(errs, fmt.Errorf("%w %q", ErrUnknownAutomation, a.name))
+			continue
+		}
+		delete(app.runners, a.runtime)
+		retired = append(retired, a.runtime)
+	}
+
+	var pending []*pendingRuns
+	for eventType, bindings := range app.automations {
+		// Built anew rather than filtered in place: dispatch reads the slice
+		// it took under the read lock after letting go of it.
+		var kept []binding
+		for _, b := range bindings {
+			if slices.Contains(retired, b.automation.runtime) {
+				pending = append(pending, b.pending)
+				continue
+			}
+			kept = append(kept, b)
+		}
+		if len(kept) == 0 {
+			delete(app.automations, eventType)
+			continue
+		}
+		app.automations[eventType] = kept
+	}
+
+	for name, a := range app.named {
+		if slices.Contains(retired, a.runtime) {
+			delete(app.named, name)
+		}
+	}
+	app.registryMu.Unlock()
+
+	for _, r := range retired {
+		r.retired.Store(true)
+	}
+	unscheduled := func(t scheduling.Trigger) bool {
+		a, ok := t.(schedulerAdapter)
+		return ok && slices.Contains(retired, a.runner)
+	}
+	app.schedules.remove(unscheduled)
+	for _, p := range pending {
+		p.stop()
+	}
+	for _, r := range retired {
+		r.stop()
+	}
+	return errors.Join(errs...)
+}
+
 // scheduleAutomation queues the trigger and reports whether it could be. A
 // trigger with no next occurrence is a configuration error, not something to
 // drop quietly and leave the caller believing it registered.
 func (app *App) scheduleAutomation(a Automation, trig ScheduleTrigger) bool {
 	// A trigger declared before any App exists has nothing to read from until
-	// it joins one. Sun triggers derive their times from an entity.
+	// it joins one. Sun triggers derive their times from an entity, and a
+	// daily trigger narrowed by a workday sensor reads one.
 	if b, ok := trig.(interface{ bind(StateReader) }); ok {
 		b.bind(app.state)
 	}
+	if l, ok := trig.(interface {
+		locate(func() (float64, float64, bool))
+	}); ok {
+		l.locate(app.homeLocation)
+	}
 
-	return app.schedules.add(schedulerAdapter{trigger: trig}, func() {
+	return app.schedules.add(schedulerAdapter{trigger: trig, automation: a.name, runner: a.runtime}, func() {
+		if s, ok := trig.(interface{ skips(time.Time) bool }); ok && s.skips(app.clock.Now()) {
+			app.log.Debug("Skipping scheduled run", "automation", a.name, "trigger", fmt.Sprint(trig))
+			return
+		}
 		ec := EvalContext{Clock: app.clock, State: app.state}
 		deps := Run{Services: app.service, State: app.state, Trigger: trig}
 
@@ -119,31 +221,85 @@ func (app *App) scheduleAutomation(a Automation, trig ScheduleTrigger) bool {
 }
 
 func (app *App) subscribeAutomation(a Automation, trig EventTrigger) error {
-	var fresh []string
 	b := binding{automation: a, trigger: trig, pending: newPendingRuns()}
+	var eventTypes []string
+	var streams []Subscription
 
 	app.registryMu.Lock()
 	for _, sub := range trig.Subscriptions() {
-		if _, seen := app.automations[sub.EventType]; !seen {
-			fresh = append(fresh, sub.EventType)
-		}
+		// Filed under its type like any other, so listing, stopping and
+		// shutting down find it, though only its own stream dispatches to it.
 		app.automations[sub.EventType] = append(app.automations[sub.EventType], b)
+		if sub.Command != nil {
+			streams = append(streams, sub)
+			continue
+		}
+		eventTypes = append(eventTypes, sub.EventType)
 	}
 	app.registryMu.Unlock()
 
-	// Subscribing only after the map is published and unlocked. Home Assistant
-	// delivers as soon as the request lands, on a worker goroutine that reads
-	// the very map being written here.
-	var errs []error
-	for _, eventType := range fresh {
+	errs := []error{app.subscribeTypes(eventTypes)}
+	for _, sub := range streams {
+		errs = append(errs, app.subscribeStream(sub, b))
+	}
+	return errors.Join(errs...)
+}
+
+// subscribeStream opens the command's stream for the one trigger that asked
+// for it. A refusal is logged by the client rather than returned, as it is
+// for event subscriptions, and the stream is asked for again on reconnect.
+func (app *App) subscribeStream(sub Subscription, b binding) error {
+	decode := sub.decode
+	if decode == nil {
+		decode = func(raw []byte) (Event, error) { return Event{Type: sub.EventType, Raw: raw}, nil }
+	}
+
+	err := app.client.Subscribe(connect.Subscription{Command: sub.Command}, func(msg connect.Message) {
+		if !app.started.Load() {
+			return
+		}
+		ev, err := decode(msg.Raw)
+		if err != nil {
+			app.undecodable.record(msg.Raw, err)
+			return
+		}
+		app.dispatch(ev, []binding{b})
+	})
+	if err != nil {
+		return fmt.Errorf("subscribing to %s: %w", sub.Command["type"], err)
+	}
+	return nil
+}
+
+// subscribeTypes asks Home Assistant for any of the event types not already
+// subscribed to.
+//
+// Callers publish whatever will receive the events before calling this. Home
+// Assistant delivers as soon as the request lands, on a worker goroutine that
+// reads the very map being written.
+func (app *App) subscribeTypes(eventTypes []string) error {
+	var fresh []string
+	app.registryMu.Lock()
+	for _, eventType := range eventTypes {
 		// state_changed is subscribed at construction to feed the cache, and
 		// its dispatch already routes here.
-		if eventType == eventStateChanged {
+		if _, seen := app.subscribed[eventType]; seen || eventType == eventStateChanged {
 			continue
 		}
+		app.subscribed[eventType] = struct{}{}
+		fresh = append(fresh, eventType)
+	}
+	app.registryMu.Unlock()
+
+	var errs []error
+	for _, eventType := range fresh {
+		handler := app.onEvent
+		if eventType == anyEventType {
+			handler = app.onAnyEvent
+		}
 		if err := app.client.Subscribe(
 			connect.Subscription{EventType: eventType},
-			app.onEvent,
+			handler,
 		); err != nil {
 			errs = append(errs, fmt.Errorf("subscribing to %s: %w", eventType, err))
 		}
@@ -152,16 +308,47 @@ func (app *App) subscribeAutomation(a Automation, trig EventTrigger) error {
 	return errors.Join(errs...)
 }
 
-// dispatchEvent runs every automation whose trigger matches the event.
+// dispatchEvent runs every automation whose trigger matches the event. One
+// that cannot be decoded was already counted and reported by the reader, as it
+// applied the event to the cache, so it is passed over here.
 func (app *App) dispatchEvent(raw []byte) {
-	ev := parseEvent(raw)
-	if ev.Type == "" {
+	ev, err := parseEvent(raw)
+	if err != nil {
 		return
 	}
 
+	app.stats.count(ev, app.clock.Now())
+
 	app.registryMu.RLock()
 	bindings := app.automations[ev.Type]
 	app.registryMu.RUnlock()
+
+	app.wakeWaiters(ev)
+	app.dispatch(ev, bindings)
+}
+
+// dispatchToAll runs the automations subscribed to every event. They have a
+// subscription of their own, which delivers each event alongside whatever
+// subscription is for its type, so only they are dispatched to here.
+func (app *App) dispatchToAll(raw []byte) {
+	ev, err := parseEvent(raw)
+	if err != nil {
+		return
+	}
+
+	app.registryMu.RLock()
+	bindings := app.automations[anyEventType]
+	_, counted := app.subscribed[ev.Type]
+	app.registryMu.RUnlock()
+
+	// Counted here only when no subscription for its type counts it already.
+	if !counted && ev.Type != eventStateChanged {
+		app.stats.count(ev, app.clock.Now())
+	}
+	app.dispatch(ev, bindings)
+}
+
+func (app *App) dispatch(ev Event, bindings []binding) {
 	if len(bindings) == 0 {
 		return
 	}
@@ -169,6 +356,13 @@ func (app *App) dispatchEvent(raw []byte) {
 	ec := EvalContext{Clock: app.clock, State: app.state, Event: ev}
 
 	for _, b := range bindings {
+		if at, ok := b.trigger.(advancingTrigger); ok {
+			if at.concerns(ev.EntityID) {
+				app.advance(at, b, ev)
+			}
+			continue
+		}
+
 		matched := b.trigger.Matches(ev)
 		deps := Run{Services: app.service, State: app.state, Event: ev, Trigger: b.trigger}
 
@@ -197,7 +391,84 @@ func (app *App) dispatchEvent(raw []byte) {
 		// Keyed by entity, so one automation watching many entities keeps a
 		// separate throttle window and run slot for each.
 		if !b.automation.fire(app.ctx, ec, deps, ev.EntityID) {
-			slog.Debug("Automation did not run", "automation", b.automation.name, "entity", ev.EntityID)
+			b.automation.runtime.logger().Debug("Automation did not run", "entity", ev.EntityID)
+		}
+	}
+}
+
+// advance feeds an event to a trigger such as WhenAll, firing its automation
+// once the requirements come together and waiting out any For among them. The
+// wait is kept under the empty key, which no entity has, and the run is keyed
+// the same way: the automation fires for the requirements together, not for
+// whichever entity changed last. A run after a wait is given no event, rather
+// than the one that started it, which is stale by then.
+func (app *App) advance(t advancingTrigger, b binding, ev Event) {
+	fire, wait := t.advance(ev, app.state.cache.get, app.clock.Now())
+	switch {
+	case fire:
+		ec := EvalContext{Clock: app.clock, State: app.state, Event: ev}
+		deps := Run{Services: app.service, State: app.state, Event: ev, Trigger: b.trigger}
+		b.automation.fire(app.ctx, ec, deps, "")
+	case wait > 0:
+		b.pending.arm("", wait, func() { app.advance(t, b, Event{}) })
+	default:
+		b.pending.disarm("")
+	}
+}
+
+// payloadTrigger is implemented by triggers whose event carries a payload the
+// action reads, such as a webhook's request, which a run without an event
+// cannot supply.
+type payloadTrigger interface{ carriesPayload() }
+
+// RunAutomation runs the named automation's action now, for debugging a
+// deployment. Its conditions are skipped, as Home Assistant's "run actions"
+// skips them, but its mode is not: one already running under ModeSingle turns
+// it away. Where two automations share a name, the first registered runs. One
+// fired only by webhooks or MQTT messages is refused with ErrInvalidArgs, since
+// its action expects the request or message it has no way to be given.
+func (app *App) RunAutomation(name string) error {
+	if !app.started.Load() || app.ctx.Err() != nil {
+		return ErrNotRunning
+	}
+
+	app.registryMu.RLock()
+	a, ok := app.named[name]
+	app.registryMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownAutomation, name)
+	}
+
+	runnable := false
+	for _, t := range a.triggers {
+		if _, ok := t.(payloadTrigger); !ok {
+			runnable = true
+			break
+		}
+	}
+	if !runnable {
+		return fmt.Errorf("%w: %q is fired only by triggers with a payload, which a manual run cannot supply", ErrInvalidArgs, name)
+	}
+
+	deps := Run{Services: app.service, State: app.state}
+	if !a.runtime.run(app.ctx, "", func(ctx context.Context) { a.act(ctx, deps) }) {
+		return fmt.Errorf("%w: %q did not run, its mode or throttle turned it away", ErrAutomationBusy, name)
+	}
+	return nil
+}
+
+// ConditionErrors reports, per automation name, how many times a condition
+// could not be evaluated. Each retry under RetryThenSkip counts separately. An
+// automation whose conditions have always settled is left out.
+func (app *App) ConditionErrors() map[string]uint64 {
+	app.registryMu.RLock()
+	defer app.registryMu.RUnlock()
+
+	out := map[string]uint64{}
+	for r, name := range app.runners {
+		if n := r.conditionErrors.Load(); n > 0 {
+			out[name] += n
 		}
 	}
+	return out
 }
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"log/slog"
 	"testing"
 	"time"
 
@@ -19,11 +20,14 @@ func testApp(entities ...EntityState) *App {
 	return &App{
 		ctx:         context.Background(),
 		clock:       clock,
+		log:         slog.Default(),
 		state:       stateWith(entities...),
 		schedules:   newScheduler(clock),
 		intervals:   newScheduler(clock),
 		automations: map[string][]binding{},
-		runners:     map[*runner]struct{}{},
+		subscribed:  map[string]struct{}{},
+		waiters:     map[string]map[*waiter]struct{}{},
+		runners:     map[*runner]string{},
 	}
 }
 
@@ -3,7 +3,9 @@ package core
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -107,6 +109,23 @@ func TestBranchedAutomationsDoNotShareConditions(t *testing.T) {
 	assert.Nil(t, loose.condition, "a sibling's condition must not attach here")
 }
 
+func TestIDFollowsTheNameAndTriggers(t *testing.T) {
+	build := func(name string, triggers ...Trigger) Automation {
+		return NewAutomation(name).On(triggers...).Do(noAction).MustBuild()
+	}
+	porch := build("porch", StateChanged("binary_sensor.porch").To("on"), Sunset())
+
+	// Built twice, as it is on every start of the program.
+	assert.Equal(t, porch.ID(), build("porch", StateChanged("binary_sensor.porch").To("on"), Sunset()).ID())
+	assert.Len(t, porch.ID(), 16)
+
+	assert.NotEqual(t, porch.ID(), build("hall", StateChanged("binary_sensor.porch").To("on"), Sunset()).ID())
+	assert.NotEqual(t, porch.ID(), build("porch", StateChanged("binary_sensor.porch").To("on").For(time.Minute), Sunset()).ID())
+	assert.NotEqual(t, porch.ID(), build("porch", StateChanged("binary_sensor.porch").To("on")).ID())
+
+	assert.Equal(t, "porch: state change on binary_sensor.porch to on or sunset (single)", porch.String())
+}
+
 func TestFireRunsTheActionWhenConditionsHold(t *testing.T) {
 	ran := make(chan struct{}, 1)
 	a := NewAutomation("a").
@@ -153,6 +172,89 @@ func TestRunAnywayFiresDespiteAnUnevaluableCondition(t *testing.T) {
 	assertReceived(t, ran)
 }
 
+func TestPanickingConditionSkipsTheRunEvenUnderRunAnyway(t *testing.T) {
+	var reported error
+	a := NewAutomation("a").
+		On(Daily(TimeOfDay(9, 0))).
+		When(ConditionFunc(func(context.Context, EvalContext) (bool, error) { panic("bad condition") })).
+		OnConditionError(RunAnyway).
+		Do(func(context.Context, Run) error { t.Error("action must not run"); return nil }).
+		MustBuild()
+	a.runtime.withReporter(func(err error, _ Event) { reported = err })
+
+	assert.False(t, a.fire(context.Background(), EvalContext{Clock: testClock()}, Run{}, ""))
+	var p *PanicError
+	require.ErrorAs(t, reported, &p)
+	assert.Equal(t, "bad condition", p.Value)
+	assert.Equal(t, uint64(1), a.runtime.conditionErrors.Load())
+}
+
+// flaky fails its first n evaluations, then holds.
+func flaky(n int64, evals *atomic.Int64) Condition {
+	return ConditionFunc(func(context.Context, EvalContext) (bool, error) {
+		if evals.Add(1) <= n {
+			return false, &EntityReadError{EntityID: "sensor.flaky", Err: errUndecided}
+		}
+		return true, nil
+	})
+}
+
+func TestRetryThenSkipRunsOnceTheConditionSettles(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var evals, runs atomic.Int64
+		a := NewAutomation("a").
+			On(Daily(TimeOfDay(9, 0))).
+			When(flaky(2, &evals)).
+			OnConditionError(RetryThenSkip(3)).
+			Do(func(context.Context, Run) error { runs.Add(1); return nil }).
+			MustBuild()
+		defer a.runtime.stop()
+
+		assert.False(t, a.fire(context.Background(), EvalContext{Clock: testClock()}, Run{}, ""),
+			"the first failure defers the run rather than admitting it")
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+		assert.Equal(t, int64(2), evals.Load())
+		assert.Zero(t, runs.Load())
+
+		// The second retry comes after a longer wait, and succeeds.
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		assert.Equal(t, int64(3), evals.Load())
+		assert.Equal(t, int64(1), runs.Load())
+		assert.Equal(t, uint64(2), a.runtime.conditionErrors.Load())
+	})
+}
+
+func TestRetryThenSkipGivesUp(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var evals atomic.Int64
+		a := NewAutomation("a").
+			On(Daily(TimeOfDay(9, 0))).
+			When(flaky(100, &evals)).
+			OnConditionError(RetryThenSkip(2)).
+			Do(func(context.Context, Run) error { t.Error("action must not run"); return nil }).
+			MustBuild()
+		defer a.runtime.stop()
+
+		a.fire(context.Background(), EvalContext{Clock: testClock()}, Run{}, "")
+		time.Sleep(time.Minute)
+		synctest.Wait()
+
+		assert.Equal(t, int64(3), evals.Load(), "one evaluation and two retries")
+	})
+}
+
+func TestRetryThenSkipNeedsAnAttempt(t *testing.T) {
+	_, err := NewAutomation("a").
+		On(Daily(TimeOfDay(9, 0))).
+		OnConditionError(RetryThenSkip(0)).
+		Do(noAction).
+		Build()
+	assert.ErrorIs(t, err, ErrInvalidAutomation)
+}
+
 // A failing action is logged and the automation stays live, rather than taking
 // the process down with it.
 func TestActionErrorsDoNotStopTheAutomation(t *testing.T) {
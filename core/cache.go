@@ -15,10 +15,19 @@ type entityCache struct {
 	touched map[string]struct{}
 	pending bool
 	seeded  bool
+
+	// firstSeed is closed when the first snapshot lands, for whoever waits on
+	// the cache becoming usable. A later outage does not reopen it.
+	firstSeed chan struct{}
 }
 
 func newEntityCache() *entityCache {
-	return &entityCache{entities: map[string]EntityState{}}
+	return &entityCache{entities: map[string]EntityState{}, firstSeed: make(chan struct{})}
+}
+
+// seededOnce is closed once the first snapshot has landed.
+func (c *entityCache) seededOnce() <-chan struct{} {
+	return c.firstSeed
 }
 
 // beginSeed opens a snapshot window. It must be called before the request that
@@ -55,6 +64,11 @@ func (c *entityCache) finishSeed(list []EntityState) {
 	c.touched = nil
 	c.pending = false
 	c.seeded = true
+	select {
+	case <-c.firstSeed:
+	default:
+		close(c.firstSeed)
+	}
 }
 
 // abandonSeed closes a window whose snapshot never arrived. Left open, the
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xevion/go-ha/services"
+)
+
+// calendarMarker opens the description of every event the app publishes. It is
+// how a later sync tells its own events from those a person added to the same
+// calendar, which it must never touch.
+const calendarMarker = "Scheduled by go-ha"
+
+const (
+	// publishDebounce collects the burst of changes a single registration or
+	// sun refresh makes into one sync.
+	publishDebounce = 2 * time.Second
+
+	// publishPerTrigger caps how many occurrences of one trigger are listed, so
+	// a minutely interval does not bury the calendar.
+	publishPerTrigger = 48
+)
+
+// plannedEvent is one scheduled run as it appears on the calendar.
+type plannedEvent struct {
+	summary     string
+	description string
+	start       time.Time
+}
+
+func (e plannedEvent) key() string {
+	return e.summary + "\x00" + e.start.UTC().Format(time.RFC3339)
+}
+
+// schedulePublisher mirrors the schedule queue onto a calendar entity.
+type schedulePublisher struct {
+	app      *App
+	calendar string
+	horizon  time.Duration
+	pending  *pendingRuns
+
+	// mu serialises syncs, which would otherwise create the same event twice.
+	mu   sync.Mutex
+	last []plannedEvent
+}
+
+// PublishSchedules keeps a Home Assistant calendar listing the app's scheduled
+// runs over the coming horizon, so they show on the calendar dashboard. The
+// calendar should be a local calendar set aside for the purpose, though events
+// added to it by hand are left alone. A horizon of zero means a day.
+//
+// The calendar is written at startup and again shortly after the schedule
+// changes: as runs fire, as sun times move, or as automations are registered.
+func (app *App) PublishSchedules(calendarID string, horizon time.Duration) error {
+	if !strings.HasPrefix(calendarID, "calendar.") {
+		return fmt.Errorf("%w: %q is not a calendar entity", ErrInvalidArgs, calendarID)
+	}
+	if horizon <= 0 {
+		horizon = 24 * time.Hour
+	}
+
+	p := &schedulePublisher{app: app, calendar: calendarID, horizon: horizon, pending: newPendingRuns()}
+
+	app.registryMu.Lock()
+	if app.publisher != nil {
+		app.registryMu.Unlock()
+		return fmt.Errorf("%w: schedules are already published to %s", ErrInvalidArgs, app.publisher.calendar)
+	}
+	app.publisher = p
+	app.registryMu.Unlock()
+
+	app.schedules.onChange(p.poke)
+
+	return app.RegisterAutomations(
+		NewAutomation("publish schedules to " + calendarID).
+			On(AtStartup()).
+			Do(func(_ context.Context, _ Run) error { return p.sync() }).
+			MustBuild(),
+	)
+}
+
+// poke asks for a sync once the schedule settles. Changes before Start are
+// covered by the startup sync.
+func (p *schedulePublisher) poke() {
+	if !p.app.started.Load() {
+		return
+	}
+	p.pending.arm("", publishDebounce, func() {
+		if err := p.sync(); err != nil {
+			p.app.log.Warn("Failed to publish schedules", "calendar", p.calendar, "error", err)
+		}
+	})
+}
+
+// sync brings the calendar in line with the schedule queue: events for runs no
+// longer planned are deleted, and runs not yet listed are added.
+func (p *schedulePublisher) sync() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.app.clock.Now()
+	until := now.Add(p.horizon)
+
+	var planned []plannedEvent
+	for _, r := range p.app.schedules.upcoming(now, until, publishPerTrigger) {
+		adapter, ok := r.trigger.(schedulerAdapter)
+		if !ok {
+			continue
+		}
+		planned = append(planned, plannedEvent{
+			summary:     adapter.automation,
+			description: calendarMarker + ": " + adapter.String(),
+			start:       r.at,
+		})
+	}
+	if slices.Equal(planned, p.last) {
+		return nil
+	}
+
+	raw, err := p.app.httpClient.GetCalendarEvents(p.calendar, now, until)
+	if err != nil {
+		return err
+	}
+	var existing []struct {
+		UID         string `json:"uid"`
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Start       struct {
+			DateTime time.Time `json:"dateTime"`
+		} `json:"start"`
+	}
+	if err := json.Unmarshal(raw, &existing); err != nil {
+		return fmt.Errorf("decoding events of %s: %w", p.calendar, err)
+	}
+
+	wanted := make(map[string]plannedEvent, len(planned))
+	for _, e := range planned {
+		wanted[e.key()] = e
+	}
+
+	var errs []error
+	for _, e := range existing {
+		if !strings.HasPrefix(e.Description, calendarMarker) {
+			continue
+		}
+		key := plannedEvent{summary: e.Summary, start: e.Start.DateTime}.key()
+		if _, ok := wanted[key]; ok {
+			delete(wanted, key)
+			continue
+		}
+		if _, err := p.app.Command(map[string]any{
+			"type":      "calendar/event/delete",
+			"entity_id": p.calendar,
+			"uid":       e.UID,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %q: %w", e.Summary, err))
+		}
+	}
+
+	for _, e := range planned {
+		if _, ok := wanted[e.key()]; !ok {
+			continue
+		}
+		_, err := p.app.service.CallWithResult(p.app.ctx, "calendar", "create_event", services.EntityID(p.calendar), map[string]any{
+			"summary":         e.summary,
+			"description":     e.description,
+			"start_date_time": e.start.Format(time.RFC3339),
+			"end_date_time":   e.start.Add(time.Minute).Format(time.RFC3339),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("creating %q: %w", e.summary, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		// Left unrecorded, so the next change retries the whole difference.
+		p.last = nil
+		return err
+	}
+	p.last = planned
+	return nil
+}
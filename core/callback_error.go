@@ -0,0 +1,116 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is a panic recovered from a callback: an automation's action or
+// condition, an AfterFunc, a lifecycle hook or a subscription handler. The app
+// carries on without the run that panicked.
+type PanicError struct {
+	// Value is what was passed to panic.
+	Value any
+
+	// Stack is the stack of the goroutine that panicked, as it was then.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic: %v", e.Value) }
+
+// Unwrap returns the value panicked with, if it was an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// AutomationMeta says which callback failed, for OnCallbackError.
+type AutomationMeta struct {
+	// Name is the automation's name, or for a callback outside any
+	// automation, what it is, such as "AfterFunc" or "OnReady hook 2".
+	Name string
+
+	// ID is the automation's ID, and empty outside one.
+	ID string
+
+	// Event is what triggered the run. It is the zero Event for a schedule,
+	// and outside an automation.
+	Event Event
+}
+
+// callbackErrors holds the OnCallbackError hooks.
+type callbackErrors struct {
+	mu  sync.Mutex
+	fns []func(err error, meta AutomationMeta)
+}
+
+// OnCallbackError calls fn whenever a callback fails, to alert someone that an
+// automation has crashed rather than leave it to the log. It is told of
+//
+//   - an automation's action returning an error,
+//   - an OnReady or OnStop hook returning one, and
+//   - a panic in any callback the app runs, as a *PanicError.
+//
+// A panic is recovered and logged with its stack whether or not there is a
+// hook, and the app carries on: an automation that panicked runs again at its
+// next trigger. fn runs on the goroutine that failed, so it should hand off
+// anything slow. A panic in fn itself is logged and goes no further.
+func (app *App) OnCallbackError(fn func(err error, meta AutomationMeta)) {
+	app.callbackErrors.mu.Lock()
+	defer app.callbackErrors.mu.Unlock()
+	app.callbackErrors.fns = append(app.callbackErrors.fns, fn)
+}
+
+// reportCallbackError hands a failure to the OnCallbackError hooks.
+func (app *App) reportCallbackError(err error, meta AutomationMeta) {
+	app.callbackErrors.mu.Lock()
+	fns := append([]func(error, AutomationMeta){}, app.callbackErrors.fns...)
+	app.callbackErrors.mu.Unlock()
+
+	for _, fn := range fns {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					app.log.Error("OnCallbackError hook panicked", "panic", v, "stack", string(debug.Stack()))
+				}
+			}()
+			fn(err, meta)
+		}()
+	}
+}
+
+// protect runs a callback outside any automation, recovering a panic in it.
+func (app *App) protect(meta AutomationMeta, fn func()) {
+	if err := guarded(fn); err != nil {
+		app.log.Error("Callback panicked", append([]any{"callback", meta.Name}, failureAttrs(err)...)...)
+		app.reportCallbackError(err, meta)
+	}
+}
+
+// guarded runs fn, returning a panic in it as a *PanicError.
+func guarded(fn func()) (err error) {
+	defer recoverPanic(&err)
+	fn()
+	return nil
+}
+
+// recoverPanic turns a panic under way into a *PanicError in err. It has to be
+// deferred directly, as recover only stops a panic from the deferred call.
+func recoverPanic(err *error) {
+	if v := recover(); v != nil {
+		*err = &PanicError{Value: v, Stack: debug.Stack()}
+	}
+}
+
+// failureAttrs are the log attributes of a failed callback: the error, and for
+// a panic where it happened.
+func failureAttrs(err error) []any {
+	attrs := []any{"error", err}
+	var p *PanicError
+	if errors.As(err, &p) {
+		attrs = append(attrs, "stack", string(p.Stack))
+	}
+	return attrs
+}
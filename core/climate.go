@@ -0,0 +1,41 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/Xevion/go-ha/types"
+)
+
+// climateLimits answers the climate service's checks from the state cache and
+// Home Assistant's configuration.
+type climateLimits struct {
+	state  *state
+	config *homeConfig
+}
+
+func (c *climateLimits) TemperatureUnit() (types.TemperatureUnit, error) {
+	config, err := c.config.get()
+	if err != nil {
+		return "", err
+	}
+	if config.UnitSystem.Temperature == "" {
+		return "", fmt.Errorf("config names no temperature unit")
+	}
+	return config.UnitSystem.Temperature, nil
+}
+
+// TemperatureRange reads the entity's limits from the cache alone. A check
+// that needed a round trip first would delay every setpoint for a lookup that
+// rarely finds anything wrong.
+func (c *climateLimits) TemperatureRange(entityID string) (float32, float32, bool) {
+	es, found, _ := c.state.cache.lookup(entityID)
+	if !found {
+		return 0, 0, false
+	}
+	minTemp, okMin := es.Attributes["min_temp"].(float64)
+	maxTemp, okMax := es.Attributes["max_temp"].(float64)
+	if !okMin || !okMax {
+		return 0, 0, false
+	}
+	return float32(minTemp), float32(maxTemp), true
+}
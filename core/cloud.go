@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cloud connection states, as cloud/status reports them.
+const (
+	CloudConnected    = "connected"
+	CloudConnecting   = "connecting"
+	CloudDisconnected = "disconnected"
+)
+
+// remoteUIEntity follows the remote UI tunnel. Watching it lets a drop be seen
+// as it happens rather than at the next poll.
+const remoteUIEntity = "binary_sensor.remote_ui"
+
+// cloudPollInterval is how often the cloud connection is checked for changes
+// that nothing announces.
+const cloudPollInterval = time.Minute
+
+// CloudStatus is Home Assistant Cloud's view of its own connection.
+type CloudStatus struct {
+	// LoggedIn reports whether a Home Assistant Cloud account is signed in.
+	// Nothing else here means anything without one.
+	LoggedIn bool `json:"logged_in"`
+
+	// Cloud is the connection to the cloud service: CloudConnected,
+	// CloudConnecting or CloudDisconnected.
+	Cloud string `json:"cloud"`
+
+	// RemoteEnabled reports whether remote UI access is switched on.
+	RemoteEnabled bool `json:"remote_enabled"`
+
+	// RemoteConnected reports whether the remote UI tunnel is up.
+	RemoteConnected bool `json:"remote_connected"`
+}
+
+// Connected reports whether the cloud connection is up.
+func (s CloudStatus) Connected() bool {
+	return s.LoggedIn && s.Cloud == CloudConnected
+}
+
+// CloudStatus asks Home Assistant Cloud how its connection stands.
+func (app *App) CloudStatus() (CloudStatus, error) {
+	raw, err := app.Command(map[string]any{"type": "cloud/status"})
+	if err != nil {
+		return CloudStatus{}, err
+	}
+
+	var status CloudStatus
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return CloudStatus{}, fmt.Errorf("decoding cloud status: %w", err)
+	}
+
+	// Home Assistant leaves the connection fields out entirely when nobody is
+	// signed in, which reads as connected to nothing.
+	if status.Cloud == "" {
+		status.Cloud = CloudDisconnected
+	}
+	return status, nil
+}
+
+// OnCloudConnectionChange calls fn whenever the cloud connection or the remote
+// UI tunnel changes state, with the status it changed to. Use it to fall back,
+// say, to a notification channel that does not depend on the cloud.
+//
+// Changes are checked for every minute, and at once when the remote UI sensor
+// changes. The status found at startup is the baseline, not a change.
+func (app *App) OnCloudConnectionChange(fn func(CloudStatus)) error {
+	var mu sync.Mutex
+	var last *CloudStatus
+
+	return app.RegisterAutomations(
+		NewAutomation("cloud connection").
+			On(AtStartup(), Every(cloudPollInterval), StateChanged(remoteUIEntity)).
+			Mode(ModeQueued).
+			Do(func(context.Context, Run) error {
+				status, err := app.CloudStatus()
+				if err != nil {
+					return err
+				}
+
+				mu.Lock()
+				changed := last != nil &&
+					(last.Connected() != status.Connected() || last.RemoteConnected != status.RemoteConnected)
+				last = &status
+				mu.Unlock()
+
+				if changed {
+					fn(status)
+				}
+				return nil
+			}).
+			MustBuild(),
+	)
+}
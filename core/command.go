@@ -0,0 +1,129 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Xevion/go-ha/internal/connect"
+)
+
+// rawCommand is a websocket command built by the caller rather than by one of
+// the typed wrappers.
+type rawCommand map[string]any
+
+func (c rawCommand) SetID(id int64) { c["id"] = id }
+
+// toCommand turns whatever the caller built into a command map. Anything that
+// encodes to a JSON object will do, a struct with json tags or a plain map,
+// but it has to name its type.
+func toCommand(msg any) (rawCommand, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: encoding command: %w", ErrInvalidArgs, err)
+	}
+
+	var cmd rawCommand
+	if err := json.Unmarshal(data, &cmd); err != nil || cmd == nil {
+		return nil, fmt.Errorf("%w: a command must encode to a JSON object", ErrInvalidArgs)
+	}
+	if t, _ := cmd["type"].(string); t == "" {
+		return nil, fmt.Errorf("%w: a command needs a type", ErrInvalidArgs)
+	}
+
+	// Ids belong to the connection, which stamps its own.
+	delete(cmd, "id")
+	return cmd, nil
+}
+
+// writeVerbs are the verbs that open the names of commands that change Home
+// Assistant, such as call_service, or config/area_registry/create and
+// lovelace/config/save: the protocol does not mark them, but it names them
+// consistently.
+var writeVerbs = []string{
+	"call", "fire", "execute",
+	"create", "update", "delete", "remove", "save", "set", "import",
+	"enable", "disable", "reload", "restart", "clear", "dismiss", "generate",
+}
+
+// writes reports whether the command changes Home Assistant, judged by the
+// verb its type's last segment starts with.
+func (c rawCommand) writes() bool {
+	t, _ := c["type"].(string)
+	verb := t[strings.LastIndex(t, "/")+1:]
+	verb, _, _ = strings.Cut(verb, "_")
+	return slices.Contains(writeVerbs, verb)
+}
+
+// Command sends any websocket command and returns its result, for the many
+// commands without a typed wrapper, such as config/automation/list or
+// lovelace/config. A command Home Assistant refuses is returned as an error,
+// as is one it does not answer within the app's ServiceTimeout. A read-only
+// app refuses the commands that write, such as call_service or
+// calendar/event/delete, with ErrReadOnly, and a dry-run app skips them,
+// listing them in DryRunCalls, and returns a null result.
+func (app *App) Command(msg any) (json.RawMessage, error) {
+	cmd, err := toCommand(msg)
+	if err != nil {
+		return nil, err
+	}
+	if app.readOnly && cmd.writes() {
+		return nil, readOnlySender{log: app.log}.refuse(cmd)
+	}
+	if app.dryRun != nil && cmd.writes() {
+		return json.RawMessage("null"), app.dryRun.skip(cmd)
+	}
+
+	waiting := resultSender{client: app.client, ctx: app.ctx, timeout: app.serviceTimeout}
+	return waiting.SendForResult(app.ctx, cmd)
+}
+
+// resultOf extracts the result a successful answer carries.
+func resultOf(answer connect.Message) (json.RawMessage, error) {
+	var body struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(answer.Raw, &body); err != nil {
+		return nil, fmt.Errorf("decoding result: %w", err)
+	}
+	return body.Result, nil
+}
+
+// RawSubscription is a stream opened with App.Subscribe.
+type RawSubscription struct {
+	handle connect.Handle
+}
+
+// Cancel ends the stream. The handler is not called again once it returns.
+func (s RawSubscription) Cancel() error {
+	return s.handle.Cancel()
+}
+
+// Subscribe sends a command that opens a stream, such as subscribe_events or
+// subscribe_trigger, and calls handler with the payload of each message it
+// delivers. Like every other subscription it survives a reconnect.
+//
+// A refused command is returned as an error. The handler runs on a worker
+// goroutine, so it may block without stalling the connection.
+func (app *App) Subscribe(msg any, handler func(json.RawMessage)) (RawSubscription, error) {
+	cmd, err := toCommand(msg)
+	if err != nil {
+		return RawSubscription{}, err
+	}
+
+	h, err := app.client.Watch(app.ctx, connect.Subscription{Command: cmd}, func(m connect.Message) {
+		var body struct {
+			Event json.RawMessage `json:"event"`
+		}
+		if err := json.Unmarshal(m.Raw, &body); err != nil {
+			app.log.Error("Failed to decode a subscription message", "err", err)
+			return
+		}
+		app.protect(AutomationMeta{Name: fmt.Sprintf("subscription to %s", cmd["type"])}, func() { handler(body.Event) })
+	})
+	if err != nil {
+		return RawSubscription{}, err
+	}
+	return RawSubscription{handle: h}, nil
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/Xevion/go-ha/types"
 )
@@ -27,6 +28,18 @@ type Condition interface {
 	Eval(ctx context.Context, ec EvalContext) (bool, error)
 }
 
+// EntityReadError reports a condition that could not read the entity it
+// depends on. It names the entity so a skipped run can say which one was to
+// blame, and unwraps to the underlying failure.
+type EntityReadError struct {
+	EntityID string
+	Err      error
+}
+
+func (e *EntityReadError) Error() string { return fmt.Sprintf("reading %s: %v", e.EntityID, e.Err) }
+
+func (e *EntityReadError) Unwrap() error { return e.Err }
+
 // ConditionFunc adapts a plain function to Condition.
 type ConditionFunc func(ctx context.Context, ec EvalContext) (bool, error)
 
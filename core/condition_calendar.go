@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Xevion/go-ha/internal"
+)
+
+// calendarCondition reads a calendar entity, which is on while one of its
+// events is underway and carries that event, or else the next, in its
+// attributes. Only the one event shows: of several overlapping, Home
+// Assistant picks which.
+type calendarCondition struct {
+	calendar  string
+	summaries []string
+
+	// lead, when set, makes the condition hold from that long before the
+	// event starts rather than only while it is underway.
+	lead time.Duration
+}
+
+// CalendarEventActive holds while an event is underway on the calendar, such
+// as a day off on a holidays calendar or a shift on a work one. Given
+// summaries, only an event whose summary contains one of them counts,
+// compared without regard to case.
+func CalendarEventActive[T EntityRef](calendar T, summaries ...string) Condition {
+	return calendarCondition{calendar: string(calendar), summaries: summaries}
+}
+
+// CalendarEventWithin holds from lead before an event on the calendar starts
+// until it ends, to warm the office before a working day or wake the house
+// before an early flight. Summaries narrow the events as CalendarEventActive's
+// do.
+func CalendarEventWithin[T EntityRef](calendar T, lead time.Duration, summaries ...string) Condition {
+	return calendarCondition{calendar: string(calendar), summaries: summaries, lead: lead}
+}
+
+func (c calendarCondition) Eval(_ context.Context, ec EvalContext) (bool, error) {
+	entity, err := ec.State.Get(c.calendar)
+	if err != nil {
+		return false, &EntityReadError{EntityID: c.calendar, Err: err}
+	}
+	if !c.matches(entity) {
+		return false, nil
+	}
+	if entity.State == "on" {
+		return true, nil
+	}
+	if c.lead <= 0 || entity.State != "off" {
+		return false, nil
+	}
+
+	// Home Assistant gives the next event's start in its own zone, without
+	// saying which, so it is read in the clock's.
+	now := ec.Clock.Now()
+	raw, _ := entity.Attributes["start_time"].(string)
+	if raw == "" {
+		return false, nil
+	}
+	start, err := time.ParseInLocation(time.DateTime, raw, now.Location())
+	if err != nil {
+		return false, &EntityReadError{EntityID: c.calendar, Err: fmt.Errorf("reading start_time: %w", err)}
+	}
+	return !now.Before(start.Add(-c.lead)), nil
+}
+
+// matches reports whether the calendar's event is one of those asked about.
+func (c calendarCondition) matches(entity EntityState) bool {
+	if len(c.summaries) == 0 {
+		return true
+	}
+	summary, _ := entity.Attributes["message"].(string)
+	summary = strings.ToLower(summary)
+	for _, s := range c.summaries {
+		if strings.Contains(summary, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c calendarCondition) validate() error {
+	if err := validateEntityID(c.calendar); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(c.calendar, "calendar.") {
+		return fmt.Errorf("%w: %q is not a calendar entity", ErrInvalidArgs, c.calendar)
+	}
+	if c.lead < 0 {
+		return fmt.Errorf("%w: negative lead %s before events on %s", ErrInvalidArgs, c.lead, c.calendar)
+	}
+	return nil
+}
+
+func (c calendarCondition) referencedEntities() []string { return []string{c.calendar} }
+
+func (c calendarCondition) String() string {
+	s := "event on " + c.calendar
+	if len(c.summaries) > 0 {
+		s += fmt.Sprintf(" matching %v", c.summaries)
+	}
+	if c.lead > 0 {
+		s += " within " + internal.FormatDuration(c.lead)
+	}
+	return s
+}
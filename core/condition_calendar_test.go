@@ -0,0 +1,76 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// calendar is a calendar entity showing one event, as Home Assistant's do.
+func calendar(id, state, summary, start string) EntityState {
+	es := entity(id, state)
+	es.Attributes = map[string]any{"message": summary, "start_time": start}
+	return es
+}
+
+func TestCalendarEventActive(t *testing.T) {
+	s := stateWith(
+		calendar("calendar.holidays", "on", "Easter Monday", "2026-03-01 00:00:00"),
+		calendar("calendar.work", "off", "Standup", "2026-03-01 13:00:00"),
+	)
+
+	got, err := evalAgainst(t, CalendarEventActive("calendar.holidays"), s)
+	require.NoError(t, err)
+	assert.True(t, got)
+
+	got, err = evalAgainst(t, CalendarEventActive("calendar.holidays", "christmas", "easter"), s)
+	require.NoError(t, err)
+	assert.True(t, got, "summaries match without regard to case")
+
+	got, err = evalAgainst(t, CalendarEventActive("calendar.holidays", "christmas"), s)
+	require.NoError(t, err)
+	assert.False(t, got)
+
+	got, err = evalAgainst(t, CalendarEventActive("calendar.work"), s)
+	require.NoError(t, err)
+	assert.False(t, got, "the next event has not started")
+
+	_, err = evalAgainst(t, CalendarEventActive("calendar.missing"), s)
+	var readErr *EntityReadError
+	assert.ErrorAs(t, err, &readErr)
+}
+
+func TestCalendarEventWithin(t *testing.T) {
+	// The test clock reads noon; the standup is at one.
+	s := stateWith(calendar("calendar.work", "off", "Standup", "2026-03-01 13:00:00"))
+
+	got, err := evalAgainst(t, CalendarEventWithin("calendar.work", 30*time.Minute), s)
+	require.NoError(t, err)
+	assert.False(t, got)
+
+	got, err = evalAgainst(t, CalendarEventWithin("calendar.work", time.Hour), s)
+	require.NoError(t, err)
+	assert.True(t, got)
+
+	got, err = evalAgainst(t, CalendarEventWithin("calendar.work", time.Hour, "retro"), s)
+	require.NoError(t, err)
+	assert.False(t, got)
+
+	s = stateWith(calendar("calendar.work", "off", "", ""))
+	got, err = evalAgainst(t, CalendarEventWithin("calendar.work", time.Hour), s)
+	require.NoError(t, err)
+	assert.False(t, got, "no event coming up")
+}
+
+func TestCalendarConditionsValidate(t *testing.T) {
+	for _, c := range []Condition{
+		CalendarEventActive("light.kitchen"),
+		CalendarEventWithin("calendar.work", -time.Minute),
+	} {
+		v := c.(interface{ validate() error })
+		assert.ErrorIs(t, v.validate(), ErrInvalidArgs, "%v", c)
+	}
+	assert.NoError(t, CalendarEventWithin("calendar.work", time.Hour).(interface{ validate() error }).validate())
+}
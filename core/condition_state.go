@@ -29,11 +29,15 @@ func StateIsNot[T EntityRef](entityID T, state string) Condition {
 func (c stateIsCondition) Eval(_ context.Context, ec EvalContext) (bool, error) {
 	entity, err := ec.State.Get(c.entityID)
 	if err != nil {
-		return false, fmt.Errorf("reading %s: %w", c.entityID, err)
+		return false, &EntityReadError{EntityID: c.entityID, Err: err}
 	}
 	return slices.Contains(c.states, entity.State), nil
 }
 
+func (c stateIsCondition) validate() error { return validateEntityID(c.entityID) }
+
+func (c stateIsCondition) referencedEntities() []string { return []string{c.entityID} }
+
 func (c stateIsCondition) String() string {
 	return fmt.Sprintf("%s is %v", c.entityID, c.states)
 }
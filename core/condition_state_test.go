@@ -63,6 +63,11 @@ func TestStateIsIsUndecidedForAnUnknownEntity(t *testing.T) {
 
 	_, err := evalAgainst(t, StateIs("light.missing", "on"), s)
 	assert.ErrorIs(t, err, internal.ErrEntityNotFound)
+
+	// The entity is named, so the warning can say which one was to blame.
+	var read *EntityReadError
+	require.ErrorAs(t, err, &read)
+	assert.Equal(t, "light.missing", read.EntityID)
 }
 
 func TestStateIsNotIsUndecidedForAnUnknownEntity(t *testing.T) {
@@ -1,9 +1,13 @@
 package core
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/Xevion/go-ha/internal"
 )
 
 // ErrInvalidTimeOfDay reports an hour or minute outside a real clock face.
@@ -30,6 +34,39 @@ func (c ClockTime) String() string {
 	return fmt.Sprintf("%02d:%02d", c.hour, c.minute)
 }
 
+// Hour reports the hour, 0 to 23.
+func (c ClockTime) Hour() int { return c.hour }
+
+// Minute reports the minute, 0 to 59.
+func (c ClockTime) Minute() int { return c.minute }
+
+// Compare returns -1, 0 or +1 as c falls before, at or after other on the
+// clock face. Neither has a date, so 23:00 is after 01:00 rather than before
+// the next day's.
+func (c ClockTime) Compare(other ClockTime) int {
+	return cmp.Compare(c.minuteOfDay(), other.minuteOfDay())
+}
+
+// Before reports whether c falls earlier on the clock face than other.
+func (c ClockTime) Before(other ClockTime) bool { return c.Compare(other) < 0 }
+
+// After reports whether c falls later on the clock face than other.
+func (c ClockTime) After(other ClockTime) bool { return c.Compare(other) > 0 }
+
+// Equal reports whether both name the same minute.
+func (c ClockTime) Equal(other ClockTime) bool { return c.Compare(other) == 0 }
+
+// On returns the instant this time falls on date's calendar day, in date's
+// location.
+//
+// It is built from the date and zone rather than by adjusting an instant, so a
+// daylight saving transition cannot shift it. A time the spring-forward jump
+// skips resolves to the instant the clock lands on, and a time the fall-back
+// repeats resolves to its first pass.
+func (c ClockTime) On(date time.Time) time.Time {
+	return internal.WallClock(date, c.hour, c.minute)
+}
+
 // minuteOfDay is this time as minutes since midnight.
 func (c ClockTime) minuteOfDay() int {
 	return c.hour*60 + c.minute
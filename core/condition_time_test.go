@@ -155,3 +155,32 @@ func TestTimeBetweenHandlesTheRepeatedFallBackHour(t *testing.T) {
 		assert.True(t, got, "01:30 reads the same on both passes through the hour (%v)", at)
 	}
 }
+
+func TestClockTimeComparison(t *testing.T) {
+	early, late := TimeOfDay(1, 0), TimeOfDay(23, 0)
+
+	assert.True(t, early.Before(late))
+	assert.True(t, late.After(early))
+	assert.False(t, early.After(late))
+	assert.True(t, early.Equal(TimeOfDay(1, 0)))
+	assert.Equal(t, 0, late.Compare(TimeOfDay(23, 0)))
+	assert.Equal(t, -1, early.Compare(late))
+
+	assert.Equal(t, 23, late.Hour())
+	assert.Equal(t, 0, late.Minute())
+}
+
+// On builds the instant from the date and zone, so the spring-forward gap
+// cannot move it earlier than the reading it names.
+func TestClockTimeOnResolvesTheGap(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+
+	date := time.Date(2026, 3, 8, 12, 0, 0, 0, chicago)
+
+	got := TimeOfDay(2, 15).On(date)
+	assert.Equal(t, "03:00", got.Format("15:04"))
+	assert.True(t, got.After(TimeOfDay(1, 45).On(date)))
+
+	assert.Equal(t, time.Date(2026, 3, 8, 18, 30, 0, 0, chicago), TimeOfDay(18, 30).On(date))
+}
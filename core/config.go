@@ -0,0 +1,94 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Xevion/go-ha/types"
+)
+
+// Config is Home Assistant's core configuration: where the home is, the units
+// its entities report in, and the version it runs.
+type Config struct {
+	LocationName string  `json:"location_name"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+
+	// Elevation is the home's height above sea level, in metres.
+	Elevation float64 `json:"elevation"`
+
+	// TimeZone is the zone's IANA name, such as Europe/Amsterdam.
+	TimeZone string `json:"time_zone"`
+
+	UnitSystem UnitSystem `json:"unit_system"`
+
+	// Country and Currency are ISO codes, and Language the installation's
+	// language tag. Any may be empty on an installation set up before Home
+	// Assistant asked for them.
+	Country  string `json:"country"`
+	Currency string `json:"currency"`
+	Language string `json:"language"`
+
+	Version string `json:"version"`
+}
+
+// UnitSystem is the unit Home Assistant reports each kind of measurement in,
+// as the symbol it uses, such as km or °C.
+type UnitSystem struct {
+	Temperature              types.TemperatureUnit `json:"temperature"`
+	Length                   string                `json:"length"`
+	Mass                     string                `json:"mass"`
+	Volume                   string                `json:"volume"`
+	Area                     string                `json:"area"`
+	Pressure                 string                `json:"pressure"`
+	WindSpeed                string                `json:"wind_speed"`
+	AccumulatedPrecipitation string                `json:"accumulated_precipitation"`
+}
+
+// homeConfig fetches the configuration with get_config and keeps it. It
+// changes only when someone edits it in Home Assistant, which is rare enough
+// not to watch for.
+type homeConfig struct {
+	sender resultSender
+
+	mu     sync.Mutex
+	config *Config
+}
+
+// get returns the configuration, fetching it the first time. A fetch that
+// fails is tried again by the next call.
+func (c *homeConfig) get() (Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config != nil {
+		return *c.config, nil
+	}
+	raw, err := c.sender.SendForResult(c.sender.ctx, rawCommand{"type": "get_config"})
+	if err != nil {
+		return Config{}, fmt.Errorf("reading Home Assistant's config: %w", err)
+	}
+	var config Config
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return Config{}, fmt.Errorf("decoding config: %w", err)
+	}
+	c.config = &config
+	return config, nil
+}
+
+// Config returns Home Assistant's core configuration. It is fetched as the app
+// connects and kept, so only a call made before then, or after that fetch
+// failed, waits on Home Assistant.
+func (app *App) Config() (Config, error) {
+	return app.config.get()
+}
+
+// homeLocation is the home's position for the triggers that compute solar
+// times. Like the sun.sun a published time is read from, it is fetched when
+// not yet known, and a trigger asked for its time before the app has
+// connected waits for it.
+func (app *App) homeLocation() (float64, float64, bool) {
+	config, err := app.config.get()
+	return config.Latitude, config.Longitude, err == nil
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnectionStats reports how the websocket connection has been coping with
+// load since the app started.
+type ConnectionStats struct {
+	// Dropped counts events discarded because handlers could not keep up.
+	Dropped uint64
+
+	// WriteStall is the total time outgoing messages spent blocked on a slow
+	// socket. It stays at zero while the connection is healthy.
+	WriteStall time.Duration
+
+	// WriteTimeouts counts writes that overran the write timeout, each of
+	// which cost a reconnect.
+	WriteTimeouts uint64
+
+	// Undecodable counts messages and events that could not be decoded, and
+	// so were handed to NewAppRequest.OnUndecodable instead of dispatched. A
+	// count that climbs after a Home Assistant upgrade points at a protocol
+	// change.
+	Undecodable uint64
+}
+
+// ConnectionStats reports the connection's load counters.
+func (app *App) ConnectionStats() ConnectionStats {
+	return ConnectionStats{
+		Dropped:       app.client.Dropped(),
+		WriteStall:    app.client.WriteStall(),
+		WriteTimeouts: app.client.WriteTimeouts(),
+		Undecodable:   app.undecodable.count.Load(),
+	}
+}
+
+// undecodableReportInterval is the shortest gap between two warnings about
+// undecodable messages. Every one is counted and handed to the hook; only the
+// logging is held back, so a protocol change does not flood the log.
+const undecodableReportInterval = time.Minute
+
+// deadLetters receives every message that could not be decoded: it counts it,
+// hands it to the user's hook, and logs now and then.
+type deadLetters struct {
+	hook func(raw []byte, err error)
+	log  *slog.Logger
+
+	// report hands a panic in the hook to the app's OnCallbackError hooks.
+	// It is set once the app exists, before anything is read.
+	report func(err error, meta AutomationMeta)
+	count  atomic.Uint64
+
+	mu    sync.Mutex
+	since int
+	last  time.Time
+}
+
+// record takes one undecodable message. It runs on the reader goroutine, so
+// the hook must not block.
+func (d *deadLetters) record(raw []byte, err error) {
+	d.count.Add(1)
+	if d.hook != nil {
+		if perr := guarded(func() { d.hook(raw, err) }); perr != nil {
+			d.log.Error("OnUndecodable hook panicked", failureAttrs(perr)...)
+			if d.report != nil {
+				d.report(perr, AutomationMeta{Name: "OnUndecodable"})
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.since++
+	now := time.Now()
+	if !d.last.IsZero() && now.Sub(d.last) < undecodableReportInterval {
+		return
+	}
+	d.last = now
+	d.log.Warn("Discarding undecodable messages", "count", d.since, "error", err)
+	d.since = 0
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DayRule picks days of the month, as cron's day fields do, for narrowing a
+// trigger with OnlyOn. Build one with FirstWeekday, LastWeekday, NthWeekday or
+// DayOfMonth.
+type DayRule struct {
+	label string
+	match func(day time.Time) bool
+	err   error
+}
+
+// NthWeekday is the nth of the given weekday in each month, counting from one.
+// A month has four or five of each, so n runs from 1 to 5, and a month with
+// only four is passed over when n is 5.
+func NthWeekday(n int, day time.Weekday) DayRule {
+	label := fmt.Sprintf("%s %s", ordinal(n), day)
+	if n < 1 || n > 5 {
+		return DayRule{label: label, err: fmt.Errorf("%w: NthWeekday takes 1 to 5, not %d", ErrInvalidArgs, n)}
+	}
+	return DayRule{label: label, match: func(d time.Time) bool {
+		return d.Weekday() == day && (d.Day()-1)/7+1 == n
+	}}
+}
+
+// FirstWeekday is the first of the given weekday in each month.
+func FirstWeekday(day time.Weekday) DayRule { return NthWeekday(1, day) }
+
+// LastWeekday is the last of the given weekday in each month, whether that is
+// the fourth or the fifth.
+func LastWeekday(day time.Weekday) DayRule {
+	return DayRule{label: "last " + day.String(), match: func(d time.Time) bool {
+		return d.Weekday() == day && d.AddDate(0, 0, 7).Month() != d.Month()
+	}}
+}
+
+// DayOfMonth is the nth day of each month. Months too short for it are passed
+// over, as cron passes over them.
+func DayOfMonth(n int) DayRule {
+	label := ordinal(n) + " of the month"
+	if n < 1 || n > 31 {
+		return DayRule{label: label, err: fmt.Errorf("%w: DayOfMonth takes 1 to 31, not %d", ErrInvalidArgs, n)}
+	}
+	return DayRule{label: label, match: func(d time.Time) bool { return d.Day() == n }}
+}
+
+// Matches reports whether the rule picks the calendar day t falls on.
+func (r DayRule) Matches(t time.Time) bool {
+	return r.err == nil && r.match != nil && r.match(t)
+}
+
+func (r DayRule) String() string { return r.label }
+
+// dayRules are the rules a trigger is narrowed to. A day matching any of them
+// is kept.
+type dayRules []DayRule
+
+func (rules dayRules) matches(t time.Time) bool {
+	for _, r := range rules {
+		if r.Matches(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextDay finds the first day after the one t falls on that a rule picks, as
+// midnight in t's location. Every rule picks a day at least once a year, so
+// the search stops after one.
+func (rules dayRules) nextDay(t time.Time) (time.Time, bool) {
+	y, m, d := t.Date()
+	for i := 1; i <= searchDays; i++ {
+		day := time.Date(y, m, d+i, 0, 0, 0, 0, t.Location())
+		if rules.matches(day) {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (rules dayRules) validate() error {
+	if rules != nil && len(rules) == 0 {
+		return fmt.Errorf("%w: OnlyOn needs at least one rule", ErrInvalidArgs)
+	}
+	for _, r := range rules {
+		if r.err != nil {
+			return r.err
+		}
+		if r.match == nil {
+			return fmt.Errorf("%w: OnlyOn given a zero DayRule", ErrInvalidArgs)
+		}
+	}
+	return nil
+}
+
+func (rules dayRules) String() string {
+	labels := make([]string, len(rules))
+	for i, r := range rules {
+		labels[i] = "the " + r.label
+	}
+	return strings.Join(labels, " or ")
+}
+
+// ordinal renders 1 as "1st", 2 as "2nd" and so on.
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
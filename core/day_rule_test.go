@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDayRules(t *testing.T) {
+	// October 2026 starts on a Thursday; its Fridays are the 2nd, 9th, 16th,
+	// 23rd and 30th.
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 12, 0, 0, 0, time.Local) }
+
+	for _, tt := range []struct {
+		rule DayRule
+		yes  []int
+		no   []int
+	}{
+		{FirstWeekday(time.Friday), []int{2}, []int{1, 9, 30}},
+		{NthWeekday(2, time.Friday), []int{9}, []int{2, 16}},
+		{NthWeekday(5, time.Friday), []int{30}, []int{23}},
+		{LastWeekday(time.Friday), []int{30}, []int{23, 31}},
+		{LastWeekday(time.Saturday), []int{31}, []int{24}},
+		{DayOfMonth(15), []int{15}, []int{14, 16}},
+	} {
+		t.Run(tt.rule.String(), func(t *testing.T) {
+			for _, d := range tt.yes {
+				assert.True(t, tt.rule.Matches(day(d)), "October %d", d)
+			}
+			for _, d := range tt.no {
+				assert.False(t, tt.rule.Matches(day(d)), "October %d", d)
+			}
+		})
+	}
+
+	assert.Equal(t, "2nd Friday", fmt.Sprint(NthWeekday(2, time.Friday)))
+	assert.Equal(t, "last Sunday", fmt.Sprint(LastWeekday(time.Sunday)))
+	assert.Equal(t, "23rd of the month", fmt.Sprint(DayOfMonth(23)))
+}
+
+func TestDayRulesAreChecked(t *testing.T) {
+	for _, trig := range []SunTrigger{
+		Sunset().OnlyOn(NthWeekday(6, time.Monday)),
+		Sunset().OnlyOn(DayOfMonth(0)),
+		Sunset().OnlyOn(),
+		Sunset().OnlyOn(DayRule{}),
+	} {
+		_, err := NewAutomation("monthly").On(trig).Do(noAction).Build()
+		assert.ErrorIs(t, err, ErrInvalidArgs, "%v", trig)
+	}
+}
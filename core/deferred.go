@@ -0,0 +1,248 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
+)
+
+// deferredCall is one call Service.At queued, as it is kept on disk. Exactly
+// one of Call and Event is set.
+type deferredCall struct {
+	At    time.Time                    `json:"at"`
+	Call  *services.BaseServiceRequest `json:"call,omitempty"`
+	Event *services.FireEventRequest   `json:"event,omitempty"`
+}
+
+func (c deferredCall) request() types.Request {
+	if c.Call != nil {
+		return c.Call
+	}
+	return c.Event
+}
+
+// deferredCalls holds the calls still due, soonest first, and makes each when
+// its time comes. With a path, they are written out on every change and read
+// back by the next app, so a restart does not lose them.
+type deferredCalls struct {
+	send  services.Sender
+	clock Clock
+	log   *slog.Logger
+	path  string
+
+	// readOnly refuses calls as they are queued, for a read-only app.
+	readOnly bool
+
+	// wake tells the loop the soonest call may have changed.
+	wake chan struct{}
+
+	mu      sync.Mutex
+	pending []deferredCall
+}
+
+func newDeferredCalls(send services.Sender, clock Clock, log *slog.Logger, path string, readOnly bool) (*deferredCalls, error) {
+	d := &deferredCalls{send: send, clock: clock, log: log, path: path, readOnly: readOnly, wake: make(chan struct{}, 1)}
+	if path == "" {
+		return d, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading deferred calls: %w", err)
+	}
+	if err := json.Unmarshal(data, &d.pending); err != nil {
+		return nil, fmt.Errorf("%w: deferred calls in %s: %w", ErrInvalidArgs, path, err)
+	}
+	d.pending = slices.DeleteFunc(d.pending, func(c deferredCall) bool { return c.request() == nil })
+	d.sort()
+	return d, nil
+}
+
+// At returns the same services with every call queued to be made at t rather
+// than now: "turn the porch light off at 22:00 tonight".
+//
+//	run.Services.At(tonight).Light.TurnOff("light.porch")
+//
+// A queued call is made once, and does not repeat; Schedule is for that. A
+// call queued for a time already past is made at once. The calls return when
+// queued, so an error from Home Assistant is only logged. Targeting applies to
+// a deferred call whichever side of At it is called on.
+//
+// With NewAppRequest's DeferredCallsFile set, the queue is kept in that file,
+// and calls still due when the app stops are made once it starts again, late
+// if their time passed while it was down. Without it they are lost with the
+// app. A read-only app refuses them as it refuses any other call.
+func (s *Service) At(t time.Time) *Service {
+	at := deferSender{calls: s.deferred, at: t, targets: s.targets}
+	svc := s.clone(at, at)
+	// The deferSender applies them, so Targeting adds only its own.
+	svc.targets = nil
+	return svc
+}
+
+// deferSender queues every call it is given for its time.
+type deferSender struct {
+	calls   *deferredCalls
+	at      time.Time
+	targets []services.Target
+}
+
+func (d deferSender) Send(req types.Request) error {
+	req = targetSender{targets: d.targets}.apply(req)
+	call := deferredCall{At: d.at}
+	switch r := req.(type) {
+	case *services.BaseServiceRequest:
+		call.Call = r
+	case *services.FireEventRequest:
+		call.Event = r
+	default:
+		return fmt.Errorf("%w: cannot defer %T", ErrInvalidArgs, req)
+	}
+	return d.calls.add(call)
+}
+
+func (d deferSender) SendForResult(context.Context, types.Request) (json.RawMessage, error) {
+	return nil, fmt.Errorf("%w: a deferred call has no result to wait for", ErrInvalidArgs)
+}
+
+func (d *deferredCalls) add(call deferredCall) error {
+	// Refused now, not when it comes due, so the caller hears of it.
+	if d.readOnly {
+		return d.send.Send(call.request())
+	}
+
+	d.mu.Lock()
+	d.pending = append(d.pending, call)
+	d.sort()
+	err := d.saveLocked()
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	d.log.Info("Deferred a call", "call", call.name(), "at", call.At)
+	d.notify()
+	return nil
+}
+
+func (d *deferredCalls) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *deferredCalls) sort() {
+	slices.SortStableFunc(d.pending, func(a, b deferredCall) int { return a.At.Compare(b.At) })
+}
+
+// saveLocked writes the queue out, replacing the file whole so a crash part
+// way through leaves the last good copy.
+func (d *deferredCalls) saveLocked() error {
+	if d.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(d.pending)
+	if err != nil {
+		return fmt.Errorf("encoding deferred calls: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".*")
+	if err != nil {
+		return fmt.Errorf("saving deferred calls: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving deferred calls: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving deferred calls: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		return fmt.Errorf("saving deferred calls: %w", err)
+	}
+	return nil
+}
+
+// run makes each call as it comes due, until ctx is cancelled.
+func (d *deferredCalls) run(ctx context.Context) {
+	for {
+		d.mu.Lock()
+		var next *deferredCall
+		if len(d.pending) > 0 {
+			next = &d.pending[0]
+		}
+		var wait time.Duration
+		if next != nil {
+			wait = next.At.Sub(d.clock.Now())
+		}
+		d.mu.Unlock()
+
+		if next != nil && wait <= 0 {
+			d.makeDue()
+			continue
+		}
+
+		var fired <-chan time.Time
+		stop := func() {}
+		if next != nil {
+			fired, stop = clockTimer(d.clock, wait)
+		}
+		select {
+		case <-fired:
+		case <-d.wake:
+			stop()
+		case <-ctx.Done():
+			stop()
+			return
+		}
+	}
+}
+
+// makeDue makes every call whose time has come. Each is taken off the queue,
+// and the queue saved, before it is made: a crash in between loses the call
+// rather than making it twice.
+func (d *deferredCalls) makeDue() {
+	now := d.clock.Now()
+
+	d.mu.Lock()
+	i := 0
+	for i < len(d.pending) && !d.pending[i].At.After(now) {
+		i++
+	}
+	due := slices.Clone(d.pending[:i])
+	d.pending = slices.Delete(d.pending, 0, i)
+	err := d.saveLocked()
+	d.mu.Unlock()
+	if err != nil {
+		d.log.Error("Failed to save deferred calls", "error", err)
+	}
+
+	for _, call := range due {
+		if late := now.Sub(call.At); late > time.Minute {
+			d.log.Warn("Making a deferred call late", "call", call.name(), "at", call.At, "late", late)
+		}
+		if err := d.send.Send(call.request()); err != nil {
+			d.log.Error("Deferred call failed", "call", call.name(), "error", err)
+		}
+	}
+}
+
+// name is how the call is logged.
+func (c deferredCall) name() string {
+	if c.Call != nil {
+		return c.Call.Domain + "." + c.Call.Service
+	}
+	return "event " + c.Event.EventType
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
+)
+
+// dryRunKeep is how many skipped calls a dry-run app remembers. The oldest
+// are forgotten past it, so a long-running deployment does not grow without
+// bound.
+const dryRunKeep = 1000
+
+// DryRunCall is a write a dry-run app skipped.
+type DryRunCall struct {
+	At time.Time
+
+	// Domain and Service name the service that would have been called, and
+	// Target what it was aimed at. They are empty for an event.
+	Domain  string
+	Service string
+	Target  string
+
+	// EventType names the event that would have been fired, for an event.
+	EventType string
+
+	// Command is the type of a websocket command sent with App.Command that
+	// would have been sent. A call_service also fills in Domain, Service and
+	// Target.
+	Command string
+
+	// State is the state SetState would have published for the entity named
+	// in Target.
+	State string
+
+	// Data is the service data, the event data, the rest of a command, or the
+	// attributes of a state.
+	Data map[string]any
+}
+
+// dryRunSender skips every request as if it had succeeded, logging and
+// recording what it would have sent, so automations carry on as they would
+// in production.
+type dryRunSender struct {
+	log   *slog.Logger
+	clock Clock
+
+	mu    sync.Mutex
+	calls []DryRunCall
+}
+
+func (d *dryRunSender) Send(req types.Request) error {
+	return d.skip(req)
+}
+
+func (d *dryRunSender) SendForResult(_ context.Context, req types.Request) (json.RawMessage, error) {
+	return nil, d.skip(req)
+}
+
+func (d *dryRunSender) skip(req types.Request) error {
+	call := DryRunCall{At: d.clock.Now()}
+	switch r := req.(type) {
+	case *services.BaseServiceRequest:
+		call.Domain, call.Service, call.Target, call.Data = r.Domain, r.Service, r.Target.String(), r.ServiceData
+		d.log.Info("Dry run, not calling", "service", r.Domain+"."+r.Service, "target", call.Target, "data", r.ServiceData)
+	case *services.FireEventRequest:
+		call.EventType, call.Data = r.EventType, r.EventData
+		d.log.Info("Dry run, not firing", "event_type", r.EventType, "data", r.EventData)
+	case rawCommand:
+		call.Command, _ = r["type"].(string)
+		call.Data = map[string]any{}
+		for k, v := range r {
+			if k != "type" {
+				call.Data[k] = v
+			}
+		}
+		if call.Command == "call_service" {
+			call.Domain, _ = r["domain"].(string)
+			call.Service, _ = r["service"].(string)
+			call.Target = commandTarget(r["target"])
+			call.Data, _ = r["service_data"].(map[string]any)
+		}
+		d.log.Info("Dry run, not sending", "command", call.Command, "data", call.Data)
+	default:
+		// Nothing else is sent through a service sender. Refused rather than
+		// passed on, since a dry run must not write.
+		return fmt.Errorf("%w: dry run cannot skip %T", ErrInvalidArgs, req)
+	}
+
+	d.record(call)
+	return nil
+}
+
+// setState skips a SetState, as skip does a call.
+func (d *dryRunSender) setState(entityID, value string, attributes map[string]any) {
+	d.log.Info("Dry run, not setting state", "entity_id", entityID, "state", value, "attributes", attributes)
+	d.record(DryRunCall{At: d.clock.Now(), Target: entityID, State: value, Data: attributes})
+}
+
+func (d *dryRunSender) record(call DryRunCall) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls = append(d.calls, call)
+	if len(d.calls) > dryRunKeep {
+		d.calls = append(d.calls[:0], d.calls[len(d.calls)-dryRunKeep:]...)
+	}
+}
+
+// commandTarget reads a raw call_service's target the way ServiceTarget
+// prints one.
+func commandTarget(raw any) string {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return ""
+	}
+	var target services.ServiceTarget
+	if err := json.Unmarshal(data, &target); err != nil {
+		// entity_id given as a list, which the target only takes joined.
+		var listed struct {
+			EntityID []string `json:"entity_id"`
+			AreaID   []string `json:"area_id"`
+			DeviceID []string `json:"device_id"`
+		}
+		if json.Unmarshal(data, &listed) != nil {
+			return ""
+		}
+		target = services.ServiceTarget{EntityId: strings.Join(listed.EntityID, ", "), AreaId: listed.AreaID, DeviceId: listed.DeviceID}
+	}
+	return target.String()
+}
+
+// DryRunCalls lists the service calls, events, commands and states a dry-run
+// app skipped,
+// oldest first, up to the last thousand. It is empty for any other app.
+func (app *App) DryRunCalls() []DryRunCall {
+	if app.dryRun == nil {
+		return nil
+	}
+	app.dryRun.mu.Lock()
+	defer app.dryRun.mu.Unlock()
+	return append([]DryRunCall(nil), app.dryRun.calls...)
+}
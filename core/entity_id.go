@@ -0,0 +1,105 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownEntity reports an automation naming an entity Home Assistant does
+// not have, found at registration when the app is built with CheckEntities.
+var ErrUnknownEntity = errors.New("unknown entity")
+
+// validateEntityID checks an id has Home Assistant's domain.object_id shape:
+// lowercase letters, digits and underscores either side of one dot, with no
+// underscore leading or trailing either part and none doubled. An id without
+// that shape can never match, so a typo such as "lightliving_room" would
+// otherwise leave an automation that silently never fires.
+func validateEntityID(id string) error {
+	domain, object, ok := strings.Cut(id, ".")
+	if !ok || !validIDPart(domain) || !validIDPart(object) || strings.Contains(id, "__") {
+		return fmt.Errorf("%w: %q is not an entity id of the form domain.object_id", ErrInvalidArgs, id)
+	}
+	return nil
+}
+
+func validIDPart(part string) bool {
+	if part == "" || part[0] == '_' || part[len(part)-1] == '_' {
+		return false
+	}
+	for _, r := range part {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// entityReferrer is implemented by triggers and conditions naming entities, so
+// registration can check they exist.
+type entityReferrer interface {
+	referencedEntities() []string
+}
+
+// referencedEntities lists the entities an automation's triggers and
+// conditions name, patterns excluded, without repeats.
+func (a Automation) referencedEntities() []string {
+	var ids []string
+	add := func(x any) {
+		if r, ok := x.(entityReferrer); ok {
+			for _, id := range r.referencedEntities() {
+				if !isPattern(id) && !slices.Contains(ids, id) {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	for _, t := range a.triggers {
+		add(t)
+	}
+	var walk func(Condition)
+	walk = func(c Condition) {
+		switch v := c.(type) {
+		case allCondition:
+			for _, inner := range v.conditions {
+				walk(inner)
+			}
+		case anyCondition:
+			for _, inner := range v.conditions {
+				walk(inner)
+			}
+		case notCondition:
+			walk(v.condition)
+		default:
+			add(v)
+		}
+	}
+	walk(a.condition)
+	return ids
+}
+
+// checkEntities reports the entities an automation names that Home Assistant
+// does not have.
+func (app *App) checkEntities(a Automation) error {
+	ids := a.referencedEntities()
+	if len(ids) == 0 {
+		return nil
+	}
+	entities, err := app.state.ListEntities()
+	if err != nil {
+		return fmt.Errorf("checking the entities of %q: %w", a.name, err)
+	}
+	known := make(map[string]struct{}, len(entities))
+	for _, e := range entities {
+		known[e.EntityID] = struct{}{}
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if _, ok := known[id]; !ok {
+			errs = append(errs, fmt.Errorf("%w %q: %w %s", ErrInvalidAutomation, a.name, ErrUnknownEntity, id))
+		}
+	}
+	return errors.Join(errs...)
+}
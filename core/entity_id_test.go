@@ -0,0 +1,68 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEntityID(t *testing.T) {
+	for _, id := range []string{"light.living_room", "sensor.temp_2", "binary_sensor.door1", "a.b"} {
+		assert.NoError(t, validateEntityID(id), id)
+	}
+	for _, id := range []string{
+		"lightliving_room", // the dot forgotten
+		"light.",
+		".living_room",
+		"Light.Living_Room",
+		"light.living room",
+		"light.living-room",
+		"light._hall",
+		"light.hall_",
+		"light.living__room",
+		"light.hall.lamp",
+	} {
+		assert.ErrorIs(t, validateEntityID(id), ErrInvalidArgs, id)
+	}
+}
+
+func TestBuildRejectsMalformedEntityIDs(t *testing.T) {
+	_, err := NewAutomation("typo").On(StateChanged("lightliving_room")).Do(noAction).Build()
+	assert.ErrorIs(t, err, ErrInvalidAutomation)
+	assert.ErrorContains(t, err, "lightliving_room")
+
+	_, err = NewAutomation("typo").
+		On(StateChanged("light.hall")).
+		When(Not(StateIs("Light.Hall", "on"))).
+		Do(noAction).
+		Build()
+	assert.ErrorIs(t, err, ErrInvalidAutomation, "conditions inside combinators are checked too")
+
+	_, err = NewAutomation("pattern").On(StateChanged("light.*")).Do(noAction).Build()
+	assert.NoError(t, err, "patterns are not entity ids")
+}
+
+func TestCheckEntitiesRefusesUnknownEntities(t *testing.T) {
+	app := testApp(entity("light.hall", "off"), entity("binary_sensor.motion", "off"))
+	app.strictEntities = true
+
+	known := NewAutomation("known").
+		On(StateChanged("binary_sensor.motion", "binary_sensor.door_*")).
+		When(StateIs("light.hall", "off")).
+		Do(noAction).
+		MustBuild()
+	require.NoError(t, app.RegisterAutomations(known), "patterns may match entities added later")
+
+	unknown := NewAutomation("unknown").
+		On(StateChanged("binary_sensor.motion")).
+		When(Any(StateIs("light.porch", "off"), StateIs("light.hall", "off"))).
+		Do(noAction).
+		MustBuild()
+	err := app.RegisterAutomations(unknown)
+	assert.ErrorIs(t, err, ErrUnknownEntity)
+	assert.ErrorContains(t, err, "light.porch")
+
+	app.strictEntities = false
+	assert.NoError(t, app.RegisterAutomations(unknown), "only checked when asked for")
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"time"
+
+	"github.com/Xevion/go-ha/internal/connect"
+)
+
+// entityStream keeps the cache from Home Assistant's subscribe_entities
+// stream. Its first message on each connection is every entity in full, which
+// stands in for the REST snapshot; each after it says only what changed, in
+// the compressed form below, which is expanded against the cached state.
+//
+// It runs on the reader, so the cache takes these in wire order alongside the
+// state_changed events the automations dispatch from.
+type entityStream struct {
+	state *state
+
+	// sub is the subscription id of the connection the cache was last
+	// installed from. Ids are never reused, so a message under another is
+	// the first of a new connection.
+	sub int64
+}
+
+// entityDiff is one subscribe_entities message: entities added in full,
+// changes to existing ones, and entities removed.
+type entityDiff struct {
+	Added   map[string]compressedState  `json:"a"`
+	Changed map[string]compressedChange `json:"c"`
+	Removed []string                    `json:"r"`
+}
+
+// compressedState is an entity state with its keys shortened. Times are
+// seconds since the epoch, and last_updated is left out when it equals
+// last_changed.
+type compressedState struct {
+	State       string         `json:"s"`
+	Attributes  map[string]any `json:"a"`
+	LastChanged float64        `json:"lc"`
+	LastUpdated float64        `json:"lu"`
+}
+
+// compressedChange is what changed about an entity: values that are new or
+// different under "+", attributes that went away under "-".
+type compressedChange struct {
+	Set struct {
+		State       *string        `json:"s"`
+		Attributes  map[string]any `json:"a"`
+		LastChanged float64        `json:"lc"`
+		LastUpdated float64        `json:"lu"`
+	} `json:"+"`
+	Unset struct {
+		Attributes []string `json:"a"`
+	} `json:"-,"`
+}
+
+// subscription is what NewApp subscribes to in place of seeding the cache
+// over REST.
+func (s *entityStream) subscription(undecodable *deadLetters) connect.Subscription {
+	return connect.Subscription{
+		Command: map[string]any{"type": "subscribe_entities"},
+		Apply: func(m connect.Message) {
+			if err := s.apply(m); err != nil {
+				undecodable.record(m.Raw, err)
+			}
+		},
+	}
+}
+
+// apply folds one message into the cache. One that cannot be decoded changes
+// nothing and is returned as an error.
+func (s *entityStream) apply(m connect.Message) error {
+	var msg struct {
+		Event entityDiff `json:"event"`
+	}
+	if err := json.Unmarshal(m.Raw, &msg); err != nil {
+		return fmt.Errorf("%w: subscribe_entities: %v", ErrMalformedEvent, err)
+	}
+	diff := msg.Event
+
+	if m.ID != s.sub {
+		s.sub = m.ID
+		list := make([]EntityState, 0, len(diff.Added))
+		for id, cs := range diff.Added {
+			list = append(list, cs.expand(id))
+		}
+		s.state.install(list)
+		return nil
+	}
+
+	cache := s.state.cache
+	for id, cs := range diff.Added {
+		cache.apply(cs.expand(id))
+	}
+	for id, change := range diff.Changed {
+		// A change to an entity the cache never held has nothing to apply
+		// to. The full state arrives as an addition, never as a change.
+		if es, ok := cache.get(id); ok {
+			cache.apply(change.applyTo(es))
+		}
+	}
+	for _, id := range diff.Removed {
+		cache.remove(id)
+	}
+	return nil
+}
+
+func (cs compressedState) expand(entityID string) EntityState {
+	es := EntityState{
+		EntityID:    entityID,
+		State:       cs.State,
+		Attributes:  cs.Attributes,
+		LastChanged: epochTime(cs.LastChanged),
+	}
+	if es.Attributes == nil {
+		es.Attributes = map[string]any{}
+	}
+	es.LastUpdated = es.LastChanged
+	if cs.LastUpdated != 0 {
+		es.LastUpdated = epochTime(cs.LastUpdated)
+	}
+	return es
+}
+
+// applyTo returns es with the change made. The attributes are copied before
+// they are touched, since the cached map is shared with whoever read it.
+func (c compressedChange) applyTo(es EntityState) EntityState {
+	if c.Set.State != nil {
+		es.State = *c.Set.State
+	}
+	// A new last_changed moves last_updated with it.
+	if c.Set.LastChanged != 0 {
+		es.LastChanged = epochTime(c.Set.LastChanged)
+		es.LastUpdated = es.LastChanged
+	} else if c.Set.LastUpdated != 0 {
+		es.LastUpdated = epochTime(c.Set.LastUpdated)
+	}
+
+	if len(c.Set.Attributes) > 0 || len(c.Unset.Attributes) > 0 {
+		attrs := maps.Clone(es.Attributes)
+		if attrs == nil {
+			attrs = map[string]any{}
+		}
+		maps.Copy(attrs, c.Set.Attributes)
+		for _, name := range c.Unset.Attributes {
+			delete(attrs, name)
+		}
+		es.Attributes = attrs
+	}
+	return es
+}
+
+// epochTime converts the stream's fractional seconds, to the microsecond Home
+// Assistant keeps.
+func epochTime(seconds float64) time.Time {
+	return time.UnixMicro(int64(math.Round(seconds * 1e6))).UTC()
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Xevion/go-ha/internal/connect"
+)
+
+func streamMessage(id int64, event string) connect.Message {
+	return connect.Message{ID: id, Type: "event", Raw: []byte(`{"id":1,"type":"event","event":` + event + `}`)}
+}
+
+func TestEntityStreamExpandsDiffsIntoTheCache(t *testing.T) {
+	s, calls := stateWithServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	stream := &entityStream{state: s}
+
+	require.NoError(t, stream.apply(streamMessage(4, `{"a":{
+		"light.kitchen":{"s":"on","a":{"brightness":200,"color_mode":"hs"},"c":"01H","lc":1760000000.25},
+		"sensor.power":{"s":"12","a":{},"c":"01J","lc":1760000000,"lu":1760000060.5}}}`)))
+	require.True(t, s.cache.ready(), "the first message is the whole snapshot")
+
+	power, _ := s.cache.get("sensor.power")
+	assert.Equal(t, time.Unix(1760000060, 5e8).UTC(), power.LastUpdated)
+	kitchen, _ := s.cache.get("light.kitchen")
+	assert.Equal(t, kitchen.LastChanged, kitchen.LastUpdated, "an omitted lu is lc")
+
+	require.NoError(t, stream.apply(streamMessage(4, `{
+		"c":{"light.kitchen":{"+":{"s":"off","a":{"brightness":null},"lc":1760000100},"-":{"a":["color_mode"]}},
+		     "light.unknown":{"+":{"s":"on"}}},
+		"a":{"switch.fan":{"s":"off","a":{},"c":"01K","lc":1760000100}},
+		"r":["sensor.power"]}`)))
+
+	kitchen, _ = s.cache.get("light.kitchen")
+	assert.Equal(t, "off", kitchen.State)
+	assert.Equal(t, map[string]any{"brightness": nil}, kitchen.Attributes)
+	assert.Equal(t, time.Unix(1760000100, 0).UTC(), kitchen.LastUpdated, "a new lc moves lu with it")
+
+	_, ok := s.cache.get("sensor.power")
+	assert.False(t, ok)
+	_, ok = s.cache.get("switch.fan")
+	assert.True(t, ok)
+	_, ok = s.cache.get("light.unknown")
+	assert.False(t, ok, "a change to an entity never added has nothing to apply to")
+	assert.Zero(t, *calls, "the stream replaces the REST snapshot")
+}
+
+func TestEntityStreamReinstallsOnANewConnection(t *testing.T) {
+	s, _ := stateWithServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	stream := &entityStream{state: s}
+
+	require.NoError(t, stream.apply(streamMessage(4, `{"a":{"light.kitchen":{"s":"on","a":{},"lc":1}}}`)))
+	require.NoError(t, stream.apply(streamMessage(9, `{"a":{"light.hall":{"s":"on","a":{},"lc":2}}}`)))
+
+	_, ok := s.cache.get("light.kitchen")
+	assert.False(t, ok, "an entity missing from the new connection's snapshot is gone")
+	_, ok = s.cache.get("light.hall")
+	assert.True(t, ok)
+}
+
+func TestEntityStreamLeavesTheCacheAloneOnAMalformedMessage(t *testing.T) {
+	s, _ := stateWithServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	stream := &entityStream{state: s}
+	require.NoError(t, stream.apply(streamMessage(4, `{"a":{"light.kitchen":{"s":"on","a":{},"lc":1}}}`)))
+
+	assert.ErrorIs(t, stream.apply(streamMessage(4, `{"c":["light.kitchen"]}`)), ErrMalformedEvent)
+	kitchen, _ := s.cache.get("light.kitchen")
+	assert.Equal(t, "on", kitchen.State)
+}
@@ -2,6 +2,8 @@ package core
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -28,28 +30,38 @@ type stateChangedPayload struct {
 	} `json:"event"`
 }
 
+// ErrMalformedEvent reports an event that could not be decoded, or that lacks
+// what every event of its type carries. Such an event is counted and handed to
+// NewAppRequest.OnUndecodable rather than dispatched with zero values.
+var ErrMalformedEvent = errors.New("malformed event")
+
 // parseEvent decodes a delivered event. Everything but state_changed is left
 // in Raw, since this package does not model the payloads of arbitrary
-// integrations.
-func parseEvent(raw []byte) Event {
+// integrations. An event without a type, or a state_changed without an entity,
+// is an error: dispatched anyway, it would reach triggers as zero values.
+func parseEvent(raw []byte) (Event, error) {
 	var envelope eventEnvelope
 	if err := json.Unmarshal(raw, &envelope); err != nil {
-		return Event{Raw: raw}
+		return Event{Raw: raw}, fmt.Errorf("%w: %v", ErrMalformedEvent, err)
 	}
 
 	ev := Event{Type: envelope.Event.EventType, Raw: raw}
+	if ev.Type == "" {
+		return ev, fmt.Errorf("%w: no event_type", ErrMalformedEvent)
+	}
 	if ev.Type != eventStateChanged {
-		return ev
+		return ev, nil
 	}
 
 	var payload stateChangedPayload
 	if err := json.Unmarshal(raw, &payload); err != nil {
-		// The type is still known and worth dispatching on, even though this
-		// particular payload could not be read.
-		return ev
+		return ev, fmt.Errorf("%w: state_changed: %v", ErrMalformedEvent, err)
 	}
 
 	data := payload.Event.Data
+	if data.EntityID == "" {
+		return ev, fmt.Errorf("%w: state_changed without an entity_id", ErrMalformedEvent)
+	}
 	ev.EntityID = data.EntityID
 	ev.Created = data.OldState == nil
 	ev.Deleted = data.NewState == nil
@@ -60,7 +72,7 @@ func parseEvent(raw []byte) Event {
 	if data.NewState != nil {
 		ev.To = data.NewState.entityState(data.EntityID)
 	}
-	return ev
+	return ev, nil
 }
 
 func (s msgState) entityState(entityID string) EntityState {
@@ -93,6 +105,23 @@ type Event struct {
 	// Deleted reports an entity removed from Home Assistant.
 	Deleted bool
 
+	// Replayed reports an event read back from history on startup for a
+	// trigger with ReplayLast, rather than one delivered live.
+	Replayed bool
+
+	// MQTT is the message an MQTT trigger received. It is nil for every
+	// other event.
+	MQTT *MQTTMessage
+
+	// Webhook is the request a webhook trigger received. It is nil for
+	// every other event.
+	Webhook *WebhookRequest
+
+	// Variables are the trigger variables Home Assistant reported for a
+	// RemoteTrigger, as its own automations see them under trigger. They
+	// are nil for every other event.
+	Variables map[string]any
+
 	// Raw is the undecoded payload, for event types this package does not
 	// model.
 	Raw []byte
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// statsBucket is the resolution event rates are kept at, and statsBuckets
+	// how many are kept: enough for the longest window reported.
+	statsBucket  = 10 * time.Second
+	statsBuckets = int64(15 * time.Minute / statsBucket)
+)
+
+// EventRates is how often one kind of event has arrived, in events per second
+// over each window. A window longer than the app has been counting is
+// measured over the time it has.
+type EventRates struct {
+	Minute         float64
+	FiveMinutes    float64
+	FifteenMinutes float64
+
+	// Total counts every event since the app started.
+	Total uint64
+}
+
+// EventStats breaks down the events the app has received.
+type EventStats struct {
+	// ByType is keyed by event type, such as state_changed.
+	ByType map[string]EventRates
+
+	// ByDomain is keyed by the domain of the entity a state_changed event
+	// concerns, such as sensor.
+	ByDomain map[string]EventRates
+}
+
+// EventStats reports the rates events are arriving at, by type and by entity
+// domain. A domain far busier than its automations need, typically a sensor
+// reporting every second, is the one worth filtering or throttling first.
+//
+// Only events the app subscribes to are counted: state_changed always, and
+// whatever types its triggers and waits ask for. Counting starts at Start.
+func (app *App) EventStats() EventStats {
+	return app.stats.snapshot(app.clock.Now())
+}
+
+// eventCounter keeps a ring of per-bucket counts for each event type and
+// domain. The zero value is ready to count.
+type eventCounter struct {
+	mu      sync.Mutex
+	since   time.Time
+	types   map[string]*rateRing
+	domains map[string]*rateRing
+}
+
+// rateRing holds the counts of the last statsBuckets buckets. head is the
+// number of the newest bucket, counted from the epoch.
+type rateRing struct {
+	counts [statsBuckets]uint64
+	head   int64
+	total  uint64
+}
+
+func (c *eventCounter) count(ev Event, now time.Time) {
+	bucket := now.UnixNano() / int64(statsBucket)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.types == nil {
+		c.since = now
+		c.types = map[string]*rateRing{}
+		c.domains = map[string]*rateRing{}
+	}
+	ringFor(c.types, ev.Type).add(bucket)
+	if domain, _, ok := strings.Cut(ev.EntityID, "."); ok {
+		ringFor(c.domains, domain).add(bucket)
+	}
+}
+
+func ringFor(rings map[string]*rateRing, key string) *rateRing {
+	r, ok := rings[key]
+	if !ok {
+		r = &rateRing{}
+		rings[key] = r
+	}
+	return r
+}
+
+func (c *eventCounter) snapshot(now time.Time) EventStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := EventStats{ByType: map[string]EventRates{}, ByDomain: map[string]EventRates{}}
+	for key, r := range c.types {
+		stats.ByType[key] = r.rates(now, c.since)
+	}
+	for key, r := range c.domains {
+		stats.ByDomain[key] = r.rates(now, c.since)
+	}
+	return stats
+}
+
+func (r *rateRing) add(bucket int64) {
+	r.advance(bucket)
+	r.counts[bucket%statsBuckets]++
+	r.total++
+}
+
+// advance clears the buckets between the newest and bucket, which have passed
+// without an event.
+func (r *rateRing) advance(bucket int64) {
+	if bucket <= r.head {
+		return
+	}
+	for b := max(r.head+1, bucket-statsBuckets+1); b <= bucket; b++ {
+		r.counts[b%statsBuckets] = 0
+	}
+	r.head = bucket
+}
+
+func (r *rateRing) rates(now, since time.Time) EventRates {
+	bucket := now.UnixNano() / int64(statsBucket)
+	r.advance(bucket)
+
+	// The current bucket is only partly over, so the window runs from the
+	// start of its oldest bucket to now, not over whole buckets.
+	intoBucket := now.Sub(time.Unix(0, bucket*int64(statsBucket)))
+	rate := func(window time.Duration) float64 {
+		n := int64(window / statsBucket)
+		var sum uint64
+		for b := bucket - n + 1; b <= bucket; b++ {
+			sum += r.counts[b%statsBuckets]
+		}
+		span := min(time.Duration(n-1)*statsBucket+intoBucket, now.Sub(since))
+		if span <= 0 {
+			return 0
+		}
+		return float64(sum) / span.Seconds()
+	}
+
+	return EventRates{
+		Minute:         rate(time.Minute),
+		FiveMinutes:    rate(5 * time.Minute),
+		FifteenMinutes: rate(15 * time.Minute),
+		Total:          r.total,
+	}
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Xevion/go-ha/internal"
+)
+
+func TestEventStatsRatesByTypeAndDomain(t *testing.T) {
+	app := testApp()
+	clock := app.clock.(*internal.FakeClock)
+
+	doorbell, _ := json.Marshal(map[string]any{"type": "event", "event": map[string]any{"event_type": "doorbell"}})
+
+	// A sensor reporting every second, a light toggled twice, a doorbell once.
+	for i := range 60 {
+		app.dispatchEvent(stateChangedJSON("sensor.power", "1", "2"))
+		if i%30 == 0 {
+			app.dispatchEvent(stateChangedJSON("light.hall", "off", "on"))
+		}
+		clock.Advance(time.Second)
+	}
+	app.dispatchEvent(doorbell)
+
+	stats := app.EventStats()
+	require.Contains(t, stats.ByDomain, "sensor")
+	assert.InDelta(t, 1.0, stats.ByDomain["sensor"].Minute, 0.001)
+	assert.InDelta(t, 1.0, stats.ByDomain["sensor"].FifteenMinutes, 0.001, "measured over the minute the app has counted")
+	assert.Equal(t, uint64(60), stats.ByDomain["sensor"].Total)
+	assert.Equal(t, uint64(2), stats.ByDomain["light"].Total)
+	assert.Equal(t, uint64(62), stats.ByType[eventStateChanged].Total)
+	assert.Equal(t, uint64(1), stats.ByType["doorbell"].Total)
+	assert.NotContains(t, stats.ByDomain, "", "events without an entity have no domain")
+
+	// Ten quiet minutes later the burst has left the short window but not the
+	// long one.
+	clock.Advance(10 * time.Minute)
+	stats = app.EventStats()
+	assert.Zero(t, stats.ByDomain["sensor"].Minute)
+	assert.Zero(t, stats.ByDomain["sensor"].FiveMinutes)
+	assert.InDelta(t, 60.0/(11*60), stats.ByDomain["sensor"].FifteenMinutes, 0.001)
+	assert.Equal(t, uint64(60), stats.ByDomain["sensor"].Total)
+
+	// After the longest window it is gone from every rate.
+	clock.Advance(20 * time.Minute)
+	assert.Zero(t, app.EventStats().ByDomain["sensor"].FifteenMinutes)
+}
+
+func TestEventStatsIsEmptyBeforeAnyEvent(t *testing.T) {
+	stats := testApp().EventStats()
+	assert.Empty(t, stats.ByType)
+	assert.Empty(t, stats.ByDomain)
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Task is one run of an automation's action, as handed to an Executor. Its
+// exported fields are what an external worker needs to run it again, and
+// encode as JSON for a queue to carry.
+type Task struct {
+	// Automation is the name of the automation that fired.
+	Automation string `json:"automation"`
+
+	// Event is the event that fired it, the zero value for a schedule.
+	Event Event `json:"event"`
+
+	// run is the action bound to this process's run, for Run.
+	run func(ctx context.Context) error
+}
+
+// Run does the task's work here, in this process.
+func (t Task) Run(ctx context.Context) error {
+	if t.run == nil {
+		return fmt.Errorf("%w: task for %q has no action in this process", ErrInvalidArgs, t.Automation)
+	}
+	return t.run(ctx)
+}
+
+// Executor runs the actions of automations built with ExecuteWith. It is where
+// heavy work can leave the process: an Executor that publishes the task to a
+// queue, such as NATS or Redis, returns as soon as it is enqueued, and the
+// automation's run slot is free for the next event. A worker holding the same
+// automations picks the task up and hands it to App.ExecuteTask.
+//
+// An error from Execute is the action's error, logged and counted as one.
+type Executor interface {
+	Execute(ctx context.Context, task Task) error
+}
+
+// InProcess is the Executor every automation has by default: it runs the
+// action right away, in the run's own goroutine.
+var InProcess Executor = inProcess{}
+
+type inProcess struct{}
+
+func (inProcess) Execute(ctx context.Context, task Task) error { return task.Run(ctx) }
+
+// ExecuteWith hands the action to e to run, in place of running it here. The
+// automation's conditions and mode still apply where it fires; whatever e does
+// with the task after it returns is outside them. A nil e, including a nil
+// pointer or channel of a type that implements Executor, runs it in process.
+func (b AutomationBuilder) ExecuteWith(e Executor) AutomationBuilder {
+	if isNil(e) {
+		e = nil
+	}
+	b.a.executor = e
+	return b
+}
+
+// isNil reports whether e is nil, or holds a nil of a type that can be nil.
+// Either would fail on its first Execute, or in a nil channel's case block on
+// it forever.
+func isNil(e Executor) bool {
+	if e == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(e); v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Map, reflect.Pointer, reflect.Interface, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// RegisterTaskHandlers makes the automations' actions available to
+// ExecuteTask, on a worker, without subscribing to their triggers: the worker
+// runs what the listener's Executor carries to it, and would otherwise fire
+// them itself as well. Register the same automations the listener does.
+func (app *App) RegisterTaskHandlers(automations ...Automation) error {
+	app.registryMu.Lock()
+	defer app.registryMu.Unlock()
+
+	for _, a := range automations {
+		if a.runtime == nil {
+			return fmt.Errorf("%w %q: not built, call Build or MustBuild", ErrInvalidAutomation, a.name)
+		}
+	}
+	if app.tasks == nil {
+		app.tasks = map[string]Automation{}
+	}
+	for _, a := range automations {
+		if _, taken := app.tasks[a.name]; !taken {
+			app.tasks[a.name] = a
+		}
+	}
+	return nil
+}
+
+// ExecuteTask runs a task an Executor carried here from another process,
+// with the action of the automation of its name registered with
+// RegisterTaskHandlers, or failing that with RegisterAutomations. The task has
+// already passed its automation's conditions and mode where it fired, so
+// neither applies again, and run.Trigger is nil, not surviving the trip. Where
+// two automations share a name, the first registered runs.
+func (app *App) ExecuteTask(ctx context.Context, task Task) error {
+	if !app.started.Load() || app.ctx.Err() != nil {
+		return ErrNotRunning
+	}
+
+	app.registryMu.RLock()
+	a, ok := app.tasks[task.Automation]
+	if !ok {
+		a, ok = app.named[task.Automation]
+	}
+	app.registryMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownAutomation, task.Automation)
+	}
+	return a.action(ctx, Run{Services: app.service, State: app.state, Event: task.Event})
+}
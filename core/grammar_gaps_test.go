@@ -41,18 +41,26 @@ func TestPolicyLimitFallsBackToTheDefault(t *testing.T) {
 }
 
 func TestParseEventHandlesNonStateChangedEvents(t *testing.T) {
-	ev := parseEvent([]byte(`{"type":"event","event":{"event_type":"call_service",
+	ev, err := parseEvent([]byte(`{"type":"event","event":{"event_type":"call_service",
 		"data":{"domain":"light","service":"turn_on"}}}`))
+	require.NoError(t, err)
 
 	assert.Equal(t, "call_service", ev.Type)
 	assert.Empty(t, ev.EntityID, "only state_changed carries an entity")
 	assert.NotEmpty(t, ev.Raw, "the payload stays available for types we do not model")
 }
 
-func TestParseEventSurvivesMalformedPayloads(t *testing.T) {
-	ev := parseEvent([]byte(`not json at all`))
-	assert.Empty(t, ev.Type)
-	assert.NotEmpty(t, ev.Raw)
+func TestParseEventRejectsMalformedPayloads(t *testing.T) {
+	for _, raw := range []string{
+		`not json at all`,
+		`{"type":"event","event":{"data":{}}}`,
+		`{"type":"event","event":{"event_type":"state_changed","data":{"entity_id":["light.a"]}}}`,
+		`{"type":"event","event":{"event_type":"state_changed","data":{"new_state":{"state":"on"}}}}`,
+	} {
+		ev, err := parseEvent([]byte(raw))
+		assert.ErrorIs(t, err, ErrMalformedEvent, raw)
+		assert.NotEmpty(t, ev.Raw, "the frame is kept for the dead-letter hook")
+	}
 }
 
 // An entity removed while a snapshot is in flight must stay removed: the
@@ -81,19 +89,21 @@ func TestModeNames(t *testing.T) {
 // carries entity_id as a list, which does not fit the state_changed schema;
 // decoding both together dropped the whole event.
 func TestParseEventKeepsEventsWhoseDataCollidesWithStateChanged(t *testing.T) {
-	ev := parseEvent([]byte(`{"type":"event","event":{"event_type":"call_service","data":{
+	ev, err := parseEvent([]byte(`{"type":"event","event":{"event_type":"call_service","data":{
 		"domain":"light","service":"turn_on",
 		"service_data":{"entity_id":["light.a","light.b"]},
 		"entity_id":["light.a","light.b"]}}}`))
+	require.NoError(t, err)
 
 	assert.Equal(t, "call_service", ev.Type, "the event type survives a payload we cannot model")
 }
 
 func TestParseEventMarksEntityCreation(t *testing.T) {
-	ev := parseEvent([]byte(`{"type":"event","event":{"event_type":"state_changed","data":{
+	ev, err := parseEvent([]byte(`{"type":"event","event":{"event_type":"state_changed","data":{
 		"entity_id":"light.new",
 		"old_state":null,
 		"new_state":{"entity_id":"light.new","state":"on"}}}}`))
+	require.NoError(t, err)
 
 	assert.True(t, ev.Created)
 	assert.False(t, ev.Deleted)
@@ -102,10 +112,11 @@ func TestParseEventMarksEntityCreation(t *testing.T) {
 }
 
 func TestParseEventMarksEntityDeletion(t *testing.T) {
-	ev := parseEvent([]byte(`{"type":"event","event":{"event_type":"state_changed","data":{
+	ev, err := parseEvent([]byte(`{"type":"event","event":{"event_type":"state_changed","data":{
 		"entity_id":"light.gone",
 		"old_state":{"entity_id":"light.gone","state":"on"},
 		"new_state":null}}}`))
+	require.NoError(t, err)
 
 	assert.True(t, ev.Deleted)
 	assert.False(t, ev.Created)
@@ -117,10 +128,11 @@ func TestParseEventMarksEntityDeletion(t *testing.T) {
 func TestStateChangedIgnoresDeletion(t *testing.T) {
 	trig := StateChanged("light.gone")
 
-	ev := parseEvent([]byte(`{"type":"event","event":{"event_type":"state_changed","data":{
+	ev, err := parseEvent([]byte(`{"type":"event","event":{"event_type":"state_changed","data":{
 		"entity_id":"light.gone",
 		"old_state":{"entity_id":"light.gone","state":"on"},
 		"new_state":null}}}`))
+	require.NoError(t, err)
 
 	assert.False(t, trig.Matches(ev))
 }
@@ -128,10 +140,11 @@ func TestStateChangedIgnoresDeletion(t *testing.T) {
 func TestStateChangedFiresOnCreation(t *testing.T) {
 	trig := StateChanged("light.new").To("on")
 
-	ev := parseEvent([]byte(`{"type":"event","event":{"event_type":"state_changed","data":{
+	ev, err := parseEvent([]byte(`{"type":"event","event":{"event_type":"state_changed","data":{
 		"entity_id":"light.new",
 		"old_state":null,
 		"new_state":{"entity_id":"light.new","state":"on"}}}}`))
+	require.NoError(t, err)
 
 	assert.True(t, trig.Matches(ev), "an entity appearing in the state it is watched for is a real transition")
 }
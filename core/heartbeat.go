@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xevion/go-ha/internal"
+)
+
+// heartbeat is the liveness sensor WithHeartbeat maintains.
+type heartbeat struct {
+	entityID string
+	interval time.Duration
+
+	// mu orders the beats against the final write, so a beat already under
+	// way when Close comes cannot land after the sensor is turned off.
+	mu      sync.Mutex
+	stopped bool
+}
+
+// WithHeartbeat keeps a binary sensor in Home Assistant on while the app is
+// alive, so an automation there can raise the alarm when it dies. The sensor is
+// rewritten every interval with last_seen and expires_at attributes; a sensor
+// whose expires_at has passed belongs to an app that stopped without saying so.
+// A clean Close turns it off.
+//
+// The entity needs no configuration in Home Assistant; it is created on first
+// write. It does not survive a Home Assistant restart until the next beat.
+func (app *App) WithHeartbeat(entityID string, interval time.Duration) error {
+	if !strings.HasPrefix(entityID, "binary_sensor.") {
+		return fmt.Errorf("%w: heartbeat %q is not a binary sensor", ErrInvalidArgs, entityID)
+	}
+	if interval <= 0 {
+		return fmt.Errorf("%w: heartbeat interval must be positive", ErrInvalidArgs)
+	}
+
+	hb := &heartbeat{entityID: entityID, interval: interval}
+
+	app.registryMu.Lock()
+	if app.heartbeat != nil {
+		app.registryMu.Unlock()
+		return fmt.Errorf("%w: heartbeat is already published to %s", ErrInvalidArgs, app.heartbeat.entityID)
+	}
+	app.heartbeat = hb
+	app.registryMu.Unlock()
+
+	return app.RegisterAutomations(
+		NewAutomation("heartbeat to "+entityID).
+			On(AtStartup().Order(-1), Every(interval)).
+			Do(func(_ context.Context, _ Run) error { return hb.beat(app) }).
+			MustBuild(),
+	)
+}
+
+func (hb *heartbeat) beat(app *App) error {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	if hb.stopped {
+		return nil
+	}
+	now := app.clock.Now()
+	return app.SetState(hb.entityID, "on", map[string]any{
+		"device_class": "connectivity",
+		"last_seen":    now.Format(time.RFC3339),
+		// Two beats, so one slow write is not taken for a death.
+		"expires_at":       now.Add(2 * hb.interval).Format(time.RFC3339),
+		"interval_seconds": hb.interval.Seconds(),
+		"version":          internal.Version,
+	})
+}
+
+// stopHeartbeat turns the heartbeat off as the app shuts down, so a deliberate
+// stop is told apart from a crash.
+func (app *App) stopHeartbeat() {
+	app.registryMu.RLock()
+	hb := app.heartbeat
+	app.registryMu.RUnlock()
+
+	if hb == nil || !app.started.Load() || app.ctx.Err() != nil {
+		return
+	}
+
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	hb.stopped = true
+	err := app.SetState(hb.entityID, "off", map[string]any{
+		"device_class": "connectivity",
+		"last_seen":    app.clock.Now().Format(time.RFC3339),
+		"version":      internal.Version,
+	})
+	if err != nil {
+		app.log.Warn("Failed to turn heartbeat off", "entity_id", hb.entityID, "error", err)
+	}
+}
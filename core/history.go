@@ -0,0 +1,59 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HistoricalState is one state an entity held, from LastChanged until Until.
+type HistoricalState struct {
+	EntityState
+
+	// Until is when the entity left this state: the next state's LastChanged,
+	// or for the last one, the end of the span asked about or now, whichever
+	// is earlier.
+	Until time.Time
+}
+
+// History reads the states an entity held between start and end from Home
+// Assistant's recorder, oldest first, for decisions about past behaviour, such
+// as whether the door has opened in the last day. The first is the state in
+// effect at start, with its LastChanged moved up to start.
+//
+// Only what the recorder kept is there: entities it excludes have no history,
+// and most attribute-only updates are left out.
+func (app *App) History(entityID string, start, end time.Time) ([]HistoricalState, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("%w: history from %s to %s is empty", ErrInvalidArgs, start, end)
+	}
+
+	raw, err := app.httpClient.GetHistory(entityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	// One list per entity asked for, and an entity without history is left
+	// out rather than given an empty list.
+	var lists [][]EntityState
+	if err := json.Unmarshal(raw, &lists); err != nil {
+		return nil, fmt.Errorf("decoding history of %s: %w", entityID, err)
+	}
+	if len(lists) == 0 {
+		return nil, nil
+	}
+
+	states := lists[0]
+	last := end
+	if now := app.clock.Now(); now.Before(last) {
+		last = now
+	}
+	out := make([]HistoricalState, len(states))
+	for i, es := range states {
+		until := last
+		if i+1 < len(states) {
+			until = states[i+1].LastChanged
+		}
+		out[i] = HistoricalState{EntityState: es, Until: until}
+	}
+	return out, nil
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Inspection is a snapshot of what an app has registered and how it has run,
+// as Inspect returns it, for dumping to a log or serving from an admin page.
+type Inspection struct {
+	// Automations lists every registered automation, by name.
+	Automations []AutomationInfo
+
+	// Schedules lists every schedule trigger still queued, soonest first.
+	Schedules []ScheduleInfo
+
+	// Listeners lists every event trigger, by automation.
+	Listeners []ListenerInfo
+}
+
+// AutomationInfo describes one automation and its runs.
+type AutomationInfo struct {
+	Name string
+
+	// ID is the automation's ID, which stays the same across restarts.
+	ID string
+
+	Mode Mode
+
+	// Running reports a run in flight or queued behind one.
+	Running bool
+
+	// LastRun is when a run last started, and zero if none has.
+	LastRun time.Time
+
+	// LastError is the latest error an action returned, at LastErrorAt. It is
+	// kept after later runs succeed, so it answers "what went wrong last".
+	LastError   error
+	LastErrorAt time.Time
+
+	// ConditionErrors counts the times a condition could not be evaluated.
+	ConditionErrors uint64
+}
+
+// ScheduleInfo describes one queued schedule trigger.
+type ScheduleInfo struct {
+	Automation string
+	Trigger    string
+
+	// NextRun is when it next fires. It is zero for a trigger waiting for
+	// its source to give it a time, such as a sun trigger before sun.sun has
+	// loaded.
+	NextRun time.Time
+}
+
+// ListenerInfo describes one event trigger.
+type ListenerInfo struct {
+	Automation string
+	Trigger    string
+
+	// EventTypes are the Home Assistant event types it is subscribed to.
+	EventTypes []string
+
+	// Entities are the ids and patterns it watches. Empty means it names
+	// none, and so hears every entity or none is involved.
+	Entities []string
+}
+
+// Inspect reports what the app has registered, when its schedules next fire
+// and how each automation last ran.
+func (app *App) Inspect() Inspection {
+	var out Inspection
+
+	app.registryMu.RLock()
+	for r, name := range app.runners {
+		r.mu.Lock()
+		out.Automations = append(out.Automations, AutomationInfo{
+			Name:            name,
+			ID:              r.id,
+			Mode:            r.policy.Mode,
+			Running:         r.active > 0 || r.waiting > 0,
+			LastRun:         r.lastStarted,
+			LastError:       r.lastErr,
+			LastErrorAt:     r.lastErrAt,
+			ConditionErrors: r.conditionErrors.Load(),
+		})
+		r.mu.Unlock()
+	}
+
+	// A trigger subscribed to several event types is bound once for each, and
+	// its bindings share their pending runs, which tells them apart from
+	// another trigger's.
+	listeners := map[*pendingRuns]*ListenerInfo{}
+	var order []*pendingRuns
+	for eventType, bindings := range app.automations {
+		for _, b := range bindings {
+			l, seen := listeners[b.pending]
+			if !seen {
+				l = &ListenerInfo{Automation: b.automation.name, Trigger: fmt.Sprint(b.trigger)}
+				if ref, ok := b.trigger.(entityReferrer); ok {
+					l.Entities = slices.Clone(ref.referencedEntities())
+				}
+				listeners[b.pending] = l
+				order = append(order, b.pending)
+			}
+			l.EventTypes = append(l.EventTypes, eventType)
+		}
+	}
+	app.registryMu.RUnlock()
+
+	for _, p := range order {
+		l := listeners[p]
+		slices.Sort(l.EventTypes)
+		out.Listeners = append(out.Listeners, *l)
+	}
+	out.Schedules = append(app.schedules.inspect(), app.intervals.inspect()...)
+
+	slices.SortFunc(out.Automations, func(a, b AutomationInfo) int { return strings.Compare(a.Name, b.Name) })
+	slices.SortStableFunc(out.Listeners, func(a, b ListenerInfo) int {
+		if c := strings.Compare(a.Automation, b.Automation); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Trigger, b.Trigger)
+	})
+	slices.SortStableFunc(out.Schedules, func(a, b ScheduleInfo) int {
+		// Waiting triggers have no time yet, and go last.
+		if a.NextRun.IsZero() != b.NextRun.IsZero() {
+			if a.NextRun.IsZero() {
+				return 1
+			}
+			return -1
+		}
+		return a.NextRun.Compare(b.NextRun)
+	})
+	return out
+}
+
+// inspect lists the scheduler's entries, parked ones included.
+func (s *scheduler) inspect() []ScheduleInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	describe := func(entry *scheduledEntry, next time.Time) ScheduleInfo {
+		info := ScheduleInfo{Trigger: fmt.Sprint(entry.trigger), NextRun: next}
+		if a, ok := entry.trigger.(schedulerAdapter); ok {
+			info.Automation = a.automation
+		}
+		return info
+	}
+
+	out := make([]ScheduleInfo, 0, len(s.queue)+len(s.parked))
+	for _, entry := range s.queue {
+		out = append(out, describe(entry, entry.fireAt))
+	}
+	for _, entry := range s.parked {
+		out = append(out, describe(entry, time.Time{}))
+	}
+	return out
+}
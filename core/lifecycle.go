@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Hook is user code run at a point in the app's life, registered with
+// OnStart, OnReady or OnStop.
+type Hook func(ctx context.Context) error
+
+// stopHookTimeout bounds the OnStop hooks when Close is given no deadline of
+// its own. They run while the connection is still up, and a hook that hangs
+// must not keep the process from exiting.
+const stopHookTimeout = 10 * time.Second
+
+// lifecycle holds the hooks registered on an app.
+type lifecycle struct {
+	mu      sync.Mutex
+	start   []Hook
+	ready   []Hook
+	stop    []Hook
+	stopped bool
+
+	// running tracks the goroutine waiting to run the OnReady hooks, so
+	// shutdown does not return while one is still making calls.
+	running sync.WaitGroup
+}
+
+// OnStart registers a hook run when Start begins, before any schedule or
+// trigger can fire. The connection is up, but the first snapshot of entity
+// state may not have landed yet; use OnReady to read state. Hooks run in the
+// order registered, and one returning an error stops Start, which returns it.
+func (app *App) OnStart(hook Hook) {
+	app.lifecycle.mu.Lock()
+	defer app.lifecycle.mu.Unlock()
+	app.lifecycle.start = append(app.lifecycle.start, hook)
+}
+
+// OnReady registers a hook run once the app is live: Start has opened the gate
+// to automations, the subscriptions are in place and entity state has been
+// loaded. It is the place for "automations online" announcements. It runs
+// once per Start, not again after a reconnect, and on its own goroutine, so
+// Start is not held up by it. An error is logged.
+func (app *App) OnReady(hook Hook) {
+	app.lifecycle.mu.Lock()
+	defer app.lifecycle.mu.Unlock()
+	app.lifecycle.ready = append(app.lifecycle.ready, hook)
+}
+
+// OnStop registers a hook run when Close begins, while the connection can
+// still reach Home Assistant, for teardown such as turning off a status light.
+// Hooks run in the order registered and only if Start was called. Together
+// they have ten seconds, or until the deadline given to Shutdown. An error is
+// logged and the rest still run.
+func (app *App) OnStop(hook Hook) {
+	app.lifecycle.mu.Lock()
+	defer app.lifecycle.mu.Unlock()
+	app.lifecycle.stop = append(app.lifecycle.stop, hook)
+}
+
+// runStartHooks runs the OnStart hooks, stopping at the first error.
+func (app *App) runStartHooks() error {
+	app.lifecycle.mu.Lock()
+	hooks := append([]Hook(nil), app.lifecycle.start...)
+	app.lifecycle.mu.Unlock()
+
+	for i, hook := range hooks {
+		if err := callHook(app.ctx, hook); err != nil {
+			var p *PanicError
+			if errors.As(err, &p) {
+				app.reportCallbackError(err, AutomationMeta{Name: fmt.Sprintf("OnStart hook %d", i+1)})
+			}
+			return fmt.Errorf("OnStart hook %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// awaitReady replays history for triggers asking for it and then runs the
+// OnReady hooks, once the first snapshot has landed, unless the app shuts down
+// first.
+func (app *App) awaitReady() {
+	app.lifecycle.mu.Lock()
+	hooks := append([]Hook(nil), app.lifecycle.ready...)
+	app.lifecycle.mu.Unlock()
+	replays := app.replayBindings()
+	if len(hooks) == 0 && len(replays) == 0 {
+		return
+	}
+
+	app.lifecycle.running.Add(1)
+	go func() {
+		defer app.lifecycle.running.Done()
+		select {
+		case <-app.state.cache.seededOnce():
+		case <-app.ctx.Done():
+			return
+		}
+		for _, b := range replays {
+			app.replay(b)
+		}
+		for i, hook := range hooks {
+			name := fmt.Sprintf("OnReady hook %d", i+1)
+			if err := callHook(app.ctx, hook); err != nil {
+				app.log.Error("OnReady hook failed", append([]any{"hook", i + 1}, failureAttrs(err)...)...)
+				app.reportCallbackError(err, AutomationMeta{Name: name})
+			}
+		}
+	}()
+}
+
+// runStopHooks runs the OnStop hooks, once however often Close is called. They
+// are bounded by ctx when it has a deadline, and by stopHookTimeout otherwise.
+func (app *App) runStopHooks(ctx context.Context) {
+	app.lifecycle.mu.Lock()
+	if app.lifecycle.stopped || !app.starting.Load() {
+		app.lifecycle.mu.Unlock()
+		return
+	}
+	app.lifecycle.stopped = true
+	hooks := append([]Hook(nil), app.lifecycle.stop...)
+	app.lifecycle.mu.Unlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	// Detached from the app's context, which may already be cancelled if the
+	// connection was abandoned, but still carrying the app for the hooks.
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(stopHookTimeout)
+	}
+	hookCtx, cancel := context.WithDeadline(context.WithoutCancel(app.ctx), deadline)
+	defer cancel()
+	defer context.AfterFunc(ctx, cancel)()
+	for i, hook := range hooks {
+		name := fmt.Sprintf("OnStop hook %d", i+1)
+		if err := callHook(hookCtx, hook); err != nil {
+			app.log.Error("OnStop hook failed", append([]any{"hook", i + 1}, failureAttrs(err)...)...)
+			app.reportCallbackError(err, AutomationMeta{Name: name})
+		}
+	}
+}
+
+// callHook runs a lifecycle hook, returning a panic in it as a *PanicError.
+func callHook(ctx context.Context, hook Hook) (err error) {
+	defer recoverPanic(&err)
+	return hook(ctx)
+}
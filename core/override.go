@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xevion/go-ha/services"
+)
+
+// overridable lists the domains TemporaryOverride drives, all of which turn
+// on and off with turn_on and turn_off.
+var overridable = []string{"fan", "input_boolean", "light", "switch"}
+
+// overrides tracks the TemporaryOverride calls whose restore is still due.
+type overrides struct {
+	app *App
+
+	mu     sync.Mutex
+	active map[string]*override
+}
+
+// override is one entity's pending restore. saved is the state from before
+// the first override, which a second override of the same entity extends
+// rather than replaces.
+type override struct {
+	saved  EntityState
+	cancel context.CancelFunc
+}
+
+// turner sends an override's calls through the services it was made on, so
+// At and Targeting apply to them as to any other call.
+type turner struct {
+	send services.Sender
+}
+
+// TemporaryOverride turns the entity on or off for d, then puts it back the
+// way it was: "leave the porch light on for ten minutes". desired is "on" or
+// "off". A light is restored to its brightness as well as its state.
+//
+// Someone changing the entity in the meantime cancels the restore, since
+// putting it back would undo what they just did. Overriding an entity again
+// before the restore restarts the wait, and still restores the state from
+// before the first override.
+//
+// The calls, the restore included, go through these services, so At,
+// Targeting and WithResult apply to them. An override that fails leaves one
+// already running on the entity as it was.
+//
+// Lights, switches, fans and input booleans can be overridden.
+func (s *Service) TemporaryOverride(entityID, desired string, d time.Duration) error {
+	return s.overrides.start(turner{send: s.conn}, entityID, desired, d)
+}
+
+func (o *overrides) start(t turner, entityID, desired string, d time.Duration) error {
+	domain, _, _ := strings.Cut(entityID, ".")
+	if !slices.Contains(overridable, domain) {
+		return fmt.Errorf("%w: cannot override %s; only %s turn on and off", ErrInvalidArgs, entityID, strings.Join(overridable, ", "))
+	}
+	if desired != "on" && desired != "off" {
+		return fmt.Errorf("%w: override state %q is neither on nor off", ErrInvalidArgs, desired)
+	}
+	if d <= 0 {
+		return fmt.Errorf("%w: override of %s needs a positive duration", ErrInvalidArgs, entityID)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	saved := EntityState{}
+	existing, extending := o.active[entityID]
+	if extending {
+		saved = existing.saved
+	} else {
+		current, err := o.app.state.Get(entityID)
+		if err != nil {
+			return fmt.Errorf("reading %s before overriding it: %w", entityID, err)
+		}
+		saved = current
+	}
+
+	// Listening before sending, so a change landing the moment after the
+	// command is not missed. The override's own change lands in the desired
+	// state, which is not an intervention.
+	w, err := o.app.addWaiter(eventStateChanged, func(ev Event) bool {
+		return ev.EntityID == entityID && (ev.Deleted || ev.To.State != desired)
+	})
+	if err != nil {
+		return err
+	}
+	if err := t.turn(entityID, desired, nil); err != nil {
+		o.app.removeWaiter(eventStateChanged, w)
+		return err
+	}
+	// Only now the new one has taken, so a failure leaves the old restore,
+	// and the state it saved, in place.
+	if extending {
+		existing.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(o.app.ctx)
+	ov := &override{saved: saved, cancel: cancel}
+	if o.active == nil {
+		o.active = map[string]*override{}
+	}
+	o.active[entityID] = ov
+
+	go func() {
+		defer o.app.removeWaiter(eventStateChanged, w)
+		_, err := w.wait(ctx, d, entityID)
+
+		o.mu.Lock()
+		current := o.active[entityID] == ov
+		if current {
+			delete(o.active, entityID)
+		}
+		o.mu.Unlock()
+
+		switch {
+		case !current || ctx.Err() != nil:
+			// Superseded by a later override, or the app is closing.
+		case err == nil:
+			o.app.log.Info("Override interrupted, leaving the entity as it was set", "entity", entityID)
+		case errors.Is(err, ErrWaitTimeout):
+			if err := t.restore(saved); err != nil {
+				o.app.log.Error("Failed to restore after an override", "entity", entityID, "error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// restore puts the entity back the way it was saved. An entity that was
+// unavailable has no state to go back to and is left alone.
+func (t turner) restore(saved EntityState) error {
+	switch saved.State {
+	case "off":
+		return t.turn(saved.EntityID, "off", nil)
+	case "on":
+		var data map[string]any
+		if brightness, ok := saved.Attributes["brightness"]; ok && brightness != nil && strings.HasPrefix(saved.EntityID, "light.") {
+			data = map[string]any{"brightness": brightness}
+		}
+		return t.turn(saved.EntityID, "on", data)
+	}
+	return nil
+}
+
+func (t turner) turn(entityID, state string, data map[string]any) error {
+	domain, _, _ := strings.Cut(entityID, ".")
+	req := services.NewBaseServiceRequest(entityID)
+	req.Domain = domain
+	req.Service = "turn_" + state
+	if data != nil {
+		req.ServiceData = data
+	}
+	return t.send.Send(&req)
+}
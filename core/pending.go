@@ -28,6 +28,11 @@ type pendingRuns struct {
 	gen map[string]uint64
 
 	closed bool
+
+	// firing tracks callbacks that have passed their check and are running, so
+	// stop can wait for them. One that slipped past before closed was set would
+	// otherwise start a run behind shutdown's back.
+	firing sync.WaitGroup
 }
 
 func newPendingRuns() *pendingRuns {
@@ -60,10 +65,12 @@ func (p *pendingRuns) arm(entityID string, d time.Duration, run func()) {
 		current := p.gen[entityID] == mine && !p.closed
 		if current {
 			delete(p.timers, entityID)
+			p.firing.Add(1)
 		}
 		p.mu.Unlock()
 
 		if current {
+			defer p.firing.Done()
 			run()
 		}
 	})
@@ -84,14 +91,30 @@ func (p *pendingRuns) disarm(entityID string) {
 	p.gen[entityID]++
 }
 
-// stop cancels every wait and refuses further ones, for shutdown.
+// stop cancels every wait and refuses further ones, for shutdown. It returns
+// once any callback already under way has finished handing off its run.
 func (p *pendingRuns) stop() {
+	p.mu.Lock()
+	p.closed = true
+	for id, timer := range p.timers {
+		timer.Stop()
+		delete(p.timers, id)
+	}
+	p.mu.Unlock()
+
+	p.firing.Wait()
+}
+
+// clear cancels every wait without refusing further ones, for a sub-app being
+// stopped that may be started again.
+func (p *pendingRuns) clear() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.closed = true
 	for id, timer := range p.timers {
 		timer.Stop()
 		delete(p.timers, id)
+		// As disarm does, so a callback already in flight retires.
+		p.gen[id]++
 	}
 }
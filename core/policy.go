@@ -2,7 +2,9 @@ package core
 
 import (
 	"context"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -50,6 +52,10 @@ type Policy struct {
 	// one.
 	Throttle time.Duration
 
+	// Trailing runs the last trigger Throttle dropped once its window closes,
+	// so the final state of a burst is acted on rather than lost.
+	Trailing bool
+
 	// Limit caps in-flight runs under ModeParallel and waiting runs under
 	// ModeQueued. Zero means the default.
 	Limit int
@@ -75,11 +81,27 @@ type runner struct {
 	policy Policy
 	clock  Clock
 
+	// id is the automation's ID, kept for Inspect.
+	id string
+
+	// log carries the automation's name and ID on every line it writes.
+	log *slog.Logger
+
+	// report hands a failed run to the app's OnCallbackError hooks. Nil
+	// until the automation is registered.
+	report func(err error, ev Event)
+
 	mu sync.Mutex
 
 	// lastRan holds the last admitted run per throttle key.
 	lastRan map[string]time.Time
 
+	// lastStarted, lastErr and lastErrAt are the latest run and the latest
+	// failure across every key, as Inspect reports them.
+	lastStarted time.Time
+	lastErr     error
+	lastErrAt   time.Time
+
 	active  int
 	waiting int
 
@@ -92,10 +114,39 @@ type runner struct {
 	// wg tracks in-flight runs so shutdown can wait them out instead of
 	// abandoning them mid-service-call.
 	wg sync.WaitGroup
+
+	// trailing holds, per throttle key, the most recent trigger the throttle
+	// dropped, waiting for the window to close. Arming replaces the previous
+	// one, which is exactly the "latest wins" a trailing edge wants.
+	trailing *pendingRuns
+
+	// retries holds, per throttle key, an evaluation waiting out a
+	// RetryThenSkip backoff.
+	retries *pendingRuns
+
+	// conditionErrors counts evaluations that ended undecided.
+	conditionErrors atomic.Uint64
+
+	// scope, set for an automation registered through a sub-app, supplies the
+	// context its runs derive from in place of the app's, so stopping the
+	// sub-app refuses and cancels them.
+	scope func() context.Context
+
+	// retired is set once the automation is unregistered. A trigger dispatched
+	// from a snapshot taken before then, or a schedule already taken off the
+	// queue to fire, is turned away rather than running it one last time.
+	retired atomic.Bool
 }
 
 func newRunner(policy Policy, clock Clock) *runner {
-	return &runner{policy: policy, clock: clock, lastRan: map[string]time.Time{}}
+	return &runner{
+		policy:   policy,
+		clock:    clock,
+		log:      slog.Default(),
+		lastRan:  map[string]time.Time{},
+		trailing: newPendingRuns(),
+		retries:  newPendingRuns(),
+	}
 }
 
 // withClock points the runner at the app's clock. Conditions already read it,
@@ -107,18 +158,81 @@ func (r *runner) withClock(clock Clock) {
 	r.clock = clock
 }
 
+// withLogger points the runner at the app's logger, which Build has no app to
+// read either.
+func (r *runner) withLogger(log *slog.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = log
+}
+
+// withReporter points the runner at the app's OnCallbackError hooks.
+func (r *runner) withReporter(report func(err error, ev Event)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report = report
+}
+
+// failed records a run whose action returned an error.
+func (r *runner) failed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastErr, r.lastErrAt = err, r.clock.Now()
+}
+
+// reportFailure hands a failed run to the app's OnCallbackError hooks.
+func (r *runner) reportFailure(err error, ev Event) {
+	r.mu.Lock()
+	report := r.report
+	r.mu.Unlock()
+	if report != nil {
+		report(err, ev)
+	}
+}
+
+// logger is where the automation's runs report.
+func (r *runner) logger() *slog.Logger {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.log
+}
+
 // run admits a trigger under the policy and reports whether it was accepted.
 // The work happens on its own goroutine, so the caller, which is a dispatch
 // worker, is never held by a slow automation.
 func (r *runner) run(parent context.Context, key string, fn func(context.Context)) bool {
+	return r.admit(parent, key, fn, false)
+}
+
+// admit is run, with the throttle optionally waived for a trailing run whose
+// window has already been waited out.
+func (r *runner) admit(parent context.Context, key string, fn func(context.Context), trailing bool) bool {
+	if r.retired.Load() {
+		return false
+	}
+	if r.scope != nil {
+		parent = r.scope()
+	}
+
+	// A run admitted once shutdown has begun would act on a closed connection.
+	// Its context is already done, so there is nothing for it to do.
+	if parent.Err() != nil {
+		return false
+	}
+
 	r.mu.Lock()
 	now := r.clock.Now()
 
 	// Admission and the stamp that records it are one critical section, so two
 	// triggers cannot both read the same lastRan and both decide they are past
 	// the window.
-	if last, seen := r.lastRan[key]; r.policy.Throttle > 0 && seen &&
+	if last, seen := r.lastRan[key]; !trailing && r.policy.Throttle > 0 && seen &&
 		now.Sub(last) < r.policy.Throttle {
+		if r.policy.Trailing {
+			r.trailing.arm(key, last.Add(r.policy.Throttle).Sub(now), func() {
+				r.admit(parent, key, fn, true)
+			})
+		}
 		r.mu.Unlock()
 		return false
 	}
@@ -147,8 +261,15 @@ func (r *runner) run(parent context.Context, key string, fn func(context.Context
 	}
 
 	r.lastRan[key] = now
+	r.lastStarted = now
 	r.active++
 
+	// Anything held for the trailing edge is older than this run, and acting
+	// on it afterwards would replay a state that has already been superseded.
+	if r.policy.Trailing && !trailing {
+		r.trailing.disarm(key)
+	}
+
 	ctx, cancel := context.WithCancel(parent)
 	r.cancel = cancel
 
@@ -184,5 +305,18 @@ func (r *runner) finish() {
 	r.active--
 }
 
+// stop discards every trailing run still waiting for its window, for shutdown.
+func (r *runner) stop() {
+	r.trailing.stop()
+	r.retries.stop()
+}
+
+// running reports whether a run is in flight or queued behind one.
+func (r *runner) running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active > 0 || r.waiting > 0
+}
+
 // wait blocks until every admitted run has finished.
 func (r *runner) wait() { r.wg.Wait() }
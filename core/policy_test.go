@@ -214,3 +214,73 @@ func waitFor(t *testing.T, counter *atomic.Int64, n int64) {
 		}
 	}
 }
+
+// A burst inside the window must still end with its last value acted on, or
+// a sensor that settles during the throttle leaves the automation stale.
+func TestThrottleTrailingRunsTheLastDroppedTrigger(t *testing.T) {
+	clock := testClock()
+	r := newRunner(Policy{Mode: ModeParallel, Throttle: 20 * time.Millisecond, Trailing: true}, clock)
+
+	var mu sync.Mutex
+	var ran []string
+	record := func(v string) func(context.Context) {
+		return func(context.Context) {
+			mu.Lock()
+			ran = append(ran, v)
+			mu.Unlock()
+		}
+	}
+
+	require.True(t, r.run(context.Background(), "sensor.power", record("first")))
+	require.False(t, r.run(context.Background(), "sensor.power", record("second")))
+	require.False(t, r.run(context.Background(), "sensor.power", record("third")))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ran) == 2
+	}, time.Second, time.Millisecond)
+
+	// Only the newest suppressed trigger runs, once.
+	time.Sleep(40 * time.Millisecond)
+	r.wait()
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"first", "third"}, ran)
+}
+
+// A trigger admitted after the window supersedes whatever was held, which
+// would otherwise replay an older state after a newer one.
+func TestThrottleTrailingIsSupersededByAnAdmittedRun(t *testing.T) {
+	clock := testClock()
+	r := newRunner(Policy{Mode: ModeParallel, Throttle: 30 * time.Millisecond, Trailing: true}, clock)
+
+	var stale atomic.Bool
+	require.True(t, r.run(context.Background(), "sensor.power", noRun))
+	require.False(t, r.run(context.Background(), "sensor.power", func(context.Context) { stale.Store(true) }))
+
+	clock.Advance(time.Minute)
+	require.True(t, r.run(context.Background(), "sensor.power", noRun))
+
+	time.Sleep(60 * time.Millisecond)
+	r.wait()
+	assert.False(t, stale.Load())
+}
+
+func TestThrottleTrailingIsDiscardedOnStop(t *testing.T) {
+	r := newRunner(Policy{Mode: ModeParallel, Throttle: 20 * time.Millisecond, Trailing: true}, testClock())
+
+	var ran atomic.Bool
+	require.True(t, r.run(context.Background(), "sensor.power", noRun))
+	require.False(t, r.run(context.Background(), "sensor.power", func(context.Context) { ran.Store(true) }))
+	r.stop()
+
+	time.Sleep(40 * time.Millisecond)
+	r.wait()
+	assert.False(t, ran.Load())
+}
+
+func TestThrottleTrailingNeedsAThrottle(t *testing.T) {
+	_, err := NewAutomation("burst").On(AtStartup()).ThrottleTrailing().Do(noAction).Build()
+	assert.ErrorIs(t, err, ErrInvalidAutomation)
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
+)
+
+// ErrReadOnly reports a call refused because the app was built ReadOnly.
+var ErrReadOnly = errors.New("app is read-only")
+
+// readOnlySender refuses every request, logging what it would have sent, so a
+// read-only app's log shows what it would have done.
+type readOnlySender struct {
+	log *slog.Logger
+}
+
+func (r readOnlySender) Send(req types.Request) error {
+	return r.refuse(req)
+}
+
+func (r readOnlySender) SendForResult(_ context.Context, req types.Request) (json.RawMessage, error) {
+	return nil, r.refuse(req)
+}
+
+func (r readOnlySender) refuse(req types.Request) error {
+	switch call := req.(type) {
+	case *services.BaseServiceRequest:
+		target := call.Target.String()
+		r.log.Info("Read-only, not calling", "service", call.Domain+"."+call.Service, "target", target, "data", call.ServiceData)
+		if target != "" {
+			return fmt.Errorf("%w: refused %s.%s on %s", ErrReadOnly, call.Domain, call.Service, target)
+		}
+		return fmt.Errorf("%w: refused %s.%s", ErrReadOnly, call.Domain, call.Service)
+	case *services.FireEventRequest:
+		r.log.Info("Read-only, not firing", "event_type", call.EventType, "data", call.EventData)
+		return fmt.Errorf("%w: refused firing %s", ErrReadOnly, call.EventType)
+	case rawCommand:
+		r.log.Info("Read-only, not sending", "command", call["type"])
+		return fmt.Errorf("%w: refused %s", ErrReadOnly, call["type"])
+	default:
+		return fmt.Errorf("%w: refused %T", ErrReadOnly, req)
+	}
+}
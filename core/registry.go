@@ -0,0 +1,318 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Xevion/go-ha/internal/connect"
+)
+
+// Area is a room or space from Home Assistant's area registry.
+type Area struct {
+	ID   string
+	Name string
+}
+
+// Device is a device from Home Assistant's device registry.
+type Device struct {
+	ID string
+	// Name is the name the user gave the device, or the integration's name for
+	// it when they gave none.
+	Name         string
+	Manufacturer string
+	Model        string
+	AreaID       string
+	Disabled     bool
+}
+
+// RegistryEntry is an entity's entry in Home Assistant's entity registry.
+type RegistryEntry struct {
+	EntityID string
+	DeviceID string
+	// AreaID is the entity's own area. Most entities leave it empty and sit in
+	// their device's; EntitiesInArea accounts for both.
+	AreaID   string
+	Platform string
+	Disabled bool
+	Hidden   bool
+}
+
+// registryEvents announce a change to one of the registries.
+var registryEvents = []string{"area_registry_updated", "device_registry_updated", "entity_registry_updated"}
+
+// Registry reads Home Assistant's area, device and entity registries, so an
+// automation can address a room rather than a list of entities that goes stale
+// the next time a lamp is added.
+//
+// The registries are fetched on first use and again after Home Assistant
+// announces a change to any of them.
+type Registry struct {
+	app *App
+
+	mu         sync.Mutex
+	subscribed bool
+	stale      bool
+	loaded     bool
+	areas      []Area
+	devices    []Device
+	entities   []RegistryEntry
+}
+
+// Registry returns the app's view of Home Assistant's registries.
+func (app *App) Registry() *Registry {
+	return app.registry
+}
+
+// Refresh fetches the registries now, rather than on the next read after a
+// change.
+func (r *Registry) Refresh() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fetchLocked()
+}
+
+// loadLocked brings the registries up to date if they need it. Called with
+// mu held.
+func (r *Registry) loadLocked() error {
+	if r.loaded && !r.stale {
+		return nil
+	}
+	return r.fetchLocked()
+}
+
+func (r *Registry) fetchLocked() error {
+	if !r.subscribed {
+		for _, eventType := range registryEvents {
+			if err := r.app.client.Subscribe(connect.Subscription{EventType: eventType}, r.invalidate); err != nil {
+				return fmt.Errorf("subscribing to %s: %w", eventType, err)
+			}
+		}
+		r.subscribed = true
+	}
+	// Cleared before fetching, so a change announced while the lists are in
+	// flight marks them stale again rather than being lost.
+	r.stale = false
+
+	var areas []struct {
+		AreaID string `json:"area_id"`
+		Name   string `json:"name"`
+	}
+	var devices []struct {
+		ID           string  `json:"id"`
+		Name         *string `json:"name"`
+		NameByUser   *string `json:"name_by_user"`
+		Manufacturer *string `json:"manufacturer"`
+		Model        *string `json:"model"`
+		AreaID       *string `json:"area_id"`
+		DisabledBy   *string `json:"disabled_by"`
+	}
+	var entities []struct {
+		EntityID   string  `json:"entity_id"`
+		DeviceID   *string `json:"device_id"`
+		AreaID     *string `json:"area_id"`
+		Platform   string  `json:"platform"`
+		DisabledBy *string `json:"disabled_by"`
+		HiddenBy   *string `json:"hidden_by"`
+	}
+	for _, list := range []struct {
+		command string
+		into    any
+	}{
+		{"config/area_registry/list", &areas},
+		{"config/device_registry/list", &devices},
+		{"config/entity_registry/list", &entities},
+	} {
+		raw, err := r.app.Command(map[string]any{"type": list.command})
+		if err != nil {
+			return fmt.Errorf("%s: %w", list.command, err)
+		}
+		if err := json.Unmarshal(raw, list.into); err != nil {
+			return fmt.Errorf("decoding %s: %w", list.command, err)
+		}
+	}
+
+	r.areas = make([]Area, 0, len(areas))
+	for _, a := range areas {
+		r.areas = append(r.areas, Area{ID: a.AreaID, Name: a.Name})
+	}
+	r.devices = make([]Device, 0, len(devices))
+	for _, d := range devices {
+		name := deref(d.Name)
+		if d.NameByUser != nil && *d.NameByUser != "" {
+			name = *d.NameByUser
+		}
+		r.devices = append(r.devices, Device{
+			ID:           d.ID,
+			Name:         name,
+			Manufacturer: deref(d.Manufacturer),
+			Model:        deref(d.Model),
+			AreaID:       deref(d.AreaID),
+			Disabled:     d.DisabledBy != nil,
+		})
+	}
+	r.entities = make([]RegistryEntry, 0, len(entities))
+	for _, e := range entities {
+		r.entities = append(r.entities, RegistryEntry{
+			EntityID: e.EntityID,
+			DeviceID: deref(e.DeviceID),
+			AreaID:   deref(e.AreaID),
+			Platform: e.Platform,
+			Disabled: e.DisabledBy != nil,
+			Hidden:   e.HiddenBy != nil,
+		})
+	}
+	r.loaded = true
+	return nil
+}
+
+func (r *Registry) invalidate(connect.Message) {
+	r.mu.Lock()
+	r.stale = true
+	r.mu.Unlock()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Areas lists every area.
+func (r *Registry) Areas() ([]Area, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(); err != nil {
+		return nil, err
+	}
+	return slices.Clone(r.areas), nil
+}
+
+// Devices lists every device.
+func (r *Registry) Devices() ([]Device, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(); err != nil {
+		return nil, err
+	}
+	return slices.Clone(r.devices), nil
+}
+
+// Entities lists every entity registry entry.
+func (r *Registry) Entities() ([]RegistryEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(); err != nil {
+		return nil, err
+	}
+	return slices.Clone(r.entities), nil
+}
+
+// EntitiesInArea lists the entities in an area, given by id or by name: those
+// assigned to it directly, and those of its devices that are not assigned
+// elsewhere. Disabled entities are left out, having no state to act on. The
+// ids are sorted.
+func (r *Registry) EntitiesInArea(area string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(); err != nil {
+		return nil, err
+	}
+	areaID, err := r.areaIDLocked(area)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceArea := make(map[string]string, len(r.devices))
+	for _, d := range r.devices {
+		deviceArea[d.ID] = d.AreaID
+	}
+	var ids []string
+	for _, e := range r.entities {
+		if e.Disabled {
+			continue
+		}
+		in := e.AreaID
+		if in == "" {
+			in = deviceArea[e.DeviceID]
+		}
+		if in == areaID {
+			ids = append(ids, e.EntityID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// DevicesInArea lists the devices assigned to an area, given by id or by name.
+func (r *Registry) DevicesInArea(area string) ([]Device, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(); err != nil {
+		return nil, err
+	}
+	areaID, err := r.areaIDLocked(area)
+	if err != nil {
+		return nil, err
+	}
+	var out []Device
+	for _, d := range r.devices {
+		if d.AreaID == areaID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// DevicesByManufacturer lists the devices of a manufacturer, compared without
+// regard to case, since integrations disagree on "IKEA" and "Ikea".
+func (r *Registry) DevicesByManufacturer(manufacturer string) ([]Device, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(); err != nil {
+		return nil, err
+	}
+	var out []Device
+	for _, d := range r.devices {
+		if strings.EqualFold(d.Manufacturer, manufacturer) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// EntitiesOfDevice lists a device's entities, sorted, disabled ones included.
+func (r *Registry) EntitiesOfDevice(deviceID string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(); err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range r.entities {
+		if e.DeviceID == deviceID {
+			ids = append(ids, e.EntityID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// areaIDLocked resolves an area given by id, or by name without regard to
+// case.
+func (r *Registry) areaIDLocked(area string) (string, error) {
+	for _, a := range r.areas {
+		if a.ID == area {
+			return a.ID, nil
+		}
+	}
+	for _, a := range r.areas {
+		if strings.EqualFold(a.Name, area) {
+			return a.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no area %q", ErrInvalidArgs, area)
+}
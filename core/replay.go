@@ -0,0 +1,95 @@
+package core
+
+import (
+	"slices"
+	"time"
+)
+
+// replayLookback is how far back ReplayLast reads history: the recorder's
+// default keep_days, past which there is nothing left to read.
+const replayLookback = 10 * 24 * time.Hour
+
+// replayBindings returns the bindings whose trigger asks for a replay.
+func (app *App) replayBindings() []binding {
+	app.registryMu.RLock()
+	defer app.registryMu.RUnlock()
+
+	var out []binding
+	for _, b := range app.automations[eventStateChanged] {
+		if t, ok := b.trigger.(StateChangeTrigger); ok && t.replay > 0 {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// replay fires the binding's automation with the last transitions its trigger
+// would have fired on, oldest first.
+func (app *App) replay(b binding) {
+	trig := b.trigger.(StateChangeTrigger)
+	now := app.clock.Now()
+
+	var events []Event
+	for _, id := range app.replayEntities(trig) {
+		states, err := app.History(id, now.Add(-replayLookback), now)
+		if err != nil {
+			b.automation.runtime.logger().Warn("Could not read history to replay", "entity", id, "error", err)
+			continue
+		}
+		// The first state is the one in effect when the window opened, moved
+		// up to it, so only what follows it is a transition.
+		for i := 1; i < len(states); i++ {
+			ev := Event{
+				Type:     eventStateChanged,
+				EntityID: id,
+				From:     states[i-1].EntityState,
+				To:       states[i].EntityState,
+				Replayed: true,
+			}
+			if trig.Matches(ev) {
+				events = append(events, ev)
+			}
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b Event) int {
+		return a.To.LastChanged.Compare(b.To.LastChanged)
+	})
+	if len(events) > trig.replay {
+		events = events[len(events)-trig.replay:]
+	}
+
+	for _, ev := range events {
+		if app.ctx.Err() != nil {
+			return
+		}
+		ec := EvalContext{Clock: app.clock, State: app.state, Event: ev}
+		deps := Run{Services: app.service, State: app.state, Event: ev, Trigger: trig}
+		if !b.automation.fire(app.ctx, ec, deps, ev.EntityID) {
+			b.automation.runtime.logger().Debug("Replayed event did not run", "entity", ev.EntityID)
+		}
+	}
+}
+
+// replayEntities resolves the trigger's ids to entities, expanding patterns
+// against the entities known now.
+func (app *App) replayEntities(t StateChangeTrigger) []string {
+	var ids []string
+	var patterns bool
+	for _, id := range t.entityIDs {
+		if isPattern(id) {
+			patterns = true
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if patterns {
+		all, _ := app.state.cache.snapshot()
+		for _, es := range all {
+			if !slices.Contains(ids, es.EntityID) && t.watches(es.EntityID) {
+				ids = append(ids, es.EntityID)
+			}
+		}
+	}
+	return ids
+}
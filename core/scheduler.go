@@ -1,61 +1,101 @@
 package core
 
 import (
+	"container/heap"
 	"context"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
-	"github.com/Workiva/go-datastructures/queue"
-
 	"github.com/Xevion/go-ha/internal/scheduling"
+	"github.com/Xevion/go-ha/types"
 )
 
-// queueItem adapts a scheduled entry to the priority queue's interface. It is
-// unexported deliberately: exporting it would put the queue dependency in this
-// package's public API.
-type queueItem struct {
-	Value    any
-	Priority float64
-}
-
-func (i queueItem) Compare(other queue.Item) int {
-	switch {
-	case i.Priority > other.(queueItem).Priority:
-		return 1
-	case i.Priority == other.(queueItem).Priority:
-		return 0
-	default:
-		return -1
-	}
-}
-
 // scheduledEntry pairs a trigger with the callback to run when it fires, and
 // remembers the instant it is currently queued for.
 type scheduledEntry struct {
 	trigger scheduling.Trigger
 	run     func()
 	fireAt  time.Time
+
+	// seq numbers entries in the order they were added, which is the order
+	// entries otherwise alike are staggered in, and fire in when due at the
+	// same instant.
+	seq uint64
+}
+
+// entryHeap orders entries by fire time, then by the order they were added. It
+// is a plain slice under the scheduler's lock rather than a concurrent queue:
+// every operation on it is already serialised by that lock, and a queue that
+// blocked on being read empty was one unguarded call away from hanging the
+// run loop.
+type entryHeap []*scheduledEntry
+
+func (h entryHeap) Len() int { return len(h) }
+
+func (h entryHeap) Less(i, j int) bool {
+	if !h[i].fireAt.Equal(h[j].fireAt) {
+		return h[i].fireAt.Before(h[j].fireAt)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h entryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *entryHeap) Push(x any) { *h = append(*h, x.(*scheduledEntry)) }
+
+func (h *entryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
 }
 
 // scheduler orders triggers by their next fire time. It needs nothing but a
 // Clock, so queue ordering and requeue arithmetic can be exercised without a
 // connection, an HTTP client or a context.
 type scheduler struct {
-	// mu makes composite operations atomic against each other. refresh drains
-	// the whole queue and refills it, and without this the run loop can peek
-	// into that window, see an empty queue, and conclude there is nothing left
-	// to do ever again.
+	// mu guards everything below. Callbacks are run outside it, so one that
+	// registers an automation, or blocks on a slow state read, holds up
+	// neither the queue nor anyone adding to it.
 	mu    sync.Mutex
-	queue *queue.PriorityQueue
+	queue entryHeap
 	clock Clock
+
+	// log is where the scheduler reports, slog's default logger until the app
+	// hands it its own.
+	log *slog.Logger
+
+	// changed, if set, is told whenever the queued times move: an entry
+	// added, fired or re-derived. It is called under mu and must not block.
+	changed func()
+
+	// wake interrupts the run loop's sleep when the queued times move, so an
+	// entry added at runtime for earlier than the one slept on is not late.
+	wake chan struct{}
+
+	added uint64
+
+	// parked holds dynamic entries that had no next time when asked. Their
+	// source may yet supply one, as sun.sun does once it is readable again,
+	// so refresh retries them rather than their being dropped for good.
+	parked []*scheduledEntry
 }
 
 func newScheduler(clock Clock) *scheduler {
-	return &scheduler{
-		queue: queue.NewPriorityQueue(100, false),
-		clock: clock,
+	return &scheduler{clock: clock, log: slog.Default(), wake: make(chan struct{}, 1)}
+}
+
+// triggerAttrs describes a trigger for the log, naming the automation it
+// belongs to when it has one.
+func triggerAttrs(trigger scheduling.Trigger) []any {
+	attrs := []any{"trigger", trigger}
+	if a, ok := trigger.(schedulerAdapter); ok {
+		attrs = append(attrs, "automation", a.automation)
 	}
+	return attrs
 }
 
 // add queues trigger for its first fire time after the clock's current instant.
@@ -66,42 +106,91 @@ func (s *scheduler) add(trigger scheduling.Trigger, run func()) bool {
 
 	next := trigger.NextTime(s.clock.Now())
 	if next == nil {
-		slog.Warn("Trigger has no next occurrence, not scheduling", "trigger", trigger)
+		s.log.Warn("Trigger has no next occurrence, not scheduling", triggerAttrs(trigger)...)
 		return false
 	}
 
-	s.push(&scheduledEntry{trigger: trigger, run: run, fireAt: *next})
+	s.added++
+	s.push(&scheduledEntry{trigger: trigger, run: run, fireAt: *next, seq: s.added})
+	s.notifyLocked()
 	return true
 }
 
-func (s *scheduler) push(entry *scheduledEntry) {
-	s.queue.Put(queueItem{
-		Value:    entry,
-		Priority: float64(entry.fireAt.Unix()),
+// remove unschedules every entry whose trigger matches, parked ones included,
+// and reports how many went. A run already taken off the queue to fire is not
+// recalled.
+func (s *scheduler) remove(match func(scheduling.Trigger) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	kept := s.queue[:0]
+	for _, entry := range s.queue {
+		if match(entry.trigger) {
+			removed++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	clear(s.queue[len(kept):])
+	s.queue = kept
+	heap.Init(&s.queue)
+
+	s.parked = slices.DeleteFunc(s.parked, func(entry *scheduledEntry) bool {
+		if match(entry.trigger) {
+			removed++
+			return true
+		}
+		return false
 	})
+
+	if removed > 0 {
+		s.notifyLocked()
+	}
+	return removed
 }
 
-// pop removes and returns the entry due soonest, or nil when nothing is queued.
-func (s *scheduler) pop() *scheduledEntry {
-	// Get blocks until something is queued, and nothing else ever will be once
-	// the run loop owns the scheduler, so it must never be called unguarded.
-	if s.queue.Empty() {
-		return nil
+// onChange sets the function told when the queued times move.
+func (s *scheduler) onChange(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changed = fn
+}
+
+func (s *scheduler) notifyLocked() {
+	if s.changed != nil {
+		s.changed()
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
 	}
+}
+
+func (s *scheduler) push(entry *scheduledEntry) {
+	heap.Push(&s.queue, entry)
+}
 
-	items, err := s.queue.Get(1)
-	if err != nil || len(items) == 0 {
+// pop removes and returns the entry due soonest, or nil when nothing is queued.
+func (s *scheduler) pop() *scheduledEntry {
+	if len(s.queue) == 0 {
 		return nil
 	}
-	return items[0].(queueItem).Value.(*scheduledEntry)
+	return heap.Pop(&s.queue).(*scheduledEntry)
 }
 
-// requeue puts an entry back for its following occurrence, or drops it when the
-// trigger has none left.
+// requeue puts an entry back for its following occurrence. One whose trigger
+// has none left is dropped, unless the trigger is dynamic, in which case it is
+// parked until a refresh finds it a time.
 func (s *scheduler) requeue(entry *scheduledEntry) bool {
 	next := entry.trigger.NextTime(entry.fireAt)
 	if next == nil {
-		slog.Warn("Trigger has no further occurrence, dropping", "trigger", entry.trigger)
+		if isDynamic(entry.trigger) {
+			s.log.Warn("Trigger has no next occurrence for now, waiting for its source to change", triggerAttrs(entry.trigger)...)
+			s.parked = append(s.parked, entry)
+			return false
+		}
+		s.log.Warn("Trigger has no further occurrence, dropping", triggerAttrs(entry.trigger)...)
 		return false
 	}
 
@@ -133,42 +222,46 @@ func (s *scheduler) nextFireAt() (time.Time, bool) {
 }
 
 func (s *scheduler) peekLocked() *scheduledEntry {
-	item := s.queue.Peek()
-	if item == nil {
+	if len(s.queue) == 0 {
 		return nil
 	}
-	return item.(queueItem).Value.(*scheduledEntry)
+	return s.queue[0]
 }
 
 // runDue fires every entry due at or before now, requeueing each, and reports
 // how many ran. A process suspended across several slots catches up here rather
 // than losing them.
+//
+// The due entries are taken and requeued under the lock, and run once it is
+// released, in the order they fell due.
 func (s *scheduler) runDue(now time.Time) int {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	var due []func()
 
-	fired := 0
+	s.mu.Lock()
 	for {
-		entry := s.pop()
-		if entry == nil {
-			return fired
-		}
-
-		if entry.fireAt.After(now) {
-			s.push(entry)
-			return fired
+		entry := s.peekLocked()
+		if entry == nil || entry.fireAt.After(now) {
+			break
 		}
-
-		entry.run()
+		s.pop()
+		due = append(due, entry.run)
 		s.requeue(entry)
-		fired++
 	}
+	if len(due) > 0 {
+		s.notifyLocked()
+	}
+	s.mu.Unlock()
+
+	for _, run := range due {
+		run()
+	}
+	return len(due)
 }
 
 func (s *scheduler) len() int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.queue.Len()
+	return len(s.queue)
 }
 
 // dynamicTrigger is implemented by triggers whose times move on their own,
@@ -178,6 +271,11 @@ type dynamicTrigger interface {
 	dynamic() bool
 }
 
+func isDynamic(trigger scheduling.Trigger) bool {
+	dyn, ok := trigger.(dynamicTrigger)
+	return ok && dyn.dynamic()
+}
+
 // refresh re-derives the fire time of every dynamic entry and reports how many
 // moved.
 //
@@ -189,42 +287,123 @@ func (s *scheduler) refresh(now time.Time) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.queue.Empty() {
-		return 0
-	}
+	moved := 0
 
-	items, err := s.queue.Get(s.queue.Len())
-	if err != nil {
-		return 0
+	// Parked entries are tried first, and any that now have a time rejoin
+	// the queue as moved.
+	parked := s.parked
+	s.parked = nil
+	for _, entry := range parked {
+		next := entry.trigger.NextTime(now)
+		if next == nil {
+			s.parked = append(s.parked, entry)
+			continue
+		}
+		entry.fireAt = *next
+		s.push(entry)
+		moved++
 	}
 
-	moved := 0
-	for _, item := range items {
-		entry := item.(queueItem).Value.(*scheduledEntry)
-
+	for _, entry := range s.queue {
 		// An entry already due is about to run. Re-deriving it here would push
 		// it past now and skip that occurrence entirely.
-		if entry.fireAt.After(now) {
-			if dyn, ok := entry.trigger.(dynamicTrigger); ok && dyn.dynamic() {
-				if next := entry.trigger.NextTime(now); next != nil && !next.Equal(entry.fireAt) {
-					entry.fireAt = *next
-					moved++
-				}
+		if entry.fireAt.After(now) && isDynamic(entry.trigger) {
+			if next := entry.trigger.NextTime(now); next != nil && !next.Equal(entry.fireAt) {
+				entry.fireAt = *next
+				moved++
 			}
 		}
-		s.push(entry)
 	}
+	heap.Init(&s.queue)
 
+	if moved > 0 {
+		s.notifyLocked()
+	}
 	return moved
 }
 
+// stagger spreads the startup runs still queued out from start, one every
+// interval, ordered by their Order and then by when they were added. Everything
+// else is left where it was.
+func (s *scheduler) stagger(start time.Time, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var startup []*scheduledEntry
+	for _, entry := range s.queue {
+		if startupOrder(entry) != nil {
+			startup = append(startup, entry)
+		}
+	}
+
+	slices.SortFunc(startup, func(a, b *scheduledEntry) int {
+		if oa, ob := *startupOrder(a), *startupOrder(b); oa != ob {
+			return oa - ob
+		}
+		return int(a.seq) - int(b.seq)
+	})
+	for i, entry := range startup {
+		entry.fireAt = start.Add(time.Duration(i) * interval)
+	}
+	if len(startup) > 0 {
+		heap.Init(&s.queue)
+		s.notifyLocked()
+	}
+}
+
+// startupOrder returns the order of an entry for a startup trigger, or nil for
+// any other.
+func startupOrder(entry *scheduledEntry) *int {
+	adapter, ok := entry.trigger.(schedulerAdapter)
+	if !ok {
+		return nil
+	}
+	st, ok := adapter.trigger.(*startupTrigger)
+	if !ok {
+		return nil
+	}
+	return &st.order
+}
+
+// upcomingRun is one future occurrence of a queued trigger.
+type upcomingRun struct {
+	trigger scheduling.Trigger
+	at      time.Time
+}
+
+// upcoming lists every occurrence due after from and no later than until,
+// soonest first, up to limit per trigger so a short interval cannot swamp
+// the rest. The queue itself is left as it was.
+func (s *scheduler) upcoming(from, until time.Time, limit int) []upcomingRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var runs []upcomingRun
+	for _, entry := range s.queue {
+		at := entry.fireAt
+		for n := 0; n < limit && !at.After(until); n++ {
+			if at.After(from) {
+				runs = append(runs, upcomingRun{trigger: entry.trigger, at: at})
+			}
+			next := entry.trigger.NextTime(at)
+			if next == nil || !next.After(at) {
+				break
+			}
+			at = *next
+		}
+	}
+
+	slices.SortStableFunc(runs, func(a, b upcomingRun) int { return a.at.Compare(b.at) })
+	return runs
+}
+
 // run drives the scheduler until the context is cancelled. It fires everything
 // due, then sleeps until the next entry falls due, a dynamic trigger moves, or
 // the app shuts down.
 func (s *scheduler) run(ctx context.Context, rescheduled <-chan struct{}, what string) {
 	for {
 		if ctx.Err() != nil {
-			slog.Info("Scheduler shutting down", "kind", what)
+			s.log.Info("Scheduler shutting down", "kind", what)
 			return
 		}
 
@@ -238,24 +417,43 @@ func (s *scheduler) run(ctx context.Context, rescheduled <-chan struct{}, what s
 		// peek during a refresh stopped every schedule for good.
 		wait := time.Hour
 		if next, ok := s.nextFireAt(); ok {
-			wait = time.Until(next)
+			wait = next.Sub(s.clock.Now())
 		}
 
-		timer := time.NewTimer(wait)
+		fired, stop := s.timer(wait)
 		select {
-		case <-timer.C:
+		case <-fired:
 		case <-rescheduled:
 			// A dynamic trigger moved, and the new time can be earlier than
 			// the one being slept on, so the queue is re-read.
-			timer.Stop()
+			stop()
+		case <-s.wake:
+			// Likewise for an entry added or removed while sleeping.
+			stop()
 		case <-ctx.Done():
-			timer.Stop()
-			slog.Info("Scheduler shutting down", "kind", what)
+			stop()
+			s.log.Info("Scheduler shutting down", "kind", what)
 			return
 		}
 	}
 }
 
+// timer sleeps on the clock when it can be slept on, so a test clock that is
+// advanced past the next entry wakes the loop, and on a real timer otherwise.
+// The wait is measured on the clock either way: a fixed clock far from the
+// wall clock would otherwise be slept on for the wrong gap.
+func (s *scheduler) timer(d time.Duration) (<-chan time.Time, func()) {
+	return clockTimer(s.clock, d)
+}
+
+func clockTimer(clock Clock, d time.Duration) (<-chan time.Time, func()) {
+	if clock, ok := clock.(types.TimerClock); ok {
+		return clock.Timer(d)
+	}
+	t := time.NewTimer(d)
+	return t.C, func() { t.Stop() }
+}
+
 // sameDate reports whether a and b fall on the same calendar day.
 func sameDate(a, b time.Time) bool {
 	y1, m1, d1 := a.Date()
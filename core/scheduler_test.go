@@ -1,6 +1,9 @@
 package core
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -250,3 +253,186 @@ func TestSchedulerKeepsDistinctEntriesAtTheSameInstant(t *testing.T) {
 
 	assert.ElementsMatch(t, []string{"first", "second"}, fired)
 }
+
+func TestSchedulerUpcomingListsOccurrencesWithoutConsumingThem(t *testing.T) {
+	s := newScheduler(internal.NewFakeClock(schedulerBase))
+	s.add(fixedAt(18, 0), noop)
+	s.add(fixedAt(9, 0), noop)
+
+	runs := s.upcoming(schedulerBase, schedulerBase.Add(48*time.Hour), 10)
+	var got []time.Time
+	for _, r := range runs {
+		got = append(got, r.at)
+	}
+	assert.Equal(t, []time.Time{
+		time.Date(2025, time.November, 1, 18, 0, 0, 0, time.Local),
+		time.Date(2025, time.November, 2, 9, 0, 0, 0, time.Local),
+		time.Date(2025, time.November, 2, 18, 0, 0, 0, time.Local),
+		time.Date(2025, time.November, 3, 9, 0, 0, 0, time.Local),
+	}, got)
+	assert.Equal(t, 2, s.len(), "listing leaves the queue as it was")
+
+	assert.Len(t, s.upcoming(schedulerBase, schedulerBase.Add(48*time.Hour), 1), 2, "capped per trigger")
+}
+
+func TestSchedulerReportsChanges(t *testing.T) {
+	clock := internal.NewFakeClock(schedulerBase)
+	s := newScheduler(clock)
+	changes := 0
+	s.onChange(func() { changes++ })
+
+	s.add(fixedAt(18, 0), noop)
+	assert.Equal(t, 1, changes)
+
+	s.runDue(clock.Now())
+	assert.Equal(t, 1, changes, "nothing fired, nothing moved")
+
+	clock.Set(time.Date(2025, time.November, 1, 18, 0, 1, 0, time.Local))
+	s.runDue(clock.Now())
+	assert.Equal(t, 2, changes)
+}
+
+func TestSchedulerStaggersStartupRunsInOrder(t *testing.T) {
+	s := newScheduler(internal.NewFakeClock(schedulerBase))
+
+	var fired []string
+	add := func(name string, trig ScheduleTrigger) {
+		s.add(schedulerAdapter{trigger: trig}, func() { fired = append(fired, name) })
+	}
+	add("late", AtStartup().Order(5))
+	add("first registered", AtStartup())
+	add("early", AtStartup().Order(-1))
+	add("second registered", AtStartup())
+	s.add(fixedAt(14, 0), noop)
+
+	s.stagger(schedulerBase, time.Second)
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, 1, s.runDue(schedulerBase.Add(time.Duration(i)*time.Second)), "one every interval")
+	}
+	assert.Equal(t, []string{"early", "first registered", "second registered", "late"}, fired)
+	assert.Equal(t, 1, s.len(), "only the daily entry is left")
+}
+
+func TestSchedulerRemove(t *testing.T) {
+	s := newScheduler(internal.NewFakeClock(schedulerBase))
+	keep, drop := fixedAt(9, 0), fixedAt(18, 0)
+	s.add(keep, noop)
+	s.add(drop, noop)
+	s.add(drop, noop)
+
+	assert.Equal(t, 2, s.remove(func(t scheduling.Trigger) bool { return t == drop }))
+	assert.Equal(t, 1, s.len())
+	assert.Same(t, keep, s.peek().trigger)
+	assert.Zero(t, s.remove(func(t scheduling.Trigger) bool { return t == drop }))
+}
+
+// A callback runs outside the scheduler's lock, so it may add to the scheduler
+// it was fired from. Under the lock this deadlocked.
+func TestSchedulerCallbackMayAddSchedules(t *testing.T) {
+	clock := internal.NewFakeClock(schedulerBase)
+	s := newScheduler(clock)
+	s.add(fixedAt(14, 0), func() { s.add(fixedAt(15, 0), noop) })
+
+	done := make(chan int)
+	go func() { done <- s.runDue(time.Date(2025, time.November, 1, 14, 0, 0, 0, time.Local)) }()
+	select {
+	case n := <-done:
+		assert.Equal(t, 1, n)
+	case <-time.After(5 * time.Second):
+		t.Fatal("runDue deadlocked on a callback adding a schedule")
+	}
+	assert.Equal(t, 2, s.len())
+}
+
+// An entry added while the loop sleeps on a later one must wake it, or the new
+// entry waits for the old one's time.
+func TestSchedulerRunWakesForAnEarlierEntry(t *testing.T) {
+	s := newScheduler(internal.RealClock{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.run(ctx, nil, "test")
+
+	s.add(&oneShotTrigger{at: time.Now().Add(time.Hour)}, noop)
+	time.Sleep(20 * time.Millisecond)
+
+	fired := make(chan struct{})
+	s.add(&oneShotTrigger{at: time.Now().Add(10 * time.Millisecond)}, func() { close(fired) })
+	select {
+	case <-fired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the earlier entry waited behind the later one")
+	}
+}
+
+// On a clock that can be slept on, the loop fires when the clock is moved past
+// an entry, with no real time waited.
+func TestSchedulerRunSleepsOnTheClock(t *testing.T) {
+	clock := testClock()
+	s := newScheduler(clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fired := make(chan struct{})
+	s.add(&oneShotTrigger{at: clock.Now().Add(6 * time.Hour)}, func() { close(fired) })
+	go s.run(ctx, nil, "test")
+
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	clock.Advance(5 * time.Hour)
+	select {
+	case <-fired:
+		t.Fatal("fired an hour early")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Hour)
+	select {
+	case <-fired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("advancing the clock did not wake the loop")
+	}
+}
+
+// Registering and removing schedules from many goroutines while the loop runs
+// must neither race nor leave the queue out of order. Run with -race.
+func TestSchedulerUnderConcurrentRegistration(t *testing.T) {
+	s := newScheduler(internal.RealClock{})
+	ctx, cancel := context.WithCancel(context.Background())
+	loop := make(chan struct{})
+	go func() { defer close(loop); s.run(ctx, nil, "test") }()
+
+	var fired atomic.Int64
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				every, err := scheduling.NewIntervalTrigger(time.Duration(10+(w+i)%5*10) * time.Millisecond)
+				require.NoError(t, err)
+				s.add(every, func() { fired.Add(1) })
+				switch i % 4 {
+				case 0:
+					s.remove(func(t scheduling.Trigger) bool { return t == every })
+				case 1:
+					s.refresh(time.Now())
+				case 2:
+					s.upcoming(time.Now(), time.Now().Add(10*time.Millisecond), 3)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-loop
+
+	assert.Positive(t, fired.Load())
+	assert.Equal(t, 8*150, s.len(), "each worker removed one in four of its schedules")
+
+	var last time.Time
+	for entry := s.pop(); entry != nil; entry = s.pop() {
+		assert.False(t, entry.fireAt.Before(last), "the queue came out of order")
+		last = entry.fireAt
+	}
+}
@@ -1,60 +1,245 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
 	"github.com/Xevion/go-ha/internal/connect"
 	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
 )
 
 type Service struct {
-	AdaptiveLighting  *services.AdaptiveLighting
-	AlarmControlPanel *services.AlarmControlPanel
-	Climate           *services.Climate
-	Cover             *services.Cover
-	HomeAssistant     *services.HomeAssistant
-	Light             *services.Light
-	Lock              *services.Lock
-	MediaPlayer       *services.MediaPlayer
-	Switch            *services.Switch
-	InputBoolean      *services.InputBoolean
-	InputButton       *services.InputButton
-	InputText         *services.InputText
-	InputDatetime     *services.InputDatetime
-	InputNumber       *services.InputNumber
-	Event             *services.Event
-	Notify            *services.Notify
-	Number            *services.Number
-	Scene             *services.Scene
-	Script            *services.Script
-	Timer             *services.Timer
-	TTS               *services.TTS
-	Vacuum            *services.Vacuum
-	ZWaveJS           *services.ZWaveJS
-}
-
-func newService(conn *connect.Client) *Service {
+	AdaptiveLighting       *services.AdaptiveLighting
+	AlarmControlPanel      *services.AlarmControlPanel
+	Calendar               *services.Calendar
+	Climate                *services.Climate
+	Cover                  *services.Cover
+	HomeAssistant          *services.HomeAssistant
+	Light                  *services.Light
+	Lock                   *services.Lock
+	Logbook                *services.Logbook
+	MediaPlayer            *services.MediaPlayer
+	MQTT                   *services.MQTT
+	Switch                 *services.Switch
+	InputBoolean           *services.InputBoolean
+	InputButton            *services.InputButton
+	InputText              *services.InputText
+	InputDatetime          *services.InputDatetime
+	InputNumber            *services.InputNumber
+	Event                  *services.Event
+	Notify                 *services.Notify
+	Number                 *services.Number
+	PersistentNotification *services.PersistentNotification
+	Scene                  *services.Scene
+	Script                 *services.Script
+	Timer                  *services.Timer
+	TTS                    *services.TTS
+	Vacuum                 *services.Vacuum
+	ZWaveJS                *services.ZWaveJS
+
+	// Template renders templates with Home Assistant's engine.
+	Template *Template
+
+	// conn carries calls that return once sent, and waiting those that wait
+	// for Home Assistant's answer.
+	conn    services.Sender
+	waiting services.ResultSender
+
+	// limits checks climate setpoints before they are sent.
+	limits services.ClimateLimits
+
+	// log is handed on to the services, for those that log.
+	log *slog.Logger
+
+	// state lists a domain's entities, for the calls that act on all of
+	// them but some.
+	state StateReader
+
+	// done is closed when the app stops, ending what the services leave
+	// running, such as a colour loop.
+	done <-chan struct{}
+
+	// overrides holds the restores TemporaryOverride has scheduled.
+	overrides *overrides
+
+	// deferred queues the calls made through At, and targets are those
+	// Targeting added, which At applies before queuing a call.
+	deferred *deferredCalls
+	targets  []services.Target
+}
+
+func newService(conn services.Sender, waiting services.ResultSender, limits services.ClimateLimits, state StateReader, log *slog.Logger, done <-chan struct{}) *Service {
+	// The typed services find their logger, a domain's entities and when the
+	// app stops on the sender they are built on.
+	logged := loggingSender{Sender: conn, log: log, state: state, done: done}
 	return &Service{
-		AdaptiveLighting:  services.BuildService[services.AdaptiveLighting](conn),
-		AlarmControlPanel: services.BuildService[services.AlarmControlPanel](conn),
-		Climate:           services.BuildService[services.Climate](conn),
-		Cover:             services.BuildService[services.Cover](conn),
-		Light:             services.BuildService[services.Light](conn),
-		HomeAssistant:     services.BuildService[services.HomeAssistant](conn),
-		Lock:              services.BuildService[services.Lock](conn),
-		MediaPlayer:       services.BuildService[services.MediaPlayer](conn),
-		Switch:            services.BuildService[services.Switch](conn),
-		InputBoolean:      services.BuildService[services.InputBoolean](conn),
-		InputButton:       services.BuildService[services.InputButton](conn),
-		InputText:         services.BuildService[services.InputText](conn),
-		InputDatetime:     services.BuildService[services.InputDatetime](conn),
-		InputNumber:       services.BuildService[services.InputNumber](conn),
-		Event:             services.BuildService[services.Event](conn),
-		Notify:            services.BuildService[services.Notify](conn),
-		Number:            services.BuildService[services.Number](conn),
-		Scene:             services.BuildService[services.Scene](conn),
-		Script:            services.BuildService[services.Script](conn),
-		Timer:             services.BuildService[services.Timer](conn),
-		TTS:               services.BuildService[services.TTS](conn),
-		Vacuum:            services.BuildService[services.Vacuum](conn),
-		ZWaveJS:           services.BuildService[services.ZWaveJS](conn),
+		conn:                   conn,
+		waiting:                waiting,
+		limits:                 limits,
+		state:                  state,
+		log:                    log,
+		done:                   done,
+		AdaptiveLighting:       services.BuildService[services.AdaptiveLighting](logged),
+		AlarmControlPanel:      services.BuildService[services.AlarmControlPanel](logged),
+		Calendar:               services.NewCalendar(logged, waiting),
+		Climate:                services.NewClimate(logged, limits),
+		Cover:                  services.BuildService[services.Cover](logged),
+		Light:                  services.BuildService[services.Light](logged),
+		HomeAssistant:          services.BuildService[services.HomeAssistant](logged),
+		Lock:                   services.BuildService[services.Lock](logged),
+		Logbook:                services.BuildService[services.Logbook](logged),
+		MediaPlayer:            services.BuildService[services.MediaPlayer](logged),
+		MQTT:                   services.BuildService[services.MQTT](logged),
+		Switch:                 services.BuildService[services.Switch](logged),
+		InputBoolean:           services.BuildService[services.InputBoolean](logged),
+		InputButton:            services.BuildService[services.InputButton](logged),
+		InputText:              services.BuildService[services.InputText](logged),
+		InputDatetime:          services.BuildService[services.InputDatetime](logged),
+		InputNumber:            services.BuildService[services.InputNumber](logged),
+		Event:                  services.BuildService[services.Event](logged),
+		Notify:                 services.BuildService[services.Notify](logged),
+		Number:                 services.BuildService[services.Number](logged),
+		PersistentNotification: services.BuildService[services.PersistentNotification](logged),
+		Scene:                  services.BuildService[services.Scene](logged),
+		Script:                 services.BuildService[services.Script](logged),
+		Timer:                  services.BuildService[services.Timer](logged),
+		TTS:                    services.BuildService[services.TTS](logged),
+		Vacuum:                 services.BuildService[services.Vacuum](logged),
+		ZWaveJS:                services.BuildService[services.ZWaveJS](logged),
+	}
+}
+
+// loggingSender tells the services built on it where to log, which entities
+// a domain has, and when the app stops.
+type loggingSender struct {
+	services.Sender
+	log   *slog.Logger
+	state StateReader
+	done  <-chan struct{}
+}
+
+func (l loggingSender) Logger() *slog.Logger { return l.log }
+
+func (l loggingSender) Done() <-chan struct{} { return l.done }
+
+func (l loggingSender) EntitiesInDomain(domain string) ([]string, error) {
+	entities, err := EntitiesInDomain(l.state, domain)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(entities))
+	for _, es := range entities {
+		ids = append(ids, es.EntityID)
+	}
+	return ids, nil
+}
+
+// WithResult returns the same services with every call waiting for Home
+// Assistant to answer, up to the app's ServiceTimeout, and returning the error
+// it reports. The plain services return as soon as a call is sent, so a call
+// against a missing entity fails without the caller hearing of it.
+func (s *Service) WithResult() *Service {
+	return s.clone(s.waiting, s.waiting)
+}
+
+// clone returns the same services sending through conn and waiting instead,
+// sharing everything else with s.
+func (s *Service) clone(conn services.Sender, waiting services.ResultSender) *Service {
+	svc := newService(conn, waiting, s.limits, s.state, s.log, s.done)
+	svc.Template = s.Template
+	svc.overrides = s.overrides
+	svc.deferred, svc.targets = s.deferred, s.targets
+	return svc
+}
+
+// Targeting returns the same services with every call also acting on the
+// given targets, so a room can be switched as one:
+//
+//	run.Services.Targeting(services.InArea("living_room")).Light.TurnOn("")
+//
+// The entity a call names, if any, is kept alongside them. Calls that take
+// their entity in service data rather than as a target, such as
+// adaptive_lighting's, are sent a target all the same, which their
+// integrations ignore.
+func (s *Service) Targeting(targets ...services.Target) *Service {
+	svc := s.clone(targetSender{next: s.conn, targets: targets}, targetSender{next: s.waiting, targets: targets})
+	svc.targets = append(slices.Clip(s.targets), targets...)
+	return svc
+}
+
+// targetSender adds targets to every service call it forwards.
+type targetSender struct {
+	next    services.Sender
+	targets []services.Target
+}
+
+func (t targetSender) Send(req types.Request) error {
+	return t.next.Send(t.apply(req))
+}
+
+func (t targetSender) SendForResult(ctx context.Context, req types.Request) (json.RawMessage, error) {
+	waiting, ok := t.next.(services.ResultSender)
+	if !ok {
+		return nil, errors.New("targeted sender cannot wait for results")
+	}
+	return waiting.SendForResult(ctx, t.apply(req))
+}
+
+func (t targetSender) apply(req types.Request) types.Request {
+	if call, ok := req.(*services.BaseServiceRequest); ok {
+		for _, target := range t.targets {
+			target.Apply(call)
+		}
+	}
+	return req
+}
+
+// CallWithResult invokes any service, including ones without a typed wrapper,
+// and waits for Home Assistant to confirm it.
+func (s *Service) CallWithResult(ctx context.Context, domain, service string, entityID services.EntityID, data map[string]any) (services.ServiceResult, error) {
+	return services.CallWithResult(ctx, s.waiting, domain, service, entityID, data)
+}
+
+// CallForResponse invokes a service that returns data, such as
+// weather.get_forecasts, and waits for what it returns.
+func (s *Service) CallForResponse(ctx context.Context, domain, service string, entityID services.EntityID, data map[string]any) (services.ServiceResult, error) {
+	return services.CallForResponse(ctx, s.waiting, domain, service, entityID, data)
+}
+
+// defaultServiceTimeout is how long a waiting call gives Home Assistant when
+// the app does not say.
+const defaultServiceTimeout = 10 * time.Second
+
+// resultSender sends each call and waits for its answer. The correlation is the
+// client's: every request carries an id, and the result naming it is routed
+// back to the caller.
+type resultSender struct {
+	client  *connect.Client
+	ctx     context.Context
+	timeout time.Duration
+}
+
+func (r resultSender) Send(req types.Request) error {
+	_, err := r.SendForResult(r.ctx, req)
+	return err
+}
+
+func (r resultSender) SendForResult(ctx context.Context, req types.Request) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	answer, err := r.client.Call(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil && r.ctx.Err() == nil {
+			return nil, fmt.Errorf("no answer within %s: %w", r.timeout, err)
+		}
+		return nil, err
 	}
+	return resultOf(answer)
 }
@@ -3,6 +3,9 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,13 +18,32 @@ type StateReader interface {
 	Equals(entityId, state string) (bool, error)
 }
 
+// staleReadLimit is how old a state read from Home Assistant may be and still
+// stand in for it when reading it again fails, so a brief network blip does
+// not flip a condition that read the entity a moment ago.
+const staleReadLimit = 60 * time.Second
+
 // state is used to retrieve state from Home Assistant.
 type state struct {
 	httpClient *internal.HttpClient
 	cache      *entityCache
+	clock      Clock
+	log        *slog.Logger
 
 	// seedMu serialises snapshot fetches against each other.
 	seedMu sync.Mutex
+
+	// recent holds the states read from Home Assistant while the cache could
+	// not answer, with when they were read, as the fallback for a read that
+	// fails. Guarded by recentMu.
+	recentMu sync.Mutex
+	recent   map[string]recentRead
+}
+
+// recentRead is a state read from Home Assistant and when it was read.
+type recentRead struct {
+	es EntityState
+	at time.Time
 }
 
 type EntityState struct {
@@ -35,12 +57,16 @@ type EntityState struct {
 	// LastUpdated moves on any change, attributes included, which is what
 	// orders two updates to the same entity.
 	LastUpdated time.Time `json:"last_updated"`
+
+	// Stale is set on a state read earlier standing in for one Home Assistant
+	// could not be reached for. It is never sent or received.
+	Stale bool `json:"-"`
 }
 
 // newState builds a reader backed by an empty cache. It fills on the first
 // connection, when the snapshot is fetched.
-func newState(c *internal.HttpClient) *state {
-	return &state{httpClient: c, cache: newEntityCache()}
+func newState(c *internal.HttpClient, clock Clock, log *slog.Logger) *state {
+	return &state{httpClient: c, cache: newEntityCache(), clock: clock, log: log}
 }
 
 // seed replaces the cache with a fresh snapshot of every entity. The window
@@ -67,22 +93,45 @@ func (s *state) seed() error {
 	}
 
 	s.cache.finishSeed(list)
+	s.forgetRecent()
 	return nil
 }
 
+// install replaces the cache with a complete set of states taken off the event
+// stream. Nothing on the stream can have overtaken it, so unlike seed it needs
+// no window to guard against racing events.
+func (s *state) install(list []EntityState) {
+	s.cache.beginSeed()
+	s.cache.finishSeed(list)
+	s.forgetRecent()
+}
+
+// forgetRecent drops the reads kept as fallbacks once the cache holds a
+// snapshot: it answers from here on, and anything read around it is older.
+func (s *state) forgetRecent() {
+	s.recentMu.Lock()
+	s.recent = nil
+	s.recentMu.Unlock()
+}
+
 // applyEvent folds a state_changed event into the cache. A null new state means
-// the entity was deleted.
-func (s *state) applyEvent(raw []byte) {
-	ev := parseEvent(raw)
-	if ev.Type != eventStateChanged || ev.EntityID == "" {
-		return
+// the entity was deleted. An event that cannot be decoded changes nothing and
+// is returned as an error.
+func (s *state) applyEvent(raw []byte) error {
+	ev, err := parseEvent(raw)
+	if err != nil {
+		return err
+	}
+	if ev.Type != eventStateChanged {
+		return nil
 	}
 
 	if ev.Deleted {
 		s.cache.remove(ev.EntityID)
-		return
+		return nil
 	}
 	s.cache.apply(ev.To)
+	return nil
 }
 
 func (s *state) Get(entityId string) (EntityState, error) {
@@ -96,10 +145,54 @@ func (s *state) Get(entityId string) (EntityState, error) {
 
 	resp, err := s.httpClient.GetState(entityId)
 	if err != nil {
+		// Only while Home Assistant cannot be reached: an answer, such as a
+		// refused token, is not bridged over.
+		if !internal.Transient(err) {
+			return EntityState{}, err
+		}
+		stale, ok := s.lastRead(entityId)
+		if !ok {
+			return EntityState{}, err
+		}
+		s.log.Warn("Home Assistant unreachable, serving a state read earlier", "entity", entityId, "error", err)
+		stale.Stale = true
+		return stale, nil
+	}
+	if err := json.Unmarshal(resp, &es); err != nil {
 		return EntityState{}, err
 	}
-	err = json.Unmarshal(resp, &es)
-	return es, err
+	s.remember(entityId, es)
+	return es, nil
+}
+
+// remember keeps a state read from Home Assistant as the fallback for the
+// next read of it.
+func (s *state) remember(entityId string, es EntityState) {
+	s.recentMu.Lock()
+	defer s.recentMu.Unlock()
+	if s.recent == nil {
+		s.recent = map[string]recentRead{}
+	}
+	s.recent[entityId] = recentRead{es: es, at: s.now()}
+}
+
+// lastRead returns the entity's state as last read from Home Assistant, if
+// that was within staleReadLimit.
+func (s *state) lastRead(entityId string) (EntityState, bool) {
+	s.recentMu.Lock()
+	defer s.recentMu.Unlock()
+	r, ok := s.recent[entityId]
+	if !ok || s.now().Sub(r.at) > staleReadLimit {
+		return EntityState{}, false
+	}
+	return r.es, true
+}
+
+func (s *state) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
 }
 
 // ListEntities returns a list of all entities in Home Assistant.
@@ -118,6 +211,24 @@ func (s *state) ListEntities() ([]EntityState, error) {
 	return es, err
 }
 
+// EntitiesInDomain lists the entities of one domain, such as "light", sorted
+// by id, for routines that act on every one of them.
+func EntitiesInDomain(state StateReader, domain string) ([]EntityState, error) {
+	all, err := state.ListEntities()
+	if err != nil {
+		return nil, err
+	}
+	prefix := domain + "."
+	var out []EntityState
+	for _, es := range all {
+		if strings.HasPrefix(es.EntityID, prefix) {
+			out = append(out, es)
+		}
+	}
+	slices.SortFunc(out, func(a, b EntityState) int { return strings.Compare(a.EntityID, b.EntityID) })
+	return out, nil
+}
+
 func (s *state) Equals(entityId string, expectedState string) (bool, error) {
 	currentState, err := s.Get(entityId)
 	if err != nil {
@@ -125,3 +236,17 @@ func (s *state) Equals(entityId string, expectedState string) (bool, error) {
 	}
 	return currentState.State == expectedState, nil
 }
+
+// set publishes a state for an entity. The cache is not written here: Home
+// Assistant announces the change as a state_changed event like any other,
+// and folding it in from there keeps one path for every update.
+func (s *state) set(entityId, value string, attributes map[string]any) error {
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+	_, err := s.httpClient.PostState(entityId, map[string]any{
+		"state":      value,
+		"attributes": attributes,
+	})
+	return err
+}
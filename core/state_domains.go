@@ -0,0 +1,237 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Xevion/go-ha/color"
+)
+
+// LightState is a light's state with its attributes decoded, as GetLight
+// reads it. Attributes a light does not report are left zero: Brightness and
+// the colours are absent while it is off, and a white-only bulb has no colour.
+type LightState struct {
+	EntityState `json:"-"`
+
+	On bool `json:"-"`
+
+	// Brightness is 0 to 255, the scale Home Assistant reports it on.
+	Brightness int `json:"brightness"`
+
+	// ColorMode is how the light is showing its colour, such as color_temp
+	// or rgb, and SupportedColorModes every mode it can.
+	ColorMode           string   `json:"color_mode"`
+	SupportedColorModes []string `json:"supported_color_modes"`
+
+	ColorTemp    color.Kelvin `json:"color_temp_kelvin"`
+	MinColorTemp color.Kelvin `json:"min_color_temp_kelvin"`
+	MaxColorTemp color.Kelvin `json:"max_color_temp_kelvin"`
+
+	RGBColor color.RGB `json:"rgb_color"`
+	HSColor  color.HS  `json:"-"`
+	XYColor  color.XY  `json:"-"`
+
+	Effect     string   `json:"effect"`
+	EffectList []string `json:"effect_list"`
+}
+
+// BrightnessPercent is the brightness on the 0 to 100 scale brightness_pct
+// takes, rounded to the nearest.
+func (l LightState) BrightnessPercent() int {
+	return (l.Brightness*100 + 127) / 255
+}
+
+// ClimateState is a thermostat's state with its attributes decoded, as
+// GetClimate reads it. The temperatures are in Home Assistant's unit.
+type ClimateState struct {
+	EntityState `json:"-"`
+
+	// HVACMode is the mode the thermostat is set to, its state, and
+	// HVACModes every mode it offers.
+	HVACMode  string   `json:"-"`
+	HVACModes []string `json:"hvac_modes"`
+
+	// HVACAction is what it is doing now, such as heating or idle.
+	HVACAction string `json:"hvac_action"`
+
+	CurrentTemperature float64 `json:"current_temperature"`
+	CurrentHumidity    float64 `json:"current_humidity"`
+
+	// Temperature is the target in single-setpoint modes. TargetTempLow
+	// and TargetTempHigh are the range in heat_cool, where Temperature is
+	// zero.
+	Temperature    float64 `json:"temperature"`
+	TargetTempLow  float64 `json:"target_temp_low"`
+	TargetTempHigh float64 `json:"target_temp_high"`
+
+	MinTemp float64 `json:"min_temp"`
+	MaxTemp float64 `json:"max_temp"`
+
+	PresetMode  string   `json:"preset_mode"`
+	PresetModes []string `json:"preset_modes"`
+	FanMode     string   `json:"fan_mode"`
+	FanModes    []string `json:"fan_modes"`
+}
+
+// MediaPlayerState is a media player's state with its attributes decoded, as
+// GetMediaPlayer reads it. What is playing is empty while nothing is.
+type MediaPlayerState struct {
+	EntityState `json:"-"`
+
+	// VolumeLevel is 0 to 1.
+	VolumeLevel   float64 `json:"volume_level"`
+	IsVolumeMuted bool    `json:"is_volume_muted"`
+
+	MediaContentType string `json:"media_content_type"`
+	MediaTitle       string `json:"media_title"`
+	MediaArtist      string `json:"media_artist"`
+	MediaAlbumName   string `json:"media_album_name"`
+
+	// MediaDuration is the length of what is playing, and MediaPosition how
+	// far into it playback was at MediaPositionUpdatedAt.
+	MediaDuration          time.Duration `json:"-"`
+	MediaPosition          time.Duration `json:"-"`
+	MediaPositionUpdatedAt time.Time     `json:"media_position_updated_at"`
+
+	Source     string   `json:"source"`
+	SourceList []string `json:"source_list"`
+	AppName    string   `json:"app_name"`
+}
+
+// Playing reports whether the player is playing.
+func (m MediaPlayerState) Playing() bool { return m.State == "playing" }
+
+// CoverState is a cover's state with its attributes decoded, as GetCover reads
+// it. The state is open, closed, opening or closing.
+type CoverState struct {
+	EntityState `json:"-"`
+
+	// Position is 0, closed, to 100, open, and Tilt the slats' angle on the
+	// same scale. Either is nil for a cover that does not report it.
+	Position *int `json:"current_position"`
+	Tilt     *int `json:"current_tilt_position"`
+
+	DeviceClass string `json:"device_class"`
+}
+
+// BinarySensorState is a binary sensor's state with its attributes decoded,
+// as GetBinarySensor reads it.
+type BinarySensorState struct {
+	EntityState `json:"-"`
+
+	On bool `json:"-"`
+
+	// DeviceClass is what the sensor detects, such as motion or door, which
+	// decides what on means.
+	DeviceClass string `json:"device_class"`
+}
+
+// GetLight reads a light with its attributes decoded.
+func GetLight[E EntityRef](state StateReader, entityID E) (LightState, error) {
+	var l LightState
+	es, err := getDomain(state, string(entityID), "light", &l)
+	if err != nil {
+		return LightState{}, err
+	}
+	l.EntityState, l.On = es, es.State == "on"
+	if hs, ok := pair(es.Attributes["hs_color"]); ok {
+		l.HSColor = color.HS{Hue: hs[0], Saturation: hs[1]}
+	}
+	if xy, ok := pair(es.Attributes["xy_color"]); ok {
+		l.XYColor = color.XY{X: xy[0], Y: xy[1]}
+	}
+	return l, nil
+}
+
+// GetClimate reads a thermostat with its attributes decoded.
+func GetClimate[E EntityRef](state StateReader, entityID E) (ClimateState, error) {
+	var c ClimateState
+	es, err := getDomain(state, string(entityID), "climate", &c)
+	if err != nil {
+		return ClimateState{}, err
+	}
+	c.EntityState, c.HVACMode = es, es.State
+	return c, nil
+}
+
+// GetMediaPlayer reads a media player with its attributes decoded.
+func GetMediaPlayer[E EntityRef](state StateReader, entityID E) (MediaPlayerState, error) {
+	var m MediaPlayerState
+	es, err := getDomain(state, string(entityID), "media_player", &m)
+	if err != nil {
+		return MediaPlayerState{}, err
+	}
+	m.EntityState = es
+	m.MediaDuration = seconds(es.Attributes["media_duration"])
+	m.MediaPosition = seconds(es.Attributes["media_position"])
+	return m, nil
+}
+
+// GetCover reads a cover with its attributes decoded.
+func GetCover[E EntityRef](state StateReader, entityID E) (CoverState, error) {
+	var c CoverState
+	es, err := getDomain(state, string(entityID), "cover", &c)
+	if err != nil {
+		return CoverState{}, err
+	}
+	c.EntityState = es
+	return c, nil
+}
+
+// GetBinarySensor reads a binary sensor with its attributes decoded.
+func GetBinarySensor[E EntityRef](state StateReader, entityID E) (BinarySensorState, error) {
+	var b BinarySensorState
+	es, err := getDomain(state, string(entityID), "binary_sensor", &b)
+	if err != nil {
+		return BinarySensorState{}, err
+	}
+	b.EntityState, b.On = es, es.State == "on"
+	return b, nil
+}
+
+// getDomain reads an entity of the given domain and decodes its attributes
+// into out, whose json tags name the ones it wants. An entity that is
+// unavailable or unknown is ErrNoValue, as it is for GetStateAs, since its
+// attributes are as missing as its state.
+func getDomain(state StateReader, entityID, domain string, out any) (EntityState, error) {
+	if d, _, _ := strings.Cut(entityID, "."); d != domain {
+		return EntityState{}, fmt.Errorf("%w: %s is not a %s", ErrWrongType, entityID, domain)
+	}
+	es, err := state.Get(entityID)
+	if err != nil {
+		return EntityState{}, err
+	}
+	if es.State == "unavailable" || es.State == "unknown" {
+		return EntityState{}, fmt.Errorf("%w: %s is %s", ErrNoValue, entityID, es.State)
+	}
+
+	// The attributes arrive decoded from JSON, so encoding them again and
+	// decoding into out is the conversion JSON itself would have made.
+	b, err := json.Marshal(es.Attributes)
+	if err == nil {
+		err = json.Unmarshal(b, out)
+	}
+	if err != nil {
+		return EntityState{}, fmt.Errorf("attributes of %s: %w: %v", entityID, ErrWrongType, err)
+	}
+	return es, nil
+}
+
+// pair reads a two-number attribute such as hs_color.
+func pair(raw any) ([2]float64, bool) {
+	list, ok := raw.([]any)
+	if !ok || len(list) != 2 {
+		return [2]float64{}, false
+	}
+	a, aOK := list[0].(float64)
+	b, bOK := list[1].(float64)
+	return [2]float64{a, b}, aOK && bOK
+}
+
+// seconds reads an attribute given in seconds, such as media_duration.
+func seconds(raw any) time.Duration {
+	s, _ := raw.(float64)
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Xevion/go-ha/color"
+)
+
+// withAttributes gives the entity attributes decoded from JSON, as they
+// arrive from Home Assistant.
+func withAttributes(t *testing.T, es EntityState, attrs string) EntityState {
+	t.Helper()
+	require.NoError(t, json.Unmarshal([]byte(attrs), &es.Attributes))
+	return es
+}
+
+func TestGetLightDecodesItsAttributes(t *testing.T) {
+	s := stateWith(
+		withAttributes(t, entity("light.kitchen", "on"), `{
+			"brightness": 128, "color_mode": "hs", "supported_color_modes": ["color_temp", "hs"],
+			"color_temp_kelvin": null, "min_color_temp_kelvin": 2000, "max_color_temp_kelvin": 6535,
+			"hs_color": [30.5, 80], "rgb_color": [255, 163, 51], "xy_color": [0.55, 0.4],
+			"effect": "none", "effect_list": ["none", "colorloop"]}`),
+		withAttributes(t, entity("light.porch", "off"), `{"brightness": null, "color_mode": null}`),
+	)
+
+	kitchen, err := GetLight(s, "light.kitchen")
+	require.NoError(t, err)
+	assert.True(t, kitchen.On)
+	assert.Equal(t, 128, kitchen.Brightness)
+	assert.Equal(t, 50, kitchen.BrightnessPercent())
+	assert.Equal(t, "hs", kitchen.ColorMode)
+	assert.Equal(t, []string{"color_temp", "hs"}, kitchen.SupportedColorModes)
+	assert.Zero(t, kitchen.ColorTemp)
+	assert.Equal(t, color.Kelvin(6535), kitchen.MaxColorTemp)
+	assert.Equal(t, color.HS{Hue: 30.5, Saturation: 80}, kitchen.HSColor)
+	assert.Equal(t, color.RGB{255, 163, 51}, kitchen.RGBColor)
+	assert.Equal(t, color.XY{X: 0.55, Y: 0.4}, kitchen.XYColor)
+	assert.Equal(t, "light.kitchen", kitchen.EntityID)
+
+	porch, err := GetLight(s, "light.porch")
+	require.NoError(t, err)
+	assert.False(t, porch.On)
+	assert.Zero(t, porch.Brightness)
+}
+
+func TestGetClimateDecodesItsAttributes(t *testing.T) {
+	s := stateWith(withAttributes(t, entity("climate.hall", "heat_cool"), `{
+		"hvac_modes": ["off", "heat", "heat_cool"], "hvac_action": "heating",
+		"current_temperature": 19.5, "temperature": null, "target_temp_low": 20, "target_temp_high": 24,
+		"min_temp": 7, "max_temp": 35, "preset_mode": "home", "preset_modes": ["home", "away"]}`))
+
+	c, err := GetClimate(s, "climate.hall")
+	require.NoError(t, err)
+	assert.Equal(t, "heat_cool", c.HVACMode)
+	assert.Equal(t, "heating", c.HVACAction)
+	assert.Equal(t, 19.5, c.CurrentTemperature)
+	assert.Zero(t, c.Temperature)
+	assert.Equal(t, 20.0, c.TargetTempLow)
+	assert.Equal(t, 24.0, c.TargetTempHigh)
+	assert.Equal(t, []string{"home", "away"}, c.PresetModes)
+}
+
+func TestGetMediaPlayerDecodesItsAttributes(t *testing.T) {
+	s := stateWith(withAttributes(t, entity("media_player.lounge", "playing"), `{
+		"volume_level": 0.35, "is_volume_muted": false, "media_content_type": "music",
+		"media_title": "Teardrop", "media_artist": "Massive Attack", "media_duration": 330.5,
+		"media_position": 61, "media_position_updated_at": "2026-07-18T20:00:00+00:00", "source": "Spotify"}`))
+
+	m, err := GetMediaPlayer(s, "media_player.lounge")
+	require.NoError(t, err)
+	assert.True(t, m.Playing())
+	assert.Equal(t, 0.35, m.VolumeLevel)
+	assert.Equal(t, "Teardrop", m.MediaTitle)
+	assert.Equal(t, 330*time.Second+500*time.Millisecond, m.MediaDuration)
+	assert.Equal(t, 61*time.Second, m.MediaPosition)
+	assert.True(t, m.MediaPositionUpdatedAt.Equal(time.Date(2026, 7, 18, 20, 0, 0, 0, time.UTC)))
+}
+
+func TestGetCoverTellsAMissingPositionFromAClosedOne(t *testing.T) {
+	s := stateWith(
+		withAttributes(t, entity("cover.garage", "closed"), `{"current_position": 0, "device_class": "garage"}`),
+		withAttributes(t, entity("cover.shed", "open"), `{"device_class": "door"}`),
+	)
+
+	garage, err := GetCover(s, "cover.garage")
+	require.NoError(t, err)
+	require.NotNil(t, garage.Position)
+	assert.Zero(t, *garage.Position)
+	assert.Nil(t, garage.Tilt)
+	assert.Equal(t, "garage", garage.DeviceClass)
+
+	shed, err := GetCover(s, "cover.shed")
+	require.NoError(t, err)
+	assert.Nil(t, shed.Position)
+}
+
+func TestGetBinarySensorAndItsErrors(t *testing.T) {
+	s := stateWith(
+		withAttributes(t, entity("binary_sensor.front_door", "on"), `{"device_class": "door"}`),
+		entity("binary_sensor.shed_door", "unavailable"),
+		withAttributes(t, entity("light.bad", "on"), `{"brightness": "bright"}`),
+	)
+
+	door, err := GetBinarySensor(s, "binary_sensor.front_door")
+	require.NoError(t, err)
+	assert.True(t, door.On)
+	assert.Equal(t, "door", door.DeviceClass)
+
+	_, err = GetBinarySensor(s, "binary_sensor.shed_door")
+	assert.ErrorIs(t, err, ErrNoValue)
+
+	_, err = GetBinarySensor(s, "light.bad")
+	assert.ErrorIs(t, err, ErrWrongType, "not a binary sensor")
+
+	_, err = GetLight(s, "light.bad")
+	assert.ErrorIs(t, err, ErrWrongType)
+}
@@ -3,10 +3,12 @@ package core
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,6 +34,7 @@ func stateWithServer(t *testing.T, handler http.HandlerFunc) (*state, *int) {
 	return &state{
 		httpClient: internal.NewHttpClient(context.Background(), u, "token"),
 		cache:      newEntityCache(),
+		log:        slog.New(slog.DiscardHandler),
 	}, &calls
 }
 
@@ -136,6 +139,73 @@ func TestGetPropagatesHTTPErrors(t *testing.T) {
 	assert.True(t, errors.Is(err, internal.ErrEntityNotFound))
 }
 
+func TestGetRetriesABlipQuickly(t *testing.T) {
+	served := 0
+	s, calls := stateWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		served++
+		if served == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"entity_id":"light.kitchen","state":"on"}`))
+	})
+
+	start := time.Now()
+	got, err := s.Get("light.kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, "on", got.State)
+	assert.Equal(t, 2, *calls)
+	assert.Less(t, time.Since(start), time.Second, "a condition's read backs off briefly, not for seconds")
+}
+
+func TestGetFallsBackToARecentReadWhenHomeAssistantFails(t *testing.T) {
+	clock := testClock()
+	failing := false
+	s, _ := stateWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"entity_id":"light.kitchen","state":"on"}`))
+	})
+	s.clock = clock
+
+	got, err := s.Get("light.kitchen")
+	require.NoError(t, err)
+	assert.False(t, got.Stale)
+
+	failing = true
+	clock.Advance(30 * time.Second)
+	got, err = s.Get("light.kitchen")
+	require.NoError(t, err, "read half a minute ago, the last state stands in")
+	assert.Equal(t, "on", got.State)
+	assert.True(t, got.Stale, "and says so")
+
+	clock.Advance(31 * time.Second)
+	_, err = s.Get("light.kitchen")
+	assert.ErrorIs(t, err, internal.ErrServerError, "past a minute it is too old to trust")
+}
+
+// A refusal is Home Assistant answering, not failing to, and is not bridged
+// over with an earlier read.
+func TestGetReportsARefusalDespiteARecentRead(t *testing.T) {
+	failing := false
+	s, _ := stateWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"entity_id":"light.kitchen","state":"on"}`))
+	})
+
+	_, err := s.Get("light.kitchen")
+	require.NoError(t, err)
+
+	failing = true
+	_, err = s.Get("light.kitchen")
+	assert.ErrorIs(t, err, internal.ErrUnauthorized)
+}
+
 func TestApplyEventUpdatesTheCache(t *testing.T) {
 	s, _ := stateWithServer(t, func(w http.ResponseWriter, r *http.Request) {})
 	s.cache.beginSeed()
@@ -172,8 +242,8 @@ func TestApplyEventIgnoresMalformedMessages(t *testing.T) {
 	s.cache.beginSeed()
 	s.cache.finishSeed([]EntityState{entity("light.kitchen", "on")})
 
-	s.applyEvent([]byte(`not json`))
-	s.applyEvent([]byte(`{"event":{"data":{}}}`))
+	assert.ErrorIs(t, s.applyEvent([]byte(`not json`)), ErrMalformedEvent)
+	assert.ErrorIs(t, s.applyEvent([]byte(`{"event":{"data":{}}}`)), ErrMalformedEvent)
 
 	got, ok := s.cache.get("light.kitchen")
 	require.True(t, ok, "a malformed event must not disturb known state")
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xevion/go-ha/units"
+)
+
+var (
+	// ErrNoValue reports an entity that is unavailable or unknown, or an
+	// attribute it does not carry, so there is nothing to convert.
+	ErrNoValue = errors.New("no value")
+
+	// ErrWrongType reports a value that cannot be read as the requested type.
+	ErrWrongType = errors.New("value is not of the requested type")
+)
+
+// StateValue lists the types an entity's state can be read as. Home Assistant
+// sends every state as a string, so each is parsed from it: numbers as
+// decimals, bools from on/off or true/false, times from RFC 3339.
+type StateValue interface {
+	string | bool | int | int64 | float64 | time.Time
+}
+
+// GetStateAs reads the entity's state as T, as in
+// GetStateAs[float64](state, "sensor.temperature"). A state of unavailable or
+// unknown is ErrNoValue rather than a parse failure, since it is the entity
+// and not the reading that is missing.
+func GetStateAs[T StateValue, E EntityRef](state StateReader, entityID E) (T, error) {
+	var zero T
+	es, err := state.Get(string(entityID))
+	if err != nil {
+		return zero, err
+	}
+	if es.State == "unavailable" || es.State == "unknown" {
+		return zero, fmt.Errorf("%w: %s is %s", ErrNoValue, entityID, es.State)
+	}
+	v, err := parseState[T](es.State)
+	if err != nil {
+		return zero, fmt.Errorf("state of %s: %w", entityID, err)
+	}
+	return v, nil
+}
+
+// GetStateIn reads a numeric sensor's state in the given unit, as
+// GetStateIn(state, "sensor.outside", units.Celsius). See
+// [EntityState.NumericIn].
+func GetStateIn[E EntityRef](state StateReader, entityID E, unit string) (float64, error) {
+	es, err := state.Get(string(entityID))
+	if err != nil {
+		return 0, err
+	}
+	return es.NumericIn(unit)
+}
+
+// NumericIn reads the entity's state as a number in the given unit, converted
+// from the unit its unit_of_measurement attribute gives, so an automation
+// behaves the same whichever unit system Home Assistant is set to. A state of
+// unavailable or unknown, or a sensor without a unit, is ErrNoValue; units that
+// cannot be converted are units.ErrIncompatibleUnits or units.ErrUnknownUnit.
+func (e EntityState) NumericIn(unit string) (float64, error) {
+	if e.State == "unavailable" || e.State == "unknown" {
+		return 0, fmt.Errorf("%w: %s is %s", ErrNoValue, e.EntityID, e.State)
+	}
+	v, err := parseState[float64](e.State)
+	if err != nil {
+		return 0, fmt.Errorf("state of %s: %w", e.EntityID, err)
+	}
+	from, _ := e.Attributes["unit_of_measurement"].(string)
+	if from == "" {
+		return 0, fmt.Errorf("%w: %s has no unit_of_measurement", ErrNoValue, e.EntityID)
+	}
+	converted, err := units.Convert(v, from, unit)
+	if err != nil {
+		return 0, fmt.Errorf("state of %s: %w", e.EntityID, err)
+	}
+	return converted, nil
+}
+
+// GetAttribute reads one of the entity's attributes as T. A value already of
+// type T is returned as is. A string is parsed when T is a number, bool or
+// time, since integrations are not consistent about quoting. Anything else,
+// such as a list or an object, is decoded into T as JSON would be, so
+// GetAttribute[[]string] reads a list of strings.
+func GetAttribute[T any, E EntityRef](state StateReader, entityID E, attr string) (T, error) {
+	var zero T
+	es, err := state.Get(string(entityID))
+	if err != nil {
+		return zero, err
+	}
+	raw, ok := es.Attributes[attr]
+	if !ok || raw == nil {
+		return zero, fmt.Errorf("%w: %s has no %s", ErrNoValue, entityID, attr)
+	}
+	v, err := convertAttribute[T](raw)
+	if err != nil {
+		return zero, fmt.Errorf("%s of %s: %w", attr, entityID, err)
+	}
+	return v, nil
+}
+
+func parseState[T StateValue](s string) (T, error) {
+	var out T
+	var err error
+	switch p := any(&out).(type) {
+	case *string:
+		*p = s
+	case *bool:
+		*p, err = parseBool(s)
+	case *int:
+		*p, err = strconv.Atoi(s)
+	case *int64:
+		*p, err = strconv.ParseInt(s, 10, 64)
+	case *float64:
+		*p, err = strconv.ParseFloat(s, 64)
+	case *time.Time:
+		*p, err = time.Parse(time.RFC3339, s)
+	}
+	if err != nil {
+		return out, fmt.Errorf("%w: %q as %T", ErrWrongType, s, out)
+	}
+	return out, nil
+}
+
+// parseBool takes the spellings Home Assistant's states use for a boolean.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on", "true", "yes", "open", "home":
+		return true, nil
+	case "off", "false", "no", "closed", "not_home":
+		return false, nil
+	}
+	return false, strconv.ErrSyntax
+}
+
+func convertAttribute[T any](raw any) (T, error) {
+	if v, ok := raw.(T); ok {
+		return v, nil
+	}
+
+	var out T
+	if s, ok := raw.(string); ok {
+		switch any(out).(type) {
+		case bool, int, int64, float64, time.Time:
+			return convertString[T](s)
+		}
+	}
+
+	// Attributes arrive decoded from JSON, so encoding one again and decoding
+	// it into T is the conversion JSON itself would have made.
+	b, err := json.Marshal(raw)
+	if err == nil {
+		err = json.Unmarshal(b, &out)
+	}
+	if err != nil {
+		return out, fmt.Errorf("%w: %v as %T", ErrWrongType, raw, out)
+	}
+	return out, nil
+}
+
+// convertString parses s into T, which the caller has already checked is one
+// of the StateValue types.
+func convertString[T any](s string) (T, error) {
+	var out T
+	var v any
+	var err error
+	switch any(out).(type) {
+	case bool:
+		v, err = parseState[bool](s)
+	case int:
+		v, err = parseState[int](s)
+	case int64:
+		v, err = parseState[int64](s)
+	case float64:
+		v, err = parseState[float64](s)
+	case time.Time:
+		v, err = parseState[time.Time](s)
+	}
+	if err != nil {
+		return out, err
+	}
+	return v.(T), nil
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Xevion/go-ha/units"
+)
+
+func TestGetStateAsParsesTheState(t *testing.T) {
+	s := stateWith(
+		entity("sensor.temperature", "21.5"),
+		entity("sensor.count", "3"),
+		entity("binary_sensor.door", "on"),
+		entity("sensor.last_boot", "2026-03-01T07:00:00+00:00"),
+		entity("sensor.broken", "unavailable"),
+	)
+
+	temp, err := GetStateAs[float64](s, "sensor.temperature")
+	require.NoError(t, err)
+	assert.Equal(t, 21.5, temp)
+
+	count, err := GetStateAs[int](s, "sensor.count")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	open, err := GetStateAs[bool](s, "binary_sensor.door")
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	boot, err := GetStateAs[time.Time](s, "sensor.last_boot")
+	require.NoError(t, err)
+	assert.True(t, boot.Equal(time.Date(2026, 3, 1, 7, 0, 0, 0, time.UTC)))
+
+	_, err = GetStateAs[int](s, "sensor.temperature")
+	assert.ErrorIs(t, err, ErrWrongType, "21.5 is not a whole number")
+
+	_, err = GetStateAs[float64](s, "sensor.broken")
+	assert.ErrorIs(t, err, ErrNoValue, "an unavailable sensor is missing, not malformed")
+	assert.NotErrorIs(t, err, ErrWrongType)
+}
+
+func TestNumericInConverts(t *testing.T) {
+	s := stateWith(
+		EntityState{
+			EntityID:   "sensor.outside",
+			State:      "50",
+			Attributes: map[string]any{"unit_of_measurement": "°F"},
+		},
+		EntityState{
+			EntityID:   "sensor.power",
+			State:      "1250",
+			Attributes: map[string]any{"unit_of_measurement": "W"},
+		},
+		entity("sensor.count", "3"),
+		EntityState{
+			EntityID:   "sensor.offline",
+			State:      "unavailable",
+			Attributes: map[string]any{"unit_of_measurement": "°C"},
+		},
+	)
+
+	celsius, err := GetStateIn(s, "sensor.outside", units.Celsius)
+	require.NoError(t, err)
+	assert.InDelta(t, 10.0, celsius, 1e-9)
+
+	kw, err := GetStateIn(s, "sensor.power", units.Kilowatts)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.25, kw, 1e-9)
+
+	_, err = GetStateIn(s, "sensor.power", units.Celsius)
+	assert.ErrorIs(t, err, units.ErrIncompatibleUnits)
+
+	_, err = GetStateIn(s, "sensor.count", units.Celsius)
+	assert.ErrorIs(t, err, ErrNoValue, "a sensor without a unit cannot be converted")
+
+	_, err = GetStateIn(s, "sensor.offline", units.Celsius)
+	assert.ErrorIs(t, err, ErrNoValue)
+}
+
+func TestGetAttributeConverts(t *testing.T) {
+	s := stateWith(EntityState{
+		EntityID: "climate.lounge",
+		State:    "heat",
+		Attributes: map[string]any{
+			"current_temperature": 20.0,
+			"target_temp_step":    "0.5",
+			"hvac_modes":          []any{"off", "heat"},
+			"friendly_name":       "Lounge",
+		},
+	})
+
+	current, err := GetAttribute[float64](s, "climate.lounge", "current_temperature")
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, current)
+
+	whole, err := GetAttribute[int](s, "climate.lounge", "current_temperature")
+	require.NoError(t, err)
+	assert.Equal(t, 20, whole)
+
+	step, err := GetAttribute[float64](s, "climate.lounge", "target_temp_step")
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, step, "a quoted number is still a number")
+
+	modes, err := GetAttribute[[]string](s, "climate.lounge", "hvac_modes")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"off", "heat"}, modes)
+
+	_, err = GetAttribute[float64](s, "climate.lounge", "friendly_name")
+	assert.ErrorIs(t, err, ErrWrongType)
+
+	_, err = GetAttribute[string](s, "climate.lounge", "preset_mode")
+	assert.ErrorIs(t, err, ErrNoValue)
+
+	_, err = GetAttribute[string](s, "climate.elsewhere", "preset_mode")
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StatisticsPeriod is the span each row of Statistics summarises.
+type StatisticsPeriod string
+
+// The periods Home Assistant keeps statistics for. Five-minute statistics are
+// purged with the short-term history, after ten days by default; the rest are
+// kept.
+const (
+	Period5Minutes StatisticsPeriod = "5minute"
+	PeriodHour     StatisticsPeriod = "hour"
+	PeriodDay      StatisticsPeriod = "day"
+	PeriodWeek     StatisticsPeriod = "week"
+	PeriodMonth    StatisticsPeriod = "month"
+)
+
+// Statistic is one period of an entity's long-term statistics.
+//
+// Which values it carries depends on the entity. A measurement, such as a
+// temperature, has Mean, Min and Max; a total, such as an energy meter, has
+// State, the reading at the end of the period, Sum, the running total since
+// statistics began, and Change, what it grew by within the period. The rest
+// are nil.
+type Statistic struct {
+	Start time.Time
+	End   time.Time
+
+	Mean *float64
+	Min  *float64
+	Max  *float64
+
+	State  *float64
+	Sum    *float64
+	Change *float64
+}
+
+// statisticRow is a Statistic as recorder/statistics_during_period answers
+// with it, its times in milliseconds since the epoch.
+type statisticRow struct {
+	Start  float64  `json:"start"`
+	End    float64  `json:"end"`
+	Mean   *float64 `json:"mean"`
+	Min    *float64 `json:"min"`
+	Max    *float64 `json:"max"`
+	State  *float64 `json:"state"`
+	Sum    *float64 `json:"sum"`
+	Change *float64 `json:"change"`
+}
+
+// Statistics reads the recorder's long-term statistics of the entities between
+// start and end, summarised per period, oldest first, keyed by entity. Unlike
+// History, they are kept indefinitely, so they answer questions over weeks and
+// months, such as how this week's energy use compares with last week's.
+//
+// Only entities with a state class have statistics. One without is left out
+// of the result rather than given an empty list.
+func (app *App) Statistics(entityIDs []string, start, end time.Time, period StatisticsPeriod) (map[string][]Statistic, error) {
+	if len(entityIDs) == 0 {
+		return nil, fmt.Errorf("%w: statistics of no entities", ErrInvalidArgs)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("%w: statistics from %s to %s are empty", ErrInvalidArgs, start, end)
+	}
+	switch period {
+	case Period5Minutes, PeriodHour, PeriodDay, PeriodWeek, PeriodMonth:
+	default:
+		return nil, fmt.Errorf("%w: unknown statistics period %q", ErrInvalidArgs, period)
+	}
+
+	raw, err := app.Command(map[string]any{
+		"type":          "recorder/statistics_during_period",
+		"statistic_ids": entityIDs,
+		"start_time":    start.UTC().Format(time.RFC3339),
+		"end_time":      end.UTC().Format(time.RFC3339),
+		"period":        string(period),
+		"types":         []string{"mean", "min", "max", "state", "sum", "change"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("recorder/statistics_during_period: %w", err)
+	}
+	var rows map[string][]statisticRow
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("decoding statistics: %w", err)
+	}
+
+	out := make(map[string][]Statistic, len(rows))
+	for id, list := range rows {
+		stats := make([]Statistic, len(list))
+		for i, r := range list {
+			stats[i] = Statistic{
+				Start: time.UnixMilli(int64(r.Start)).In(app.clock.Now().Location()),
+				End:   time.UnixMilli(int64(r.End)).In(app.clock.Now().Location()),
+				Mean:  r.Mean, Min: r.Min, Max: r.Max,
+				State: r.State, Sum: r.Sum, Change: r.Change,
+			}
+		}
+		out[id] = stats
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SubApp is a named group of automations within an app, for organising a large
+// installation by room or feature. It shares the app's connection, state cache
+// and scheduler, but can be stopped and started again on its own: stopped, its
+// automations' triggers are turned away, and runs under way are cancelled
+// through their context.
+type SubApp struct {
+	app  *App
+	name string
+
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	runners []*runner
+}
+
+// SubApp returns a new group of automations named name, running until stopped.
+// Its automations are registered under "name/automation", which is how they
+// appear in logs and reports.
+func (app *App) SubApp(name string) (*SubApp, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("%w: sub-app name %q must be non-empty and free of slashes", ErrInvalidArgs, name)
+	}
+	s := &SubApp{app: app, name: name}
+	s.ctx, s.cancel = context.WithCancel(app.ctx)
+	return s, nil
+}
+
+// Name returns the name the sub-app was created with.
+func (s *SubApp) Name() string { return s.name }
+
+// RegisterAutomations is App.RegisterAutomations for the sub-app's own
+// automations. When the app refuses them, none is registered, and the sub-app
+// is left as it was.
+func (s *SubApp) RegisterAutomations(automations ...Automation) error {
+	scoped := make([]Automation, 0, len(automations))
+	scopes := make([]func() context.Context, len(automations))
+	var added []*runner
+	s.mu.Lock()
+	for i, a := range automations {
+		if a.runtime != nil {
+			scopes[i] = a.runtime.scope
+			a.runtime.scope = s.context
+			added = append(added, a.runtime)
+		}
+		a.name = s.name + "/" + a.name
+		scoped = append(scoped, a)
+	}
+	s.runners = append(s.runners, added...)
+	s.mu.Unlock()
+
+	err := s.app.RegisterAutomations(scoped...)
+	if err != nil {
+		s.mu.Lock()
+		for i, a := range automations {
+			if a.runtime != nil {
+				a.runtime.scope = scopes[i]
+			}
+		}
+		s.runners = slices.DeleteFunc(s.runners, func(r *runner) bool { return slices.Contains(added, r) })
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// Stop turns the sub-app's triggers away until Start, cancels its runs under
+// way, and drops any waiting out a For, a throttle or a condition retry. It
+// returns without waiting for cancelled runs to finish.
+func (s *SubApp) Stop() {
+	s.mu.Lock()
+	s.cancel()
+	runners := slices.Clone(s.runners)
+	s.mu.Unlock()
+
+	s.app.registryMu.RLock()
+	var pending []*pendingRuns
+	for _, bindings := range s.app.automations {
+		for _, b := range bindings {
+			if slices.Contains(runners, b.automation.runtime) {
+				pending = append(pending, b.pending)
+			}
+		}
+	}
+	s.app.registryMu.RUnlock()
+
+	for _, p := range pending {
+		p.clear()
+	}
+	for _, r := range runners {
+		r.trailing.clear()
+		r.retries.clear()
+	}
+}
+
+// Start lets a stopped sub-app's triggers run again. Triggers that came while
+// it was stopped are not replayed. Starting a running sub-app does nothing.
+func (s *SubApp) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() == nil {
+		return
+	}
+	s.ctx, s.cancel = context.WithCancel(s.app.ctx)
+}
+
+// Running reports whether the sub-app is started.
+func (s *SubApp) Running() bool {
+	return s.context().Err() == nil
+}
+
+// context is what the sub-app's runs derive from, cancelled by Stop. It still
+// descends from the app's, so Close reaches the sub-app's runs too.
+func (s *SubApp) context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
@@ -2,14 +2,19 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/Xevion/go-ha/internal"
+	"github.com/Xevion/go-ha/internal/solar"
 )
 
 // SunEntityID is the entity Home Assistant publishes solar times on.
 const SunEntityID = "sun.sun"
 
-// SunEvent names one of the solar times Home Assistant publishes.
+// SunEvent names one of the solar times a sun trigger fires at. Dawn and dusk
+// are civil twilight's, as Home Assistant has them.
 type SunEvent int
 
 const (
@@ -17,19 +22,48 @@ const (
 	SunSetting
 	SunDawn
 	SunDusk
+	SunNoon
+	SunNauticalDawn
+	SunNauticalDusk
+	SunAstronomicalDawn
+	SunAstronomicalDusk
 )
 
+// attribute is the sun.sun attribute Home Assistant publishes this event's
+// next time in. It is empty for the deeper twilights, which it does not
+// publish.
 func (e SunEvent) attribute() string {
 	switch e {
+	case SunRising:
+		return "next_rising"
 	case SunSetting:
 		return "next_setting"
 	case SunDawn:
 		return "next_dawn"
 	case SunDusk:
 		return "next_dusk"
-	default:
-		return "next_rising"
+	case SunNoon:
+		return "next_noon"
+	}
+	return ""
+}
+
+// depression is how far below the horizon the sun's centre is at this event.
+func (e SunEvent) depression() float64 {
+	switch e {
+	case SunDawn, SunDusk:
+		return solar.Civil
+	case SunNauticalDawn, SunNauticalDusk:
+		return solar.Nautical
+	case SunAstronomicalDawn, SunAstronomicalDusk:
+		return solar.Astronomical
 	}
+	return solar.Horizon
+}
+
+// rising reports whether this event happens as the sun comes up.
+func (e SunEvent) rising() bool {
+	return e == SunRising || e == SunDawn || e == SunNauticalDawn || e == SunAstronomicalDawn
 }
 
 func (e SunEvent) String() string {
@@ -40,40 +74,174 @@ func (e SunEvent) String() string {
 		return "dawn"
 	case SunDusk:
 		return "dusk"
+	case SunNoon:
+		return "solar noon"
+	case SunNauticalDawn:
+		return "nautical dawn"
+	case SunNauticalDusk:
+		return "nautical dusk"
+	case SunAstronomicalDawn:
+		return "astronomical dawn"
+	case SunAstronomicalDusk:
+		return "astronomical dusk"
 	default:
 		return "sunrise"
 	}
 }
 
-// sunTrigger fires at a solar time read from sun.sun.
+// homeZoneID is the zone Home Assistant keeps the home's location in. Sun
+// times Home Assistant does not publish are computed from it when its
+// configuration could not be read.
+const homeZoneID = "zone.home"
+
+// ErrInvalidLocation reports a latitude or longitude off the globe.
+var ErrInvalidLocation = errors.New("invalid location")
+
+// SunTrigger fires at a solar time. By default it is the home's, read from
+// sun.sun; AtLocation and AtZone move it somewhere else.
+type SunTrigger interface {
+	ScheduleTrigger
+
+	// AtLocation fires at this solar event as seen from the given latitude and
+	// longitude, in degrees, north and east positive.
+	AtLocation(lat, lon float64) SunTrigger
+
+	// AtZone fires at this solar event as seen from a zone entity, such as
+	// zone.cabin. Its coordinates are read from the zone each time the next
+	// occurrence is worked out, so a zone edited in Home Assistant is
+	// followed.
+	AtZone(zoneID string) SunTrigger
+
+	// WhenMissing decides what happens on days the event does not occur, as
+	// through a polar summer or winter. The default is SkipDay.
+	WhenMissing(fallback SunFallback) SunTrigger
+
+	// OnlyOn fires only on days one of the rules picks, such as the last
+	// Friday of the month. Other days are passed over when the next time is
+	// worked out.
+	OnlyOn(rules ...DayRule) SunTrigger
+}
+
+// SunFallback is what a sun trigger does on a day its event does not occur.
+// Build one with SkipDay or UseFixedTime.
+type SunFallback struct {
+	fixed bool
+	at    ClockTime
+}
+
+// SkipDay lets a day without the event pass without firing, so the trigger
+// next fires whenever the event returns, which near the poles can be months
+// away.
+func SkipDay() SunFallback { return SunFallback{} }
+
+// UseFixedTime fires at the given local time on days without the event, so
+// "lights on at sunset" still happens through the polar summer.
+func UseFixedTime(hour, minute int) SunFallback {
+	return SunFallback{fixed: true, at: TimeOfDay(hour, minute)}
+}
+
+// sunTrigger fires at a solar time, read from sun.sun unless it has been moved
+// to another location.
 //
-// The times come from Home Assistant rather than being computed here. It runs
-// astral against the observer's latitude, longitude AND elevation, with a
+// The home's times come from Home Assistant rather than being computed here. It
+// runs astral against the observer's latitude, longitude AND elevation, with a
 // configurable solar depression for dawn and dusk. Computing them locally
 // means quietly disagreeing with the times on the user's own dashboard.
+//
+// Home Assistant publishes no such entity for anywhere else, though, so for
+// another property the times are computed from its coordinates. So are those
+// of the home it does not publish, the deeper twilights and elevations, from
+// the location in Home Assistant's configuration, or zone.home's.
 type sunTrigger struct {
 	event  SunEvent
 	offset time.Duration
 
+	// Set by SunRisesAbove and SunDropsBelow, which fire at an elevation,
+	// in degrees, rather than at an event. event then says only which way the
+	// sun is going.
+	byElevation bool
+	elevation   float64
+
+	// Set by AtLocation. at is whether they are, since 0,0 is a real place.
+	at       bool
+	lat, lon float64
+
+	// zone, set by AtZone, names an entity to take coordinates from instead.
+	zone string
+
+	fallback SunFallback
+
+	// days, set by OnlyOn, narrows the trigger to some days of the month.
+	days dayRules
+
 	// state is bound at registration. A trigger is declared before an App
 	// exists, so it has nothing to read until it joins one.
 	state StateReader
+
+	// home, also bound at registration, reads the home's location from the
+	// app's configuration.
+	home func() (lat, lon float64, ok bool)
 }
 
 // Sunrise fires when the sun rises, optionally offset. A negative offset fires
 // before the event.
-func Sunrise(offset ...time.Duration) ScheduleTrigger { return newSunTrigger(SunRising, offset) }
+func Sunrise(offset ...time.Duration) SunTrigger { return newSunTrigger(SunRising, offset) }
 
 // Sunset fires when the sun sets, optionally offset.
-func Sunset(offset ...time.Duration) ScheduleTrigger { return newSunTrigger(SunSetting, offset) }
+func Sunset(offset ...time.Duration) SunTrigger { return newSunTrigger(SunSetting, offset) }
 
 // Dawn fires at the start of civil twilight, optionally offset.
-func Dawn(offset ...time.Duration) ScheduleTrigger { return newSunTrigger(SunDawn, offset) }
+func Dawn(offset ...time.Duration) SunTrigger { return newSunTrigger(SunDawn, offset) }
 
 // Dusk fires at the end of civil twilight, optionally offset.
-func Dusk(offset ...time.Duration) ScheduleTrigger { return newSunTrigger(SunDusk, offset) }
+func Dusk(offset ...time.Duration) SunTrigger { return newSunTrigger(SunDusk, offset) }
+
+// SolarNoon fires when the sun is at its highest, optionally offset.
+func SolarNoon(offset ...time.Duration) SunTrigger { return newSunTrigger(SunNoon, offset) }
+
+// NauticalDawn fires at the start of nautical twilight, with the sun 12° below
+// the horizon, optionally offset. Home Assistant does not publish it, so the
+// home's is computed from the home's location.
+func NauticalDawn(offset ...time.Duration) SunTrigger {
+	return newSunTrigger(SunNauticalDawn, offset)
+}
+
+// NauticalDusk fires at the end of nautical twilight, optionally offset.
+func NauticalDusk(offset ...time.Duration) SunTrigger {
+	return newSunTrigger(SunNauticalDusk, offset)
+}
+
+// AstronomicalDawn fires at the start of astronomical twilight, with the sun
+// 18° below the horizon, optionally offset. Like NauticalDawn, the home's is
+// computed from the home's location.
+func AstronomicalDawn(offset ...time.Duration) SunTrigger {
+	return newSunTrigger(SunAstronomicalDawn, offset)
+}
+
+// AstronomicalDusk fires at the end of astronomical twilight, optionally
+// offset.
+func AstronomicalDusk(offset ...time.Duration) SunTrigger {
+	return newSunTrigger(SunAstronomicalDusk, offset)
+}
+
+// SunRisesAbove fires in the morning when the sun climbs past the elevation,
+// in degrees above the horizon, optionally offset. A negative elevation is
+// below the horizon. The home's time is computed from the home's location.
+func SunRisesAbove(degrees float64, offset ...time.Duration) SunTrigger {
+	t := newSunTrigger(SunRising, offset).(*sunTrigger)
+	t.byElevation, t.elevation = true, degrees
+	return t
+}
+
+// SunDropsBelow fires in the evening when the sun sinks past the elevation,
+// such as 4° for lights that should come on while it is still light out.
+func SunDropsBelow(degrees float64, offset ...time.Duration) SunTrigger {
+	t := newSunTrigger(SunSetting, offset).(*sunTrigger)
+	t.byElevation, t.elevation = true, degrees
+	return t
+}
 
-func newSunTrigger(event SunEvent, offset []time.Duration) ScheduleTrigger {
+func newSunTrigger(event SunEvent, offset []time.Duration) SunTrigger {
 	t := &sunTrigger{event: event}
 	if len(offset) > 0 {
 		t.offset = offset[0]
@@ -83,34 +251,134 @@ func newSunTrigger(event SunEvent, offset []time.Duration) ScheduleTrigger {
 
 func (t *sunTrigger) trigger() {}
 
+func (t *sunTrigger) AtLocation(lat, lon float64) SunTrigger {
+	next := *t
+	next.at, next.lat, next.lon = true, lat, lon
+	next.zone = ""
+	return &next
+}
+
+func (t *sunTrigger) AtZone(zoneID string) SunTrigger {
+	next := *t
+	next.zone = zoneID
+	next.at = false
+	return &next
+}
+
+func (t *sunTrigger) WhenMissing(fallback SunFallback) SunTrigger {
+	next := *t
+	next.fallback = fallback
+	return &next
+}
+
+func (t *sunTrigger) OnlyOn(rules ...DayRule) SunTrigger {
+	next := *t
+	// Never nil, so narrowing to no days at all is told apart from not
+	// narrowing, and refused.
+	next.days = append(dayRules{}, rules...)
+	return &next
+}
+
+func (t *sunTrigger) validate() error {
+	if err := t.days.validate(); err != nil {
+		return err
+	}
+	if t.byElevation && (t.elevation <= -90 || t.elevation >= 90) {
+		return fmt.Errorf("%w: the sun never crosses an elevation of %g°", ErrInvalidArgs, t.elevation)
+	}
+	if t.at && (t.lat < -90 || t.lat > 90 || t.lon < -180 || t.lon > 180) {
+		return fmt.Errorf("%w: %g,%g", ErrInvalidLocation, t.lat, t.lon)
+	}
+	if t.fallback.fixed && t.fallback.at.err != nil {
+		return fmt.Errorf("%s fallback: %w", t.event, t.fallback.at.err)
+	}
+	return nil
+}
+
 // bind gives the trigger the reader it derives its times from.
 func (t *sunTrigger) bind(state StateReader) { t.state = state }
 
+// locate gives the trigger where the home is.
+func (t *sunTrigger) locate(home func() (float64, float64, bool)) { t.home = home }
+
 // dynamic reports that this trigger's times move independently of it firing,
-// so the scheduler re-derives them whenever sun.sun changes.
-func (t *sunTrigger) dynamic() bool { return true }
+// so the scheduler re-derives them whenever sun.sun changes. Times computed
+// for another location, or for the home from its location, depend on nothing
+// sun.sun says.
+func (t *sunTrigger) dynamic() bool { return !t.at && t.zone == "" && t.published() != "" }
+
+// published is the sun.sun attribute the home's time is read from, or empty
+// when it is computed instead.
+func (t *sunTrigger) published() string {
+	if t.byElevation {
+		return ""
+	}
+	return t.event.attribute()
+}
 
 func (t *sunTrigger) NextTime(after time.Time) (time.Time, bool) {
+	next, ok := t.next(after)
+	if !ok || t.days == nil {
+		return next, ok
+	}
+
+	for range searchDays {
+		if t.days.matches(next) {
+			return next, true
+		}
+		day, found := t.days.nextDay(next)
+		if !found {
+			return time.Time{}, false
+		}
+		if !t.dynamic() {
+			next, ok = t.next(day)
+			if !ok {
+				return time.Time{}, false
+			}
+			continue
+		}
+
+		// sun.sun publishes only the next event, so a day weeks away is
+		// estimated at the same time of day. The estimate is re-derived each
+		// time sun.sun changes, and by the day itself sun.sun is publishing
+		// that day's event, so the time it finally fires at is exact.
+		return internal.WallClock(day, next.Hour(), next.Minute()).Add(
+			time.Duration(next.Second())*time.Second + time.Duration(next.Nanosecond()),
+		), true
+	}
+	return time.Time{}, false
+}
+
+// next finds the first occurrence after the given instant, on any day.
+func (t *sunTrigger) next(after time.Time) (time.Time, bool) {
+	if t.at {
+		return t.computed(after, t.lat, t.lon)
+	}
 	if t.state == nil {
 		return time.Time{}, false
 	}
+	if t.zone != "" || t.published() == "" {
+		lat, lon, ok := t.place()
+		if !ok {
+			return time.Time{}, false
+		}
+		return t.computed(after, lat, lon)
+	}
 
 	sun, err := t.state.Get(SunEntityID)
 	if err != nil {
 		return time.Time{}, false
 	}
-
-	raw, ok := sun.Attributes[t.event.attribute()].(string)
+	parsed, ok := sunTime(sun, t.published(), after.Location())
 	if !ok {
 		return time.Time{}, false
 	}
-	parsed, err := time.Parse(time.RFC3339, raw)
-	if err != nil {
-		return time.Time{}, false
-	}
 
-	next := parsed.Local().Add(t.offset)
+	next := parsed.Add(t.offset)
 	if next.After(after) {
+		if fixed, ok := t.fixedBefore(after, parsed); ok && fixed.Before(next) {
+			return fixed, true
+		}
 		return next, true
 	}
 
@@ -121,18 +389,191 @@ func (t *sunTrigger) NextTime(after time.Time) (time.Time, bool) {
 	return next.AddDate(0, 0, 1), true
 }
 
+// sunTime reads one of the times sun.sun publishes, in the given location,
+// which is the app clock's: the days it falls on are counted there.
+func sunTime(sun EntityState, attribute string, loc *time.Location) (time.Time, bool) {
+	raw, ok := sun.Attributes[attribute].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed.In(loc), true
+}
+
+// searchDays bounds how far ahead computed triggers look. Near the poles an
+// event can go missing for months, and a year covers every season.
+const searchDays = 366
+
+// computed finds the first occurrence after the given instant at a location.
+// It starts a day early: an event with an offset can fall on the calendar day
+// before the one it belongs to.
+func (t *sunTrigger) computed(after time.Time, lat, lon float64) (time.Time, bool) {
+	day := after
+	for i := -1; i <= searchDays; i++ {
+		date := day.AddDate(0, 0, i)
+		at, ok := t.on(date, lat, lon)
+		if !ok {
+			if fixed := t.fallback.at.On(date); t.fallback.fixed && fixed.After(after) {
+				return fixed, true
+			}
+			continue
+		}
+		if next := at.Add(t.offset); next.After(after) {
+			return next, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// on works out the event on the given day at a location.
+func (t *sunTrigger) on(date time.Time, lat, lon float64) (time.Time, bool) {
+	switch {
+	case t.byElevation:
+		return solar.Event(date, lat, lon, -t.elevation, t.event.rising())
+	case t.event == SunNoon:
+		return solar.Noon(date, lon), true
+	}
+	return solar.Event(date, lat, lon, t.event.depression(), t.event.rising())
+}
+
+// fixedBefore finds the first fallback time after the given instant on a day
+// sun.sun shows to be without the event: one before the day of its next
+// occurrence. When that occurrence is tomorrow, today is taken to have had
+// one already, since sun.sun cannot say.
+func (t *sunTrigger) fixedBefore(after, event time.Time) (time.Time, bool) {
+	if !t.fallback.fixed {
+		return time.Time{}, false
+	}
+	today := internal.WallClock(after, 0, 0)
+	eventDay := internal.WallClock(event, 0, 0)
+	if !today.AddDate(0, 0, 1).Before(eventDay) {
+		return time.Time{}, false
+	}
+	for day := today; day.Before(eventDay); day = day.AddDate(0, 0, 1) {
+		if fixed := t.fallback.at.On(day); fixed.After(after) {
+			return fixed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// place finds the coordinates to compute times for: the zone's, or the home's
+// from the configuration, falling back to zone.home when it could not be read.
+func (t *sunTrigger) place() (float64, float64, bool) {
+	if t.zone != "" {
+		return t.zoneCoordinates(t.zone)
+	}
+	if t.home != nil {
+		if lat, lon, ok := t.home(); ok {
+			return lat, lon, true
+		}
+	}
+	return t.zoneCoordinates(homeZoneID)
+}
+
+// zoneCoordinates reads a zone's position from its attributes, where Home
+// Assistant publishes it as plain numbers.
+func (t *sunTrigger) zoneCoordinates(zoneID string) (float64, float64, bool) {
+	zone, err := t.state.Get(zoneID)
+	if err != nil {
+		return 0, 0, false
+	}
+	lat, latOK := zone.Attributes["latitude"].(float64)
+	lon, lonOK := zone.Attributes["longitude"].(float64)
+	return lat, lon, latOK && lonOK
+}
+
 func (t *sunTrigger) String() string {
-	if t.offset == 0 {
-		return t.event.String()
+	label := t.event.String()
+	if t.byElevation {
+		label = fmt.Sprintf("sun rises above %g°", t.elevation)
+		if !t.event.rising() {
+			label = fmt.Sprintf("sun drops below %g°", t.elevation)
+		}
 	}
-	// Duration renders its own minus sign but never a plus, and the + flag does
-	// nothing for a string verb.
+	// A duration renders its own minus sign but never a plus.
 	if t.offset > 0 {
-		return fmt.Sprintf("%s+%s", t.event, t.offset)
+		label += "+" + internal.FormatDuration(t.offset)
+	} else if t.offset < 0 {
+		label += internal.FormatDuration(t.offset)
+	}
+
+	switch {
+	case t.at:
+		label = fmt.Sprintf("%s at %g,%g", label, t.lat, t.lon)
+	case t.zone != "":
+		label = fmt.Sprintf("%s at %s", label, t.zone)
+	}
+	if t.fallback.fixed {
+		label = fmt.Sprintf("%s, else %s", label, t.fallback.at)
+	}
+	if len(t.days) > 0 {
+		label = fmt.Sprintf("%s on %s", label, t.days)
+	}
+	return label
+}
+
+// darkTrigger is an interval confined to the night, built by WhileDark. It
+// fires at sunset, then on the interval's usual ticks until sunrise.
+//
+// The ticks stay on the interval's own grid rather than counting from sunset,
+// so the times it gives are the same whenever it is asked. The scheduler asks
+// again each time sun.sun changes, and counting from the last answer would
+// push the next tick back every time.
+type darkTrigger struct {
+	every scheduleTrigger
+
+	// state is bound at registration, as for a sunTrigger.
+	state StateReader
+}
+
+func (t *darkTrigger) trigger() {}
+
+func (t *darkTrigger) validate() error { return t.every.err }
+
+func (t *darkTrigger) bind(state StateReader) { t.state = state }
+
+// dynamic has the scheduler re-derive the times whenever sun.sun changes, which
+// is how each night's window is found.
+func (t *darkTrigger) dynamic() bool { return true }
+
+func (t *darkTrigger) NextTime(after time.Time) (time.Time, bool) {
+	if t.state == nil {
+		return time.Time{}, false
+	}
+	sun, err := t.state.Get(SunEntityID)
+	if err != nil {
+		return time.Time{}, false
+	}
+	setting, okSet := sunTime(sun, SunSetting.attribute(), after.Location())
+	rising, okRise := sunTime(sun, SunRising.attribute(), after.Location())
+	if !okSet || !okRise {
+		return time.Time{}, false
+	}
+
+	// By day the next sunset comes before the next sunrise, and the window is
+	// between them. By night the sunrise comes first, and the window is
+	// already open until then.
+	open := rising.Before(setting) || !setting.After(after)
+	if !open {
+		return setting, true
 	}
-	return fmt.Sprintf("%s%s", t.event, t.offset)
+	if tick, ok := t.every.NextTime(after); ok && tick.Before(rising) {
+		return tick, true
+	}
+	if setting.After(after) {
+		return setting, true
+	}
+	// The window has closed and sun.sun has not yet published the next
+	// sunset. The refresh its update triggers finds the time.
+	return time.Time{}, false
 }
 
+func (t *darkTrigger) String() string { return t.every.label + " while dark" }
+
 type sunUpCondition struct{ up bool }
 
 // SunIsUp holds while Home Assistant reports the sun above the horizon.
@@ -144,7 +585,7 @@ func SunIsDown() Condition { return sunUpCondition{up: false} }
 func (c sunUpCondition) Eval(_ context.Context, ec EvalContext) (bool, error) {
 	sun, err := ec.State.Get(SunEntityID)
 	if err != nil {
-		return false, fmt.Errorf("reading %s: %w", SunEntityID, err)
+		return false, &EntityReadError{EntityID: SunEntityID, Err: err}
 	}
 	return (sun.State == "above_horizon") == c.up, nil
 }
@@ -155,3 +596,40 @@ func (c sunUpCondition) String() string {
 	}
 	return "sun is down"
 }
+
+type sunElevationCondition struct {
+	degrees float64
+	above   bool
+}
+
+// SunIsAbove holds while Home Assistant reports the sun higher than the
+// elevation, in degrees above the horizon.
+func SunIsAbove(degrees float64) Condition {
+	return sunElevationCondition{degrees: degrees, above: true}
+}
+
+// SunIsBelow holds while Home Assistant reports the sun lower than the
+// elevation, such as 4° for a room that needs its lights before sunset.
+func SunIsBelow(degrees float64) Condition { return sunElevationCondition{degrees: degrees} }
+
+func (c sunElevationCondition) Eval(_ context.Context, ec EvalContext) (bool, error) {
+	sun, err := ec.State.Get(SunEntityID)
+	if err != nil {
+		return false, &EntityReadError{EntityID: SunEntityID, Err: err}
+	}
+	elevation, ok := sun.Attributes["elevation"].(float64)
+	if !ok {
+		return false, &EntityReadError{EntityID: SunEntityID, Err: errors.New("no elevation attribute")}
+	}
+	if c.above {
+		return elevation > c.degrees, nil
+	}
+	return elevation < c.degrees, nil
+}
+
+func (c sunElevationCondition) String() string {
+	if c.above {
+		return fmt.Sprintf("sun is above %g°", c.degrees)
+	}
+	return fmt.Sprintf("sun is below %g°", c.degrees)
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -61,6 +62,22 @@ func TestSunTriggerAppliesOffset(t *testing.T) {
 	assert.True(t, got.Equal(setting.Add(-30*time.Minute)), "got %v", got)
 }
 
+// The days a sun time falls on are counted in the clock's zone, which the app's
+// Timezone sets, rather than the process's.
+func TestSunTimesFollowTheClocksZone(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+	setting := time.Date(2026, 7, 19, 20, 33, 0, 0, chicago)
+	s := stateWith(sunEntity(setting.Add(-14*time.Hour), setting))
+
+	trig := Sunset()
+	trig.(interface{ bind(StateReader) }).bind(s)
+
+	got, ok := trig.NextTime(time.Date(2026, 7, 19, 3, 0, 0, 0, chicago))
+	require.True(t, ok)
+	assert.Equal(t, setting, got)
+}
+
 // An unbound trigger has nothing to read. It must report that rather than
 // answering with a zero time the scheduler would treat as due immediately.
 func TestUnboundSunTriggerDoesNotFire(t *testing.T) {
@@ -147,3 +164,321 @@ func TestSunAutomationRegistersAndBinds(t *testing.T) {
 
 	assert.True(t, app.schedules.peek().fireAt.Equal(setting.Add(-15*time.Minute)))
 }
+
+// Another property has no sun.sun of its own, so its times are computed. Even a
+// few hundred kilometres west moves sunset by many minutes.
+func TestSunTriggerAtLocationComputesItsOwnTimes(t *testing.T) {
+	setting := time.Date(2026, 6, 21, 21, 21, 0, 0, time.Local)
+	home := stateWith(sunEntity(setting.Add(-16*time.Hour), setting))
+
+	london := Sunset()
+	london.(interface{ bind(StateReader) }).bind(home)
+	cabin := Sunset().AtLocation(51.5074, -0.1278)
+	cabin.(interface{ bind(StateReader) }).bind(home)
+
+	after := time.Date(2026, 6, 21, 0, 0, 0, 0, time.UTC)
+	got, ok := cabin.NextTime(after)
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Date(2026, 6, 21, 20, 21, 0, 0, time.UTC), got, 3*time.Minute)
+
+	// The next one is the following evening, not the same one again.
+	again, ok := cabin.NextTime(got)
+	require.True(t, ok)
+	assert.WithinDuration(t, got.AddDate(0, 0, 1), again, 2*time.Minute)
+
+	assert.False(t, cabin.(dynamicTrigger).dynamic(), "sun.sun says nothing about the cabin")
+	assert.True(t, london.(dynamicTrigger).dynamic())
+}
+
+func TestSunTriggerAtZoneReadsTheZone(t *testing.T) {
+	s := stateWith(EntityState{
+		EntityID:   "zone.cabin",
+		State:      "0",
+		Attributes: map[string]any{"latitude": 51.5074, "longitude": -0.1278},
+	})
+
+	trig := Sunrise(-10 * time.Minute).AtZone("zone.cabin")
+	trig.(interface{ bind(StateReader) }).bind(s)
+
+	got, ok := trig.NextTime(time.Date(2026, 6, 21, 0, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Date(2026, 6, 21, 3, 33, 0, 0, time.UTC), got, 3*time.Minute)
+	assert.Equal(t, "sunrise-10m at zone.cabin", fmt.Sprint(trig))
+}
+
+func TestSunTriggerAtMissingZoneDoesNotFire(t *testing.T) {
+	trig := Sunset().AtZone("zone.nowhere")
+	trig.(interface{ bind(StateReader) }).bind(stateWith())
+
+	_, ok := trig.NextTime(time.Now())
+	assert.False(t, ok)
+}
+
+func TestSunTriggerRejectsALocationOffTheGlobe(t *testing.T) {
+	_, err := NewAutomation("cabin").
+		On(Sunset().AtLocation(91, 0)).
+		Do(noAction).
+		Build()
+	assert.ErrorIs(t, err, ErrInvalidLocation)
+}
+
+// Tromsø has no sunset from late May to late July. Skipping leaves the trigger
+// waiting for the sun to set again; a fixed fallback fires every evening.
+func TestSunTriggerFallsBackThroughAPolarSummer(t *testing.T) {
+	midsummer := time.Date(2026, 6, 21, 0, 0, 0, 0, time.Local)
+
+	skip := Sunset().AtLocation(69.65, 18.96).WhenMissing(SkipDay())
+	next, ok := skip.NextTime(midsummer)
+	require.True(t, ok)
+	assert.True(t, next.After(time.Date(2026, 7, 15, 0, 0, 0, 0, time.Local)), "got %v", next)
+
+	fixed := Sunset().AtLocation(69.65, 18.96).WhenMissing(UseFixedTime(22, 0))
+	next, ok = fixed.NextTime(midsummer)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 6, 21, 22, 0, 0, 0, time.Local), next)
+
+	next, ok = fixed.NextTime(next)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 6, 22, 22, 0, 0, 0, time.Local), next, "and again the next evening")
+	assert.Equal(t, "sunset at 69.65,18.96, else 22:00", fmt.Sprint(fixed))
+}
+
+// sun.sun shows a missing event as a next time days away. The days before it
+// fall back; the day before an ordinary occurrence does not.
+func TestHomeSunTriggerFallsBackUntilTheNextOccurrence(t *testing.T) {
+	now := time.Date(2026, 6, 21, 12, 0, 0, 0, time.Local)
+	trig := Sunset().WhenMissing(UseFixedTime(21, 0))
+
+	returns := time.Date(2026, 7, 22, 0, 30, 0, 0, time.Local)
+	trig.(interface{ bind(StateReader) }).bind(stateWith(sunEntity(returns, returns)))
+	next, ok := trig.NextTime(now)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 6, 21, 21, 0, 0, 0, time.Local), next)
+
+	tomorrow := time.Date(2026, 6, 22, 0, 30, 0, 0, time.Local)
+	trig.(interface{ bind(StateReader) }).bind(stateWith(sunEntity(tomorrow, tomorrow)))
+	next, ok = trig.NextTime(now)
+	require.True(t, ok)
+	assert.Equal(t, tomorrow, next, "a sunset just after midnight is not a missing one")
+}
+
+// sun.sun only knows the next sunset, so one weeks away is estimated at the
+// same time of day, and corrected once sun.sun publishes that day's.
+func TestSunTriggerOnlyOnLastFriday(t *testing.T) {
+	setting := time.Date(2026, 10, 5, 18, 40, 0, 0, time.Local)
+	trig := Sunset().OnlyOn(LastWeekday(time.Friday))
+	trig.(interface{ bind(StateReader) }).bind(stateWith(sunEntity(setting.Add(-12*time.Hour), setting)))
+
+	next, ok := trig.NextTime(time.Date(2026, 10, 5, 9, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 10, 30, 18, 40, 0, 0, time.Local), next)
+	assert.Equal(t, "sunset on the last Friday", fmt.Sprint(trig))
+
+	actual := time.Date(2026, 10, 30, 17, 52, 0, 0, time.Local)
+	trig.(interface{ bind(StateReader) }).bind(stateWith(sunEntity(actual.Add(-12*time.Hour), actual)))
+	next, ok = trig.NextTime(time.Date(2026, 10, 30, 9, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.Equal(t, actual, next, "on the day, sun.sun's time is used as is")
+
+	// Once it has fired, the next is November's.
+	next, ok = trig.NextTime(actual)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 11, 27, 17, 52, 0, 0, time.Local), next)
+}
+
+// Times computed for a location are exact on any day, so the trigger asks for
+// the chosen day's own.
+func TestSunTriggerAtLocationOnlyOnFirstMonday(t *testing.T) {
+	trig := Sunrise().AtLocation(51.5074, -0.1278).OnlyOn(FirstWeekday(time.Monday), DayOfMonth(15))
+
+	next, ok := trig.NextTime(time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Date(2026, 6, 15, 3, 43, 0, 0, time.UTC), next, 3*time.Minute)
+
+	next, ok = trig.NextTime(next)
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Date(2026, 7, 6, 3, 52, 0, 0, time.UTC), next, 3*time.Minute)
+}
+
+func TestSunFallbackTimeIsChecked(t *testing.T) {
+	_, err := NewAutomation("polar").
+		On(Sunset().WhenMissing(UseFixedTime(25, 0))).
+		Do(noAction).
+		Build()
+	assert.ErrorIs(t, err, ErrInvalidTimeOfDay)
+}
+
+// A sun trigger that loses sun.sun has no next time, but sun.sun coming back
+// gives it one. It is parked in the meantime rather than dropped for good.
+func TestSchedulerParksASunTriggerWithoutATime(t *testing.T) {
+	setting := time.Date(2026, 7, 19, 20, 33, 0, 0, time.Local)
+	s := stateWith(sunEntity(setting.Add(-14*time.Hour), setting))
+
+	clock := testClock()
+	clock.Set(setting.Add(-time.Hour))
+	sched := newScheduler(clock)
+
+	trig := Sunset()
+	trig.(interface{ bind(StateReader) }).bind(s)
+	require.True(t, sched.add(schedulerAdapter{trigger: trig}, func() {}))
+
+	s.cache.remove(SunEntityID)
+	assert.Equal(t, 1, sched.runDue(setting))
+	assert.Equal(t, 0, sched.len(), "nothing to queue it for")
+
+	assert.Equal(t, 0, sched.refresh(setting), "still nothing to read")
+
+	tomorrow := setting.AddDate(0, 0, 1)
+	s.cache.apply(sunEntity(tomorrow.Add(-14*time.Hour), tomorrow))
+	assert.Equal(t, 1, sched.refresh(setting))
+	require.Equal(t, 1, sched.len())
+	assert.True(t, sched.peek().fireAt.Equal(tomorrow))
+}
+
+func TestWhileDarkOpensAtSunset(t *testing.T) {
+	rising := time.Date(2026, 7, 20, 6, 30, 0, 0, time.Local)
+	setting := time.Date(2026, 7, 19, 20, 33, 0, 0, time.Local)
+	trig := Every(time.Hour).WhileDark()
+	trig.(interface{ bind(StateReader) }).bind(stateWith(sunEntity(rising, setting)))
+
+	got, ok := trig.NextTime(time.Date(2026, 7, 19, 12, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.True(t, got.Equal(setting), "by day the first run is at sunset, got %v", got)
+
+	// Asked again from sunset, before sun.sun has rolled over, the window is
+	// open and the interval takes over.
+	got, ok = trig.NextTime(setting)
+	require.True(t, ok)
+	want, _ := Every(time.Hour).NextTime(setting)
+	assert.True(t, got.Equal(want), "want %v, got %v", want, got)
+}
+
+func TestWhileDarkClosesAtSunrise(t *testing.T) {
+	// After sunset, sun.sun shows tomorrow's sunset and the coming sunrise.
+	rising := time.Date(2026, 7, 20, 6, 30, 0, 0, time.Local)
+	setting := time.Date(2026, 7, 20, 20, 32, 0, 0, time.Local)
+	trig := Every(time.Hour).WhileDark()
+	trig.(interface{ bind(StateReader) }).bind(stateWith(sunEntity(rising, setting)))
+
+	night := time.Date(2026, 7, 19, 23, 10, 0, 0, time.Local)
+	got, ok := trig.NextTime(night)
+	require.True(t, ok)
+	want, _ := Every(time.Hour).NextTime(night)
+	assert.True(t, got.Equal(want), "want %v, got %v", want, got)
+
+	got, ok = trig.NextTime(rising.Add(-10 * time.Minute))
+	require.True(t, ok)
+	assert.True(t, got.Equal(setting), "no run past sunrise; the next is at sunset, got %v", got)
+}
+
+// Every sun.sun update has the scheduler ask again. The answer must not depend
+// on when it asks, or each update would push the next run back.
+func TestRefreshLeavesAWhileDarkTickAlone(t *testing.T) {
+	rising := time.Date(2026, 7, 20, 6, 30, 0, 0, time.Local)
+	setting := time.Date(2026, 7, 20, 20, 32, 0, 0, time.Local)
+	clock := testClock()
+	clock.Set(time.Date(2026, 7, 19, 23, 10, 0, 0, time.Local))
+	sched := newScheduler(clock)
+
+	trig := Every(time.Hour).WhileDark()
+	trig.(interface{ bind(StateReader) }).bind(stateWith(sunEntity(rising, setting)))
+	require.True(t, sched.add(schedulerAdapter{trigger: trig}, func() {}))
+	before := sched.peek().fireAt
+
+	clock.Set(time.Date(2026, 7, 19, 23, 40, 0, 0, time.Local))
+	assert.Equal(t, 0, sched.refresh(clock.Now()))
+	assert.True(t, sched.peek().fireAt.Equal(before))
+}
+
+func TestWhileDarkWithoutTheSunDoesNotFire(t *testing.T) {
+	trig := Every(time.Hour).WhileDark()
+	trig.(interface{ bind(StateReader) }).bind(stateWith())
+
+	_, ok := trig.NextTime(time.Now())
+	assert.False(t, ok)
+	assert.Equal(t, "every 1h while dark", fmt.Sprint(trig))
+}
+
+func TestSolarNoonReadsHomeAssistantsTime(t *testing.T) {
+	noon := time.Date(2026, 6, 21, 13, 2, 0, 0, time.Local)
+	sun := sunEntity(noon.Add(-8*time.Hour), noon.Add(8*time.Hour))
+	sun.Attributes["next_noon"] = noon.Format(time.RFC3339)
+
+	trig := SolarNoon()
+	trig.(interface{ bind(StateReader) }).bind(stateWith(sun))
+
+	got, ok := trig.NextTime(time.Date(2026, 6, 21, 3, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.True(t, got.Equal(noon), "got %v", got)
+	assert.True(t, trig.(dynamicTrigger).dynamic())
+}
+
+// Home Assistant publishes no deeper twilight and no elevations, so the home's
+// are computed from zone.home.
+func TestUnpublishedSunTimesAreComputedFromTheHomeZone(t *testing.T) {
+	setting := time.Date(2026, 6, 21, 20, 21, 0, 0, time.UTC)
+	home := stateWith(sunEntity(setting.Add(-16*time.Hour), setting), EntityState{
+		EntityID:   "zone.home",
+		State:      "0",
+		Attributes: map[string]any{"latitude": 51.5074, "longitude": -0.1278},
+	})
+	after := time.Date(2026, 6, 21, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		trig  SunTrigger
+		label string
+		want  time.Time
+	}{
+		{NauticalDusk(), "nautical dusk", time.Date(2026, 6, 21, 22, 25, 0, 0, time.UTC)},
+		{SunDropsBelow(4), "sun drops below 4°", time.Date(2026, 6, 21, 19, 47, 0, 0, time.UTC)},
+	} {
+		t.Run(tt.label, func(t *testing.T) {
+			tt.trig.(interface{ bind(StateReader) }).bind(home)
+
+			got, ok := tt.trig.NextTime(after)
+			require.True(t, ok)
+			assert.WithinDuration(t, tt.want, got, 5*time.Minute)
+			assert.False(t, tt.trig.(dynamicTrigger).dynamic(), "sun.sun says nothing of it")
+			assert.Equal(t, tt.label, fmt.Sprint(tt.trig))
+		})
+	}
+
+	// The midsummer sky over London never gets that dark, so astronomical
+	// dusk waits for the first night it does.
+	trig := AstronomicalDusk()
+	trig.(interface{ bind(StateReader) }).bind(home)
+	got, ok := trig.NextTime(after)
+	require.True(t, ok)
+	assert.True(t, got.After(time.Date(2026, 7, 20, 0, 0, 0, 0, time.UTC)), "got %v", got)
+
+	unplaced := SunRisesAbove(10)
+	unplaced.(interface{ bind(StateReader) }).bind(stateWith())
+	_, ok = unplaced.NextTime(after)
+	assert.False(t, ok, "no zone.home, nowhere to compute from")
+}
+
+func TestSunElevationIsChecked(t *testing.T) {
+	_, err := NewAutomation("zenith").
+		On(SunRisesAbove(90)).
+		Do(noAction).
+		Build()
+	assert.ErrorIs(t, err, ErrInvalidArgs)
+}
+
+func TestSunElevationConditions(t *testing.T) {
+	sun := sunEntity(time.Now(), time.Now())
+	sun.Attributes["elevation"] = 3.5
+	s := stateWith(sun)
+
+	ok, err := evalAgainst(t, SunIsBelow(4), s)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = evalAgainst(t, SunIsAbove(4), s)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	delete(sun.Attributes, "elevation")
+	_, err = evalAgainst(t, SunIsAbove(4), stateWith(sun))
+	var readErr *EntityReadError
+	assert.ErrorAs(t, err, &readErr)
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Xevion/go-ha/types"
+)
+
+const (
+	// SupervisorURL is where an add-on reaches Home Assistant, through the
+	// Supervisor's proxy.
+	SupervisorURL = "http://supervisor/core"
+
+	// supervisorTokenEnv names the variable the Supervisor gives each add-on
+	// its token in.
+	supervisorTokenEnv = "SUPERVISOR_TOKEN"
+)
+
+// supervisorDefaults fills in the URL and token of an app running as a Home
+// Assistant add-on, when neither was given. Outside an add-on it changes
+// nothing, so NewApp reports the missing fields as before.
+func supervisorDefaults(request types.NewAppRequest) types.NewAppRequest {
+	if request.URL != "" || request.HAAuthToken != "" {
+		return request
+	}
+	if token := os.Getenv(supervisorTokenEnv); token != "" {
+		request.URL, request.HAAuthToken = SupervisorURL, token
+	}
+	return request
+}
+
+// NewAppFromSupervisor is NewApp for an app running as a Home Assistant
+// add-on, taking the URL and token from the add-on's environment. The add-on
+// needs homeassistant_api: true in its config for the Supervisor to issue the
+// token. Any URL or token in the request is replaced.
+//
+// NewApp does the same when given neither, so one binary runs unchanged inside
+// and outside an add-on; this is for when running anywhere else is a mistake.
+func NewAppFromSupervisor(request types.NewAppRequest) (*App, error) {
+	token := os.Getenv(supervisorTokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("%w: %s is not set; is this running as an add-on with homeassistant_api enabled?",
+			ErrInvalidArgs, supervisorTokenEnv)
+	}
+	request.URL, request.HAAuthToken = SupervisorURL, token
+	return NewApp(request)
+}
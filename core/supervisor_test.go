@@ -0,0 +1,31 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestSupervisorDefaultsInsideAnAddOn(t *testing.T) {
+	t.Setenv(supervisorTokenEnv, "addon-token")
+
+	got := supervisorDefaults(types.NewAppRequest{})
+	assert.Equal(t, SupervisorURL, got.URL)
+	assert.Equal(t, "addon-token", got.HAAuthToken)
+
+	// Given either, the caller meant something and nothing is filled in.
+	got = supervisorDefaults(types.NewAppRequest{URL: "http://ha.local:8123"})
+	assert.Equal(t, "http://ha.local:8123", got.URL)
+	assert.Empty(t, got.HAAuthToken)
+}
+
+func TestSupervisorDefaultsOutsideAnAddOn(t *testing.T) {
+	t.Setenv(supervisorTokenEnv, "")
+
+	assert.Equal(t, types.NewAppRequest{}, supervisorDefaults(types.NewAppRequest{}))
+
+	_, err := NewAppFromSupervisor(types.NewAppRequest{})
+	assert.ErrorIs(t, err, ErrInvalidArgs)
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrTemplate reports a template Home Assistant could render at first but not
+// on a later change, such as one reading an entity that has since gone.
+var ErrTemplate = errors.New("template error")
+
+// Template renders Jinja templates with Home Assistant's own engine, so an
+// automation can ask anything a template can answer, such as
+// "{{ states('sensor.temp') | float > 20 }}", without reimplementing it.
+type Template struct {
+	app     *App
+	timeout time.Duration
+}
+
+// templateMessage is one rendering render_template delivers: the result, or
+// the error a re-render ran into.
+type templateMessage struct {
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+// text is the result as the string a template writes. Home Assistant parses
+// results into numbers, booleans and lists where it can; those come back as
+// their JSON text, so true is "true" and 21.5 is "21.5".
+func (m templateMessage) text() (string, error) {
+	if m.Error != "" {
+		return "", fmt.Errorf("%w: %s", ErrTemplate, m.Error)
+	}
+	var s string
+	if err := json.Unmarshal(m.Result, &s); err == nil {
+		return s, nil
+	}
+	if string(m.Result) == "null" {
+		return "", nil
+	}
+	return string(m.Result), nil
+}
+
+func (t *Template) open(template string, handler func(templateMessage)) (RawSubscription, error) {
+	if template == "" {
+		return RawSubscription{}, fmt.Errorf("%w: an empty template", ErrInvalidArgs)
+	}
+	return t.app.Subscribe(map[string]any{
+		"type":          "render_template",
+		"template":      template,
+		"report_errors": true,
+	}, func(raw json.RawMessage) {
+		var msg templateMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.app.log.Error("Failed to decode a template rendering", "err", err)
+			return
+		}
+		handler(msg)
+	})
+}
+
+// Render renders the template once and returns the result. A template Home
+// Assistant cannot parse is returned as an error, as is no answer within the
+// app's ServiceTimeout.
+func (t *Template) Render(template string) (string, error) {
+	first := make(chan templateMessage, 1)
+	sub, err := t.open(template, func(msg templateMessage) {
+		select {
+		case first <- msg:
+		default:
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = sub.Cancel() }()
+
+	ctx, cancel := context.WithTimeout(t.app.ctx, t.timeout)
+	defer cancel()
+
+	select {
+	case msg := <-first:
+		return msg.text()
+	case <-ctx.Done():
+		return "", fmt.Errorf("rendering template: %w", ctx.Err())
+	}
+}
+
+// Watch renders the template and calls fn with the result, then again each
+// time the result changes, as the entities it reads change. Renderings that
+// match the last, as after a reconnect, are not passed on. A re-render that
+// fails is logged and skipped.
+//
+// fn runs on a worker goroutine. Cancel the subscription to stop watching.
+func (t *Template) Watch(template string, fn func(result string)) (RawSubscription, error) {
+	var (
+		mu   sync.Mutex
+		seen bool
+		last string
+	)
+	return t.open(template, func(msg templateMessage) {
+		result, err := msg.text()
+		if err != nil {
+			t.app.log.Warn("Failed to render a watched template", "template", template, "error", err)
+			return
+		}
+
+		mu.Lock()
+		changed := !seen || result != last
+		seen, last = true, result
+		mu.Unlock()
+
+		if changed {
+			fn(result)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	// Containers often ship without a zone database, and a zone that cannot
+	// be loaded is exactly what sends an app back to UTC.
+	_ "time/tzdata"
+
+	"github.com/Xevion/go-ha/internal"
+	"github.com/Xevion/go-ha/types"
+)
+
+// zonedClock reads another clock in a fixed location. Every schedule and
+// condition takes its wall-clock times from the instant it is handed, so
+// moving that instant into the configured zone is all it takes for daily
+// triggers, cron expressions and time windows to follow the zone rather than
+// the process's.
+type zonedClock struct {
+	clock Clock
+	loc   *time.Location
+}
+
+func (c zonedClock) Now() time.Time { return c.clock.Now().In(c.loc) }
+
+// zonedTimerClock is a zonedClock over a TimerClock, which keeps its timers,
+// so a test clock advanced past a schedule still wakes the scheduler.
+type zonedTimerClock struct {
+	zonedClock
+}
+
+func (c zonedTimerClock) Timer(d time.Duration) (<-chan time.Time, func()) {
+	return c.clock.(types.TimerClock).Timer(d)
+}
+
+// inZone wraps the clock to read in the named zone, or in Home Assistant's
+// own for HomeTimezone.
+func inZone(clock Clock, name string, http *internal.HttpClient) (Clock, error) {
+	if name == types.HomeTimezone {
+		raw, err := http.GetConfig()
+		if err != nil {
+			return nil, fmt.Errorf("reading Home Assistant's time zone: %w", err)
+		}
+		var config struct {
+			TimeZone string `json:"time_zone"`
+		}
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, fmt.Errorf("decoding config: %w", err)
+		}
+		if config.TimeZone == "" {
+			return nil, fmt.Errorf("config names no time zone")
+		}
+		name = config.TimeZone
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: time zone %q: %w", ErrInvalidArgs, name, err)
+	}
+	zoned := zonedClock{clock: clock, loc: loc}
+	if _, ok := clock.(types.TimerClock); ok {
+		return zonedTimerClock{zoned}, nil
+	}
+	return zoned, nil
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TraceSummary is one run of a Home Assistant automation or script, as
+// trace/list reports it.
+type TraceSummary struct {
+	RunID  string
+	Domain string
+	ItemID string
+
+	// State is where the run stands: "running" or "stopped".
+	State string
+
+	// Execution is how a stopped run ended: "finished", "error", "aborted",
+	// "cancelled", "failed_conditions" and so on.
+	Execution string
+
+	// LastStep is the path of the last step reached, such as "action/2".
+	LastStep string
+
+	// Error is the error the run ended with, if it ended with one.
+	Error string
+
+	// Trigger describes what started an automation's run.
+	Trigger string
+
+	Start  time.Time
+	Finish time.Time
+}
+
+// Failed reports whether the run ended in an error.
+func (s TraceSummary) Failed() bool {
+	return s.Error != "" || s.Execution == "error"
+}
+
+func (s *TraceSummary) UnmarshalJSON(data []byte) error {
+	var wire struct {
+		RunID     string `json:"run_id"`
+		Domain    string `json:"domain"`
+		ItemID    string `json:"item_id"`
+		State     string `json:"state"`
+		Execution string `json:"script_execution"`
+		LastStep  string `json:"last_step"`
+		Error     string `json:"error"`
+		Trigger   string `json:"trigger"`
+		Timestamp struct {
+			Start  time.Time  `json:"start"`
+			Finish *time.Time `json:"finish"`
+		} `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*s = TraceSummary{
+		RunID:     wire.RunID,
+		Domain:    wire.Domain,
+		ItemID:    wire.ItemID,
+		State:     wire.State,
+		Execution: wire.Execution,
+		LastStep:  wire.LastStep,
+		Error:     wire.Error,
+		Trigger:   wire.Trigger,
+		Start:     wire.Timestamp.Start,
+	}
+	// A run still going has a null finish.
+	if wire.Timestamp.Finish != nil {
+		s.Finish = *wire.Timestamp.Finish
+	}
+	return nil
+}
+
+// Trace is one run in full, as trace/get reports it.
+type Trace struct {
+	TraceSummary
+
+	// Steps are the steps the run took, in the order it took them.
+	Steps []TraceStep
+
+	// Config is the automation or script as it stood when it ran.
+	Config map[string]any
+}
+
+// TraceStep is one step of a run.
+type TraceStep struct {
+	// Path places the step in the config, such as "action/1/sequence/0".
+	Path      string         `json:"path"`
+	Timestamp time.Time      `json:"timestamp"`
+	Variables map[string]any `json:"changed_variables"`
+	Result    map[string]any `json:"result"`
+	Error     string         `json:"error"`
+}
+
+func (t *Trace) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &t.TraceSummary); err != nil {
+		return err
+	}
+	var wire struct {
+		Trace  map[string][]TraceStep `json:"trace"`
+		Config map[string]any         `json:"config"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	t.Config = wire.Config
+	t.Steps = nil
+	for _, steps := range wire.Trace {
+		t.Steps = append(t.Steps, steps...)
+	}
+	slices.SortStableFunc(t.Steps, func(a, b TraceStep) int {
+		if c := a.Timestamp.Compare(b.Timestamp); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return nil
+}
+
+// Traces lists the stored runs of an automation or script, newest first, so
+// Go code that invoked one can find out why it failed. id is the entity, such
+// as "script.good_night" or "automation.porch_lights". Home Assistant keeps
+// the last few runs of each, five by default.
+func (app *App) Traces(id string) ([]TraceSummary, error) {
+	domain, item, err := app.traceItem(id)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := app.Command(map[string]any{"type": "trace/list", "domain": domain, "item_id": item})
+	if err != nil {
+		return nil, err
+	}
+	var list []TraceSummary
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("decoding traces of %s: %w", id, err)
+	}
+	slices.SortStableFunc(list, func(a, b TraceSummary) int { return b.Start.Compare(a.Start) })
+	return list, nil
+}
+
+// Trace reads one run of an automation or script in full, step by step. An
+// empty runID reads the latest run.
+func (app *App) Trace(id, runID string) (Trace, error) {
+	domain, item, err := app.traceItem(id)
+	if err != nil {
+		return Trace{}, err
+	}
+	if runID == "" {
+		list, err := app.Traces(id)
+		if err != nil {
+			return Trace{}, err
+		}
+		if len(list) == 0 {
+			return Trace{}, fmt.Errorf("%w: %s has no stored runs", ErrInvalidArgs, id)
+		}
+		runID = list[0].RunID
+	}
+
+	raw, err := app.Command(map[string]any{"type": "trace/get", "domain": domain, "item_id": item, "run_id": runID})
+	if err != nil {
+		return Trace{}, err
+	}
+	var trace Trace
+	if err := json.Unmarshal(raw, &trace); err != nil {
+		return Trace{}, fmt.Errorf("decoding trace %s of %s: %w", runID, id, err)
+	}
+	return trace, nil
+}
+
+// traceItem finds what Home Assistant files an entity's traces under. A
+// script's are under its object id, an automation's under the id in its
+// config, which its state carries as an attribute.
+func (app *App) traceItem(id string) (domain, item string, err error) {
+	domain, object, ok := strings.Cut(id, ".")
+	if !ok || object == "" {
+		return "", "", fmt.Errorf("%w: %q is not an entity id", ErrInvalidArgs, id)
+	}
+
+	switch domain {
+	case "script":
+		return domain, object, nil
+	case "automation":
+		es, err := app.state.Get(id)
+		if err != nil {
+			return "", "", err
+		}
+		item, _ := es.Attributes["id"].(string)
+		if item == "" {
+			return "", "", fmt.Errorf("%w: %s has no id in its config, and Home Assistant only traces automations that do", ErrInvalidArgs, id)
+		}
+		return domain, item, nil
+	}
+	return "", "", fmt.Errorf("%w: only automations and scripts are traced, not %s", ErrInvalidArgs, id)
+}
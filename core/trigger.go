@@ -2,8 +2,10 @@ package core
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Xevion/go-ha/internal"
 	"github.com/Xevion/go-ha/internal/scheduling"
 	"github.com/Xevion/go-ha/types"
 )
@@ -41,11 +43,25 @@ type EventTrigger interface {
 	Matches(ev Event) bool
 }
 
-// Subscription declares an event type an automation needs delivered.
+// Subscription declares an event type an automation needs delivered. An empty
+// EventType asks for every event.
 type Subscription struct {
 	EventType string
+
+	// Command, when set, is a websocket command that opens a stream of its
+	// own, such as mqtt/subscribe, in place of subscribing to EventType. The
+	// stream is the trigger's alone: each message it delivers reaches the
+	// trigger as an Event of type EventType, with the message in Raw.
+	Command map[string]any
+
+	// decode builds the event from a message of Command's stream, for the
+	// triggers here that model what theirs carry.
+	decode func(raw []byte) (Event, error)
 }
 
+// anyEventType is the Subscription EventType that receives every event.
+const anyEventType = ""
+
 // scheduleTrigger adapts the internal scheduling triggers to the public
 // contract, which reports absence with a bool rather than a nil pointer.
 type scheduleTrigger struct {
@@ -71,24 +87,157 @@ func (t scheduleTrigger) validate() error { return t.err }
 
 func (t scheduleTrigger) String() string { return t.label }
 
+// DailyTrigger fires at a time of day, every day unless narrowed to some days
+// of the week.
+type DailyTrigger interface {
+	ScheduleTrigger
+
+	// OnWeekdays fires only on the given days of the week. Days outside them
+	// are passed over when the next time is worked out, so the automation is
+	// never woken on them to be turned away by a condition.
+	OnWeekdays(days ...time.Weekday) DailyTrigger
+
+	// OnWeekends fires only on Saturdays and Sundays.
+	OnWeekends() DailyTrigger
+
+	// OnWorkdays fires only on working days: Monday to Friday, less public
+	// holidays, as the given Workdays tells them. Without one it reads
+	// binary_sensor.workday_sensor.
+	OnWorkdays(workdays ...Workdays) DailyTrigger
+
+	// OnHolidays fires only on the days OnWorkdays passes over: weekends and
+	// public holidays.
+	OnHolidays(workdays ...Workdays) DailyTrigger
+}
+
+// dailyTrigger is the scheduleTrigger for a FixedTimeTrigger, keeping what it
+// was built from so it can be narrowed.
+type dailyTrigger struct {
+	scheduleTrigger
+	at   ClockTime
+	days []time.Weekday
+
+	// work, set by OnWorkdays and OnHolidays, narrows it further.
+	work *workdayFilter
+}
+
 // Daily fires at the same time every day.
-func Daily(at ClockTime) ScheduleTrigger {
-	if at.err != nil {
-		return scheduleTrigger{err: at.err, label: "daily"}
+func Daily(at ClockTime) DailyTrigger {
+	return newDaily(at, nil)
+}
+
+func newDaily(at ClockTime, days []time.Weekday) dailyTrigger {
+	label := "daily at " + at.String()
+	if days != nil {
+		names := make([]string, len(days))
+		for i, d := range days {
+			names[i] = d.String()[:3]
+		}
+		label = "at " + at.String() + " on " + strings.Join(names, ", ")
 	}
-	return scheduleTrigger{
-		inner: &scheduling.FixedTimeTrigger{Hour: at.hour, Minute: at.minute},
-		label: "daily at " + at.String(),
+
+	t := dailyTrigger{at: at, days: days, scheduleTrigger: scheduleTrigger{label: label}}
+	switch {
+	case at.err != nil:
+		t.err = at.err
+	case days != nil && len(days) == 0:
+		t.err = fmt.Errorf("%w: OnWeekdays needs at least one day", ErrInvalidArgs)
+	default:
+		t.inner = &scheduling.FixedTimeTrigger{Hour: at.hour, Minute: at.minute, Days: days}
 	}
+	return t
+}
+
+func (t dailyTrigger) OnWeekdays(days ...time.Weekday) DailyTrigger {
+	// Never nil, so narrowing to no days at all is told apart from not
+	// narrowing, and refused.
+	return newDaily(t.at, append([]time.Weekday{}, days...)).narrowed(t.work)
+}
+
+func (t dailyTrigger) OnWeekends() DailyTrigger {
+	return t.OnWeekdays(time.Saturday, time.Sunday)
+}
+
+func (t dailyTrigger) OnWorkdays(workdays ...Workdays) DailyTrigger {
+	return t.narrowed(newWorkdayFilter(false, workdays))
+}
+
+func (t dailyTrigger) OnHolidays(workdays ...Workdays) DailyTrigger {
+	return t.narrowed(newWorkdayFilter(true, workdays))
+}
+
+// narrowed is the trigger confined by the filter, in place of any it had.
+func (t dailyTrigger) narrowed(work *workdayFilter) dailyTrigger {
+	if work == nil {
+		return t
+	}
+	next := newDaily(t.at, t.days)
+	next.work = work
+	next.label += " " + work.String()
+	if next.err == nil {
+		next.err = work.workdays.err
+	}
+	return next
+}
+
+func (t dailyTrigger) NextTime(after time.Time) (time.Time, bool) {
+	next, ok := t.scheduleTrigger.NextTime(after)
+	if !ok || t.work == nil || !t.work.ahead() {
+		return next, ok
+	}
+	for range searchDays {
+		if t.work.picks(next) {
+			return next, true
+		}
+		if next, ok = t.scheduleTrigger.NextTime(next); !ok {
+			return next, false
+		}
+	}
+	return time.Time{}, false
+}
+
+// bind gives a trigger narrowed by a workday sensor the reader to read it
+// with.
+func (t dailyTrigger) bind(state StateReader) {
+	if t.work != nil {
+		t.work.state = state
+	}
+}
+
+// skips reports a day the trigger is due but turns out not to fire on, which
+// for a workday sensor is only known on the day.
+func (t dailyTrigger) skips(now time.Time) bool {
+	return t.work != nil && !t.work.ahead() && !t.work.picks(now)
+}
+
+// IntervalTrigger fires on a fixed interval, around the clock unless confined
+// to the dark hours.
+type IntervalTrigger interface {
+	ScheduleTrigger
+
+	// WhileDark fires only between sunset and the following sunrise, taken
+	// from sun.sun each day: once at sunset, then on the interval until
+	// sunrise. It replaces a fixed window that drifts out of step with the
+	// seasons.
+	WhileDark() ScheduleTrigger
+}
+
+// intervalTrigger is the scheduleTrigger for an IntervalTrigger.
+type intervalTrigger struct {
+	scheduleTrigger
 }
 
 // Every fires on a fixed interval.
-func Every(interval time.Duration) ScheduleTrigger {
+func Every(interval time.Duration) IntervalTrigger {
 	inner, err := scheduling.NewIntervalTrigger(interval)
 	if err != nil {
-		return scheduleTrigger{err: err, label: "every " + interval.String()}
+		return intervalTrigger{scheduleTrigger{err: err, label: "every " + internal.FormatDuration(interval)}}
 	}
-	return scheduleTrigger{inner: inner, label: "every " + interval.String()}
+	return intervalTrigger{scheduleTrigger{inner: inner, label: "every " + internal.FormatDuration(interval)}}
+}
+
+func (t intervalTrigger) WhileDark() ScheduleTrigger {
+	return &darkTrigger{every: t.scheduleTrigger}
 }
 
 // Cron fires on a cron expression, for schedules the other triggers cannot
@@ -104,16 +253,32 @@ func Cron(expression string) ScheduleTrigger {
 	return scheduleTrigger{inner: inner, label: "cron(" + expression + ")"}
 }
 
+// StartupTrigger fires once, when the app starts.
+type StartupTrigger interface {
+	ScheduleTrigger
+
+	// Order places this run among the other startup runs when the app
+	// staggers them, lowest first. Those sharing an order go in registration
+	// order, and the default is zero. Without a stagger every startup run
+	// starts at once and the order is moot.
+	Order(n int) StartupTrigger
+}
+
 // startupTrigger fires once, when the app starts.
-type startupTrigger struct{ fired bool }
+type startupTrigger struct {
+	fired bool
+	order int
+}
 
 // AtStartup fires once when Start is called, for automations that need to act
 // on the state of the world as they find it rather than waiting for it to
 // change.
-func AtStartup() ScheduleTrigger { return &startupTrigger{} }
+func AtStartup() StartupTrigger { return &startupTrigger{} }
 
 func (t *startupTrigger) trigger() {}
 
+func (t *startupTrigger) Order(n int) StartupTrigger { return &startupTrigger{order: n} }
+
 // NextTime reports the given instant the first time it is asked and never
 // again, which fires it on the scheduler's first pass and then retires it.
 func (t *startupTrigger) NextTime(after time.Time) (time.Time, bool) {
@@ -124,7 +289,12 @@ func (t *startupTrigger) NextTime(after time.Time) (time.Time, bool) {
 	return after, true
 }
 
-func (t *startupTrigger) String() string { return "startup" }
+func (t *startupTrigger) String() string {
+	if t.order != 0 {
+		return fmt.Sprintf("startup (order %d)", t.order)
+	}
+	return "startup"
+}
 
 // EntityRef is anything that names an entity: a plain string, or one of the
 // domain-typed ids cmd/generate emits.
@@ -0,0 +1,281 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xevion/go-ha/internal"
+)
+
+// Requirement is what one entity has to hold for a WhenAll to fire. Build one
+// with Require.
+type Requirement struct {
+	entityID  string
+	attribute string
+	to        []string
+	from      []string
+	above     *float64
+	below     *float64
+	hold      time.Duration
+}
+
+// Require starts a requirement on an entity. Narrow it with To, From, Above,
+// Below and For; one left as it is holds whatever state the entity is in.
+func Require[T EntityRef](entityID T) Requirement {
+	return Requirement{entityID: string(entityID)}
+}
+
+// To requires the entity to be in one of the given states.
+func (r Requirement) To(states ...string) Requirement {
+	r.to = append(slices.Clone(r.to), states...)
+	return r
+}
+
+// From requires the entity to have come to its state from one of those given.
+// Only transitions seen since the app started count, so the requirement is
+// unmet until the entity has changed at least once.
+func (r Requirement) From(states ...string) Requirement {
+	r.from = append(slices.Clone(r.from), states...)
+	return r
+}
+
+// Above requires the entity's value to be above n. A value that is not a
+// number fails it.
+func (r Requirement) Above(n float64) Requirement {
+	r.above = &n
+	return r
+}
+
+// Below requires the entity's value to be below n. A value that is not a
+// number fails it.
+func (r Requirement) Below(n float64) Requirement {
+	r.below = &n
+	return r
+}
+
+// Attribute tests an attribute in place of the state, for To, Above and
+// Below.
+func (r Requirement) Attribute(name string) Requirement {
+	r.attribute = name
+	return r
+}
+
+// For requires the rest to have held for d before it counts as met.
+func (r Requirement) For(d time.Duration) Requirement {
+	r.hold = d
+	return r
+}
+
+// met reports whether the entity's state satisfies the requirement. came is
+// the state it last changed from, or empty when no change has been seen.
+func (r Requirement) met(es EntityState, came string) bool {
+	value := es.State
+	if r.attribute != "" {
+		v, ok := es.Attributes[r.attribute]
+		if !ok || v == nil {
+			return false
+		}
+		value = fmt.Sprint(v)
+	}
+	if len(r.to) > 0 && !slices.Contains(r.to, value) {
+		return false
+	}
+	if len(r.from) > 0 && !slices.Contains(r.from, came) {
+		return false
+	}
+	if r.above != nil || r.below != nil {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		if (r.above != nil && n <= *r.above) || (r.below != nil && n >= *r.below) {
+			return false
+		}
+	}
+	return true
+}
+
+// heldSince is the earliest the requirement can have been met from, going by
+// the entity alone: what it tests has not changed since.
+func (r Requirement) heldSince(es EntityState) time.Time {
+	if r.attribute != "" {
+		return es.LastUpdated
+	}
+	return es.LastChanged
+}
+
+func (r Requirement) String() string {
+	s := r.entityID
+	if r.attribute != "" {
+		s += " " + r.attribute
+	}
+	if len(r.from) > 0 {
+		s += " from " + strings.Join(r.from, " or ")
+	}
+	if len(r.to) > 0 {
+		s += " is " + strings.Join(r.to, " or ")
+	}
+	if r.above != nil {
+		s += fmt.Sprintf(" above %g", *r.above)
+	}
+	if r.below != nil {
+		s += fmt.Sprintf(" below %g", *r.below)
+	}
+	if r.hold > 0 {
+		s += " for " + internal.FormatDuration(r.hold)
+	}
+	return s
+}
+
+// advancingTrigger is implemented by triggers that judge from the state cache,
+// one event after another, when they fire, rather than matching each event on
+// its own. They remember what they have seen, so Build gives each automation
+// a fresh copy.
+type advancingTrigger interface {
+	Trigger
+
+	// concerns reports whether a change to the entity can move the trigger.
+	concerns(entityID string) bool
+
+	// advance takes in the event, or the zero Event when a wait comes due,
+	// and reports whether the trigger fires now, or how long until it may.
+	advance(ev Event, get func(string) (EntityState, bool), now time.Time) (fire bool, wait time.Duration)
+
+	// fresh returns a copy remembering nothing.
+	fresh() advancingTrigger
+}
+
+// WhenAllTrigger fires when every one of its requirements is met at once.
+// Build one with WhenAll.
+type WhenAllTrigger struct {
+	parts []Requirement
+	track *allTracking
+}
+
+// allTracking is what a WhenAll remembers between events.
+type allTracking struct {
+	mu sync.Mutex
+
+	// since is when each requirement was last seen to become met, or zero
+	// while it is not.
+	since []time.Time
+
+	// came is the state each entity last changed from.
+	came map[string]string
+
+	// fired is set once the trigger fires, and cleared when a requirement
+	// stops being met, so it fires once each time they all come together.
+	fired bool
+}
+
+// WhenAll fires when every requirement is met at once, "motion and it is
+// dark", and fires again only once one of them has lapsed and they have come
+// together anew:
+//
+//	WhenAll(
+//		Require("binary_sensor.hall_motion").To("on"),
+//		Require("sensor.hall_lux").Below(20),
+//	)
+//
+// It is evaluated from the state cache on every change to one of the
+// entities. A requirement with For counts only once it has held that long,
+// which the trigger waits out. Each automation built with a WhenAll keeps its
+// own memory of the events, so one can be shared between several.
+func WhenAll(requirements ...Requirement) WhenAllTrigger {
+	return WhenAllTrigger{parts: slices.Clone(requirements)}.fresh().(WhenAllTrigger)
+}
+
+func (t WhenAllTrigger) fresh() advancingTrigger {
+	t.track = &allTracking{since: make([]time.Time, len(t.parts)), came: map[string]string{}}
+	return t
+}
+
+func (t WhenAllTrigger) trigger() {}
+
+func (t WhenAllTrigger) Subscriptions() []Subscription {
+	return []Subscription{{EventType: eventStateChanged}}
+}
+
+// Matches reports whether the event concerns one of the entities. Whether
+// that brings the requirements together is for advance to judge.
+func (t WhenAllTrigger) Matches(ev Event) bool {
+	return ev.Type == eventStateChanged && t.concerns(ev.EntityID)
+}
+
+func (t WhenAllTrigger) concerns(entityID string) bool {
+	return slices.ContainsFunc(t.parts, func(r Requirement) bool { return r.entityID == entityID })
+}
+
+// advance takes in the event, which may be the zero Event when a wait comes
+// due, and reports whether the trigger fires now. When every requirement is
+// met but some have yet to hold for long enough, wait is how long until they
+// all have.
+func (t WhenAllTrigger) advance(ev Event, get func(string) (EntityState, bool), now time.Time) (fire bool, wait time.Duration) {
+	t.track.mu.Lock()
+	defer t.track.mu.Unlock()
+
+	if ev.EntityID != "" && ev.From.State != ev.To.State {
+		t.track.came[ev.EntityID] = ev.From.State
+	}
+
+	all := true
+	for i, r := range t.parts {
+		es, ok := get(r.entityID)
+		if !ok || !r.met(es, t.track.came[r.entityID]) {
+			t.track.since[i] = time.Time{}
+			all = false
+			continue
+		}
+		if t.track.since[i].IsZero() {
+			t.track.since[i] = r.heldSince(es)
+		}
+		wait = max(wait, r.hold-now.Sub(t.track.since[i]))
+	}
+
+	switch {
+	case !all:
+		t.track.fired = false
+		return false, 0
+	case t.track.fired:
+		return false, 0
+	case wait > 0:
+		return false, wait
+	}
+	t.track.fired = true
+	return true, 0
+}
+
+func (t WhenAllTrigger) validate() error {
+	if len(t.parts) == 0 {
+		return fmt.Errorf("%w: WhenAll needs at least one requirement", ErrInvalidArgs)
+	}
+	for _, r := range t.parts {
+		if err := validateEntityID(r.entityID); err != nil {
+			return err
+		}
+		if r.hold < 0 {
+			return fmt.Errorf("%w: requirement on %s has a negative For", ErrInvalidArgs, r.entityID)
+		}
+	}
+	return nil
+}
+
+func (t WhenAllTrigger) referencedEntities() []string {
+	ids := make([]string, 0, len(t.parts))
+	for _, r := range t.parts {
+		ids = append(ids, r.entityID)
+	}
+	return ids
+}
+
+func (t WhenAllTrigger) String() string {
+	parts := make([]string, 0, len(t.parts))
+	for _, r := range t.parts {
+		parts = append(parts, r.String())
+	}
+	return "all of " + strings.Join(parts, ", ")
+}
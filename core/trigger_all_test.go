@@ -0,0 +1,82 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// world is a state cache for WhenAll to read, changed one event at a time.
+type world map[string]EntityState
+
+func (w world) get(id string) (EntityState, bool) {
+	es, ok := w[id]
+	return es, ok
+}
+
+func (w world) change(id, to string, at time.Time) Event {
+	ev := stateChange(id, w[id].State, to)
+	w[id] = EntityState{EntityID: id, State: to, LastChanged: at, LastUpdated: at}
+	return ev
+}
+
+func TestWhenAllFiresOnceTheyComeTogether(t *testing.T) {
+	now := time.Date(2026, 10, 17, 21, 0, 0, 0, time.UTC)
+	w := world{"binary_sensor.motion": entity("binary_sensor.motion", "off"), "sensor.lux": entity("sensor.lux", "45")}
+	trig := WhenAll(Require("binary_sensor.motion").To("on"), Require("sensor.lux").Below(20))
+
+	fire, _ := trig.advance(w.change("binary_sensor.motion", "on", now), w.get, now)
+	assert.False(t, fire, "still light")
+
+	fire, _ = trig.advance(w.change("sensor.lux", "12", now), w.get, now)
+	assert.True(t, fire)
+
+	fire, _ = trig.advance(w.change("sensor.lux", "8", now), w.get, now)
+	assert.False(t, fire, "already together")
+
+	trig.advance(w.change("binary_sensor.motion", "off", now), w.get, now)
+	fire, _ = trig.advance(w.change("binary_sensor.motion", "on", now), w.get, now)
+	assert.True(t, fire, "together again after a lapse")
+
+	fire, _ = trig.advance(w.change("sensor.lux", "dark", now), w.get, now)
+	assert.False(t, fire, "a value that is not a number fails Below")
+}
+
+func TestWhenAllWaitsOutAFor(t *testing.T) {
+	now := time.Date(2026, 10, 17, 21, 0, 0, 0, time.UTC)
+	w := world{"binary_sensor.motion": entity("binary_sensor.motion", "off"), "input_boolean.guest": entity("input_boolean.guest", "on")}
+	trig := WhenAll(
+		Require("binary_sensor.motion").From("off").To("on").For(time.Minute),
+		Require("input_boolean.guest").To("on"),
+	)
+
+	fire, wait := trig.advance(w.change("binary_sensor.motion", "on", now), w.get, now)
+	assert.False(t, fire)
+	assert.Equal(t, time.Minute, wait)
+
+	fire, wait = trig.advance(Event{}, w.get, now.Add(time.Minute))
+	assert.True(t, fire)
+	assert.Zero(t, wait)
+}
+
+func TestWhenAllFromNeedsASeenTransition(t *testing.T) {
+	now := time.Date(2026, 10, 17, 21, 0, 0, 0, time.UTC)
+	w := world{"lock.front": entity("lock.front", "unlocked")}
+	trig := WhenAll(Require("lock.front").From("locked").To("unlocked"))
+
+	fire, _ := trig.advance(Event{}, w.get, now)
+	assert.False(t, fire, "how it came to be unlocked is not known")
+
+	w.change("lock.front", "locked", now)
+	fire, _ = trig.advance(w.change("lock.front", "unlocked", now), w.get, now)
+	assert.True(t, fire)
+}
+
+func TestWhenAllValidates(t *testing.T) {
+	assert.ErrorIs(t, WhenAll().validate(), ErrInvalidArgs)
+	assert.Error(t, WhenAll(Require("motion")).validate())
+	assert.NoError(t, WhenAll(Require("sensor.lux").Attribute("illuminance").Below(20)).validate())
+	assert.Equal(t, "all of binary_sensor.motion is on for 1m, sensor.lux below 20",
+		WhenAll(Require("binary_sensor.motion").To("on").For(time.Minute), Require("sensor.lux").Below(20)).String())
+}
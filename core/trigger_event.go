@@ -1,10 +1,14 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"path"
 	"slices"
 	"strings"
 	"time"
+
+	"github.com/Xevion/go-ha/internal"
 )
 
 const eventStateChanged = "state_changed"
@@ -16,11 +20,18 @@ type StateChangeTrigger struct {
 	from      string
 	to        string
 	hold      time.Duration
+	replay    int
+	sameState bool
 }
 
 // StateChanged fires when any of the given entities changes state. With no
 // entities it fires on every state change, which is rarely what you want.
 //
+// An id may be a glob pattern, so "binary_sensor.door_*" watches every door
+// sensor and "light.*" every light, including those added after registration.
+// The syntax is path.Match's: * for any run of characters, ? for one, and
+// [...] for a class.
+//
 // It is generic over the id type so the domain-typed constants cmd/generate
 // emits can be passed directly, as well as plain strings.
 func StateChanged[T EntityRef](entityIDs ...T) StateChangeTrigger {
@@ -51,6 +62,34 @@ func (t StateChangeTrigger) For(d time.Duration) StateChangeTrigger {
 	return t
 }
 
+// ReplayLast primes the automation on startup with the last n transitions
+// the trigger would have fired on, read from the recorder's history, so an
+// automation reacting to the doorbell knows of the last press instead of
+// starting blind. They are fired once the first snapshot of entity state has
+// landed, oldest first, with Event.Replayed set, and pass the automation's
+// conditions and run mode as live events do: under ModeSingle a replay that
+// lands while an earlier one for the same entity is running is dropped, so use
+// ModeQueued to see all n. For does not apply to them.
+//
+// History reaches back as far as the recorder keeps it, ten days by default.
+// Home Assistant keeps no such record of other events, so only state changes
+// can be replayed, and the trigger has to name its entities.
+func (t StateChangeTrigger) ReplayLast(n int) StateChangeTrigger {
+	t.replay = n
+	return t
+}
+
+// AllowSameStateTransitions fires on events that leave the state as it was,
+// which are otherwise dropped. Home Assistant sends one whenever an
+// attribute moves, and some integrations send one for a repeated report, such
+// as a lock jammed on a second attempt, that is worth announcing again. From
+// and To still apply, to the state either side. It cannot be combined with
+// For, since each repeat would restart the wait.
+func (t StateChangeTrigger) AllowSameStateTransitions() StateChangeTrigger {
+	t.sameState = true
+	return t
+}
+
 func (t StateChangeTrigger) trigger() {}
 
 // holdFor reports how long the state must persist before firing.
@@ -63,9 +102,61 @@ func (t StateChangeTrigger) concerns(ev Event) bool {
 	if ev.Type != eventStateChanged {
 		return false
 	}
-	return len(t.entityIDs) == 0 || slices.Contains(t.entityIDs, ev.EntityID)
+	return t.watches(ev.EntityID)
+}
+
+// watches reports whether the entity is one of the trigger's, by id or by
+// pattern.
+func (t StateChangeTrigger) watches(entityID string) bool {
+	if len(t.entityIDs) == 0 {
+		return true
+	}
+	for _, id := range t.entityIDs {
+		if id == entityID {
+			return true
+		}
+		// Patterns were checked at registration, so an error here cannot
+		// happen, and a plain id matches only itself.
+		if isPattern(id) {
+			if ok, _ := path.Match(id, entityID); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isPattern reports whether an id or event type holds glob syntax rather than
+// naming one. Neither can contain these characters.
+func isPattern(id string) bool {
+	return strings.ContainsAny(id, "*?[")
 }
 
+func (t StateChangeTrigger) validate() error {
+	var errs []error
+	if t.replay < 0 {
+		errs = append(errs, fmt.Errorf("%w: ReplayLast takes a positive count, not %d", ErrInvalidArgs, t.replay))
+	}
+	if t.sameState && t.hold > 0 {
+		errs = append(errs, fmt.Errorf("%w: AllowSameStateTransitions cannot be combined with For", ErrInvalidArgs))
+	}
+	if t.replay > 0 && len(t.entityIDs) == 0 {
+		errs = append(errs, fmt.Errorf("%w: ReplayLast needs the trigger to name its entities", ErrInvalidArgs))
+	}
+	for _, id := range t.entityIDs {
+		if !isPattern(id) {
+			errs = append(errs, validateEntityID(id))
+			continue
+		}
+		if _, err := path.Match(id, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%w: entity pattern %q: %w", ErrInvalidArgs, id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t StateChangeTrigger) referencedEntities() []string { return t.entityIDs }
+
 func (t StateChangeTrigger) Subscriptions() []Subscription {
 	return []Subscription{{EventType: eventStateChanged}}
 }
@@ -82,12 +173,13 @@ func (t StateChangeTrigger) Matches(ev Event) bool {
 	}
 
 	// Home Assistant emits a state_changed whenever attributes move too. A
-	// transition to the state it already held is not a change worth firing on.
-	if ev.To.State == ev.From.State {
+	// transition to the state it already held is not a change worth firing on,
+	// unless the trigger asked for those.
+	if ev.To.State == ev.From.State && !t.sameState {
 		return false
 	}
 
-	if len(t.entityIDs) > 0 && !slices.Contains(t.entityIDs, ev.EntityID) {
+	if !t.watches(ev.EntityID) {
 		return false
 	}
 	if t.from != "" && ev.From.State != t.from {
@@ -107,6 +199,15 @@ func (t StateChangeTrigger) String() string {
 	if t.to != "" {
 		s += " to " + t.to
 	}
+	if t.hold > 0 {
+		s += " for " + internal.FormatDuration(t.hold)
+	}
+	if t.sameState {
+		s += ", repeats included"
+	}
+	if t.replay > 0 {
+		s += fmt.Sprintf(", replaying the last %d", t.replay)
+	}
 	return s
 }
 
@@ -117,6 +218,11 @@ type EventTypeTrigger struct {
 
 // EventFired fires on any of the given Home Assistant event types, for events
 // this package does not model directly.
+//
+// A type may be a glob pattern, such as "zwave_js_*", for integrations that
+// emit families of related events. Home Assistant cannot filter by pattern, so
+// a trigger holding one subscribes to every event and picks its own out, which
+// on a busy instance is a great deal more traffic than a named type.
 func EventFired(eventTypes ...string) EventTypeTrigger {
 	return EventTypeTrigger{eventTypes: eventTypes}
 }
@@ -124,6 +230,11 @@ func EventFired(eventTypes ...string) EventTypeTrigger {
 func (t EventTypeTrigger) trigger() {}
 
 func (t EventTypeTrigger) Subscriptions() []Subscription {
+	// One subscription to everything covers the named types as well, and
+	// subscribing to them besides would deliver each of their events twice.
+	if slices.ContainsFunc(t.eventTypes, isPattern) {
+		return []Subscription{{EventType: anyEventType}}
+	}
 	subs := make([]Subscription, 0, len(t.eventTypes))
 	for _, et := range t.eventTypes {
 		subs = append(subs, Subscription{EventType: et})
@@ -132,13 +243,31 @@ func (t EventTypeTrigger) Subscriptions() []Subscription {
 }
 
 func (t EventTypeTrigger) Matches(ev Event) bool {
-	return slices.Contains(t.eventTypes, ev.Type)
+	for _, et := range t.eventTypes {
+		if et == ev.Type {
+			return true
+		}
+		if isPattern(et) {
+			if ok, _ := path.Match(et, ev.Type); ok {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (t EventTypeTrigger) validate() error {
 	if len(t.eventTypes) == 0 {
 		return fmt.Errorf("%w: EventFired needs at least one event type", ErrInvalidArgs)
 	}
+	for _, et := range t.eventTypes {
+		if !isPattern(et) {
+			continue
+		}
+		if _, err := path.Match(et, ""); err != nil {
+			return fmt.Errorf("%w: event type pattern %q: %w", ErrInvalidArgs, et, err)
+		}
+	}
 	return nil
 }
 
@@ -2,6 +2,7 @@ package core
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,6 +25,29 @@ func TestStateChangedMatchesItsEntities(t *testing.T) {
 	assert.False(t, trig.Matches(stateChange("light.porch", "off", "on")))
 }
 
+func TestStateChangedMatchesPatterns(t *testing.T) {
+	doors := StateChanged("binary_sensor.door_*")
+	assert.True(t, doors.Matches(stateChange("binary_sensor.door_front", "off", "on")))
+	assert.True(t, doors.Matches(stateChange("binary_sensor.door_garage", "off", "on")))
+	assert.False(t, doors.Matches(stateChange("binary_sensor.window_kitchen", "off", "on")))
+	assert.False(t, doors.Matches(stateChange("sensor.door_front_battery", "90", "89")))
+
+	lights := StateChanged("light.*", "switch.fan_?")
+	assert.True(t, lights.Matches(stateChange("light.anything", "off", "on")))
+	assert.True(t, lights.Matches(stateChange("switch.fan_1", "off", "on")))
+	assert.False(t, lights.Matches(stateChange("switch.fan_10", "off", "on")))
+	assert.False(t, lights.Matches(stateChange("lightning.strike", "off", "on")))
+
+	// A pattern's changes away from the awaited state are its concern too,
+	// for For to cancel on.
+	assert.True(t, doors.To("on").concerns(stateChange("binary_sensor.door_front", "on", "off")))
+}
+
+func TestStateChangedRefusesABadPattern(t *testing.T) {
+	assert.NoError(t, StateChanged("light.*", "light.kitchen").validate())
+	assert.ErrorIs(t, StateChanged("light.[kitchen").validate(), ErrInvalidArgs)
+}
+
 // Home Assistant emits state_changed for attribute-only updates, where the
 // state itself is unchanged. Firing on those surprises everyone.
 func TestStateChangedIgnoresUnchangedState(t *testing.T) {
@@ -32,6 +56,16 @@ func TestStateChangedIgnoresUnchangedState(t *testing.T) {
 	assert.False(t, trig.Matches(stateChange("device_tracker.phone", "home", "home")))
 }
 
+func TestAllowSameStateTransitionsFiresOnRepeats(t *testing.T) {
+	trig := StateChanged("lock.front_door").To("jammed").AllowSameStateTransitions()
+
+	assert.True(t, trig.Matches(stateChange("lock.front_door", "jammed", "jammed")))
+	assert.True(t, trig.Matches(stateChange("lock.front_door", "locked", "jammed")))
+	assert.False(t, trig.Matches(stateChange("lock.front_door", "locked", "locked")), "To still applies")
+
+	assert.ErrorIs(t, trig.For(time.Minute).validate(), ErrInvalidArgs)
+}
+
 func TestStateChangedNarrowsByTransition(t *testing.T) {
 	toOn := StateChanged("light.kitchen").To("on")
 	assert.True(t, toOn.Matches(stateChange("light.kitchen", "off", "on")))
@@ -76,6 +110,19 @@ func TestEventFiredSubscribesToEachType(t *testing.T) {
 	assert.Equal(t, "zha_event", subs[1].EventType)
 }
 
+func TestEventFiredMatchesPatterns(t *testing.T) {
+	trig := EventFired("zwave_js_*", "zha_event")
+	assert.True(t, trig.Matches(Event{Type: "zwave_js_notification"}))
+	assert.True(t, trig.Matches(Event{Type: "zwave_js_value_notification"}))
+	assert.True(t, trig.Matches(Event{Type: "zha_event"}))
+	assert.False(t, trig.Matches(Event{Type: "deconz_event"}))
+
+	// A pattern needs every event, and the named type comes with them.
+	assert.Equal(t, []Subscription{{EventType: ""}}, trig.Subscriptions())
+
+	assert.ErrorIs(t, EventFired("zwave_js_[").validate(), ErrInvalidArgs)
+}
+
 func TestEventFiredNeedsAtLeastOneType(t *testing.T) {
 	assert.ErrorIs(t, EventFired().validate(), ErrInvalidArgs)
 }
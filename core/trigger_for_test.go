@@ -157,3 +157,33 @@ func TestSupersededWaitDoesNotOrphanItsReplacement(t *testing.T) {
 
 	assert.False(t, ranB.Load(), "the replacement fired despite being disarmed")
 }
+
+// A callback can pass its check an instant before shutdown. Stop has to wait
+// for it to hand off, or the run it starts races the wait on its runner.
+func TestStopWaitsForACallbackUnderWay(t *testing.T) {
+	p := newPendingRuns()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	p.arm("light.a", 0, func() {
+		close(entered)
+		<-release
+	})
+	<-entered
+
+	stopped := make(chan struct{})
+	go func() { p.stop(); close(stopped) }()
+
+	select {
+	case <-stopped:
+		t.Fatal("stop returned while a callback was still running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop never returned")
+	}
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// eventMQTT is the Event type of a message an MQTT trigger received.
+const eventMQTT = "mqtt"
+
+// MQTTMessage is a message received on an MQTT topic.
+type MQTTMessage struct {
+	Topic   string `json:"topic"`
+	Payload string `json:"payload"`
+	QoS     int    `json:"qos"`
+	Retain  bool   `json:"retain"`
+}
+
+// MQTTTrigger fires on messages published to MQTT topics. Build one with MQTT.
+type MQTTTrigger struct {
+	topics   []string
+	payloads []string
+}
+
+// MQTT fires on every message published to the given topics, such as
+// "zigbee2mqtt/+/action". A topic may use MQTT's wildcards: + for one level
+// and # for every level below. The message reaches the action as
+// run.Event.MQTT.
+//
+// The topics are subscribed through Home Assistant's MQTT integration, on the
+// broker it is connected to, so no broker settings are needed here.
+func MQTT(topics ...string) MQTTTrigger {
+	return MQTTTrigger{topics: topics}
+}
+
+// Payload narrows the trigger to messages whose payload is one of those given,
+// such as a button's "single" or "double".
+func (t MQTTTrigger) Payload(payloads ...string) MQTTTrigger {
+	t.payloads = append(slices.Clone(t.payloads), payloads...)
+	return t
+}
+
+func (t MQTTTrigger) trigger() {}
+
+func (t MQTTTrigger) carriesPayload() {}
+
+// Subscriptions opens one stream per topic, which Home Assistant filters on
+// the broker's side.
+func (t MQTTTrigger) Subscriptions() []Subscription {
+	subs := make([]Subscription, 0, len(t.topics))
+	for _, topic := range t.topics {
+		subs = append(subs, Subscription{
+			EventType: eventMQTT,
+			Command:   map[string]any{"type": "mqtt/subscribe", "topic": topic},
+			decode:    decodeMQTT,
+		})
+	}
+	return subs
+}
+
+// decodeMQTT reads a message of an mqtt/subscribe stream.
+func decodeMQTT(raw []byte) (Event, error) {
+	var body struct {
+		Event *MQTTMessage `json:"event"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return Event{Raw: raw}, fmt.Errorf("%w: mqtt: %v", ErrMalformedEvent, err)
+	}
+	if body.Event == nil || body.Event.Topic == "" {
+		return Event{Raw: raw}, fmt.Errorf("%w: mqtt message without a topic", ErrMalformedEvent)
+	}
+	return Event{Type: eventMQTT, MQTT: body.Event, Raw: raw}, nil
+}
+
+func (t MQTTTrigger) Matches(ev Event) bool {
+	if ev.Type != eventMQTT || ev.MQTT == nil {
+		return false
+	}
+	if len(t.payloads) > 0 && !slices.Contains(t.payloads, ev.MQTT.Payload) {
+		return false
+	}
+	return slices.ContainsFunc(t.topics, func(filter string) bool {
+		return topicMatches(filter, ev.MQTT.Topic)
+	})
+}
+
+// topicMatches reports whether an MQTT topic filter matches a topic.
+func topicMatches(filter, topic string) bool {
+	want, got := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, level := range want {
+		if level == "#" {
+			return true
+		}
+		if i >= len(got) {
+			return false
+		}
+		if level != "+" && level != got[i] {
+			return false
+		}
+	}
+	return len(want) == len(got)
+}
+
+func (t MQTTTrigger) validate() error {
+	if len(t.topics) == 0 {
+		return fmt.Errorf("%w: MQTT needs at least one topic", ErrInvalidArgs)
+	}
+	var errs []error
+	for _, topic := range t.topics {
+		errs = append(errs, validateTopic(topic))
+	}
+	return errors.Join(errs...)
+}
+
+// validateTopic checks a filter the way the broker would: a wildcard takes up
+// a whole level, and # only the last.
+func validateTopic(filter string) error {
+	if filter == "" {
+		return fmt.Errorf("%w: an MQTT topic cannot be empty", ErrInvalidArgs)
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		switch {
+		case level == "#" && i != len(levels)-1:
+			return fmt.Errorf("%w: MQTT topic %q has # before its last level", ErrInvalidArgs, filter)
+		case level != "#" && level != "+" && strings.ContainsAny(level, "#+"):
+			return fmt.Errorf("%w: MQTT topic %q has a wildcard sharing a level", ErrInvalidArgs, filter)
+		}
+	}
+	return nil
+}
+
+func (t MQTTTrigger) String() string {
+	s := "mqtt " + strings.Join(t.topics, ", ")
+	if len(t.payloads) > 0 {
+		s += " with payload " + strings.Join(t.payloads, " or ")
+	}
+	return s
+}
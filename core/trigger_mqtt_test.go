@@ -0,0 +1,45 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mqttMessage(topic, payload string) Event {
+	return Event{Type: eventMQTT, MQTT: &MQTTMessage{Topic: topic, Payload: payload}}
+}
+
+func TestMQTTMatchesWildcards(t *testing.T) {
+	actions := MQTT("zigbee2mqtt/+/action")
+	assert.True(t, actions.Matches(mqttMessage("zigbee2mqtt/hall_button/action", "single")))
+	assert.False(t, actions.Matches(mqttMessage("zigbee2mqtt/hall_button", "{}")))
+	assert.False(t, actions.Matches(mqttMessage("zigbee2mqtt/a/b/action", "single")))
+
+	all := MQTT("frigate/#")
+	assert.True(t, all.Matches(mqttMessage("frigate/events", "{}")))
+	assert.True(t, all.Matches(mqttMessage("frigate/front/person/snapshot", "")))
+	assert.False(t, all.Matches(mqttMessage("zigbee2mqtt/frigate", "")))
+
+	assert.False(t, actions.Matches(stateChange("light.kitchen", "off", "on")))
+}
+
+func TestMQTTNarrowsByPayload(t *testing.T) {
+	presses := MQTT("zigbee2mqtt/+/action").Payload("single", "double")
+	assert.True(t, presses.Matches(mqttMessage("zigbee2mqtt/hall_button/action", "double")))
+	assert.False(t, presses.Matches(mqttMessage("zigbee2mqtt/hall_button/action", "hold")))
+}
+
+func TestMQTTRefusesABadTopic(t *testing.T) {
+	assert.NoError(t, MQTT("a/+/b", "a/#", "#").validate())
+	assert.ErrorIs(t, MQTT().validate(), ErrInvalidArgs)
+	assert.ErrorIs(t, MQTT("a/#/b").validate(), ErrInvalidArgs)
+	assert.ErrorIs(t, MQTT("a/b+").validate(), ErrInvalidArgs)
+	assert.ErrorIs(t, MQTT("").validate(), ErrInvalidArgs)
+}
+
+func TestMQTTSubscribesPerTopic(t *testing.T) {
+	subs := MQTT("a/+", "b/#").Subscriptions()
+	assert.Len(t, subs, 2)
+	assert.Equal(t, map[string]any{"type": "mqtt/subscribe", "topic": "b/#"}, subs[1].Command)
+}
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// eventRemote is the Event type of a firing of a RemoteTrigger.
+const eventRemote = "trigger"
+
+// remoteOptions lists the options each platform RemoteTrigger builds takes,
+// so one set for another platform is refused before Home Assistant sees it.
+var remoteOptions = map[string][]string{
+	"state":         {"entity_id", "attribute", "from", "to", "for"},
+	"numeric_state": {"entity_id", "attribute", "above", "below", "for"},
+	"time_pattern":  {"hours", "minutes", "seconds"},
+}
+
+// RemoteTrigger is a trigger Home Assistant evaluates itself, with its own
+// trigger engine, and reports over subscribe_trigger. Build one with
+// RemoteState, RemoteNumericState, RemoteTimePattern or Remote.
+//
+// Where a local trigger waits out a For duration in this process, and loses
+// the wait on a restart, a remote one is timed by Home Assistant. What it
+// reports reaches the action as run.Event.Variables, the trigger variables
+// Home Assistant's own automations see as trigger.*. A state or numeric state
+// trigger also fills in run.Event's EntityID, From and To.
+type RemoteTrigger struct {
+	config map[string]any
+
+	// custom marks one written by hand with Remote, which is Home
+	// Assistant's to judge.
+	custom bool
+}
+
+// RemoteState fires when the entities change state, as Home Assistant's state
+// trigger does.
+func RemoteState(entityIDs ...string) RemoteTrigger {
+	return RemoteTrigger{config: map[string]any{"platform": "state", "entity_id": entityIDs}}
+}
+
+// RemoteNumericState fires when the entities' values cross into the range
+// Above and Below mark out, as Home Assistant's numeric_state trigger does.
+func RemoteNumericState(entityIDs ...string) RemoteTrigger {
+	return RemoteTrigger{config: map[string]any{"platform": "numeric_state", "entity_id": entityIDs}}
+}
+
+// RemoteTimePattern fires when the clock matches the pattern Hours, Minutes
+// and Seconds set, as Home Assistant's time_pattern trigger does.
+func RemoteTimePattern() RemoteTrigger {
+	return RemoteTrigger{config: map[string]any{"platform": "time_pattern"}}
+}
+
+// Remote subscribes to any trigger Home Assistant knows, written as its
+// automations write one, for platforms the builders here do not cover:
+//
+//	Remote(map[string]any{"platform": "zone", "entity_id": "person.ann", "zone": "zone.home", "event": "enter"})
+func Remote(config map[string]any) RemoteTrigger {
+	return RemoteTrigger{config: maps.Clone(config), custom: true}
+}
+
+func (t RemoteTrigger) with(key string, value any) RemoteTrigger {
+	t.config = maps.Clone(t.config)
+	t.config[key] = value
+	return t
+}
+
+// From fires only on changes away from one of the given states.
+func (t RemoteTrigger) From(states ...string) RemoteTrigger {
+	return t.with("from", oneOrMany(states))
+}
+
+// To fires only on changes to one of the given states.
+func (t RemoteTrigger) To(states ...string) RemoteTrigger {
+	return t.with("to", oneOrMany(states))
+}
+
+// Attribute watches an attribute in place of the state.
+func (t RemoteTrigger) Attribute(name string) RemoteTrigger {
+	return t.with("attribute", name)
+}
+
+// For fires only once the change has held for d.
+func (t RemoteTrigger) For(d time.Duration) RemoteTrigger {
+	return t.with("for", map[string]any{"seconds": d.Seconds()})
+}
+
+// Above fires when the value rises above n.
+func (t RemoteTrigger) Above(n float64) RemoteTrigger {
+	return t.with("above", n)
+}
+
+// Below fires when the value falls below n.
+func (t RemoteTrigger) Below(n float64) RemoteTrigger {
+	return t.with("below", n)
+}
+
+// Hours matches the hour, as "6", "/2" for every second hour, or "*".
+func (t RemoteTrigger) Hours(pattern string) RemoteTrigger {
+	return t.with("hours", pattern)
+}
+
+// Minutes matches the minute, as "30", "/5" for every fifth minute, or "*".
+func (t RemoteTrigger) Minutes(pattern string) RemoteTrigger {
+	return t.with("minutes", pattern)
+}
+
+// Seconds matches the second, as "0", "/10" for every tenth second, or "*".
+func (t RemoteTrigger) Seconds(pattern string) RemoteTrigger {
+	return t.with("seconds", pattern)
+}
+
+// oneOrMany is a single state as itself and several as a list, as Home
+// Assistant writes them.
+func oneOrMany(states []string) any {
+	if len(states) == 1 {
+		return states[0]
+	}
+	return slices.Clone(states)
+}
+
+func (t RemoteTrigger) trigger() {}
+
+func (t RemoteTrigger) platform() string {
+	p, _ := t.config["platform"].(string)
+	return p
+}
+
+// Subscriptions asks Home Assistant to run the trigger, each firing of which
+// arrives over the websocket.
+func (t RemoteTrigger) Subscriptions() []Subscription {
+	return []Subscription{{
+		EventType: eventRemote,
+		Command:   map[string]any{"type": "subscribe_trigger", "trigger": t.config},
+		decode:    decodeRemote,
+	}}
+}
+
+// decodeRemote reads a firing of a subscribed trigger.
+func decodeRemote(raw []byte) (Event, error) {
+	var body struct {
+		Event struct {
+			Variables struct {
+				Trigger json.RawMessage `json:"trigger"`
+			} `json:"variables"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return Event{Raw: raw}, fmt.Errorf("%w: trigger: %v", ErrMalformedEvent, err)
+	}
+	var vars map[string]any
+	if err := json.Unmarshal(body.Event.Variables.Trigger, &vars); err != nil || vars == nil {
+		return Event{Raw: raw}, fmt.Errorf("%w: trigger without its variables", ErrMalformedEvent)
+	}
+
+	ev := Event{Type: eventRemote, Variables: vars, Raw: raw}
+	ev.EntityID, _ = vars["entity_id"].(string)
+	// Only the state-like platforms carry states. The rest leave them zero.
+	var states struct {
+		From *EntityState `json:"from_state"`
+		To   *EntityState `json:"to_state"`
+	}
+	if err := json.Unmarshal(body.Event.Variables.Trigger, &states); err != nil {
+		return Event{Raw: raw}, fmt.Errorf("%w: trigger states: %v", ErrMalformedEvent, err)
+	}
+	if states.From != nil {
+		ev.From = *states.From
+	}
+	if states.To != nil {
+		ev.To = *states.To
+	}
+	return ev, nil
+}
+
+func (t RemoteTrigger) Matches(ev Event) bool {
+	return ev.Type == eventRemote && ev.Variables != nil
+}
+
+func (t RemoteTrigger) validate() error {
+	platform := t.platform()
+	if platform == "" {
+		return fmt.Errorf("%w: a remote trigger needs a platform", ErrInvalidArgs)
+	}
+	allowed, built := remoteOptions[platform]
+	if t.custom || !built {
+		return nil
+	}
+	for key := range t.config {
+		if key != "platform" && !slices.Contains(allowed, key) {
+			return fmt.Errorf("%w: a remote %s trigger takes no %s", ErrInvalidArgs, platform, key)
+		}
+	}
+	if ids, ok := t.config["entity_id"].([]string); ok {
+		if len(ids) == 0 {
+			return fmt.Errorf("%w: a remote %s trigger needs an entity", ErrInvalidArgs, platform)
+		}
+		for _, id := range ids {
+			if err := validateEntityID(id); err != nil {
+				return err
+			}
+		}
+	}
+	if platform == "numeric_state" && t.config["above"] == nil && t.config["below"] == nil {
+		return fmt.Errorf("%w: a remote numeric_state trigger needs Above or Below", ErrInvalidArgs)
+	}
+	return nil
+}
+
+func (t RemoteTrigger) String() string {
+	rest := maps.Clone(t.config)
+	delete(rest, "platform")
+	keys := slices.Sorted(maps.Keys(rest))
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, rest[k]))
+	}
+	return strings.TrimSpace("remote " + t.platform() + " " + strings.Join(parts, " "))
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteStateBuildsHomeAssistantsConfig(t *testing.T) {
+	trig := RemoteState("binary_sensor.front_door").To("on").For(5 * time.Minute)
+	subs := trig.Subscriptions()
+	require.Len(t, subs, 1)
+	assert.Equal(t, map[string]any{"type": "subscribe_trigger", "trigger": map[string]any{
+		"platform": "state", "entity_id": []string{"binary_sensor.front_door"},
+		"to": "on", "for": map[string]any{"seconds": 300.0},
+	}}, subs[0].Command)
+	assert.NoError(t, trig.validate())
+
+	both := RemoteState("light.a").From("on", "unavailable")
+	assert.Equal(t, []string{"on", "unavailable"}, both.config["from"])
+	assert.NotContains(t, trig.config, "from", "narrowing one copy leaves the other alone")
+}
+
+func TestRemoteTriggerRefusesAnOptionOfAnotherPlatform(t *testing.T) {
+	assert.ErrorIs(t, RemoteTimePattern().Minutes("/5").For(time.Minute).validate(), ErrInvalidArgs)
+	assert.ErrorIs(t, RemoteState("light.a").Above(3).validate(), ErrInvalidArgs)
+	assert.ErrorIs(t, RemoteNumericState("sensor.co2").validate(), ErrInvalidArgs)
+	assert.ErrorIs(t, RemoteState().validate(), ErrInvalidArgs)
+	assert.ErrorIs(t, Remote(map[string]any{"entity_id": "x"}).validate(), ErrInvalidArgs)
+
+	assert.NoError(t, RemoteNumericState("sensor.co2").Above(1000).validate())
+	assert.NoError(t, Remote(map[string]any{"platform": "state", "entity_id": "light.a", "not_to": "unavailable"}).validate())
+}
+
+func TestRemoteTriggerDeliversItsVariables(t *testing.T) {
+	ev, err := decodeRemote([]byte(`{"id":7,"type":"event","event":{"variables":{"trigger":{
+		"id":"0","idx":"0","platform":"state","entity_id":"binary_sensor.front_door",
+		"from_state":{"entity_id":"binary_sensor.front_door","state":"off","attributes":{}},
+		"to_state":{"entity_id":"binary_sensor.front_door","state":"on","attributes":{}},
+		"for":{"__type":"<class 'datetime.timedelta'>","total_seconds":300.0},
+		"description":"state of binary_sensor.front_door"}},"context":null}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "binary_sensor.front_door", ev.EntityID)
+	assert.Equal(t, "off", ev.From.State)
+	assert.Equal(t, "on", ev.To.State)
+	assert.Equal(t, "state", ev.Variables["platform"])
+	assert.True(t, RemoteState("binary_sensor.front_door").Matches(ev))
+
+	pattern, err := decodeRemote([]byte(`{"event":{"variables":{"trigger":{"platform":"time_pattern","now":"2026-10-17T10:05:00+00:00"}}}}`))
+	require.NoError(t, err)
+	assert.Empty(t, pattern.EntityID)
+	assert.Equal(t, "2026-10-17T10:05:00+00:00", pattern.Variables["now"])
+
+	_, err = decodeRemote([]byte(`{"event":{"variables":{}}}`))
+	assert.ErrorIs(t, err, ErrMalformedEvent)
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -82,3 +83,75 @@ func TestEveryReportsAnInvalidInterval(t *testing.T) {
 	v := trig.(interface{ validate() error })
 	assert.Error(t, v.validate())
 }
+
+func TestDailyOnWeekends(t *testing.T) {
+	trig := Daily(TimeOfDay(9, 0)).OnWeekends()
+
+	// 2026-07-20 is a Monday.
+	next, ok := trig.NextTime(time.Date(2026, 7, 20, 12, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.Equal(t, time.Saturday, next.Weekday())
+	assert.Equal(t, 25, next.Day())
+	assert.Equal(t, "at 09:00 on Sat, Sun", fmt.Sprint(trig))
+}
+
+func TestDailyOnWorkdaysPassesOverHolidays(t *testing.T) {
+	trig := Daily(TimeOfDay(7, 0)).OnWorkdays(HolidaysIn("GB"))
+
+	// 2026-04-02 is the Thursday before Easter: Good Friday, the weekend and
+	// Easter Monday follow.
+	next, ok := trig.NextTime(time.Date(2026, 4, 2, 12, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 4, 7, 7, 0, 0, 0, time.Local), next)
+	assert.Equal(t, "daily at 07:00 on workdays (holidays in GB)", fmt.Sprint(trig))
+
+	off := Daily(TimeOfDay(9, 0)).OnHolidays(HolidaysIn("GB"))
+	next, ok = off.NextTime(time.Date(2026, 4, 1, 12, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 4, 3, 9, 0, 0, 0, time.Local), next, "Good Friday")
+
+	// Narrowed to weekdays as well, only the working ones among them.
+	mondays := Daily(TimeOfDay(7, 0)).OnWorkdays(HolidaysIn("GB")).OnWeekdays(time.Monday)
+	next, ok = mondays.NextTime(time.Date(2026, 4, 2, 12, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 4, 13, 7, 0, 0, 0, time.Local), next)
+}
+
+func TestDailyOnWorkdaysFromASensorIsDecidedOnTheDay(t *testing.T) {
+	trig := Daily(TimeOfDay(7, 0)).OnWorkdays().(dailyTrigger)
+	assert.Equal(t, "daily at 07:00 on workdays (binary_sensor.workday_sensor)", fmt.Sprint(trig))
+
+	// Every day is a candidate until the sensor has its say.
+	next, ok := trig.NextTime(time.Date(2026, 7, 24, 12, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.Equal(t, time.Saturday, next.Weekday())
+
+	// Unread, Monday to Friday are the working days.
+	assert.True(t, trig.skips(next))
+
+	monday := time.Date(2026, 7, 27, 7, 0, 0, 0, time.Local)
+	trig.bind(stateWith(entity(DefaultWorkdaySensor, "off")))
+	assert.True(t, trig.skips(monday), "a holiday, by the sensor")
+	trig.bind(stateWith(entity(DefaultWorkdaySensor, "on")))
+	assert.False(t, trig.skips(monday))
+}
+
+func TestWorkdaysReportBadSources(t *testing.T) {
+	for _, trig := range []DailyTrigger{
+		Daily(TimeOfDay(7, 0)).OnWorkdays(HolidaysIn("XX")),
+		Daily(TimeOfDay(7, 0)).OnHolidays(WorkdaySensor("sensor.workday")),
+	} {
+		v := trig.(interface{ validate() error })
+		assert.ErrorIs(t, v.validate(), ErrInvalidArgs, fmt.Sprint(trig))
+	}
+}
+
+func TestDailyOnNoWeekdaysIsInvalid(t *testing.T) {
+	trig := Daily(TimeOfDay(9, 0)).OnWeekdays()
+
+	v := trig.(interface{ validate() error })
+	assert.ErrorIs(t, v.validate(), ErrInvalidArgs)
+
+	_, fires := trig.NextTime(time.Now())
+	assert.False(t, fires)
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// eventWebhook is the Event type of a request a webhook trigger received.
+const eventWebhook = "webhook"
+
+// webhookMethods are the methods Home Assistant lets a webhook accept.
+var webhookMethods = []string{"GET", "HEAD", "POST", "PUT"}
+
+// WebhookRequest is a request an external system made to a webhook.
+type WebhookRequest struct {
+	// ID is the webhook's id, the last part of its URL.
+	ID string
+
+	// JSON is the body, when it was JSON. Unmarshal it into a type of your
+	// own.
+	JSON json.RawMessage
+
+	// Data is the body, when it was a form.
+	Data map[string]any
+
+	// Query is the URL's query parameters.
+	Query map[string]string
+}
+
+// WebhookTrigger fires on requests to a Home Assistant webhook. Build one with
+// Webhook.
+type WebhookTrigger struct {
+	id      string
+	methods []string
+	remote  bool
+}
+
+// Webhook fires on every request to Home Assistant's /api/webhook/<id>, so a
+// system outside it can run Go code: a doorbell, a CI job, a phone shortcut.
+// The request reaches the action as run.Event.Webhook.
+//
+// The webhook lives in Home Assistant for as long as the app is connected, and
+// needs nothing configured there. Anyone who knows the id can call it, so
+// choose one that is hard to guess. By default it takes POST and PUT from the
+// local network only.
+func Webhook(id string) WebhookTrigger {
+	return WebhookTrigger{id: id}
+}
+
+// Methods replaces the HTTP methods the webhook accepts, among GET, HEAD,
+// POST and PUT.
+func (t WebhookTrigger) Methods(methods ...string) WebhookTrigger {
+	t.methods = slices.Clone(methods)
+	return t
+}
+
+// AllowRemote accepts requests from outside the local network, such as those
+// arriving through Home Assistant Cloud.
+func (t WebhookTrigger) AllowRemote() WebhookTrigger {
+	t.remote = true
+	return t
+}
+
+func (t WebhookTrigger) trigger() {}
+
+func (t WebhookTrigger) carriesPayload() {}
+
+// Subscriptions asks Home Assistant for a webhook trigger of its own, whose
+// firings arrive over the websocket.
+func (t WebhookTrigger) Subscriptions() []Subscription {
+	config := map[string]any{
+		"platform":   "webhook",
+		"webhook_id": t.id,
+		"local_only": !t.remote,
+	}
+	if len(t.methods) > 0 {
+		config["allowed_methods"] = t.methods
+	}
+	return []Subscription{{
+		EventType: eventWebhook,
+		Command:   map[string]any{"type": "subscribe_trigger", "trigger": config},
+		decode:    decodeWebhook,
+	}}
+}
+
+// decodeWebhook reads a firing of a subscribed webhook trigger.
+func decodeWebhook(raw []byte) (Event, error) {
+	var body struct {
+		Event struct {
+			Variables struct {
+				Trigger *struct {
+					WebhookID string            `json:"webhook_id"`
+					JSON      json.RawMessage   `json:"json"`
+					Data      map[string]any    `json:"data"`
+					Query     map[string]string `json:"query"`
+				} `json:"trigger"`
+			} `json:"variables"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return Event{Raw: raw}, fmt.Errorf("%w: webhook: %v", ErrMalformedEvent, err)
+	}
+	trig := body.Event.Variables.Trigger
+	if trig == nil || trig.WebhookID == "" {
+		return Event{Raw: raw}, fmt.Errorf("%w: webhook trigger without a webhook_id", ErrMalformedEvent)
+	}
+	req := &WebhookRequest{ID: trig.WebhookID, Data: trig.Data, Query: trig.Query}
+	// A body that was not JSON is absent, or null.
+	if len(trig.JSON) > 0 && string(trig.JSON) != "null" {
+		req.JSON = trig.JSON
+	}
+	return Event{Type: eventWebhook, Webhook: req, Raw: raw}, nil
+}
+
+func (t WebhookTrigger) Matches(ev Event) bool {
+	return ev.Type == eventWebhook && ev.Webhook != nil && ev.Webhook.ID == t.id
+}
+
+func (t WebhookTrigger) validate() error {
+	if t.id == "" {
+		return fmt.Errorf("%w: a webhook needs an id", ErrInvalidArgs)
+	}
+	for _, m := range t.methods {
+		if !slices.Contains(webhookMethods, m) {
+			return fmt.Errorf("%w: webhook %s cannot accept %s, only %s", ErrInvalidArgs, t.id, m, strings.Join(webhookMethods, ", "))
+		}
+	}
+	return nil
+}
+
+func (t WebhookTrigger) String() string {
+	return "webhook " + t.id
+}
+
+// RegisterWebhook runs handler on every request to the webhook, for when an
+// automation of its own would be ceremony. It registers one named "webhook
+// <id>" that runs requests in parallel, so a slow handler does not turn the
+// next caller away; build one with Webhook to choose otherwise.
+func (app *App) RegisterWebhook(id string, handler func(ctx context.Context, req WebhookRequest) error) error {
+	if handler == nil {
+		return fmt.Errorf("%w: webhook %s has no handler", ErrInvalidArgs, id)
+	}
+	a, err := NewAutomation("webhook " + id).
+		On(Webhook(id)).
+		Mode(ModeParallel).
+		Do(func(ctx context.Context, run Run) error {
+			if run.Event.Webhook == nil {
+				return fmt.Errorf("%w: webhook %s ran without a request", ErrInvalidArgs, id)
+			}
+			return handler(ctx, *run.Event.Webhook)
+		}).
+		Build()
+	if err != nil {
+		return err
+	}
+	return app.RegisterAutomations(a)
+}
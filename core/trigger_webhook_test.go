@@ -0,0 +1,36 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDecodesAFiring(t *testing.T) {
+	ev, err := decodeWebhook([]byte(`{"id":4,"type":"event","event":{"variables":{"trigger":{
+		"platform":"webhook","webhook_id":"doorbell","json":{"button":"front"},
+		"query":{"source":"esp"},"description":"webhook"}},"context":{"id":"x"}}}`))
+	require.NoError(t, err)
+	require.NotNil(t, ev.Webhook)
+	assert.Equal(t, "doorbell", ev.Webhook.ID)
+	assert.JSONEq(t, `{"button":"front"}`, string(ev.Webhook.JSON))
+	assert.Equal(t, "esp", ev.Webhook.Query["source"])
+
+	assert.True(t, Webhook("doorbell").Matches(ev))
+	assert.False(t, Webhook("garage").Matches(ev))
+
+	_, err = decodeWebhook([]byte(`{"event":{"variables":{}}}`))
+	assert.ErrorIs(t, err, ErrMalformedEvent)
+}
+
+func TestWebhookSubscribesATriggerOfItsOwn(t *testing.T) {
+	subs := Webhook("doorbell").Methods("GET").AllowRemote().Subscriptions()
+	require.Len(t, subs, 1)
+	assert.Equal(t, map[string]any{"type": "subscribe_trigger", "trigger": map[string]any{
+		"platform": "webhook", "webhook_id": "doorbell", "local_only": false, "allowed_methods": []string{"GET"},
+	}}, subs[0].Command)
+
+	assert.ErrorIs(t, Webhook("").validate(), ErrInvalidArgs)
+	assert.ErrorIs(t, Webhook("doorbell").Methods("DELETE").validate(), ErrInvalidArgs)
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrWaitTimeout reports a wait that gave up before what it was waiting for
+// happened.
+var ErrWaitTimeout = errors.New("timed out waiting")
+
+// appContextKey carries the app in the context every run is given.
+type appContextKey struct{}
+
+// appFrom recovers the app a run's context belongs to.
+func appFrom(ctx context.Context) (*App, error) {
+	app, ok := ctx.Value(appContextKey{}).(*App)
+	if !ok {
+		return nil, fmt.Errorf("%w: waiting needs the context an automation run was given", ErrInvalidArgs)
+	}
+	return app, nil
+}
+
+// waiter is one blocked WaitForEvent. matched receives the first event that
+// passes the filter; it is buffered so the dispatching worker never blocks on
+// a waiter that has just given up.
+type waiter struct {
+	filter  func(Event) bool
+	matched chan Event
+}
+
+// WaitForEvent blocks a run until an event of the given type passes filter, and
+// returns it. A nil filter takes the first event of the type; a filter runs on
+// the goroutine delivering events, so it should decide quickly. It gives up with
+// ErrWaitTimeout after timeout, or sooner if ctx ends, as it does when a
+// restart-mode automation is superseded.
+//
+// ctx must be the one the run was given, which is how the wait finds the app.
+// Outside a run, use App.WaitForEvent.
+func WaitForEvent(ctx context.Context, eventType string, filter func(Event) bool, timeout time.Duration) (Event, error) {
+	app, err := appFrom(ctx)
+	if err != nil {
+		return Event{}, err
+	}
+	return app.WaitForEvent(ctx, eventType, filter, timeout)
+}
+
+// WaitUntil blocks a run until the entity's state is want, returning at once if
+// it already is. It is what makes "do this, wait for the door to close, then do
+// that" one straight-line action. It gives up with ErrWaitTimeout after timeout.
+//
+// state is read for the entity's current state; ctx must be the one the run
+// was given.
+func WaitUntil(ctx context.Context, state StateReader, entityID, want string, timeout time.Duration) error {
+	app, err := appFrom(ctx)
+	if err != nil {
+		return err
+	}
+	return app.waitUntil(ctx, state, entityID, want, timeout)
+}
+
+// WaitForEvent is the package-level WaitForEvent for code holding the app
+// rather than a run's context.
+func (app *App) WaitForEvent(ctx context.Context, eventType string, filter func(Event) bool, timeout time.Duration) (Event, error) {
+	if eventType == "" {
+		return Event{}, fmt.Errorf("%w: WaitForEvent needs an event type", ErrInvalidArgs)
+	}
+	w, err := app.addWaiter(eventType, filter)
+	if err != nil {
+		return Event{}, err
+	}
+	defer app.removeWaiter(eventType, w)
+
+	return w.wait(ctx, timeout, eventType)
+}
+
+// WaitUntil is the package-level WaitUntil for code holding the app rather
+// than a run's context.
+func (app *App) WaitUntil(ctx context.Context, entityID, want string, timeout time.Duration) error {
+	return app.waitUntil(ctx, app.state, entityID, want, timeout)
+}
+
+func (app *App) waitUntil(ctx context.Context, state StateReader, entityID, want string, timeout time.Duration) error {
+	if entityID == "" {
+		return fmt.Errorf("%w: WaitUntil needs an entity", ErrInvalidArgs)
+	}
+
+	// Listening before looking, so a change landing between the two is caught
+	// by one or the other rather than slipping past both.
+	w, err := app.addWaiter(eventStateChanged, func(ev Event) bool {
+		return ev.EntityID == entityID && !ev.Deleted && ev.To.State == want
+	})
+	if err != nil {
+		return err
+	}
+	defer app.removeWaiter(eventStateChanged, w)
+
+	if current, err := state.Get(entityID); err == nil && current.State == want {
+		return nil
+	}
+	_, err = w.wait(ctx, timeout, entityID+" to be "+want)
+	return err
+}
+
+func (w *waiter) wait(ctx context.Context, timeout time.Duration, what string) (Event, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case ev := <-w.matched:
+		return ev, nil
+	case <-timer.C:
+		return Event{}, fmt.Errorf("%w for %s after %s", ErrWaitTimeout, what, timeout)
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+func (app *App) addWaiter(eventType string, filter func(Event) bool) (*waiter, error) {
+	w := &waiter{filter: filter, matched: make(chan Event, 1)}
+
+	app.registryMu.Lock()
+	if app.waiters[eventType] == nil {
+		app.waiters[eventType] = map[*waiter]struct{}{}
+	}
+	app.waiters[eventType][w] = struct{}{}
+	app.registryMu.Unlock()
+
+	if err := app.subscribeTypes([]string{eventType}); err != nil {
+		app.removeWaiter(eventType, w)
+		return nil, err
+	}
+	return w, nil
+}
+
+func (app *App) removeWaiter(eventType string, w *waiter) {
+	app.registryMu.Lock()
+	defer app.registryMu.Unlock()
+
+	delete(app.waiters[eventType], w)
+	if len(app.waiters[eventType]) == 0 {
+		delete(app.waiters, eventType)
+	}
+}
+
+// wakeWaiters hands the event to every waiter it satisfies. Each is woken
+// once; a waiter already holding an event ignores later ones.
+func (app *App) wakeWaiters(ev Event) {
+	app.registryMu.RLock()
+	waiters := make([]*waiter, 0, len(app.waiters[ev.Type]))
+	for w := range app.waiters[ev.Type] {
+		waiters = append(waiters, w)
+	}
+	app.registryMu.RUnlock()
+
+	for _, w := range waiters {
+		if w.filter != nil && !w.filter(ev) {
+			continue
+		}
+		select {
+		case w.matched <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Xevion/go-ha/internal/holidays"
+)
+
+// DefaultWorkdaySensor is the entity Home Assistant's workday integration
+// names its first sensor, which OnWorkdays and OnHolidays read when given no
+// Workdays.
+const DefaultWorkdaySensor = "binary_sensor.workday_sensor"
+
+// Workdays tells working days from days off, for a DailyTrigger's OnWorkdays
+// and OnHolidays. Build one with WorkdaySensor or HolidaysIn.
+type Workdays struct {
+	label   string
+	sensor  string
+	country string
+	err     error
+}
+
+// WorkdaySensor reads a binary sensor of Home Assistant's workday integration,
+// which is on on working days. It says only whether today is one, so a trigger
+// narrowed by it is still scheduled every day and, when it falls due, passes
+// over a day the sensor says otherwise. A sensor that cannot be read leaves
+// Monday to Friday as the working days.
+func WorkdaySensor(entityID string) Workdays {
+	w := Workdays{label: entityID, sensor: entityID}
+	if !strings.HasPrefix(entityID, "binary_sensor.") {
+		w.err = fmt.Errorf("%w: %q is not a binary_sensor", ErrInvalidArgs, entityID)
+	}
+	return w
+}
+
+// HolidaysIn counts Monday to Friday as working days, less the public holidays
+// kept nationwide in the country, named by its ISO 3166 code: DE, FR, GB
+// (England and Wales), NL or US. Its days off are known ahead, so a trigger
+// narrowed by it is never woken on one.
+func HolidaysIn(country string) Workdays {
+	w := Workdays{label: "holidays in " + strings.ToUpper(country), country: country}
+	if !holidays.Known(country) {
+		w.err = fmt.Errorf("%w: no holidays known for %q, only %s",
+			ErrInvalidArgs, country, strings.Join(holidays.Countries(), ", "))
+	}
+	return w
+}
+
+func (w Workdays) String() string { return w.label }
+
+// workdayFilter narrows a trigger to working days, or with off to the days
+// off between them.
+type workdayFilter struct {
+	workdays Workdays
+	off      bool
+
+	// state is bound at registration, for reading the sensor. A trigger is
+	// declared before an App exists, so it has nothing to read until it joins
+	// one.
+	state StateReader
+}
+
+func newWorkdayFilter(off bool, workdays []Workdays) *workdayFilter {
+	w := WorkdaySensor(DefaultWorkdaySensor)
+	if len(workdays) > 0 {
+		w = workdays[0]
+	}
+	return &workdayFilter{workdays: w, off: off}
+}
+
+// ahead reports whether the days picked are known in advance, and so can be
+// passed over when the next time is worked out.
+func (f *workdayFilter) ahead() bool { return f.workdays.country != "" }
+
+// picks reports whether the trigger fires on the day t falls on. For a sensor
+// that is only known on the day itself.
+func (f *workdayFilter) picks(t time.Time) bool {
+	worked := t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+	switch {
+	case f.ahead():
+		worked = worked && !holidays.Is(f.workdays.country, t)
+	case f.state != nil:
+		if s, err := f.state.Get(f.workdays.sensor); err == nil && (s.State == "on" || s.State == "off") {
+			worked = s.State == "on"
+		}
+	}
+	return worked != f.off
+}
+
+func (f *workdayFilter) String() string {
+	if f.off {
+		return "on days off (" + f.workdays.label + ")"
+	}
+	return "on workdays (" + f.workdays.label + ")"
+}
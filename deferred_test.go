@@ -0,0 +1,88 @@
+package ha_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestAtMakesTheCallWhenItsTimeComes(t *testing.T) {
+	server := hatest.New(t)
+	clock := hatest.NewClock(time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC))
+	app := newAppWithClock(t, server, clock)
+	start(t, app)
+
+	tonight := time.Date(2026, 10, 17, 22, 0, 0, 0, time.UTC)
+	require.NoError(t, app.Services().At(tonight).Light.TurnOff("light.porch"))
+	require.True(t, clock.WaitForSleepers(3))
+
+	clock.Advance(3*time.Hour + 59*time.Minute)
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, server.Calls(), "a minute early")
+
+	clock.Advance(time.Minute)
+	server.AssertServiceCalled("light", "turn_off", "light.porch")
+
+	// Made once: it does not come round again tomorrow.
+	clock.Advance(24 * time.Hour)
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, server.Calls(), 1)
+}
+
+func TestDeferredCallsSurviveARestart(t *testing.T) {
+	server := hatest.New(t)
+	clock := hatest.NewClock(time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC))
+	file := filepath.Join(t.TempDir(), "deferred.json")
+	newApp := func() *ha.App {
+		app, err := ha.NewApp(types.NewAppRequest{
+			URL:               server.URL(),
+			HAAuthToken:       hatest.Token,
+			Clock:             clock,
+			DeferredCallsFile: file,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = app.Close() })
+		return app
+	}
+
+	first := newApp()
+	start(t, first)
+	tonight := time.Date(2026, 10, 17, 22, 0, 0, 0, time.UTC)
+	require.NoError(t, first.Services().Targeting(services.InArea("porch")).At(tonight).Light.TurnOff(""))
+	require.NoError(t, first.Close())
+
+	// The app was down when the call came due, so it is made as soon as the
+	// next one starts.
+	clock.Advance(5 * time.Hour)
+	start(t, newApp())
+	call := server.WaitForCalls(1)[0]
+	assert.Equal(t, "turn_off", call.Service)
+	assert.Equal(t, []string{"porch"}, call.AreaIDs)
+
+	// And only once.
+	start(t, newApp())
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, server.Calls(), 1)
+}
+
+func TestAtIsRefusedByAReadOnlyApp(t *testing.T) {
+	server := hatest.New(t)
+	app, err := ha.NewApp(types.NewAppRequest{
+		URL:         server.URL(),
+		HAAuthToken: hatest.Token,
+		ReadOnly:    true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = app.Close() })
+
+	err = app.Services().At(time.Now().Add(time.Hour)).Light.TurnOff("light.porch")
+	assert.ErrorIs(t, err, ha.ErrReadOnly)
+}
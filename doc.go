@@ -31,7 +31,9 @@
 // Sun times come from Home Assistant's own sun.sun entity. It runs astral
 // against the observer's latitude, longitude and elevation with a configurable
 // solar depression, so computing them locally would disagree with the times on
-// the user's own dashboard.
+// the user's own dashboard. A trigger moved elsewhere with
+// [SunTrigger.AtLocation] or [SunTrigger.AtZone] has no such entity to read, so
+// its times are computed from the coordinates instead.
 //
 // # Conditions
 //
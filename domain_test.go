@@ -0,0 +1,30 @@
+package ha_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestGoodnightTurnsOffEveryLightButTheLanding(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("light.porch", "on")
+	server.SetState("light.hall", "on")
+	server.SetState("light.landing", "on")
+	server.SetState("switch.fridge", "on")
+	app := newApp(t, server)
+	start(t, app)
+
+	lights, err := ha.EntitiesInDomain(app.State(), "light")
+	require.NoError(t, err)
+	require.Len(t, lights, 3)
+	assert.Equal(t, "light.hall", lights[0].EntityID, "sorted by id")
+
+	require.NoError(t, app.Services().Light.TurnOffAll("light.landing"))
+	calls := server.WaitForCalls(1)
+	assert.Equal(t, "light.hall,light.porch", calls[0].EntityID)
+}
@@ -0,0 +1,111 @@
+package ha_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestDryRunSkipsCallsAndCarriesOn(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+
+	app, err := ha.NewApp(types.NewAppRequest{
+		URL:         server.URL(),
+		HAAuthToken: hatest.Token,
+		DryRun:      true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = app.Close() })
+
+	ran := make(chan error, 1)
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("hall light").
+			On(ha.StateChanged("binary_sensor.hall_motion").To("on")).
+			Do(func(_ context.Context, run ha.Run) error {
+				if err := run.Services.Light.TurnOn("light.hall", map[string]any{"brightness": 40}); err != nil {
+					ran <- err
+					return err
+				}
+				// Reached only because the first call reported success.
+				err := run.Services.Event.Fire("hall_lit")
+				ran <- err
+				return err
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	select {
+	case err := <-ran:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the automation never ran")
+	}
+	require.NoError(t, app.SetState("sensor.virtual", "1", nil))
+
+	calls := app.DryRunCalls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, "light", calls[0].Domain)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Contains(t, calls[0].Target, "light.hall")
+	assert.Equal(t, 40, calls[0].Data["brightness"])
+	assert.Equal(t, "hall_lit", calls[1].EventType)
+	assert.Equal(t, "sensor.virtual", calls[2].Target)
+	assert.Equal(t, "1", calls[2].State)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+	_, _, ok := server.State("sensor.virtual")
+	assert.False(t, ok)
+}
+
+func TestDryRunSkipsCommandsThatWrite(t *testing.T) {
+	server := hatest.New(t)
+	server.HandleCommand("config/automation/list", func(map[string]any) (any, error) {
+		return []any{}, nil
+	})
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.DryRun = true })
+
+	_, err := app.Command(map[string]any{
+		"type":         "call_service",
+		"domain":       "light",
+		"service":      "turn_on",
+		"target":       map[string]any{"entity_id": []any{"light.hall", "light.porch"}},
+		"service_data": map[string]any{"brightness": 40},
+	})
+	require.NoError(t, err)
+	_, err = app.Command(map[string]any{"type": "calendar/event/delete", "entity_id": "calendar.go_ha", "uid": "1"})
+	require.NoError(t, err)
+	_, err = app.Command(map[string]any{"type": "config/automation/list"})
+	require.NoError(t, err, "reads pass")
+
+	calls := app.DryRunCalls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call_service", calls[0].Command)
+	assert.Equal(t, "light", calls[0].Domain)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Equal(t, "light.hall, light.porch", calls[0].Target)
+	assert.EqualValues(t, 40, calls[0].Data["brightness"])
+	assert.Equal(t, "calendar/event/delete", calls[1].Command)
+	assert.Equal(t, "1", calls[1].Data["uid"])
+	assert.Empty(t, server.Calls())
+}
+
+func TestDryRunAndReadOnlyAreExclusive(t *testing.T) {
+	_, err := ha.NewApp(types.NewAppRequest{
+		URL:         "http://localhost:8123",
+		HAAuthToken: hatest.Token,
+		DryRun:      true,
+		ReadOnly:    true,
+	})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
@@ -0,0 +1,45 @@
+package ha_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestStreamEntitiesKeepsTheStateCache(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("light.kitchen", "on", map[string]any{"brightness": 200, "color_mode": "hs"})
+	server.SetState("sensor.power", "12")
+
+	app, err := ha.NewApp(types.NewAppRequest{
+		URL:            server.URL(),
+		HAAuthToken:    hatest.Token,
+		StreamEntities: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = app.Close() })
+	start(t, app)
+
+	kitchen, err := app.State().Get("light.kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, float64(200), kitchen.Attributes["brightness"])
+
+	server.ChangeState("light.kitchen", "off", map[string]any{"brightness": 0})
+	server.RemoveState("sensor.power")
+	assert.Eventually(t, func() bool {
+		kitchen, err := app.State().Get("light.kitchen")
+		_, gone := kitchen.Attributes["color_mode"]
+		return err == nil && kitchen.State == "off" && !gone
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		_, err := app.State().Get("sensor.power")
+		return err != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Zero(t, app.ConnectionStats().Undecodable)
+}
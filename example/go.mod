@@ -7,9 +7,7 @@ toolchain go1.26.1
 require github.com/Xevion/go-ha v0.9.0
 
 require (
-	github.com/Workiva/go-datastructures v1.1.5 // indirect
 	github.com/coder/websocket v1.8.14 // indirect
-	github.com/dromara/carbon/v2 v2.6.16 // indirect
 	github.com/robfig/cron/v3 v3.0.0 // indirect
 	golang.org/x/net v0.42.0 // indirect
 	resty.dev/v3 v3.0.0-beta.3 // indirect
@@ -97,7 +97,7 @@ func ExampleNewAutomation() {
 	}
 
 	fmt.Println(automation)
-	// Output: hallway light (2 trigger(s), restart)
+	// Output: hallway light: state change on binary_sensor.hall to on or sunset (restart)
 }
 
 // ExampleStateChanged narrows a trigger to one transition, held for a duration:
@@ -114,7 +114,28 @@ func ExampleStateChanged() {
 		MustBuild()
 
 	fmt.Println(automation)
-	// Output: door left open (1 trigger(s), single)
+	// Output: door left open: state change on binary_sensor.front_door from off to on for 2m (single)
+}
+
+// ExampleCron schedules on a cron expression, here nine o'clock on weekdays.
+// Cron is an ordinary trigger, so it registers and composes like any other.
+func ExampleCron() {
+	weekdayMornings := ha.Cron("0 9 * * 1-5")
+
+	automation := ha.NewAutomation("open the office blinds").
+		On(weekdayMornings).
+		Do(func(ctx context.Context, run ha.Run) error {
+			return run.Services.Cover.Open("cover.office")
+		}).
+		MustBuild()
+
+	// 2026-07-18 is a Saturday, so the next run is Monday's.
+	next, _ := weekdayMornings.NextTime(time.Date(2026, 7, 18, 12, 0, 0, 0, time.Local))
+	fmt.Println(automation)
+	fmt.Println(next.Weekday(), next.Format("15:04"))
+	// Output:
+	// open the office blinds: cron(0 9 * * 1-5) (single)
+	// Monday 09:00
 }
 
 // ExampleAll composes conditions and evaluates the result on its own, against a
@@ -0,0 +1,121 @@
+package ha_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+// queue stands in for NATS or Redis: it carries tasks as JSON, and does no
+// work itself.
+type queue chan []byte
+
+func (q queue) Execute(_ context.Context, task ha.Task) error {
+	msg, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	q <- msg
+	return nil
+}
+
+// doorbell is the automation both sides register: the listener to fire it
+// through the queue, and the worker, with no executor of its own, to know what
+// its action is.
+func doorbell(e ha.Executor) ha.Automation {
+	return ha.NewAutomation("doorbell snapshot").
+		On(ha.StateChanged("binary_sensor.doorbell").To("on")).
+		Mode(ha.ModeSingle).
+		ExecuteWith(e).
+		Do(func(_ context.Context, run ha.Run) error {
+			// Heavy: encoding a snapshot, say.
+			time.Sleep(300 * time.Millisecond)
+			return run.Services.Light.TurnOn("light.porch")
+		}).
+		MustBuild()
+}
+
+func TestExecutorCarriesHeavyActionsToAWorker(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.doorbell", "off")
+	q := make(queue, 10)
+
+	listener := newApp(t, server)
+	require.NoError(t, listener.RegisterAutomations(doorbell(q)))
+	start(t, listener)
+
+	worker := newApp(t, server)
+	require.NoError(t, worker.RegisterTaskHandlers(doorbell(nil)))
+	start(t, worker)
+
+	// Under ModeSingle a second ring while the first ran would be dropped.
+	// Enqueueing is quick, so one well inside the action's time is admitted.
+	server.ChangeState("binary_sensor.doorbell", "on")
+	time.Sleep(50 * time.Millisecond)
+	server.ChangeState("binary_sensor.doorbell", "off")
+	server.ChangeState("binary_sensor.doorbell", "on")
+	require.Eventually(t, func() bool { return len(q) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, server.Calls(), "nothing ran in the listener")
+
+	for range 2 {
+		var task ha.Task
+		require.NoError(t, json.Unmarshal(<-q, &task))
+		assert.Equal(t, "doorbell snapshot", task.Automation)
+		assert.Equal(t, "on", task.Event.To.State)
+		require.NoError(t, worker.ExecuteTask(t.Context(), task))
+	}
+	assert.Len(t, server.WaitForCalls(2), 2)
+
+	err := worker.ExecuteTask(t.Context(), ha.Task{Automation: "nobody"})
+	assert.ErrorIs(t, err, ha.ErrUnknownAutomation)
+
+	var carried ha.Task
+	assert.ErrorIs(t, carried.Run(t.Context()), ha.ErrInvalidArgs, "a task off the wire has no action of its own")
+}
+
+func TestInProcessExecutorRunsTheActionHere(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.doorbell", "off")
+	app := newApp(t, server)
+
+	got := make(chan ha.Task, 1)
+	var unset queue
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("doorbell").
+			On(ha.StateChanged("binary_sensor.doorbell").To("on")).
+			ExecuteWith(executorFunc(func(ctx context.Context, task ha.Task) error {
+				got <- task
+				return ha.InProcess.Execute(ctx, task)
+			})).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn("light.porch")
+			}).
+			MustBuild(),
+		// A queue never made is no executor at all, rather than one that
+		// blocks every run on its nil channel.
+		ha.NewAutomation("doorbell chime").
+			On(ha.StateChanged("binary_sensor.doorbell").To("on")).
+			ExecuteWith(unset).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Switch.TurnOn("switch.chime")
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.ChangeState("binary_sensor.doorbell", "on")
+	server.AssertServiceCalled("light", "turn_on", "light.porch")
+	server.AssertServiceCalled("switch", "turn_on", "switch.chime")
+	assert.Equal(t, "doorbell", (<-got).Automation)
+}
+
+type executorFunc func(ctx context.Context, task ha.Task) error
+
+func (f executorFunc) Execute(ctx context.Context, task ha.Task) error { return f(ctx, task) }
@@ -5,19 +5,17 @@ go 1.25.0
 toolchain go1.26.1
 
 require (
-	github.com/Workiva/go-datastructures v1.1.5
 	github.com/coder/websocket v1.8.14
-	github.com/dromara/carbon/v2 v2.6.16
 	github.com/robfig/cron/v3 v3.0.0
 	github.com/stretchr/testify v1.11.1
+	go.uber.org/goleak v1.3.0
 	gopkg.in/yaml.v3 v3.0.1
 	resty.dev/v3 v3.0.0-beta.3
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/kr/pretty v0.1.0 // indirect
+	github.com/kr/text v0.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/net v0.42.0 // indirect
-	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 )
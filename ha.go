@@ -7,6 +7,7 @@ import (
 	"github.com/Xevion/go-ha/core"
 	"github.com/Xevion/go-ha/internal"
 	"github.com/Xevion/go-ha/internal/connect"
+	"github.com/Xevion/go-ha/services"
 	"github.com/Xevion/go-ha/types"
 )
 
@@ -17,6 +18,18 @@ const Version = internal.Version
 // SunEntityID is the entity Home Assistant publishes solar times on.
 const SunEntityID = core.SunEntityID
 
+// DefaultWorkdaySensor is the workday sensor [DailyTrigger.OnWorkdays] reads
+// when given no [Workdays].
+const DefaultWorkdaySensor = core.DefaultWorkdaySensor
+
+// SupervisorURL is where an add-on reaches Home Assistant, through the
+// Supervisor's proxy.
+const SupervisorURL = core.SupervisorURL
+
+// HomeTimezone, as NewAppRequest.Timezone, reads the clock in the time zone
+// Home Assistant is configured with.
+const HomeTimezone = types.HomeTimezone
+
 // Errors this package returns, so a caller can classify a failure with
 // errors.Is rather than matching on message text.
 var (
@@ -33,9 +46,19 @@ var (
 	// ErrInvalidAutomation reports an automation that cannot be built.
 	ErrInvalidAutomation = core.ErrInvalidAutomation
 
+	// ErrUnknownAutomation reports a name no registered automation has.
+	ErrUnknownAutomation = core.ErrUnknownAutomation
+
+	// ErrAutomationBusy reports a run by hand that the automation's mode or
+	// throttle refused.
+	ErrAutomationBusy = core.ErrAutomationBusy
+
 	// ErrInvalidTimeOfDay reports an hour or minute outside its range.
 	ErrInvalidTimeOfDay = core.ErrInvalidTimeOfDay
 
+	// ErrInvalidLocation reports a latitude or longitude off the globe.
+	ErrInvalidLocation = core.ErrInvalidLocation
+
 	// ErrEntityNotFound reports an entity Home Assistant does not know about.
 	ErrEntityNotFound = internal.ErrEntityNotFound
 
@@ -50,6 +73,34 @@ var (
 
 	// ErrAuthFailed reports a rejected websocket handshake.
 	ErrAuthFailed = connect.ErrAuthFailed
+
+	// ErrCallFailed reports a command or service call Home Assistant refused.
+	ErrCallFailed = connect.ErrCallFailed
+
+	// ErrWaitTimeout reports a WaitUntil or WaitForEvent that gave up.
+	ErrWaitTimeout = core.ErrWaitTimeout
+
+	// ErrReadOnly reports a call refused by an app built ReadOnly.
+	ErrReadOnly = core.ErrReadOnly
+
+	// ErrNoValue reports an unavailable entity, or a missing attribute, read
+	// with GetStateAs, GetAttribute or one of the typed readers like GetLight.
+	ErrNoValue = core.ErrNoValue
+
+	// ErrWrongType reports a state or attribute that cannot be read as the
+	// requested type.
+	ErrWrongType = core.ErrWrongType
+
+	// ErrUnknownEntity reports an automation naming an entity Home Assistant
+	// does not have, refused because the app was built with CheckEntities.
+	ErrUnknownEntity = core.ErrUnknownEntity
+
+	// ErrTemplate reports a watched template that failed to re-render.
+	ErrTemplate = core.ErrTemplate
+
+	// ErrMalformedEvent reports an event that could not be decoded, as
+	// handed to NewAppRequest.OnUndecodable.
+	ErrMalformedEvent = core.ErrMalformedEvent
 )
 
 // Condition reports whether an automation should run.
@@ -102,6 +153,10 @@ type (
 	// ConditionFunc adapts a plain function to [Condition].
 	ConditionFunc = core.ConditionFunc
 
+	// EntityReadError reports a condition that could not read the entity it
+	// depends on, naming it.
+	EntityReadError = core.EntityReadError
+
 	// ConditionErrorPolicy decides what an automation does when a condition
 	// cannot be evaluated.
 	ConditionErrorPolicy = core.ConditionErrorPolicy
@@ -111,8 +166,19 @@ type (
 
 	// Mode mirrors Home Assistant's automation mode.
 	Mode = core.Mode
+
+	// Executor runs the actions of automations built with ExecuteWith, such
+	// as by handing them to an external worker queue.
+	Executor = core.Executor
+
+	// Task is one run of an automation's action, as handed to an Executor.
+	Task = core.Task
 )
 
+// InProcess is the Executor every automation has by default, running the
+// action in the run's own goroutine.
+var InProcess = core.InProcess
+
 // Triggers. The two families are united by [Trigger] so one automation can hold
 // a mixed list, which is what lets it express Home Assistant's real grammar.
 type (
@@ -133,12 +199,67 @@ type (
 	// EventTypeTrigger fires on Home Assistant events by type.
 	EventTypeTrigger = core.EventTypeTrigger
 
+	// MQTTTrigger fires on messages published to MQTT topics. Narrow it with
+	// Payload.
+	MQTTTrigger = core.MQTTTrigger
+
+	// MQTTMessage is a message an MQTT trigger received, as run.Event.MQTT.
+	MQTTMessage = core.MQTTMessage
+
+	// WebhookTrigger fires on requests to a Home Assistant webhook. Narrow
+	// it with Methods, or widen it with AllowRemote.
+	WebhookTrigger = core.WebhookTrigger
+
+	// WebhookRequest is a request a webhook trigger received, as
+	// run.Event.Webhook.
+	WebhookRequest = core.WebhookRequest
+
+	// RemoteTrigger is a trigger Home Assistant's own engine evaluates,
+	// reported over subscribe_trigger. Its trigger variables reach the action
+	// as run.Event.Variables.
+	RemoteTrigger = core.RemoteTrigger
+
+	// WhenAllTrigger fires when every one of its requirements is met at once.
+	WhenAllTrigger = core.WhenAllTrigger
+
+	// Requirement is what one entity has to hold for a WhenAll to fire.
+	// Narrow it with To, From, Above, Below, Attribute and For.
+	Requirement = core.Requirement
+
+	// DailyTrigger fires at a time of day, narrowed to some days of the week
+	// with OnWeekdays or OnWeekends.
+	DailyTrigger = core.DailyTrigger
+
+	// IntervalTrigger fires on a fixed interval, and can be confined to the
+	// dark hours.
+	IntervalTrigger = core.IntervalTrigger
+
+	// StartupTrigger fires once when the app starts, in the place Order
+	// gives it when startup runs are staggered.
+	StartupTrigger = core.StartupTrigger
+
+	// SunTrigger fires at a solar time, the home's unless moved with
+	// AtLocation or AtZone.
+	SunTrigger = core.SunTrigger
+
 	// Subscription declares what an event trigger needs delivered.
 	Subscription = core.Subscription
 
 	// SunEvent names one of the solar times Home Assistant publishes.
 	SunEvent = core.SunEvent
 
+	// SunFallback is what a sun trigger does on a day its event does not
+	// occur, built with [SkipDay] or [UseFixedTime].
+	SunFallback = core.SunFallback
+
+	// DayRule picks days of the month for [SunTrigger.OnlyOn], built with
+	// [FirstWeekday], [LastWeekday], [NthWeekday] or [DayOfMonth].
+	DayRule = core.DayRule
+
+	// Workdays tells working days from days off for
+	// [DailyTrigger.OnWorkdays], built with [WorkdaySensor] or [HolidaysIn].
+	Workdays = core.Workdays
+
 	// ClockTime is a time of day, built with [TimeOfDay].
 	ClockTime = core.ClockTime
 )
@@ -154,11 +275,143 @@ type (
 	// EntityState is one entity's state and attributes.
 	EntityState = core.EntityState
 
+	// LightState, ClimateState, MediaPlayerState, CoverState and
+	// BinarySensorState are an entity's state with its domain's attributes
+	// decoded, as [GetLight] and its siblings read them.
+	LightState        = core.LightState
+	ClimateState      = core.ClimateState
+	MediaPlayerState  = core.MediaPlayerState
+	CoverState        = core.CoverState
+	BinarySensorState = core.BinarySensorState
+
+	// AuthInfo is what [App.VerifyAuth] learned about the access token.
+	AuthInfo = core.AuthInfo
+
+	// Config is Home Assistant's core configuration, as [App.Config] reports
+	// it: the home's location, its time zone, units and version.
+	Config = core.Config
+
+	// UnitSystem is the unit Home Assistant reports each kind of measurement
+	// in.
+	UnitSystem = core.UnitSystem
+
+	// Target widens service calls to areas, devices or several entities,
+	// through [Service.Targeting].
+	Target = services.Target
+
+	// SubApp is a named group of automations that can be stopped and
+	// started apart from the rest of the app, made with App.SubApp.
+	SubApp = core.SubApp
+
+	// HistoricalState is one state an entity held, as App.History returns.
+	HistoricalState = core.HistoricalState
+
+	// Statistic is one period of an entity's long-term statistics, as
+	// App.Statistics returns.
+	Statistic = core.Statistic
+
+	// StatisticsPeriod is the span each Statistic summarises.
+	StatisticsPeriod = core.StatisticsPeriod
+
+	// StateValue lists the types [GetStateAs] can read a state as.
+	StateValue = core.StateValue
+
 	// Event is a Home Assistant event delivered to a trigger or an action.
 	Event = core.Event
 
+	// Hook is user code run at a point in the app's life, registered with
+	// [App.OnStart], [App.OnReady] or [App.OnStop].
+	Hook = core.Hook
+
+	// PanicError is a panic recovered from a callback, as handed to
+	// [App.OnCallbackError].
+	PanicError = core.PanicError
+
+	// AutomationMeta says which callback failed, for [App.OnCallbackError].
+	AutomationMeta = core.AutomationMeta
+
 	// Clock is the time source, injectable so automations can be tested.
 	Clock = types.Clock
+
+	// TimerClock is a Clock the schedulers can also sleep on, so advancing it
+	// fires schedules. [hatest.Clock] is one.
+	TimerClock = types.TimerClock
+
+	// RawSubscription is a stream opened with [App.Subscribe].
+	RawSubscription = core.RawSubscription
+
+	// Template renders templates with Home Assistant's engine.
+	Template = core.Template
+
+	// CloudStatus is Home Assistant Cloud's view of its own connection.
+	CloudStatus = core.CloudStatus
+
+	// ConnectionStats holds the connection's load counters, as
+	// [App.ConnectionStats] reports them.
+	ConnectionStats = core.ConnectionStats
+
+	// EventStats breaks down the events received, as [App.EventStats]
+	// reports them.
+	EventStats = core.EventStats
+
+	// EventRates is how often one kind of event arrives.
+	EventRates = core.EventRates
+
+	// TraceSummary is one stored run of an automation or script, as
+	// [App.Traces] lists them.
+	TraceSummary = core.TraceSummary
+
+	// Trace is one run of an automation or script in full, as [App.Trace]
+	// reads it.
+	Trace = core.Trace
+
+	// TraceStep is one step of a traced run.
+	TraceStep = core.TraceStep
+
+	// DryRunCall is a service call or event a dry-run app skipped, as
+	// [App.DryRunCalls] lists it.
+	DryRunCall = core.DryRunCall
+
+	// Inspection is what an app has registered and how it has run, as
+	// [App.Inspect] reports it.
+	Inspection = core.Inspection
+
+	// AutomationInfo describes one automation and its runs.
+	AutomationInfo = core.AutomationInfo
+
+	// ScheduleInfo describes one queued schedule trigger.
+	ScheduleInfo = core.ScheduleInfo
+
+	// ListenerInfo describes one event trigger.
+	ListenerInfo = core.ListenerInfo
+
+	// Registry reads Home Assistant's area, device and entity registries.
+	Registry = core.Registry
+
+	// Area is a room or space from the area registry.
+	Area = core.Area
+
+	// Device is a device from the device registry.
+	Device = core.Device
+
+	// RegistryEntry is an entity's entry in the entity registry.
+	RegistryEntry = core.RegistryEntry
+)
+
+// Cloud connection states, as [CloudStatus] reports them.
+const (
+	CloudConnected    = core.CloudConnected
+	CloudConnecting   = core.CloudConnecting
+	CloudDisconnected = core.CloudDisconnected
+)
+
+// Statistics periods, as [App.Statistics] takes them.
+const (
+	Period5Minutes = core.Period5Minutes
+	PeriodHour     = core.PeriodHour
+	PeriodDay      = core.PeriodDay
+	PeriodWeek     = core.PeriodWeek
+	PeriodMonth    = core.PeriodMonth
 )
 
 // Modes, matching Home Assistant's automation mode.
@@ -186,45 +439,121 @@ const (
 	RunAnyway = core.RunAnyway
 )
 
-// The solar events read from sun.sun.
+// RetryThenSkip re-evaluates an unevaluable condition up to attempts more
+// times, backing off between them, and skips the run only if it never settles.
+func RetryThenSkip(attempts int) ConditionErrorPolicy { return core.RetryThenSkip(attempts) }
+
+// The solar events a sun trigger fires at. Those up to SunNoon are read from
+// sun.sun, the deeper twilights computed.
 const (
-	SunRising  = core.SunRising
-	SunSetting = core.SunSetting
-	SunDawn    = core.SunDawn
-	SunDusk    = core.SunDusk
+	SunRising           = core.SunRising
+	SunSetting          = core.SunSetting
+	SunDawn             = core.SunDawn
+	SunDusk             = core.SunDusk
+	SunNoon             = core.SunNoon
+	SunNauticalDawn     = core.SunNauticalDawn
+	SunNauticalDusk     = core.SunNauticalDusk
+	SunAstronomicalDawn = core.SunAstronomicalDawn
+	SunAstronomicalDusk = core.SunAstronomicalDusk
 )
 
 // NewApp connects to Home Assistant and returns an app to register automations
 // on. Call [App.Start] to run it.
 func NewApp(request types.NewAppRequest) (*App, error) { return core.NewApp(request) }
 
+// NewAppFromSupervisor is [NewApp] for an app running as a Home Assistant
+// add-on, taking the URL and token from the add-on's environment.
+func NewAppFromSupervisor(request types.NewAppRequest) (*App, error) {
+	return core.NewAppFromSupervisor(request)
+}
+
 // NewAutomation starts building an automation. The name appears in logs.
 func NewAutomation(name string) AutomationBuilder { return core.NewAutomation(name) }
 
 // Daily fires once a day at the given time.
-func Daily(at ClockTime) ScheduleTrigger { return core.Daily(at) }
+func Daily(at ClockTime) DailyTrigger { return core.Daily(at) }
 
 // Every fires on a fixed interval.
-func Every(interval time.Duration) ScheduleTrigger { return core.Every(interval) }
+func Every(interval time.Duration) IntervalTrigger { return core.Every(interval) }
 
 // Cron fires on a cron expression.
 func Cron(expression string) ScheduleTrigger { return core.Cron(expression) }
 
 // AtStartup fires once, when the app starts.
-func AtStartup() ScheduleTrigger { return core.AtStartup() }
+func AtStartup() StartupTrigger { return core.AtStartup() }
 
 // Sunrise fires when the sun rises, optionally offset. A negative offset fires
 // before the event.
-func Sunrise(offset ...time.Duration) ScheduleTrigger { return core.Sunrise(offset...) }
+func Sunrise(offset ...time.Duration) SunTrigger { return core.Sunrise(offset...) }
 
 // Sunset fires when the sun sets, optionally offset.
-func Sunset(offset ...time.Duration) ScheduleTrigger { return core.Sunset(offset...) }
+func Sunset(offset ...time.Duration) SunTrigger { return core.Sunset(offset...) }
 
 // Dawn fires at the start of civil twilight, optionally offset.
-func Dawn(offset ...time.Duration) ScheduleTrigger { return core.Dawn(offset...) }
+func Dawn(offset ...time.Duration) SunTrigger { return core.Dawn(offset...) }
 
 // Dusk fires at the end of civil twilight, optionally offset.
-func Dusk(offset ...time.Duration) ScheduleTrigger { return core.Dusk(offset...) }
+func Dusk(offset ...time.Duration) SunTrigger { return core.Dusk(offset...) }
+
+// SolarNoon fires when the sun is at its highest, optionally offset.
+func SolarNoon(offset ...time.Duration) SunTrigger { return core.SolarNoon(offset...) }
+
+// NauticalDawn fires at the start of nautical twilight, optionally offset,
+// computed from the home's location.
+func NauticalDawn(offset ...time.Duration) SunTrigger { return core.NauticalDawn(offset...) }
+
+// NauticalDusk fires at the end of nautical twilight, optionally offset.
+func NauticalDusk(offset ...time.Duration) SunTrigger { return core.NauticalDusk(offset...) }
+
+// AstronomicalDawn fires at the start of astronomical twilight, optionally
+// offset, computed from the home's location.
+func AstronomicalDawn(offset ...time.Duration) SunTrigger {
+	return core.AstronomicalDawn(offset...)
+}
+
+// AstronomicalDusk fires at the end of astronomical twilight, optionally
+// offset.
+func AstronomicalDusk(offset ...time.Duration) SunTrigger {
+	return core.AstronomicalDusk(offset...)
+}
+
+// SunRisesAbove fires in the morning when the sun climbs past the elevation,
+// in degrees, computed from the home's location.
+func SunRisesAbove(degrees float64, offset ...time.Duration) SunTrigger {
+	return core.SunRisesAbove(degrees, offset...)
+}
+
+// SunDropsBelow fires in the evening when the sun sinks past the elevation,
+// in degrees, computed from the home's location.
+func SunDropsBelow(degrees float64, offset ...time.Duration) SunTrigger {
+	return core.SunDropsBelow(degrees, offset...)
+}
+
+// SkipDay lets a day without the sun event pass without firing.
+func SkipDay() SunFallback { return core.SkipDay() }
+
+// UseFixedTime fires at the given local time on days without the sun event.
+func UseFixedTime(hour, minute int) SunFallback { return core.UseFixedTime(hour, minute) }
+
+// NthWeekday is the nth (1 to 5) of the given weekday in each month.
+func NthWeekday(n int, day time.Weekday) DayRule { return core.NthWeekday(n, day) }
+
+// FirstWeekday is the first of the given weekday in each month.
+func FirstWeekday(day time.Weekday) DayRule { return core.FirstWeekday(day) }
+
+// LastWeekday is the last of the given weekday in each month.
+func LastWeekday(day time.Weekday) DayRule { return core.LastWeekday(day) }
+
+// DayOfMonth is the nth day of each month, passing over months too short.
+func DayOfMonth(n int) DayRule { return core.DayOfMonth(n) }
+
+// WorkdaySensor takes working days from a workday integration sensor, which
+// only knows today.
+func WorkdaySensor(entityID string) Workdays { return core.WorkdaySensor(entityID) }
+
+// HolidaysIn takes working days to be Monday to Friday less the country's
+// public holidays: DE, FR, GB, NL or US.
+func HolidaysIn(country string) Workdays { return core.HolidaysIn(country) }
 
 // StateChanged fires when any of the given entities changes state. With no
 // entities it fires on every state change, which is rarely what you want.
@@ -236,6 +565,39 @@ func StateChanged[T EntityRef](entityIDs ...T) StateChangeTrigger {
 // this package does not model directly.
 func EventFired(eventTypes ...string) EventTypeTrigger { return core.EventFired(eventTypes...) }
 
+// MQTT fires on messages published to the given topics, which may use MQTT's +
+// and # wildcards, through Home Assistant's MQTT integration.
+func MQTT(topics ...string) MQTTTrigger { return core.MQTT(topics...) }
+
+// Webhook fires on requests to Home Assistant's /api/webhook/<id>, which it
+// serves for as long as the app is connected.
+func Webhook(id string) WebhookTrigger { return core.Webhook(id) }
+
+// RemoteState fires when the entities change state, as Home Assistant's state
+// trigger judges it. Narrow it with From, To, Attribute and For.
+func RemoteState(entityIDs ...string) RemoteTrigger { return core.RemoteState(entityIDs...) }
+
+// RemoteNumericState fires when the entities' values cross Above or Below, as
+// Home Assistant's numeric_state trigger judges it.
+func RemoteNumericState(entityIDs ...string) RemoteTrigger {
+	return core.RemoteNumericState(entityIDs...)
+}
+
+// RemoteTimePattern fires when the clock matches Hours, Minutes and Seconds,
+// as Home Assistant's time_pattern trigger judges it.
+func RemoteTimePattern() RemoteTrigger { return core.RemoteTimePattern() }
+
+// Remote runs any trigger Home Assistant knows, given as its automations
+// write one.
+func Remote(config map[string]any) RemoteTrigger { return core.Remote(config) }
+
+// WhenAll fires when every requirement is met at once, evaluated from the
+// state cache, and again only once they have lapsed and come together anew.
+func WhenAll(requirements ...Requirement) WhenAllTrigger { return core.WhenAll(requirements...) }
+
+// Require starts a requirement on an entity, for WhenAll.
+func Require[T EntityRef](entityID T) Requirement { return core.Require(entityID) }
+
 // TimeOfDay is a wall-clock time. An hour or minute out of range fails the
 // build rather than panicking when the automation fires.
 func TimeOfDay(hour, minute int) ClockTime { return core.TimeOfDay(hour, minute) }
@@ -280,12 +642,104 @@ func OnDates(dates ...time.Time) Condition { return core.OnDates(dates...) }
 // InDateRange holds between two dates, inclusive.
 func InDateRange(start, end time.Time) Condition { return core.InDateRange(start, end) }
 
+// CalendarEventActive holds while an event is underway on the calendar,
+// optionally only one whose summary contains one of the given words.
+func CalendarEventActive[T EntityRef](calendar T, summaries ...string) Condition {
+	return core.CalendarEventActive(calendar, summaries...)
+}
+
+// CalendarEventWithin holds from lead before an event on the calendar starts
+// until it ends, narrowed by summary as CalendarEventActive is.
+func CalendarEventWithin[T EntityRef](calendar T, lead time.Duration, summaries ...string) Condition {
+	return core.CalendarEventWithin(calendar, lead, summaries...)
+}
+
 // SunIsUp holds while Home Assistant reports the sun above the horizon.
 func SunIsUp() Condition { return core.SunIsUp() }
 
 // SunIsDown holds while Home Assistant reports the sun below the horizon.
 func SunIsDown() Condition { return core.SunIsDown() }
 
+// SunIsAbove holds while Home Assistant reports the sun higher than the
+// elevation, in degrees.
+func SunIsAbove(degrees float64) Condition { return core.SunIsAbove(degrees) }
+
+// SunIsBelow holds while Home Assistant reports the sun lower than the
+// elevation, in degrees.
+func SunIsBelow(degrees float64) Condition { return core.SunIsBelow(degrees) }
+
+// WaitUntil blocks a run until the entity's state is want, or the timeout
+// passes. ctx must be the one the run was given.
+func WaitUntil(ctx context.Context, state StateReader, entityID, want string, timeout time.Duration) error {
+	return core.WaitUntil(ctx, state, entityID, want, timeout)
+}
+
+// WaitForEvent blocks a run until an event of the type passes filter, or the
+// timeout passes. ctx must be the one the run was given.
+func WaitForEvent(ctx context.Context, eventType string, filter func(Event) bool, timeout time.Duration) (Event, error) {
+	return core.WaitForEvent(ctx, eventType, filter, timeout)
+}
+
+// EntitiesInDomain lists the entities of one domain, such as "light", sorted by
+// id, for routines that act on every one of them.
+func EntitiesInDomain(state StateReader, domain string) ([]EntityState, error) {
+	return core.EntitiesInDomain(state, domain)
+}
+
+// GetStateAs reads the entity's state as T, parsing it from the string Home
+// Assistant sends.
+func GetStateAs[T StateValue, E EntityRef](state StateReader, entityID E) (T, error) {
+	return core.GetStateAs[T](state, entityID)
+}
+
+// GetStateIn reads a numeric sensor's state converted to the given unit, one
+// of those in package units.
+func GetStateIn[E EntityRef](state StateReader, entityID E, unit string) (float64, error) {
+	return core.GetStateIn(state, entityID, unit)
+}
+
+// GetAttribute reads one of the entity's attributes as T.
+func GetAttribute[T any, E EntityRef](state StateReader, entityID E, attr string) (T, error) {
+	return core.GetAttribute[T](state, entityID, attr)
+}
+
+// GetLight reads a light with its brightness, colour and effect decoded.
+func GetLight[E EntityRef](state StateReader, entityID E) (LightState, error) {
+	return core.GetLight(state, entityID)
+}
+
+// GetClimate reads a thermostat with its mode, temperatures and presets
+// decoded.
+func GetClimate[E EntityRef](state StateReader, entityID E) (ClimateState, error) {
+	return core.GetClimate(state, entityID)
+}
+
+// GetMediaPlayer reads a media player with its volume and what it is playing
+// decoded.
+func GetMediaPlayer[E EntityRef](state StateReader, entityID E) (MediaPlayerState, error) {
+	return core.GetMediaPlayer(state, entityID)
+}
+
+// GetCover reads a cover with its position and tilt decoded.
+func GetCover[E EntityRef](state StateReader, entityID E) (CoverState, error) {
+	return core.GetCover(state, entityID)
+}
+
+// GetBinarySensor reads a binary sensor with its device class decoded.
+func GetBinarySensor[E EntityRef](state StateReader, entityID E) (BinarySensorState, error) {
+	return core.GetBinarySensor(state, entityID)
+}
+
+// InArea targets every entity in the given areas, for [Service.Targeting].
+func InArea(areaIDs ...string) Target { return services.InArea(areaIDs...) }
+
+// OnDevice targets every entity of the given devices, for
+// [Service.Targeting].
+func OnDevice(deviceIDs ...string) Target { return services.OnDevice(deviceIDs...) }
+
+// Entities targets several entities at once, for [Service.Targeting].
+func Entities(entityIDs ...string) Target { return services.Entities(entityIDs...) }
+
 // toCore converts a slice of the locally declared Condition to the one core
 // takes. The interfaces are identical, so this is a copy rather than a
 // conversion, and it stops compiling the moment they diverge.
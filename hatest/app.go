@@ -0,0 +1,57 @@
+package hatest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/types"
+)
+
+// readyTimeout bounds how long StartApp waits for an app to come up against
+// the server, which answers at once.
+const readyTimeout = 5 * time.Second
+
+// NewApp builds an app connected to the server, closed when the test ends.
+// configure edits the request before the app is built, to give it a Clock or
+// make it ReadOnly, say.
+func NewApp(t testing.TB, s *Server, configure ...func(*types.NewAppRequest)) *ha.App {
+	t.Helper()
+
+	request := types.NewAppRequest{URL: s.URL(), HAAuthToken: Token}
+	for _, c := range configure {
+		c(&request)
+	}
+	app, err := ha.NewApp(request)
+	if err != nil {
+		t.Fatalf("hatest: connecting an app: %v", err)
+	}
+	t.Cleanup(func() { _ = app.Close() })
+	return app
+}
+
+// StartApp runs the app and waits until it is ready: its automations are
+// live, their subscriptions in place and the first snapshot of entity state
+// loaded, so a state the test changes next is seen. The app is closed when the
+// test ends.
+func StartApp(t testing.TB, app *ha.App) {
+	t.Helper()
+
+	ready := make(chan struct{})
+	app.OnReady(func(context.Context) error {
+		close(ready)
+		return nil
+	})
+	stopped := make(chan error, 1)
+	go func() { stopped <- app.Start() }()
+	t.Cleanup(func() { _ = app.Close() })
+
+	select {
+	case <-ready:
+	case err := <-stopped:
+		t.Fatalf("hatest: the app stopped before it was ready: %v", err)
+	case <-time.After(readyTimeout):
+		t.Fatalf("hatest: the app was not ready within %s", readyTimeout)
+	}
+}
@@ -0,0 +1,93 @@
+package hatest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AssertServiceCalled waits for a call to domain.service on entityID, and
+// returns it so its data can be checked too. An empty entityID matches a call
+// on any entity, or none. A call on several entities matches each of them.
+//
+// Under New a call that has not arrived within the timeout fails the test,
+// without stopping it; under Start it is reported as false.
+func (s *Server) AssertServiceCalled(domain, service, entityID string) (ServiceCall, bool) {
+	if s.t != nil {
+		s.t.Helper()
+	}
+
+	deadline := time.Now().Add(callTimeout)
+	for {
+		for _, call := range s.Calls() {
+			if call.matches(domain, service, entityID) {
+				return call, true
+			}
+		}
+		if time.Now().After(deadline) {
+			if s.t != nil {
+				s.t.Errorf("expected a call to %s, saw %s", describe(domain, service, entityID), s.callSummary())
+			}
+			return ServiceCall{}, false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// AssertServiceNotCalled checks no call to domain.service on entityID has been
+// made, matching as AssertServiceCalled does. It does not wait: call it once the
+// automation has had its chance, such as after asserting on a later call.
+func (s *Server) AssertServiceNotCalled(domain, service, entityID string) bool {
+	if s.t != nil {
+		s.t.Helper()
+	}
+
+	for _, call := range s.Calls() {
+		if call.matches(domain, service, entityID) {
+			if s.t != nil {
+				s.t.Errorf("expected no call to %s, saw one", describe(domain, service, entityID))
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// ResetCalls forgets the calls made so far, so a test can check what happens
+// after some step and nothing before it.
+func (s *Server) ResetCalls() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+func (c ServiceCall) matches(domain, service, entityID string) bool {
+	if c.Domain != domain || c.Service != service {
+		return false
+	}
+	if entityID == "" {
+		return true
+	}
+	return slices.Contains(strings.Split(c.EntityID, ", "), entityID)
+}
+
+func describe(domain, service, entityID string) string {
+	if entityID == "" {
+		return domain + "." + service
+	}
+	return fmt.Sprintf("%s.%s on %s", domain, service, entityID)
+}
+
+// callSummary lists the calls made, for a failure message.
+func (s *Server) callSummary() string {
+	calls := s.Calls()
+	if len(calls) == 0 {
+		return "no calls"
+	}
+	parts := make([]string, len(calls))
+	for i, c := range calls {
+		parts[i] = describe(c.Domain, c.Service, c.EntityID)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,63 @@
+package hatest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+// failures stands in for the test a server reports to, so an assertion that
+// should fail can be seen to.
+type failures struct {
+	testing.TB
+	errors []string
+}
+
+func (f *failures) Helper() {}
+
+func (f *failures) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertServiceCalled(t *testing.T) {
+	s := hatest.New(t)
+	app, err := ha.NewApp(types.NewAppRequest{URL: s.URL(), HAAuthToken: hatest.Token})
+	require.NoError(t, err)
+	defer app.Close()
+
+	require.NoError(t, app.Services().Light.TurnOn("light.pantry", map[string]any{"brightness": 128}))
+	require.NoError(t, app.Services().Targeting(ha.Entities("switch.a", "switch.b")).Switch.TurnOff(""))
+
+	call, ok := s.AssertServiceCalled("light", "turn_on", "light.pantry")
+	require.True(t, ok)
+	assert.EqualValues(t, 128, call.ServiceData["brightness"])
+	s.AssertServiceCalled("switch", "turn_off", "switch.b")
+	s.AssertServiceCalled("light", "turn_on", "")
+	s.AssertServiceNotCalled("light", "turn_off", "")
+
+	s.ResetCalls()
+	assert.Empty(t, s.Calls())
+}
+
+func TestAssertServiceCalledReportsWhatWasCalled(t *testing.T) {
+	tb := &failures{TB: t}
+	s := hatest.New(tb)
+	app, err := ha.NewApp(types.NewAppRequest{URL: s.URL(), HAAuthToken: hatest.Token})
+	require.NoError(t, err)
+	defer app.Close()
+
+	require.NoError(t, app.Services().Light.TurnOn("light.hall"))
+	s.WaitForCalls(1)
+
+	_, ok := s.AssertServiceCalled("light", "turn_on", "light.pantry")
+	assert.False(t, ok)
+	assert.False(t, s.AssertServiceNotCalled("light", "turn_on", "light.hall"))
+	require.Len(t, tb.errors, 2)
+	assert.Equal(t, "expected a call to light.turn_on on light.pantry, saw light.turn_on on light.hall", tb.errors[0])
+}
@@ -0,0 +1,146 @@
+package hatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// CalendarEvent is an event on one of the server's calendars.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+}
+
+// CalendarEvents returns a calendar's events, soonest first, for asserting on
+// what an app wrote to it. Calendars come into being with their first event, as
+// calendar.create_event adds one.
+func (s *Server) CalendarEvents(entityID string) []CalendarEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := slices.Clone(s.calendars[entityID])
+	slices.SortStableFunc(events, func(a, b CalendarEvent) int { return a.Start.Compare(b.Start) })
+	return events
+}
+
+// createEvent stands in for calendar.create_event against a local calendar.
+func (s *Server) createEvent(msg map[string]any) {
+	var entityID string
+	if target, ok := msg["target"].(map[string]any); ok {
+		entityID, _ = target["entity_id"].(string)
+	}
+	data, _ := msg["service_data"].(map[string]any)
+
+	ev := CalendarEvent{}
+	ev.Summary, _ = data["summary"].(string)
+	ev.Description, _ = data["description"].(string)
+	for key, at := range map[string]*time.Time{"start_date_time": &ev.Start, "end_date_time": &ev.End} {
+		raw, _ := data[key].(string)
+		*at, _ = time.Parse(time.RFC3339, raw)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUID++
+	ev.UID = fmt.Sprintf("hatest-event-%d", s.lastUID)
+	s.calendars[entityID] = append(s.calendars[entityID], ev)
+}
+
+// deleteEvent stands in for the calendar/event/delete command, reporting
+// whether the event existed.
+func (s *Server) deleteEvent(msg map[string]any) bool {
+	entityID, _ := msg["entity_id"].(string)
+	uid, _ := msg["uid"].(string)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.calendars[entityID]
+	i := slices.IndexFunc(events, func(ev CalendarEvent) bool { return ev.UID == uid })
+	if i < 0 {
+		return false
+	}
+	s.calendars[entityID] = slices.Delete(events, i, i+1)
+	return true
+}
+
+// getEvents stands in for calendar.get_events, answering with the events of
+// the targeted calendars that overlap the requested span.
+func (s *Server) getEvents(msg map[string]any) (any, error) {
+	var ids []string
+	if target, ok := msg["target"].(map[string]any); ok {
+		ids = stringList(target["entity_id"])
+	}
+	data, _ := msg["service_data"].(map[string]any)
+	startRaw, _ := data["start_date_time"].(string)
+	endRaw, _ := data["end_date_time"].(string)
+	start, errStart := time.Parse(time.RFC3339, startRaw)
+	end, errEnd := time.Parse(time.RFC3339, endRaw)
+	if errStart != nil || errEnd != nil {
+		return nil, fmt.Errorf("invalid start_date_time or end_date_time")
+	}
+
+	response := map[string]any{}
+	for _, id := range ids {
+		events := []map[string]any{}
+		for _, ev := range s.CalendarEvents(id) {
+			if !ev.End.After(start) || !ev.Start.Before(end) {
+				continue
+			}
+			events = append(events, map[string]any{
+				"summary":     ev.Summary,
+				"description": ev.Description,
+				"start":       ev.Start.Format(time.RFC3339),
+				"end":         ev.End.Format(time.RFC3339),
+			})
+		}
+		response[id] = map[string]any{"events": events}
+	}
+	return response, nil
+}
+
+// serveCalendar lists a calendar's events overlapping the requested span, in
+// the shape Home Assistant's calendar endpoint uses.
+func (s *Server) serveCalendar(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Path[len("/api/calendars/"):]
+
+	start, errStart := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+	end, errEnd := time.Parse(time.RFC3339, r.URL.Query().Get("end"))
+	if errStart != nil || errEnd != nil {
+		http.Error(w, `{"message":"Invalid datetime format."}`, http.StatusBadRequest)
+		return
+	}
+
+	type when struct {
+		DateTime string `json:"dateTime"`
+	}
+	type event struct {
+		UID         string `json:"uid"`
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Start       when   `json:"start"`
+		End         when   `json:"end"`
+	}
+
+	list := []event{}
+	for _, ev := range s.CalendarEvents(id) {
+		if ev.End.Before(start) || ev.Start.After(end) {
+			continue
+		}
+		list = append(list, event{
+			UID:         ev.UID,
+			Summary:     ev.Summary,
+			Description: ev.Description,
+			Start:       when{ev.Start.Format(time.RFC3339)},
+			End:         when{ev.End.Format(time.RFC3339)},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
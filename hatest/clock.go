@@ -1,41 +1,67 @@
 package hatest
 
 import (
-	"sync"
 	"time"
+
+	"github.com/Xevion/go-ha/internal"
 )
 
 // Clock is a time source a test drives by hand. Give one to
 // types.NewAppRequest to make schedules, throttles and For durations resolve
 // on demand instead of on the wall clock.
+//
+// The app's schedulers sleep on the clock too, so advancing it past a
+// schedule's time fires the schedule at once:
+//
+//	clock := hatest.NewClock(time.Date(2026, 3, 1, 6, 0, 0, 0, time.Local))
+//	// ... register ha.Daily(ha.TimeOfDay(7, 30)) and start the app ...
+//	clock.WaitForSleepers(2)
+//	clock.Advance(90 * time.Minute)
+//	server.WaitForCalls(1)
 type Clock struct {
-	mu  sync.RWMutex
-	now time.Time
+	fake *internal.FakeClock
 }
 
 // NewClock returns a clock parked at the given instant.
 func NewClock(now time.Time) *Clock {
-	return &Clock{now: now}
+	return &Clock{fake: internal.NewFakeClock(now)}
 }
 
 // Now reports the current instant. It is read from automation callbacks, which
 // run on their own goroutines, so it is guarded.
 func (c *Clock) Now() time.Time {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return c.now
+	return c.fake.Now()
+}
+
+// Timer delivers once the clock has been moved d or more past the current
+// instant. It never fires with the clock standing still.
+func (c *Clock) Timer(d time.Duration) (<-chan time.Time, func()) {
+	return c.fake.Timer(d)
 }
 
-// Set replaces the current instant.
+// Set replaces the current instant, waking anything now due.
 func (c *Clock) Set(now time.Time) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.now = now
+	c.fake.Set(now)
 }
 
-// Advance moves the clock forward. A negative duration moves it back.
+// Advance moves the clock forward, waking anything now due. A negative
+// duration moves it back.
 func (c *Clock) Advance(d time.Duration) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.now = c.now.Add(d)
+	c.fake.Advance(d)
+}
+
+// WaitForSleepers waits up to two seconds for at least n timers to be waiting
+// on the clock, and reports whether they were. A running app's two schedulers,
+// one for schedules and one for intervals, each sleep on it between runs, so
+// waiting for them before advancing avoids stepping past a schedule before it
+// has been queued.
+func (c *Clock) WaitForSleepers(n int) bool {
+	deadline := time.Now().Add(callTimeout)
+	for c.fake.Waiters() < n {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
 }
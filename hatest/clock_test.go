@@ -22,3 +22,12 @@ func TestClock(t *testing.T) {
 	c.Set(reset)
 	assert.Equal(t, reset, c.Now())
 }
+
+func TestClockTimers(t *testing.T) {
+	c := NewClock(time.Date(2026, 7, 19, 12, 0, 0, 0, time.UTC))
+	fired, _ := c.Timer(time.Minute)
+	assert.True(t, c.WaitForSleepers(1))
+
+	c.Advance(time.Minute)
+	assert.Len(t, fired, 1)
+}
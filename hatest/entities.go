@@ -0,0 +1,106 @@
+package hatest
+
+import (
+	"reflect"
+	"time"
+)
+
+// subscribeEntities answers subscribe_entities: the result, then every entity
+// in full as the stream's first message. Changes after it are sent as the
+// compressed diffs Home Assistant sends.
+func (s *Server) subscribeEntities(c *connection, id int64) {
+	s.mu.Lock()
+	added := make(map[string]any, len(s.entities))
+	for entityID, e := range s.entities {
+		added[entityID] = compress(e)
+	}
+	s.mu.Unlock()
+
+	c.mu.Lock()
+	c.streams[id] = struct{}{}
+	c.mu.Unlock()
+
+	_ = c.write(map[string]any{"id": id, "type": "result", "success": true})
+	_ = c.write(map[string]any{"id": id, "type": "event", "event": map[string]any{"a": added}})
+}
+
+// compress shortens an entity the way the stream's first message does.
+func compress(e entity) map[string]any {
+	out := map[string]any{
+		"s":  e.State,
+		"a":  e.Attributes,
+		"c":  "hatest",
+		"lc": epoch(e.LastChanged),
+	}
+	if !e.LastUpdated.Equal(e.LastChanged) {
+		out["lu"] = epoch(e.LastUpdated)
+	}
+	return out
+}
+
+// diff describes how next differs from old, for an entity the stream has
+// already sent.
+func diff(old, next entity) map[string]any {
+	set := map[string]any{}
+	if next.State != old.State {
+		set["s"] = next.State
+	}
+	if !next.LastChanged.Equal(old.LastChanged) {
+		set["lc"] = epoch(next.LastChanged)
+	} else if !next.LastUpdated.Equal(old.LastUpdated) {
+		set["lu"] = epoch(next.LastUpdated)
+	}
+
+	attrs := map[string]any{}
+	for k, v := range next.Attributes {
+		if prev, ok := old.Attributes[k]; !ok || !reflect.DeepEqual(normalise(prev), normalise(v)) {
+			attrs[k] = v
+		}
+	}
+	if len(attrs) > 0 {
+		set["a"] = attrs
+	}
+
+	out := map[string]any{"+": set}
+	var gone []string
+	for k := range old.Attributes {
+		if _, ok := next.Attributes[k]; !ok {
+			gone = append(gone, k)
+		}
+	}
+	if len(gone) > 0 {
+		out["-"] = map[string]any{"a": gone}
+	}
+	return out
+}
+
+func epoch(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+// streamEntities sends a diff to every entity stream: an addition when old is
+// nil, a removal when next is.
+func (s *Server) streamEntities(conns []*connection, entityID string, old, next *entity) {
+	var event map[string]any
+	switch {
+	case next == nil:
+		event = map[string]any{"r": []string{entityID}}
+	case old == nil:
+		event = map[string]any{"a": map[string]any{entityID: compress(*next)}}
+	default:
+		event = map[string]any{"c": map[string]any{entityID: diff(*old, *next)}}
+	}
+
+	for _, c := range conns {
+		c.mu.Lock()
+		ids := make([]int64, 0, len(c.streams))
+		for id := range c.streams {
+			ids = append(ids, id)
+		}
+		c.mu.Unlock()
+
+		for _, id := range ids {
+			_ = c.write(map[string]any{"id": id, "type": "event", "event": event})
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package hatest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Fixture is a recorded conversation with one Home Assistant: the frames each
+// side sent over the websocket, in order, and the states its REST API served.
+// Replay plays it back to a client, so a change in how the client speaks the
+// protocol is caught against what a real server said, without one running.
+//
+// On disk it is JSON:
+//
+//	{
+//	  "ha_version": "2024.6.4",
+//	  "states": [{"entity_id": "light.kitchen", "state": "off", ...}],
+//	  "frames": [
+//	    {"server": {"type": "auth_required", "ha_version": "2024.6.4"}},
+//	    {"client": {"type": "auth"}},
+//	    {"server": {"type": "auth_ok", "ha_version": "2024.6.4"}},
+//	    ...
+//	  ]
+//	}
+type Fixture struct {
+	// Name is the file the fixture was loaded from, without its extension.
+	Name string `json:"-"`
+
+	// HAVersion is the version of Home Assistant the frames were recorded
+	// from.
+	HAVersion string `json:"ha_version"`
+
+	// States is what GET /api/states answered.
+	States []map[string]any `json:"states"`
+
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is one websocket message of a Fixture. Exactly one of its fields is
+// set.
+type Frame struct {
+	// Server is a message Home Assistant sent, which Replay sends in turn.
+	Server map[string]any `json:"server,omitempty"`
+
+	// Client is a message the client sent, which Replay waits for before
+	// going on. A message matches it when it has every field recorded, with
+	// the same value, other than its id and access token: ids are numbered
+	// afresh by every client, and tokens are not worth recording.
+	Client map[string]any `json:"client,omitempty"`
+}
+
+// LoadFixture reads a fixture from a file.
+func LoadFixture(path string) (Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return Fixture{}, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	for i, frame := range f.Frames {
+		if (frame.Server == nil) == (frame.Client == nil) {
+			return Fixture{}, fmt.Errorf("fixture %s: frame %d must be either server or client", path, i)
+		}
+	}
+	f.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return f, nil
+}
+
+// LoadFixtures reads every fixture whose file matches the pattern, such as
+// "testdata/protocol/*.json", in the order of their names.
+func LoadFixtures(pattern string) ([]Fixture, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no fixtures match %s", pattern)
+	}
+	fixtures := make([]Fixture, 0, len(paths))
+	for _, path := range paths {
+		f, err := LoadFixture(path)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+// Replay is a Home Assistant that plays back a Fixture to the first client
+// that connects. It sends the server's frames in order, holding at each of the
+// client's until the client sends a message that matches it. The ids of the
+// server's frames are rewritten to those the client chose.
+//
+// A message the fixture does not expect is answered with a bare success, or a
+// pong for a ping, and kept for Unexpected, so the client is not left hanging
+// on something the recording never covered.
+type Replay struct {
+	fixture Fixture
+	http    *httptest.Server
+
+	done chan struct{}
+
+	mu         sync.Mutex
+	closed     bool
+	started    bool
+	played     int
+	unexpected []map[string]any
+	conns      map[*websocket.Conn]struct{}
+	handlers   sync.WaitGroup
+}
+
+// NewReplay starts a server playing the fixture and registers its shutdown
+// with t.
+func NewReplay(t testing.TB, f Fixture) *Replay {
+	t.Helper()
+
+	r := &Replay{fixture: f, done: make(chan struct{}), conns: map[*websocket.Conn]struct{}{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/{$}", serveAPIStatus)
+	mux.HandleFunc("/api/websocket", r.serveWebsocket)
+	mux.HandleFunc("/api/states", r.serveStates)
+	r.http = httptest.NewServer(mux)
+	t.Cleanup(r.Close)
+	return r
+}
+
+// URL is the address to give ha.NewAppRequest.
+func (r *Replay) URL() string { return r.http.URL }
+
+// Close shuts the server down.
+func (r *Replay) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	conns := make([]*websocket.Conn, 0, len(r.conns))
+	for ws := range r.conns {
+		conns = append(conns, ws)
+	}
+	r.mu.Unlock()
+
+	for _, ws := range conns {
+		_ = ws.CloseNow()
+	}
+	r.handlers.Wait()
+	r.http.Close()
+}
+
+// Wait blocks until every frame of the fixture has been played, and reports
+// the frame it was still waiting at if that takes longer than the timeout.
+func (r *Replay) Wait(timeout time.Duration) error {
+	select {
+	case <-r.done:
+		return nil
+	case <-time.After(timeout):
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.played >= len(r.fixture.Frames) {
+			return nil
+		}
+		frame, _ := json.Marshal(r.fixture.Frames[r.played])
+		return fmt.Errorf("fixture %s: stuck at frame %d: %s", r.fixture.Name, r.played, frame)
+	}
+}
+
+// Unexpected lists the messages the client sent that the fixture did not
+// expect, in the order they arrived.
+func (r *Replay) Unexpected() []map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]any(nil), r.unexpected...)
+}
+
+func (r *Replay) serveStates(w http.ResponseWriter, _ *http.Request) {
+	states := r.fixture.States
+	if states == nil {
+		states = []map[string]any{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(states)
+}
+
+func (r *Replay) serveWebsocket(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	if r.started {
+		// A fixture is one conversation. A client that reconnects has
+		// already strayed from it.
+		r.mu.Unlock()
+		http.Error(w, "fixture already played", http.StatusServiceUnavailable)
+		return
+	}
+	r.started = true
+	r.handlers.Add(1)
+	r.mu.Unlock()
+	defer r.handlers.Done()
+
+	ws, err := websocket.Accept(w, req, nil)
+	if err != nil {
+		return
+	}
+	ws.SetReadLimit(16 << 20)
+	defer ws.CloseNow()
+
+	// A Close that came during the handshake has not seen this socket to
+	// close it, so it is closed here instead.
+	r.mu.Lock()
+	r.conns[ws] = struct{}{}
+	closed := r.closed
+	r.mu.Unlock()
+	if closed {
+		return
+	}
+
+	c := &connection{ws: ws}
+	ctx := req.Context()
+	ids := map[float64]any{}
+
+	for i, frame := range r.fixture.Frames {
+		if frame.Server != nil {
+			msg := frame.Server
+			if id, ok := msg["id"].(float64); ok {
+				if actual, bound := ids[id]; bound {
+					msg = cloneWith(msg, "id", actual)
+				}
+			}
+			if err := c.write(msg); err != nil {
+				return
+			}
+		} else {
+			for {
+				msg, err := c.read(ctx)
+				if err != nil {
+					return
+				}
+				if matches(frame.Client, msg) {
+					if id, ok := frame.Client["id"].(float64); ok {
+						ids[id] = msg["id"]
+					}
+					break
+				}
+				if err := r.answer(c, msg); err != nil {
+					return
+				}
+			}
+		}
+		r.mu.Lock()
+		r.played = i + 1
+		r.mu.Unlock()
+	}
+	close(r.done)
+
+	for {
+		msg, err := c.read(ctx)
+		if err != nil {
+			return
+		}
+		if err := r.answer(c, msg); err != nil {
+			return
+		}
+	}
+}
+
+// answer replies to a message the fixture did not expect.
+func (r *Replay) answer(c *connection, msg map[string]any) error {
+	if msg["type"] == "ping" {
+		return c.write(map[string]any{"id": msg["id"], "type": "pong"})
+	}
+	r.mu.Lock()
+	r.unexpected = append(r.unexpected, msg)
+	r.mu.Unlock()
+	if msg["type"] == "auth" {
+		return errors.New("unexpected auth")
+	}
+	return c.write(map[string]any{"id": msg["id"], "type": "result", "success": true, "result": nil})
+}
+
+// matches reports whether msg has every field recorded, other than the id
+// and access token.
+func matches(recorded, msg map[string]any) bool {
+	for key, want := range recorded {
+		if key == "id" || key == "access_token" {
+			continue
+		}
+		if !reflect.DeepEqual(want, msg[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+func cloneWith(msg map[string]any, key string, value any) map[string]any {
+	out := maps.Clone(msg)
+	out[key] = value
+	return out
+}
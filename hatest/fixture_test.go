@@ -0,0 +1,36 @@
+package hatest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFixtureNamesItAfterItsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "2024.6.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"ha_version":"2024.6.4","frames":[
+		{"server":{"type":"auth_required"}},{"client":{"type":"auth"}}]}`), 0o600))
+
+	f, err := LoadFixture(path)
+	require.NoError(t, err)
+	assert.Equal(t, "2024.6", f.Name)
+	assert.Equal(t, "2024.6.4", f.HAVersion)
+	assert.Len(t, f.Frames, 2)
+}
+
+func TestLoadFixtureRefusesAFrameWithoutASide(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"frames":[{}]}`), 0o600))
+
+	_, err := LoadFixture(path)
+	assert.ErrorContains(t, err, "frame 0")
+}
+
+func TestMatchesIgnoresTheIDAndToken(t *testing.T) {
+	recorded := map[string]any{"id": 1.0, "type": "auth", "access_token": "redacted"}
+	assert.True(t, matches(recorded, map[string]any{"id": 7.0, "type": "auth", "access_token": "real"}))
+	assert.False(t, matches(recorded, map[string]any{"type": "ping"}))
+}
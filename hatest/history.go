@@ -0,0 +1,67 @@
+package hatest
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AddHistory records that the entity held a state from the given instant, for
+// apps reading the recorder's history. It neither changes the entity's current
+// state nor announces anything; states set through SetState and ChangeState
+// are recorded as they happen.
+func (s *Server) AddHistory(entityID, state string, at time.Time, attributes ...map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.buildEntity(entityID, state, attributes)
+	e.LastChanged, e.LastUpdated = at, at
+	s.history[entityID] = append(s.history[entityID], e)
+	slices.SortStableFunc(s.history[entityID], func(a, b entity) int {
+		return a.LastChanged.Compare(b.LastChanged)
+	})
+}
+
+// serveHistory stands in for the recorder's history endpoint: for each entity
+// asked for, the state in effect at the start, moved up to it, and every state
+// begun before the end.
+func (s *Server) serveHistory(w http.ResponseWriter, r *http.Request) {
+	start, err := time.Parse(time.RFC3339, strings.TrimPrefix(r.URL.Path, "/api/history/period/"))
+	if err != nil {
+		http.Error(w, `{"message":"Invalid datetime"}`, http.StatusBadRequest)
+		return
+	}
+	end := time.Now()
+	if raw := r.URL.Query().Get("end_time"); raw != "" {
+		if end, err = time.Parse(time.RFC3339, raw); err != nil {
+			http.Error(w, `{"message":"Invalid end_time"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	out := [][]entity{}
+	for _, id := range strings.Split(r.URL.Query().Get("filter_entity_id"), ",") {
+		var list []entity
+		for _, e := range s.history[id] {
+			switch {
+			case e.LastChanged.After(end):
+			case e.LastChanged.After(start):
+				list = append(list, e)
+			default:
+				// Only the latest before the start survives.
+				e.LastChanged, e.LastUpdated = start, start
+				list = []entity{e}
+			}
+		}
+		if len(list) > 0 {
+			out = append(out, list)
+		}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
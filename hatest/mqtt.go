@@ -0,0 +1,57 @@
+package hatest
+
+import "strings"
+
+// PublishMQTT delivers a message to every mqtt/subscribe stream whose topic
+// filter matches, as a broker behind Home Assistant's MQTT integration
+// would. An app's own mqtt.publish calls are delivered the same way.
+func (s *Server) PublishMQTT(topic, payload string) {
+	s.mu.Lock()
+	conns := make([]*connection, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.mu.Lock()
+		var ids []int64
+		for id, filter := range c.mqtt {
+			if topicMatches(filter, topic) {
+				ids = append(ids, id)
+			}
+		}
+		c.mu.Unlock()
+
+		for _, id := range ids {
+			_ = c.write(map[string]any{"id": id, "type": "event", "event": map[string]any{
+				"topic": topic, "payload": payload, "qos": 0, "retain": false,
+			}})
+		}
+	}
+}
+
+// subscribeMQTT answers mqtt/subscribe, remembering the filter for
+// PublishMQTT.
+func (s *Server) subscribeMQTT(c *connection, id int64, msg map[string]any) {
+	topic, _ := msg["topic"].(string)
+	c.mu.Lock()
+	c.mqtt[id] = topic
+	c.mu.Unlock()
+	_ = c.write(map[string]any{"id": id, "type": "result", "success": true})
+}
+
+// topicMatches reports whether an MQTT topic filter, with its + and #
+// wildcards, matches a topic.
+func topicMatches(filter, topic string) bool {
+	want, got := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, level := range want {
+		if level == "#" {
+			return true
+		}
+		if i >= len(got) || (level != "+" && level != got[i]) {
+			return false
+		}
+	}
+	return len(want) == len(got)
+}
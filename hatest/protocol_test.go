@@ -3,6 +3,7 @@ package hatest
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/coder/websocket"
@@ -116,3 +117,25 @@ func TestServeStateReturnsASeededEntity(t *testing.T) {
 	assert.Equal(t, "on", got["state"])
 	assert.Equal(t, "light.hall", got["entity_id"])
 }
+
+// Setting a state over REST creates the entity and reports whether it existed,
+// as Home Assistant does with 201 and 200.
+func TestPostStateCreatesThenUpdates(t *testing.T) {
+	s := New(t)
+
+	post := func() int {
+		resp, err := http.Post(s.URL()+"/api/states/sensor.virtual", "application/json",
+			strings.NewReader(`{"state":"42","attributes":{"unit_of_measurement":"%"}}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusCreated, post())
+	assert.Equal(t, http.StatusOK, post())
+
+	state, attrs, ok := s.State("sensor.virtual")
+	require.True(t, ok)
+	assert.Equal(t, "42", state)
+	assert.Equal(t, "%", attrs["unit_of_measurement"])
+}
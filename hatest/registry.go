@@ -0,0 +1,107 @@
+package hatest
+
+// Area is an area in the server's area registry.
+type Area struct {
+	ID   string
+	Name string
+}
+
+// Device is a device in the server's device registry.
+type Device struct {
+	ID           string
+	Name         string
+	Manufacturer string
+	Model        string
+	AreaID       string
+}
+
+// RegistryEntity is an entity's entry in the server's entity registry. An
+// entity without an AreaID is in its device's area, as in Home Assistant.
+type RegistryEntity struct {
+	EntityID string
+	DeviceID string
+	AreaID   string
+	Disabled bool
+}
+
+// registry holds the three registries config/*_registry/list answer from.
+type registry struct {
+	areas    []Area
+	devices  []Device
+	entities []RegistryEntity
+}
+
+// AddArea adds an area to the area registry.
+func (s *Server) AddArea(a Area) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registry.areas = append(s.registry.areas, a)
+}
+
+// AddDevice adds a device to the device registry.
+func (s *Server) AddDevice(d Device) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registry.devices = append(s.registry.devices, d)
+}
+
+// AddRegistryEntity adds an entry to the entity registry. It does not create
+// the entity's state; use SetState for that.
+func (s *Server) AddRegistryEntity(e RegistryEntity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registry.entities = append(s.registry.entities, e)
+}
+
+// orNil sends an empty id as null, as Home Assistant does.
+func orNil(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func (s *Server) listAreas(map[string]any) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []map[string]any{}
+	for _, a := range s.registry.areas {
+		out = append(out, map[string]any{
+			"area_id": a.ID, "name": a.Name, "aliases": []string{}, "floor_id": nil, "labels": []string{},
+		})
+	}
+	return out, nil
+}
+
+func (s *Server) listDevices(map[string]any) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []map[string]any{}
+	for _, d := range s.registry.devices {
+		out = append(out, map[string]any{
+			"id": d.ID, "name": d.Name, "name_by_user": nil, "manufacturer": orNil(d.Manufacturer),
+			"model": orNil(d.Model), "area_id": orNil(d.AreaID), "disabled_by": nil,
+		})
+	}
+	return out, nil
+}
+
+func (s *Server) listRegistryEntities(map[string]any) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []map[string]any{}
+	for _, e := range s.registry.entities {
+		var disabledBy any
+		if e.Disabled {
+			disabledBy = "user"
+		}
+		out = append(out, map[string]any{
+			"entity_id": e.EntityID, "device_id": orNil(e.DeviceID), "area_id": orNil(e.AreaID),
+			"disabled_by": disabledBy, "hidden_by": nil, "name": nil, "platform": "hatest",
+		})
+	}
+	return out, nil
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -29,6 +30,10 @@ type ServiceCall struct {
 	Service     string
 	EntityID    string
 	ServiceData map[string]any
+
+	// AreaIDs and DeviceIDs are the areas and devices the call targets.
+	AreaIDs   []string
+	DeviceIDs []string
 }
 
 // Server is an in-process Home Assistant.
@@ -46,8 +51,36 @@ type Server struct {
 	closed   bool
 	entities map[string]entity
 	calls    []ServiceCall
+	commands map[string]CommandHandler
+	services map[string]CommandHandler
+
+	// templates renders render_template subscriptions.
+	templates TemplateRenderer
+
+	// registry holds the areas, devices and entity registry entries.
+	registry registry
+
+	// history holds every state each entity has held, oldest first.
+	history map[string][]entity
+
+	// statistics holds the long-term statistics rows of each entity.
+	statistics map[string][]statistic
+
+	// calendars holds the events of each calendar entity, and lastUID the id
+	// given to the newest.
+	calendars map[string][]CalendarEvent
+	lastUID   int
+
+	// config is the configuration get_config and the REST API report.
+	config map[string]any
+
 	// subs maps a subscription id to the event type it wants, per connection.
 	conns map[*connection]struct{}
+
+	// connections counts the websockets that have authenticated, and
+	// withholdPongs leaves pings unanswered.
+	connections   int
+	withholdPongs bool
 }
 
 type entity struct {
@@ -59,9 +92,13 @@ type entity struct {
 }
 
 type connection struct {
-	ws   *websocket.Conn
-	mu   sync.Mutex
-	subs map[int64]string
+	ws        *websocket.Conn
+	mu        sync.Mutex
+	subs      map[int64]string
+	templates map[int64]*renderedTemplate
+	streams   map[int64]struct{}
+	mqtt      map[int64]string
+	triggers  map[int64]map[string]any
 }
 
 // New starts a server and registers its shutdown with t.
@@ -87,14 +124,38 @@ func Start() *Server {
 
 func newServer() *Server {
 	s := &Server{
-		entities: map[string]entity{},
-		conns:    map[*connection]struct{}{},
+		entities:   map[string]entity{},
+		commands:   map[string]CommandHandler{},
+		services:   map[string]CommandHandler{},
+		history:    map[string][]entity{},
+		calendars:  map[string][]CalendarEvent{},
+		statistics: map[string][]statistic{},
+		conns:      map[*connection]struct{}{},
+		config:     defaultConfig(),
+	}
+
+	// Registered as ordinary handlers, so a test can replace them.
+	s.commands["config/area_registry/list"] = s.listAreas
+	s.commands["config/device_registry/list"] = s.listDevices
+	s.commands["config/entity_registry/list"] = s.listRegistryEntities
+	s.services["calendar.get_events"] = s.getEvents
+	s.commands["recorder/statistics_during_period"] = s.statisticsDuringPeriod
+	s.commands["get_config"] = func(map[string]any) (any, error) { return s.configuration(), nil }
+	s.commands["auth/current_user"] = func(map[string]any) (any, error) {
+		return map[string]any{
+			"id": "hatest-user", "name": "hatest", "is_owner": true, "is_admin": true,
+			"credentials": []any{}, "mfa_modules": []any{},
+		}, nil
 	}
 
 	mux := http.NewServeMux()
+	mux.HandleFunc("/api/{$}", serveAPIStatus)
 	mux.HandleFunc("/api/websocket", s.serveWebsocket)
 	mux.HandleFunc("/api/states/", s.serveState)
 	mux.HandleFunc("/api/states", s.serveStates)
+	mux.HandleFunc("/api/calendars/", s.serveCalendar)
+	mux.HandleFunc("/api/config", s.serveConfig)
+	mux.HandleFunc("/api/history/period/", s.serveHistory)
 
 	s.http = httptest.NewServer(mux)
 	return s
@@ -130,12 +191,31 @@ func (s *Server) Close() {
 	s.http.Close()
 }
 
+// Connections reports how many websockets have authenticated, so a test can
+// see a client reconnect.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// WithholdPongs leaves pings unanswered while withhold is true, as a
+// connection a NAT or proxy has silently dropped does. Everything else is
+// still answered.
+func (s *Server) WithholdPongs(withhold bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.withholdPongs = withhold
+}
+
 // SetState installs an entity without announcing it, for setting up the world
 // before an App connects.
 func (s *Server) SetState(entityID, state string, attributes ...map[string]any) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.entities[entityID] = s.buildEntity(entityID, state, attributes)
+	e := s.buildEntity(entityID, state, attributes)
+	s.entities[entityID] = e
+	s.history[entityID] = append(s.history[entityID], e)
 }
 
 // SetSun installs sun.sun with the given times, which is what sun triggers
@@ -181,6 +261,7 @@ func (s *Server) ChangeState(entityID, state string, attributes ...map[string]an
 	old, existed := s.entities[entityID]
 	next := s.buildEntity(entityID, state, attributes)
 	s.entities[entityID] = next
+	s.history[entityID] = append(s.history[entityID], next)
 
 	conns := make([]*connection, 0, len(s.conns))
 	for c := range s.conns {
@@ -189,8 +270,9 @@ func (s *Server) ChangeState(entityID, state string, attributes ...map[string]an
 	s.mu.Unlock()
 
 	var oldState any
+	var prev *entity
 	if existed {
-		oldState = old
+		oldState, prev = old, &old
 	}
 
 	s.broadcast(conns, "state_changed", map[string]any{
@@ -198,6 +280,8 @@ func (s *Server) ChangeState(entityID, state string, attributes ...map[string]an
 		"old_state": oldState,
 		"new_state": next,
 	})
+	s.streamEntities(conns, entityID, prev, &next)
+	s.rerender(conns)
 }
 
 // RemoveState announces an entity being deleted.
@@ -220,6 +304,8 @@ func (s *Server) RemoveState(entityID string) {
 		"old_state": old,
 		"new_state": nil,
 	})
+	s.streamEntities(conns, entityID, &old, nil)
+	s.rerender(conns)
 }
 
 // Fire announces an arbitrary event, for triggers watching event types this
@@ -259,6 +345,10 @@ func (s *Server) broadcast(conns []*connection, eventType string, data map[strin
 	}
 }
 
+// callTimeout is how long WaitForCalls and AssertServiceCalled give an
+// automation to make the call expected of it.
+const callTimeout = 2 * time.Second
+
 // Calls returns the service calls made so far, oldest first.
 func (s *Server) Calls() []ServiceCall {
 	s.mu.Lock()
@@ -275,7 +365,7 @@ func (s *Server) WaitForCalls(n int) []ServiceCall {
 		s.t.Helper()
 	}
 
-	deadline := time.Now().Add(2 * time.Second)
+	deadline := time.Now().Add(callTimeout)
 	for {
 		if calls := s.Calls(); len(calls) >= n {
 			return calls
@@ -302,9 +392,71 @@ func (s *Server) serveStates(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(list)
 }
 
+// serveAPIStatus answers the REST API's root, which is how a client checks its
+// token: it is the one endpoint here that refuses any other.
+func serveAPIStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+Token {
+		http.Error(w, "401: Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"message": "API running."})
+}
+
+// defaultConfig describes a metric installation in London, in UTC.
+func defaultConfig() map[string]any {
+	return map[string]any{
+		"location_name": "Home",
+		"latitude":      51.5074,
+		"longitude":     -0.1278,
+		"elevation":     11,
+		"time_zone":     "UTC",
+		"unit_system": map[string]any{
+			"temperature":               "°C",
+			"length":                    "km",
+			"mass":                      "g",
+			"volume":                    "L",
+			"area":                      "m²",
+			"pressure":                  "Pa",
+			"wind_speed":                "m/s",
+			"accumulated_precipitation": "mm",
+		},
+		"country":  "GB",
+		"currency": "GBP",
+		"language": "en",
+		"version":  "2026.7.0",
+	}
+}
+
+// SetConfig replaces one field of the configuration Home Assistant reports,
+// over get_config and the REST API alike, such as "time_zone" or
+// "unit_system". An app reads the configuration as it connects, so a change
+// is set before NewApp.
+func (s *Server) SetConfig(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config[key] = value
+}
+
+func (s *Server) configuration() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.config)
+}
+
+func (s *Server) serveConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.configuration())
+}
+
 func (s *Server) serveState(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Path[len("/api/states/"):]
 
+	if r.Method == http.MethodPost {
+		s.postState(w, r, id)
+		return
+	}
+
 	s.mu.Lock()
 	e, ok := s.entities[id]
 	s.mu.Unlock()
@@ -318,6 +470,69 @@ func (s *Server) serveState(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(e)
 }
 
+// postState stands in for Home Assistant's set-state endpoint, which announces
+// the change as a state_changed event just as an integration's update would.
+func (s *Server) postState(w http.ResponseWriter, r *http.Request, id string) {
+	var body struct {
+		State      string         `json:"state"`
+		Attributes map[string]any `json:"attributes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"message":"Invalid JSON specified."}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	_, existed := s.entities[id]
+	s.mu.Unlock()
+
+	s.ChangeState(id, body.State, body.Attributes)
+
+	s.mu.Lock()
+	e := s.entities[id]
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if existed {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+// CommandHandler answers a websocket command. It receives the decoded message
+// and returns the result to send back, or an error to refuse the command with.
+type CommandHandler func(msg map[string]any) (result any, err error)
+
+// HandleCommand answers every websocket command of the given type with fn.
+// Commands nobody handles succeed with an empty result.
+func (s *Server) HandleCommand(msgType string, fn CommandHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands[msgType] = fn
+}
+
+// HandleService answers calls to domain.service with fn, which sees the whole
+// call_service message. Its result is sent back as the service response, and
+// an error fails the call the way Home Assistant fails one against a missing
+// entity. Calls are recorded either way. Services nobody handles succeed with
+// no response.
+func (s *Server) HandleService(domain, service string, fn CommandHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services[domain+"."+service] = fn
+}
+
+// State returns an entity as the server currently holds it, for asserting on
+// what an app published.
+func (s *Server) State(entityID string) (state string, attributes map[string]any, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entities[entityID]
+	return e.State, e.Attributes, ok
+}
+
 func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
 	s.handlers.Add(1)
 	defer s.handlers.Done()
@@ -328,7 +543,14 @@ func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
 	}
 	ws.SetReadLimit(16 << 20)
 
-	c := &connection{ws: ws, subs: map[int64]string{}}
+	c := &connection{
+		ws:        ws,
+		subs:      map[int64]string{},
+		templates: map[int64]*renderedTemplate{},
+		streams:   map[int64]struct{}{},
+		mqtt:      map[int64]string{},
+		triggers:  map[int64]map[string]any{},
+	}
 	ctx := r.Context()
 
 	// Registered before the handshake, not after. Close only shuts connections
@@ -348,6 +570,9 @@ func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
 	if err := s.authenticate(ctx, c); err != nil {
 		return
 	}
+	s.mu.Lock()
+	s.connections++
+	s.mu.Unlock()
 
 	s.readLoop(ctx, c)
 }
@@ -388,17 +613,105 @@ func (s *Server) readLoop(ctx context.Context, c *connection) {
 
 		case "call_service":
 			s.recordCall(msg)
+			if msg["domain"] == "calendar" && msg["service"] == "create_event" {
+				s.createEvent(msg)
+			}
+			s.answerCall(c, int64(id), msg)
+			if msg["domain"] == "mqtt" && msg["service"] == "publish" {
+				data, _ := msg["service_data"].(map[string]any)
+				topic, _ := data["topic"].(string)
+				payload, _ := data["payload"].(string)
+				s.PublishMQTT(topic, payload)
+			}
+
+		case "calendar/event/delete":
+			if !s.deleteEvent(msg) {
+				_ = c.write(map[string]any{"id": int64(id), "type": "result", "success": false,
+					"error": map[string]any{"code": "not_found", "message": "Unable to find event"}})
+				continue
+			}
+			_ = c.write(map[string]any{"id": int64(id), "type": "result", "success": true})
+
+		case "unsubscribe_events":
+			sub, _ := msg["subscription"].(float64)
+			c.mu.Lock()
+			delete(c.subs, int64(sub))
+			delete(c.templates, int64(sub))
+			delete(c.streams, int64(sub))
+			delete(c.mqtt, int64(sub))
+			delete(c.triggers, int64(sub))
+			c.mu.Unlock()
 			_ = c.write(map[string]any{"id": int64(id), "type": "result", "success": true})
 
+		case "render_template":
+			s.subscribeTemplate(c, int64(id), msg)
+
+		case "subscribe_entities":
+			s.subscribeEntities(c, int64(id))
+
+		case "mqtt/subscribe":
+			s.subscribeMQTT(c, int64(id), msg)
+
+		case "subscribe_trigger":
+			s.subscribeTrigger(c, int64(id), msg)
+
 		case "ping":
-			_ = c.write(map[string]any{"id": int64(id), "type": "pong"})
+			s.mu.Lock()
+			withhold := s.withholdPongs
+			s.mu.Unlock()
+			if !withhold {
+				_ = c.write(map[string]any{"id": int64(id), "type": "pong"})
+			}
 
 		default:
-			_ = c.write(map[string]any{"id": int64(id), "type": "result", "success": true})
+			msgType, _ := msg["type"].(string)
+			s.mu.Lock()
+			handle, ok := s.commands[msgType]
+			s.mu.Unlock()
+
+			if !ok {
+				_ = c.write(map[string]any{"id": int64(id), "type": "result", "success": true})
+				continue
+			}
+
+			result, err := handle(msg)
+			if err != nil {
+				_ = c.write(map[string]any{"id": int64(id), "type": "result", "success": false,
+					"error": map[string]any{"code": "unknown_error", "message": err.Error()}})
+				continue
+			}
+			_ = c.write(map[string]any{"id": int64(id), "type": "result", "success": true, "result": result})
 		}
 	}
 }
 
+// answerCall replies to a call_service the way Home Assistant does: a context
+// naming the call, and the service's response when one was asked for.
+func (s *Server) answerCall(c *connection, id int64, msg map[string]any) {
+	domain, _ := msg["domain"].(string)
+	service, _ := msg["service"].(string)
+
+	s.mu.Lock()
+	handle, ok := s.services[domain+"."+service]
+	s.mu.Unlock()
+
+	result := map[string]any{
+		"context": map[string]any{"id": fmt.Sprintf("hatest-%d", id), "parent_id": nil, "user_id": nil},
+	}
+	if ok {
+		response, err := handle(msg)
+		if err != nil {
+			_ = c.write(map[string]any{"id": id, "type": "result", "success": false,
+				"error": map[string]any{"code": "home_assistant_error", "message": err.Error()}})
+			return
+		}
+		if wants, _ := msg["return_response"].(bool); wants {
+			result["response"] = response
+		}
+	}
+	_ = c.write(map[string]any{"id": id, "type": "result", "success": true, "result": result})
+}
+
 func (s *Server) recordCall(msg map[string]any) {
 	call := ServiceCall{}
 	call.Domain, _ = msg["domain"].(string)
@@ -409,6 +722,8 @@ func (s *Server) recordCall(msg map[string]any) {
 	}
 	if target, ok := msg["target"].(map[string]any); ok {
 		call.EntityID, _ = target["entity_id"].(string)
+		call.AreaIDs = stringList(target["area_id"])
+		call.DeviceIDs = stringList(target["device_id"])
 	}
 
 	s.mu.Lock()
@@ -416,6 +731,23 @@ func (s *Server) recordCall(msg map[string]any) {
 	s.mu.Unlock()
 }
 
+// stringList reads a target field Home Assistant accepts as one id or a list.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
 func (c *connection) write(v any) error {
 	raw, err := json.Marshal(v)
 	if err != nil {
@@ -0,0 +1,72 @@
+package hatest
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// statistic is one row of an entity's long-term statistics.
+type statistic struct {
+	start  time.Time
+	values map[string]float64
+}
+
+// AddStatistic records a row of the entity's long-term statistics, for apps
+// reading them with recorder/statistics_during_period. Values are keyed as
+// Home Assistant keys them: mean, min and max for a measurement, state, sum
+// and change for a total. The row is reported for whatever period is asked
+// for, so a test adds rows as far apart as the period it asks about.
+func (s *Server) AddStatistic(entityID string, start time.Time, values map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statistics[entityID] = append(s.statistics[entityID], statistic{start: start, values: maps.Clone(values)})
+	slices.SortStableFunc(s.statistics[entityID], func(a, b statistic) int { return a.start.Compare(b.start) })
+}
+
+// statisticsDuringPeriod stands in for recorder/statistics_during_period: for
+// each entity asked for that has any, the rows starting within the span, their
+// times in milliseconds as Home Assistant sends them.
+func (s *Server) statisticsDuringPeriod(msg map[string]any) (any, error) {
+	start, _ := time.Parse(time.RFC3339, stringOf(msg["start_time"]))
+	end, err := time.Parse(time.RFC3339, stringOf(msg["end_time"]))
+	if err != nil {
+		end = time.Now()
+	}
+	length := map[string]time.Duration{
+		"5minute": 5 * time.Minute, "hour": time.Hour, "day": 24 * time.Hour, "week": 7 * 24 * time.Hour,
+	}[stringOf(msg["period"])]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := map[string]any{}
+	ids, _ := msg["statistic_ids"].([]any)
+	for _, id := range ids {
+		var rows []map[string]any
+		for _, st := range s.statistics[stringOf(id)] {
+			if st.start.Before(start) || !st.start.Before(end) {
+				continue
+			}
+			stEnd := st.start.Add(length)
+			if length == 0 {
+				stEnd = st.start.AddDate(0, 1, 0)
+			}
+			row := map[string]any{"start": st.start.UnixMilli(), "end": stEnd.UnixMilli()}
+			for k, v := range st.values {
+				row[k] = v
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) > 0 {
+			out[stringOf(id)] = rows
+		}
+	}
+	return out, nil
+}
+
+func stringOf(v any) string {
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,107 @@
+package hatest
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// TemplateRenderer stands in for Home Assistant's template engine: it is given
+// a template's source and returns what it renders to, or an error to refuse it
+// with as a template error.
+type TemplateRenderer func(template string) (result any, err error)
+
+// renderedTemplate is one live render_template subscription.
+type renderedTemplate struct {
+	source string
+	last   any
+}
+
+// HandleTemplate renders every template with fn. A template is rendered when
+// it is subscribed to and again after each state change, and its subscriber
+// hears of the result whenever it differs from the last, as Home Assistant
+// re-renders a template when the entities it reads change. Without a renderer,
+// a template renders to its own source, as plain text does.
+func (s *Server) HandleTemplate(fn TemplateRenderer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.templates = fn
+}
+
+func (s *Server) render(source string) (any, error) {
+	s.mu.Lock()
+	fn := s.templates
+	s.mu.Unlock()
+
+	if fn == nil {
+		return source, nil
+	}
+	return fn(source)
+}
+
+// subscribeTemplate answers a render_template command: the result, then the
+// first rendering as an event.
+func (s *Server) subscribeTemplate(c *connection, id int64, msg map[string]any) {
+	source, _ := msg["template"].(string)
+	result, err := s.render(source)
+	if err != nil {
+		_ = c.write(map[string]any{"id": id, "type": "result", "success": false,
+			"error": map[string]any{"code": "template_error", "message": err.Error()}})
+		return
+	}
+
+	c.mu.Lock()
+	c.templates[id] = &renderedTemplate{source: source, last: result}
+	c.mu.Unlock()
+
+	_ = c.write(map[string]any{"id": id, "type": "result", "success": true})
+	_ = c.write(map[string]any{"id": id, "type": "event", "event": templateEvent(result)})
+}
+
+// rerender renders each live template again and sends those whose result
+// changed.
+func (s *Server) rerender(conns []*connection) {
+	for _, c := range conns {
+		c.mu.Lock()
+		live := make(map[int64]*renderedTemplate, len(c.templates))
+		for id, t := range c.templates {
+			live[id] = t
+		}
+		c.mu.Unlock()
+
+		for id, t := range live {
+			result, err := s.render(t.source)
+			if err != nil {
+				_ = c.write(map[string]any{"id": id, "type": "event",
+					"event": map[string]any{"error": err.Error(), "level": "ERROR"}})
+				continue
+			}
+			c.mu.Lock()
+			changed := !reflect.DeepEqual(normalise(result), normalise(t.last))
+			t.last = result
+			c.mu.Unlock()
+			if !changed {
+				continue
+			}
+			_ = c.write(map[string]any{"id": id, "type": "event", "event": templateEvent(result)})
+		}
+	}
+}
+
+func templateEvent(result any) map[string]any {
+	return map[string]any{
+		"result":    result,
+		"listeners": map[string]any{"all": false, "entities": []string{}, "domains": []string{}, "time": false},
+	}
+}
+
+// normalise puts a result in the form it takes on the wire, so an int and the
+// float it decodes to compare equal.
+func normalise(v any) any {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
@@ -0,0 +1,112 @@
+package hatest
+
+import (
+	"encoding/json"
+	"maps"
+)
+
+// CallWebhook makes a request to a webhook, as an external system would, with
+// body sent as JSON. Every subscribed webhook trigger with that id fires. It
+// reports whether any did, since Home Assistant answers a request to a webhook
+// nobody holds all the same.
+func (s *Server) CallWebhook(webhookID string, body any) bool {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		if s.t != nil {
+			s.t.Fatalf("hatest: encoding webhook body: %v", err)
+		}
+		return false
+	}
+	return s.fireTriggers(func(config map[string]any) (map[string]any, bool) {
+		if config["platform"] != "webhook" || config["webhook_id"] != webhookID {
+			return nil, false
+		}
+		return map[string]any{
+			"platform":    "webhook",
+			"webhook_id":  webhookID,
+			"json":        json.RawMessage(raw),
+			"query":       map[string]any{},
+			"description": "webhook",
+		}, true
+	})
+}
+
+// FireTrigger fires every subscribed trigger of the platform, as Home
+// Assistant's trigger engine would, reporting variables as its trigger
+// variables alongside the platform's name. It reports whether any fired.
+//
+// Nothing here evaluates a trigger; the test decides when one fires, and
+// Triggers shows what was asked for.
+func (s *Server) FireTrigger(platform string, variables map[string]any) bool {
+	return s.fireTriggers(func(config map[string]any) (map[string]any, bool) {
+		if config["platform"] != platform {
+			return nil, false
+		}
+		vars := map[string]any{"platform": platform}
+		maps.Copy(vars, variables)
+		return vars, true
+	})
+}
+
+// Triggers lists the config of every trigger subscribed to, across every
+// connection.
+func (s *Server) Triggers() []map[string]any {
+	s.mu.Lock()
+	conns := make([]*connection, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	var out []map[string]any
+	for _, c := range conns {
+		c.mu.Lock()
+		for _, config := range c.triggers {
+			out = append(out, config)
+		}
+		c.mu.Unlock()
+	}
+	return out
+}
+
+// fireTriggers fires every subscribed trigger match accepts, with the trigger
+// variables it returns.
+func (s *Server) fireTriggers(match func(config map[string]any) (map[string]any, bool)) bool {
+	s.mu.Lock()
+	conns := make([]*connection, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	fired := false
+	for _, c := range conns {
+		c.mu.Lock()
+		vars := map[int64]map[string]any{}
+		for id, config := range c.triggers {
+			if v, ok := match(config); ok {
+				vars[id] = v
+			}
+		}
+		c.mu.Unlock()
+
+		for id, v := range vars {
+			fired = true
+			_ = c.write(map[string]any{"id": id, "type": "event", "event": map[string]any{
+				"variables": map[string]any{"trigger": v},
+				"context":   map[string]any{"id": "01HATESTTRIGGER", "parent_id": nil, "user_id": nil},
+			}})
+		}
+	}
+	return fired
+}
+
+// subscribeTrigger answers subscribe_trigger, remembering the trigger's config
+// for whatever fires it.
+func (s *Server) subscribeTrigger(c *connection, id int64, msg map[string]any) {
+	config, _ := msg["trigger"].(map[string]any)
+	c.mu.Lock()
+	c.triggers[id] = config
+	c.mu.Unlock()
+	_ = c.write(map[string]any{"id": id, "type": "result", "success": true})
+}
@@ -0,0 +1,46 @@
+package ha_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestHeartbeatIsOnWhileRunningAndOffAfterClose(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	require.NoError(t, app.WithHeartbeat("binary_sensor.go_ha_alive", 50*time.Millisecond))
+	start(t, app)
+
+	state, attrs, ok := server.State("binary_sensor.go_ha_alive")
+	require.True(t, ok, "the first beat is at startup")
+	assert.Equal(t, "on", state)
+	assert.Equal(t, "connectivity", attrs["device_class"])
+	first := attrs["expires_at"]
+
+	lastSeen, err := time.Parse(time.RFC3339, attrs["last_seen"].(string))
+	require.NoError(t, err)
+	expires, err := time.Parse(time.RFC3339, first.(string))
+	require.NoError(t, err)
+	assert.False(t, expires.Before(lastSeen))
+
+	require.NoError(t, app.Close())
+	state, _, _ = server.State("binary_sensor.go_ha_alive")
+	assert.Equal(t, "off", state, "a clean stop is not a death")
+}
+
+func TestHeartbeatChecksItsArguments(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	assert.ErrorIs(t, app.WithHeartbeat("sensor.alive", time.Minute), ha.ErrInvalidArgs)
+	assert.ErrorIs(t, app.WithHeartbeat("binary_sensor.alive", 0), ha.ErrInvalidArgs)
+	require.NoError(t, app.WithHeartbeat("binary_sensor.alive", time.Minute))
+	assert.ErrorIs(t, app.WithHeartbeat("binary_sensor.other", time.Minute), ha.ErrInvalidArgs, "one heartbeat per app")
+}
@@ -0,0 +1,44 @@
+package ha_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestHistoryReadsThePastStates(t *testing.T) {
+	server := hatest.New(t)
+	now := time.Now().Truncate(time.Second)
+	server.AddHistory("binary_sensor.front_door", "off", now.Add(-48*time.Hour))
+	server.AddHistory("binary_sensor.front_door", "on", now.Add(-3*time.Hour), map[string]any{"device_class": "door"})
+	server.AddHistory("binary_sensor.front_door", "off", now.Add(-3*time.Hour+time.Minute))
+
+	app := newApp(t, server)
+
+	states, err := app.History("binary_sensor.front_door", now.Add(-24*time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, states, 3)
+
+	assert.Equal(t, "off", states[0].State)
+	assert.True(t, states[0].LastChanged.Equal(now.Add(-24*time.Hour)), "the state at the start begins there")
+	assert.True(t, states[0].Until.Equal(now.Add(-3*time.Hour)))
+
+	assert.Equal(t, "on", states[1].State)
+	assert.Equal(t, "door", states[1].Attributes["device_class"])
+	assert.Equal(t, time.Minute, states[1].Until.Sub(states[1].LastChanged))
+
+	assert.Equal(t, "binary_sensor.front_door", states[2].EntityID)
+	assert.False(t, states[2].Until.After(now), "the last state runs to the end of the span")
+
+	none, err := app.History("binary_sensor.back_door", now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	_, err = app.History("binary_sensor.front_door", now, now.Add(-time.Hour))
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
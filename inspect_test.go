@@ -0,0 +1,62 @@
+package ha_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestInspectListsWhatIsRegistered(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.porch_motion", "off")
+	app := newApp(t, server)
+
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("porch lights").
+			On(ha.StateChanged("binary_sensor.porch_motion").To("on")).
+			Mode(ha.ModeRestart).
+			Do(func(context.Context, ha.Run) error { return errors.New("bulb missing") }).
+			MustBuild(),
+		ha.NewAutomation("morning").
+			On(ha.Daily(ha.TimeOfDay(7, 0))).
+			Do(func(context.Context, ha.Run) error { return nil }).
+			MustBuild(),
+	))
+
+	before := app.Inspect()
+	require.Len(t, before.Automations, 2)
+	assert.Equal(t, "morning", before.Automations[0].Name, "sorted by name")
+	porch := before.Automations[1]
+	assert.Equal(t, "porch lights", porch.Name)
+	assert.Equal(t, ha.ModeRestart, porch.Mode)
+	assert.True(t, porch.LastRun.IsZero(), "nothing has run yet")
+
+	require.Len(t, before.Schedules, 1)
+	assert.Equal(t, "morning", before.Schedules[0].Automation)
+	assert.Equal(t, 7, before.Schedules[0].NextRun.Hour())
+	assert.True(t, before.Schedules[0].NextRun.After(time.Now()))
+
+	require.Len(t, before.Listeners, 1)
+	assert.Equal(t, "porch lights", before.Listeners[0].Automation)
+	assert.Equal(t, []string{"binary_sensor.porch_motion"}, before.Listeners[0].Entities)
+	assert.Equal(t, []string{"state_changed"}, before.Listeners[0].EventTypes)
+
+	start(t, app)
+	server.ChangeState("binary_sensor.porch_motion", "on")
+
+	var after ha.AutomationInfo
+	require.Eventually(t, func() bool {
+		after = app.Inspect().Automations[1]
+		return after.LastError != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, after.LastRun.IsZero())
+	assert.EqualError(t, after.LastError, "bulb missing")
+	assert.False(t, after.LastErrorAt.Before(after.LastRun))
+}
@@ -2,6 +2,7 @@ package ha_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,26 +22,14 @@ func newApp(t *testing.T, server *hatest.Server) *ha.App {
 
 func newAppWithClock(t *testing.T, server *hatest.Server, clock types.Clock) *ha.App {
 	t.Helper()
-
-	app, err := ha.NewApp(types.NewAppRequest{
-		URL:         server.URL(),
-		HAAuthToken: hatest.Token,
-		Clock:       clock,
-	})
-	require.NoError(t, err)
-	t.Cleanup(func() { _ = app.Close() })
-
-	return app
+	return hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.Clock = clock })
 }
 
 // start runs the app and waits for it to be live, since automations are gated
 // until it is.
 func start(t *testing.T, app *ha.App) {
 	t.Helper()
-
-	go func() { _ = app.Start() }()
-	t.Cleanup(func() { _ = app.Close() })
-	time.Sleep(100 * time.Millisecond)
+	hatest.StartApp(t, app)
 }
 
 func TestAutomationRunsAgainstAFakeHomeAssistant(t *testing.T) {
@@ -99,6 +88,67 @@ func TestConditionsReadSeededState(t *testing.T) {
 	server.WaitForCalls(1)
 }
 
+// A condition reading an entity Home Assistant does not have cannot decide. The
+// run is skipped, and the failure is counted rather than passing silently.
+func TestUnevaluableConditionsAreCounted(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.motion", "off")
+
+	app := newApp(t, server)
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("hall light").
+			On(ha.StateChanged("binary_sensor.motion").To("on")).
+			When(ha.StateIs("light.missing", "off")).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn("light.hall")
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+	assert.Empty(t, app.ConditionErrors())
+
+	server.ChangeState("binary_sensor.motion", "on")
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Empty(t, server.Calls())
+	assert.Equal(t, map[string]uint64{"hall light": 1}, app.ConditionErrors())
+}
+
+// A state_changed whose payload does not fit the protocol is counted and handed
+// to the dead-letter hook, never dispatched with an empty entity.
+func TestUndecodableEventsGoToTheHook(t *testing.T) {
+	server := hatest.New(t)
+
+	dead := make(chan error, 1)
+	app, err := ha.NewApp(types.NewAppRequest{
+		URL:           server.URL(),
+		HAAuthToken:   hatest.Token,
+		OnUndecodable: func(_ []byte, err error) { dead <- err },
+	})
+	require.NoError(t, err)
+
+	fired := make(chan struct{}, 1)
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("anything").
+			On(ha.StateChanged[string]()).
+			Do(func(context.Context, ha.Run) error { fired <- struct{}{}; return nil }).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.Fire("state_changed", map[string]any{"entity_id": []string{"light.a", "light.b"}})
+	select {
+	case err := <-dead:
+		assert.ErrorIs(t, err, ha.ErrMalformedEvent)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the hook was not called")
+	}
+	assert.Equal(t, uint64(1), app.ConnectionStats().Undecodable)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, fired, "no automation sees a malformed event")
+}
+
 func TestSunTriggerReadsTheServersTimes(t *testing.T) {
 	server := hatest.New(t)
 	server.SetSun(true, time.Now().Add(12*time.Hour), time.Now().Add(300*time.Millisecond))
@@ -164,6 +214,42 @@ func TestEventTriggerFiresOnACustomEvent(t *testing.T) {
 	server.WaitForCalls(1)
 }
 
+// A pattern subscribes to every event. Each event must still reach each
+// automation once, however many subscriptions deliver it.
+func TestEventPatternFiresOncePerEvent(t *testing.T) {
+	server := hatest.New(t)
+
+	app := newApp(t, server)
+	var mu sync.Mutex
+	var seen []string
+	record := func(name string) ha.Action {
+		return func(_ context.Context, run ha.Run) error {
+			mu.Lock()
+			seen = append(seen, name+" "+run.Event.Type)
+			mu.Unlock()
+			return nil
+		}
+	}
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("z-wave").On(ha.EventFired("zwave_js_*")).Mode(ha.ModeParallel).Do(record("pattern")).MustBuild(),
+		ha.NewAutomation("notification").On(ha.EventFired("zwave_js_notification")).Do(record("named")).MustBuild(),
+	))
+	start(t, app)
+
+	server.Fire("zwave_js_notification", nil)
+	server.Fire("zwave_js_value_notification", nil)
+	server.Fire("zha_event", nil)
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{
+		"pattern zwave_js_notification",
+		"pattern zwave_js_value_notification",
+		"named zwave_js_notification",
+	}, seen)
+}
+
 // Throttle windows are measured against the injected clock, so a test can step
 // past one instead of sleeping through it. Without injection none of this
 // behaviour was observable from outside the module.
@@ -202,6 +288,114 @@ func TestInjectedClockDrivesTheThrottleWindow(t *testing.T) {
 	server.WaitForCalls(2)
 }
 
+// The schedulers sleep on an injected clock that can be slept on, so a daily
+// schedule fires when the test steps the clock to it.
+func TestInjectedClockFiresSchedules(t *testing.T) {
+	server := hatest.New(t)
+	clock := hatest.NewClock(time.Date(2026, 3, 1, 6, 0, 0, 0, time.Local))
+	app := newAppWithClock(t, server, clock)
+
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("morning").
+			On(ha.Daily(ha.TimeOfDay(7, 30))).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn("light.kitchen")
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+	require.True(t, clock.WaitForSleepers(2))
+
+	clock.Advance(89 * time.Minute)
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, server.Calls(), "a minute early")
+
+	clock.Advance(time.Minute)
+	server.WaitForCalls(1)
+
+	// The next run is tomorrow's.
+	require.True(t, clock.WaitForSleepers(2))
+	clock.Advance(24 * time.Hour)
+	server.WaitForCalls(2)
+}
+
+// A workday sensor only knows today, so the run is called off when it falls due.
+func TestWorkdaySensorCallsOffARunOnADayOff(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState(ha.DefaultWorkdaySensor, "off")
+	// 2026-03-02 is a Monday, here a holiday.
+	clock := hatest.NewClock(time.Date(2026, 3, 2, 6, 0, 0, 0, time.Local))
+	app := newAppWithClock(t, server, clock)
+
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("wake up").
+			On(ha.Daily(ha.TimeOfDay(7, 0)).OnWorkdays()).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn("light.bedroom")
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+	require.True(t, clock.WaitForSleepers(2))
+
+	clock.Advance(time.Hour)
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, server.Calls(), "no alarm on a holiday")
+
+	server.ChangeState(ha.DefaultWorkdaySensor, "on")
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, clock.WaitForSleepers(2))
+	clock.Advance(24 * time.Hour)
+	server.WaitForCalls(1)
+}
+
+// A container's clock reads UTC, and the household's morning is somewhere else.
+func TestTimezoneMovesDailySchedules(t *testing.T) {
+	server := hatest.New(t)
+	clock := hatest.NewClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) {
+		r.Clock = clock
+		r.Timezone = "America/Chicago"
+	})
+
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("morning").
+			On(ha.Daily(ha.TimeOfDay(7, 30))).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn("light.kitchen")
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+	require.True(t, clock.WaitForSleepers(2))
+
+	// 07:30 in Chicago is 13:30 UTC.
+	clock.Advance(89 * time.Minute)
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, server.Calls(), "a minute early")
+
+	clock.Advance(time.Minute)
+	server.WaitForCalls(1)
+	assert.Equal(t, "America/Chicago", app.Clock().Now().Location().String())
+}
+
+func TestTimezoneFromHomeAssistant(t *testing.T) {
+	server := hatest.New(t)
+	chicago, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+	clock := hatest.NewClock(time.Date(2026, 3, 1, 6, 0, 0, 0, chicago))
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) {
+		r.Clock = clock
+		r.Timezone = ha.HomeTimezone
+	})
+
+	// The test server is configured for UTC.
+	assert.Equal(t, "UTC", app.Clock().Now().Location().String())
+
+	_, err = ha.NewApp(types.NewAppRequest{URL: server.URL(), HAAuthToken: hatest.Token, Timezone: "Mars/Olympus_Mons"})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
+
 func TestAppRefusesABadToken(t *testing.T) {
 	server := hatest.New(t)
 
@@ -211,3 +405,26 @@ func TestAppRefusesABadToken(t *testing.T) {
 	})
 	assert.Error(t, err, "a refused token must surface rather than retry silently")
 }
+
+func TestCachedAndFreshState(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("sensor.outside", "12")
+	app := newApp(t, server)
+	start(t, app)
+
+	es, ok := app.CachedState("sensor.outside")
+	require.True(t, ok)
+	assert.Equal(t, "12", es.State)
+
+	_, ok = app.CachedState("sensor.nowhere")
+	assert.False(t, ok, "the cache never falls through to the network")
+
+	// Changed behind the event stream's back, so only a fresh read sees it.
+	server.SetState("sensor.outside", "13")
+	fresh, err := app.FreshState("sensor.outside")
+	require.NoError(t, err)
+	assert.Equal(t, "13", fresh.State)
+
+	cached, _ := app.CachedState("sensor.outside")
+	assert.Equal(t, "12", cached.State)
+}
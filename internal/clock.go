@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"slices"
 	"sync"
 	"time"
 )
@@ -18,11 +19,24 @@ func (RealClock) Now() time.Time {
 	return time.Now()
 }
 
+// Timer waits on a real timer.
+func (RealClock) Timer(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTimer(d)
+	return t.C, func() { t.Stop() }
+}
+
 // FakeClock reports a fixed instant until moved by Set or Advance. Callbacks run
 // on their own goroutines and read the clock freely, so access is guarded.
 type FakeClock struct {
-	mutex sync.RWMutex
-	now   time.Time
+	mutex   sync.RWMutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a Timer still waiting for the clock to reach at.
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
 }
 
 // NewFakeClock returns a FakeClock pinned to the given instant.
@@ -36,16 +50,65 @@ func (c *FakeClock) Now() time.Time {
 	return c.now
 }
 
-// Set replaces the current instant.
+// Timer delivers once Set or Advance moves the clock d or more past the
+// current instant. Nothing fires on its own: a waiter is only released when
+// the clock is moved.
+func (c *FakeClock) Timer(d time.Duration) (<-chan time.Time, func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch, func() {}
+	}
+	w := &fakeWaiter{at: c.now.Add(d), ch: ch}
+	c.waiters = append(c.waiters, w)
+	return ch, func() { c.stop(w) }
+}
+
+func (c *FakeClock) stop(w *fakeWaiter) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.waiters = slices.DeleteFunc(c.waiters, func(other *fakeWaiter) bool { return other == w })
+}
+
+// Waiters reports how many timers are still waiting, so a test can hold off
+// advancing until whatever it drives has gone to sleep.
+func (c *FakeClock) Waiters() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.waiters)
+}
+
+// Set replaces the current instant, releasing any waiter now due.
 func (c *FakeClock) Set(now time.Time) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	c.now = now
+	c.releaseLocked()
 }
 
-// Advance moves the clock forward by d. Negative durations move it back.
+// Advance moves the clock forward by d, releasing any waiter now due. Negative
+// durations move it back.
 func (c *FakeClock) Advance(d time.Duration) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	c.now = c.now.Add(d)
+	c.releaseLocked()
+}
+
+// releaseLocked delivers to every waiter whose time has come. Each channel is
+// buffered for the one send, so an abandoned waiter never blocks the clock.
+func (c *FakeClock) releaseLocked() {
+	kept := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			kept = append(kept, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	clear(c.waiters[len(kept):])
+	c.waiters = kept
 }
@@ -72,3 +72,40 @@ func TestFakeClockIsSafeUnderConcurrentReads(t *testing.T) {
 
 	assert.Equal(t, clockBase.Add(100*time.Second), c.Now())
 }
+
+func TestFakeClockTimerFiresOnlyWhenMoved(t *testing.T) {
+	c := NewFakeClock(clockBase)
+	fired, _ := c.Timer(time.Hour)
+
+	select {
+	case <-fired:
+		t.Fatal("fired with the clock standing still")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	c.Advance(59 * time.Minute)
+	assert.Empty(t, fired, "not due yet")
+
+	c.Advance(time.Minute)
+	select {
+	case at := <-fired:
+		assert.Equal(t, clockBase.Add(time.Hour), at)
+	default:
+		t.Fatal("due timer was not released")
+	}
+	assert.Zero(t, c.Waiters())
+}
+
+func TestFakeClockTimerStop(t *testing.T) {
+	c := NewFakeClock(clockBase)
+	fired, stop := c.Timer(time.Minute)
+	assert.Equal(t, 1, c.Waiters())
+
+	stop()
+	assert.Zero(t, c.Waiters())
+	c.Advance(time.Hour)
+	assert.Empty(t, fired, "a stopped timer never fires")
+
+	now, _ := c.Timer(0)
+	assert.Len(t, now, 1, "a timer for no time at all fires at once")
+}
@@ -11,6 +11,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/Xevion/go-ha/internal"
 )
 
 // Options tunes the connection layer. The zero value is not usable; start from
@@ -24,6 +26,7 @@ type Options struct {
 
 	// Workers is the number of goroutines draining the queue. Handlers run on
 	// these, so a slow handler costs a worker rather than the connection.
+	// Events for one entity are still handled one at a time, in wire order.
 	Workers int
 
 	// PingInterval is how often liveness is checked once the connection is idle.
@@ -37,9 +40,16 @@ type Options struct {
 	// handshake.
 	DialTimeout time.Duration
 
-	// WriteTimeout bounds a single outgoing message.
+	// WriteTimeout bounds a single outgoing message. A write that overruns it
+	// means the socket has stopped draining, and the connection is dropped
+	// and re-established rather than left to wedge every later send.
 	WriteTimeout time.Duration
 
+	// ReadTimeout bounds how long the connection may go without receiving
+	// anything. Pings keep a healthy connection talking, so silence past this
+	// means the peer is gone. Defaults to PingInterval plus PingTimeout.
+	ReadTimeout time.Duration
+
 	// HealthyAfter is how long a connection must survive before the backoff
 	// sequence resets. Without it a connection that dies immediately after each
 	// handshake would retry at the base delay forever.
@@ -50,14 +60,24 @@ type Options struct {
 	// reader cannot wait on it without backing the socket up.
 	OnConnected func()
 
-	// OnEvent, if set, is called for every event message in the order it
-	// arrives off the wire, on the reader goroutine, before the message is
-	// queued for its handler. Handlers run concurrently on the worker pool, so
-	// their order is not the wire order; this hook is where ordered state, such
-	// as a cache the handlers read, is maintained. It must not block, for the
-	// same reason the reader must not: a stalled reader stops draining the
-	// socket and Home Assistant hangs up.
+	// OnEvent, if set, is called for every event message of an event
+	// subscription in the order it arrives off the wire, on the reader
+	// goroutine, before the message is queued for its handler. Handlers for
+	// different entities run concurrently on the worker pool, so across
+	// entities their order is not the wire order; this hook is where ordered
+	// state, such as a cache the handlers read, is maintained. It must not
+	// block, for the same reason the reader must not: a stalled reader stops
+	// draining the socket and Home Assistant hangs up.
 	OnEvent func(Message)
+
+	// OnUndecodable, if set, is given each message whose envelope could not be
+	// decoded, in place of the warning otherwise logged. It runs on the reader
+	// goroutine and must not block.
+	OnUndecodable func(raw []byte, err error)
+
+	// Logger receives everything the client logs. Defaults to slog's default
+	// logger.
+	Logger *slog.Logger
 }
 
 // DefaultOptions returns the settings used when none are supplied.
@@ -96,6 +116,13 @@ func (o Options) withDefaults() Options {
 	if o.HealthyAfter <= 0 {
 		o.HealthyAfter = d.HealthyAfter
 	}
+	if o.ReadTimeout <= 0 {
+		// Derived after the ping settings, so it tracks a custom interval.
+		o.ReadTimeout = o.PingInterval + o.PingTimeout
+	}
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
 	return o
 }
 
@@ -134,6 +161,21 @@ type Client struct {
 	events  chan Message
 	dropped atomic.Uint64
 
+	// writeStall accumulates the time, in nanoseconds, spent in writes slow
+	// enough to count as stalled. writeTimeouts counts those that gave up.
+	writeStall    atomic.Int64
+	writeTimeouts atomic.Uint64
+
+	// orderMu guards busy and held, which keep one entity's events in order
+	// across the worker pool.
+	orderMu sync.Mutex
+	// busy maps an entity with an event queued or being handled to the events
+	// for it that arrived meanwhile. The worker handling it drains them in
+	// turn, so no other worker can overtake it with a later transition.
+	busy map[string][]Message
+	// held counts the events parked in busy.
+	held int
+
 	wg sync.WaitGroup
 }
 
@@ -157,6 +199,7 @@ func newClientWithDialer(dial dialer, token string, opts Options) *Client {
 		pending: map[int64]func(Message){},
 		routes:  map[int64]*subscription{},
 		events:  make(chan Message, opts.QueueSize),
+		busy:    map[string][]Message{},
 		gen:     1,
 	}
 }
@@ -221,6 +264,19 @@ func (c *Client) Dropped() uint64 {
 	return c.dropped.Load()
 }
 
+// WriteStall reports the total time outgoing messages have spent blocked on a
+// slow socket. Writes quicker than a tenth of a second are not counted, so on a
+// healthy connection it stays at zero.
+func (c *Client) WriteStall() time.Duration {
+	return time.Duration(c.writeStall.Load())
+}
+
+// WriteTimeouts reports how many writes overran WriteTimeout, each of which
+// cost the connection.
+func (c *Client) WriteTimeouts() uint64 {
+	return c.writeTimeouts.Load()
+}
+
 // Done is closed once the client has stopped for good, whether because it was
 // closed or because reconnection was abandoned.
 //
@@ -285,12 +341,31 @@ func (c *Client) authenticate(ctx context.Context, conn transport) error {
 	case typeAuthOK:
 		return nil
 	case typeAuthInvalid:
-		return ErrAuthFailed
+		return c.authError(msg)
 	default:
 		return fmt.Errorf("expected %s or %s, got %s", typeAuthOK, typeAuthInvalid, msg.Type)
 	}
 }
 
+// authError says why a token was refused, as far as Home Assistant and the
+// token itself tell. Home Assistant's own message is always the same generic
+// one, so an expired or mistyped token is spelled out where it can be.
+func (c *Client) authError(msg Message) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(msg.Raw, &body)
+
+	err := ErrAuthFailed
+	if body.Message != "" {
+		err = fmt.Errorf("%w: %s", err, body.Message)
+	}
+	if hint := internal.TokenHint(c.token, time.Now()); hint != "" {
+		err = fmt.Errorf("%w; %s", err, hint)
+	}
+	return err
+}
+
 func readOne(ctx context.Context, conn transport) (Message, error) {
 	raw, err := conn.Read(ctx)
 	if err != nil {
@@ -329,7 +404,7 @@ func (c *Client) run(conn transport) {
 		if c.ctx.Err() != nil {
 			return
 		}
-		slog.Warn("Home Assistant connection lost, reconnecting", "err", err)
+		c.opts.Logger.Warn("Home Assistant connection lost, reconnecting", "err", err)
 
 		if time.Since(start) >= c.opts.HealthyAfter {
 			// The connection worked for a while, so this is a fresh outage
@@ -363,7 +438,7 @@ func (c *Client) announceConnected() {
 func (c *Client) reconnect() (transport, bool) {
 	for {
 		delay := c.backoff.next()
-		slog.Info("Reconnecting to Home Assistant", "in", delay)
+		c.opts.Logger.Info("Reconnecting to Home Assistant", "in", delay)
 
 		timer := time.NewTimer(delay)
 		select {
@@ -375,7 +450,7 @@ func (c *Client) reconnect() (transport, bool) {
 
 		conn, err := c.connectOnce(c.ctx)
 		if err == nil {
-			slog.Info("Reconnected to Home Assistant")
+			c.opts.Logger.Info("Reconnected to Home Assistant")
 			c.setConn(conn)
 			return conn, true
 		}
@@ -383,31 +458,35 @@ func (c *Client) reconnect() (transport, bool) {
 		if errors.Is(err, ErrAuthFailed) {
 			// Retrying a refused token only produces the same answer more
 			// slowly, and hides the real problem behind reconnect noise.
-			slog.Error("Home Assistant refused the access token, giving up", "err", err)
+			c.opts.Logger.Error("Home Assistant refused the access token, giving up", "err", err)
 			c.cancel()
 			return nil, false
 		}
 		if c.ctx.Err() != nil {
 			return nil, false
 		}
-		slog.Warn("Reconnect attempt failed", "err", err)
+		c.opts.Logger.Warn("Reconnect attempt failed", "err", err)
 	}
 }
 
 // readLoop consumes messages until the connection fails. It returns the error
 // that ended it.
 func (c *Client) readLoop(ctx context.Context, conn transport) error {
-	reporter := dropReporter{}
+	reporter := dropReporter{log: c.opts.Logger}
 
 	for {
-		raw, err := conn.Read(ctx)
+		raw, err := c.readOne(ctx, conn)
 		if err != nil {
 			return err
 		}
 
 		msg, err := parseMessage(raw)
 		if err != nil {
-			slog.Warn("Discarding undecodable message", "err", err)
+			if c.opts.OnUndecodable != nil {
+				c.opts.OnUndecodable(raw, err)
+			} else {
+				c.opts.Logger.Warn("Discarding undecodable message", "err", err)
+			}
 			continue
 		}
 
@@ -415,6 +494,20 @@ func (c *Client) readLoop(ctx context.Context, conn transport) error {
 	}
 }
 
+// readOne waits for the next message, giving up after ReadTimeout of silence.
+// Without a bound a half-open TCP connection, which never errors, would leave
+// the reader waiting forever.
+func (c *Client) readOne(ctx context.Context, conn transport) ([]byte, error) {
+	readCtx, cancel := context.WithTimeout(ctx, c.opts.ReadTimeout)
+	defer cancel()
+
+	raw, err := conn.Read(readCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(readCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("nothing received for %s: %w", c.opts.ReadTimeout, err)
+	}
+	return raw, err
+}
+
 // route hands a message to whoever is waiting for it. It must never block: a
 // reader that stalls stops handling control frames, and Home Assistant hangs up
 // on a client whose messages back up for five seconds.
@@ -425,7 +518,15 @@ func (c *Client) route(msg Message, reporter *dropReporter) {
 	}
 
 	if msg.Type != typeEvent {
-		slog.Debug("Ignoring unsolicited message", "type", msg.Type, "id", msg.ID)
+		c.opts.Logger.Debug("Ignoring unsolicited message", "type", msg.Type, "id", msg.ID)
+		return
+	}
+
+	c.mu.Lock()
+	sub, ok := c.routes[msg.ID]
+	c.mu.Unlock()
+	if ok && sub.sub.Apply != nil {
+		sub.sub.Apply(msg)
 		return
 	}
 
@@ -433,10 +534,18 @@ func (c *Client) route(msg Message, reporter *dropReporter) {
 	// before the workers dispatch out of order. A dropped event was still
 	// applied, which is correct: the cache should reflect it even when the
 	// backlog means no handler runs for it.
-	if c.opts.OnEvent != nil {
+	//
+	// A command's stream is passed over: its messages are not Home Assistant
+	// events, and are its handler's alone to read.
+	if c.opts.OnEvent != nil && (!ok || sub.sub.Command == nil) {
 		c.opts.OnEvent(msg)
 	}
 
+	if msg.entityID != "" {
+		c.routeInOrder(msg, reporter)
+		return
+	}
+
 	select {
 	case c.events <- msg:
 	default:
@@ -445,6 +554,36 @@ func (c *Client) route(msg Message, reporter *dropReporter) {
 	}
 }
 
+// routeInOrder queues an event that concerns one entity. At most one event per
+// entity is ever on the queue or in a handler; the rest wait behind it and the
+// worker that handles it takes them next, in the order they arrived.
+//
+// This has to be decided here, on the reader. Two workers each holding an
+// event for the same entity can reach any later check in either order.
+func (c *Client) routeInOrder(msg Message, reporter *dropReporter) {
+	c.orderMu.Lock()
+	defer c.orderMu.Unlock()
+
+	// Parked events still count against the queue, or one slow handler would
+	// let its entity's backlog grow without bound.
+	if len(c.events)+c.held >= cap(c.events) {
+		c.dropped.Add(1)
+		reporter.record(len(c.events) + c.held)
+		return
+	}
+
+	if backlog, busy := c.busy[msg.entityID]; busy {
+		c.busy[msg.entityID] = append(backlog, msg)
+		c.held++
+		return
+	}
+
+	// Cannot block: the check above left room, and the reader is the only
+	// sender.
+	c.busy[msg.entityID] = nil
+	c.events <- msg
+}
+
 // deliverResult completes the request this message answers.
 func (c *Client) deliverResult(msg Message) {
 	c.mu.Lock()
@@ -455,7 +594,7 @@ func (c *Client) deliverResult(msg Message) {
 	c.mu.Unlock()
 
 	if !ok {
-		slog.Debug("Result for an unknown request", "id", msg.ID, "type", msg.Type)
+		c.opts.Logger.Debug("Result for an unknown request", "id", msg.ID, "type", msg.Type)
 		return
 	}
 	// Called without the lock: a waiter that re-enters the client would
@@ -465,21 +604,54 @@ func (c *Client) deliverResult(msg Message) {
 
 // worker drains the event queue. Handlers run here, so blocking in one costs a
 // worker rather than the connection.
+//
+// Events for different entities run in parallel, but two for the same entity
+// never do, and never out of order: the worker that handles one takes the
+// entity's next event itself once it is done. Reordering two transitions of
+// one entity would leave a For wait armed for a state already left, or
+// throttle the newer of the two.
 func (c *Client) worker() {
 	defer c.wg.Done()
 
 	for msg := range c.events {
-		c.mu.Lock()
-		sub, ok := c.routes[msg.ID]
-		c.mu.Unlock()
-
-		if !ok {
-			// Arrives for a subscription established by a previous connection,
-			// or one cancelled while this message was queued.
+		if msg.entityID == "" {
+			c.deliver(msg)
 			continue
 		}
-		sub.handler(msg)
+		for ok := true; ok; msg, ok = c.next(msg.entityID) {
+			c.deliver(msg)
+		}
+	}
+}
+
+// next returns the following parked event for an entity this worker owns, or
+// releases the entity when there is none.
+func (c *Client) next(entityID string) (Message, bool) {
+	c.orderMu.Lock()
+	defer c.orderMu.Unlock()
+
+	backlog := c.busy[entityID]
+	if len(backlog) == 0 {
+		delete(c.busy, entityID)
+		return Message{}, false
+	}
+	c.busy[entityID] = backlog[1:]
+	c.held--
+	return backlog[0], true
+}
+
+// deliver hands an event to the subscription it belongs to.
+func (c *Client) deliver(msg Message) {
+	c.mu.Lock()
+	sub, ok := c.routes[msg.ID]
+	c.mu.Unlock()
+
+	if !ok {
+		// Arrives for a subscription established by a previous connection,
+		// or one cancelled while this message was queued.
+		return
 	}
+	sub.handler(msg)
 }
 
 // teardown closes the connection and fails everything still waiting on it.
@@ -533,7 +705,7 @@ func (c *Client) keepalive(ctx context.Context) {
 			return
 		}
 
-		slog.Warn("Ping went unanswered, dropping the connection", "err", err)
+		c.opts.Logger.Warn("Ping went unanswered, dropping the connection", "err", err)
 		c.mu.Lock()
 		conn := c.conn
 		c.mu.Unlock()
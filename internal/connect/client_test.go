@@ -44,6 +44,7 @@ func TestClientConnectRejectsBadToken(t *testing.T) {
 
 		err := c.Connect(context.Background())
 		assert.ErrorIs(t, err, ErrAuthFailed)
+		assert.ErrorContains(t, err, "not a Home Assistant access token", "the refusal says why it likely happened")
 
 		// A refused token must not leave the supervisor retrying in the
 		// background, so there is nothing left to wait on.
@@ -106,6 +107,39 @@ func TestClientIgnoresEventsForUnknownSubscriptions(t *testing.T) {
 	})
 }
 
+// A frame that is not even JSON goes to the dead-letter hook, and the reader
+// carries on with the next one.
+func TestClientHandsUndecodableFramesToTheHook(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ha := newFakeHA(t, testToken)
+
+		var mu sync.Mutex
+		var dead []string
+		c := connectedClient(t, ha, Options{OnUndecodable: func(raw []byte, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Error(t, err)
+			dead = append(dead, string(raw))
+		}})
+
+		var got atomic.Int64
+		require.NoError(t, c.Subscribe(Subscription{EventType: "state_changed"}, func(Message) {
+			got.Add(1)
+		}))
+		synctest.Wait()
+
+		conn := ha.current()
+		conn.push(`{"type":"event",`)
+		conn.emit(conn.subscriptions()[0], "state_changed")
+		synctest.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{`{"type":"event",`}, dead)
+		assert.Equal(t, int64(1), got.Load(), "the reader carries on")
+	})
+}
+
 func TestClientCallCorrelatesResult(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		ha := newFakeHA(t, testToken)
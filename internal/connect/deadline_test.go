@@ -0,0 +1,77 @@
+package connect
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A socket that stops draining must cost the connection, not every send queued
+// behind the one that is stuck.
+func TestClientReconnectsAfterAWriteTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ha := newFakeHA(t, testToken)
+		c := connectedClient(t, ha, Options{
+			WriteTimeout: 2 * time.Second,
+			PingInterval: time.Hour,
+		})
+		synctest.Wait()
+		ha.current().stallWritesFrom()
+
+		err := c.Send(mapRequest{"type": "fire_event", "event_type": "stuck"})
+		require.Error(t, err)
+		assert.Equal(t, uint64(1), c.WriteTimeouts())
+		assert.Equal(t, 2*time.Second, c.WriteStall())
+
+		// Let the backoff elapse and the fresh connection come up.
+		time.Sleep(5 * time.Second)
+		synctest.Wait()
+		assert.Equal(t, 2, ha.dialCount())
+
+		_, err = c.Call(context.Background(), mapRequest{"type": typePing})
+		assert.NoError(t, err, "the new connection must carry traffic again")
+	})
+}
+
+func TestClientWriteStallStartsAtZero(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ha := newFakeHA(t, testToken)
+		c := connectedClient(t, ha, Options{})
+
+		_, err := c.Call(context.Background(), mapRequest{"type": typePing})
+		require.NoError(t, err)
+
+		assert.Zero(t, c.WriteStall())
+		assert.Zero(t, c.WriteTimeouts())
+	})
+}
+
+// A half-open connection never errors. With pings too far apart to notice, the
+// read deadline is what tears it down.
+func TestClientDropsASilentConnection(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ha := newFakeHA(t, testToken)
+		connectedClient(t, ha, Options{
+			PingInterval: time.Hour,
+			ReadTimeout:  10 * time.Second,
+		})
+		synctest.Wait()
+
+		time.Sleep(9 * time.Second)
+		synctest.Wait()
+		assert.Equal(t, 1, ha.dialCount(), "quiet is not dead until the deadline passes")
+
+		time.Sleep(5 * time.Second)
+		synctest.Wait()
+		assert.Equal(t, 2, ha.dialCount())
+	})
+}
+
+func TestReadTimeoutFollowsThePingInterval(t *testing.T) {
+	opts := Options{PingInterval: 5 * time.Second, PingTimeout: 2 * time.Second}.withDefaults()
+	assert.Equal(t, 7*time.Second, opts.ReadTimeout)
+}
@@ -46,6 +46,10 @@ func (h *fakeHA) dialGaps() []time.Duration {
 	return gaps
 }
 
+// unknownCommand is a message type the fake refuses, as Home Assistant refuses
+// any command it has no handler for.
+const unknownCommand = "no_such/command"
+
 func newFakeHA(t *testing.T, token string) *fakeHA {
 	return &fakeHA{t: t, token: token}
 }
@@ -167,6 +171,9 @@ func (h *fakeHA) serve(conn *fakeConn) {
 			conn.pushf(`{"id":%d,"type":"pong"}`, req.ID)
 		case typeSubscribeEvents:
 			conn.pushf(`{"id":%d,"type":"result","success":true,"result":null}`, req.ID)
+		case unknownCommand:
+			conn.pushf(`{"id":%d,"type":"result","success":false,"error":{"code":"unknown_command","message":"Unknown command."}}`,
+				req.ID)
 		default:
 			// The marker is echoed so a test can tell whose answer this is.
 			// Matching on id alone cannot catch a correlation that hands every
@@ -188,6 +195,7 @@ type fakeConn struct {
 	mu           sync.Mutex
 	seen         []seenRequest
 	ignorePings  bool
+	stallWrites  bool
 	subscribeIDs []int64
 }
 
@@ -213,6 +221,18 @@ func (c *fakeConn) Write(ctx context.Context, data []byte) error {
 	buf := make([]byte, len(data))
 	copy(buf, data)
 
+	c.mu.Lock()
+	stalled := c.stallWrites
+	c.mu.Unlock()
+	if stalled {
+		select {
+		case <-c.closed:
+			return errors.New("write on closed fake connection")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
 	select {
 	case c.fromClient <- buf:
 		return nil
@@ -281,6 +301,17 @@ func (c *fakeConn) countOf(msgType string) int {
 	return n
 }
 
+// idsOf returns the ids of the messages of a type this connection received.
+func (c *fakeConn) idsOf(msgType string) []int64 {
+	var ids []int64
+	for _, r := range c.requests() {
+		if r.Type == msgType {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids
+}
+
 // subscriptions returns the ids of the subscribe_events requests received.
 func (c *fakeConn) subscriptions() []int64 {
 	c.mu.Lock()
@@ -296,6 +327,14 @@ func (c *fakeConn) ignorePingsFrom() {
 	c.ignorePings = true
 }
 
+// stallWritesFrom makes every later write block, as one does once the peer
+// stops reading and the TCP send buffer fills.
+func (c *fakeConn) stallWritesFrom() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stallWrites = true
+}
+
 func (c *fakeConn) swallowPings() bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -306,3 +345,10 @@ func (c *fakeConn) swallowPings() bool {
 func (c *fakeConn) emit(subID int64, eventType string) {
 	c.pushf(`{"id":%d,"type":"event","event":{"event_type":%q,"data":{}}}`, subID, eventType)
 }
+
+// emitState sends a state_changed for one entity, numbered so a test can tell
+// the order handlers saw them in.
+func (c *fakeConn) emitState(subID int64, entityID string, seq int) {
+	c.pushf(`{"id":%d,"type":"event","event":{"event_type":"state_changed","data":{"entity_id":%q,"seq":%d}}}`,
+		subID, entityID, seq)
+}
@@ -16,6 +16,7 @@ const (
 	typePing            = "ping"
 	typePong            = "pong"
 	typeSubscribeEvents = "subscribe_events"
+	typeUnsubscribe     = "unsubscribe_events"
 )
 
 // Message is a decoded frame from Home Assistant. Raw is retained because
@@ -26,6 +27,10 @@ type Message struct {
 	Success bool
 	Raw     []byte
 	Error   *MessageError
+
+	// entityID is the entity an event concerns, when it names exactly one.
+	// Events sharing it are handled in the order they arrived.
+	entityID string
 }
 
 // MessageError is the error object Home Assistant attaches to a failed result.
@@ -44,6 +49,13 @@ type envelope struct {
 	Type    string        `json:"type"`
 	Success *bool         `json:"success"`
 	Error   *MessageError `json:"error"`
+	Event   *struct {
+		Data struct {
+			// Raw because some events carry a list here, which names no
+			// single entity to order by.
+			EntityID json.RawMessage `json:"entity_id"`
+		} `json:"data"`
+	} `json:"event"`
 }
 
 // parseMessage decodes the envelope shared by all messages, leaving the payload
@@ -61,13 +73,18 @@ func parseMessage(raw []byte) (Message, error) {
 		success = *env.Success
 	}
 
-	return Message{
+	msg := Message{
 		ID:      env.ID,
 		Type:    env.Type,
 		Success: success,
 		Raw:     raw,
 		Error:   env.Error,
-	}, nil
+	}
+	if env.Event != nil {
+		// Anything but a plain string, a list say, leaves it empty.
+		_ = json.Unmarshal(env.Event.Data.EntityID, &msg.entityID)
+	}
+	return msg, nil
 }
 
 // isResult reports whether this message answers a request the client sent,
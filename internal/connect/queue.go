@@ -15,6 +15,7 @@ const dropReportInterval = 10 * time.Second
 // It needs no synchronisation: only the reader goroutine ever touches it, and
 // each connection gets its own.
 type dropReporter struct {
+	log   *slog.Logger
 	since int
 	last  time.Time
 }
@@ -29,7 +30,7 @@ func (r *dropReporter) record(queued int) {
 	}
 	r.last = now
 
-	slog.Warn("Event queue full, shedding events",
+	r.log.Warn("Event queue full, shedding events",
 		"dropped", r.since,
 		"queued", queued,
 	)
@@ -2,6 +2,8 @@ package connect
 
 import (
 	"context"
+	"encoding/json"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"testing/synctest"
@@ -108,3 +110,91 @@ func TestClientDroppedStartsAtZero(t *testing.T) {
 		assert.Zero(t, c.Dropped(), "a queue at its default size must absorb an ordinary burst")
 	})
 }
+
+// Several workers drain the queue, but two transitions of one entity must not
+// overtake each other. Other entities keep flowing meanwhile.
+func TestEventsForOneEntityAreHandledInOrder(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ha := newFakeHA(t, testToken)
+		c := connectedClient(t, ha, Options{Workers: 4})
+
+		var mu sync.Mutex
+		seen := map[string][]int{}
+		require.NoError(t, c.Subscribe(Subscription{EventType: "state_changed"}, func(m Message) {
+			var ev struct {
+				Event struct {
+					Data struct {
+						EntityID string `json:"entity_id"`
+						Seq      int    `json:"seq"`
+					} `json:"data"`
+				} `json:"event"`
+			}
+			require.NoError(t, json.Unmarshal(m.Raw, &ev))
+
+			// The first transition is slow, which is what gives a free worker
+			// the chance to run the second one ahead of it.
+			if ev.Event.Data.Seq == 0 {
+				time.Sleep(time.Second)
+			}
+
+			mu.Lock()
+			seen[ev.Event.Data.EntityID] = append(seen[ev.Event.Data.EntityID], ev.Event.Data.Seq)
+			mu.Unlock()
+		}))
+		synctest.Wait()
+
+		conn := ha.current()
+		subID := conn.subscriptions()[0]
+		for seq := range 5 {
+			conn.emitState(subID, "light.a", seq)
+			conn.emitState(subID, "light.b", seq+1)
+		}
+
+		// light.b is never slow, so it finishes while light.a's first event is
+		// still being handled: the pool is not serialised as a whole.
+		time.Sleep(time.Millisecond)
+		synctest.Wait()
+		mu.Lock()
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, seen["light.b"])
+		assert.Empty(t, seen["light.a"])
+		mu.Unlock()
+
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, seen["light.a"])
+	})
+}
+
+// Events parked behind a busy entity still count against the queue, or one
+// slow handler would let the backlog grow without bound.
+func TestParkedEventsCountAgainstTheQueue(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const emitted = 50
+
+		ha := newFakeHA(t, testToken)
+		c := connectedClient(t, ha, Options{QueueSize: 4, Workers: 4})
+
+		release := make(chan struct{})
+		var handled atomic.Int64
+		require.NoError(t, c.Subscribe(Subscription{EventType: "state_changed"}, func(Message) {
+			<-release
+			handled.Add(1)
+		}))
+		synctest.Wait()
+
+		conn := ha.current()
+		subID := conn.subscriptions()[0]
+		for seq := range emitted {
+			conn.emitState(subID, "light.a", seq)
+		}
+		synctest.Wait()
+
+		assert.Greater(t, c.Dropped(), uint64(0))
+
+		close(release)
+		synctest.Wait()
+		assert.Equal(t, int64(emitted), handled.Load()+int64(c.Dropped()))
+	})
+}
@@ -1,6 +1,8 @@
 package connect
 
 import (
+	"bytes"
+	"log/slog"
 	"net/url"
 	"sync"
 	"testing"
@@ -29,7 +31,7 @@ func TestOnEventFiresInWireOrderIncludingDropped(t *testing.T) {
 
 	// Nothing drains c.events here, so after QueueSize events the rest are
 	// dropped. Every one must still have been applied, in the order it arrived.
-	var rep dropReporter
+	rep := dropReporter{log: c.opts.Logger}
 	for id := int64(1); id <= 5; id++ {
 		c.route(Message{Type: typeEvent, ID: id}, &rep)
 	}
@@ -37,3 +39,55 @@ func TestOnEventFiresInWireOrderIncludingDropped(t *testing.T) {
 	assert.Equal(t, []int64{1, 2, 3, 4, 5}, applied, "every event applied, in order")
 	assert.Equal(t, uint64(3), c.Dropped(), "the three past the queue size were shed")
 }
+
+func TestClientLogsToItsLogger(t *testing.T) {
+	var out bytes.Buffer
+	c, err := NewClient(&url.URL{Scheme: "http", Host: "localhost:8123"}, "test-token", Options{
+		QueueSize: 1,
+		Logger:    slog.New(slog.NewTextHandler(&out, nil)),
+	})
+	require.NoError(t, err)
+
+	rep := dropReporter{log: c.opts.Logger}
+	for id := int64(1); id <= 2; id++ {
+		c.route(Message{Type: typeEvent, ID: id}, &rep)
+	}
+
+	assert.Contains(t, out.String(), "Event queue full")
+}
+
+func TestApplyTakesItsSubscriptionOffTheQueue(t *testing.T) {
+	var onEvent, applied []int64
+	c, err := NewClient(&url.URL{Scheme: "http", Host: "localhost:8123"}, "test-token", Options{
+		QueueSize: 1,
+		OnEvent:   func(m Message) { onEvent = append(onEvent, m.ID) },
+	})
+	require.NoError(t, err)
+	c.routes[7] = &subscription{sub: Subscription{Apply: func(m Message) { applied = append(applied, m.ID) }}}
+
+	rep := dropReporter{log: c.opts.Logger}
+	for _, id := range []int64{7, 3, 7, 7} {
+		c.route(Message{Type: typeEvent, ID: id}, &rep)
+	}
+
+	assert.Equal(t, []int64{7, 7, 7}, applied, "every message applied, in order")
+	assert.Equal(t, []int64{3}, onEvent, "OnEvent sees only the other subscriptions")
+	assert.Zero(t, c.Dropped(), "applied messages never take a place on the queue")
+}
+
+func TestOnEventSkipsACommandsStream(t *testing.T) {
+	var onEvent []int64
+	c, err := NewClient(&url.URL{Scheme: "http", Host: "localhost:8123"}, "test-token", Options{
+		OnEvent: func(m Message) { onEvent = append(onEvent, m.ID) },
+	})
+	require.NoError(t, err)
+	c.routes[2] = &subscription{sub: Subscription{EventType: "state_changed"}}
+	c.routes[5] = &subscription{sub: Subscription{Command: map[string]any{"type": "subscribe_trigger"}}}
+
+	rep := dropReporter{log: c.opts.Logger}
+	for _, id := range []int64{2, 5, 9} {
+		c.route(Message{Type: typeEvent, ID: id}, &rep)
+	}
+
+	assert.Equal(t, []int64{2, 9}, onEvent, "events, and those of a subscription already gone, but not a command's messages")
+}
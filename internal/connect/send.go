@@ -3,8 +3,9 @@ package connect
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log/slog"
+	"time"
 
 	"github.com/Xevion/go-ha/types"
 )
@@ -55,7 +56,7 @@ func (c *Client) dispatch(req types.Request, onAnswer func(Message)) (int64, err
 	req.SetID(id)
 
 	if onAnswer == nil {
-		onAnswer = logFailure
+		onAnswer = c.logFailure
 	}
 
 	c.mu.Lock()
@@ -87,7 +88,82 @@ func (c *Client) Subscribe(sub Subscription, handler Handler) error {
 
 	// Establishing is a no-op while disconnected; run replays it once a
 	// connection exists.
-	return c.establish(s)
+	_, err := c.establish(s, nil)
+	return err
+}
+
+// Watch is Subscribe for a stream its owner may want to end, and for commands
+// that can be refused. While connected it waits for Home Assistant to accept
+// the subscription, so a malformed command fails here rather than in a log.
+// The subscription is replayed on reconnect like any other until it is
+// cancelled through the returned handle.
+func (c *Client) Watch(ctx context.Context, sub Subscription, handler Handler) (Handle, error) {
+	s := &subscription{sub: sub, handler: handler}
+	h := Handle{client: c, sub: s}
+
+	c.mu.Lock()
+	c.subs = append(c.subs, s)
+	c.mu.Unlock()
+
+	answer := make(chan Message, 1)
+	sent, err := c.establish(s, func(msg Message) { answer <- msg })
+	if err != nil {
+		c.forget(s)
+		return Handle{}, err
+	}
+	if !sent {
+		// Disconnected, or a replay got there first. Either way the answer
+		// goes to the log rather than here.
+		return h, nil
+	}
+
+	select {
+	case msg := <-answer:
+		if err := msg.err(); err != nil {
+			c.forget(s)
+			return Handle{}, err
+		}
+		return h, nil
+	case <-ctx.Done():
+		_ = h.Cancel()
+		return Handle{}, ctx.Err()
+	case <-c.ctx.Done():
+		return Handle{}, ErrClosed
+	}
+}
+
+// Cancel ends the subscription. It stops delivery at once, and tells Home
+// Assistant to stop sending if the subscription is live on this connection.
+func (h Handle) Cancel() error {
+	if h.client == nil {
+		return nil
+	}
+	id, live := h.client.forget(h.sub)
+	if !live {
+		return nil
+	}
+
+	_, err := h.client.Call(h.client.ctx, mapRequest{"type": typeUnsubscribe, "subscription": id})
+	return err
+}
+
+// forget drops s from the replay set and from routing, reporting the id it has
+// on the current connection if it was established there.
+func (c *Client) forget(s *subscription) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s.cancelled = true
+	for i, other := range c.subs {
+		if other == s {
+			c.subs = append(c.subs[:i:i], c.subs[i+1:]...)
+			break
+		}
+	}
+
+	live := c.conn != nil && s.gen == c.gen && c.routes[s.id] == s
+	delete(c.routes, s.id)
+	return s.id, live
 }
 
 // establish sends the subscribe request for s on the current connection, and
@@ -97,21 +173,30 @@ func (c *Client) Subscribe(sub Subscription, handler Handler) error {
 // check meaningful: without it, Subscribe and a replay could both decide a
 // subscription still needed establishing and leave two streams running for the
 // life of the connection.
-func (c *Client) establish(s *subscription) error {
+//
+// onAnswer receives Home Assistant's verdict on the request, and is only
+// registered when establish reports it sent one. Without it a refusal is only
+// logged.
+func (c *Client) establish(s *subscription, onAnswer func(Message)) (bool, error) {
 	c.writeMu.Lock()
 	defer c.writeMu.Unlock()
 
 	c.mu.Lock()
 	conn := c.conn
-	if conn == nil || s.gen == c.gen {
+	if conn == nil || s.gen == c.gen || s.cancelled {
 		c.mu.Unlock()
-		return nil
+		return false, nil
+	}
+
+	if onAnswer == nil {
+		onAnswer = c.logFailure
 	}
 
 	id := c.nextID.Add(1)
 	c.routes[id] = s
 	s.gen = c.gen
-	c.pending[id] = logFailure
+	s.id = id
+	c.pending[id] = onAnswer
 	c.mu.Unlock()
 
 	req := s.sub.request()
@@ -124,9 +209,9 @@ func (c *Client) establish(s *subscription) error {
 		// Leave gen behind so the next replay retries this subscription.
 		s.gen = 0
 		c.mu.Unlock()
-		return err
+		return false, err
 	}
-	return nil
+	return true, nil
 }
 
 // resubscribe replays every subscription not yet established on the current
@@ -137,17 +222,17 @@ func (c *Client) resubscribe() {
 	c.mu.Unlock()
 
 	for _, s := range subs {
-		if err := c.establish(s); err != nil {
-			slog.Error("Failed to replay a subscription", "err", err)
+		if _, err := c.establish(s, nil); err != nil {
+			c.opts.Logger.Error("Failed to replay a subscription", "err", err)
 		}
 	}
 }
 
 // logFailure is the answer handler for requests whose outcome is only worth
 // reporting, rather than waiting on.
-func logFailure(msg Message) {
+func (c *Client) logFailure(msg Message) {
 	if err := msg.err(); err != nil {
-		slog.Error("Home Assistant rejected a request", "id", msg.ID, "err", err)
+		c.opts.Logger.Error("Home Assistant rejected a request", "id", msg.ID, "err", err)
 	}
 }
 
@@ -171,8 +256,27 @@ func (c *Client) writeTo(conn transport, v any) error {
 	ctx, cancel := context.WithTimeout(c.ctx, c.opts.WriteTimeout)
 	defer cancel()
 
-	if err := conn.Write(ctx, data); err != nil {
-		return fmt.Errorf("sending message: %w", err)
+	start := time.Now()
+	err = conn.Write(ctx, data)
+	if took := time.Since(start); took >= slowWrite {
+		c.writeStall.Add(int64(took))
 	}
-	return nil
+	if err == nil {
+		return nil
+	}
+
+	if c.ctx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		c.writeTimeouts.Add(1)
+		c.opts.Logger.Warn("Write stalled past its timeout, dropping the connection", "timeout", c.opts.WriteTimeout)
+		// Every later send queues behind writeMu, so a socket that has stopped
+		// draining would hold up all output. Closing it sends the reader into
+		// its reconnect path, and the fresh connection starts with an empty
+		// buffer.
+		_ = conn.Close()
+	}
+	return fmt.Errorf("sending message: %w", err)
 }
+
+// slowWrite is how long a write must take before it counts towards WriteStall.
+// Anything quicker is ordinary scheduling noise.
+const slowWrite = 100 * time.Millisecond
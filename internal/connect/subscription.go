@@ -10,6 +10,17 @@ type Subscription struct {
 	// EventType names the event to receive. An empty value subscribes to every
 	// event Home Assistant emits.
 	EventType string
+
+	// Command, when set, is sent in place of subscribe_events, for the other
+	// commands that open a stream such as subscribe_trigger. EventType is
+	// ignored. Any id it carries is replaced, since ids are the client's.
+	Command map[string]any
+
+	// Apply, when set, receives the subscription's messages on the reader in
+	// the order they arrive, in place of both Options.OnEvent and the handler.
+	// It is for a stream that maintains ordered state of its own, and must not
+	// block, for the same reason OnEvent must not.
+	Apply func(Message)
 }
 
 // Handler receives each message delivered for a subscription. It runs on a
@@ -24,11 +35,27 @@ type subscription struct {
 	// what stops a replay from duplicating a subscription that Subscribe has
 	// already sent on the new connection.
 	gen uint64
+	// id is the subscription's id on the connection it was last established
+	// for, which is what Home Assistant needs to end it.
+	id int64
+	// cancelled stops a replay already in flight from re-establishing a
+	// subscription its owner has ended.
+	cancelled bool
 }
 
 // request builds the wire message that establishes this subscription. The id is
 // stamped by the client at send time, since it is only valid for one connection.
 func (s Subscription) request() mapRequest {
+	if s.Command != nil {
+		// Copied so stamping the id never writes into the caller's map, which
+		// a replay would otherwise share with whoever built it.
+		req := make(mapRequest, len(s.Command))
+		for k, v := range s.Command {
+			req[k] = v
+		}
+		return req
+	}
+
 	req := mapRequest{"type": typeSubscribeEvents}
 	if s.EventType != "" {
 		req["event_type"] = s.EventType
@@ -36,6 +63,12 @@ func (s Subscription) request() mapRequest {
 	return req
 }
 
+// Handle ends a subscription made with Watch.
+type Handle struct {
+	client *Client
+	sub    *subscription
+}
+
 // mapRequest is an ad-hoc request built inline, for protocol messages that have
 // no dedicated type.
 type mapRequest map[string]any
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/coder/websocket"
 
@@ -64,7 +65,9 @@ func (w *wsTransport) Close() error {
 // Assistant websocket endpoint derived from baseUrl.
 func websocketDialer(baseUrl *url.URL) (dialer, error) {
 	endpoint := *baseUrl
-	endpoint.Path = "/api/websocket"
+	// Under the base path, not in place of it, for a Home Assistant reached
+	// through a proxy such as the Supervisor's http://supervisor/core.
+	endpoint.Path = strings.TrimSuffix(baseUrl.Path, "/") + "/api/websocket"
 	scheme, err := internal.GetEquivalentWebsocketScheme(baseUrl.Scheme)
 	if err != nil {
 		return nil, fmt.Errorf("building websocket url: %w", err)
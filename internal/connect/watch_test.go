@@ -0,0 +1,89 @@
+package connect
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchSendsTheRawCommand(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ha := newFakeHA(t, testToken)
+		c := connectedClient(t, ha, Options{})
+
+		var delivered atomic.Int64
+		_, err := c.Watch(context.Background(), Subscription{
+			Command: map[string]any{"type": "subscribe_trigger", "trigger": map[string]any{"platform": "homeassistant"}},
+		}, func(Message) { delivered.Add(1) })
+		require.NoError(t, err)
+		synctest.Wait()
+
+		ids := ha.current().idsOf("subscribe_trigger")
+		require.Len(t, ids, 1)
+		assert.Empty(t, ha.current().subscriptions(), "a command replaces subscribe_events")
+
+		ha.current().emit(ids[0], "trigger")
+		synctest.Wait()
+		assert.Equal(t, int64(1), delivered.Load())
+	})
+}
+
+func TestWatchReportsARefusedCommand(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ha := newFakeHA(t, testToken)
+		c := connectedClient(t, ha, Options{})
+
+		_, err := c.Watch(context.Background(), Subscription{
+			Command: map[string]any{"type": unknownCommand},
+		}, func(Message) {})
+		require.ErrorIs(t, err, ErrCallFailed)
+
+		// A refused subscription is not worth replaying.
+		ha.current().serverClose()
+		awaitReconnect()
+		assert.Empty(t, ha.current().idsOf(unknownCommand))
+	})
+}
+
+func TestCancelStopsDeliveryAndReplay(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ha := newFakeHA(t, testToken)
+		c := connectedClient(t, ha, Options{})
+
+		var delivered atomic.Int64
+		h, err := c.Watch(context.Background(), Subscription{EventType: "state_changed"}, func(Message) {
+			delivered.Add(1)
+		})
+		require.NoError(t, err)
+		synctest.Wait()
+
+		first := ha.current()
+		id := first.subscriptions()[0]
+
+		require.NoError(t, h.Cancel())
+		assert.Len(t, first.idsOf(typeUnsubscribe), 1, "Home Assistant is told to stop sending")
+
+		first.emit(id, "state_changed")
+		synctest.Wait()
+		assert.Zero(t, delivered.Load(), "nothing is delivered after cancelling")
+
+		first.serverClose()
+		awaitReconnect()
+		assert.Empty(t, ha.current().subscriptions(), "a cancelled subscription is not replayed")
+	})
+}
+
+// A raw command map belongs to its caller. Stamping each replay's id into it
+// would race with anyone still holding it.
+func TestCommandIsNotModified(t *testing.T) {
+	cmd := map[string]any{"type": "subscribe_trigger"}
+	req := Subscription{Command: cmd}.request()
+	req.SetID(7)
+
+	assert.NotContains(t, cmd, "id")
+	assert.Equal(t, int64(7), req["id"])
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"strconv"
+	"time"
+)
+
+// FormatDuration renders d the way a person writes it, leaving out the units
+// that are zero: 1h rather than time.Duration's 1h0m0s, and 1h30m rather than
+// 1h30m0s. A duration with a fraction of a second has no shorter form and
+// renders as time.Duration does.
+//
+// It builds the text in a fixed buffer, so the returned string is the only
+// allocation, and labels made from it can be rebuilt on every log line.
+func FormatDuration(d time.Duration) string {
+	if d%time.Second != 0 {
+		return d.String()
+	}
+	if d == 0 {
+		return "0s"
+	}
+
+	// Enough for "-2562047h47m16s", the longest a whole-second Duration gets.
+	var buf [24]byte
+	b := buf[:0]
+	u := uint64(d / time.Second)
+	if d < 0 {
+		b = append(b, '-')
+		u = -u
+	}
+	h, m, s := u/3600, u/60%60, u%60
+	if h > 0 {
+		b = strconv.AppendUint(b, h, 10)
+		b = append(b, 'h')
+	}
+	if m > 0 {
+		b = strconv.AppendUint(b, m, 10)
+		b = append(b, 'm')
+	}
+	if s > 0 {
+		b = strconv.AppendUint(b, s, 10)
+		b = append(b, 's')
+	}
+	return string(b)
+}
@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                                  "0s",
+		time.Hour:                          "1h",
+		90 * time.Minute:                   "1h30m",
+		time.Hour + 5*time.Second:          "1h5s",
+		2 * time.Minute:                    "2m",
+		-10 * time.Minute:                  "-10m",
+		36 * time.Hour:                     "36h",
+		1500 * time.Millisecond:            "1.5s",
+		time.Duration(1<<63-1) / 1e9 * 1e9: "2562047h47m16s",
+	} {
+		assert.Equal(t, want, FormatDuration(d), "%d", d)
+	}
+}
+
+func TestFormatDurationAllocatesOnlyItsResult(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() { _ = FormatDuration(90 * time.Minute) })
+	assert.LessOrEqual(t, allocs, 1.0)
+}
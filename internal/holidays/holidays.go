@@ -0,0 +1,236 @@
+// Package holidays lists the public holidays of a handful of countries.
+//
+// Only holidays kept nationwide are listed: regional ones, such as a German
+// state's or a US state's, and one-off days declared for a coronation or a
+// jubilee are not. Where a country moves a holiday falling on a weekend to a
+// weekday, the day it is kept on is listed, which is the day that matters to
+// whoever is deciding whether to get up for work.
+package holidays
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Holiday is one public holiday, on the date it is kept.
+type Holiday struct {
+	Name string
+	Date time.Time
+}
+
+// rules list a country's holidays for a year, as kept. A holiday can be kept
+// in the year before, as the US keeps a New Year's Day falling on a Saturday
+// on the Friday.
+var rules = map[string]func(year int) []Holiday{
+	"DE": germany,
+	"FR": france,
+	"GB": britain,
+	"NL": netherlands,
+	"US": unitedStates,
+}
+
+// Countries are the ISO 3166 codes holidays are known for.
+func Countries() []string {
+	codes := make([]string, 0, len(rules))
+	for code := range rules {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// Known reports whether holidays are known for the country.
+func Known(country string) bool {
+	_, ok := rules[strings.ToUpper(country)]
+	return ok
+}
+
+// Year lists the country's holidays kept in the year, in date order.
+func Year(country string, year int) ([]Holiday, error) {
+	rule, ok := rules[strings.ToUpper(country)]
+	if !ok {
+		return nil, fmt.Errorf("no holidays known for %q, only %s", country, strings.Join(Countries(), ", "))
+	}
+	var kept []Holiday
+	for _, h := range append(rule(year), rule(year+1)...) {
+		if h.Date.Year() == year {
+			kept = append(kept, h)
+		}
+	}
+	slices.SortStableFunc(kept, func(a, b Holiday) int { return a.Date.Compare(b.Date) })
+	return kept, nil
+}
+
+// Is reports whether the calendar day t falls on is a holiday in the country.
+// An unknown country has none.
+func Is(country string, t time.Time) bool {
+	kept, err := Year(country, t.Year())
+	if err != nil {
+		return false
+	}
+	day := date(t.Year(), t.Month(), t.Day())
+	return slices.ContainsFunc(kept, func(h Holiday) bool { return h.Date.Equal(day) })
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// nth is the nth of the weekday in the month, counting from one, or the last
+// when n is -1.
+func nth(year int, month time.Month, n int, weekday time.Weekday) time.Time {
+	if n < 0 {
+		last := date(year, month+1, 0)
+		back := (int(last.Weekday()) - int(weekday) + 7) % 7
+		return last.AddDate(0, 0, -back)
+	}
+	first := date(year, month, 1)
+	ahead := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, ahead+7*(n-1))
+}
+
+// easter is Easter Sunday in the Gregorian calendar, by the anonymous
+// algorithm published in Nature in 1876.
+func easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return date(year, time.Month(month), day)
+}
+
+// observedUS moves a holiday on a Saturday to the Friday before and one on a
+// Sunday to the Monday after.
+func observedUS(h Holiday) Holiday {
+	switch h.Date.Weekday() {
+	case time.Saturday:
+		h.Date = h.Date.AddDate(0, 0, -1)
+	case time.Sunday:
+		h.Date = h.Date.AddDate(0, 0, 1)
+	}
+	return h
+}
+
+// substitute moves each holiday falling on a weekend to the first weekday
+// after it that is not a holiday already, the way British bank holidays are
+// substituted. Holidays are moved in date order, so Christmas on a Saturday
+// takes the Monday and Boxing Day the Tuesday.
+func substitute(hs []Holiday) []Holiday {
+	taken := map[time.Time]bool{}
+	for _, h := range hs {
+		if !weekend(h.Date) {
+			taken[h.Date] = true
+		}
+	}
+	for i, h := range hs {
+		if !weekend(h.Date) {
+			continue
+		}
+		for weekend(h.Date) || taken[h.Date] {
+			h.Date = h.Date.AddDate(0, 0, 1)
+		}
+		taken[h.Date] = true
+		hs[i] = h
+	}
+	return hs
+}
+
+func weekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+func unitedStates(year int) []Holiday {
+	hs := []Holiday{
+		observedUS(Holiday{"New Year's Day", date(year, time.January, 1)}),
+		{"Martin Luther King Jr. Day", nth(year, time.January, 3, time.Monday)},
+		{"Washington's Birthday", nth(year, time.February, 3, time.Monday)},
+		{"Memorial Day", nth(year, time.May, -1, time.Monday)},
+		observedUS(Holiday{"Juneteenth", date(year, time.June, 19)}),
+		observedUS(Holiday{"Independence Day", date(year, time.July, 4)}),
+		{"Labor Day", nth(year, time.September, 1, time.Monday)},
+		{"Columbus Day", nth(year, time.October, 2, time.Monday)},
+		observedUS(Holiday{"Veterans Day", date(year, time.November, 11)}),
+		{"Thanksgiving Day", nth(year, time.November, 4, time.Thursday)},
+		observedUS(Holiday{"Christmas Day", date(year, time.December, 25)}),
+	}
+	if year < 2021 {
+		// Juneteenth became a federal holiday in 2021.
+		hs = slices.DeleteFunc(hs, func(h Holiday) bool { return h.Name == "Juneteenth" })
+	}
+	return hs
+}
+
+// britain is England and Wales. Scotland and Northern Ireland keep some days
+// of their own.
+func britain(year int) []Holiday {
+	e := easter(year)
+	return substitute([]Holiday{
+		{"New Year's Day", date(year, time.January, 1)},
+		{"Good Friday", e.AddDate(0, 0, -2)},
+		{"Easter Monday", e.AddDate(0, 0, 1)},
+		{"Early May bank holiday", nth(year, time.May, 1, time.Monday)},
+		{"Spring bank holiday", nth(year, time.May, -1, time.Monday)},
+		{"Summer bank holiday", nth(year, time.August, -1, time.Monday)},
+		{"Christmas Day", date(year, time.December, 25)},
+		{"Boxing Day", date(year, time.December, 26)},
+	})
+}
+
+func germany(year int) []Holiday {
+	e := easter(year)
+	return []Holiday{
+		{"Neujahr", date(year, time.January, 1)},
+		{"Karfreitag", e.AddDate(0, 0, -2)},
+		{"Ostermontag", e.AddDate(0, 0, 1)},
+		{"Tag der Arbeit", date(year, time.May, 1)},
+		{"Christi Himmelfahrt", e.AddDate(0, 0, 39)},
+		{"Pfingstmontag", e.AddDate(0, 0, 50)},
+		{"Tag der Deutschen Einheit", date(year, time.October, 3)},
+		{"Erster Weihnachtstag", date(year, time.December, 25)},
+		{"Zweiter Weihnachtstag", date(year, time.December, 26)},
+	}
+}
+
+func france(year int) []Holiday {
+	e := easter(year)
+	return []Holiday{
+		{"Jour de l'an", date(year, time.January, 1)},
+		{"Lundi de Pâques", e.AddDate(0, 0, 1)},
+		{"Fête du Travail", date(year, time.May, 1)},
+		{"Victoire 1945", date(year, time.May, 8)},
+		{"Ascension", e.AddDate(0, 0, 39)},
+		{"Lundi de Pentecôte", e.AddDate(0, 0, 50)},
+		{"Fête nationale", date(year, time.July, 14)},
+		{"Assomption", date(year, time.August, 15)},
+		{"Toussaint", date(year, time.November, 1)},
+		{"Armistice 1918", date(year, time.November, 11)},
+		{"Noël", date(year, time.December, 25)},
+	}
+}
+
+func netherlands(year int) []Holiday {
+	e := easter(year)
+	// King's Day falling on a Sunday is kept the Saturday before.
+	kings := date(year, time.April, 27)
+	if kings.Weekday() == time.Sunday {
+		kings = kings.AddDate(0, 0, -1)
+	}
+	return []Holiday{
+		{"Nieuwjaarsdag", date(year, time.January, 1)},
+		{"Tweede Paasdag", e.AddDate(0, 0, 1)},
+		{"Koningsdag", kings},
+		{"Hemelvaartsdag", e.AddDate(0, 0, 39)},
+		{"Tweede Pinksterdag", e.AddDate(0, 0, 50)},
+		{"Eerste Kerstdag", date(year, time.December, 25)},
+		{"Tweede Kerstdag", date(year, time.December, 26)},
+	}
+}
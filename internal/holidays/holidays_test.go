@@ -0,0 +1,61 @@
+package holidays
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEaster(t *testing.T) {
+	for year, want := range map[int]time.Time{
+		2024: date(2024, time.March, 31),
+		2025: date(2025, time.April, 20),
+		2026: date(2026, time.April, 5),
+		2038: date(2038, time.April, 25),
+	} {
+		assert.Equal(t, want, easter(year), "%d", year)
+	}
+}
+
+func TestUSHolidaysAreObservedOnWeekdays(t *testing.T) {
+	// Independence Day 2026 is a Saturday, kept the Friday before.
+	assert.True(t, Is("US", date(2026, time.July, 3)))
+	assert.False(t, Is("US", date(2026, time.July, 4)))
+	assert.True(t, Is("US", date(2026, time.November, 26)), "Thanksgiving")
+
+	// New Year's Day 2028 is a Saturday, kept on the last day of 2027.
+	assert.True(t, Is("us", date(2027, time.December, 31)))
+	kept, err := Year("US", 2028)
+	require.NoError(t, err)
+	assert.NotEqual(t, "New Year's Day", kept[0].Name)
+
+	assert.False(t, Is("US", date(2020, time.June, 19)), "before Juneteenth was a holiday")
+}
+
+func TestBritishHolidaysAreSubstituted(t *testing.T) {
+	// Christmas 2027 is a Saturday and Boxing Day a Sunday.
+	kept, err := Year("GB", 2027)
+	require.NoError(t, err)
+	last := kept[len(kept)-2:]
+	assert.Equal(t, date(2027, time.December, 27), last[0].Date)
+	assert.Equal(t, date(2027, time.December, 28), last[1].Date)
+
+	assert.True(t, Is("GB", date(2026, time.April, 3)), "Good Friday")
+	assert.True(t, Is("GB", date(2026, time.May, 25)), "Spring bank holiday")
+	assert.True(t, Is("GB", date(2026, time.August, 31)), "Summer bank holiday")
+}
+
+func TestMovableFeastsFollowEaster(t *testing.T) {
+	assert.True(t, Is("DE", date(2026, time.May, 14)), "Christi Himmelfahrt")
+	assert.True(t, Is("FR", date(2026, time.May, 25)), "Lundi de Pentecôte")
+	assert.True(t, Is("NL", date(2025, time.April, 26)), "Koningsdag moved off a Sunday")
+}
+
+func TestUnknownCountry(t *testing.T) {
+	_, err := Year("XX", 2026)
+	assert.Error(t, err)
+	assert.False(t, Known("XX"))
+	assert.False(t, Is("XX", date(2026, time.January, 1)))
+}
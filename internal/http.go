@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"resty.dev/v3"
@@ -18,6 +19,9 @@ var (
 	ErrEntityNotFound = errors.New("entity not found")
 	// ErrHttpStatus reports any other non-success response.
 	ErrHttpStatus = errors.New("unexpected http status")
+	// ErrServerError reports a 5xx response: Home Assistant, or a proxy in
+	// front of it, failing rather than refusing. It is an ErrHttpStatus too.
+	ErrServerError = fmt.Errorf("%w: server error", ErrHttpStatus)
 	// ErrEmptyResponse reports a success status carrying no body, which these
 	// endpoints never legitimately return. It is worth its own error because a
 	// transport that silently drops a body reads as an empty document
@@ -34,9 +38,28 @@ func statusError(resp *resty.Response) error {
 	case http.StatusNotFound:
 		return ErrEntityNotFound
 	}
+	if resp.StatusCode() >= 500 {
+		return fmt.Errorf("%w %s", ErrServerError, resp.Status())
+	}
 	return fmt.Errorf("%w %s", ErrHttpStatus, resp.Status())
 }
 
+// Transient reports whether err is a failure to reach Home Assistant, which
+// may pass on its own, rather than an answer from it: a network error, a
+// timeout, or a 5xx from it or a proxy. A cancelled request is not.
+func Transient(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, ErrServerError):
+		return true
+	case errors.Is(err, ErrHttpStatus), errors.Is(err, ErrUnauthorized),
+		errors.Is(err, ErrEntityNotFound), errors.Is(err, ErrEmptyResponse):
+		return false
+	}
+	return true
+}
+
 type HttpClient struct {
 	client *resty.Client
 	token  string
@@ -45,7 +68,7 @@ type HttpClient struct {
 func NewHttpClient(ctx context.Context, baseUrl *url.URL, token string) *HttpClient {
 	// Shallow copy the URL to avoid modifying the original
 	u := *baseUrl
-	u.Path = "/api"
+	u.Path = strings.TrimSuffix(baseUrl.Path, "/") + "/api"
 
 	// Create resty client with configuration
 	client := resty.New().
@@ -79,8 +102,21 @@ func (c *HttpClient) getRequest() *resty.Request {
 		SetAuthToken(c.token)
 }
 
+// A single entity's state is read under condition checks, where a blip is
+// better retried at once, with jitter, than after the seconds of backoff a
+// snapshot can afford.
+const (
+	stateRetries      = 2
+	stateRetryWait    = 100 * time.Millisecond
+	stateRetryMaxWait = 500 * time.Millisecond
+)
+
 func (c *HttpClient) GetState(entityId string) ([]byte, error) {
-	resp, err := c.getRequest().Get("/states/" + entityId)
+	resp, err := c.getRequest().
+		SetRetryCount(stateRetries).
+		SetRetryWaitTime(stateRetryWait).
+		SetRetryMaxWaitTime(stateRetryMaxWait).
+		Get("/states/" + entityId)
 
 	if err != nil {
 		return nil, fmt.Errorf("requesting state of %q: %w", entityId, err)
@@ -117,3 +153,93 @@ func (c *HttpClient) GetStates() ([]byte, error) {
 
 	return body, nil
 }
+
+// PostState creates or replaces an entity's state. Home Assistant holds it
+// only until the entity's integration next reports, or until a restart for an
+// entity no integration owns, which is what makes it suitable for a virtual
+// sensor the app maintains itself.
+func (c *HttpClient) PostState(entityId string, body any) ([]byte, error) {
+	resp, err := c.getRequest().SetBody(body).Post("/states/" + entityId)
+
+	if err != nil {
+		return nil, fmt.Errorf("setting state of %q: %w", entityId, err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("setting state of %q: %w: %s", entityId, statusError(resp), resp.Bytes())
+	}
+
+	return resp.Bytes(), nil
+}
+
+// GetCalendarEvents returns a calendar's events overlapping the given span.
+func (c *HttpClient) GetCalendarEvents(entityId string, start, end time.Time) ([]byte, error) {
+	resp, err := c.getRequest().
+		SetQueryParam("start", start.Format(time.RFC3339)).
+		SetQueryParam("end", end.Format(time.RFC3339)).
+		Get("/calendars/" + entityId)
+
+	if err != nil {
+		return nil, fmt.Errorf("requesting events of %q: %w", entityId, err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("requesting events of %q: %w: %s", entityId, statusError(resp), resp.Bytes())
+	}
+
+	return resp.Bytes(), nil
+}
+
+// GetHistory returns an entity's recorded states over the given span, as Home
+// Assistant's recorder kept them. The state in effect at start is included.
+func (c *HttpClient) GetHistory(entityId string, start, end time.Time) ([]byte, error) {
+	resp, err := c.getRequest().
+		SetQueryParam("filter_entity_id", entityId).
+		SetQueryParam("end_time", end.UTC().Format(time.RFC3339)).
+		// UTC, so the path carries a Z rather than an offset whose plus sign
+		// would need escaping.
+		Get("/history/period/" + start.UTC().Format(time.RFC3339))
+
+	if err != nil {
+		return nil, fmt.Errorf("requesting history of %q: %w", entityId, err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("requesting history of %q: %w: %s", entityId, statusError(resp), resp.Bytes())
+	}
+
+	return resp.Bytes(), nil
+}
+
+// CheckAPI asks the REST API's root whether it accepts the token.
+func (c *HttpClient) CheckAPI() error {
+	resp, err := c.getRequest().Get("/")
+
+	if err != nil {
+		return fmt.Errorf("requesting the API status: %w", err)
+	}
+
+	// Read even when unused: an unread body holds its connection open.
+	body := resp.Bytes()
+	if resp.StatusCode() >= 400 {
+		return fmt.Errorf("requesting the API status: %w: %s", statusError(resp), body)
+	}
+
+	return nil
+}
+
+// GetConfig returns Home Assistant's core configuration: its location, time
+// zone and unit system.
+func (c *HttpClient) GetConfig() ([]byte, error) {
+	resp, err := c.getRequest().Get("/config")
+
+	if err != nil {
+		return nil, fmt.Errorf("requesting config: %w", err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("requesting config: %w: %s", statusError(resp), resp.Bytes())
+	}
+
+	return resp.Bytes(), nil
+}
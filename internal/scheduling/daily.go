@@ -2,10 +2,10 @@ package scheduling
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/Xevion/go-ha/internal"
-	"github.com/dromara/carbon/v2"
 )
 
 type Trigger interface {
@@ -17,17 +17,34 @@ type Trigger interface {
 type FixedTimeTrigger struct {
 	Hour   int // 0-23
 	Minute int // 0-59
+
+	// Days narrows it to these days of the week. Empty means every day.
+	Days []time.Weekday
 }
 
+// NextTime builds each candidate from an explicit date and zone rather than by
+// moving the fields of now, which is what kept a daylight saving jump from
+// landing it an hour off. A time the jump skips fires when the clock lands, and
+// a time the fall-back repeats fires on its first pass only. The time of day is
+// read in now's location, which the app's clock decides.
 func (t *FixedTimeTrigger) NextTime(now time.Time) *time.Time {
-	next := carbon.NewCarbon(now).SetTimeMilli(t.Hour, t.Minute, 0, 0)
-
-	// If the calculated time is before or equal to now, advance to the next day
-	if !next.StdTime().After(now) {
-		next = next.AddDay()
+	local := now
+
+	// Today and the following week cover every weekday there is.
+	y, m, d := local.Date()
+	for offset := 0; offset <= 7; offset++ {
+		// Calendar days, not 24 hours on: a day either side of a transition is
+		// 23 or 25 hours long.
+		day := time.Date(y, m, d+offset, 12, 0, 0, 0, local.Location())
+		if len(t.Days) > 0 && !slices.Contains(t.Days, day.Weekday()) {
+			continue
+		}
+		if next := internal.WallClock(day, t.Hour, t.Minute); next.After(now) {
+			return &next
+		}
 	}
 
-	return internal.Ptr(next.StdTime().Local())
+	return nil
 }
 
 func (t *FixedTimeTrigger) String() string {
@@ -64,3 +64,73 @@ func TestFixedTimeTrigger_NextTime(t *testing.T) {
 		})
 	}
 }
+
+// Carbon moved the fields of now, so a time inside the spring-forward gap came
+// out an hour early, before the previous occurrence it was meant to follow.
+func TestFixedTimeTrigger_NextTimeAcrossDST(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+
+	restore := time.Local
+	time.Local = chicago
+	t.Cleanup(func() { time.Local = restore })
+
+	t.Run("a skipped time fires when the clock lands", func(t *testing.T) {
+		trigger := &FixedTimeTrigger{Hour: 2, Minute: 30}
+		now := time.Date(2026, 3, 8, 0, 0, 0, 0, chicago)
+
+		next := trigger.NextTime(now)
+		require.NotNil(t, next)
+		assert.Equal(t, "2026-03-08 03:00", next.Format("2006-01-02 15:04"))
+
+		// Asked again from that instant, it moves to the next day's 02:30,
+		// which exists.
+		following := trigger.NextTime(*next)
+		require.NotNil(t, following)
+		assert.Equal(t, "2026-03-09 02:30", following.Format("2006-01-02 15:04"))
+	})
+
+	t.Run("a repeated time fires once", func(t *testing.T) {
+		trigger := &FixedTimeTrigger{Hour: 1, Minute: 30}
+		now := time.Date(2026, 11, 1, 0, 0, 0, 0, chicago)
+
+		first := trigger.NextTime(now)
+		require.NotNil(t, first)
+		assert.Equal(t, "2026-11-01 01:30 CDT", first.Format("2006-01-02 15:04 MST"))
+
+		second := trigger.NextTime(*first)
+		require.NotNil(t, second)
+		assert.Equal(t, "2026-11-02 01:30 CST", second.Format("2006-01-02 15:04 MST"),
+			"the repeated pass on CST must not fire again")
+	})
+
+	t.Run("the day after a transition is a calendar day", func(t *testing.T) {
+		trigger := &FixedTimeTrigger{Hour: 8, Minute: 0}
+		now := time.Date(2026, 3, 8, 9, 0, 0, 0, chicago)
+
+		next := trigger.NextTime(now)
+		require.NotNil(t, next)
+		assert.Equal(t, "2026-03-09 08:00", next.Format("2006-01-02 15:04"),
+			"24 hours on from 08:00 across the jump would read 09:00")
+	})
+}
+
+func TestFixedTimeTriggerSkipsDaysNotChosen(t *testing.T) {
+	// 2025-08-01 is a Friday.
+	friday := time.Date(2025, 8, 1, 10, 0, 0, 0, time.Local)
+	trigger := &FixedTimeTrigger{Hour: 7, Minute: 0, Days: []time.Weekday{time.Monday, time.Wednesday}}
+
+	next := trigger.NextTime(friday)
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2025, 8, 4, 7, 0, 0, 0, time.Local), *next, "passes over the weekend to Monday")
+
+	next = trigger.NextTime(*next)
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2025, 8, 6, 7, 0, 0, 0, time.Local), *next)
+
+	// A chosen day whose time has already passed waits a full week.
+	weekly := &FixedTimeTrigger{Hour: 7, Minute: 0, Days: []time.Weekday{time.Friday}}
+	next = weekly.NextTime(friday)
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2025, 8, 8, 7, 0, 0, 0, time.Local), *next)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/Xevion/go-ha/internal"
 )
 
 // IntervalTrigger represents a trigger that fires at a sequence of intervals.
@@ -54,11 +56,11 @@ func (t *IntervalTrigger) NextTime(now time.Time) *time.Time {
 	}
 
 	// Cycle arithmetic is over absolute durations, so the epoch's location only
-	// decides how the emitted instants report themselves. Default to local, to
+	// decides how the emitted instants report themselves. Default to now's, to
 	// match the fixed time and sun triggers.
 	epoch := t.epoch
 	if epoch.IsZero() {
-		epoch = time.Unix(0, 0).Local()
+		epoch = time.Unix(0, 0).In(now.Location())
 	}
 
 	// If the current time is before the epoch, the next time is the first one in the cycle.
@@ -89,7 +91,7 @@ func (t *IntervalTrigger) NextTime(now time.Time) *time.Time {
 func (t *IntervalTrigger) String() string {
 	parts := make([]string, 0, len(t.intervals))
 	for _, d := range t.intervals {
-		parts = append(parts, d.String())
+		parts = append(parts, internal.FormatDuration(d))
 	}
 	return "every " + strings.Join(parts, " then ")
 }
@@ -0,0 +1,112 @@
+// Package solar computes sunrise, sunset and twilight for a point on Earth.
+//
+// It follows NOAA's simplified sunrise equation, which is good to about a
+// minute at the latitudes people live at. That is plenty for turning on porch
+// lights, but it takes no account of elevation or refraction beyond the
+// standard correction, so where Home Assistant's own sun.sun applies it should
+// be preferred.
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+// Depressions of the sun's centre below the horizon, in degrees, that define
+// the events this package computes.
+const (
+	// Horizon is sunrise and sunset: the upper limb touching the horizon,
+	// corrected for the standard atmospheric refraction.
+	Horizon = 0.833
+
+	// Civil is the edge of civil twilight, which is what Home Assistant calls
+	// dawn and dusk.
+	Civil = 6.0
+
+	// Nautical is the edge of nautical twilight, when the horizon at sea can
+	// no longer be made out.
+	Nautical = 12.0
+
+	// Astronomical is the edge of astronomical twilight, beyond which the sky
+	// is fully dark.
+	Astronomical = 18.0
+)
+
+// j2000 is the Julian date of 2000-01-01 12:00 UTC, the epoch the equation's
+// constants are expressed against.
+const j2000 = 2451545.0
+
+// unixEpochJD is the Julian date of the Unix epoch.
+const unixEpochJD = 2440587.5
+
+// Event returns the instant on the given calendar day that the sun crosses
+// the given depression below the horizon, rising in the morning or setting in
+// the evening. Latitude and longitude are in degrees, north and east positive.
+//
+// The day is date's calendar day in date's location. The bool is false when
+// the sun does not cross that depression that day at all, as happens near the
+// poles in summer and winter.
+func Event(date time.Time, lat, lon, depression float64, rising bool) (time.Time, bool) {
+	noon, sinDecl := transit(date, lon)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	phi := radians(lat)
+
+	cosHour := (math.Sin(radians(-depression)) - math.Sin(phi)*sinDecl) / (math.Cos(phi) * cosDecl)
+	if cosHour < -1 || cosHour > 1 {
+		return time.Time{}, false
+	}
+	hour := degrees(math.Acos(cosHour)) / 360
+
+	jd := noon + hour
+	if rising {
+		jd = noon - hour
+	}
+	return fromJulian(jd).In(date.Location()), true
+}
+
+// Noon returns the instant on the given calendar day that the sun is highest,
+// as seen from the given longitude.
+func Noon(date time.Time, lon float64) time.Time {
+	jd, _ := transit(date, lon)
+	return fromJulian(jd).In(date.Location())
+}
+
+// transit works out the Julian date of solar noon on date's calendar day, and
+// the sine of the sun's declination then.
+func transit(date time.Time, lon float64) (jd, sinDecl float64) {
+	y, m, d := date.Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+
+	n := math.Round(julian(noon)-j2000) + 0.0008
+	meanNoon := n - lon/360
+
+	anomaly := normalise(357.5291 + 0.98560028*meanNoon)
+	mRad := radians(anomaly)
+	centre := 1.9148*math.Sin(mRad) + 0.0200*math.Sin(2*mRad) + 0.0003*math.Sin(3*mRad)
+	longitude := radians(normalise(anomaly + centre + 180 + 102.9372))
+
+	jd = j2000 + meanNoon + 0.0053*math.Sin(mRad) - 0.0069*math.Sin(2*longitude)
+	sinDecl = math.Sin(longitude) * math.Sin(radians(23.4397))
+	return jd, sinDecl
+}
+
+func julian(t time.Time) float64 {
+	return float64(t.Unix())/86400 + unixEpochJD
+}
+
+func fromJulian(jd float64) time.Time {
+	secs := (jd - unixEpochJD) * 86400
+	return time.Unix(0, int64(secs*float64(time.Second))).Truncate(time.Second)
+}
+
+func normalise(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
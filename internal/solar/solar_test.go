@@ -0,0 +1,98 @@
+package solar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+// Reference times are NOAA's published values, which this equation should match
+// to within a couple of minutes.
+func TestEventMatchesPublishedTimes(t *testing.T) {
+	london := zone(t, "Europe/London")
+	sydney := zone(t, "Australia/Sydney")
+
+	for _, tt := range []struct {
+		name       string
+		date       time.Time
+		lat, lon   float64
+		depression float64
+		rising     bool
+		want       time.Time
+	}{
+		{
+			"london sunrise at midsummer",
+			time.Date(2026, 6, 21, 0, 0, 0, 0, london), 51.5074, -0.1278, Horizon, true,
+			time.Date(2026, 6, 21, 4, 43, 0, 0, london),
+		},
+		{
+			"london sunset at midsummer",
+			time.Date(2026, 6, 21, 0, 0, 0, 0, london), 51.5074, -0.1278, Horizon, false,
+			time.Date(2026, 6, 21, 21, 21, 0, 0, london),
+		},
+		{
+			"sydney sunset in midwinter",
+			time.Date(2026, 6, 21, 0, 0, 0, 0, sydney), -33.8688, 151.2093, Horizon, false,
+			time.Date(2026, 6, 21, 16, 54, 0, 0, sydney),
+		},
+		{
+			"london civil dawn at midwinter",
+			time.Date(2026, 12, 21, 0, 0, 0, 0, london), 51.5074, -0.1278, Civil, true,
+			time.Date(2026, 12, 21, 7, 24, 0, 0, london),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Event(tt.date, tt.lat, tt.lon, tt.depression, tt.rising)
+			require.True(t, ok)
+			assert.WithinDuration(t, tt.want, got, 3*time.Minute)
+			assert.Equal(t, tt.date.Location(), got.Location())
+		})
+	}
+}
+
+// The midnight sun never sets, and the polar night never has a sunrise.
+func TestEventReportsPolarDays(t *testing.T) {
+	tromso := time.Date(2026, 6, 21, 0, 0, 0, 0, time.UTC)
+	_, ok := Event(tromso, 69.6492, 18.9553, Horizon, false)
+	assert.False(t, ok)
+
+	winter := time.Date(2026, 12, 21, 0, 0, 0, 0, time.UTC)
+	_, ok = Event(winter, 69.6492, 18.9553, Horizon, true)
+	assert.False(t, ok)
+}
+
+func TestNoonMatchesPublishedTimes(t *testing.T) {
+	london := zone(t, "Europe/London")
+
+	got := Noon(time.Date(2026, 6, 21, 0, 0, 0, 0, london), -0.1278)
+	assert.WithinDuration(t, time.Date(2026, 6, 21, 13, 2, 0, 0, london), got, 3*time.Minute)
+	assert.Equal(t, london, got.Location())
+}
+
+func TestDeeperTwilightComesEarlierAndLater(t *testing.T) {
+	london := zone(t, "Europe/London")
+	day := time.Date(2026, 12, 21, 0, 0, 0, 0, london)
+
+	civil, ok := Event(day, 51.5074, -0.1278, Civil, true)
+	require.True(t, ok)
+	nautical, ok := Event(day, 51.5074, -0.1278, Nautical, true)
+	require.True(t, ok)
+	astronomical, ok := Event(day, 51.5074, -0.1278, Astronomical, true)
+	require.True(t, ok)
+	assert.True(t, astronomical.Before(nautical) && nautical.Before(civil))
+
+	// An elevation above the horizon is a negative depression.
+	high, ok := Event(day, 51.5074, -0.1278, -4, false)
+	require.True(t, ok)
+	sunset, _ := Event(day, 51.5074, -0.1278, Horizon, false)
+	assert.True(t, high.Before(sunset))
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// TokenClaims is what an access token says about itself. Home Assistant's
+// tokens are JWTs, and their claims are readable without the signing key, which
+// is enough to explain a refusal: the signature is Home Assistant's to check.
+type TokenClaims struct {
+	// Issuer is the id of the refresh token the access token hangs off.
+	Issuer    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// ParseTokenClaims reads the claims of a JWT without verifying it. It reports
+// false for anything that is not shaped like one.
+func ParseTokenClaims(token string) (TokenClaims, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return TokenClaims{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return TokenClaims{}, false
+	}
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return TokenClaims{}, false
+	}
+
+	out := TokenClaims{Issuer: claims.Iss}
+	if claims.Iat > 0 {
+		out.IssuedAt = time.Unix(claims.Iat, 0)
+	}
+	if claims.Exp > 0 {
+		out.ExpiresAt = time.Unix(claims.Exp, 0)
+	}
+	return out, true
+}
+
+// TokenHint explains why Home Assistant might have refused a token, from what
+// the token says about itself, or returns "" when it gives no reason.
+func TokenHint(token string, now time.Time) string {
+	claims, ok := ParseTokenClaims(token)
+	switch {
+	case !ok:
+		return "the token is not a Home Assistant access token; create a long-lived one under your user profile"
+	case !claims.ExpiresAt.IsZero() && !claims.ExpiresAt.After(now):
+		return "the token expired at " + claims.ExpiresAt.Format(time.RFC3339)
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwt builds an unsigned token with the given payload, which is all the claims
+// reader looks at.
+func jwt(payload string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		enc.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestParseTokenClaims(t *testing.T) {
+	claims, ok := ParseTokenClaims(jwt(`{"iss":"abc","iat":1700000000,"exp":2015360000}`))
+	require.True(t, ok)
+	assert.Equal(t, "abc", claims.Issuer)
+	assert.Equal(t, time.Unix(1700000000, 0), claims.IssuedAt)
+	assert.Equal(t, time.Unix(2015360000, 0), claims.ExpiresAt)
+
+	_, ok = ParseTokenClaims("not-a-jwt")
+	assert.False(t, ok)
+	_, ok = ParseTokenClaims("a.%%%.c")
+	assert.False(t, ok)
+}
+
+func TestTokenHint(t *testing.T) {
+	now := time.Unix(1800000000, 0)
+
+	assert.Contains(t, TokenHint("hunter2", now), "not a Home Assistant access token")
+	assert.Contains(t, TokenHint(jwt(`{"exp":1700000000}`), now), "expired at")
+	assert.Empty(t, TokenHint(jwt(`{"exp":2015360000}`), now), "a valid-looking token gives no reason")
+}
@@ -0,0 +1,65 @@
+package internal
+
+import "time"
+
+// WallClock returns the instant the clock on the wall reads hour:minute on
+// date's calendar day, in date's location.
+//
+// time.Date alone is not enough for this. A daylight saving jump deletes a
+// stretch of the local clock, and asking for a reading inside it returns an
+// instant that does not read that way at all, sometimes an hour early. The
+// fall-back repeats a stretch instead, and which of the two passes time.Date
+// picks is documented as unspecified. Either way an automation built on it
+// fires at a time nobody asked for, or twice.
+//
+// The answer here is deterministic. A reading skipped by a jump resolves to the
+// instant the jump lands on, which is the first moment the clock has passed
+// it. A repeated reading resolves to its first pass.
+func WallClock(date time.Time, hour, minute int) time.Time {
+	loc := date.Location()
+	y, m, d := date.Date()
+
+	t := time.Date(y, m, d, hour, minute, 0, 0, loc)
+
+	if t.Hour() != hour || t.Minute() != minute {
+		return afterGap(t, y, m, d, hour*60+minute)
+	}
+
+	// A repeated reading has an earlier twin whenever the clock was set back
+	// over it. Offsets change by whole quarter hours at most this much at once.
+	for back := 15 * time.Minute; back <= 2*time.Hour; back += 15 * time.Minute {
+		earlier := t.Add(-back)
+		if earlier.Hour() == hour && earlier.Minute() == minute && earlier.Day() == d {
+			return earlier
+		}
+	}
+	return t
+}
+
+// afterGap finds the first instant on the given day whose wall reading is at or
+// past target minutes. Readings inside a gap never occur, so that instant is
+// the one the clock jumps to.
+func afterGap(near time.Time, y int, m time.Month, d int, target int) time.Time {
+	lo, hi := near.Add(-3*time.Hour), near.Add(3*time.Hour)
+	reached := func(t time.Time) bool {
+		ty, tm, td := t.Date()
+		if ty != y || tm != m || td != d {
+			// A different day is before or after the whole of this one.
+			return t.After(near)
+		}
+		return t.Hour()*60+t.Minute() >= target
+	}
+
+	// The wall clock only moves forward inside a gap's neighbourhood, so the
+	// predicate is monotone over this window and a bisection to the minute is
+	// exact. Offsets are whole minutes everywhere this matters.
+	for hi.Sub(lo) > time.Minute {
+		mid := lo.Add(hi.Sub(lo) / time.Minute / 2 * time.Minute)
+		if reached(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chicago(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+	return loc
+}
+
+func TestWallClockOnAnOrdinaryDay(t *testing.T) {
+	loc := chicago(t)
+	date := time.Date(2026, 6, 1, 18, 45, 12, 0, loc)
+
+	got := WallClock(date, 7, 30)
+	assert.Equal(t, time.Date(2026, 6, 1, 7, 30, 0, 0, loc), got)
+}
+
+// 2026-03-08 in Chicago jumps from 02:00 CST to 03:00 CDT, so 02:30 never
+// reads on the clock. It resolves to the instant the clock lands on.
+func TestWallClockInsideTheSpringForwardGap(t *testing.T) {
+	loc := chicago(t)
+	date := time.Date(2026, 3, 8, 12, 0, 0, 0, loc)
+
+	got := WallClock(date, 2, 30)
+	assert.Equal(t, "03:00", got.Format("15:04"))
+	assert.Equal(t, 8, got.Day())
+
+	// The landing instant is exactly one minute after 01:59 CST.
+	before := WallClock(date, 1, 59)
+	assert.Equal(t, time.Minute, got.Sub(before))
+}
+
+// 2026-11-01 in Chicago repeats 01:00 to 02:00. The first pass is chosen, so a
+// daily trigger there fires once rather than twice or an hour late.
+func TestWallClockInTheRepeatedFallBackHour(t *testing.T) {
+	loc := chicago(t)
+	date := time.Date(2026, 11, 1, 12, 0, 0, 0, loc)
+
+	got := WallClock(date, 1, 30)
+	assert.Equal(t, "01:30", got.Format("15:04"))
+	_, offset := got.Zone()
+	assert.Equal(t, -5*60*60, offset, "the first pass is on daylight time")
+}
+
+func TestWallClockKeepsTheDatesLocation(t *testing.T) {
+	date := time.Date(2026, 1, 15, 23, 0, 0, 0, time.UTC)
+	got := WallClock(date, 0, 5)
+	assert.Equal(t, time.UTC, got.Location())
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 5, 0, 0, time.UTC), got)
+}
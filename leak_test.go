@@ -0,0 +1,95 @@
+package ha_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+// closedApp runs an app with the given automations against a fresh server,
+// lets arm do whatever leaves work outstanding, then shuts both down.
+func closedApp(t *testing.T, arm func(*hatest.Server), automations ...ha.Automation) {
+	t.Helper()
+
+	server := hatest.Start()
+	server.SetState("binary_sensor.door", "off", nil)
+
+	app, err := ha.NewApp(types.NewAppRequest{URL: server.URL(), HAAuthToken: hatest.Token})
+	require.NoError(t, err)
+	require.NoError(t, app.RegisterAutomations(automations...))
+
+	done := make(chan struct{})
+	go func() { defer close(done); _ = app.Start() }()
+	time.Sleep(100 * time.Millisecond)
+
+	arm(server)
+	time.Sleep(50 * time.Millisecond)
+
+	require.NoError(t, app.Close())
+	<-done
+	server.Close()
+}
+
+// A trigger waiting out its For duration holds a timer. Shutdown has to cancel
+// it: left alone it fires an hour later into an app that no longer exists.
+func TestCloseCancelsPendingDelays(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var ran atomic.Bool
+	closedApp(t,
+		func(s *hatest.Server) { s.ChangeState("binary_sensor.door", "on", nil) },
+		ha.NewAutomation("door left open").
+			On(ha.StateChanged("binary_sensor.door").To("on").For(150*time.Millisecond)).
+			Do(func(context.Context, ha.Run) error { ran.Store(true); return nil }).
+			MustBuild(),
+	)
+
+	time.Sleep(200 * time.Millisecond)
+	assert.False(t, ran.Load(), "a delay outlived the app")
+}
+
+// The same holds for a trailing throttle, which is also a timer.
+func TestCloseCancelsTrailingRuns(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var runs atomic.Int64
+	closedApp(t,
+		func(s *hatest.Server) {
+			s.ChangeState("binary_sensor.door", "on", nil)
+			s.ChangeState("binary_sensor.door", "off", nil)
+		},
+		ha.NewAutomation("door burst").
+			On(ha.StateChanged("binary_sensor.door")).
+			Throttle(150*time.Millisecond).
+			ThrottleTrailing().
+			Do(func(context.Context, ha.Run) error { runs.Add(1); return nil }).
+			MustBuild(),
+	)
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int64(1), runs.Load(), "the trailing run outlived the app")
+}
+
+// Every goroutine an app starts is gone once Close returns: the connection's,
+// the scheduler loops, runs in flight and the HTTP client's pooled connections.
+func TestCloseLeavesNoGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	closedApp(t,
+		func(s *hatest.Server) { s.ChangeState("binary_sensor.door", "on", nil) },
+		ha.NewAutomation("hourly").On(ha.Every(time.Hour)).Do(func(context.Context, ha.Run) error { return nil }).MustBuild(),
+		ha.NewAutomation("door").
+			On(ha.StateChanged("binary_sensor.door")).
+			Do(func(ctx context.Context, _ ha.Run) error { <-ctx.Done(); return nil }).
+			MustBuild(),
+	)
+}
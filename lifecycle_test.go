@@ -0,0 +1,173 @@
+package ha_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestLifecycleHooksRunInOrder(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("light.status", "off")
+	app := newApp(t, server)
+
+	var mu sync.Mutex
+	var seen []string
+	note := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, s)
+	}
+
+	app.OnStart(func(context.Context) error { note("start"); return nil })
+	ready := make(chan struct{})
+	app.OnReady(func(context.Context) error {
+		// State has loaded by now.
+		es, ok := app.CachedState("light.status")
+		if ok {
+			note("ready " + es.State)
+		}
+		close(ready)
+		return app.Services().Light.TurnOn("light.status")
+	})
+	app.OnStop(func(ctx context.Context) error {
+		note("stop")
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline, "a stop hook cannot hang shutdown")
+		return app.Services().Light.TurnOff("light.status")
+	})
+
+	start(t, app)
+	select {
+	case <-ready:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnReady never ran")
+	}
+	server.AssertServiceCalled("light", "turn_on", "light.status")
+
+	require.NoError(t, app.Close())
+	require.NoError(t, app.Close(), "a second Close runs nothing twice")
+	server.AssertServiceCalled("light", "turn_off", "light.status")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"start", "ready off", "stop"}, seen)
+}
+
+func TestOnStartErrorStopsStart(t *testing.T) {
+	app := newApp(t, hatest.New(t))
+	boom := errors.New("boom")
+	app.OnStart(func(context.Context) error { return boom })
+
+	ran := false
+	app.OnStop(func(context.Context) error { ran = true; return nil })
+
+	assert.ErrorIs(t, app.Start(), boom)
+	require.NoError(t, app.Close())
+	assert.True(t, ran, "Start was called, so its teardown still runs")
+}
+
+func TestStopHooksNeedAStart(t *testing.T) {
+	app := newApp(t, hatest.New(t))
+	ran := false
+	app.OnStop(func(context.Context) error { ran = true; return nil })
+
+	require.NoError(t, app.Close())
+	assert.False(t, ran)
+}
+
+// A run in flight when shutdown begins is waited for, not abandoned.
+func TestShutdownWaitsForRunsInFlight(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.door", "off")
+	app := newApp(t, server)
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("slow").
+			On(ha.StateChanged("binary_sensor.door").To("on")).
+			Do(func(context.Context, ha.Run) error {
+				close(started)
+				time.Sleep(200 * time.Millisecond)
+				finished.Store(true)
+				return nil
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.ChangeState("binary_sensor.door", "on")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, app.Shutdown(ctx))
+	assert.True(t, finished.Load())
+}
+
+// A run that outlasts the deadline is named, and the shutdown it interrupted
+// carries on: a later Close waits for it.
+func TestShutdownReportsWhatOutlastedTheDeadline(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.door", "off")
+	app := newApp(t, server)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("stuck").
+			On(ha.StateChanged("binary_sensor.door").To("on")).
+			Do(func(context.Context, ha.Run) error {
+				close(started)
+				<-release
+				return nil
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.ChangeState("binary_sensor.door", "on")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := app.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "stuck")
+
+	close(release)
+	assert.NoError(t, app.Close())
+}
+
+func TestAfterFuncRunsOnTheAppClockAndNotAfterClose(t *testing.T) {
+	server := hatest.New(t)
+	clock := hatest.NewClock(time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC))
+	app := newAppWithClock(t, server, clock)
+	start(t, app)
+
+	fired := make(chan struct{}, 2)
+	app.AfterFunc(time.Minute, func() { fired <- struct{}{} })
+	stop := app.AfterFunc(time.Minute, func() { fired <- struct{}{} })
+	assert.True(t, stop(), "stopped before it was due")
+
+	clock.Advance(time.Minute)
+	<-fired
+	assert.False(t, stop(), "already stopped")
+
+	late := app.AfterFunc(time.Minute, func() { fired <- struct{}{} })
+	require.NoError(t, app.Close())
+	clock.Advance(time.Minute)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, fired, "nothing runs once the app has closed")
+	late()
+}
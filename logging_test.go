@@ -0,0 +1,66 @@
+package ha_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+// syncBuffer is a buffer the app's goroutines can log to at once.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLoggerReceivesTheAppsLines(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.door", "off")
+
+	var out syncBuffer
+	app, err := ha.NewApp(types.NewAppRequest{
+		URL:         server.URL(),
+		HAAuthToken: hatest.Token,
+		Logger:      slog.New(slog.NewTextHandler(&out, nil)),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = app.Close() })
+
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("door chime").
+			On(ha.StateChanged("binary_sensor.door").To("on")).
+			Do(func(context.Context, ha.Run) error { return errors.New("no chime") }).
+			MustBuild(),
+	))
+	start(t, app)
+	server.ChangeState("binary_sensor.door", "on")
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "Automation action failed")
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Contains(t, out.String(), `automation="door chime"`, "each automation's lines carry its name")
+	assert.Contains(t, out.String(), "msg=Starting", "the app's own lines go there too")
+}
@@ -0,0 +1,185 @@
+// Package aggregate derives one sensor from the states of several entities.
+//
+// Questions like "where was motion last seen" or "how many windows are open"
+// span many entities, and Home Assistant's own answer is a template sensor
+// written in Jinja. Here it is a function over the source states, and the
+// result is published back to Home Assistant as an ordinary sensor that
+// dashboards and other automations can read.
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+	"sync"
+
+	ha "github.com/Xevion/go-ha"
+)
+
+// Func reduces the sources' current states to the aggregate's value, along
+// with any attributes worth publishing beside it. States arrive in the order
+// the sources were given; a source Home Assistant does not know is left out.
+type Func func(states []ha.EntityState) (value string, attributes map[string]any)
+
+// unknown is what Home Assistant shows for a sensor with no value.
+const unknown = "unknown"
+
+// Latest is the id of the source that changed most recently, with that
+// source's state alongside. Over a set of motion sensors it says where motion
+// was last seen.
+func Latest(states []ha.EntityState) (string, map[string]any) {
+	var latest *ha.EntityState
+	for i := range states {
+		if latest == nil || states[i].LastChanged.After(latest.LastChanged) {
+			latest = &states[i]
+		}
+	}
+	if latest == nil {
+		return unknown, nil
+	}
+
+	attrs := map[string]any{
+		"source_state": latest.State,
+		"changed_at":   latest.LastChanged,
+	}
+	if name, ok := latest.Attributes["friendly_name"]; ok {
+		attrs["source_name"] = name
+	}
+	return latest.EntityID, attrs
+}
+
+// Min is the lowest numeric state among the sources, naming the source it came
+// from. Sources reporting something that is not a number, such as unavailable,
+// are skipped.
+func Min(states []ha.EntityState) (string, map[string]any) {
+	return extreme(states, func(a, b float64) bool { return a < b })
+}
+
+// Max is the highest numeric state among the sources, naming the source it
+// came from.
+func Max(states []ha.EntityState) (string, map[string]any) {
+	return extreme(states, func(a, b float64) bool { return a > b })
+}
+
+func extreme(states []ha.EntityState, better func(a, b float64) bool) (string, map[string]any) {
+	best, source := math.NaN(), ""
+	for _, s := range states {
+		v, err := strconv.ParseFloat(s.State, 64)
+		if err != nil || math.IsNaN(v) {
+			continue
+		}
+		if source == "" || better(v, best) {
+			best, source = v, s.EntityID
+		}
+	}
+	if source == "" {
+		return unknown, nil
+	}
+	return strconv.FormatFloat(best, 'f', -1, 64), map[string]any{"source": source}
+}
+
+// CountOn is how many of the sources are on, listing them.
+func CountOn(states []ha.EntityState) (string, map[string]any) {
+	on := []string{}
+	for _, s := range states {
+		if s.State == "on" {
+			on = append(on, s.EntityID)
+		}
+	}
+	return strconv.Itoa(len(on)), map[string]any{"entities": on}
+}
+
+// Sensor is an aggregate kept up to date on Home Assistant.
+type Sensor struct {
+	app      *ha.App
+	entityID string
+	fn       Func
+	sources  []string
+
+	mu        sync.Mutex
+	published bool
+	value     string
+	attrs     map[string]any
+}
+
+// NewSensor publishes fn over the sources as entityID, such as
+// sensor.last_motion_area, and republishes it whenever a source changes. The
+// first value is published when the app starts.
+func NewSensor[T ha.EntityRef](app *ha.App, entityID string, fn Func, sources ...T) (*Sensor, error) {
+	if entityID == "" {
+		return nil, fmt.Errorf("%w: an aggregate needs an entity to publish as", ha.ErrInvalidArgs)
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("%w: aggregate %s needs a function", ha.ErrInvalidArgs, entityID)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%w: aggregate %s needs sources", ha.ErrInvalidArgs, entityID)
+	}
+
+	s := &Sensor{app: app, entityID: entityID, fn: fn}
+	for _, src := range sources {
+		s.sources = append(s.sources, string(src))
+	}
+
+	a, err := ha.NewAutomation("aggregate "+entityID).
+		On(ha.AtStartup(), ha.StateChanged(s.sources...)).
+		Mode(ha.ModeQueued).
+		Do(func(context.Context, ha.Run) error { return s.Refresh() }).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("aggregate %s: %w", entityID, err)
+	}
+	if err := app.RegisterAutomations(a); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Value reports the value last published, empty before the first.
+func (s *Sensor) Value() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value
+}
+
+// Refresh recomputes the aggregate and publishes it if it changed. Changes
+// arriving from Home Assistant call this already; it is for sources that
+// change without saying so.
+func (s *Sensor) Refresh() error {
+	states := make([]ha.EntityState, 0, len(s.sources))
+	for _, id := range s.sources {
+		st, err := s.app.State().Get(id)
+		if errors.Is(err, ha.ErrEntityNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", id, err)
+		}
+		states = append(states, st)
+	}
+
+	value, attrs := s.fn(states)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Publishing an unchanged value would still ripple a state_changed through
+	// every automation watching the aggregate.
+	if s.published && value == s.value && maps.EqualFunc(attrs, s.attrs, equal) {
+		return nil
+	}
+	if err := s.app.SetState(s.entityID, value, attrs); err != nil {
+		return fmt.Errorf("publishing %s: %w", s.entityID, err)
+	}
+	s.published, s.value, s.attrs = true, value, attrs
+	return nil
+}
+
+// equal compares attribute values, which may be slices and so not comparable
+// with ==.
+func equal(a, b any) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
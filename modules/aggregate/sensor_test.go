@@ -0,0 +1,84 @@
+package aggregate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/aggregate"
+)
+
+func states(values map[string]string) []ha.EntityState {
+	out := []ha.EntityState{}
+	for _, id := range []string{"sensor.a", "sensor.b", "sensor.c"} {
+		if v, ok := values[id]; ok {
+			out = append(out, ha.EntityState{EntityID: id, State: v})
+		}
+	}
+	return out
+}
+
+func TestMinAndMaxSkipNonNumericStates(t *testing.T) {
+	in := states(map[string]string{"sensor.a": "21.5", "sensor.b": "unavailable", "sensor.c": "18"})
+
+	value, attrs := aggregate.Min(in)
+	assert.Equal(t, "18", value)
+	assert.Equal(t, "sensor.c", attrs["source"])
+
+	value, attrs = aggregate.Max(in)
+	assert.Equal(t, "21.5", value)
+	assert.Equal(t, "sensor.a", attrs["source"])
+
+	value, _ = aggregate.Max(states(map[string]string{"sensor.a": "unknown"}))
+	assert.Equal(t, "unknown", value, "nothing numeric means no value")
+}
+
+func TestCountOn(t *testing.T) {
+	value, attrs := aggregate.CountOn(states(map[string]string{"sensor.a": "on", "sensor.b": "off", "sensor.c": "on"}))
+	assert.Equal(t, "2", value)
+	assert.Equal(t, []string{"sensor.a", "sensor.c"}, attrs["entities"])
+}
+
+func TestNewSensorNeedsSources(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+
+	_, err := aggregate.NewSensor[string](app, "sensor.last_motion", aggregate.Latest)
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+
+	_, err = aggregate.NewSensor(app, "sensor.last_motion", aggregate.Latest, "kitchen_motion")
+	assert.ErrorIs(t, err, ha.ErrInvalidAutomation, "a source without a domain is an error, not a panic")
+}
+
+func TestLatestFollowsTheMostRecentChange(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.kitchen_motion", "off")
+	server.SetState("binary_sensor.hall_motion", "off")
+	app := hatest.NewApp(t, server)
+
+	s, err := aggregate.NewSensor(app, "sensor.last_motion_area", aggregate.Latest,
+		"binary_sensor.kitchen_motion", "binary_sensor.hall_motion")
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	// Published at startup, from the state as it stands.
+	require.Eventually(t, func() bool {
+		_, _, ok := server.State("sensor.last_motion_area")
+		return ok
+	}, 2*time.Second, 10*time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+	server.ChangeState("binary_sensor.kitchen_motion", "on")
+
+	require.Eventually(t, func() bool {
+		value, _, _ := server.State("sensor.last_motion_area")
+		return value == "binary_sensor.kitchen_motion"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	_, attrs, _ := server.State("sensor.last_motion_area")
+	assert.Equal(t, "on", attrs["source_state"])
+	assert.Equal(t, "binary_sensor.kitchen_motion", s.Value())
+}
@@ -0,0 +1,283 @@
+// Package alarm follows an alarm_control_panel through its states and
+// escalates an alarm that goes off.
+//
+// Alarm panels, Home Assistant's manual one and those of integrations alike,
+// move through the same states: armed, pending while the entry delay runs
+// down, and triggered once it has. OnAlarmPending and OnAlarmTriggered hand an
+// action those moves, and Escalate builds the usual response on them:
+// notifications that grow more insistent the longer the alarm goes
+// unanswered, a siren held back long enough for a resident to disarm, and
+// disarming when one of them comes home.
+package alarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/modules/presence"
+	"github.com/Xevion/go-ha/services"
+)
+
+// The alarm_control_panel states the helpers watch for. An armed panel is in
+// one of several armed_ states, named for the mode.
+const (
+	Disarmed  = "disarmed"
+	Pending   = "pending"
+	Triggered = "triggered"
+)
+
+// Change is an alarm panel moving into a state.
+type Change struct {
+	Panel services.AlarmControlPanelID
+
+	// From is the state the panel left. Going pending or triggered, it is
+	// usually the mode the panel was armed in, such as armed_away.
+	From string
+	To   string
+
+	// ChangedBy is who last armed or disarmed the panel, where the panel
+	// reports it. It is often empty.
+	ChangedBy string
+
+	// At is when the panel changed.
+	At time.Time
+}
+
+// OnAlarmPending calls fn when the panel starts its entry delay: someone has
+// come in, and has that long to disarm it.
+func OnAlarmPending(app *ha.App, panel services.AlarmControlPanelID, fn func(ctx context.Context, c Change) error) error {
+	return onState(app, panel, Pending, fn)
+}
+
+// OnAlarmTriggered calls fn when the alarm goes off.
+func OnAlarmTriggered(app *ha.App, panel services.AlarmControlPanelID, fn func(ctx context.Context, c Change) error) error {
+	return onState(app, panel, Triggered, fn)
+}
+
+func onState(app *ha.App, panel services.AlarmControlPanelID, state string, fn func(ctx context.Context, c Change) error) error {
+	if err := validatePanel(panel); err != nil {
+		return err
+	}
+	if fn == nil {
+		return fmt.Errorf("%w: no action for %s", ha.ErrInvalidArgs, panel)
+	}
+	a, err := ha.NewAutomation(state + " alarm on " + string(panel)).
+		On(ha.StateChanged(panel).To(state)).
+		Mode(ha.ModeQueued).
+		Do(func(ctx context.Context, run ha.Run) error {
+			return fn(ctx, changeOf(run.Event))
+		}).
+		Build()
+	if err != nil {
+		return err
+	}
+	return app.RegisterAutomations(a)
+}
+
+// Stage is one step of an escalation, taken once the alarm has gone
+// unanswered for After.
+type Stage struct {
+	After  time.Duration
+	Action func(ctx context.Context, c Change) error
+}
+
+// Escalation is the response to a panel's alarm.
+type Escalation struct {
+	// Panel is the alarm panel. Required.
+	Panel services.AlarmControlPanelID
+
+	// Stages are taken in turn while the alarm stays triggered, each After
+	// it went off: a notification to the residents, then one to a neighbour,
+	// and so on. One whose After is zero is taken as it goes off. Disarming
+	// the panel abandons those still to come.
+	Stages []Stage
+
+	// Siren is turned on, with homeassistant.turn_on so that a siren or a
+	// switch both do, once the alarm has gone unanswered for SirenDelay, and
+	// off when the panel is disarmed.
+	Siren      services.EntityID
+	SirenDelay time.Duration
+
+	// Timer counts SirenDelay down. It is a Home Assistant timer, so the
+	// countdown shows on a dashboard and outlives a restart of the app, and
+	// the siren sounds when it finishes. Required with a SirenDelay.
+	Timer services.TimerID
+
+	// DisarmOnArrival lists residents, as person or device_tracker entities,
+	// whose coming home disarms the panel once they have stayed for
+	// ArrivalSettle, debounced as presence.OnArriveHome debounces.
+	DisarmOnArrival []string
+	ArrivalSettle   time.Duration
+
+	// Code disarms the panel, for one that asks for a code.
+	Code string
+}
+
+// Escalate responds to the panel's alarms as the escalation describes.
+func Escalate(app *ha.App, e Escalation) error {
+	if err := validatePanel(e.Panel); err != nil {
+		return err
+	}
+	for i, s := range e.Stages {
+		switch {
+		case s.After < 0:
+			return fmt.Errorf("%w: stage %d of %s has a negative After", ha.ErrInvalidArgs, i+1, e.Panel)
+		case s.Action == nil:
+			return fmt.Errorf("%w: stage %d of %s has no action", ha.ErrInvalidArgs, i+1, e.Panel)
+		}
+	}
+	switch {
+	case e.SirenDelay < 0:
+		return fmt.Errorf("%w: negative siren delay %s", ha.ErrInvalidArgs, e.SirenDelay)
+	case e.SirenDelay > 0 && e.Siren == "":
+		return fmt.Errorf("%w: a siren delay needs a Siren", ha.ErrInvalidArgs)
+	case e.SirenDelay > 0 && !strings.HasPrefix(string(e.Timer), "timer."):
+		return fmt.Errorf("%w: a siren delay needs a timer entity to count it down, not %q", ha.ErrInvalidArgs, e.Timer)
+	case e.ArrivalSettle < 0:
+		return fmt.Errorf("%w: negative arrival settle time %s", ha.ErrInvalidArgs, e.ArrivalSettle)
+	}
+	// Checked here as well as by presence, so that a bad resident is turned
+	// away before anything is registered.
+	for _, person := range e.DisarmOnArrival {
+		if !strings.HasPrefix(person, "person.") && !strings.HasPrefix(person, "device_tracker.") {
+			return fmt.Errorf("%w: %q is not a person or device_tracker", ha.ErrInvalidArgs, person)
+		}
+	}
+
+	var automations []ha.Automation
+	add := func(b ha.AutomationBuilder) error {
+		a, err := b.Build()
+		if err != nil {
+			return err
+		}
+		automations = append(automations, a)
+		return nil
+	}
+
+	name := "escalation of " + string(e.Panel)
+	for i, s := range e.Stages {
+		// The hold is what abandons a stage: a disarm before After cancels
+		// its pending run.
+		on := ha.StateChanged(e.Panel).To(Triggered)
+		if s.After > 0 {
+			on = on.For(s.After)
+		}
+		err := add(ha.NewAutomation(fmt.Sprintf("%s, stage %d", name, i+1)).
+			On(on).
+			Do(func(ctx context.Context, run ha.Run) error {
+				return s.Action(ctx, changeOf(run.Event))
+			}))
+		if err != nil {
+			return err
+		}
+	}
+
+	if e.Siren != "" {
+		err := add(ha.NewAutomation(name + ", siren").
+			On(ha.StateChanged(e.Panel).To(Triggered)).
+			Do(func(_ context.Context, run ha.Run) error {
+				if e.SirenDelay == 0 {
+					return run.Services.HomeAssistant.TurnOn(e.Siren)
+				}
+				return run.Services.Timer.Start(e.Timer, timerDuration(e.SirenDelay))
+			}))
+		if err != nil {
+			return err
+		}
+		err = add(ha.NewAutomation(name + ", silence").
+			On(ha.StateChanged(e.Panel).From(Triggered)).
+			Do(func(_ context.Context, run ha.Run) error {
+				if e.SirenDelay > 0 {
+					if err := run.Services.Timer.Cancel(e.Timer); err != nil {
+						return err
+					}
+				}
+				return run.Services.HomeAssistant.TurnOff(e.Siren)
+			}))
+		if err != nil {
+			return err
+		}
+	}
+
+	if e.SirenDelay > 0 {
+		err := add(ha.NewAutomation(name + ", siren delay").
+			On(ha.EventFired("timer.finished")).
+			Do(func(_ context.Context, run ha.Run) error {
+				if finishedTimer(run.Event.Raw) != string(e.Timer) {
+					return nil
+				}
+				// Disarmed as the timer ran out, the cancel too late to stop it.
+				if st, err := run.State.Get(string(e.Panel)); err != nil || st.State != Triggered {
+					return err
+				}
+				return run.Services.HomeAssistant.TurnOn(e.Siren)
+			}))
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := app.RegisterAutomations(automations...); err != nil {
+		return err
+	}
+
+	for _, person := range e.DisarmOnArrival {
+		err := presence.OnArriveHome(app, person, e.ArrivalSettle, func(_ context.Context, run ha.Run) error {
+			st, err := run.State.Get(string(e.Panel))
+			if err != nil || st.State == Disarmed {
+				return err
+			}
+			if e.Code == "" {
+				return run.Services.AlarmControlPanel.Disarm(e.Panel)
+			}
+			return run.Services.AlarmControlPanel.Disarm(e.Panel, map[string]any{"code": e.Code})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePanel(panel services.AlarmControlPanelID) error {
+	if !strings.HasPrefix(string(panel), "alarm_control_panel.") {
+		return fmt.Errorf("%w: %q is not an alarm_control_panel", ha.ErrInvalidArgs, panel)
+	}
+	return nil
+}
+
+func changeOf(ev ha.Event) Change {
+	changedBy, _ := ev.To.Attributes["changed_by"].(string)
+	return Change{
+		Panel:     services.AlarmControlPanelID(ev.EntityID),
+		From:      ev.From.State,
+		To:        ev.To.State,
+		ChangedBy: changedBy,
+		At:        ev.To.LastChanged,
+	}
+}
+
+// finishedTimer reads which timer a timer.finished event is for.
+func finishedTimer(raw []byte) string {
+	var msg struct {
+		Event struct {
+			Data struct {
+				EntityID string `json:"entity_id"`
+			} `json:"data"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return ""
+	}
+	return msg.Event.Data.EntityID
+}
+
+// timerDuration formats a duration as timer.start takes it.
+func timerDuration(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
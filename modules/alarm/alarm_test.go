@@ -0,0 +1,163 @@
+package alarm_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/alarm"
+)
+
+const panel = "alarm_control_panel.house"
+
+func settle() { time.Sleep(100 * time.Millisecond) }
+
+// changes collects what a listener was handed.
+type changes struct {
+	mu  sync.Mutex
+	got []alarm.Change
+}
+
+func (c *changes) record(_ context.Context, ch alarm.Change) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.got = append(c.got, ch)
+	return nil
+}
+
+func (c *changes) take() []alarm.Change {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	got := c.got
+	c.got = nil
+	return got
+}
+
+func TestListenersAreHandedTheChange(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState(panel, "armed_away")
+	app := hatest.NewApp(t, server)
+
+	pending, triggered := &changes{}, &changes{}
+	require.NoError(t, alarm.OnAlarmPending(app, panel, pending.record))
+	require.NoError(t, alarm.OnAlarmTriggered(app, panel, triggered.record))
+	hatest.StartApp(t, app)
+
+	server.ChangeState(panel, alarm.Pending, map[string]any{"changed_by": "keypad"})
+	settle()
+	got := pending.take()
+	require.Len(t, got, 1)
+	assert.Equal(t, alarm.Change{Panel: panel, From: "armed_away", To: alarm.Pending, ChangedBy: "keypad", At: got[0].At}, got[0])
+	assert.Empty(t, triggered.take())
+
+	server.ChangeState(panel, alarm.Triggered)
+	settle()
+	got = triggered.take()
+	require.Len(t, got, 1)
+	assert.Equal(t, alarm.Pending, got[0].From)
+}
+
+func TestEscalationStagesAndTheSiren(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState(panel, "armed_away")
+	app := hatest.NewApp(t, server)
+
+	first, second := &changes{}, &changes{}
+	require.NoError(t, alarm.Escalate(app, alarm.Escalation{
+		Panel: panel,
+		Stages: []alarm.Stage{
+			{Action: first.record},
+			{After: 200 * time.Millisecond, Action: second.record},
+		},
+		Siren:      "siren.hall",
+		SirenDelay: 90 * time.Second,
+		Timer:      "timer.siren_delay",
+	}))
+	hatest.StartApp(t, app)
+
+	server.ChangeState(panel, alarm.Triggered)
+	call, _ := server.AssertServiceCalled("timer", "start", "timer.siren_delay")
+	assert.Equal(t, "00:01:30", call.ServiceData["duration"])
+	settle()
+	assert.Len(t, first.take(), 1, "the first stage goes as the alarm does")
+	assert.Empty(t, second.take())
+	assert.True(t, server.AssertServiceNotCalled("homeassistant", "turn_on", "siren.hall"))
+
+	// Some other timer finishing is nothing to do with the siren.
+	server.Fire("timer.finished", map[string]any{"entity_id": "timer.laundry"})
+	settle()
+	assert.True(t, server.AssertServiceNotCalled("homeassistant", "turn_on", "siren.hall"))
+
+	server.Fire("timer.finished", map[string]any{"entity_id": "timer.siren_delay"})
+	server.AssertServiceCalled("homeassistant", "turn_on", "siren.hall")
+	settle()
+	assert.Len(t, second.take(), 1)
+
+	server.ChangeState(panel, alarm.Disarmed)
+	server.AssertServiceCalled("homeassistant", "turn_off", "siren.hall")
+	server.AssertServiceCalled("timer", "cancel", "timer.siren_delay")
+}
+
+func TestDisarmingAbandonsTheStagesToCome(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState(panel, "armed_away")
+	app := hatest.NewApp(t, server)
+
+	late := &changes{}
+	require.NoError(t, alarm.Escalate(app, alarm.Escalation{
+		Panel:  panel,
+		Stages: []alarm.Stage{{After: 200 * time.Millisecond, Action: late.record}},
+	}))
+	hatest.StartApp(t, app)
+
+	server.ChangeState(panel, alarm.Triggered)
+	settle()
+	server.ChangeState(panel, alarm.Disarmed)
+	time.Sleep(300 * time.Millisecond)
+	assert.Empty(t, late.take())
+}
+
+func TestArrivingHomeDisarms(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState(panel, "armed_away")
+	server.SetState("person.alice", "not_home")
+	app := hatest.NewApp(t, server)
+
+	require.NoError(t, alarm.Escalate(app, alarm.Escalation{
+		Panel:           panel,
+		DisarmOnArrival: []string{"person.alice"},
+		Code:            "1234",
+	}))
+	hatest.StartApp(t, app)
+
+	server.ChangeState("person.alice", "home")
+	call, _ := server.AssertServiceCalled("alarm_control_panel", "alarm_disarm", panel)
+	assert.Equal(t, "1234", call.ServiceData["code"])
+}
+
+func TestAlarmHelpersRejectBadArguments(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+	noop := func(context.Context, alarm.Change) error { return nil }
+
+	assert.ErrorIs(t, alarm.OnAlarmPending(app, "switch.house", noop), ha.ErrInvalidArgs)
+	assert.ErrorIs(t, alarm.OnAlarmTriggered(app, panel, nil), ha.ErrInvalidArgs)
+
+	for name, e := range map[string]alarm.Escalation{
+		"not a panel":     {Panel: "switch.house"},
+		"negative stage":  {Panel: panel, Stages: []alarm.Stage{{After: -time.Second, Action: noop}}},
+		"stage no action": {Panel: panel, Stages: []alarm.Stage{{After: time.Second}}},
+		"delay no siren":  {Panel: panel, SirenDelay: time.Minute, Timer: "timer.siren_delay"},
+		"delay no timer":  {Panel: panel, Siren: "siren.hall", SirenDelay: time.Minute},
+		"not a resident":  {Panel: panel, DisarmOnArrival: []string{"zone.home"}},
+		"negative settle": {Panel: panel, ArrivalSettle: -time.Minute},
+		"negative delay":  {Panel: panel, Siren: "siren.hall", SirenDelay: -time.Minute},
+	} {
+		assert.ErrorIs(t, alarm.Escalate(app, e), ha.ErrInvalidArgs, name)
+	}
+}
@@ -0,0 +1,136 @@
+// Package announce speaks messages where somebody will hear them.
+//
+// A house-wide announcement plays to empty rooms and, played everywhere,
+// wakes whoever is asleep in the one that is not. Knowing which areas are
+// occupied, from an occupancy model or the speakers' own motion sensors, an
+// announcement can go only to the speakers near someone, and to their phones
+// when nobody is near one.
+package announce
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/modules/occupancy"
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
+)
+
+// Speaker is a media player and the area it plays to.
+type Speaker struct {
+	// Area names the area, as the occupancy model names it. Required.
+	Area string
+
+	// Player is the media player. Required.
+	Player services.MediaPlayerID
+
+	// Motion lists sensors whose being on means someone is near the speaker,
+	// for an area the occupancy model does not cover, or without one.
+	Motion []string
+
+	// Language is spoken on this speaker, for a household whose rooms speak
+	// different ones. It defaults to the Options' Language.
+	Language string
+}
+
+// Options describes the speakers and where an announcement goes without them.
+type Options struct {
+	// Engine is the tts entity that speaks, such as tts.google_en_com.
+	// Required.
+	Engine services.EntityID
+
+	// Language is the language spoken, in the engine's code for it, such as
+	// "de". Empty leaves the engine's own.
+	Language string
+
+	Speakers []Speaker
+
+	// Occupancy tells which areas are occupied. A speaker's area counts as
+	// occupied when the model says so or one of its Motion sensors is on.
+	Occupancy *occupancy.Model
+
+	// Fallback lists the notify services, such as mobile_app_alices_phone, an
+	// announcement is sent to when nobody is near a speaker.
+	Fallback []string
+}
+
+// Announcer routes announcements to the speakers of occupied areas.
+type Announcer struct {
+	app  *ha.App
+	opts Options
+}
+
+// New prepares to announce on the speakers.
+func New(app *ha.App, opts Options) (*Announcer, error) {
+	if !strings.HasPrefix(string(opts.Engine), "tts.") {
+		return nil, fmt.Errorf("%w: %q is not a tts entity", ha.ErrInvalidArgs, opts.Engine)
+	}
+	if len(opts.Speakers) == 0 {
+		return nil, fmt.Errorf("%w: announcing needs speakers", ha.ErrInvalidArgs)
+	}
+	for _, s := range opts.Speakers {
+		switch {
+		case s.Area == "":
+			return nil, fmt.Errorf("%w: speaker %s has no area", ha.ErrInvalidArgs, s.Player)
+		case !strings.HasPrefix(string(s.Player), "media_player."):
+			return nil, fmt.Errorf("%w: %q is not a media_player", ha.ErrInvalidArgs, s.Player)
+		case len(s.Motion) == 0 && opts.Occupancy == nil:
+			return nil, fmt.Errorf("%w: speaker %s has no way to tell whether its area is occupied", ha.ErrInvalidArgs, s.Player)
+		}
+	}
+	opts.Speakers = slices.Clone(opts.Speakers)
+	opts.Fallback = slices.Clone(opts.Fallback)
+	return &Announcer{app: app, opts: opts}, nil
+}
+
+// Announce speaks message on the speakers of the occupied areas among those
+// given, or among all of them when none are. When none of those areas is
+// occupied it goes to the Fallback notify services instead, and when there
+// are none of those either it is dropped, since nobody would hear it.
+func (a *Announcer) Announce(message string, areas ...string) error {
+	var errs []error
+	spoken := false
+	for _, s := range a.opts.Speakers {
+		if len(areas) > 0 && !slices.Contains(areas, s.Area) {
+			continue
+		}
+		if !a.occupied(s) {
+			continue
+		}
+		language := s.Language
+		if language == "" {
+			language = a.opts.Language
+		}
+		if err := a.app.Services().TTS.Speak(a.opts.Engine, s.Player, message, language); err != nil {
+			errs = append(errs, fmt.Errorf("announcing on %s: %w", s.Player, err))
+			continue
+		}
+		spoken = true
+	}
+	if spoken {
+		return errors.Join(errs...)
+	}
+
+	for _, service := range a.opts.Fallback {
+		if err := a.app.Services().Notify.Notify(types.NotifyRequest{ServiceName: service, Message: message}); err != nil {
+			errs = append(errs, fmt.Errorf("announcing to %s: %w", service, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// occupied reports whether someone is near the speaker.
+func (a *Announcer) occupied(s Speaker) bool {
+	if a.opts.Occupancy != nil && a.opts.Occupancy.IsOccupied(s.Area) {
+		return true
+	}
+	for _, id := range s.Motion {
+		if st, err := a.app.State().Get(id); err == nil && st.State == "on" {
+			return true
+		}
+	}
+	return false
+}
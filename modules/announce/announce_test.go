@@ -0,0 +1,99 @@
+package announce_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/announce"
+	"github.com/Xevion/go-ha/modules/occupancy"
+	"github.com/Xevion/go-ha/services"
+)
+
+func TestAnnouncementsGoWhereSomebodyIs(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.kitchen_motion", "on")
+	server.SetState("binary_sensor.study_motion", "off")
+	server.SetState("binary_sensor.lounge_motion", "off")
+	app := hatest.NewApp(t, server)
+
+	model, err := occupancy.New(app, occupancy.Area{
+		Name:    "lounge",
+		Motion:  []string{"binary_sensor.lounge_motion"},
+		Timeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+	// The lounge sensor has only just cleared.
+	require.Eventually(t, func() bool { return !model.IsOccupied("lounge") }, 2*time.Second, 10*time.Millisecond)
+
+	a, err := announce.New(app, announce.Options{
+		Engine:   "tts.piper",
+		Language: "en",
+		Speakers: []announce.Speaker{
+			{Area: "kitchen", Player: "media_player.kitchen", Motion: []string{"binary_sensor.kitchen_motion"}},
+			{Area: "study", Player: "media_player.study", Motion: []string{"binary_sensor.study_motion"}},
+			{Area: "lounge", Player: "media_player.lounge", Language: "de"},
+		},
+		Occupancy: model,
+		Fallback:  []string{"mobile_app_alices_phone"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Announce("dinner is ready"))
+	calls := server.WaitForCalls(1)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "speak", calls[0].Service)
+	assert.Equal(t, "tts.piper", calls[0].EntityID)
+	assert.Equal(t, "media_player.kitchen", calls[0].ServiceData["media_player_entity_id"])
+	assert.Equal(t, "en", calls[0].ServiceData["language"])
+	server.ResetCalls()
+
+	// Someone in the lounge, which the occupancy model tracks.
+	server.ChangeState("binary_sensor.lounge_motion", "on")
+	require.Eventually(t, func() bool { return model.IsOccupied("lounge") }, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, a.Announce("the washing is done", "lounge", "study"))
+	calls = server.WaitForCalls(1)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "media_player.lounge", calls[0].ServiceData["media_player_entity_id"])
+	assert.Equal(t, "de", calls[0].ServiceData["language"], "the speaker's own language")
+}
+
+func TestAnnouncementsFallBackToNotifications(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.study_motion", "off")
+	app := hatest.NewApp(t, server)
+	hatest.StartApp(t, app)
+
+	a, err := announce.New(app, announce.Options{
+		Engine:   "tts.piper",
+		Speakers: []announce.Speaker{{Area: "study", Player: "media_player.study", Motion: []string{"binary_sensor.study_motion"}}},
+		Fallback: []string{"mobile_app_alices_phone"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Announce("the door is open"))
+	call, _ := server.AssertServiceCalled("notify", "mobile_app_alices_phone", "")
+	assert.Equal(t, "the door is open", call.ServiceData["message"])
+	assert.True(t, server.AssertServiceNotCalled("tts", "speak", "tts.piper"))
+}
+
+func TestNewRejectsBadOptions(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+	study := announce.Speaker{Area: "study", Player: "media_player.study", Motion: []string{"binary_sensor.study_motion"}}
+
+	for name, opts := range map[string]announce.Options{
+		"not tts":      {Engine: "media_player.study", Speakers: []announce.Speaker{study}},
+		"no speakers":  {Engine: "tts.piper"},
+		"no area":      {Engine: "tts.piper", Speakers: []announce.Speaker{{Player: "media_player.study", Motion: study.Motion}}},
+		"not a player": {Engine: "tts.piper", Speakers: []announce.Speaker{{Area: "study", Player: services.MediaPlayerID("switch.radio"), Motion: study.Motion}}},
+		"no occupancy": {Engine: "tts.piper", Speakers: []announce.Speaker{{Area: "study", Player: "media_player.study"}}},
+	} {
+		_, err := announce.New(app, opts)
+		assert.ErrorIs(t, err, ha.ErrInvalidArgs, name)
+	}
+}
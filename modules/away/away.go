@@ -0,0 +1,258 @@
+// Package away secures the house once everyone has left it.
+//
+// Leaving is a checklist: the lights off, the heating down, the doors locked,
+// the alarm set, and perhaps the vacuum let loose on floors with nobody on
+// them. Each step is a service call that can fail quietly, a lock jammed on
+// its bolt or an alarm refusing to arm with a window open, and a house half
+// secured is worse than one visibly not, since nobody goes back to check. Arm
+// runs the checklist when the last person leaves, waits for each step to be
+// seen to take, and says so when one does not.
+package away
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/services"
+)
+
+// Routine is what leaving the house sets in motion. Its steps run in the
+// order of its fields, lights to vacuum, and any left empty are skipped.
+type Routine struct {
+	// People are the person entities that have to be away. Required.
+	People []string
+
+	// Settle is how long everyone has to have been away before the routine
+	// runs, so stepping out to the bins or a phone dropping off the wifi
+	// does not lock the house. Defaults to ten minutes.
+	Settle time.Duration
+
+	// Lights are turned off.
+	Lights []services.LightID
+
+	// Climate are set to Preset, which defaults to eco.
+	Climate []services.ClimateID
+	Preset  string
+
+	// Locks are locked.
+	Locks []services.LockID
+
+	// Alarm is armed away, with AlarmCode if the panel needs one.
+	Alarm     services.AlarmControlPanelID
+	AlarmCode string
+
+	// Vacuum is started.
+	Vacuum services.VacuumID
+
+	// Verify is how long each step waits to see its entities reach the
+	// state it asked for: off, locked, armed_away or cleaning. An alarm
+	// panel's exit delay has to fit within it. Defaults to a minute.
+	Verify time.Duration
+
+	// RollBack undoes the steps already taken when one fails: lights that
+	// were on are turned on, thermostats go back to their presets, the alarm
+	// is disarmed and the vacuum sent home. Locks are never unlocked.
+	RollBack bool
+
+	// OnFailure is told when a step fails, to notify someone the house is
+	// not secure. OnDone is told when every step has taken.
+	OnFailure func(ctx context.Context, f Failure) error
+	OnDone    func(ctx context.Context) error
+}
+
+// Failure is a step of the routine that did not take.
+type Failure struct {
+	// Step names the step, one of "lights", "climate", "locks", "alarm" or
+	// "vacuum", and Entity the entity that failed it.
+	Step   string
+	Entity string
+
+	// Err is why: the service call's error, or ha.ErrWaitTimeout for an
+	// entity that never reached its state.
+	Err error
+
+	// RolledBack reports whether the steps before it were undone, and
+	// RollBackErr what went wrong doing so.
+	RolledBack  bool
+	RollBackErr error
+}
+
+func (f Failure) Error() string {
+	return fmt.Sprintf("away: %s: %s: %v", f.Step, f.Entity, f.Err)
+}
+
+func (f Failure) Unwrap() error { return f.Err }
+
+// step is one entity's part in a step of the routine.
+type step struct {
+	name   string
+	entity string
+	do     func(svc *ha.Service) error
+
+	// want is the state do is verified by, or empty for a call whose effect
+	// does not show in the entity's state.
+	want string
+
+	// undo reverses do, or is nil where it cannot or should not be.
+	undo func(svc *ha.Service) error
+}
+
+// Arm runs the routine whenever everyone has been away for its Settle. It
+// runs once per departure: not again until someone has come home. Someone
+// coming home part way through stops it where it is.
+func Arm(app *ha.App, r Routine) error {
+	if len(r.People) == 0 {
+		return fmt.Errorf("%w: an away routine needs people", ha.ErrInvalidArgs)
+	}
+	if r.Settle < 0 || r.Verify < 0 {
+		return fmt.Errorf("%w: away routine has a negative Settle or Verify", ha.ErrInvalidArgs)
+	}
+	if r.Settle == 0 {
+		r.Settle = 10 * time.Minute
+	}
+	if r.Verify == 0 {
+		r.Verify = time.Minute
+	}
+	if r.Preset == "" {
+		r.Preset = "eco"
+	}
+
+	// ran reports a departure the routine has already run for; a run of
+	// the queued automation below is never concurrent with another.
+	var ran bool
+	name := "away routine"
+	a, err := ha.NewAutomation(name).
+		On(ha.StateChanged(r.People...).For(r.Settle), ha.StateChanged(r.People...).To("home")).
+		Mode(ha.ModeQueued).
+		Do(func(ctx context.Context, run ha.Run) error {
+			if run.Event.To.State == "home" {
+				ran = false
+				return nil
+			}
+			if ran || !everyoneAway(run.State, r.People) {
+				return nil
+			}
+			ran = true
+			return r.run(ctx, run)
+		}).
+		Build()
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return app.RegisterAutomations(a)
+}
+
+// run takes the routine's steps in turn, stopping at the first to fail.
+func (r Routine) run(ctx context.Context, run ha.Run) error {
+	svc := run.Services.WithResult()
+	steps := r.steps(run.State)
+	for i, s := range steps {
+		if !everyoneAway(run.State, r.People) {
+			return nil
+		}
+		err := s.do(svc)
+		if err == nil && s.want != "" {
+			err = ha.WaitUntil(ctx, run.State, s.entity, s.want, r.Verify)
+		}
+		if err == nil {
+			continue
+		}
+
+		f := Failure{Step: s.name, Entity: s.entity, Err: err}
+		if r.RollBack {
+			f.RolledBack, f.RollBackErr = true, undo(svc, steps[:i])
+		}
+		if r.OnFailure != nil {
+			if err := r.OnFailure(ctx, f); err != nil {
+				return errors.Join(f, err)
+			}
+		}
+		return f
+	}
+	if r.OnDone != nil {
+		return r.OnDone(ctx)
+	}
+	return nil
+}
+
+// steps lists what the routine does, each entity its own step, with what is
+// needed to undo it read before it is done.
+func (r Routine) steps(state ha.StateReader) []step {
+	var steps []step
+	for _, id := range r.Lights {
+		s := step{
+			name: "lights", entity: string(id), want: "off",
+			do: func(svc *ha.Service) error { return svc.Light.TurnOff(id) },
+		}
+		if st, err := state.Get(string(id)); err == nil && st.State == "on" {
+			s.undo = func(svc *ha.Service) error { return svc.Light.TurnOn(id) }
+		}
+		steps = append(steps, s)
+	}
+	for _, id := range r.Climate {
+		// A preset is an attribute, not the state, so the call's answer is
+		// all there is to verify it by.
+		s := step{
+			name: "climate", entity: string(id),
+			do: func(svc *ha.Service) error { return svc.Climate.SetPresetMode(id, r.Preset) },
+		}
+		if preset, err := ha.GetAttribute[string](state, id, "preset_mode"); err == nil && preset != "" {
+			s.undo = func(svc *ha.Service) error { return svc.Climate.SetPresetMode(id, preset) }
+		}
+		steps = append(steps, s)
+	}
+	for _, id := range r.Locks {
+		steps = append(steps, step{
+			name: "locks", entity: string(id), want: "locked",
+			do: func(svc *ha.Service) error { return svc.Lock.Lock(id) },
+		})
+	}
+	if r.Alarm != "" {
+		var data []map[string]any
+		if r.AlarmCode != "" {
+			data = append(data, map[string]any{"code": r.AlarmCode})
+		}
+		steps = append(steps, step{
+			name: "alarm", entity: string(r.Alarm), want: "armed_away",
+			do:   func(svc *ha.Service) error { return svc.AlarmControlPanel.ArmAway(r.Alarm, data...) },
+			undo: func(svc *ha.Service) error { return svc.AlarmControlPanel.Disarm(r.Alarm, data...) },
+		})
+	}
+	if r.Vacuum != "" {
+		steps = append(steps, step{
+			name: "vacuum", entity: string(r.Vacuum), want: "cleaning",
+			do:   func(svc *ha.Service) error { return svc.Vacuum.Start(r.Vacuum) },
+			undo: func(svc *ha.Service) error { return svc.Vacuum.ReturnToBase(r.Vacuum) },
+		})
+	}
+	return steps
+}
+
+// undo reverses the steps taken, latest first.
+func undo(svc *ha.Service, taken []step) error {
+	var errs []error
+	for i := len(taken) - 1; i >= 0; i-- {
+		if taken[i].undo == nil {
+			continue
+		}
+		if err := taken[i].undo(svc); err != nil {
+			errs = append(errs, fmt.Errorf("undoing %s: %w", taken[i].entity, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// everyoneAway reports whether every person is known to be somewhere other
+// than home. One who cannot be placed might be home, so counts as such.
+func everyoneAway(state ha.StateReader, people []string) bool {
+	for _, p := range people {
+		s, err := state.Get(p)
+		if err != nil || s.State == "home" || s.State == "unknown" || s.State == "unavailable" {
+			return false
+		}
+	}
+	return true
+}
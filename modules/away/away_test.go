@@ -0,0 +1,179 @@
+package away_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/away"
+	"github.com/Xevion/go-ha/services"
+)
+
+func settle() { time.Sleep(50 * time.Millisecond) }
+
+// house is two people at home with the lights on, the door unlocked and the
+// alarm off. Services that change an entity's state change it on the server,
+// as Home Assistant would, except for those listed as jammed.
+func house(t *testing.T, jammed ...string) *hatest.Server {
+	t.Helper()
+
+	server := hatest.New(t)
+	server.SetState("person.alice", "home")
+	server.SetState("person.bob", "home")
+	server.SetState("light.hall", "on")
+	server.SetState("light.porch", "off")
+	server.SetState("climate.hall", "heat", map[string]any{"preset_mode": "comfort"})
+	server.SetState("lock.front_door", "unlocked")
+	server.SetState("alarm_control_panel.house", "disarmed")
+
+	reflect := func(domain, service, state string) {
+		server.HandleService(domain, service, func(msg map[string]any) (any, error) {
+			target, _ := msg["target"].(map[string]any)
+			id, _ := target["entity_id"].(string)
+			for _, j := range jammed {
+				if j == id {
+					return nil, nil
+				}
+			}
+			go server.ChangeState(id, state)
+			return nil, nil
+		})
+	}
+	reflect("light", "turn_off", "off")
+	reflect("light", "turn_on", "on")
+	reflect("lock", "lock", "locked")
+	reflect("alarm_control_panel", "alarm_arm_away", "armed_away")
+	reflect("alarm_control_panel", "alarm_disarm", "disarmed")
+	return server
+}
+
+// recorder keeps what the routine reports.
+type recorder struct {
+	mu       sync.Mutex
+	done     int
+	failures []away.Failure
+}
+
+func (r *recorder) routine() away.Routine {
+	return away.Routine{
+		People:  []string{"person.alice", "person.bob"},
+		Settle:  100 * time.Millisecond,
+		Verify:  300 * time.Millisecond,
+		Lights:  []services.LightID{"light.hall", "light.porch"},
+		Climate: []services.ClimateID{"climate.hall"},
+		Locks:   []services.LockID{"lock.front_door"},
+		Alarm:   "alarm_control_panel.house",
+		OnDone: func(context.Context) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.done++
+			return nil
+		},
+		OnFailure: func(_ context.Context, f away.Failure) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.failures = append(r.failures, f)
+			return nil
+		},
+	}
+}
+
+func (r *recorder) results() (int, []away.Failure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.done, append([]away.Failure(nil), r.failures...)
+}
+
+func leave(server *hatest.Server, people ...string) {
+	for _, p := range people {
+		server.ChangeState(p, "not_home")
+	}
+}
+
+func TestRoutineRunsOnceEveryoneHasLeft(t *testing.T) {
+	server := house(t)
+	app := hatest.NewApp(t, server)
+	var rec recorder
+	require.NoError(t, away.Arm(app, rec.routine()))
+	hatest.StartApp(t, app)
+
+	// One leaving is not everyone.
+	leave(server, "person.alice")
+	time.Sleep(250 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+
+	leave(server, "person.bob")
+	server.AssertServiceCalled("alarm_control_panel", "alarm_arm_away", "alarm_control_panel.house")
+	settle()
+
+	server.AssertServiceCalled("light", "turn_off", "light.hall")
+	server.AssertServiceCalled("light", "turn_off", "light.porch")
+	call, _ := server.AssertServiceCalled("climate", "set_preset_mode", "climate.hall")
+	assert.Equal(t, "eco", call.ServiceData["preset_mode"])
+	server.AssertServiceCalled("lock", "lock", "lock.front_door")
+	done, failures := rec.results()
+	assert.Equal(t, 1, done)
+	assert.Empty(t, failures)
+
+	// Moving on to work is no new departure.
+	server.ResetCalls()
+	server.ChangeState("person.bob", "Work")
+	time.Sleep(250 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+
+	// After coming home, the next departure runs it again.
+	server.ChangeState("person.alice", "home")
+	settle()
+	leave(server, "person.alice")
+	server.AssertServiceCalled("alarm_control_panel", "alarm_arm_away", "alarm_control_panel.house")
+}
+
+func TestFailedStepStopsAndRollsBack(t *testing.T) {
+	server := house(t, "lock.front_door")
+	app := hatest.NewApp(t, server)
+	var rec recorder
+	r := rec.routine()
+	r.RollBack = true
+	require.NoError(t, away.Arm(app, r))
+	hatest.StartApp(t, app)
+
+	leave(server, "person.alice", "person.bob")
+	server.AssertServiceCalled("lock", "lock", "lock.front_door")
+	time.Sleep(500 * time.Millisecond)
+
+	_, failures := rec.results()
+	require.Len(t, failures, 1)
+	f := failures[0]
+	assert.Equal(t, "locks", f.Step)
+	assert.Equal(t, "lock.front_door", f.Entity)
+	assert.ErrorIs(t, f.Err, ha.ErrWaitTimeout)
+	assert.True(t, f.RolledBack)
+	assert.NoError(t, f.RollBackErr)
+
+	assert.True(t, server.AssertServiceNotCalled("alarm_control_panel", "alarm_arm_away", "alarm_control_panel.house"),
+		"no alarm is armed over a door left unlocked")
+	server.AssertServiceCalled("light", "turn_on", "light.hall")
+	assert.True(t, server.AssertServiceNotCalled("light", "turn_on", "light.porch"), "it was off to begin with")
+	call, _ := server.AssertServiceCalled("climate", "set_preset_mode", "climate.hall")
+	assert.Equal(t, "eco", call.ServiceData["preset_mode"])
+	assert.True(t, server.AssertServiceNotCalled("lock", "unlock", "lock.front_door"))
+
+	var restored bool
+	for _, c := range server.Calls() {
+		if c.Service == "set_preset_mode" && c.ServiceData["preset_mode"] == "comfort" {
+			restored = true
+		}
+	}
+	assert.True(t, restored, "the thermostat goes back to its preset")
+}
+
+func TestArmRejectsARoutineWithoutPeople(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+	assert.ErrorIs(t, away.Arm(app, away.Routine{}), ha.ErrInvalidArgs)
+}
@@ -0,0 +1,243 @@
+// Package bathroom runs a bathroom's extractor fan from its humidity.
+//
+// A humidity threshold makes a poor fan switch: the same bathroom reads 55
+// percent on a dry winter day and 75 on a muggy summer one, so a threshold
+// low enough to catch every shower runs the fan all summer. A shower gives
+// itself away by how fast it drives the humidity up, whatever it started
+// from, so ExhaustFan starts the fan on the rise and runs it until the
+// humidity has come back down to where it was.
+package bathroom
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/modules/trend"
+	"github.com/Xevion/go-ha/services"
+)
+
+// Bathroom describes a bathroom's fan and the sensor it answers to.
+type Bathroom struct {
+	// Humidity is the bathroom's humidity sensor, in percent. Required.
+	Humidity string
+
+	// Fan is the fan, or the switch that powers it. It is turned on and off
+	// with homeassistant.turn_on and turn_off, so either kind works.
+	// Required.
+	Fan services.EntityID
+
+	// Rise is how fast the humidity has to climb, in points a minute over
+	// Window, to count as a shower. Defaults to one and a half.
+	Rise float64
+
+	// Window is the span the rise is measured over. Defaults to five
+	// minutes.
+	Window time.Duration
+
+	// Margin is how close to its reading when the shower was noticed the
+	// humidity has to fall before the fan stops. Defaults to two points.
+	Margin float64
+
+	// MinRun is the least the fan runs once started, however quickly the
+	// humidity falls. Defaults to five minutes.
+	MinRun time.Duration
+
+	// MaxRun is the most it runs, for a window left open on a wet day that
+	// keeps the humidity up whatever the fan does. Defaults to an hour.
+	MaxRun time.Duration
+}
+
+// bathroom is the running state of one Bathroom.
+type bathroom struct {
+	Bathroom
+	app *ha.App
+
+	mu sync.Mutex
+
+	// running reports a run this package started and has yet to end;
+	// started and baseline are when it started and what the humidity read
+	// then, and peak the highest it has read since.
+	running  bool
+	started  time.Time
+	baseline float64
+	peak     float64
+
+	// manual reports a fan someone turned on by hand. It is left alone until
+	// it is off again.
+	manual bool
+
+	// stop cancels the pending MinRun and MaxRun timers of the run.
+	stop []func() bool
+
+	// gen numbers the runs, so a MaxRun timer that fires after its run has
+	// ended cannot end the next.
+	gen uint64
+}
+
+// ExhaustFan runs the fan whenever the humidity rises like a shower, from
+// when the rise is noticed until the humidity has peaked and fallen back
+// within the margin of what it read then, for no less than MinRun and no more
+// than MaxRun.
+//
+// A fan turned on by hand is left running: it is only turned off by hand, and
+// a shower meanwhile does not take it over. A fan turned off by hand during a
+// run ends the run, and the next shower starts a new one.
+func ExhaustFan(app *ha.App, b Bathroom) error {
+	switch {
+	case b.Humidity == "":
+		return fmt.Errorf("%w: an exhaust fan needs a humidity sensor", ha.ErrInvalidArgs)
+	case b.Fan == "":
+		return fmt.Errorf("%w: exhaust fan for %s needs a fan", ha.ErrInvalidArgs, b.Humidity)
+	case b.Rise < 0 || b.Margin < 0 || b.Window < 0 || b.MinRun < 0 || b.MaxRun < 0:
+		return fmt.Errorf("%w: exhaust fan %s has a negative setting", ha.ErrInvalidArgs, b.Fan)
+	}
+	if b.Rise == 0 {
+		b.Rise = 1.5
+	}
+	if b.Window == 0 {
+		b.Window = 5 * time.Minute
+	}
+	if b.Margin == 0 {
+		b.Margin = 2
+	}
+	if b.MinRun == 0 {
+		b.MinRun = 5 * time.Minute
+	}
+	if b.MaxRun == 0 {
+		b.MaxRun = time.Hour
+	}
+	if b.MinRun > b.MaxRun {
+		return fmt.Errorf("%w: exhaust fan %s MinRun %s is longer than MaxRun %s", ha.ErrInvalidArgs, b.Fan, b.MinRun, b.MaxRun)
+	}
+
+	br := &bathroom{Bathroom: b, app: app}
+	tracker, err := trend.NewTracker(app, trend.Options{Window: b.Window}, b.Humidity)
+	if err != nil {
+		return err
+	}
+	if err := tracker.OnTrendAbove(b.Humidity, b.Rise, func(float64) { br.shower() }); err != nil {
+		return err
+	}
+
+	name := "exhaust fan " + string(b.Fan)
+	a, err := ha.NewAutomation(name).
+		On(ha.StateChanged(b.Humidity), ha.StateChanged(b.Fan)).
+		Mode(ha.ModeQueued).
+		Do(func(_ context.Context, run ha.Run) error {
+			if run.Event.EntityID == string(b.Fan) {
+				br.changed(run.Event.From.State, run.Event.To.State)
+				return nil
+			}
+			br.settle(run.State)
+			return nil
+		}).
+		Build()
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return app.RegisterAutomations(a)
+}
+
+// shower starts a run, unless one is going or the fan is on by hand.
+func (br *bathroom) shower() {
+	humidity, ok := reading(br.app.State(), br.Humidity)
+	if !ok {
+		return
+	}
+
+	br.mu.Lock()
+	if br.running || br.manual {
+		br.mu.Unlock()
+		return
+	}
+	// Marked running before the call goes out, so the fan coming on is not
+	// taken for someone at the switch.
+	br.running, br.started, br.baseline, br.peak = true, br.app.Clock().Now(), humidity, humidity
+	mine := br.gen
+	br.stop = []func() bool{
+		br.app.AfterFunc(br.MinRun, func() { br.settle(br.app.State()) }),
+		br.app.AfterFunc(br.MaxRun, func() { br.end(mine, "ran for its longest") }),
+	}
+	br.mu.Unlock()
+
+	if err := br.app.Services().HomeAssistant.TurnOn(br.Fan); err != nil {
+		br.app.Logger().Error("Exhaust fan could not be turned on", "fan", br.Fan, "error", err)
+	}
+}
+
+// settle ends the run once it has lasted MinRun and the humidity is back
+// within the margin of where it started. A shower noticed early in its rise
+// can still read close to that, so the humidity has to be falling from its
+// peak as well.
+func (br *bathroom) settle(state ha.StateReader) {
+	humidity, ok := reading(state, br.Humidity)
+	if !ok {
+		return
+	}
+	br.mu.Lock()
+	br.peak = max(br.peak, humidity)
+	done := br.running &&
+		br.app.Clock().Now().Sub(br.started) >= br.MinRun &&
+		humidity <= br.baseline+br.Margin &&
+		humidity < br.peak
+	gen := br.gen
+	br.mu.Unlock()
+	if done {
+		br.end(gen, "humidity is back down")
+	}
+}
+
+// end turns the fan off, if the run numbered gen is still going.
+func (br *bathroom) end(gen uint64, why string) {
+	br.mu.Lock()
+	if !br.running || br.gen != gen {
+		br.mu.Unlock()
+		return
+	}
+	br.stopLocked()
+	br.mu.Unlock()
+
+	br.app.Logger().Info("Turning exhaust fan off", "fan", br.Fan, "reason", why)
+	if err := br.app.Services().HomeAssistant.TurnOff(br.Fan); err != nil {
+		br.app.Logger().Error("Exhaust fan could not be turned off", "fan", br.Fan, "error", err)
+	}
+}
+
+// changed tells a run's own switching from someone else's.
+func (br *bathroom) changed(from, to string) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	switch {
+	case to == "off":
+		// Whether a run ending or someone ending it early, there is nothing
+		// left to turn off.
+		br.stopLocked()
+		br.manual = false
+	case to == "on" && from != "on" && !br.running:
+		br.manual = true
+	}
+}
+
+// stopLocked ends the run, abandoning its timers.
+func (br *bathroom) stopLocked() {
+	for _, stop := range br.stop {
+		stop()
+	}
+	br.stop, br.running = nil, false
+	br.gen++
+}
+
+// reading is the sensor's numeric state, if it has one.
+func reading(state ha.StateReader, id string) (float64, bool) {
+	s, err := state.Get(id)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s.State, 64)
+	return v, err == nil
+}
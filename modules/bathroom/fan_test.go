@@ -0,0 +1,150 @@
+package bathroom_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/bathroom"
+	"github.com/Xevion/go-ha/types"
+)
+
+const (
+	humidity = "sensor.bathroom_humidity"
+	fan      = "switch.bathroom_fan"
+)
+
+// start runs the bathroom's fan on a clock moved by hand, so a shower can
+// last minutes without the test taking them.
+func start(t *testing.T, b bathroom.Bathroom) (*hatest.Server, *hatest.Clock) {
+	t.Helper()
+
+	server := hatest.New(t)
+	server.SetState(humidity, "60")
+	server.SetState(fan, "off")
+	clock := hatest.NewClock(time.Date(2026, 3, 1, 7, 0, 0, 0, time.UTC))
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.Clock = clock })
+
+	b.Humidity, b.Fan = humidity, fan
+	require.NoError(t, bathroom.ExhaustFan(app, b))
+	hatest.StartApp(t, app)
+	return server, clock
+}
+
+// report moves the clock on a minute and publishes a new reading.
+func report(server *hatest.Server, clock *hatest.Clock, value string) {
+	clock.Advance(time.Minute)
+	server.ChangeState(humidity, value)
+	settle()
+}
+
+func settle() { time.Sleep(50 * time.Millisecond) }
+
+// shower drives the humidity up until the fan starts.
+func shower(t *testing.T, server *hatest.Server, clock *hatest.Clock) {
+	t.Helper()
+	for _, v := range []string{"61", "64", "67"} {
+		report(server, clock, v)
+	}
+	server.AssertServiceCalled("homeassistant", "turn_on", fan)
+	server.ChangeState(fan, "on")
+	settle()
+}
+
+func TestShowerRunsTheFanUntilTheHumidityFalls(t *testing.T) {
+	server, clock := start(t, bathroom.Bathroom{MinRun: time.Minute})
+
+	// A damp day drifts without starting it.
+	report(server, clock, "60.5")
+	report(server, clock, "61.5")
+	assert.True(t, server.AssertServiceNotCalled("homeassistant", "turn_on", fan))
+	clock.Advance(5 * time.Minute)
+
+	// Noticed at 64.
+	shower(t, server, clock)
+	server.ResetCalls()
+
+	// Still well above where the shower was noticed.
+	report(server, clock, "70")
+	report(server, clock, "67")
+	assert.True(t, server.AssertServiceNotCalled("homeassistant", "turn_off", fan))
+
+	// Back within the margin of 64.
+	report(server, clock, "65.5")
+	server.AssertServiceCalled("homeassistant", "turn_off", fan)
+}
+
+func TestFanRunsAtLeastMinRunAndAtMostMaxRun(t *testing.T) {
+	server, clock := start(t, bathroom.Bathroom{MinRun: 10 * time.Minute, MaxRun: 20 * time.Minute})
+
+	shower(t, server, clock)
+	server.ResetCalls()
+
+	// A quick shower: dry again well before MinRun.
+	report(server, clock, "62")
+	assert.True(t, server.AssertServiceNotCalled("homeassistant", "turn_off", fan))
+
+	// MinRun from the start, noticed at 64, is eight minutes on.
+	clock.Advance(8 * time.Minute)
+	settle()
+	server.AssertServiceCalled("homeassistant", "turn_off", fan)
+	server.ChangeState(fan, "off")
+	settle()
+	server.ResetCalls()
+
+	// A second shower that never dries out stops at MaxRun.
+	for _, v := range []string{"60", "63", "66", "69"} {
+		report(server, clock, v)
+	}
+	server.AssertServiceCalled("homeassistant", "turn_on", fan)
+	server.ChangeState(fan, "on")
+	settle()
+
+	clock.Advance(15 * time.Minute)
+	settle()
+	assert.True(t, server.AssertServiceNotCalled("homeassistant", "turn_off", fan))
+	clock.Advance(5 * time.Minute)
+	settle()
+	server.AssertServiceCalled("homeassistant", "turn_off", fan)
+}
+
+func TestFanTurnedOnByHandIsLeftAlone(t *testing.T) {
+	server, clock := start(t, bathroom.Bathroom{MinRun: time.Minute})
+
+	server.ChangeState(fan, "on")
+	settle()
+
+	for _, v := range []string{"61", "64", "67", "60"} {
+		report(server, clock, v)
+	}
+	clock.Advance(2 * time.Hour)
+	settle()
+	assert.Empty(t, server.Calls(), "a fan on by hand is neither taken over nor turned off")
+}
+
+func TestFanTurnedOffByHandEndsTheRun(t *testing.T) {
+	server, clock := start(t, bathroom.Bathroom{MinRun: time.Minute, MaxRun: 10 * time.Minute})
+
+	shower(t, server, clock)
+	server.ResetCalls()
+
+	server.ChangeState(fan, "off")
+	settle()
+	clock.Advance(10 * time.Minute)
+	settle()
+	assert.Empty(t, server.Calls(), "nothing left to turn off")
+}
+
+func TestExhaustFanRejectsBadSettings(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+
+	err := bathroom.ExhaustFan(app, bathroom.Bathroom{Fan: fan})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "no humidity sensor")
+
+	err = bathroom.ExhaustFan(app, bathroom.Bathroom{Humidity: humidity, Fan: fan, MinRun: time.Hour, MaxRun: time.Minute})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "MinRun longer than MaxRun")
+}
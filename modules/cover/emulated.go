@@ -0,0 +1,324 @@
+// Package cover estimates the position of covers that cannot report one.
+//
+// Plenty of blinds and garage doors are driven by a relay or an RF remote:
+// Home Assistant can tell them to open, close or stop, but has no idea where
+// they are. Given how long a full travel takes, the position can be estimated
+// from when each movement started, which is what lets an automation ask for
+// "half open" of a cover that only understands open and close.
+package cover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/services"
+)
+
+// ErrInvalidPosition reports a position outside 0 to 100.
+var ErrInvalidPosition = errors.New("position must be between 0 and 100")
+
+// Options describes how a cover travels.
+type Options struct {
+	// OpenTime is how long the cover takes to travel from fully closed to
+	// fully open. Required.
+	OpenTime time.Duration
+
+	// CloseTime is how long the return journey takes. Defaults to OpenTime;
+	// set it for covers that close under gravity faster than they open.
+	CloseTime time.Duration
+
+	// Sensor is the entity the estimate is published on. Defaults to
+	// sensor.<cover>_position.
+	Sensor string
+}
+
+// Emulated tracks one cover's estimated position. Drive the cover through its
+// methods rather than through the Cover service directly, so every movement is
+// seen; movements started elsewhere are picked up only if the cover reports
+// opening and closing states.
+type Emulated struct {
+	app       *ha.App
+	entity    services.CoverID
+	sensor    string
+	openTime  time.Duration
+	closeTime time.Duration
+
+	mu sync.Mutex
+	// position is where the cover was at since, and direction is which way it
+	// has been travelling since then: +1 opening, -1 closing, 0 still.
+	position  float64
+	direction int
+	since     time.Time
+
+	// arrival stops the timer that settles the current movement. gen numbers
+	// movements so a timer that fires after being superseded recognises itself
+	// and does nothing.
+	arrival func() bool
+	gen     uint64
+}
+
+// Emulate starts tracking a cover, publishing its estimated position as a
+// sensor. The initial estimate is taken from the cover's reported state: closed
+// is 0, anything else is assumed fully open.
+func Emulate(app *ha.App, entity services.CoverID, opts Options) (*Emulated, error) {
+	if opts.OpenTime <= 0 {
+		return nil, fmt.Errorf("%w: emulating %s needs an OpenTime", ha.ErrInvalidArgs, entity)
+	}
+	if opts.CloseTime <= 0 {
+		opts.CloseTime = opts.OpenTime
+	}
+	if opts.Sensor == "" {
+		_, object, _ := strings.Cut(string(entity), ".")
+		opts.Sensor = "sensor." + object + "_position"
+	}
+
+	e := &Emulated{
+		app:       app,
+		entity:    entity,
+		sensor:    opts.Sensor,
+		openTime:  opts.OpenTime,
+		closeTime: opts.CloseTime,
+		since:     app.Clock().Now(),
+	}
+
+	if current, err := app.State().Get(string(entity)); err == nil && current.State != "closed" {
+		e.position = 100
+	}
+
+	a, err := ha.NewAutomation("emulated position of " + string(entity)).
+		On(ha.StateChanged(entity)).
+		Mode(ha.ModeQueued).
+		Do(func(_ context.Context, run ha.Run) error {
+			e.observe(run.Event.To.State)
+			return nil
+		}).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("emulating %s: %w", entity, err)
+	}
+	if err := app.RegisterAutomations(a); err != nil {
+		return nil, err
+	}
+	// A movement still under way when the app stops is left where the
+	// estimate has it, rather than stopped from a closing app.
+	app.OnStop(func(context.Context) error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.gen++
+		if e.arrival != nil {
+			e.arrival()
+			e.arrival = nil
+		}
+		return nil
+	})
+
+	e.publish()
+	return e, nil
+}
+
+// Sensor is the entity the estimate is published on.
+func (e *Emulated) Sensor() string { return e.sensor }
+
+// Position reports the estimated position, 0 closed to 100 open.
+func (e *Emulated) Position() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return int(math.Round(e.estimateLocked(e.app.Clock().Now())))
+}
+
+// Open opens the cover fully.
+func (e *Emulated) Open() error {
+	if err := e.app.Services().Cover.Open(e.entity); err != nil {
+		return err
+	}
+	e.move(100, false)
+	return nil
+}
+
+// Close closes the cover fully.
+func (e *Emulated) Close() error {
+	if err := e.app.Services().Cover.Close(e.entity); err != nil {
+		return err
+	}
+	e.move(0, false)
+	return nil
+}
+
+// Stop halts the cover where it is.
+func (e *Emulated) Stop() error {
+	if err := e.app.Services().Cover.Stop(e.entity); err != nil {
+		return err
+	}
+	e.halt()
+	return nil
+}
+
+// SetPositionEmulated moves the cover to pct by running it for the share of its
+// travel time the distance accounts for, then stopping it. The ends are left to
+// the cover's own limit switches rather than stopped on a timer, which keeps a
+// drifting estimate from leaving it a few percent short.
+func (e *Emulated) SetPositionEmulated(pct int) error {
+	if pct < 0 || pct > 100 {
+		return fmt.Errorf("%w: %d", ErrInvalidPosition, pct)
+	}
+
+	current := e.Position()
+	var err error
+	switch {
+	case pct == current:
+		return nil
+	case pct > current:
+		err = e.app.Services().Cover.Open(e.entity)
+	default:
+		err = e.app.Services().Cover.Close(e.entity)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.move(float64(pct), pct != 0 && pct != 100)
+	return nil
+}
+
+// observe follows the state the cover reports, for movements started outside
+// this helper. A dumb cover typically claims open or closed the moment it is
+// told to move, so only the transitional states start a movement here, and
+// closed is trusted only while nothing is moving.
+func (e *Emulated) observe(reported string) {
+	e.mu.Lock()
+	direction := e.direction
+	e.mu.Unlock()
+
+	switch reported {
+	case "opening":
+		if direction != 1 {
+			e.move(100, false)
+		}
+	case "closing":
+		if direction != -1 {
+			e.move(0, false)
+		}
+	case "closed":
+		if direction == 0 {
+			e.settle(0)
+		}
+	}
+}
+
+// move starts travel towards target from wherever the cover is now. With stop
+// set, the cover is told to stop on arrival.
+func (e *Emulated) move(target float64, stop bool) {
+	e.mu.Lock()
+	now := e.app.Clock().Now()
+	from := e.estimateLocked(now)
+
+	e.position = from
+	e.since = now
+	e.gen++
+	mine := e.gen
+	if e.arrival != nil {
+		e.arrival()
+	}
+
+	full := e.openTime
+	e.direction = 1
+	if target < from {
+		full = e.closeTime
+		e.direction = -1
+	}
+	travel := time.Duration(math.Abs(target-from) / 100 * float64(full))
+
+	e.arrival = e.app.AfterFunc(travel, func() {
+		e.mu.Lock()
+		current := e.gen == mine
+		e.mu.Unlock()
+		if !current {
+			return
+		}
+
+		if stop {
+			if err := e.app.Services().Cover.Stop(e.entity); err != nil {
+				e.app.Logger().Warn("Failed to stop an emulated cover", "cover", e.entity, "error", err)
+			}
+		}
+		e.settle(target)
+	})
+	e.mu.Unlock()
+
+	e.publish()
+}
+
+// halt freezes the estimate where the cover is now.
+func (e *Emulated) halt() {
+	e.mu.Lock()
+	now := e.app.Clock().Now()
+	e.position = e.estimateLocked(now)
+	e.stopLocked(now)
+	e.mu.Unlock()
+
+	e.publish()
+}
+
+// settle records the cover as at rest in a known position.
+func (e *Emulated) settle(position float64) {
+	e.mu.Lock()
+	e.position = position
+	e.stopLocked(e.app.Clock().Now())
+	e.mu.Unlock()
+
+	e.publish()
+}
+
+func (e *Emulated) stopLocked(now time.Time) {
+	e.direction = 0
+	e.since = now
+	e.gen++
+	if e.arrival != nil {
+		e.arrival()
+		e.arrival = nil
+	}
+}
+
+// estimateLocked projects the position forward from the last known point.
+func (e *Emulated) estimateLocked(now time.Time) float64 {
+	elapsed := now.Sub(e.since)
+	switch e.direction {
+	case 1:
+		return math.Min(100, e.position+100*float64(elapsed)/float64(e.openTime))
+	case -1:
+		return math.Max(0, e.position-100*float64(elapsed)/float64(e.closeTime))
+	}
+	return e.position
+}
+
+// publish writes the estimate to the sensor. A failure is logged rather than
+// returned: the cover moved regardless, and the next publish corrects it.
+func (e *Emulated) publish() {
+	e.mu.Lock()
+	position := int(math.Round(e.estimateLocked(e.app.Clock().Now())))
+	moving := ""
+	switch e.direction {
+	case 1:
+		moving = "opening"
+	case -1:
+		moving = "closing"
+	}
+	e.mu.Unlock()
+
+	err := e.app.SetState(e.sensor, strconv.Itoa(position), map[string]any{
+		"unit_of_measurement": "%",
+		"cover":               string(e.entity),
+		"moving":              moving,
+		"emulated":            true,
+	})
+	if err != nil {
+		e.app.Logger().Warn("Failed to publish an emulated cover position", "sensor", e.sensor, "error", err)
+	}
+}
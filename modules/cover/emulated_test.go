@@ -0,0 +1,118 @@
+package cover_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/cover"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestEmulateNeedsATravelTime(t *testing.T) {
+	server := hatest.New(t)
+	app := hatest.NewApp(t, server)
+	hatest.StartApp(t, app)
+
+	_, err := cover.Emulate(app, "cover.blind", cover.Options{})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
+
+func TestEmulatedPositionFollowsTravel(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("cover.blind", "closed")
+	app := hatest.NewApp(t, server)
+	hatest.StartApp(t, app)
+
+	blind, err := cover.Emulate(app, "cover.blind", cover.Options{OpenTime: 400 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, "sensor.blind_position", blind.Sensor())
+	assert.Equal(t, 0, blind.Position(), "a closed cover starts at zero")
+
+	require.NoError(t, blind.Open())
+	time.Sleep(200 * time.Millisecond)
+	assert.InDelta(t, 50, blind.Position(), 15, "half the travel time is about half open")
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 100, blind.Position())
+
+	state, attrs, ok := server.State("sensor.blind_position")
+	require.True(t, ok, "the estimate is published as a sensor")
+	assert.Equal(t, "100", state)
+	assert.Equal(t, "%", attrs["unit_of_measurement"])
+
+	calls := server.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "open_cover", calls[0].Service)
+}
+
+// An intermediate position is reached by running the cover and stopping it
+// once the share of travel time has elapsed.
+func TestSetPositionEmulatedStopsPartWay(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("cover.blind", "open")
+	app := hatest.NewApp(t, server)
+	hatest.StartApp(t, app)
+
+	blind, err := cover.Emulate(app, "cover.blind", cover.Options{OpenTime: 400 * time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, 100, blind.Position())
+
+	require.NoError(t, blind.SetPositionEmulated(25))
+
+	calls := server.WaitForCalls(2)
+	assert.Equal(t, "close_cover", calls[0].Service)
+	assert.Equal(t, "stop_cover", calls[1].Service)
+	assert.Equal(t, 25, blind.Position())
+
+	assert.ErrorIs(t, blind.SetPositionEmulated(120), cover.ErrInvalidPosition)
+}
+
+// A movement started from Home Assistant's own UI shows up as a transitional
+// state, which is enough to start the estimate moving.
+func TestEmulatedCoverFollowsReportedMovement(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("cover.blind", "closed")
+	app := hatest.NewApp(t, server)
+	hatest.StartApp(t, app)
+
+	blind, err := cover.Emulate(app, "cover.blind", cover.Options{OpenTime: 200 * time.Millisecond})
+	require.NoError(t, err)
+
+	server.ChangeState("cover.blind", "opening")
+	time.Sleep(350 * time.Millisecond)
+	assert.Equal(t, 100, blind.Position())
+}
+
+func TestEmulatedCoverTravelsOnTheAppClock(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("cover.blind", "closed")
+	clock := hatest.NewClock(time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC))
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.Clock = clock })
+	hatest.StartApp(t, app)
+
+	blind, err := cover.Emulate(app, "cover.blind", cover.Options{OpenTime: time.Minute})
+	require.NoError(t, err)
+	require.NoError(t, blind.SetPositionEmulated(50))
+
+	clock.Advance(15 * time.Second)
+	assert.Equal(t, 25, blind.Position())
+	assert.Len(t, server.Calls(), 1, "still on its way")
+
+	clock.Advance(15 * time.Second)
+	calls := server.WaitForCalls(2)
+	assert.Equal(t, "stop_cover", calls[1].Service)
+	assert.Equal(t, 50, blind.Position())
+}
+
+func TestEmulateRejectsAnIDWithoutADomain(t *testing.T) {
+	server := hatest.New(t)
+	app := hatest.NewApp(t, server)
+
+	_, err := cover.Emulate(app, "blind", cover.Options{OpenTime: time.Minute})
+	assert.ErrorIs(t, err, ha.ErrInvalidAutomation)
+}
@@ -0,0 +1,221 @@
+// Package delivery tells when the post or a parcel has arrived.
+//
+// No one sensor says so. A contact sensor on the mailbox lid opens for the
+// postman, and again for whoever fetches the post; a camera's object detection
+// sees a package on the step, and keeps seeing it every few minutes until it
+// is brought in. A Detector takes signals from either and turns them into
+// deliveries: the lid and the camera seeing the same courier are one, and a
+// package detected over and over is one, however long it sits there.
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+)
+
+// Sources are the signals a Detector takes a delivery from. At least one of
+// Mailboxes, Detectors and Events is required.
+type Sources struct {
+	// Mailboxes are contact sensors on a mailbox or parcel box. One opening
+	// is a delivery.
+	Mailboxes []string
+
+	// Detectors are binary sensors a camera's object detection turns on when
+	// it sees a package, such as binary_sensor.porch_package_occupancy. One
+	// turning on is a delivery.
+	Detectors []string
+
+	// Events are Home Assistant event types object detection is reported as,
+	// for integrations that fire events rather than keep a sensor. An event
+	// is a delivery if the label in its data is one of Labels.
+	Events []string
+
+	// Labels are the detected objects that count as a delivery. Defaults to
+	// package.
+	Labels []string
+
+	// Suppress is how long after one signal another is taken for the same
+	// delivery. Each signal starts it over, so a package detected every few
+	// minutes is one delivery until it has gone unseen this long. Defaults
+	// to fifteen minutes.
+	Suppress time.Duration
+
+	// DayStart is how far past midnight a day's deliveries are counted from,
+	// so a parcel left late in the evening counts towards that day. Defaults
+	// to midnight.
+	DayStart time.Duration
+}
+
+// Delivery is one arrival.
+type Delivery struct {
+	// At is when it was first seen, and Source the entity or event type that
+	// saw it.
+	At     time.Time
+	Source string
+
+	// Count is how many deliveries there have been today, this one included.
+	Count int
+}
+
+// Detector turns signals into deliveries. Each day's count starts at zero
+// again at its DayStart.
+type Detector struct {
+	app *ha.App
+	src Sources
+
+	mu sync.Mutex
+
+	// seen is when the last signal came, and last the delivery it was part
+	// of.
+	seen time.Time
+	last Delivery
+
+	// day is the start of the day count covers.
+	day   time.Time
+	count int
+
+	fns []func(Delivery)
+}
+
+// NewDetector starts watching the sources for deliveries.
+func NewDetector(app *ha.App, src Sources) (*Detector, error) {
+	if len(src.Mailboxes)+len(src.Detectors)+len(src.Events) == 0 {
+		return nil, fmt.Errorf("%w: a delivery detector needs a mailbox, detector or event", ha.ErrInvalidArgs)
+	}
+	if src.Suppress < 0 || src.DayStart < 0 || src.DayStart >= 24*time.Hour {
+		return nil, fmt.Errorf("%w: delivery detector has a negative Suppress or a DayStart outside the day", ha.ErrInvalidArgs)
+	}
+	if src.Suppress == 0 {
+		src.Suppress = 15 * time.Minute
+	}
+	if len(src.Labels) == 0 {
+		src.Labels = []string{"package"}
+	}
+
+	d := &Detector{app: app, src: src}
+	var triggers []ha.Trigger
+	if sensors := append(slices.Clone(src.Mailboxes), src.Detectors...); len(sensors) > 0 {
+		// From off, so a sensor coming back from unavailable already on is
+		// not taken for a delivery.
+		triggers = append(triggers, ha.StateChanged(sensors...).From("off").To("on"))
+	}
+	if len(src.Events) > 0 {
+		triggers = append(triggers, ha.EventFired(src.Events...))
+	}
+
+	name := "delivery detector"
+	a, err := ha.NewAutomation(name).
+		On(triggers...).
+		Mode(ha.ModeQueued).
+		Do(func(_ context.Context, run ha.Run) error {
+			if run.Event.EntityID != "" {
+				d.signal(run.Event.EntityID)
+				return nil
+			}
+			if source, ok := d.detected(run.Event); ok {
+				d.signal(source)
+			}
+			return nil
+		}).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := app.RegisterAutomations(a); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// OnDelivery calls fn for each delivery, once however many signals it gives.
+func (d *Detector) OnDelivery(fn func(Delivery)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fns = append(d.fns, fn)
+}
+
+// Today is how many deliveries there have been since the day's DayStart.
+func (d *Detector) Today() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rollLocked(d.app.Clock().Now())
+	return d.count
+}
+
+// Last is the latest delivery, if there has been one today.
+func (d *Detector) Last() (Delivery, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rollLocked(d.app.Clock().Now())
+	return d.last, d.count > 0
+}
+
+// signal records a signal from source, and reports a delivery if it is not
+// part of the last.
+func (d *Detector) signal(source string) {
+	now := d.app.Clock().Now()
+
+	d.mu.Lock()
+	d.rollLocked(now)
+	fresh := d.seen.IsZero() || now.Sub(d.seen) >= d.src.Suppress
+	d.seen = now
+	if !fresh {
+		d.mu.Unlock()
+		return
+	}
+	d.count++
+	d.last = Delivery{At: now, Source: source, Count: d.count}
+	delivery, fns := d.last, slices.Clone(d.fns)
+	d.mu.Unlock()
+
+	d.app.Logger().Info("Delivery detected", "source", source, "today", delivery.Count)
+	// Outside the lock, so a callback can ask for Today of its own.
+	for _, fn := range fns {
+		fn(delivery)
+	}
+}
+
+// rollLocked starts the count over if now falls in a later day than it
+// covers.
+func (d *Detector) rollLocked(now time.Time) {
+	shifted := now.Add(-d.src.DayStart)
+	day := time.Date(shifted.Year(), shifted.Month(), shifted.Day(), 0, 0, 0, 0, now.Location()).Add(d.src.DayStart)
+	if day.After(d.day) {
+		d.day, d.count, d.last = day, 0, Delivery{}
+	}
+}
+
+// detected reads an object detection event, reporting what saw it if the
+// object is one of Labels. Integrations name the object label or object, and
+// the camera entity_id or camera.
+func (d *Detector) detected(ev ha.Event) (string, bool) {
+	var msg struct {
+		Event struct {
+			Data struct {
+				Label    string `json:"label"`
+				Object   string `json:"object"`
+				EntityID string `json:"entity_id"`
+				Camera   string `json:"camera"`
+			} `json:"data"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(ev.Raw, &msg); err != nil {
+		return "", false
+	}
+	data := msg.Event.Data
+	if !slices.Contains(d.src.Labels, data.Label) && !slices.Contains(d.src.Labels, data.Object) {
+		return "", false
+	}
+	for _, source := range []string{data.EntityID, data.Camera} {
+		if source != "" {
+			return source, true
+		}
+	}
+	return ev.Type, true
+}
@@ -0,0 +1,140 @@
+package delivery_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/delivery"
+	"github.com/Xevion/go-ha/types"
+)
+
+const (
+	mailbox = "binary_sensor.mailbox_lid"
+	porch   = "binary_sensor.porch_package_occupancy"
+)
+
+func settle() { time.Sleep(50 * time.Millisecond) }
+
+// start runs a detector on a clock moved by hand, set to the given time of day
+// on the first of March, and collects the deliveries it reports.
+func start(t *testing.T, at time.Duration, src delivery.Sources) (*hatest.Server, *hatest.Clock, *delivery.Detector, func() []delivery.Delivery) {
+	t.Helper()
+
+	server := hatest.New(t)
+	server.SetState(mailbox, "off")
+	server.SetState(porch, "off")
+	clock := hatest.NewClock(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC).Add(at))
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.Clock = clock })
+
+	d, err := delivery.NewDetector(app, src)
+	require.NoError(t, err)
+	var mu sync.Mutex
+	var got []delivery.Delivery
+	d.OnDelivery(func(dl delivery.Delivery) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, dl)
+	})
+	hatest.StartApp(t, app)
+
+	return server, clock, d, func() []delivery.Delivery {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]delivery.Delivery(nil), got...)
+	}
+}
+
+// pulse turns the sensor on and off again.
+func pulse(server *hatest.Server, id string) {
+	server.ChangeState(id, "on")
+	settle()
+	server.ChangeState(id, "off")
+	settle()
+}
+
+func TestSignalsCloseTogetherAreOneDelivery(t *testing.T) {
+	server, clock, d, got := start(t, 10*time.Hour, delivery.Sources{Mailboxes: []string{mailbox}, Detectors: []string{porch}})
+
+	// The courier is seen on the step, then opens the parcel box.
+	pulse(server, porch)
+	clock.Advance(time.Minute)
+	pulse(server, mailbox)
+
+	// The package is seen again every ten minutes until it is brought in.
+	for range 3 {
+		clock.Advance(10 * time.Minute)
+		pulse(server, porch)
+	}
+	require.Len(t, got(), 1)
+	assert.Equal(t, porch, got()[0].Source)
+	assert.Equal(t, 1, d.Today())
+
+	// The post, later on.
+	clock.Advance(2 * time.Hour)
+	pulse(server, mailbox)
+	require.Len(t, got(), 2)
+	assert.Equal(t, mailbox, got()[1].Source)
+	assert.Equal(t, 2, got()[1].Count)
+}
+
+func TestCountStartsOverEachDay(t *testing.T) {
+	server, clock, d, got := start(t, 20*time.Hour, delivery.Sources{Mailboxes: []string{mailbox}, DayStart: 4 * time.Hour})
+
+	pulse(server, mailbox)
+	assert.Equal(t, 1, d.Today())
+
+	// Past midnight, but before DayStart, is still the same day.
+	clock.Advance(6 * time.Hour)
+	pulse(server, mailbox)
+	assert.Equal(t, 2, d.Today())
+
+	clock.Advance(3 * time.Hour)
+	assert.Equal(t, 0, d.Today())
+	_, ok := d.Last()
+	assert.False(t, ok, "nothing yet today")
+
+	pulse(server, mailbox)
+	last, ok := d.Last()
+	require.True(t, ok)
+	assert.Equal(t, 1, last.Count)
+	assert.Len(t, got(), 3)
+}
+
+func TestDetectionEventsCountOnlyTheirLabels(t *testing.T) {
+	server, _, _, got := start(t, 10*time.Hour, delivery.Sources{Events: []string{"object_detected"}})
+
+	server.Fire("object_detected", map[string]any{"label": "person", "camera": "camera.porch"})
+	settle()
+	assert.Empty(t, got(), "a person is not a package")
+
+	server.Fire("object_detected", map[string]any{"label": "package", "camera": "camera.porch"})
+	settle()
+	require.Len(t, got(), 1)
+	assert.Equal(t, "camera.porch", got()[0].Source)
+}
+
+func TestSensorBackFromUnavailableIsNoDelivery(t *testing.T) {
+	server, _, _, got := start(t, 10*time.Hour, delivery.Sources{Mailboxes: []string{mailbox}})
+
+	server.ChangeState(mailbox, "unavailable")
+	settle()
+	server.ChangeState(mailbox, "on")
+	settle()
+	assert.Empty(t, got())
+}
+
+func TestNewDetectorRejectsBadSources(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+
+	_, err := delivery.NewDetector(app, delivery.Sources{})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "nothing to watch")
+
+	_, err = delivery.NewDetector(app, delivery.Sources{Mailboxes: []string{mailbox}, DayStart: 25 * time.Hour})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "DayStart past the day")
+}
@@ -0,0 +1,187 @@
+// Package ev sets an electric car charger's current from the time of day, the
+// tariff and the sun.
+//
+// Most chargers expose their charging current to Home Assistant as a number
+// entity, and leave it to something else to decide what it should be: as much
+// as the house can spare during the cheap night rate, enough to soak up the
+// solar surplus at midday, and little or nothing the rest of the time. Control
+// is that something else.
+package ev
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/units"
+)
+
+// Window is a stretch of time to charge at a set current, such as a tariff's
+// cheap hours.
+type Window struct {
+	// When is the window, such as
+	// ha.TimeBetween(ha.TimeOfDay(0, 30), ha.TimeOfDay(4, 30)), or a
+	// condition on a sensor the energy supplier publishes its rates on.
+	When ha.Condition
+
+	// Amps is the current to charge at while it holds.
+	Amps float64
+}
+
+// Charger describes a charger and how to drive it.
+type Charger struct {
+	// Amperage is the number entity that sets the charging current, in
+	// amps. Required.
+	Amperage services.NumberID
+
+	// MinAmps and MaxAmps bound what is ever set. A car cannot charge on
+	// less than a few amps, so a current below MinAmps, such as a cloudy
+	// hour's surplus, sets zero instead: charging stops, or runs at the
+	// charger's own minimum if its entity has one. MaxAmps is required, and
+	// should be what the circuit is rated for.
+	MinAmps float64
+	MaxAmps float64
+
+	// Schedule lists the windows to charge in, the first that holds
+	// deciding the current. DefaultAmps is the current outside all of them.
+	Schedule    []Window
+	DefaultAmps float64
+
+	// Solar is a sensor of the power being exported to the grid. With one
+	// set, the charger takes at least that surplus, converted to amps at
+	// Volts, so energy that would be sold cheaply charges the car instead.
+	Solar string
+
+	// Volts is the supply voltage, for converting the surplus. Defaults to
+	// 230.
+	Volts float64
+
+	// Hysteresis is how far the current has to move before it is changed,
+	// so a surplus flickering with passing clouds does not set a new current
+	// every minute. Defaults to one amp.
+	Hysteresis float64
+
+	// Every is how often the current is reconsidered. Defaults to a minute.
+	Every time.Duration
+
+	// Enabled, if set, is when the current is managed at all, such as
+	// while the car is plugged in. Outside it the charger is left alone.
+	Enabled ha.Condition
+}
+
+// Control drives the charger's current as c describes, from when the app
+// starts and every c.Every after.
+func Control(app *ha.App, c Charger) error {
+	if err := validate(c); err != nil {
+		return err
+	}
+	if c.Volts == 0 {
+		c.Volts = 230
+	}
+	if c.Hysteresis == 0 {
+		c.Hysteresis = 1
+	}
+	if c.Every == 0 {
+		c.Every = time.Minute
+	}
+
+	name := "charging current of " + string(c.Amperage)
+	// Build is what reports a condition made from bad arguments, such as an
+	// hour of 25, so each window's is built once to hear about it here.
+	for i, w := range c.Schedule {
+		_, err := ha.NewAutomation(name).On(ha.AtStartup()).When(w.When).Do(noAction).Build()
+		if err != nil {
+			return fmt.Errorf("%s: window %d: %w", name, i+1, err)
+		}
+	}
+
+	b := ha.NewAutomation(name).
+		On(ha.AtStartup(), ha.Every(c.Every)).
+		Do(func(ctx context.Context, run ha.Run) error { return adjust(ctx, c, app.Clock(), run) })
+	if c.Enabled != nil {
+		b = b.When(c.Enabled)
+	}
+	a, err := b.Build()
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return app.RegisterAutomations(a)
+}
+
+func validate(c Charger) error {
+	switch {
+	case !strings.HasPrefix(string(c.Amperage), "number."):
+		return fmt.Errorf("%w: charger amperage %q is not a number entity", ha.ErrInvalidArgs, c.Amperage)
+	case c.MaxAmps <= 0:
+		return fmt.Errorf("%w: charger %s needs a MaxAmps", ha.ErrInvalidArgs, c.Amperage)
+	case c.MinAmps < 0 || c.MinAmps > c.MaxAmps:
+		return fmt.Errorf("%w: charger %s MinAmps %g is outside 0 to MaxAmps", ha.ErrInvalidArgs, c.Amperage, c.MinAmps)
+	case c.DefaultAmps < 0:
+		return fmt.Errorf("%w: charger %s has a negative DefaultAmps", ha.ErrInvalidArgs, c.Amperage)
+	case c.Volts < 0 || c.Hysteresis < 0 || c.Every < 0:
+		return fmt.Errorf("%w: charger %s has a negative Volts, Hysteresis or Every", ha.ErrInvalidArgs, c.Amperage)
+	}
+	for i, w := range c.Schedule {
+		if w.When == nil {
+			return fmt.Errorf("%w: charger %s window %d has no When", ha.ErrInvalidArgs, c.Amperage, i+1)
+		}
+		if w.Amps < 0 {
+			return fmt.Errorf("%w: charger %s window %d has negative Amps", ha.ErrInvalidArgs, c.Amperage, i+1)
+		}
+	}
+	return nil
+}
+
+func noAction(context.Context, ha.Run) error { return nil }
+
+// adjust works out the current the charger should have and sets it, rounded
+// down to the entity's step, unless it is already within the hysteresis of
+// that.
+func adjust(ctx context.Context, c Charger, clock ha.Clock, run ha.Run) error {
+	target := c.DefaultAmps
+	ec := ha.EvalContext{Clock: clock, State: run.State, Event: run.Event}
+	for _, w := range c.Schedule {
+		// A window that cannot be told is not counted on.
+		if ok, err := w.When.Eval(ctx, ec); err == nil && ok {
+			target = w.Amps
+			break
+		}
+	}
+	if c.Solar != "" {
+		if watts, err := ha.GetStateIn(run.State, c.Solar, units.Watts); err == nil {
+			target = max(target, watts/c.Volts)
+		}
+	}
+	target = bound(c, target, run.State)
+
+	// Compared before rounding to the step, or a surplus hovering just under
+	// a whole amp would round a full step away and set it every time.
+	current, err := ha.GetStateAs[float64](run.State, c.Amperage)
+	if err == nil && math.Abs(target-current) < c.Hysteresis {
+		return nil
+	}
+	step, err := ha.GetAttribute[float64](run.State, c.Amperage, "step")
+	if err != nil || step <= 0 {
+		step = 1
+	}
+	return run.Services.Number.SetValue(c.Amperage, float32(math.Floor(target/step)*step))
+}
+
+// bound brings a current within the charger's bounds and its entity's.
+func bound(c Charger, amps float64, state ha.StateReader) float64 {
+	if amps < c.MinAmps {
+		amps = 0
+	}
+	amps = min(amps, c.MaxAmps)
+	if lo, err := ha.GetAttribute[float64](state, c.Amperage, "min"); err == nil {
+		amps = max(amps, lo)
+	}
+	if hi, err := ha.GetAttribute[float64](state, c.Amperage, "max"); err == nil {
+		amps = min(amps, hi)
+	}
+	return amps
+}
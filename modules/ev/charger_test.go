@@ -0,0 +1,104 @@
+package ev_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/ev"
+)
+
+const amperage = "number.charger_current"
+
+var limits = map[string]any{"min": 0.0, "max": 32.0, "step": 1.0}
+
+func charger(server *hatest.Server) ev.Charger {
+	server.SetState(amperage, "0", limits)
+	server.SetState("input_boolean.cheap_rate", "off")
+	server.SetState("sensor.grid_export", "0", map[string]any{"unit_of_measurement": "W"})
+	return ev.Charger{
+		Amperage: amperage,
+		MinAmps:  6,
+		MaxAmps:  16,
+		Schedule: []ev.Window{{When: ha.StateIs("input_boolean.cheap_rate", "on"), Amps: 32}},
+		Solar:    "sensor.grid_export",
+		Every:    50 * time.Millisecond,
+	}
+}
+
+// setTo waits for the charger to be set to amps, and has the charger take it.
+func setTo(t *testing.T, server *hatest.Server, amps float64) {
+	t.Helper()
+	call, ok := server.AssertServiceCalled("number", "set_value", amperage)
+	require.True(t, ok)
+	assert.EqualValues(t, amps, call.ServiceData["value"])
+	server.ChangeState(amperage, fmt.Sprint(amps), limits)
+	time.Sleep(100 * time.Millisecond)
+	server.ResetCalls()
+}
+
+func TestChargerFollowsTheTariffAndTheSurplus(t *testing.T) {
+	server := hatest.New(t)
+	c := charger(server)
+	app := hatest.NewApp(t, server)
+	require.NoError(t, ev.Control(app, c))
+	hatest.StartApp(t, app)
+
+	// 32 amps of cheap rate, held to what the circuit takes.
+	server.ChangeState("input_boolean.cheap_rate", "on")
+	setTo(t, server, 16)
+
+	server.ChangeState("input_boolean.cheap_rate", "off")
+	setTo(t, server, 0)
+
+	// 2.3kW exported is ten amps at 230V.
+	server.ChangeState("sensor.grid_export", "2300", map[string]any{"unit_of_measurement": "W"})
+	setTo(t, server, 10)
+
+	// A passing cloud moves it less than the hysteresis.
+	server.ChangeState("sensor.grid_export", "2100", map[string]any{"unit_of_measurement": "W"})
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+
+	// Too little to charge on stops charging rather than trickling.
+	server.ChangeState("sensor.grid_export", "0.9", map[string]any{"unit_of_measurement": "kW"})
+	setTo(t, server, 0)
+}
+
+func TestChargerIsLeftAloneWhileDisabled(t *testing.T) {
+	server := hatest.New(t)
+	c := charger(server)
+	server.SetState("binary_sensor.car_plugged_in", "off")
+	server.SetState("input_boolean.cheap_rate", "on")
+	c.Enabled = ha.StateIs("binary_sensor.car_plugged_in", "on")
+	app := hatest.NewApp(t, server)
+	require.NoError(t, ev.Control(app, c))
+	hatest.StartApp(t, app)
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+
+	server.ChangeState("binary_sensor.car_plugged_in", "on")
+	setTo(t, server, 16)
+}
+
+func TestControlRejectsBadChargers(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+
+	for name, c := range map[string]ev.Charger{
+		"not a number":   {Amperage: "sensor.charger", MaxAmps: 16},
+		"no max":         {Amperage: amperage},
+		"min above max":  {Amperage: amperage, MinAmps: 20, MaxAmps: 16},
+		"window no when": {Amperage: amperage, MaxAmps: 16, Schedule: []ev.Window{{Amps: 10}}},
+		"bad window time": {Amperage: amperage, MaxAmps: 16, Schedule: []ev.Window{
+			{When: ha.TimeBetween(ha.TimeOfDay(25, 0), ha.TimeOfDay(4, 0)), Amps: 10},
+		}},
+	} {
+		assert.Error(t, ev.Control(app, c), name)
+	}
+}
@@ -0,0 +1,189 @@
+// Package hvac keeps heating and cooling from working against the weather
+// let in through an open window.
+//
+// A thermostat has no idea a window is open. It heats the garden for as long
+// as the window stays that way, and the radiator under it, reading the cold
+// air, works hardest of all. PauseWhileOpen turns the thermostats off when a
+// window or door has been left open, and puts them back as they were once
+// everything is shut.
+package hvac
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/services"
+)
+
+// Pause describes which thermostats pause for which openings.
+type Pause struct {
+	// Climate lists the thermostats to pause. Required.
+	Climate []services.ClimateID
+
+	// Openings lists the window and door contact sensors, on while open,
+	// that pause them. Required.
+	Openings []string
+
+	// After is how long an opening has to stay open before the thermostats
+	// pause, so airing a room for a moment or walking through a door does
+	// not. Defaults to two minutes.
+	After time.Duration
+
+	// Enabled, if set, is when opening pauses the thermostats at all, such as
+	// ha.InDateRange for the heating season. Thermostats already paused are
+	// resumed once everything is shut whether or not it still holds.
+	Enabled ha.Condition
+
+	// OnPause and OnResume are told each time the thermostats pause and
+	// resume, to notify someone that a window has been left open.
+	OnPause  func(ctx context.Context, c Change) error
+	OnResume func(ctx context.Context, c Change) error
+}
+
+// Change is the thermostats pausing or resuming.
+type Change struct {
+	// Opening is the sensor whose opening paused the thermostats, or whose
+	// closing resumed them.
+	Opening string
+
+	// Modes are the modes the paused thermostats were in, and are restored
+	// to. A thermostat that was already off, or that someone turned back on
+	// while paused, is not among them.
+	Modes map[services.ClimateID]string
+}
+
+// pauser is the running state of one Pause.
+type pauser struct {
+	Pause
+
+	mu     sync.Mutex
+	paused bool
+
+	// saved holds the modes to restore, by thermostat.
+	saved map[services.ClimateID]string
+}
+
+// PauseWhileOpen turns the thermostats off once any of the openings has been
+// open for the pause's After, and restores the mode each was in once every
+// opening is shut again. Someone turning a thermostat back on while paused
+// overrules the pause for it: it is left as they set it.
+func PauseWhileOpen(app *ha.App, p Pause) error {
+	switch {
+	case len(p.Climate) == 0:
+		return fmt.Errorf("%w: pausing needs thermostats", ha.ErrInvalidArgs)
+	case len(p.Openings) == 0:
+		return fmt.Errorf("%w: pausing %s needs window or door sensors", ha.ErrInvalidArgs, names(p.Climate))
+	case p.After < 0:
+		return fmt.Errorf("%w: pausing %s after a negative %s", ha.ErrInvalidArgs, names(p.Climate), p.After)
+	}
+	if p.After == 0 {
+		p.After = 2 * time.Minute
+	}
+	ps := &pauser{Pause: p}
+
+	name := "pause " + names(p.Climate) + " while open"
+	pause := ha.NewAutomation(name).
+		On(ha.StateChanged(p.Openings...).To("on").For(p.After)).
+		Mode(ha.ModeQueued).
+		Do(ps.pause)
+	if p.Enabled != nil {
+		pause = pause.When(p.Enabled)
+	}
+	built, err := pause.Build()
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	name = "resume " + names(p.Climate) + " once shut"
+	resume, err := ha.NewAutomation(name).
+		On(ha.StateChanged(p.Openings...).From("on"), ha.StateChanged(p.Climate...)).
+		Mode(ha.ModeQueued).
+		Do(ps.observe).
+		Build()
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return app.RegisterAutomations(built, resume)
+}
+
+// pause turns the thermostats off, keeping the modes they were in.
+func (ps *pauser) pause(ctx context.Context, run ha.Run) error {
+	modes := map[services.ClimateID]string{}
+	for _, id := range ps.Climate {
+		if s, err := run.State.Get(string(id)); err == nil && s.State != "off" && s.State != "unavailable" && s.State != "unknown" {
+			modes[id] = s.State
+		}
+	}
+
+	ps.mu.Lock()
+	if ps.paused {
+		ps.mu.Unlock()
+		return nil
+	}
+	ps.paused, ps.saved = true, modes
+	ps.mu.Unlock()
+
+	for id := range modes {
+		if err := run.Services.Climate.SetHvacMode(id, "off"); err != nil {
+			return fmt.Errorf("pausing %s: %w", id, err)
+		}
+	}
+	if ps.OnPause != nil {
+		// A copy, since the saved modes lose a thermostat someone turns back on.
+		return ps.OnPause(ctx, Change{Opening: run.Event.EntityID, Modes: maps.Clone(modes)})
+	}
+	return nil
+}
+
+// observe resumes the thermostats once every opening is shut, and lets go of
+// one someone turned back on meanwhile.
+func (ps *pauser) observe(ctx context.Context, run ha.Run) error {
+	id := run.Event.EntityID
+	if !slices.Contains(ps.Openings, id) {
+		ps.mu.Lock()
+		if ps.paused && run.Event.To.State != "off" {
+			delete(ps.saved, services.ClimateID(id))
+		}
+		ps.mu.Unlock()
+		return nil
+	}
+
+	for _, o := range ps.Openings {
+		if s, err := run.State.Get(o); err == nil && s.State == "on" {
+			return nil
+		}
+	}
+
+	ps.mu.Lock()
+	if !ps.paused {
+		ps.mu.Unlock()
+		return nil
+	}
+	modes := ps.saved
+	ps.paused, ps.saved = false, nil
+	ps.mu.Unlock()
+
+	for climate, mode := range modes {
+		if err := run.Services.Climate.SetHvacMode(climate, mode); err != nil {
+			return fmt.Errorf("resuming %s: %w", climate, err)
+		}
+	}
+	if ps.OnResume != nil {
+		return ps.OnResume(ctx, Change{Opening: id, Modes: modes})
+	}
+	return nil
+}
+
+func names(ids []services.ClimateID) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = string(id)
+	}
+	return strings.Join(s, ", ")
+}
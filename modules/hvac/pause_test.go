@@ -0,0 +1,136 @@
+package hvac_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/hvac"
+	"github.com/Xevion/go-ha/services"
+)
+
+func settle() { time.Sleep(50 * time.Millisecond) }
+
+// changes records what the hooks were told.
+type changes struct {
+	mu  sync.Mutex
+	got []string
+}
+
+func (c *changes) record(kind string) func(context.Context, hvac.Change) error {
+	return func(_ context.Context, ch hvac.Change) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.got = append(c.got, kind+" "+ch.Opening)
+		return nil
+	}
+}
+
+func (c *changes) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	got := c.got
+	c.got = nil
+	return got
+}
+
+func lounge(server *hatest.Server) hvac.Pause {
+	server.SetState("climate.lounge", "heat")
+	server.SetState("climate.study", "off")
+	server.SetState("binary_sensor.lounge_window", "off")
+	server.SetState("binary_sensor.patio_door", "off")
+	return hvac.Pause{
+		Climate:  []services.ClimateID{"climate.lounge", "climate.study"},
+		Openings: []string{"binary_sensor.lounge_window", "binary_sensor.patio_door"},
+		After:    100 * time.Millisecond,
+	}
+}
+
+func TestAnOpenWindowPausesAndClosingResumes(t *testing.T) {
+	server := hatest.New(t)
+	p := lounge(server)
+	c := &changes{}
+	p.OnPause, p.OnResume = c.record("paused by"), c.record("resumed by")
+	app := hatest.NewApp(t, server)
+	require.NoError(t, hvac.PauseWhileOpen(app, p))
+	hatest.StartApp(t, app)
+
+	// Walking through the door is not leaving it open.
+	server.ChangeState("binary_sensor.patio_door", "on")
+	server.ChangeState("binary_sensor.patio_door", "off")
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+
+	server.ChangeState("binary_sensor.lounge_window", "on")
+	call, ok := server.AssertServiceCalled("climate", "set_hvac_mode", "climate.lounge")
+	require.True(t, ok)
+	assert.Equal(t, "off", call.ServiceData["hvac_mode"])
+	assert.True(t, server.AssertServiceNotCalled("climate", "set_hvac_mode", "climate.study"), "it was off already")
+	server.ChangeState("climate.lounge", "off")
+	settle()
+	assert.Equal(t, []string{"paused by binary_sensor.lounge_window"}, c.take())
+	server.ResetCalls()
+
+	// Still one opening left open.
+	server.ChangeState("binary_sensor.patio_door", "on")
+	server.ChangeState("binary_sensor.lounge_window", "off")
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+
+	server.ChangeState("binary_sensor.patio_door", "off")
+	call, ok = server.AssertServiceCalled("climate", "set_hvac_mode", "climate.lounge")
+	require.True(t, ok)
+	assert.Equal(t, "heat", call.ServiceData["hvac_mode"])
+	settle()
+	assert.Equal(t, []string{"resumed by binary_sensor.patio_door"}, c.take())
+}
+
+// Someone turning the heating back on with the window open has decided.
+func TestTurningAThermostatBackOnOverrulesThePause(t *testing.T) {
+	server := hatest.New(t)
+	p := lounge(server)
+	app := hatest.NewApp(t, server)
+	require.NoError(t, hvac.PauseWhileOpen(app, p))
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.lounge_window", "on")
+	server.AssertServiceCalled("climate", "set_hvac_mode", "climate.lounge")
+	server.ChangeState("climate.lounge", "off")
+	server.ChangeState("climate.lounge", "heat")
+	settle()
+	server.ResetCalls()
+
+	server.ChangeState("binary_sensor.lounge_window", "off")
+	time.Sleep(150 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+}
+
+func TestPauseOnlyWhileEnabled(t *testing.T) {
+	server := hatest.New(t)
+	p := lounge(server)
+	server.SetState("input_boolean.heating_season", "off")
+	p.Enabled = ha.StateIs("input_boolean.heating_season", "on")
+	app := hatest.NewApp(t, server)
+	require.NoError(t, hvac.PauseWhileOpen(app, p))
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.lounge_window", "on")
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+}
+
+func TestPauseWhileOpenRejectsBadArguments(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+	climate := []services.ClimateID{"climate.lounge"}
+	openings := []string{"binary_sensor.lounge_window"}
+
+	assert.ErrorIs(t, hvac.PauseWhileOpen(app, hvac.Pause{Openings: openings}), ha.ErrInvalidArgs)
+	assert.ErrorIs(t, hvac.PauseWhileOpen(app, hvac.Pause{Climate: climate}), ha.ErrInvalidArgs)
+	assert.ErrorIs(t, hvac.PauseWhileOpen(app, hvac.Pause{Climate: climate, Openings: openings, After: -time.Second}), ha.ErrInvalidArgs)
+}
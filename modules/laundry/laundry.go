@@ -0,0 +1,279 @@
+// Package laundry tells when a washing machine or dryer has finished, from the
+// power it draws.
+//
+// A plug with a power meter is all it takes, but the reading is not as plain
+// as on and off. A washer draws little while it soaks and pauses between
+// rinses, enough to look idle for a minute or two in the middle of a cycle,
+// and its display or a door being opened draws a moment's power without a
+// cycle running at all. A Monitor counts a cycle as started once the power has
+// stayed up for a while, and as finished only once it has stayed down for
+// longer than any pause.
+package laundry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+)
+
+// Appliance describes a washer or dryer and the power sensor it is plugged
+// into.
+type Appliance struct {
+	// Name is what the appliance is called in Cycle and the log. Defaults to
+	// the power sensor's id.
+	Name string
+
+	// Power is the sensor of the power it draws, in watts. Required.
+	Power string
+
+	// Start is the power above which a cycle may be starting, and Idle that
+	// at or below which the appliance is doing nothing. The gap between them
+	// keeps a reading hovering about one threshold from starting and ending
+	// cycles. Default to 10 and 5 watts.
+	Start float64
+	Idle  float64
+
+	// Confirm is how long the power has to stay above Idle after passing
+	// Start for the cycle to count, so a blip from the display or the door
+	// is not taken for one. Defaults to a minute.
+	Confirm time.Duration
+
+	// Quiet is how long the power has to stay at or below Idle for the cycle
+	// to have finished. It has to outlast the pauses within a cycle, soaking
+	// or a dryer's cool down. Defaults to five minutes.
+	Quiet time.Duration
+}
+
+// Cycle is one run of the appliance.
+type Cycle struct {
+	Appliance string
+
+	// Started is when the power first passed Start, and Finished when it
+	// last fell to Idle, so Quiet is not counted as part of the cycle.
+	// Finished is zero while the cycle is running.
+	Started  time.Time
+	Finished time.Time
+}
+
+// Duration is how long the cycle ran, or has run so far.
+func (c Cycle) Duration(now time.Time) time.Duration {
+	if c.Finished.IsZero() {
+		return now.Sub(c.Started)
+	}
+	return c.Finished.Sub(c.Started)
+}
+
+// phase is where a Monitor is in a cycle.
+type phase int
+
+const (
+	idle     phase = iota
+	starting       // above Start, waiting out Confirm
+	running
+	quieting // at or below Idle, waiting out Quiet
+)
+
+// Monitor follows an appliance's cycles.
+type Monitor struct {
+	app *ha.App
+	a   Appliance
+
+	mu    sync.Mutex
+	phase phase
+	cycle Cycle
+
+	// since is when the power last fell to Idle while quieting.
+	since time.Time
+
+	// stop cancels the pending Confirm or Quiet timer, and gen numbers the
+	// phases so one that fires after its phase has ended does nothing.
+	stop func() bool
+	gen  uint64
+
+	started  []func(Cycle)
+	finished []func(Cycle)
+}
+
+// NewMonitor starts following the appliance. If it is drawing power when the
+// app starts, a cycle is taken to be running from then.
+func NewMonitor(app *ha.App, a Appliance) (*Monitor, error) {
+	switch {
+	case a.Power == "":
+		return nil, fmt.Errorf("%w: a laundry monitor needs a power sensor", ha.ErrInvalidArgs)
+	case a.Start < 0 || a.Idle < 0 || a.Confirm < 0 || a.Quiet < 0:
+		return nil, fmt.Errorf("%w: laundry monitor %s has a negative setting", ha.ErrInvalidArgs, a.Power)
+	}
+	if a.Name == "" {
+		a.Name = a.Power
+	}
+	if a.Start == 0 {
+		a.Start = 10
+	}
+	if a.Idle == 0 {
+		a.Idle = min(5, a.Start)
+	}
+	if a.Confirm == 0 {
+		a.Confirm = time.Minute
+	}
+	if a.Quiet == 0 {
+		a.Quiet = 5 * time.Minute
+	}
+	if a.Idle > a.Start {
+		return nil, fmt.Errorf("%w: laundry monitor %s has Idle %g above Start %g", ha.ErrInvalidArgs, a.Power, a.Idle, a.Start)
+	}
+
+	m := &Monitor{app: app, a: a}
+	name := "laundry " + a.Name
+	auto, err := ha.NewAutomation(name).
+		On(ha.AtStartup(), ha.StateChanged(a.Power)).
+		Mode(ha.ModeQueued).
+		Do(func(_ context.Context, run ha.Run) error {
+			if run.Event.EntityID == "" {
+				m.atStartup(run.State)
+				return nil
+			}
+			if w, ok := watts(run.Event.To.State); ok {
+				m.reading(w)
+			}
+			return nil
+		}).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := app.RegisterAutomations(auto); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OnCycleStarted calls fn when a cycle has started, once the power has held
+// up for Confirm.
+func (m *Monitor) OnCycleStarted(fn func(Cycle)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started = append(m.started, fn)
+}
+
+// OnCycleFinished calls fn when a cycle has finished, once the power has been
+// down for Quiet.
+func (m *Monitor) OnCycleFinished(fn func(Cycle)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished = append(m.finished, fn)
+}
+
+// Running reports the cycle under way, if there is one. A pause within it,
+// while Quiet has yet to pass, still counts as running.
+func (m *Monitor) Running() (Cycle, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cycle, m.phase == running || m.phase == quieting
+}
+
+// atStartup takes a cycle the appliance is already running to have started
+// when the app did.
+func (m *Monitor) atStartup(state ha.StateReader) {
+	st, err := state.Get(m.a.Power)
+	if err != nil {
+		return
+	}
+	w, ok := watts(st.State)
+	if !ok || w <= m.a.Start {
+		return
+	}
+	m.mu.Lock()
+	if m.phase != idle {
+		m.mu.Unlock()
+		return
+	}
+	m.phase, m.cycle = running, Cycle{Appliance: m.a.Name, Started: m.app.Clock().Now()}
+	m.mu.Unlock()
+	m.app.Logger().Info("Laundry cycle already running", "appliance", m.a.Name)
+}
+
+// reading moves the cycle on with a new power reading.
+func (m *Monitor) reading(w float64) {
+	now := m.app.Clock().Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case m.phase == idle && w > m.a.Start:
+		m.enterLocked(starting)
+		m.cycle = Cycle{Appliance: m.a.Name, Started: now}
+		gen := m.gen
+		m.stop = m.app.AfterFunc(m.a.Confirm, func() { m.confirm(gen) })
+	case m.phase == starting && w <= m.a.Idle:
+		m.enterLocked(idle)
+	case m.phase == running && w <= m.a.Idle:
+		m.enterLocked(quieting)
+		m.since = now
+		gen := m.gen
+		m.stop = m.app.AfterFunc(m.a.Quiet, func() { m.finish(gen) })
+	case m.phase == quieting && w > m.a.Idle:
+		m.enterLocked(running)
+	}
+}
+
+// confirm starts the cycle, if it is still starting.
+func (m *Monitor) confirm(gen uint64) {
+	m.mu.Lock()
+	if m.phase != starting || m.gen != gen {
+		m.mu.Unlock()
+		return
+	}
+	m.enterLocked(running)
+	cycle, fns := m.cycle, slices.Clone(m.started)
+	m.mu.Unlock()
+
+	m.app.Logger().Info("Laundry cycle started", "appliance", m.a.Name)
+	for _, fn := range fns {
+		fn(cycle)
+	}
+}
+
+// finish ends the cycle, if it is still quiet.
+func (m *Monitor) finish(gen uint64) {
+	m.mu.Lock()
+	if m.phase != quieting || m.gen != gen {
+		m.mu.Unlock()
+		return
+	}
+	m.enterLocked(idle)
+	m.cycle.Finished = m.since
+	cycle, fns := m.cycle, slices.Clone(m.finished)
+	m.mu.Unlock()
+
+	m.app.Logger().Info("Laundry cycle finished", "appliance", m.a.Name, "ran", cycle.Duration(cycle.Finished).Round(time.Minute))
+	// Outside the lock, so a callback can ask whether another is Running.
+	for _, fn := range fns {
+		fn(cycle)
+	}
+}
+
+// enterLocked moves to the phase, abandoning the timer of the one it leaves.
+func (m *Monitor) enterLocked(p phase) {
+	if m.stop != nil {
+		m.stop()
+		m.stop = nil
+	}
+	m.phase = p
+	m.gen++
+}
+
+// watts is a power sensor's reading, if it is a number.
+func watts(state string) (float64, bool) {
+	w, err := strconv.ParseFloat(state, 64)
+	if err != nil || math.IsNaN(w) || math.IsInf(w, 0) {
+		return 0, false
+	}
+	return w, true
+}
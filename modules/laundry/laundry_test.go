@@ -0,0 +1,143 @@
+package laundry_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/laundry"
+	"github.com/Xevion/go-ha/types"
+)
+
+const power = "sensor.washer_power"
+
+var morning = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func settle() { time.Sleep(50 * time.Millisecond) }
+
+// events collects what a monitor reports.
+type events struct {
+	mu       sync.Mutex
+	started  []laundry.Cycle
+	finished []laundry.Cycle
+}
+
+func (e *events) counts() (started, finished int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.started), len(e.finished)
+}
+
+// start runs a monitor for the washer on a clock moved by hand, the washer
+// drawing the given power when the app starts.
+func start(t *testing.T, initial string) (*hatest.Server, *hatest.Clock, *laundry.Monitor, *events) {
+	t.Helper()
+
+	server := hatest.New(t)
+	server.SetState(power, initial)
+	clock := hatest.NewClock(morning)
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.Clock = clock })
+
+	m, err := laundry.NewMonitor(app, laundry.Appliance{Name: "washer", Power: power})
+	require.NoError(t, err)
+	var ev events
+	m.OnCycleStarted(func(c laundry.Cycle) {
+		ev.mu.Lock()
+		defer ev.mu.Unlock()
+		ev.started = append(ev.started, c)
+	})
+	m.OnCycleFinished(func(c laundry.Cycle) {
+		ev.mu.Lock()
+		defer ev.mu.Unlock()
+		ev.finished = append(ev.finished, c)
+	})
+	hatest.StartApp(t, app)
+	return server, clock, m, &ev
+}
+
+// draw moves the clock on and publishes a new reading.
+func draw(server *hatest.Server, clock *hatest.Clock, after time.Duration, watts string) {
+	clock.Advance(after)
+	settle()
+	server.ChangeState(power, watts)
+	settle()
+}
+
+func TestCycleFinishesAfterTheQuietPeriod(t *testing.T) {
+	server, clock, m, ev := start(t, "0.8")
+
+	draw(server, clock, time.Minute, "2000")
+	clock.Advance(time.Minute)
+	settle()
+	started, _ := ev.counts()
+	require.Equal(t, 1, started)
+	_, running := m.Running()
+	assert.True(t, running)
+
+	// A soak mid-cycle looks idle for a couple of minutes.
+	draw(server, clock, 20*time.Minute, "2")
+	draw(server, clock, 2*time.Minute, "450")
+	_, finished := ev.counts()
+	assert.Zero(t, finished, "a pause is not the end")
+
+	// The spin ends it.
+	draw(server, clock, 30*time.Minute, "1.2")
+	clock.Advance(4 * time.Minute)
+	settle()
+	_, finished = ev.counts()
+	assert.Zero(t, finished)
+	clock.Advance(time.Minute)
+	settle()
+
+	_, finished = ev.counts()
+	require.Equal(t, 1, finished)
+	c := ev.finished[0]
+	assert.Equal(t, "washer", c.Appliance)
+	assert.Equal(t, morning.Add(time.Minute), c.Started)
+	assert.Equal(t, 53*time.Minute, c.Duration(time.Time{}), "up to when the power fell, not the end of Quiet")
+	_, running = m.Running()
+	assert.False(t, running)
+}
+
+func TestBlipIsNoCycle(t *testing.T) {
+	server, clock, _, ev := start(t, "0.5")
+
+	// The door opened, lighting the display.
+	draw(server, clock, time.Minute, "15")
+	draw(server, clock, 20*time.Second, "0.5")
+	clock.Advance(time.Hour)
+	settle()
+
+	started, finished := ev.counts()
+	assert.Zero(t, started)
+	assert.Zero(t, finished)
+}
+
+func TestCycleRunningAtStartupIsFollowed(t *testing.T) {
+	server, clock, m, ev := start(t, "1800")
+
+	c, running := m.Running()
+	require.True(t, running)
+	assert.Equal(t, morning, c.Started)
+
+	draw(server, clock, 40*time.Minute, "0")
+	clock.Advance(5 * time.Minute)
+	settle()
+	_, finished := ev.counts()
+	assert.Equal(t, 1, finished)
+}
+
+func TestNewMonitorRejectsBadSettings(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+
+	_, err := laundry.NewMonitor(app, laundry.Appliance{})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "no power sensor")
+
+	_, err = laundry.NewMonitor(app, laundry.Appliance{Power: power, Start: 5, Idle: 20})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "Idle above Start")
+}
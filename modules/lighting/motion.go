@@ -0,0 +1,312 @@
+// Package lighting turns lights on and off for the people in a room.
+//
+// Motion lighting is the automation nearly every home starts with, and the
+// one nearly every first attempt gets wrong: lights snapping on at noon,
+// switching off under someone reading, blazing at full brightness at 3am, or
+// going dark on a guest who turned them on by hand. MotionLighting is that
+// automation with those cases handled, configured room by room.
+package lighting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/services"
+)
+
+// Room is one room's motion lighting profile.
+type Room struct {
+	// Name identifies the room, such as "hallway".
+	Name string
+
+	// Motion lists sensors that are on while they detect someone: motion,
+	// occupancy and presence binary sensors alike.
+	Motion []string
+
+	// Lights are the lights motion turns on, and the timeout turns off.
+	Lights []services.LightID
+
+	// Scene, if set, is activated instead of turning Lights on. Lights
+	// should then list the lights the scene sets, so they are turned off
+	// again.
+	Scene services.SceneID
+
+	// Illuminance is a light level sensor. With one set, motion only turns
+	// the lights on while it reads below MaxLux. A sensor that cannot be read
+	// counts as dark, since a light left off is the worse mistake.
+	Illuminance string
+	MaxLux      float64
+
+	// Timeout is how long the lights stay on after the last motion clears.
+	// Defaults to five minutes.
+	Timeout time.Duration
+
+	// Night is when motion turns the lights on dimmed rather than as they
+	// were, such as ha.TimeBetween(ha.TimeOfDay(23, 0), ha.TimeOfDay(6, 0))
+	// or ha.SunIsDown(). A night light sets Lights directly, even for a
+	// room with a Scene.
+	Night ha.Condition
+
+	// NightBrightness is the brightness at night, in percent. Defaults to
+	// ten.
+	NightBrightness int
+}
+
+// room is the running state of one Room.
+type room struct {
+	Room
+
+	// lit reports lights this package turned on and has yet to turn off.
+	lit bool
+
+	// manual reports lights someone turned on by hand. They are left alone
+	// until every light in the room is off again.
+	manual bool
+
+	// timeout abandons the pending turn off, nil when none is.
+	timeout func() bool
+
+	// gen numbers the timeouts, so one that fires after being stopped can
+	// tell it has been superseded.
+	gen uint64
+}
+
+// MotionLighting runs the motion lighting of a set of rooms.
+type MotionLighting struct {
+	app *ha.App
+
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+// NewMotionLighting starts motion lighting in the given rooms. Every room
+// needs a name, motion sensors and lights.
+//
+// Motion in a dark room turns its lights on, and once every sensor has been
+// clear for the timeout they go off again. Lights someone turned on by hand
+// are never turned off: the room is left alone until they are all off again,
+// however that happens. A light turned on by hand while motion already has
+// the room lit cannot be told apart, and goes off with the rest.
+func NewMotionLighting(app *ha.App, rooms ...Room) (*MotionLighting, error) {
+	if len(rooms) == 0 {
+		return nil, fmt.Errorf("%w: motion lighting needs rooms", ha.ErrInvalidArgs)
+	}
+
+	m := &MotionLighting{app: app, rooms: map[string]*room{}}
+	var automations []ha.Automation
+	for _, rm := range rooms {
+		if err := validate(rm); err != nil {
+			return nil, err
+		}
+		if _, dup := m.rooms[rm.Name]; dup {
+			return nil, fmt.Errorf("%w: motion lighting room %q declared twice", ha.ErrInvalidArgs, rm.Name)
+		}
+		if rm.Timeout <= 0 {
+			rm.Timeout = 5 * time.Minute
+		}
+		if rm.NightBrightness == 0 {
+			rm.NightBrightness = 10
+		}
+
+		r := &room{Room: rm}
+		m.rooms[rm.Name] = r
+
+		name := "motion lighting in " + rm.Name
+		a, err := ha.NewAutomation(name).
+			On(ha.StateChanged(rm.Motion...), ha.StateChanged(rm.Lights...)).
+			Mode(ha.ModeQueued).
+			Do(func(ctx context.Context, run ha.Run) error {
+				if slices.Contains(r.Motion, run.Event.EntityID) {
+					return m.motion(ctx, r, run)
+				}
+				m.light(r, run)
+				return nil
+			}).
+			Build()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		automations = append(automations, a)
+	}
+	if err := app.RegisterAutomations(automations...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func validate(rm Room) error {
+	switch {
+	case rm.Name == "":
+		return fmt.Errorf("%w: a motion lighting room needs a name", ha.ErrInvalidArgs)
+	case len(rm.Motion) == 0:
+		return fmt.Errorf("%w: motion lighting room %q needs motion sensors", ha.ErrInvalidArgs, rm.Name)
+	case len(rm.Lights) == 0:
+		return fmt.Errorf("%w: motion lighting room %q needs lights", ha.ErrInvalidArgs, rm.Name)
+	case rm.Illuminance != "" && rm.MaxLux <= 0:
+		return fmt.Errorf("%w: motion lighting room %q reads %s but has no MaxLux", ha.ErrInvalidArgs, rm.Name, rm.Illuminance)
+	case rm.NightBrightness < 0 || rm.NightBrightness > 100:
+		return fmt.Errorf("%w: motion lighting room %q night brightness %d%% is not a percentage",
+			ha.ErrInvalidArgs, rm.Name, rm.NightBrightness)
+	}
+	return nil
+}
+
+// IsManual reports whether the room's lights were turned on by hand, and so
+// are being left alone. An unknown room never is.
+func (m *MotionLighting) IsManual(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.rooms[name]
+	return ok && r.manual
+}
+
+// motion handles one of the room's sensors changing.
+func (m *MotionLighting) motion(ctx context.Context, r *room, run ha.Run) error {
+	if run.Event.To.State != "on" {
+		if anyOn(run.State, r.Motion) {
+			return nil
+		}
+		m.mu.Lock()
+		if r.lit && !r.manual {
+			m.armLocked(r)
+		}
+		m.mu.Unlock()
+		return nil
+	}
+
+	alreadyOn := anyOn(run.State, r.Lights)
+	m.mu.Lock()
+	m.stopLocked(r)
+	if r.lit || r.manual {
+		m.mu.Unlock()
+		return nil
+	}
+	if alreadyOn {
+		// On before motion saw anyone, so not on this package's account.
+		r.manual = true
+		m.mu.Unlock()
+		return nil
+	}
+	m.mu.Unlock()
+
+	if !dark(run.State, r.Room) {
+		return nil
+	}
+	night := false
+	if r.Night != nil {
+		ec := ha.EvalContext{Clock: m.app.Clock(), State: run.State, Event: run.Event}
+		ok, err := r.Night.Eval(ctx, ec)
+		if err != nil {
+			m.app.Logger().Warn("Motion lighting could not tell whether it is night", "room", r.Name, "error", err)
+		}
+		night = ok
+	}
+
+	// Marked lit before the calls go out, so the state changes they cause
+	// are not taken for someone at the switch.
+	m.mu.Lock()
+	r.lit = true
+	m.mu.Unlock()
+
+	light := run.Services.Light
+	if night {
+		var errs []error
+		for _, id := range r.Lights {
+			errs = append(errs, light.TurnOn(id, map[string]any{"brightness_pct": r.NightBrightness}))
+		}
+		return errors.Join(errs...)
+	}
+	if r.Scene != "" {
+		return run.Services.Scene.TurnOn(r.Scene)
+	}
+	var errs []error
+	for _, id := range r.Lights {
+		errs = append(errs, light.TurnOn(id))
+	}
+	return errors.Join(errs...)
+}
+
+// light handles one of the room's lights changing.
+func (m *MotionLighting) light(r *room, run ha.Run) {
+	allOff := !anyOn(run.State, r.Lights)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case allOff:
+		m.stopLocked(r)
+		r.lit, r.manual = false, false
+	case run.Event.To.State == "on" && run.Event.From.State != "on" && !r.lit:
+		m.stopLocked(r)
+		r.manual = true
+	}
+}
+
+// armLocked starts the room's timeout over.
+func (m *MotionLighting) armLocked(r *room) {
+	m.stopLocked(r)
+	mine := r.gen
+	// On the app's clock, and never once the app has stopped.
+	r.timeout = m.app.AfterFunc(r.Timeout, func() { m.expire(r, mine) })
+}
+
+// stopLocked abandons the room's timeout, if one is pending.
+func (m *MotionLighting) stopLocked(r *room) {
+	r.gen++
+	if r.timeout != nil {
+		r.timeout()
+		r.timeout = nil
+	}
+}
+
+// expire turns the room's lights off once its timeout has run out.
+func (m *MotionLighting) expire(r *room, gen uint64) {
+	m.mu.Lock()
+	current := r.gen == gen && r.lit && !r.manual
+	if current {
+		r.timeout = nil
+		r.lit = false
+	}
+	m.mu.Unlock()
+	if !current {
+		return
+	}
+
+	light := m.app.Services().Light
+	for _, id := range r.Lights {
+		if err := light.TurnOff(id); err != nil {
+			m.app.Logger().Error("Motion lighting could not turn a light off", "room", r.Name, "light", id, "error", err)
+		}
+	}
+}
+
+// dark reports whether the room is dark enough to want its lights.
+func dark(state ha.StateReader, rm Room) bool {
+	if rm.Illuminance == "" {
+		return true
+	}
+	s, err := state.Get(rm.Illuminance)
+	if err != nil {
+		return true
+	}
+	lux, err := strconv.ParseFloat(s.State, 64)
+	return err != nil || lux < rm.MaxLux
+}
+
+// anyOn reports whether any of the entities reads on.
+func anyOn[E ~string](state ha.StateReader, ids []E) bool {
+	for _, id := range ids {
+		if s, err := state.Get(string(id)); err == nil && s.State == "on" {
+			return true
+		}
+	}
+	return false
+}
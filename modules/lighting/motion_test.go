@@ -0,0 +1,131 @@
+package lighting_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/lighting"
+	"github.com/Xevion/go-ha/services"
+)
+
+func settle() { time.Sleep(50 * time.Millisecond) }
+
+func hallway(server *hatest.Server) lighting.Room {
+	server.SetState("binary_sensor.hall_motion", "off")
+	server.SetState("light.hall", "off")
+	return lighting.Room{
+		Name:    "hallway",
+		Motion:  []string{"binary_sensor.hall_motion"},
+		Lights:  []services.LightID{"light.hall"},
+		Timeout: 150 * time.Millisecond,
+	}
+}
+
+func TestMotionTurnsTheLightsOnUntilTheTimeout(t *testing.T) {
+	server := hatest.New(t)
+	room := hallway(server)
+	app := hatest.NewApp(t, server)
+	_, err := lighting.NewMotionLighting(app, room)
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	server.AssertServiceCalled("light", "turn_on", "light.hall")
+	server.ChangeState("light.hall", "on")
+
+	server.ChangeState("binary_sensor.hall_motion", "off")
+	settle()
+	assert.True(t, server.AssertServiceNotCalled("light", "turn_off", "light.hall"), "a still moment is not an empty room")
+
+	server.AssertServiceCalled("light", "turn_off", "light.hall")
+}
+
+func TestMotionInABrightRoomLeavesTheLightsOff(t *testing.T) {
+	server := hatest.New(t)
+	room := hallway(server)
+	room.Illuminance = "sensor.hall_lux"
+	room.MaxLux = 30
+	server.SetState("sensor.hall_lux", "250")
+	app := hatest.NewApp(t, server)
+	_, err := lighting.NewMotionLighting(app, room)
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	settle()
+	assert.True(t, server.AssertServiceNotCalled("light", "turn_on", "light.hall"))
+
+	server.ChangeState("sensor.hall_lux", "12")
+	server.ChangeState("binary_sensor.hall_motion", "off")
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	server.AssertServiceCalled("light", "turn_on", "light.hall")
+}
+
+// A light someone turned on stays on, however long the room is empty.
+func TestLightsTurnedOnByHandAreLeftOn(t *testing.T) {
+	server := hatest.New(t)
+	room := hallway(server)
+	app := hatest.NewApp(t, server)
+	m, err := lighting.NewMotionLighting(app, room)
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	server.ChangeState("light.hall", "on")
+	settle()
+	assert.True(t, m.IsManual("hallway"))
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	server.ChangeState("binary_sensor.hall_motion", "off")
+	time.Sleep(300 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+
+	// Off again by hand, the room is back under motion's control.
+	server.ChangeState("light.hall", "off")
+	settle()
+	assert.False(t, m.IsManual("hallway"))
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	server.AssertServiceCalled("light", "turn_on", "light.hall")
+}
+
+func TestNightDimsTheLights(t *testing.T) {
+	server := hatest.New(t)
+	room := hallway(server)
+	room.Scene = "scene.hall_bright"
+	room.Night = ha.TimeBetween(ha.TimeOfDay(0, 0), ha.TimeOfDay(23, 59))
+	app := hatest.NewApp(t, server)
+	_, err := lighting.NewMotionLighting(app, room)
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	call, ok := server.AssertServiceCalled("light", "turn_on", "light.hall")
+	require.True(t, ok)
+	assert.EqualValues(t, 10, call.ServiceData["brightness_pct"])
+	assert.True(t, server.AssertServiceNotCalled("scene", "turn_on", "scene.hall_bright"))
+}
+
+func TestNewMotionLightingRejectsBadRooms(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+	lights := []services.LightID{"light.hall"}
+	motion := []string{"binary_sensor.hall_motion"}
+
+	for name, room := range map[string]lighting.Room{
+		"no name":    {Motion: motion, Lights: lights},
+		"no motion":  {Name: "hall", Lights: lights},
+		"no lights":  {Name: "hall", Motion: motion},
+		"no max lux": {Name: "hall", Motion: motion, Lights: lights, Illuminance: "sensor.hall_lux"},
+		"too bright": {Name: "hall", Motion: motion, Lights: lights, NightBrightness: 150},
+	} {
+		_, err := lighting.NewMotionLighting(app, room)
+		assert.ErrorIs(t, err, ha.ErrInvalidArgs, name)
+	}
+
+	room := lighting.Room{Name: "hall", Motion: motion, Lights: lights}
+	_, err := lighting.NewMotionLighting(app, room, room)
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
@@ -0,0 +1,147 @@
+// Package notify batches notifications into digests.
+//
+// Chatty automations turn a phone into a stream of buzzes that nobody reads.
+// A Notifier holds low priority messages back and delivers them together on a
+// schedule, while anything urgent still goes out the moment it is sent.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/types"
+)
+
+// Priority decides whether a message waits for the digest.
+type Priority int
+
+const (
+	// Low messages are held for the next digest.
+	Low Priority = iota
+
+	// High messages are delivered immediately.
+	High
+)
+
+// Options configures a Notifier.
+type Options struct {
+	// Service is the notify service to deliver through, such as
+	// mobile_app_pixel. Required.
+	Service string
+
+	// Schedule decides when the digest goes out, for example
+	// ha.Every(time.Hour) or ha.Daily(ha.TimeOfDay(8, 0)). Required.
+	Schedule ha.ScheduleTrigger
+
+	// Title heads the digest. Defaults to "Digest".
+	Title string
+}
+
+// Notifier delivers high priority messages at once and batches the rest.
+type Notifier struct {
+	app     *ha.App
+	service string
+	title   string
+
+	mu      sync.Mutex
+	pending []entry
+}
+
+type entry struct {
+	title   string
+	message string
+}
+
+// NewNotifier registers the digest schedule on app and returns a notifier to
+// send through.
+func NewNotifier(app *ha.App, opts Options) (*Notifier, error) {
+	if opts.Service == "" {
+		return nil, fmt.Errorf("%w: a notifier needs a notify service", ha.ErrInvalidArgs)
+	}
+	if opts.Schedule == nil {
+		return nil, fmt.Errorf("%w: a notifier needs a digest schedule", ha.ErrInvalidArgs)
+	}
+	if opts.Title == "" {
+		opts.Title = "Digest"
+	}
+
+	n := &Notifier{app: app, service: opts.Service, title: opts.Title}
+
+	a, err := ha.NewAutomation("digest for " + opts.Service).
+		On(opts.Schedule).
+		Mode(ha.ModeQueued).
+		Do(func(context.Context, ha.Run) error { return n.Flush() }).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("digest for %s: %w", opts.Service, err)
+	}
+	if err := app.RegisterAutomations(a); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Send delivers a message now if it is high priority, or holds it for the next
+// digest otherwise.
+func (n *Notifier) Send(p Priority, title, message string) error {
+	if p >= High {
+		return n.deliver(title, message)
+	}
+
+	n.mu.Lock()
+	n.pending = append(n.pending, entry{title: title, message: message})
+	n.mu.Unlock()
+	return nil
+}
+
+// Pending reports how many messages are waiting for the digest.
+func (n *Notifier) Pending() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.pending)
+}
+
+// Flush delivers the digest now. With nothing waiting it sends nothing, so a
+// quiet hour does not produce an empty notification.
+//
+// A delivery that fails puts the messages back, ahead of anything that arrived
+// meanwhile, so they go out with the next digest rather than being lost.
+func (n *Notifier) Flush() error {
+	n.mu.Lock()
+	batch := n.pending
+	n.pending = nil
+	n.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(batch))
+	for _, e := range batch {
+		if e.title == "" {
+			lines = append(lines, "• "+e.message)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("• %s: %s", e.title, e.message))
+	}
+
+	title := fmt.Sprintf("%s (%d)", n.title, len(batch))
+	if err := n.deliver(title, strings.Join(lines, "\n")); err != nil {
+		n.mu.Lock()
+		n.pending = append(batch, n.pending...)
+		n.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (n *Notifier) deliver(title, message string) error {
+	return n.app.Services().Notify.Notify(types.NotifyRequest{
+		ServiceName: n.service,
+		Title:       title,
+		Message:     message,
+	})
+}
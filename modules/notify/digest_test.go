@@ -0,0 +1,89 @@
+package notify_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/notify"
+)
+
+func TestNotifierNeedsAServiceAndSchedule(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+
+	_, err := notify.NewNotifier(app, notify.Options{Schedule: ha.Every(time.Hour)})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+
+	_, err = notify.NewNotifier(app, notify.Options{Service: "mobile_app_phone"})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+
+	_, err = notify.NewNotifier(app, notify.Options{Service: "mobile_app_phone", Schedule: ha.Every(-time.Hour)})
+	assert.ErrorIs(t, err, ha.ErrInvalidAutomation, "a bad schedule is an error, not a panic")
+}
+
+func TestHighPriorityIsDeliveredImmediately(t *testing.T) {
+	server := hatest.New(t)
+	app := hatest.NewApp(t, server)
+
+	n, err := notify.NewNotifier(app, notify.Options{Service: "mobile_app_phone", Schedule: ha.Every(time.Hour)})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Send(notify.High, "Leak", "water under the sink"))
+
+	calls := server.WaitForCalls(1)
+	assert.Equal(t, "notify", calls[0].Domain)
+	assert.Equal(t, "mobile_app_phone", calls[0].Service)
+	assert.Equal(t, "Leak", calls[0].ServiceData["title"])
+	assert.Zero(t, n.Pending())
+}
+
+func TestLowPriorityWaitsForTheDigest(t *testing.T) {
+	server := hatest.New(t)
+	app := hatest.NewApp(t, server)
+
+	n, err := notify.NewNotifier(app, notify.Options{
+		Service:  "mobile_app_phone",
+		Schedule: ha.Every(time.Hour),
+		Title:    "Home",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Send(notify.Low, "Laundry", "washer finished"))
+	require.NoError(t, n.Send(notify.Low, "", "mail arrived"))
+	assert.Equal(t, 2, n.Pending())
+	assert.Empty(t, server.Calls(), "nothing goes out until the digest")
+
+	require.NoError(t, n.Flush())
+
+	calls := server.WaitForCalls(1)
+	assert.Equal(t, "Home (2)", calls[0].ServiceData["title"])
+	assert.Equal(t, "• Laundry: washer finished\n• mail arrived", calls[0].ServiceData["message"])
+	assert.Zero(t, n.Pending())
+
+	// An empty digest is not worth a notification.
+	require.NoError(t, n.Flush())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, server.Calls(), 1)
+}
+
+// The digest goes out on its schedule without anyone calling Flush.
+func TestDigestIsDeliveredOnSchedule(t *testing.T) {
+	server := hatest.New(t)
+	app := hatest.NewApp(t, server)
+
+	n, err := notify.NewNotifier(app, notify.Options{
+		Service:  "mobile_app_phone",
+		Schedule: ha.Every(100 * time.Millisecond),
+	})
+	require.NoError(t, err)
+	require.NoError(t, n.Send(notify.Low, "", "batched"))
+
+	go func() { _ = app.Start() }()
+
+	calls := server.WaitForCalls(1)
+	assert.Equal(t, "Digest (1)", calls[0].ServiceData["title"])
+}
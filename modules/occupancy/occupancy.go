@@ -0,0 +1,361 @@
+// Package occupancy estimates which areas of a home are occupied.
+//
+// No single sensor answers the question. Motion sensors see someone who moves
+// and go quiet on someone reading; person entities know who is home but not
+// where. Combined they do much better: motion marks a room occupied, a timeout
+// carries it through still moments, and a room whose doors stayed shut since
+// the last motion is still occupied however long the quiet lasts, since nobody
+// can have left it. That last rule is what keeps the bathroom light on.
+package occupancy
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+)
+
+// Area is a space whose occupancy is estimated from its sensors.
+type Area struct {
+	// Name identifies the area, such as "bathroom".
+	Name string
+
+	// Motion lists sensors that are on while they detect someone: motion,
+	// occupancy and presence binary sensors alike.
+	Motion []string
+
+	// Doors lists contact sensors on the area's doors, on while open. With
+	// every door closed since motion was last seen, the area stays occupied
+	// until one opens. Leave it empty for open-plan spaces.
+	Doors []string
+
+	// Persons lists person or device_tracker entities. The area is occupied
+	// while any of them is home, which suits an area standing for the whole
+	// house.
+	Persons []string
+
+	// Timeout is how long the area stays occupied after the last motion
+	// clears. Defaults to five minutes.
+	Timeout time.Duration
+}
+
+// vacancyWatch is one OnAreaVacant registration.
+type vacancyWatch struct {
+	hold time.Duration
+	fn   func()
+
+	// stop abandons the pending call, nil when there is none.
+	stop func() bool
+
+	// gen numbers the vacancies, so a timer that fires after being stopped
+	// can tell it belongs to a stretch that has already ended.
+	gen uint64
+}
+
+// areaState is the running estimate for one area.
+type areaState struct {
+	Area
+
+	occupied bool
+
+	// lastMotion is when motion was last seen, or last cleared. sealed is set
+	// when that happened behind closed doors and cleared when one opens.
+	lastMotion time.Time
+	sealed     bool
+
+	// expiry abandons the re-evaluation due once the timeout runs out, nil
+	// when none is.
+	expiry func() bool
+
+	onVacant   []*vacancyWatch
+	onOccupied []func()
+}
+
+// Model tracks the occupancy of a set of areas.
+type Model struct {
+	app *ha.App
+
+	mu    sync.Mutex
+	areas map[string]*areaState
+	// watching maps each entity to the areas that read it.
+	watching map[string][]*areaState
+}
+
+// New starts estimating the given areas' occupancy. Every area needs a name
+// and at least one sensor.
+func New(app *ha.App, areas ...Area) (*Model, error) {
+	if len(areas) == 0 {
+		return nil, fmt.Errorf("%w: occupancy needs areas", ha.ErrInvalidArgs)
+	}
+
+	m := &Model{app: app, areas: map[string]*areaState{}, watching: map[string][]*areaState{}}
+	var entities, names []string
+	for _, a := range areas {
+		if a.Name == "" {
+			return nil, fmt.Errorf("%w: an occupancy area needs a name", ha.ErrInvalidArgs)
+		}
+		if _, dup := m.areas[a.Name]; dup {
+			return nil, fmt.Errorf("%w: occupancy area %q declared twice", ha.ErrInvalidArgs, a.Name)
+		}
+		if len(a.Motion)+len(a.Persons) == 0 {
+			return nil, fmt.Errorf("%w: occupancy area %q needs motion sensors or persons", ha.ErrInvalidArgs, a.Name)
+		}
+		if a.Timeout <= 0 {
+			a.Timeout = 5 * time.Minute
+		}
+
+		st := &areaState{Area: a}
+		m.areas[a.Name] = st
+		names = append(names, a.Name)
+		for _, id := range slices.Concat(a.Motion, a.Doors, a.Persons) {
+			if !slices.Contains(entities, id) {
+				entities = append(entities, id)
+			}
+			m.watching[id] = append(m.watching[id], st)
+		}
+	}
+
+	// Named for its areas, so two models on one app can be told apart.
+	name := "occupancy of " + strings.Join(names, ", ")
+	a, err := ha.NewAutomation(name).
+		On(ha.AtStartup(), ha.StateChanged(entities...)).
+		Mode(ha.ModeQueued).
+		Do(func(_ context.Context, run ha.Run) error {
+			if run.Event.EntityID == "" {
+				m.seed()
+			} else {
+				m.observe(run.Event.EntityID, run.Event.To.State)
+			}
+			return nil
+		}).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := app.RegisterAutomations(a); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IsOccupied reports the area's current estimate. An unknown area is never
+// occupied.
+func (m *Model) IsOccupied(area string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.areas[area]
+	return ok && st.occupied
+}
+
+// OnAreaVacant calls fn once the area has been vacant for hold, and again
+// after each later stretch of occupancy ends the same way. Occupancy returning
+// before hold is up cancels that call.
+func (m *Model) OnAreaVacant(area string, hold time.Duration, fn func()) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.areas[area]
+	if !ok {
+		return fmt.Errorf("%w: no occupancy area %q", ha.ErrInvalidArgs, area)
+	}
+	st.onVacant = append(st.onVacant, &vacancyWatch{hold: hold, fn: fn})
+	return nil
+}
+
+// OnAreaOccupied calls fn each time the area goes from vacant to occupied.
+func (m *Model) OnAreaOccupied(area string, fn func()) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.areas[area]
+	if !ok {
+		return fmt.Errorf("%w: no occupancy area %q", ha.ErrInvalidArgs, area)
+	}
+	st.onOccupied = append(st.onOccupied, fn)
+	return nil
+}
+
+// readings holds the states of the entities some areas read. They are taken
+// before the lock is, since reading state can wait on Home Assistant.
+type readings map[string]ha.EntityState
+
+// read takes the states of every entity the areas read. The areas' entity
+// lists never change, so they are safe to walk without the lock.
+func (m *Model) read(areas ...*areaState) readings {
+	r := readings{}
+	for _, st := range areas {
+		for _, id := range slices.Concat(st.Motion, st.Doors, st.Persons) {
+			if _, done := r[id]; done {
+				continue
+			}
+			if s, err := m.app.State().Get(id); err == nil {
+				r[id] = s
+			}
+		}
+	}
+	return r
+}
+
+// is reports whether the entity was read in the given state.
+func (r readings) is(entityID, state string) bool {
+	s, ok := r[entityID]
+	return ok && s.State == state
+}
+
+// seed takes the estimate from the sensors' current states, at startup. A
+// motion sensor that cleared a minute ago leaves the area occupied for the
+// rest of its timeout, measured from when it really cleared.
+func (m *Model) seed() {
+	areas := slices.Collect(maps.Values(m.areas))
+	r := m.read(areas...)
+	var due []func()
+
+	m.mu.Lock()
+	for _, st := range areas {
+		for _, id := range st.Motion {
+			if s, ok := r[id]; ok && s.LastChanged.After(st.lastMotion) {
+				st.lastMotion = s.LastChanged
+			}
+		}
+		due = append(due, m.evaluateLocked(st, r)...)
+	}
+	m.mu.Unlock()
+
+	run(due)
+}
+
+// observe folds one entity's new state into every area reading it.
+func (m *Model) observe(entityID, state string) {
+	areas := m.watching[entityID]
+	r := m.read(areas...)
+	var due []func()
+
+	m.mu.Lock()
+	now := m.app.Clock().Now()
+	for _, st := range areas {
+		switch {
+		case slices.Contains(st.Motion, entityID):
+			// Clearing counts as well as detecting: whoever set the sensor
+			// off was there until it cleared.
+			st.lastMotion = now
+			st.sealed = allClosed(st, r)
+		case slices.Contains(st.Doors, entityID) && state == "on":
+			// Someone may be leaving, so the timeout runs from here rather
+			// than from motion that may be long past.
+			st.sealed = false
+			if st.occupied {
+				st.lastMotion = now
+			}
+		}
+		due = append(due, m.evaluateLocked(st, r)...)
+	}
+	m.mu.Unlock()
+
+	run(due)
+}
+
+// expire re-evaluates an area whose timeout has run out.
+func (m *Model) expire(st *areaState) {
+	r := m.read(st)
+
+	m.mu.Lock()
+	due := m.evaluateLocked(st, r)
+	m.mu.Unlock()
+	run(due)
+}
+
+// allClosed reports whether the area has doors and every one is shut.
+func allClosed(st *areaState, r readings) bool {
+	if len(st.Doors) == 0 {
+		return false
+	}
+	for _, id := range st.Doors {
+		if !r.is(id, "off") {
+			return false
+		}
+	}
+	return true
+}
+
+// occupiedLocked works out the area's estimate from what is known now, and
+// when it would next change on its own if nothing else happens.
+func (m *Model) occupiedLocked(st *areaState, r readings) (bool, time.Duration) {
+	for _, id := range st.Persons {
+		if r.is(id, "home") {
+			return true, 0
+		}
+	}
+	for _, id := range st.Motion {
+		if r.is(id, "on") {
+			return true, 0
+		}
+	}
+	if st.sealed {
+		return true, 0
+	}
+	remaining := st.lastMotion.Add(st.Timeout).Sub(m.app.Clock().Now())
+	if !st.lastMotion.IsZero() && remaining > 0 {
+		return true, remaining
+	}
+	return false, 0
+}
+
+// evaluateLocked brings the area's estimate up to date and returns the
+// callbacks a change makes due, to be run once the lock is released.
+func (m *Model) evaluateLocked(st *areaState, r readings) []func() {
+	occupied, expiresIn := m.occupiedLocked(st, r)
+
+	if st.expiry != nil {
+		st.expiry()
+		st.expiry = nil
+	}
+	if expiresIn > 0 {
+		// On the app's clock, and never once the app has stopped.
+		st.expiry = m.app.AfterFunc(expiresIn, func() { m.expire(st) })
+	}
+
+	if occupied == st.occupied {
+		return nil
+	}
+	st.occupied = occupied
+
+	if occupied {
+		for _, w := range st.onVacant {
+			w.gen++
+			if w.stop != nil {
+				w.stop()
+				w.stop = nil
+			}
+		}
+		return slices.Clone(st.onOccupied)
+	}
+
+	for _, w := range st.onVacant {
+		w.gen++
+		mine := w.gen
+		w.stop = m.app.AfterFunc(w.hold, func() {
+			m.mu.Lock()
+			current := w.gen == mine
+			if current {
+				w.stop = nil
+			}
+			m.mu.Unlock()
+			if current {
+				w.fn()
+			}
+		})
+	}
+	return nil
+}
+
+func run(due []func()) {
+	for _, fn := range due {
+		fn()
+	}
+}
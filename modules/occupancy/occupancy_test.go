@@ -0,0 +1,192 @@
+package occupancy_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/occupancy"
+	"github.com/Xevion/go-ha/types"
+)
+
+func settle() { time.Sleep(50 * time.Millisecond) }
+
+func TestMotionOccupiesUntilTheTimeout(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	app := hatest.NewApp(t, server)
+
+	model, err := occupancy.New(app, occupancy.Area{
+		Name:    "hall",
+		Motion:  []string{"binary_sensor.hall_motion"},
+		Timeout: 300 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	var vacated atomic.Int64
+	require.NoError(t, model.OnAreaVacant("hall", 100*time.Millisecond, func() { vacated.Add(1) }))
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	settle()
+	assert.True(t, model.IsOccupied("hall"))
+
+	server.ChangeState("binary_sensor.hall_motion", "off")
+	settle()
+	assert.True(t, model.IsOccupied("hall"), "a still moment is not an empty room")
+
+	time.Sleep(300 * time.Millisecond)
+	assert.False(t, model.IsOccupied("hall"))
+	assert.Zero(t, vacated.Load(), "vacancy has to last the hold time")
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, int64(1), vacated.Load())
+}
+
+// Nobody can leave a room without opening its door, so one that went quiet
+// behind a closed door is still occupied.
+func TestClosedDoorsKeepAnAreaOccupied(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.bathroom_motion", "off")
+	server.SetState("binary_sensor.bathroom_door", "on")
+	app := hatest.NewApp(t, server)
+
+	model, err := occupancy.New(app, occupancy.Area{
+		Name:    "bathroom",
+		Motion:  []string{"binary_sensor.bathroom_motion"},
+		Doors:   []string{"binary_sensor.bathroom_door"},
+		Timeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	server.ChangeState("binary_sensor.bathroom_door", "off")
+	server.ChangeState("binary_sensor.bathroom_motion", "on")
+	server.ChangeState("binary_sensor.bathroom_motion", "off")
+	time.Sleep(300 * time.Millisecond)
+	assert.True(t, model.IsOccupied("bathroom"), "the door has not opened since the last motion")
+
+	server.ChangeState("binary_sensor.bathroom_door", "on")
+	settle()
+	assert.True(t, model.IsOccupied("bathroom"), "the timeout runs from the door opening")
+
+	time.Sleep(150 * time.Millisecond)
+	assert.False(t, model.IsOccupied("bathroom"))
+}
+
+func TestPersonsHomeOccupyTheHouse(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("person.alex", "not_home")
+	app := hatest.NewApp(t, server)
+
+	model, err := occupancy.New(app, occupancy.Area{Name: "house", Persons: []string{"person.alex"}})
+	require.NoError(t, err)
+
+	var arrivals atomic.Int64
+	require.NoError(t, model.OnAreaOccupied("house", func() { arrivals.Add(1) }))
+	hatest.StartApp(t, app)
+	settle()
+	assert.False(t, model.IsOccupied("house"))
+
+	server.ChangeState("person.alex", "home")
+	settle()
+	assert.True(t, model.IsOccupied("house"))
+	assert.Equal(t, int64(1), arrivals.Load())
+}
+
+func TestReturningCancelsAVacancy(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.den_motion", "on")
+	app := hatest.NewApp(t, server)
+
+	model, err := occupancy.New(app, occupancy.Area{
+		Name:    "den",
+		Motion:  []string{"binary_sensor.den_motion"},
+		Timeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	var vacated atomic.Int64
+	require.NoError(t, model.OnAreaVacant("den", 200*time.Millisecond, func() { vacated.Add(1) }))
+	hatest.StartApp(t, app)
+	require.Eventually(t, func() bool { return model.IsOccupied("den") }, 2*time.Second, 5*time.Millisecond,
+		"a sensor already on at startup counts")
+
+	server.ChangeState("binary_sensor.den_motion", "off")
+	time.Sleep(150 * time.Millisecond)
+	server.ChangeState("binary_sensor.den_motion", "on")
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Zero(t, vacated.Load())
+}
+
+func TestNewRefusesBadAreas(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+
+	_, err := occupancy.New(app)
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+	_, err = occupancy.New(app, occupancy.Area{Name: "attic"})
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "an area needs something to read")
+
+	_, err = occupancy.New(app, occupancy.Area{Name: "porch", Motion: []string{"porch_motion"}})
+	assert.ErrorIs(t, err, ha.ErrInvalidAutomation, "a sensor without a domain is an error, not a panic")
+
+	model, err := occupancy.New(app, occupancy.Area{Name: "hall", Motion: []string{"binary_sensor.m"}})
+	require.NoError(t, err)
+	assert.ErrorIs(t, model.OnAreaVacant("garden", time.Minute, func() {}), ha.ErrInvalidArgs)
+}
+
+func TestTimeoutRunsOnTheAppClock(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	clock := hatest.NewClock(time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC))
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.Clock = clock })
+
+	model, err := occupancy.New(app, occupancy.Area{
+		Name:    "hall",
+		Motion:  []string{"binary_sensor.hall_motion"},
+		Timeout: 10 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	var vacated atomic.Int64
+	require.NoError(t, model.OnAreaVacant("hall", time.Minute, func() { vacated.Add(1) }))
+	hatest.StartApp(t, app)
+	require.True(t, clock.WaitForSleepers(2))
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	server.ChangeState("binary_sensor.hall_motion", "off")
+	require.True(t, clock.WaitForSleepers(3), "the timeout waits on the clock")
+	assert.True(t, model.IsOccupied("hall"))
+
+	clock.Advance(10 * time.Minute)
+	require.Eventually(t, func() bool { return !model.IsOccupied("hall") }, 2*time.Second, 5*time.Millisecond)
+
+	require.True(t, clock.WaitForSleepers(3), "the hold waits on the clock")
+	clock.Advance(time.Minute)
+	require.Eventually(t, func() bool { return vacated.Load() == 1 }, 2*time.Second, 5*time.Millisecond)
+}
+
+// Each model's automation is named for its areas, so a second model does not
+// hide the first from RunAutomation.
+func TestModelsOnOneAppAreNamedApart(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "on")
+	server.SetState("binary_sensor.den_motion", "on")
+	app := hatest.NewApp(t, server)
+
+	hall, err := occupancy.New(app, occupancy.Area{Name: "hall", Motion: []string{"binary_sensor.hall_motion"}})
+	require.NoError(t, err)
+	den, err := occupancy.New(app, occupancy.Area{Name: "den", Motion: []string{"binary_sensor.den_motion"}})
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	require.Eventually(t, func() bool { return hall.IsOccupied("hall") && den.IsOccupied("den") }, 2*time.Second, 5*time.Millisecond)
+	assert.NoError(t, app.RunAutomation("occupancy of hall"))
+	assert.NoError(t, app.RunAutomation("occupancy of den"))
+}
@@ -0,0 +1,296 @@
+// Package precondition heats or cools the house ahead of its residents coming
+// home.
+//
+// A thermostat on a schedule heats an empty house for the hours someone might
+// be back; one left off makes whoever arrives wait out the warm-up. Knowing
+// how far away the nearest resident is, and so how long until they are home,
+// the climate can be started just as far ahead of them as it needs, and only
+// when the weather calls for it.
+package precondition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
+)
+
+// Thermostat is one climate entity to pre-condition, and how.
+type Thermostat struct {
+	// Entity is the climate entity. Required.
+	Entity services.ClimateID
+
+	// Heat is the setpoint to heat to when it is colder than that outside.
+	// Nil never heats.
+	Heat *float32
+
+	// Cool is the setpoint to cool to when it is warmer than that outside.
+	// Nil never cools.
+	Cool *float32
+
+	// Lead is how far ahead of the nearest resident the entity is started,
+	// as travel time: roughly how long the room takes to come to temperature.
+	// Required.
+	Lead time.Duration
+}
+
+// Options describes the residents, the weather and the thermostats.
+type Options struct {
+	// Persons lists the residents, as person or device_tracker entities whose
+	// attributes carry a latitude and longitude. Required.
+	Persons []string
+
+	// Home is the zone measured to. Defaults to zone.home.
+	Home string
+
+	// Outdoor reads the outside temperature: a weather entity's temperature
+	// attribute, or a sensor's state. Without it, or while it is unknown, a
+	// thermostat with only one of Heat and Cool does that, and one with both
+	// does nothing, having nothing to choose by.
+	Outdoor string
+
+	// Speed is the average travel speed assumed, in km/h, turning distance
+	// into travel time. Defaults to 40, a mix of town and open road.
+	Speed float64
+
+	Thermostats []Thermostat
+}
+
+// Preconditioner watches the residents and starts each thermostat when the
+// nearest of them is within its lead.
+//
+// It only ever starts a thermostat, once per approach: nobody home and
+// someone coming within the lead. That approach ends, and the thermostat can
+// be started again, when a resident arrives or the nearest one goes back out
+// beyond the lead. Once someone is home the thermostats are theirs.
+type Preconditioner struct {
+	app     *ha.App
+	persons []string
+	home    string
+	outdoor string
+	speed   float64
+
+	mu          sync.Mutex
+	thermostats []*thermostat
+}
+
+// thermostat is a Thermostat and whether it has been started this approach.
+type thermostat struct {
+	Thermostat
+	started bool
+}
+
+// New starts pre-conditioning the thermostats.
+func New(app *ha.App, opts Options) (*Preconditioner, error) {
+	if len(opts.Persons) == 0 {
+		return nil, fmt.Errorf("%w: pre-conditioning needs residents", ha.ErrInvalidArgs)
+	}
+	for _, id := range opts.Persons {
+		if !strings.HasPrefix(id, "person.") && !strings.HasPrefix(id, "device_tracker.") {
+			return nil, fmt.Errorf("%w: %q is not a person or device_tracker", ha.ErrInvalidArgs, id)
+		}
+	}
+	if len(opts.Thermostats) == 0 {
+		return nil, fmt.Errorf("%w: pre-conditioning needs thermostats", ha.ErrInvalidArgs)
+	}
+	if opts.Home == "" {
+		opts.Home = "zone.home"
+	}
+	if opts.Speed < 0 {
+		return nil, fmt.Errorf("%w: negative travel speed %g", ha.ErrInvalidArgs, opts.Speed)
+	}
+	if opts.Speed == 0 {
+		opts.Speed = 40
+	}
+
+	p := &Preconditioner{app: app, persons: slices.Clone(opts.Persons), home: opts.Home, outdoor: opts.Outdoor, speed: opts.Speed}
+	for _, th := range opts.Thermostats {
+		switch {
+		case !strings.HasPrefix(string(th.Entity), "climate."):
+			return nil, fmt.Errorf("%w: %q is not a climate entity", ha.ErrInvalidArgs, th.Entity)
+		case th.Heat == nil && th.Cool == nil:
+			return nil, fmt.Errorf("%w: %s needs a Heat or Cool setpoint", ha.ErrInvalidArgs, th.Entity)
+		case th.Heat != nil && th.Cool != nil && *th.Heat > *th.Cool:
+			return nil, fmt.Errorf("%w: %s heats to %g, above where it cools to %g", ha.ErrInvalidArgs, th.Entity, *th.Heat, *th.Cool)
+		case th.Lead <= 0:
+			return nil, fmt.Errorf("%w: %s needs a Lead", ha.ErrInvalidArgs, th.Entity)
+		}
+		p.thermostats = append(p.thermostats, &thermostat{Thermostat: th})
+	}
+
+	watched := slices.Clone(p.persons)
+	if p.outdoor != "" {
+		watched = append(watched, p.outdoor)
+	}
+	names := make([]string, 0, len(p.thermostats))
+	for _, th := range p.thermostats {
+		names = append(names, string(th.Entity))
+	}
+	// Named for the thermostats, so two Preconditioners on one app do not
+	// collide in RunAutomation and the logs.
+	a, err := ha.NewAutomation("pre-conditioning "+strings.Join(names, ", ")).
+		// A resident moving and the weather warming are both attribute
+		// changes that leave the state as it was.
+		On(ha.AtStartup(), ha.StateChanged(watched...).AllowSameStateTransitions()).
+		Mode(ha.ModeQueued).
+		Do(func(context.Context, ha.Run) error {
+			return p.evaluate()
+		}).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	if err := app.RegisterAutomations(a); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// TravelTime is how long the nearest resident away from home is estimated to
+// take to get back. ok is false when somebody is already home, or nobody's
+// whereabouts are known.
+func (p *Preconditioner) TravelTime() (d time.Duration, ok bool) {
+	homeLat, homeLon, ok := coordinates(p.app, p.home)
+	if !ok {
+		return 0, false
+	}
+
+	nearest := math.Inf(1)
+	for _, id := range p.persons {
+		st, err := p.app.State().Get(id)
+		if err != nil {
+			continue
+		}
+		if st.State == "home" {
+			return 0, false
+		}
+		lat, lon, ok := coordinates(p.app, id)
+		if !ok {
+			continue
+		}
+		nearest = min(nearest, distance(lat, lon, homeLat, homeLon))
+	}
+	if math.IsInf(nearest, 1) {
+		return 0, false
+	}
+	return time.Duration(nearest / p.speed * float64(time.Hour)), true
+}
+
+// Started reports whether the thermostat has been started for the residents'
+// current approach.
+func (p *Preconditioner) Started(entity services.ClimateID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, th := range p.thermostats {
+		if th.Entity == entity {
+			return th.started
+		}
+	}
+	return false
+}
+
+// evaluate starts every thermostat the nearest resident has come within the
+// lead of, and re-arms those they have gone back out beyond.
+func (p *Preconditioner) evaluate() error {
+	eta, away := p.TravelTime()
+	outside, outsideKnown := p.outdoorTemperature()
+
+	type start struct {
+		th  *thermostat
+		req types.SetTemperatureRequest
+	}
+	var due []start
+
+	p.mu.Lock()
+	for _, th := range p.thermostats {
+		if !away || eta > th.Lead {
+			th.started = false
+			continue
+		}
+		if th.started {
+			continue
+		}
+		if req, ok := th.request(outside, outsideKnown); ok {
+			due = append(due, start{th, req})
+		}
+	}
+	p.mu.Unlock()
+
+	// Called without the lock, so Started is not held up behind a slow Home
+	// Assistant. Runs are queued, so no other evaluate starts one meanwhile.
+	var errs []error
+	for _, s := range due {
+		if err := p.app.Services().Climate.SetTemperature(s.th.Entity, s.req); err != nil {
+			errs = append(errs, fmt.Errorf("pre-conditioning %s: %w", s.th.Entity, err))
+			continue
+		}
+		p.mu.Lock()
+		s.th.started = true
+		p.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// request is what starting the thermostat asks of it, given the temperature
+// outside. ok is false when the weather calls for neither.
+func (th *thermostat) request(outside float64, known bool) (types.SetTemperatureRequest, bool) {
+	heat := th.Heat != nil && ((!known && th.Cool == nil) || (known && outside < float64(*th.Heat)))
+	cool := th.Cool != nil && ((!known && th.Heat == nil) || (known && outside > float64(*th.Cool)))
+	switch {
+	case heat:
+		return types.SetTemperatureRequest{Temperature: types.Ptr(*th.Heat), HvacMode: "heat"}, true
+	case cool:
+		return types.SetTemperatureRequest{Temperature: types.Ptr(*th.Cool), HvacMode: "cool"}, true
+	}
+	return types.SetTemperatureRequest{}, false
+}
+
+// outdoorTemperature reads Outdoor, from a weather entity's temperature
+// attribute or any other entity's state.
+func (p *Preconditioner) outdoorTemperature() (float64, bool) {
+	if p.outdoor == "" {
+		return 0, false
+	}
+	st, err := p.app.State().Get(p.outdoor)
+	if err != nil {
+		return 0, false
+	}
+	if strings.HasPrefix(p.outdoor, "weather.") {
+		t, ok := st.Attributes["temperature"].(float64)
+		return t, ok
+	}
+	t, err := strconv.ParseFloat(st.State, 64)
+	return t, err == nil
+}
+
+// coordinates reads an entity's position from its attributes, where Home
+// Assistant publishes it as plain numbers.
+func coordinates(app *ha.App, entityID string) (float64, float64, bool) {
+	st, err := app.State().Get(entityID)
+	if err != nil {
+		return 0, 0, false
+	}
+	lat, latOK := st.Attributes["latitude"].(float64)
+	lon, lonOK := st.Attributes["longitude"].(float64)
+	return lat, lon, latOK && lonOK
+}
+
+// distance is the great-circle distance between two points, in km.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371.0
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat, dLon := rad(lat2-lat1), rad(lon2-lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
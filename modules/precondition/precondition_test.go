@@ -0,0 +1,129 @@
+package precondition_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/precondition"
+	"github.com/Xevion/go-ha/services"
+	"github.com/Xevion/go-ha/types"
+)
+
+// at places a person the given km due north of home, near enough.
+func at(km float64) map[string]any {
+	return map[string]any{"latitude": 51.5 + km/111.2, "longitude": -0.12}
+}
+
+func TestHeatingStartsWhenTheNearestResidentIsWithinTheLead(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("zone.home", "0", map[string]any{"latitude": 51.5, "longitude": -0.12})
+	server.SetState("person.alice", "not_home", at(40))
+	server.SetState("person.bob", "not_home", at(60))
+	server.SetState("weather.home", "cloudy", map[string]any{"temperature": 4.0})
+	server.SetState("climate.lounge", "off")
+	app := hatest.NewApp(t, server)
+
+	p, err := precondition.New(app, precondition.Options{
+		Persons: []string{"person.alice", "person.bob"},
+		Outdoor: "weather.home",
+		Thermostats: []precondition.Thermostat{{
+			Entity: "climate.lounge",
+			Heat:   types.Ptr(float32(21)),
+			Cool:   types.Ptr(float32(25)),
+			Lead:   20 * time.Minute,
+		}},
+	})
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	eta, ok := p.TravelTime()
+	require.True(t, ok)
+	assert.InDelta(t, time.Hour, eta, float64(time.Minute), "40 km at 40 km/h")
+
+	// Bob heads home first, but not yet within twenty minutes.
+	server.ChangeState("person.bob", "not_home", at(20))
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+
+	server.ChangeState("person.bob", "not_home", at(10))
+	call, _ := server.AssertServiceCalled("climate", "set_temperature", "climate.lounge")
+	assert.Equal(t, "heat", call.ServiceData["hvac_mode"], "4° outside is below the heating setpoint")
+	assert.EqualValues(t, 21, call.ServiceData["temperature"])
+	assert.True(t, p.Started("climate.lounge"))
+
+	server.ChangeState("person.bob", "not_home", at(5))
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, server.Calls(), 1, "started once per approach")
+
+	server.ChangeState("person.bob", "home")
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, p.Started("climate.lounge"), "once someone is home the thermostat is theirs")
+	_, ok = p.TravelTime()
+	assert.False(t, ok)
+}
+
+func TestMildWeatherNeedsNoConditioning(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("zone.home", "0", map[string]any{"latitude": 51.5, "longitude": -0.12})
+	server.SetState("person.alice", "not_home", at(40))
+	server.SetState("sensor.outdoor_temperature", "22.5")
+	app := hatest.NewApp(t, server)
+
+	_, err := precondition.New(app, precondition.Options{
+		Persons: []string{"person.alice"},
+		Outdoor: "sensor.outdoor_temperature",
+		Thermostats: []precondition.Thermostat{{
+			Entity: "climate.lounge",
+			Heat:   types.Ptr(float32(21)),
+			Cool:   types.Ptr(float32(25)),
+			Lead:   time.Hour,
+		}},
+	})
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	server.ChangeState("person.alice", "not_home", at(10))
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+}
+
+func TestNewRejectsBadOptions(t *testing.T) {
+	app := hatest.NewApp(t, hatest.New(t))
+	lounge := precondition.Thermostat{Entity: "climate.lounge", Heat: types.Ptr(float32(21)), Lead: time.Hour}
+
+	for name, opts := range map[string]precondition.Options{
+		"no residents":   {Thermostats: []precondition.Thermostat{lounge}},
+		"not a person":   {Persons: []string{"zone.home"}, Thermostats: []precondition.Thermostat{lounge}},
+		"no thermostats": {Persons: []string{"person.alice"}},
+		"not climate":    {Persons: []string{"person.alice"}, Thermostats: []precondition.Thermostat{{Entity: "switch.heater", Heat: types.Ptr(float32(21)), Lead: time.Hour}}},
+		"no setpoint":    {Persons: []string{"person.alice"}, Thermostats: []precondition.Thermostat{{Entity: "climate.lounge", Lead: time.Hour}}},
+		"no lead":        {Persons: []string{"person.alice"}, Thermostats: []precondition.Thermostat{{Entity: "climate.lounge", Heat: types.Ptr(float32(21))}}},
+		"crossed ranges": {Persons: []string{"person.alice"}, Thermostats: []precondition.Thermostat{{Entity: "climate.lounge", Heat: types.Ptr(float32(24)), Cool: types.Ptr(float32(20)), Lead: time.Hour}}},
+		"negative speed": {Persons: []string{"person.alice"}, Speed: -1, Thermostats: []precondition.Thermostat{lounge}},
+	} {
+		_, err := precondition.New(app, opts)
+		assert.ErrorIs(t, err, ha.ErrInvalidArgs, name)
+	}
+}
+
+func TestPreconditionersOnOneAppAreNamedApart(t *testing.T) {
+	server := hatest.New(t)
+	app := hatest.NewApp(t, server)
+
+	for _, entity := range []services.ClimateID{"climate.lounge", "climate.study"} {
+		_, err := precondition.New(app, precondition.Options{
+			Persons:     []string{"person.alice"},
+			Thermostats: []precondition.Thermostat{{Entity: entity, Heat: types.Ptr(float32(21)), Lead: time.Hour}},
+		})
+		require.NoError(t, err)
+	}
+	hatest.StartApp(t, app)
+
+	assert.NoError(t, app.RunAutomation("pre-conditioning climate.lounge"))
+	assert.NoError(t, app.RunAutomation("pre-conditioning climate.study"))
+}
@@ -0,0 +1,89 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+)
+
+// OnArriveHome calls fn when the person comes home and stays for settle.
+// Phone location is jittery near the edge of the home zone, and a person
+// sitting in the garden can bounce between home and not_home a dozen times an
+// hour. A move only counts once it has held for settle, so those bounces never
+// reach fn, and an arrival is only reported after a departure that held too.
+//
+// The person may be a person or a device_tracker entity. A few minutes is a
+// typical settle time.
+func OnArriveHome(app *ha.App, person string, settle time.Duration, fn ha.Action) error {
+	return watchHome(app, person, settle, true, fn)
+}
+
+// OnLeaveHome calls fn when the person leaves home and stays away for settle,
+// debounced as OnArriveHome is.
+func OnLeaveHome(app *ha.App, person string, settle time.Duration, fn ha.Action) error {
+	return watchHome(app, person, settle, false, fn)
+}
+
+func watchHome(app *ha.App, person string, settle time.Duration, arriving bool, fn ha.Action) error {
+	if !strings.HasPrefix(person, "person.") && !strings.HasPrefix(person, "device_tracker.") {
+		return fmt.Errorf("%w: %q is not a person or device_tracker", ha.ErrInvalidArgs, person)
+	}
+	if settle < 0 {
+		return fmt.Errorf("%w: negative settle time %s", ha.ErrInvalidArgs, settle)
+	}
+	if fn == nil {
+		return fmt.Errorf("%w: no action for %s", ha.ErrInvalidArgs, person)
+	}
+
+	// home is where the person last settled, once known is set.
+	var (
+		mu    sync.Mutex
+		home  bool
+		known bool
+	)
+	settled := func(state string) (atHome, changed bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		was, wasKnown := home, known
+		home, known = state == "home", true
+		return home, wasKnown && was != home
+	}
+
+	name := "departure of " + person
+	if arriving {
+		name = "arrival of " + person
+	}
+	a, err := ha.NewAutomation(name).
+		// The hold is what debounces: a bounce back before settle cancels the
+		// pending run, so only a state that stuck arrives here.
+		On(ha.AtStartup(), ha.StateChanged(person).For(settle)).
+		Mode(ha.ModeQueued).
+		Do(func(ctx context.Context, run ha.Run) error {
+			state := run.Event.To.State
+			if run.Event.EntityID == "" {
+				// At startup, where the person is now, to measure from.
+				st, err := run.State.Get(person)
+				if err != nil || unplaced(st.State) {
+					return nil
+				}
+				settled(st.State)
+				return nil
+			}
+			if unplaced(state) {
+				return nil
+			}
+			if atHome, changed := settled(state); !changed || atHome != arriving {
+				return nil
+			}
+			return fn(ctx, run)
+		}).
+		Build()
+	if err != nil {
+		return err
+	}
+	return app.RegisterAutomations(a)
+}
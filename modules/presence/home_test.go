@@ -0,0 +1,62 @@
+package presence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/presence"
+)
+
+func TestHomeArrivalAndDepartureAreDebounced(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("person.alice", "home")
+	app := hatest.NewApp(t, server)
+
+	m := &moves{}
+	record := func(kind string) ha.Action {
+		return func(_ context.Context, run ha.Run) error {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.got = append(m.got, kind+" "+run.Event.To.State)
+			return nil
+		}
+	}
+	const settle = 150 * time.Millisecond
+	require.NoError(t, presence.OnArriveHome(app, "person.alice", settle, record("arrive")))
+	require.NoError(t, presence.OnLeaveHome(app, "person.alice", settle, record("leave")))
+	hatest.StartApp(t, app)
+
+	// GPS jitter in the garden: out and straight back is no move at all.
+	server.ChangeState("person.alice", "not_home")
+	server.ChangeState("person.alice", "home")
+	time.Sleep(2 * settle)
+	assert.Empty(t, m.take())
+
+	server.ChangeState("person.alice", "not_home")
+	time.Sleep(2 * settle)
+	assert.Equal(t, []string{"leave not_home"}, m.take())
+
+	// Coming back up the drive, bouncing at the zone's edge.
+	server.ChangeState("person.alice", "home")
+	server.ChangeState("person.alice", "not_home")
+	server.ChangeState("person.alice", "home")
+	assert.Empty(t, m.take(), "nothing before the settle time")
+	time.Sleep(2 * settle)
+	assert.Equal(t, []string{"arrive home"}, m.take(), "one arrival for the whole bounce")
+}
+
+func TestHomeHelpersRejectBadArguments(t *testing.T) {
+	server := hatest.New(t)
+	app := hatest.NewApp(t, server)
+	noop := func(context.Context, ha.Run) error { return nil }
+
+	assert.ErrorIs(t, presence.OnArriveHome(app, "zone.home", time.Minute, noop), ha.ErrInvalidArgs)
+	assert.ErrorIs(t, presence.OnLeaveHome(app, "person.a", -time.Minute, noop), ha.ErrInvalidArgs)
+	assert.ErrorIs(t, presence.OnLeaveHome(app, "person.a", time.Minute, nil), ha.ErrInvalidArgs)
+}
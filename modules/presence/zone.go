@@ -0,0 +1,201 @@
+// Package presence turns person and device_tracker states into zone arrivals
+// and departures.
+//
+// A tracker's state names the zone it is in, but not by entity id: it is
+// "home" in zone.home, "not_home" outside every zone, and the zone's friendly
+// name, such as "Work", anywhere else. Moving between two zones is one state
+// change that is both a departure and an arrival. A zone listener does that
+// bookkeeping, so an automation can say "when Alice reaches work" and mean it.
+package presence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	ha "github.com/Xevion/go-ha"
+)
+
+// ZoneRun is what a zone callback is given: the run, and which person reached
+// or left which zone.
+type ZoneRun struct {
+	ha.Run
+
+	// Person is the person or device_tracker entity that moved.
+	Person string
+
+	// Zone is the zone entity entered or left, such as zone.home.
+	Zone string
+}
+
+// ZoneAction is called on an arrival or departure.
+type ZoneAction func(ctx context.Context, run ZoneRun) error
+
+// ZoneListenerBuilder accumulates a zone listener. Like AutomationBuilder, every
+// stage returns a copy.
+type ZoneListenerBuilder struct {
+	persons []string
+	zones   []string
+	onEnter ZoneAction
+	onLeave ZoneAction
+}
+
+// NewZoneListener starts building a zone listener.
+func NewZoneListener() ZoneListenerBuilder {
+	return ZoneListenerBuilder{}
+}
+
+// Person adds person or device_tracker entities to follow.
+func (b ZoneListenerBuilder) Person(entityIDs ...string) ZoneListenerBuilder {
+	b.persons = append(slices.Clip(b.persons), entityIDs...)
+	return b
+}
+
+// Zone adds zones to report on. With several, moving from one straight into
+// another is a departure from the first and an arrival at the second.
+func (b ZoneListenerBuilder) Zone(zoneIDs ...string) ZoneListenerBuilder {
+	b.zones = append(slices.Clip(b.zones), zoneIDs...)
+	return b
+}
+
+// OnEnter sets what runs when a person arrives in one of the zones.
+func (b ZoneListenerBuilder) OnEnter(fn ZoneAction) ZoneListenerBuilder {
+	b.onEnter = fn
+	return b
+}
+
+// OnLeave sets what runs when a person leaves one of the zones.
+func (b ZoneListenerBuilder) OnLeave(fn ZoneAction) ZoneListenerBuilder {
+	b.onLeave = fn
+	return b
+}
+
+// Build produces the listener as an automation to register with the app,
+// reporting everything wrong with it at once.
+func (b ZoneListenerBuilder) Build() (ha.Automation, error) {
+	var errs []error
+	if len(b.persons) == 0 {
+		errs = append(errs, errors.New("a zone listener needs a person"))
+	}
+	for _, id := range b.persons {
+		if !strings.HasPrefix(id, "person.") && !strings.HasPrefix(id, "device_tracker.") {
+			errs = append(errs, fmt.Errorf("%q is not a person or device_tracker", id))
+		}
+	}
+	if len(b.zones) == 0 {
+		errs = append(errs, errors.New("a zone listener needs a zone"))
+	}
+	for _, id := range b.zones {
+		if !strings.HasPrefix(id, "zone.") {
+			errs = append(errs, fmt.Errorf("%q is not a zone", id))
+		}
+	}
+	if b.onEnter == nil && b.onLeave == nil {
+		errs = append(errs, errors.New("a zone listener needs OnEnter or OnLeave"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return ha.Automation{}, fmt.Errorf("%w: %w", ha.ErrInvalidAutomation, err)
+	}
+
+	l := &zoneListener{ZoneListenerBuilder: b, known: map[string]string{}}
+	return ha.NewAutomation("zones of " + strings.Join(b.persons, ", ")).
+		On(ha.StateChanged(b.persons...)).
+		Mode(ha.ModeQueued).
+		Do(l.handle).
+		Build()
+}
+
+// MustBuild builds the listener and panics if it cannot, for package level
+// declarations.
+func (b ZoneListenerBuilder) MustBuild() ha.Automation {
+	a, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// zoneListener is a built listener's running state.
+type zoneListener struct {
+	ZoneListenerBuilder
+
+	// known is each person's last state that placed them, so a tracker that
+	// drops to unavailable and comes back where it was is no move at all.
+	mu    sync.Mutex
+	known map[string]string
+}
+
+// unplaced reports a state that says nothing of where a person is.
+func unplaced(state string) bool {
+	return state == "" || state == "unavailable" || state == "unknown"
+}
+
+func (l *zoneListener) handle(ctx context.Context, run ha.Run) error {
+	ev := run.Event
+	if ev.Deleted || unplaced(ev.To.State) {
+		return nil
+	}
+
+	l.mu.Lock()
+	from := ev.From.State
+	if unplaced(from) {
+		from = l.known[ev.EntityID]
+	}
+	l.known[ev.EntityID] = ev.To.State
+	l.mu.Unlock()
+
+	if from == "" || from == ev.To.State {
+		// Nothing to compare with, or a tracker returning to where it was.
+		return nil
+	}
+
+	// Departures before arrivals, so moving from work to home reads in the
+	// order it happened.
+	var left, entered []string
+	for _, zone := range l.zones {
+		was, is := inZone(run.State, zone, from), inZone(run.State, zone, ev.To.State)
+		switch {
+		case was && !is:
+			left = append(left, zone)
+		case is && !was:
+			entered = append(entered, zone)
+		}
+	}
+
+	var errs []error
+	for _, zone := range left {
+		if l.onLeave != nil {
+			errs = append(errs, l.onLeave(ctx, ZoneRun{Run: run, Person: ev.EntityID, Zone: zone}))
+		}
+	}
+	for _, zone := range entered {
+		if l.onEnter != nil {
+			errs = append(errs, l.onEnter(ctx, ZoneRun{Run: run, Person: ev.EntityID, Zone: zone}))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// inZone reports whether a tracker state places its person in the zone. Home
+// Assistant names zone.home "home" whatever it is called, and every other zone
+// by its friendly name; a zone it cannot read is matched on its object id.
+func inZone(state ha.StateReader, zone, trackerState string) bool {
+	if trackerState == "not_home" {
+		return false
+	}
+	if zone == "zone.home" {
+		return trackerState == "home"
+	}
+
+	_, object, _ := strings.Cut(zone, ".")
+	name := object
+	if z, err := state.Get(zone); err == nil {
+		if friendly, ok := z.Attributes["friendly_name"].(string); ok && friendly != "" {
+			name = friendly
+		}
+	}
+	return strings.EqualFold(trackerState, name) || strings.EqualFold(trackerState, object)
+}
@@ -0,0 +1,115 @@
+package presence_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/presence"
+)
+
+func settle() { time.Sleep(50 * time.Millisecond) }
+
+// moves records the callbacks in the order they ran.
+type moves struct {
+	mu  sync.Mutex
+	got []string
+}
+
+func (m *moves) record(kind string) presence.ZoneAction {
+	return func(_ context.Context, run presence.ZoneRun) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.got = append(m.got, kind+" "+run.Zone)
+		return nil
+	}
+}
+
+func (m *moves) take() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	got := m.got
+	m.got = nil
+	return got
+}
+
+func TestZoneListenerFollowsMovesBetweenZones(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("person.alice", "home")
+	server.SetState("zone.home", "1", map[string]any{"friendly_name": "Our House"})
+	server.SetState("zone.work", "0", map[string]any{"friendly_name": "Office"})
+	app := hatest.NewApp(t, server)
+
+	m := &moves{}
+	require.NoError(t, app.RegisterAutomations(
+		presence.NewZoneListener().
+			Person("person.alice").
+			Zone("zone.home", "zone.work").
+			OnEnter(m.record("enter")).
+			OnLeave(m.record("leave")).
+			MustBuild(),
+	))
+	hatest.StartApp(t, app)
+
+	server.ChangeState("person.alice", "not_home")
+	settle()
+	assert.Equal(t, []string{"leave zone.home"}, m.take(), "home is home whatever the zone is called")
+
+	server.ChangeState("person.alice", "Office")
+	settle()
+	assert.Equal(t, []string{"enter zone.work"}, m.take(), "other zones go by their friendly name")
+
+	server.ChangeState("person.alice", "home")
+	settle()
+	assert.Equal(t, []string{"leave zone.work", "enter zone.home"}, m.take(), "one change, two moves, departure first")
+}
+
+// A tracker that loses its signal has not gone anywhere.
+func TestZoneListenerIgnoresUnavailableTrackers(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("device_tracker.alice_phone", "home")
+	app := hatest.NewApp(t, server)
+
+	m := &moves{}
+	require.NoError(t, app.RegisterAutomations(
+		presence.NewZoneListener().
+			Person("device_tracker.alice_phone").
+			Zone("zone.home").
+			OnEnter(m.record("enter")).
+			OnLeave(m.record("leave")).
+			MustBuild(),
+	))
+	hatest.StartApp(t, app)
+
+	server.ChangeState("device_tracker.alice_phone", "not_home")
+	server.ChangeState("device_tracker.alice_phone", "unavailable")
+	server.ChangeState("device_tracker.alice_phone", "not_home")
+	settle()
+	assert.Equal(t, []string{"leave zone.home"}, m.take())
+
+	server.ChangeState("device_tracker.alice_phone", "unknown")
+	server.ChangeState("device_tracker.alice_phone", "home")
+	settle()
+	assert.Equal(t, []string{"enter zone.home"}, m.take(), "the last known place is what a return is measured from")
+}
+
+func TestZoneListenerBuildRejectsBadArguments(t *testing.T) {
+	noop := func(context.Context, presence.ZoneRun) error { return nil }
+
+	for name, b := range map[string]presence.ZoneListenerBuilder{
+		"no person":   presence.NewZoneListener().Zone("zone.home").OnEnter(noop),
+		"not person":  presence.NewZoneListener().Person("light.a").Zone("zone.home").OnEnter(noop),
+		"no zone":     presence.NewZoneListener().Person("person.a").OnEnter(noop),
+		"not zone":    presence.NewZoneListener().Person("person.a").Zone("home").OnEnter(noop),
+		"no callback": presence.NewZoneListener().Person("person.a").Zone("zone.home"),
+	} {
+		_, err := b.Build()
+		assert.ErrorIs(t, err, ha.ErrInvalidAutomation, name)
+	}
+}
@@ -0,0 +1,214 @@
+// Package trend reports how fast numeric entities are changing.
+//
+// A threshold on the value itself is often the wrong question. Bathroom
+// humidity sits anywhere from 50 to 80 percent depending on the weather, but a
+// shower drives it up several points a minute whatever it started from. Asking
+// for the rate of change catches the shower on a humid day without firing on a
+// merely damp one.
+package trend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	ha "github.com/Xevion/go-ha"
+)
+
+// ErrNotEnoughSamples reports a window holding fewer than two readings, which
+// is too few to fit a line through.
+var ErrNotEnoughSamples = errors.New("not enough samples for a trend")
+
+// Options tunes a Tracker.
+type Options struct {
+	// Retain bounds how long readings are kept, and so the widest window Trend
+	// can be asked about. Defaults to an hour.
+	Retain time.Duration
+
+	// Window is the span OnTrendAbove fits its line over. Defaults to ten
+	// minutes.
+	Window time.Duration
+}
+
+type sample struct {
+	at    time.Time
+	value float64
+}
+
+// watcher is one OnTrendAbove registration. above records whether the last
+// fit was over the threshold, so it fires on crossing rather than on every
+// reading.
+type watcher struct {
+	slope float64
+	fn    func(slope float64)
+	above bool
+}
+
+// Tracker keeps recent readings for a set of entities. Readings are recorded
+// as Home Assistant reports them, so a trend covers only the time since the
+// tracker started.
+type Tracker struct {
+	app  *ha.App
+	opts Options
+
+	mu       sync.Mutex
+	samples  map[string][]sample
+	watchers map[string][]*watcher
+}
+
+// NewTracker starts recording the given entities' numeric states. A state that
+// is not a number, such as unavailable, is skipped rather than recorded.
+func NewTracker[T ha.EntityRef](app *ha.App, opts Options, entityIDs ...T) (*Tracker, error) {
+	if len(entityIDs) == 0 {
+		return nil, fmt.Errorf("%w: a trend tracker needs entities", ha.ErrInvalidArgs)
+	}
+	if opts.Retain <= 0 {
+		opts.Retain = time.Hour
+	}
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Minute
+	}
+	if opts.Window > opts.Retain {
+		return nil, fmt.Errorf("%w: trend window %s is longer than the %s retained", ha.ErrInvalidArgs, opts.Window, opts.Retain)
+	}
+
+	t := &Tracker{
+		app:      app,
+		opts:     opts,
+		samples:  map[string][]sample{},
+		watchers: map[string][]*watcher{},
+	}
+	ids := make([]string, 0, len(entityIDs))
+	for _, id := range entityIDs {
+		ids = append(ids, string(id))
+		t.samples[string(id)] = nil
+	}
+
+	a, err := ha.NewAutomation("trend of "+ids[0]).
+		On(ha.AtStartup(), ha.StateChanged(ids...)).
+		Mode(ha.ModeQueued).
+		Do(func(_ context.Context, run ha.Run) error {
+			if run.Event.EntityID != "" {
+				t.record(run.Event.EntityID, run.Event.To.State)
+				return nil
+			}
+			// At startup, the readings the entities already hold.
+			for _, id := range ids {
+				if st, err := app.State().Get(id); err == nil {
+					t.record(id, st.State)
+				}
+			}
+			return nil
+		}).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("trend of %s: %w", ids[0], err)
+	}
+	if err := app.RegisterAutomations(a); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Trend is the entity's rate of change over the last window, in its unit per
+// minute, fitted by least squares over the readings in that span.
+func (t *Tracker) Trend(entityID string, window time.Duration) (float64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples, ok := t.samples[entityID]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is not tracked", ha.ErrInvalidArgs, entityID)
+	}
+	if window > t.opts.Retain {
+		return 0, fmt.Errorf("%w: window %s is longer than the %s retained", ha.ErrInvalidArgs, window, t.opts.Retain)
+	}
+	return slope(samples, t.app.Clock().Now().Add(-window))
+}
+
+// OnTrendAbove calls fn when the entity's trend over the tracker's window rises
+// above slope, in its unit per minute. It fires once per crossing, and again
+// only after the trend has fallen back.
+func (t *Tracker) OnTrendAbove(entityID string, slope float64, fn func(slope float64)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.samples[entityID]; !ok {
+		return fmt.Errorf("%w: %s is not tracked", ha.ErrInvalidArgs, entityID)
+	}
+	t.watchers[entityID] = append(t.watchers[entityID], &watcher{slope: slope, fn: fn})
+	return nil
+}
+
+// record adds a reading, drops those older than Retain, and checks the
+// entity's watchers against the new fit.
+func (t *Tracker) record(entityID, state string) {
+	value, err := strconv.ParseFloat(state, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	now := t.app.Clock().Now()
+
+	t.mu.Lock()
+	samples := append(t.samples[entityID], sample{at: now, value: value})
+	cutoff := now.Add(-t.opts.Retain)
+	drop := 0
+	for drop < len(samples) && samples[drop].at.Before(cutoff) {
+		drop++
+	}
+	samples = samples[drop:]
+	t.samples[entityID] = samples
+
+	current, fitErr := slope(samples, now.Add(-t.opts.Window))
+	var due []func()
+	for _, w := range t.watchers[entityID] {
+		above := fitErr == nil && current > w.slope
+		if above && !w.above {
+			fn := w.fn
+			due = append(due, func() { fn(current) })
+		}
+		w.above = above
+	}
+	t.mu.Unlock()
+
+	// Outside the lock, so a callback can ask for a Trend of its own.
+	for _, call := range due {
+		call()
+	}
+}
+
+// slope fits a least-squares line through the samples taken since the given
+// instant and returns its gradient per minute.
+func slope(samples []sample, since time.Time) (float64, error) {
+	var n, sumX, sumY, sumXX, sumXY float64
+	var origin time.Time
+	for _, s := range samples {
+		if s.at.Before(since) {
+			continue
+		}
+		if n == 0 {
+			origin = s.at
+		}
+		x := s.at.Sub(origin).Minutes()
+		n++
+		sumX += x
+		sumY += s.value
+		sumXX += x * x
+		sumXY += x * s.value
+	}
+	if n < 2 {
+		return 0, ErrNotEnoughSamples
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		// Every reading landed at the same instant: no time passed to measure
+		// a rate over.
+		return 0, ErrNotEnoughSamples
+	}
+	return (n*sumXY - sumX*sumY) / denominator, nil
+}
@@ -0,0 +1,111 @@
+package trend_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/modules/trend"
+	"github.com/Xevion/go-ha/types"
+)
+
+// newApp builds an app on a clock moved by hand, so readings can be spread
+// over minutes without the test taking them.
+func newApp(t *testing.T, server *hatest.Server) (*ha.App, *hatest.Clock) {
+	t.Helper()
+
+	clock := hatest.NewClock(time.Date(2026, 3, 1, 7, 0, 0, 0, time.UTC))
+	return hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.Clock = clock }), clock
+}
+
+// report moves the clock on a minute and publishes a new reading.
+func report(server *hatest.Server, clock *hatest.Clock, value string) {
+	clock.Advance(time.Minute)
+	server.ChangeState("sensor.bathroom_humidity", value)
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestTrendFitsTheRecentReadings(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("sensor.bathroom_humidity", "50")
+	app, clock := newApp(t, server)
+
+	tracker, err := trend.NewTracker(app, trend.Options{}, "sensor.bathroom_humidity")
+	require.NoError(t, err)
+	hatest.StartApp(t, app)
+
+	_, err = tracker.Trend("sensor.bathroom_humidity", 10*time.Minute)
+	assert.ErrorIs(t, err, trend.ErrNotEnoughSamples, "one reading has no slope")
+
+	for _, v := range []string{"53", "56", "59"} {
+		report(server, clock, v)
+	}
+
+	slope, err := tracker.Trend("sensor.bathroom_humidity", 10*time.Minute)
+	require.NoError(t, err)
+	assert.InDelta(t, 3, slope, 0.001, "three points a minute")
+
+	// Readings older than the window no longer count.
+	clock.Advance(20 * time.Minute)
+	_, err = tracker.Trend("sensor.bathroom_humidity", 10*time.Minute)
+	assert.ErrorIs(t, err, trend.ErrNotEnoughSamples)
+}
+
+func TestOnTrendAboveFiresOncePerRise(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("sensor.bathroom_humidity", "60")
+	app, clock := newApp(t, server)
+
+	tracker, err := trend.NewTracker(app, trend.Options{Window: 3 * time.Minute}, "sensor.bathroom_humidity")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var fired []float64
+	require.NoError(t, tracker.OnTrendAbove("sensor.bathroom_humidity", 2, func(slope float64) {
+		mu.Lock()
+		fired = append(fired, slope)
+		mu.Unlock()
+	}))
+	hatest.StartApp(t, app)
+
+	// A humid day drifts; it does not climb.
+	report(server, clock, "61")
+	report(server, clock, "61.5")
+
+	// The shower starts, and keeps going.
+	report(server, clock, "66")
+	report(server, clock, "71")
+	report(server, clock, "76")
+
+	// It ends, and humidity falls away.
+	report(server, clock, "70")
+	report(server, clock, "64")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, fired, 1, "a sustained rise is one shower, not one per reading")
+	assert.Greater(t, fired[0], 2.0)
+}
+
+func TestTrackerRefusesUntrackedEntities(t *testing.T) {
+	server := hatest.New(t)
+	app := hatest.NewApp(t, server)
+
+	tracker, err := trend.NewTracker(app, trend.Options{}, "sensor.a")
+	require.NoError(t, err)
+
+	_, err = tracker.Trend("sensor.b", time.Minute)
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+	assert.ErrorIs(t, tracker.OnTrendAbove("sensor.b", 1, func(float64) {}), ha.ErrInvalidArgs)
+
+	_, err = trend.NewTracker(app, trend.Options{Window: 2 * time.Hour}, "sensor.a")
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+
+	_, err = trend.NewTracker(app, trend.Options{}, "humidity")
+	assert.ErrorIs(t, err, ha.ErrInvalidAutomation, "an entity without a domain is an error, not a panic")
+}
@@ -0,0 +1,63 @@
+package ha_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestMQTTTriggerAndPublish(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	got := make(chan ha.MQTTMessage, 4)
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("hall button").
+			On(ha.MQTT("zigbee2mqtt/+/action").Payload("single")).
+			Do(func(_ context.Context, run ha.Run) error {
+				got <- *run.Event.MQTT
+				return run.Services.MQTT.Publish("zigbee2mqtt/hall_lamp/set", `{"state":"TOGGLE"}`, false)
+			}).
+			MustBuild(),
+		ha.NewAutomation("lamp echo").
+			On(ha.MQTT("zigbee2mqtt/hall_lamp/set")).
+			Do(func(_ context.Context, run ha.Run) error {
+				got <- *run.Event.MQTT
+				return nil
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.PublishMQTT("zigbee2mqtt/hall_button/action", "hold")
+	server.PublishMQTT("zigbee2mqtt/hall_button/action", "single")
+
+	receive := func() ha.MQTTMessage {
+		t.Helper()
+		select {
+		case m := <-got:
+			return m
+		case <-time.After(2 * time.Second):
+			t.Fatal("no message arrived")
+			return ha.MQTTMessage{}
+		}
+	}
+	press := receive()
+	assert.Equal(t, "zigbee2mqtt/hall_button/action", press.Topic)
+	assert.Equal(t, "single", press.Payload)
+
+	echo := receive()
+	assert.Equal(t, `{"state":"TOGGLE"}`, echo.Payload, "the publish went out through Home Assistant")
+	server.AssertServiceCalled("mqtt", "publish", "")
+	assert.Zero(t, app.ConnectionStats().Undecodable, "stream messages are not mistaken for malformed events")
+
+	listed := app.Inspect().Listeners
+	require.Len(t, listed, 2)
+	assert.Equal(t, []string{"mqtt"}, listed[0].EventTypes)
+}
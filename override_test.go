@@ -0,0 +1,119 @@
+package ha_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/services"
+)
+
+func TestTemporaryOverrideRestoresTheState(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("light.porch", "on", map[string]any{"brightness": 80})
+	app := newApp(t, server)
+	start(t, app)
+
+	require.NoError(t, app.Services().TemporaryOverride("light.porch", "off", 150*time.Millisecond))
+	server.ChangeState("light.porch", "off")
+
+	calls := server.WaitForCalls(2)
+	assert.Equal(t, "turn_off", calls[0].Service)
+	assert.Equal(t, "turn_on", calls[1].Service)
+	assert.EqualValues(t, 80, calls[1].ServiceData["brightness"], "a light gets its brightness back too")
+}
+
+// Someone flipping the switch themselves has the last word.
+func TestTemporaryOverrideYieldsToSomeoneElse(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("switch.fountain", "off")
+	app := newApp(t, server)
+	start(t, app)
+
+	require.NoError(t, app.Services().TemporaryOverride("switch.fountain", "on", 150*time.Millisecond))
+	server.ChangeState("switch.fountain", "on")
+	server.ChangeState("switch.fountain", "off")
+	server.ChangeState("switch.fountain", "on")
+
+	time.Sleep(300 * time.Millisecond)
+	require.Len(t, server.Calls(), 1, "the restore was cancelled")
+	assert.Equal(t, "turn_on", server.Calls()[0].Service)
+}
+
+// A second override extends the first, and still restores what came before
+// either.
+func TestTemporaryOverrideExtends(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("light.hall", "off")
+	app := newApp(t, server)
+	start(t, app)
+
+	require.NoError(t, app.Services().TemporaryOverride("light.hall", "on", 150*time.Millisecond))
+	server.ChangeState("light.hall", "on")
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, app.Services().TemporaryOverride("light.hall", "on", 150*time.Millisecond))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, server.Calls(), 2, "the first restore was superseded")
+
+	calls := server.WaitForCalls(3)
+	assert.Equal(t, "turn_off", calls[2].Service)
+}
+
+// An override that fails leaves the one already running alone, so the entity
+// still goes back to how it was before either.
+func TestTemporaryOverrideFailingAgainKeepsTheFirstRestore(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("light.porch", "on", map[string]any{"brightness": 80})
+	var refused atomic.Bool
+	server.HandleService("light", "turn_on", func(map[string]any) (any, error) {
+		if refused.CompareAndSwap(false, true) {
+			return nil, errors.New("bulb unreachable")
+		}
+		return nil, nil
+	})
+	app := newApp(t, server)
+	start(t, app)
+	svc := app.Services().WithResult()
+
+	require.NoError(t, svc.TemporaryOverride("light.porch", "off", 200*time.Millisecond))
+	assert.ErrorContains(t, svc.TemporaryOverride("light.porch", "on", time.Hour), "bulb unreachable")
+
+	calls := server.WaitForCalls(3)
+	assert.Equal(t, "turn_on", calls[2].Service, "the first override still restores")
+	assert.EqualValues(t, 80, calls[2].ServiceData["brightness"])
+}
+
+// The override's calls go through the services it was made on.
+func TestTemporaryOverrideFollowsTargeting(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("switch.fountain", "off")
+	app := newApp(t, server)
+	start(t, app)
+
+	require.NoError(t, app.Services().Targeting(services.InArea("garden")).
+		TemporaryOverride("switch.fountain", "on", 100*time.Millisecond))
+
+	calls := server.WaitForCalls(2)
+	assert.Equal(t, []string{"garden"}, calls[0].AreaIDs)
+	assert.Equal(t, "turn_off", calls[1].Service)
+	assert.Equal(t, []string{"garden"}, calls[1].AreaIDs, "the restore too")
+}
+
+func TestTemporaryOverrideRejectsBadArguments(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("light.hall", "off")
+	app := newApp(t, server)
+
+	assert.ErrorIs(t, app.Services().TemporaryOverride("cover.garage", "on", time.Minute), ha.ErrInvalidArgs)
+	assert.ErrorIs(t, app.Services().TemporaryOverride("light.hall", "dim", time.Minute), ha.ErrInvalidArgs)
+	assert.ErrorIs(t, app.Services().TemporaryOverride("light.hall", "on", 0), ha.ErrInvalidArgs)
+	assert.Error(t, app.Services().TemporaryOverride("light.missing", "on", time.Minute))
+	assert.Empty(t, server.Calls())
+}
@@ -0,0 +1,84 @@
+package ha_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestReadOnlyAppObservesButDoesNotAct(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+
+	app, err := ha.NewApp(types.NewAppRequest{
+		URL:         server.URL(),
+		HAAuthToken: hatest.Token,
+		ReadOnly:    true,
+		Audit:       types.AuditOptions{Sensor: types.DefaultAuditSensor},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = app.Close() })
+
+	ran := make(chan error, 1)
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("hall light").
+			On(ha.StateChanged("binary_sensor.hall_motion").To("on")).
+			Do(func(_ context.Context, run ha.Run) error {
+				err := run.Services.Light.TurnOn("light.hall")
+				ran <- err
+				return err
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	select {
+	case err := <-ran:
+		assert.ErrorIs(t, err, ha.ErrReadOnly, "the automation still runs, and is told")
+	case <-time.After(2 * time.Second):
+		t.Fatal("the automation never ran")
+	}
+
+	_, err = app.Services().WithResult().CallWithResult(context.Background(), "light", "turn_off", "light.hall", nil)
+	assert.ErrorIs(t, err, ha.ErrReadOnly)
+	assert.ErrorIs(t, app.Services().Event.Fire("custom"), ha.ErrReadOnly)
+	assert.ErrorIs(t, app.SetState("sensor.virtual", "1", nil), ha.ErrReadOnly)
+
+	on, err := app.State().Equals("binary_sensor.hall_motion", "on")
+	require.NoError(t, err)
+	assert.True(t, on, "reads work as usual")
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, server.Calls())
+	_, _, ok := server.State("sensor.virtual")
+	assert.False(t, ok)
+	_, _, ok = server.State(types.DefaultAuditSensor)
+	assert.False(t, ok, "nothing was called, so nothing is audited")
+}
+
+func TestReadOnlyAppRefusesCommandsThatWrite(t *testing.T) {
+	server := hatest.New(t)
+	server.HandleCommand("config/automation/list", func(map[string]any) (any, error) {
+		return []any{}, nil
+	})
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) { r.ReadOnly = true })
+
+	_, err := app.Command(map[string]any{"type": "call_service", "domain": "light", "service": "turn_on"})
+	assert.ErrorIs(t, err, ha.ErrReadOnly)
+	_, err = app.Command(map[string]any{"type": "calendar/event/delete", "entity_id": "calendar.go_ha", "uid": "1"})
+	assert.ErrorIs(t, err, ha.ErrReadOnly)
+	_, err = app.Command(map[string]any{"type": "config/area_registry/create", "name": "Attic"})
+	assert.ErrorIs(t, err, ha.ErrReadOnly)
+
+	_, err = app.Command(map[string]any{"type": "config/automation/list"})
+	assert.NoError(t, err, "reads pass")
+	assert.Empty(t, server.Calls())
+}
@@ -0,0 +1,87 @@
+package ha_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+// kitchen fills the server's registries with a kitchen holding a lamp device
+// and a thermometer assigned to the room directly.
+func kitchen(server *hatest.Server) {
+	server.AddArea(hatest.Area{ID: "kitchen", Name: "Kitchen"})
+	server.AddArea(hatest.Area{ID: "hall", Name: "Hall"})
+	server.AddDevice(hatest.Device{ID: "lamp", Name: "Lamp", Manufacturer: "IKEA", AreaID: "kitchen"})
+	server.AddDevice(hatest.Device{ID: "plug", Name: "Plug", Manufacturer: "Ikea", AreaID: "hall"})
+	server.AddRegistryEntity(hatest.RegistryEntity{EntityID: "light.lamp", DeviceID: "lamp"})
+	// Its device is in the kitchen, but the entity was moved to the hall.
+	server.AddRegistryEntity(hatest.RegistryEntity{EntityID: "sensor.lamp_power", DeviceID: "lamp", AreaID: "hall"})
+	server.AddRegistryEntity(hatest.RegistryEntity{EntityID: "sensor.lamp_signal", DeviceID: "lamp", Disabled: true})
+	server.AddRegistryEntity(hatest.RegistryEntity{EntityID: "sensor.kitchen_temp", AreaID: "kitchen"})
+	server.AddRegistryEntity(hatest.RegistryEntity{EntityID: "switch.plug", DeviceID: "plug"})
+}
+
+func TestRegistryEntitiesInArea(t *testing.T) {
+	server := hatest.New(t)
+	kitchen(server)
+	app := newApp(t, server)
+
+	ids, err := app.Registry().EntitiesInArea("kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.lamp", "sensor.kitchen_temp"}, ids)
+
+	ids, err = app.Registry().EntitiesInArea("hall")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sensor.lamp_power", "switch.plug"}, ids)
+
+	byName, err := app.Registry().EntitiesInArea("KITCHEN")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.lamp", "sensor.kitchen_temp"}, byName)
+
+	_, err = app.Registry().EntitiesInArea("attic")
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
+
+func TestRegistryDevices(t *testing.T) {
+	server := hatest.New(t)
+	kitchen(server)
+	app := newApp(t, server)
+
+	devices, err := app.Registry().DevicesByManufacturer("ikea")
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "lamp", devices[0].ID)
+	assert.Equal(t, "plug", devices[1].ID)
+
+	devices, err = app.Registry().DevicesInArea("Hall")
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "Plug", devices[0].Name)
+
+	ids, err := app.Registry().EntitiesOfDevice("lamp")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.lamp", "sensor.lamp_power", "sensor.lamp_signal"}, ids)
+}
+
+func TestRegistryRefetchesAfterAnUpdate(t *testing.T) {
+	server := hatest.New(t)
+	kitchen(server)
+	app := newApp(t, server)
+
+	ids, err := app.Registry().EntitiesInArea("kitchen")
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+
+	server.AddRegistryEntity(hatest.RegistryEntity{EntityID: "light.spot", AreaID: "kitchen"})
+	server.Fire("entity_registry_updated", map[string]any{"action": "create", "entity_id": "light.spot"})
+
+	assert.Eventually(t, func() bool {
+		ids, err := app.Registry().EntitiesInArea("kitchen")
+		return err == nil && len(ids) == 3
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,50 @@
+package ha_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestRemoteTriggerRunsOnHomeAssistantsFiring(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	got := make(chan ha.Event, 1)
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("door left open").
+			On(ha.RemoteState("binary_sensor.front_door").To("on").For(10*time.Minute)).
+			Do(func(_ context.Context, run ha.Run) error {
+				got <- run.Event
+				return nil
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	triggers := server.Triggers()
+	require.Len(t, triggers, 1)
+	assert.Equal(t, "on", triggers[0]["to"])
+	assert.Equal(t, map[string]any{"seconds": 600.0}, triggers[0]["for"])
+
+	require.True(t, server.FireTrigger("state", map[string]any{
+		"entity_id":  "binary_sensor.front_door",
+		"to_state":   map[string]any{"entity_id": "binary_sensor.front_door", "state": "on"},
+		"for":        map[string]any{"__type": "<class 'datetime.timedelta'>", "total_seconds": 600.0},
+		"from_state": map[string]any{"entity_id": "binary_sensor.front_door", "state": "off"},
+	}))
+	select {
+	case ev := <-got:
+		assert.Equal(t, "binary_sensor.front_door", ev.EntityID)
+		assert.Equal(t, "on", ev.To.State)
+		assert.Equal(t, "state", ev.Variables["platform"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("the automation never ran")
+	}
+}
@@ -0,0 +1,92 @@
+package ha_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestReplayLastPrimesFromHistory(t *testing.T) {
+	server := hatest.New(t)
+	now := time.Now().Truncate(time.Second)
+	server.AddHistory("binary_sensor.doorbell", "off", now.Add(-48*time.Hour))
+	server.AddHistory("binary_sensor.doorbell", "on", now.Add(-4*time.Hour))
+	server.AddHistory("binary_sensor.doorbell", "off", now.Add(-4*time.Hour+time.Second))
+	server.AddHistory("binary_sensor.doorbell", "on", now.Add(-time.Hour))
+	server.AddHistory("binary_sensor.doorbell", "off", now.Add(-time.Hour+time.Second))
+	server.SetState("binary_sensor.doorbell", "off")
+
+	app := newApp(t, server)
+
+	last := make(chan ha.Event, 4)
+	all := make(chan ha.Event, 4)
+	record := func(ch chan ha.Event) ha.Action {
+		return func(_ context.Context, run ha.Run) error {
+			ch <- run.Event
+			return nil
+		}
+	}
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("last press").
+			On(ha.StateChanged("binary_sensor.doorbell").To("on").ReplayLast(1)).
+			Do(record(last)).
+			MustBuild(),
+		ha.NewAutomation("every press").
+			On(ha.StateChanged("binary_sensor.door*").To("on").ReplayLast(5)).
+			Mode(ha.ModeQueued).
+			Do(record(all)).
+			MustBuild(),
+	))
+	start(t, app)
+
+	receive := func(ch chan ha.Event) ha.Event {
+		t.Helper()
+		select {
+		case ev := <-ch:
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatal("no replay arrived")
+			return ha.Event{}
+		}
+	}
+
+	ev := receive(last)
+	assert.True(t, ev.Replayed)
+	assert.Equal(t, "binary_sensor.doorbell", ev.EntityID)
+	assert.Equal(t, "off", ev.From.State)
+	assert.True(t, ev.To.LastChanged.Equal(now.Add(-time.Hour)), "the most recent press, not the earlier one")
+
+	first, second := receive(all), receive(all)
+	assert.ElementsMatch(t,
+		[]time.Time{now.Add(-4 * time.Hour), now.Add(-time.Hour)},
+		[]time.Time{first.To.LastChanged.Local(), second.To.LastChanged.Local()})
+	select {
+	case ev := <-all:
+		t.Fatalf("only two presses happened, got a third at %s", ev.To.LastChanged)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Live events still arrive, and are not marked as replayed.
+	server.ChangeState("binary_sensor.doorbell", "on")
+	assert.False(t, receive(last).Replayed)
+}
+
+func TestReplayLastNeedsNamedEntities(t *testing.T) {
+	_, err := ha.NewAutomation("everything").
+		On(ha.StateChanged[string]().ReplayLast(1)).
+		Do(func(context.Context, ha.Run) error { return nil }).
+		Build()
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+
+	_, err = ha.NewAutomation("negative").
+		On(ha.StateChanged("binary_sensor.doorbell").ReplayLast(-1)).
+		Do(func(context.Context, ha.Run) error { return nil }).
+		Build()
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
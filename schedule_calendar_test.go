@@ -0,0 +1,145 @@
+package ha_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+func morningApp(t *testing.T, server *hatest.Server, clock *hatest.Clock) *ha.App {
+	t.Helper()
+
+	app := newAppWithClock(t, server, clock)
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("open the blinds").
+			On(ha.Daily(ha.TimeOfDay(7, 30))).
+			Do(func(context.Context, ha.Run) error { return nil }).
+			MustBuild(),
+	))
+	require.NoError(t, app.PublishSchedules("calendar.go_ha", 12*time.Hour))
+	return app
+}
+
+// waitForEvents waits until the calendar holds exactly the given summaries,
+// soonest first.
+func waitForEvents(t *testing.T, server *hatest.Server, summaries ...string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		var got []string
+		for _, ev := range server.CalendarEvents("calendar.go_ha") {
+			got = append(got, ev.Summary)
+		}
+		return slices.Equal(got, summaries)
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestPublishedSchedulesReplaceOnlyTheirOwnEvents(t *testing.T) {
+	server := hatest.New(t)
+	clock := hatest.NewClock(time.Date(2026, 3, 1, 6, 0, 0, 0, time.Local))
+	app := morningApp(t, server, clock)
+
+	// One event left behind by an earlier run, one a person added.
+	for _, ev := range []map[string]any{
+		{"summary": "water the plants", "description": "Scheduled by go-ha: daily(06:45)"},
+		{"summary": "dentist", "description": "bring the forms"},
+	} {
+		ev["start_date_time"] = clock.Now().Add(45 * time.Minute).Format(time.RFC3339)
+		ev["end_date_time"] = clock.Now().Add(time.Hour).Format(time.RFC3339)
+		_, err := app.Services().CallWithResult(context.Background(), "calendar", "create_event", "calendar.go_ha", ev)
+		require.NoError(t, err)
+	}
+	start(t, app)
+	waitForEvents(t, server, "dentist", "open the blinds")
+
+	blinds := server.CalendarEvents("calendar.go_ha")[1]
+	assert.True(t, blinds.Start.Equal(time.Date(2026, 3, 1, 7, 30, 0, 0, time.Local)))
+	assert.True(t, strings.HasPrefix(blinds.Description, "Scheduled by go-ha"))
+}
+
+func TestPublishingAgainDoesNotDuplicate(t *testing.T) {
+	server := hatest.New(t)
+	clock := hatest.NewClock(time.Date(2026, 3, 1, 6, 0, 0, 0, time.Local))
+
+	first := morningApp(t, server, clock)
+	start(t, first)
+	waitForEvents(t, server, "open the blinds")
+	require.NoError(t, first.Close())
+
+	// A restart finds its events already there.
+	start(t, morningApp(t, server, clock))
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, server.CalendarEvents("calendar.go_ha"), 1)
+}
+
+func TestPublishSchedulesChecksTheCalendar(t *testing.T) {
+	app := newApp(t, hatest.New(t))
+
+	assert.ErrorIs(t, app.PublishSchedules("sensor.go_ha", 0), ha.ErrInvalidArgs)
+	require.NoError(t, app.PublishSchedules("calendar.go_ha", 0))
+	assert.ErrorIs(t, app.PublishSchedules("calendar.other", 0), ha.ErrInvalidArgs, "one calendar per app")
+}
+
+func TestDryRunPublishingLeavesTheCalendarAlone(t *testing.T) {
+	server := hatest.New(t)
+	clock := hatest.NewClock(time.Date(2026, 3, 1, 6, 0, 0, 0, time.Local))
+
+	// An event left behind by an earlier run, which publishing would delete.
+	seed := newApp(t, server)
+	_, err := seed.Services().CallWithResult(context.Background(), "calendar", "create_event", "calendar.go_ha", map[string]any{
+		"summary":         "water the plants",
+		"description":     "Scheduled by go-ha: daily(06:45)",
+		"start_date_time": clock.Now().Add(45 * time.Minute).Format(time.RFC3339),
+		"end_date_time":   clock.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	require.NoError(t, err)
+
+	app := hatest.NewApp(t, server, func(r *types.NewAppRequest) {
+		r.Clock = clock
+		r.DryRun = true
+	})
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("open the blinds").
+			On(ha.Daily(ha.TimeOfDay(7, 30))).
+			Do(func(context.Context, ha.Run) error { return nil }).
+			MustBuild(),
+	))
+	require.NoError(t, app.PublishSchedules("calendar.go_ha", 12*time.Hour))
+	start(t, app)
+
+	require.Eventually(t, func() bool { return len(app.DryRunCalls()) == 2 }, 2*time.Second, 10*time.Millisecond)
+	calls := app.DryRunCalls()
+	assert.Equal(t, "calendar/event/delete", calls[0].Command)
+	assert.Equal(t, "create_event", calls[1].Service)
+	waitForEvents(t, server, "water the plants")
+}
+
+func TestCalendarServiceReadsBackTheEventsItCreates(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+	start(t, app)
+
+	cal := app.Services().Calendar
+	morning := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, cal.CreateEvent("calendar.work", "Standup", morning, morning.Add(15*time.Minute),
+		map[string]any{"description": "room 4"}))
+	require.NoError(t, cal.CreateEvent("calendar.work", "Review", morning.Add(6*time.Hour), morning.Add(7*time.Hour)))
+	require.Eventually(t, func() bool { return len(server.CalendarEvents("calendar.work")) == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	events, err := cal.GetEvents(context.Background(), "calendar.work", morning.Add(-time.Hour), morning.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 1, "the review is outside the span")
+	assert.Equal(t, "Standup", events[0].Summary)
+	assert.Equal(t, "room 4", events[0].Description)
+	assert.True(t, events[0].Start.Equal(morning))
+	assert.True(t, events[0].End.Equal(morning.Add(15*time.Minute)))
+	assert.False(t, events[0].AllDay)
+}
@@ -0,0 +1,95 @@
+package ha_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestPlainServiceCallsDoNotWait(t *testing.T) {
+	server := hatest.New(t)
+	server.HandleService("light", "turn_on", func(map[string]any) (any, error) {
+		return nil, errors.New("Entity light.missing not found")
+	})
+	app := newApp(t, server)
+	start(t, app)
+
+	// Sent, and that is all a plain call reports.
+	assert.NoError(t, app.Services().Light.TurnOn("light.missing"))
+}
+
+func TestWithResultReportsARefusedCall(t *testing.T) {
+	server := hatest.New(t)
+	server.HandleService("light", "turn_on", func(map[string]any) (any, error) {
+		return nil, errors.New("Entity light.missing not found")
+	})
+	app := newApp(t, server)
+	start(t, app)
+
+	err := app.Services().WithResult().Light.TurnOn("light.missing")
+	require.ErrorIs(t, err, ha.ErrCallFailed)
+	assert.Contains(t, err.Error(), "light.missing not found")
+
+	assert.NoError(t, app.Services().WithResult().Light.TurnOff("light.hall"))
+	assert.Len(t, server.Calls(), 2, "a refused call was still made")
+}
+
+func TestCallWithResultCarriesTheContext(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+	start(t, app)
+
+	result, err := app.Services().CallWithResult(context.Background(), "switch", "toggle", "switch.fan", nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Context.ID)
+	assert.Empty(t, result.Response, "no response was asked for")
+}
+
+func TestCallForResponseReturnsTheServiceData(t *testing.T) {
+	server := hatest.New(t)
+	server.HandleService("weather", "get_forecasts", func(msg map[string]any) (any, error) {
+		return map[string]any{"weather.home": map[string]any{"forecast": []any{map[string]any{"temperature": 21}}}}, nil
+	})
+	app := newApp(t, server)
+	start(t, app)
+
+	result, err := app.Services().CallForResponse(context.Background(), "weather", "get_forecasts", "weather.home",
+		map[string]any{"type": "daily"})
+	require.NoError(t, err)
+
+	var forecasts map[string]struct {
+		Forecast []struct {
+			Temperature float64 `json:"temperature"`
+		} `json:"forecast"`
+	}
+	require.NoError(t, json.Unmarshal(result.Response, &forecasts))
+	require.Len(t, forecasts["weather.home"].Forecast, 1)
+	assert.Equal(t, 21.0, forecasts["weather.home"].Forecast[0].Temperature)
+}
+
+func TestTargetingAddsAreasToEveryCall(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+	start(t, app)
+
+	living := app.Services().Targeting(ha.InArea("living_room"))
+	require.NoError(t, living.Light.TurnOn(""))
+	require.NoError(t, living.WithResult().Switch.TurnOff("switch.fan"))
+
+	calls := server.WaitForCalls(2)
+	assert.Equal(t, []string{"living_room"}, calls[0].AreaIDs)
+	assert.Empty(t, calls[0].EntityID)
+	assert.Equal(t, []string{"living_room"}, calls[1].AreaIDs, "WithResult keeps the targets")
+	assert.Equal(t, "switch.fan", calls[1].EntityID)
+
+	// The services it came from are untouched.
+	require.NoError(t, app.Services().Light.TurnOff("light.hall"))
+	assert.Empty(t, server.WaitForCalls(3)[2].AreaIDs)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+type Calendar struct {
+	conn    Sender
+	waiting ResultSender
+}
+
+// NewCalendar builds the calendar services. Reading events waits for Home
+// Assistant's answer, so it goes through waiting; the rest go through conn.
+func NewCalendar(conn Sender, waiting ResultSender) *Calendar {
+	return &Calendar{conn: conn, waiting: waiting}
+}
+
+// CalendarEvent is an event on a calendar, as calendar.get_events returns it.
+type CalendarEvent struct {
+	Summary     string
+	Description string
+	Location    string
+
+	// Start and End bound the event, End exclusive. An all-day event runs
+	// from the local midnight of its first day to that of the day after its
+	// last.
+	Start  time.Time
+	End    time.Time
+	AllDay bool
+}
+
+// GetEvents lists the calendar's events that overlap the span from start to
+// end, soonest first.
+func (c Calendar) GetEvents(ctx context.Context, entityId CalendarID, start, end time.Time) ([]CalendarEvent, error) {
+	if c.waiting == nil {
+		return nil, errors.New("calendar.get_events needs a sender that waits for results")
+	}
+	result, err := CallForResponse(ctx, c.waiting, "calendar", "get_events", EntityID(entityId), map[string]any{
+		"start_date_time": start.Format(time.RFC3339),
+		"end_date_time":   end.Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var response map[string]struct {
+		Events []struct {
+			Summary     string `json:"summary"`
+			Description string `json:"description"`
+			Location    string `json:"location"`
+			Start       string `json:"start"`
+			End         string `json:"end"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(result.Response, &response); err != nil {
+		return nil, fmt.Errorf("decoding events of %s: %w", entityId, err)
+	}
+
+	raw := response[string(entityId)].Events
+	events := make([]CalendarEvent, 0, len(raw))
+	for _, e := range raw {
+		ev := CalendarEvent{Summary: e.Summary, Description: e.Description, Location: e.Location}
+		var errStart, errEnd error
+		ev.Start, ev.AllDay, errStart = parseEventTime(e.Start)
+		ev.End, _, errEnd = parseEventTime(e.End)
+		if err := errors.Join(errStart, errEnd); err != nil {
+			return nil, fmt.Errorf("decoding event %q of %s: %w", e.Summary, entityId, err)
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// parseEventTime reads an event's start or end, which is a date for an
+// all-day event and a date and time otherwise.
+func parseEventTime(s string) (t time.Time, allDay bool, err error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, s)
+	return t, false, err
+}
+
+// CreateEvent adds an event to the calendar, from start to end. Takes an
+// optional map that is translated into service_data, for a description or a
+// location.
+func (c Calendar) CreateEvent(entityId CalendarID, summary string, start, end time.Time, serviceData ...map[string]any) error {
+	req := NewBaseServiceRequest(string(entityId))
+	req.Domain = "calendar"
+	req.Service = "create_event"
+	req.ServiceData = map[string]any{}
+	if len(serviceData) != 0 {
+		maps.Copy(req.ServiceData, serviceData[0])
+	}
+	req.ServiceData["summary"] = summary
+	req.ServiceData["start_date_time"] = start.Format(time.RFC3339)
+	req.ServiceData["end_date_time"] = end.Format(time.RFC3339)
+
+	return c.conn.Send(&req)
+}
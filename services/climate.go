@@ -1,11 +1,32 @@
 package services
 
 import (
+	"fmt"
+
 	"github.com/Xevion/go-ha/types"
 )
 
+// ClimateLimits tells SetTemperature what Home Assistant and the entity accept,
+// so a request it would refuse or clamp fails here instead.
+type ClimateLimits interface {
+	// TemperatureUnit is the unit Home Assistant works in.
+	TemperatureUnit() (types.TemperatureUnit, error)
+
+	// TemperatureRange is the entity's min_temp and max_temp, in that unit.
+	// ok is false when they are not known.
+	TemperatureRange(entityID string) (minTemp, maxTemp float32, ok bool)
+}
+
 type Climate struct {
-	conn Sender
+	conn   Sender
+	limits ClimateLimits
+}
+
+// NewClimate builds the climate services with their requests checked against
+// limits. Built without, only a request's own consistency is checked, and one
+// naming a unit is refused since there is nothing to convert it to.
+func NewClimate(conn Sender, limits ClimateLimits) *Climate {
+	return &Climate{conn: conn, limits: limits}
 }
 
 func (c Climate) SetFanMode(entityId ClimateID, fanMode string) error {
@@ -17,7 +38,35 @@ func (c Climate) SetFanMode(entityId ClimateID, fanMode string) error {
 	return c.conn.Send(&req)
 }
 
+// SetHvacMode sets the thermostat's mode, such as heat, cool or off.
+func (c Climate) SetHvacMode(entityId ClimateID, hvacMode string) error {
+	req := NewBaseServiceRequest(string(entityId))
+	req.Domain = "climate"
+	req.Service = "set_hvac_mode"
+	req.ServiceData = map[string]any{"hvac_mode": hvacMode}
+
+	return c.conn.Send(&req)
+}
+
+// SetPresetMode sets the thermostat's preset, such as eco, away or comfort,
+// from those its preset_modes attribute lists.
+func (c Climate) SetPresetMode(entityId ClimateID, presetMode string) error {
+	req := NewBaseServiceRequest(string(entityId))
+	req.Domain = "climate"
+	req.Service = "set_preset_mode"
+	req.ServiceData = map[string]any{"preset_mode": presetMode}
+
+	return c.conn.Send(&req)
+}
+
+// SetTemperature sets a setpoint or range, converting it from the request's
+// unit and checking it against the entity's min_temp and max_temp first.
 func (c Climate) SetTemperature(entityId ClimateID, serviceData types.SetTemperatureRequest) error {
+	serviceData, err := c.check(string(entityId), serviceData)
+	if err != nil {
+		return fmt.Errorf("climate.set_temperature on %s: %w", entityId, err)
+	}
+
 	req := NewBaseServiceRequest(string(entityId))
 	req.Domain = "climate"
 	req.Service = "set_temperature"
@@ -25,3 +74,31 @@ func (c Climate) SetTemperature(entityId ClimateID, serviceData types.SetTempera
 
 	return c.conn.Send(&req)
 }
+
+func (c Climate) check(entityID string, r types.SetTemperatureRequest) (types.SetTemperatureRequest, error) {
+	if err := r.Validate(); err != nil {
+		return r, err
+	}
+	if c.limits == nil {
+		if r.Unit != "" {
+			return r, fmt.Errorf("%w: Home Assistant's unit is not known to convert %s to", types.ErrInvalidTemperature, r.Unit)
+		}
+		return r, nil
+	}
+
+	// Without a unit of its own the request is already in Home Assistant's, so
+	// there is no need to ask which that is.
+	if r.Unit != "" {
+		unit, err := c.limits.TemperatureUnit()
+		if err != nil {
+			return r, fmt.Errorf("looking up Home Assistant's temperature unit: %w", err)
+		}
+		r = r.InUnit(unit)
+	}
+	if minTemp, maxTemp, ok := c.limits.TemperatureRange(entityID); ok {
+		if err := r.CheckLimits(minTemp, maxTemp); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
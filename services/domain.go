@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// DomainSender is a Sender that can also list the entities of a domain, which
+// calls acting on every entity but some need. The app's senders are; through a
+// plain Sender, such a call can only leave none out.
+type DomainSender interface {
+	Sender
+	EntitiesInDomain(domain string) ([]string, error)
+}
+
+// allExcept names every entity of the domain but those given, as a target.
+// With none left out it is "all", which Home Assistant expands itself. It is
+// empty when every entity is left out.
+func allExcept[T ~string](conn Sender, domain string, except []T) (string, error) {
+	if len(except) == 0 {
+		return "all", nil
+	}
+	lister, ok := conn.(DomainSender)
+	if !ok {
+		return "", errors.New("leaving entities out of " + domain + " needs a sender that can list them")
+	}
+	ids, err := lister.EntitiesInDomain(domain)
+	if err != nil {
+		return "", err
+	}
+	ids = slices.DeleteFunc(ids, func(id string) bool {
+		return slices.Contains(except, T(id))
+	})
+	return strings.Join(ids, ","), nil
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listingRecorder is a recorder that knows some lights.
+type listingRecorder struct {
+	recorder
+	lights []string
+}
+
+func (r *listingRecorder) EntitiesInDomain(domain string) ([]string, error) {
+	if domain != "light" {
+		return nil, nil
+	}
+	return r.lights, nil
+}
+
+func TestTurnOffAllTargetsAllWithNoneLeftOut(t *testing.T) {
+	r := &recorder{}
+	require.NoError(t, BuildService[Light](r).TurnOffAll())
+	assert.Equal(t, "all", r.last.Target.EntityId)
+	assert.Equal(t, "turn_off", r.last.Service)
+}
+
+func TestTurnOffAllLeavesTheExceptionsOut(t *testing.T) {
+	r := &listingRecorder{lights: []string{"light.hall", "light.landing", "light.porch"}}
+	require.NoError(t, BuildService[Light](r).TurnOffAll("light.landing"))
+	assert.Equal(t, "light.hall,light.porch", r.last.Target.EntityId)
+
+	r.last = nil
+	require.NoError(t, BuildService[Light](r).TurnOffAll("light.hall", "light.landing", "light.porch"))
+	assert.Nil(t, r.last, "nothing left to turn off sends nothing")
+}
+
+func TestTurnOffAllNeedsAListerToLeaveAnyOut(t *testing.T) {
+	assert.Error(t, BuildService[Switch](&recorder{}).TurnOffAll("switch.fridge"))
+}
@@ -10,6 +10,7 @@ type (
 	EntityID string
 
 	AlarmControlPanelID EntityID
+	CalendarID          EntityID
 	ClimateID           EntityID
 	CoverID             EntityID
 	InputBooleanID      EntityID
@@ -32,6 +33,7 @@ type (
 // should emit for it. Domains absent from it fall back to EntityID.
 var DomainIDTypes = map[string]string{
 	"alarm_control_panel": "AlarmControlPanelID",
+	"calendar":            "CalendarID",
 	"climate":             "ClimateID",
 	"cover":               "CoverID",
 	"input_boolean":       "InputBooleanID",
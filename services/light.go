@@ -1,5 +1,16 @@
 package services
 
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xevion/go-ha/color"
+	"github.com/Xevion/go-ha/types"
+)
+
 type Light struct {
 	conn Sender
 }
@@ -28,6 +39,17 @@ func (l Light) Toggle(entityId LightID, serviceData ...map[string]any) error {
 	return l.conn.Send(&req)
 }
 
+// SetColor turns a light on in the given colour, which may be any of package
+// color's models: RGB, HS, XY, Kelvin or Mireds. Extra service data, such as
+// brightness or transition, is merged in.
+func (l Light) SetColor(entityId LightID, c color.Color, serviceData ...map[string]any) error {
+	data := c.ServiceData()
+	if len(serviceData) != 0 {
+		maps.Copy(data, serviceData[0])
+	}
+	return l.TurnOn(entityId, data)
+}
+
 // TurnOff turns off a light entity.
 func (l Light) TurnOff(entityId LightID) error {
 	req := NewBaseServiceRequest(string(entityId))
@@ -35,3 +57,80 @@ func (l Light) TurnOff(entityId LightID) error {
 	req.Service = "turn_off"
 	return l.conn.Send(&req)
 }
+
+// TurnOffAll turns off every light but those given, for a goodnight routine.
+func (l Light) TurnOffAll(except ...LightID) error {
+	target, err := allExcept(l.conn, "light", except)
+	if err != nil || target == "" {
+		return err
+	}
+	req := NewBaseServiceRequest(target)
+	req.Domain = "light"
+	req.Service = "turn_off"
+	return l.conn.Send(&req)
+}
+
+// RGB is color.RGB, under the name it had before package color.
+type RGB = color.RGB
+
+// ColorLoop cycles the lights through palette, one colour every stepInterval,
+// until the returned stop is called or the app stops. Each step fades over the
+// whole interval, so the lights drift between colours rather than snapping.
+// All the lights share one call per step and change together.
+//
+// The first colour is sent straight away. Stop waits for a step in flight to
+// finish, so nothing reaches Home Assistant once it returns, and it is safe to
+// call more than once. A failed step is logged and the loop carries on: one
+// dropped colour is not worth ending a party over.
+//
+// With no lights, no colours or no interval there is nothing to cycle, and
+// the error is ErrInvalidArgs.
+func (l Light) ColorLoop(entityIds []LightID, palette []color.RGB, stepInterval time.Duration) (stop func(), err error) {
+	if len(entityIds) == 0 || len(palette) == 0 || stepInterval <= 0 {
+		return nil, fmt.Errorf("%w: a colour loop needs lights, colours and a positive interval, not %d, %d and %s",
+			types.ErrInvalidArgs, len(entityIds), len(palette), stepInterval)
+	}
+
+	ids := make([]string, len(entityIds))
+	for i, id := range entityIds {
+		ids[i] = string(id)
+	}
+	target := strings.Join(ids, ", ")
+	palette = append([]color.RGB(nil), palette...)
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	stopping := doneOf(l.conn)
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(stepInterval)
+		defer ticker.Stop()
+
+		for i := 0; ; i = (i + 1) % len(palette) {
+			req := NewBaseServiceRequest(target)
+			req.Domain = "light"
+			req.Service = "turn_on"
+			req.ServiceData = palette[i].ServiceData()
+			req.ServiceData["transition"] = stepInterval.Seconds()
+			if err := l.conn.Send(&req); err != nil {
+				loggerOf(l.conn).Error("Colour loop step failed", "lights", target, "err", err)
+			}
+
+			select {
+			case <-done:
+				return
+			case <-stopping:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-finished
+	}, nil
+}
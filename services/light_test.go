@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Xevion/go-ha/color"
+	"github.com/Xevion/go-ha/types"
+)
+
+// stepRecorder keeps every request, since a colour loop sends many.
+type stepRecorder struct {
+	mu   sync.Mutex
+	reqs []*BaseServiceRequest
+}
+
+func (r *stepRecorder) Send(req types.Request) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reqs = append(r.reqs, req.(*BaseServiceRequest))
+	return nil
+}
+
+func (r *stepRecorder) sent() []*BaseServiceRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*BaseServiceRequest(nil), r.reqs...)
+}
+
+func TestColorLoopCyclesThePalette(t *testing.T) {
+	r := &stepRecorder{}
+	palette := []color.RGB{color.MustNamed("red"), {0, 255, 0}}
+
+	stop, err := BuildService[Light](r).ColorLoop([]LightID{"light.a", "light.b"}, palette, 10*time.Millisecond)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(r.sent()) >= 3 }, time.Second, time.Millisecond)
+	stop()
+
+	reqs := r.sent()
+	assert.Equal(t, "light.a, light.b", reqs[0].Target.EntityId, "every light changes in one call")
+	assert.Equal(t, "turn_on", reqs[0].Service)
+	assert.Equal(t, []int{255, 0, 0}, reqs[0].ServiceData["rgb_color"])
+	assert.Equal(t, []int{0, 255, 0}, reqs[1].ServiceData["rgb_color"])
+	assert.Equal(t, []int{255, 0, 0}, reqs[2].ServiceData["rgb_color"], "the palette wraps around")
+	assert.InDelta(t, 0.01, reqs[0].ServiceData["transition"], 1e-9)
+}
+
+// loggingRecorder is a sender that names a logger for its services, and
+// refuses every call.
+type loggingRecorder struct {
+	log *slog.Logger
+}
+
+func (r *loggingRecorder) Send(types.Request) error { return errors.New("refused") }
+
+func (r *loggingRecorder) Logger() *slog.Logger { return r.log }
+
+func TestColorLoopLogsToTheSendersLogger(t *testing.T) {
+	var out syncBuffer
+	r := &loggingRecorder{log: slog.New(slog.NewTextHandler(&out, nil))}
+
+	stop, err := BuildService[Light](r).ColorLoop([]LightID{"light.a"}, []color.RGB{{1, 2, 3}}, time.Hour)
+	require.NoError(t, err)
+	stop()
+
+	assert.Contains(t, out.String(), "Colour loop step failed")
+}
+
+// syncBuffer is a buffer a logger can write to while a test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// stoppingRecorder is a sender for an app that stops when done closes.
+type stoppingRecorder struct {
+	stepRecorder
+	done chan struct{}
+}
+
+func (r *stoppingRecorder) Done() <-chan struct{} { return r.done }
+
+func TestColorLoopStopsWithTheApp(t *testing.T) {
+	r := &stoppingRecorder{done: make(chan struct{})}
+
+	stop, err := BuildService[Light](r).ColorLoop([]LightID{"light.a"}, []color.RGB{{1, 2, 3}}, 5*time.Millisecond)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(r.sent()) >= 1 }, time.Second, time.Millisecond)
+	close(r.done)
+
+	stopped := make(chan struct{})
+	go func() { stop(); close(stopped) }()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the loop outlived the app")
+	}
+}
+
+func TestColorLoopStopIsFinal(t *testing.T) {
+	r := &stepRecorder{}
+
+	stop, err := BuildService[Light](r).ColorLoop([]LightID{"light.a"}, []color.RGB{{1, 2, 3}}, 5*time.Millisecond)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(r.sent()) >= 1 }, time.Second, time.Millisecond)
+	stop()
+	stop()
+
+	after := len(r.sent())
+	time.Sleep(30 * time.Millisecond)
+	assert.Len(t, r.sent(), after, "nothing is sent once stop returns")
+}
+
+func TestColorLoopWithNothingToCycle(t *testing.T) {
+	r := &stepRecorder{}
+	l := BuildService[Light](r)
+
+	for _, loop := range []func() (func(), error){
+		func() (func(), error) { return l.ColorLoop(nil, []color.RGB{{1, 2, 3}}, time.Second) },
+		func() (func(), error) { return l.ColorLoop([]LightID{"light.a"}, nil, time.Second) },
+		func() (func(), error) { return l.ColorLoop([]LightID{"light.a"}, []color.RGB{{1, 2, 3}}, 0) },
+	} {
+		_, err := loop()
+		assert.ErrorIs(t, err, types.ErrInvalidArgs)
+	}
+	assert.Empty(t, r.sent())
+}
+
+func TestSetColorMergesServiceData(t *testing.T) {
+	r := &stepRecorder{}
+	require.NoError(t, BuildService[Light](r).SetColor("light.desk", color.Kelvin(2700), map[string]any{"brightness_pct": 40}))
+
+	sent := r.sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "turn_on", sent[0].Service)
+	assert.Equal(t, map[string]any{"color_temp_kelvin": 2700, "brightness_pct": 40}, sent[0].ServiceData)
+}
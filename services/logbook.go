@@ -0,0 +1,23 @@
+package services
+
+type Logbook struct {
+	conn Sender
+}
+
+// Log writes an entry into Home Assistant's logbook, under name, such as the
+// automation's, reading message. An entity, if given, files the entry with
+// that entity's history, and its first only is used.
+func (l Logbook) Log(name, message string, entityId ...EntityID) error {
+	req := NewBaseServiceRequest("")
+	req.Domain = "logbook"
+	req.Service = "log"
+	req.ServiceData = map[string]any{
+		"name":    name,
+		"message": message,
+	}
+	if len(entityId) != 0 && entityId[0] != "" {
+		req.ServiceData["entity_id"] = entityId[0]
+	}
+
+	return l.conn.Send(&req)
+}
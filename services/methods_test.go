@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,9 +31,19 @@ func TestServiceMethodPayloads(t *testing.T) {
 	}{
 		{
 			"climate set fan mode",
-			func() error { return BuildService[Climate](r).SetFanMode("climate.a", "auto") },
+			func() error { return NewClimate(r, nil).SetFanMode("climate.a", "auto") },
 			map[string]any{"fan_mode": "auto"},
 		},
+		{
+			"climate set hvac mode",
+			func() error { return NewClimate(r, nil).SetHvacMode("climate.a", "off") },
+			map[string]any{"hvac_mode": "off"},
+		},
+		{
+			"climate set preset mode",
+			func() error { return NewClimate(r, nil).SetPresetMode("climate.a", "eco") },
+			map[string]any{"preset_mode": "eco"},
+		},
 		{
 			"timer start",
 			func() error { return BuildService[Timer](r).Start("timer.a", "00:01:00") },
@@ -57,11 +69,48 @@ func TestServiceMethodPayloads(t *testing.T) {
 			func() error { return BuildService[Number](r).SetValue("number.a", 7) },
 			map[string]any{"value": float32(7)},
 		},
+		{
+			"mqtt publish",
+			func() error { return BuildService[MQTT](r).Publish("zigbee2mqtt/lamp/set", `{"state":"ON"}`, true) },
+			map[string]any{"topic": "zigbee2mqtt/lamp/set", "payload": `{"state":"ON"}`, "retain": true},
+		},
+		{
+			"tts speak",
+			func() error { return BuildService[TTS](r).Speak("tts.piper", "media_player.kitchen", "dinner", "de") },
+			map[string]any{"media_player_entity_id": MediaPlayerID("media_player.kitchen"), "message": "dinner", "language": "de"},
+		},
 		{
 			"zwavejs bulk set",
 			func() error { return BuildService[ZWaveJS](r).BulkSetPartialConfigParam("sensor.a", 3, 12) },
 			map[string]any{"parameter": 3, "value": any(12)},
 		},
+		{
+			"logbook log",
+			func() error { return BuildService[Logbook](r).Log("Porch", "turned on by motion", "light.porch") },
+			map[string]any{"name": "Porch", "message": "turned on by motion", "entity_id": EntityID("light.porch")},
+		},
+		{
+			"logbook log without an entity",
+			func() error { return BuildService[Logbook](r).Log("Heating", "paused") },
+			map[string]any{"name": "Heating", "message": "paused"},
+		},
+		{
+			"persistent notification create",
+			func() error {
+				return BuildService[PersistentNotification](r).Create("boiler fault", map[string]any{"notification_id": "boiler"})
+			},
+			map[string]any{"message": "boiler fault", "notification_id": "boiler"},
+		},
+		{
+			"persistent notification dismiss",
+			func() error { return BuildService[PersistentNotification](r).Dismiss("boiler") },
+			map[string]any{"notification_id": "boiler"},
+		},
+		{
+			"notify persistent",
+			func() error { return BuildService[Notify](r).Persistent("Automation failed", "porch light: timeout") },
+			map[string]any{"title": "Automation failed", "message": "porch light: timeout"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,7 +137,7 @@ func TestInputDatetimeSetSendsUnixTimestamp(t *testing.T) {
 func TestClimateSetTemperaturePayload(t *testing.T) {
 	r := &recorder{}
 	req := types.SetTemperatureRequest{Temperature: types.Ptr(float32(21.5)), HvacMode: "heat"}
-	require.NoError(t, BuildService[Climate](r).SetTemperature("climate.a", req))
+	require.NoError(t, NewClimate(r, nil).SetTemperature("climate.a", req))
 
 	require.NotNil(t, r.last)
 	assert.Equal(t, float32(21.5), r.last.ServiceData["temperature"])
@@ -142,6 +191,40 @@ func TestNotifyOmitsAbsentData(t *testing.T) {
 	assert.NotContains(t, r.last.ServiceData, "data")
 }
 
+// Persistent goes to persistent_notification rather than a notify platform.
+func TestNotifyPersistentCreatesANotification(t *testing.T) {
+	r := &recorder{}
+	require.NoError(t, BuildService[Notify](r).Persistent("t", "m"))
+
+	require.NotNil(t, r.last)
+	assert.Equal(t, "persistent_notification", r.last.Domain)
+	assert.Equal(t, "create", r.last.Service)
+	assert.Nil(t, r.last.Target)
+}
+
+// An all-day event comes as dates, a timed one as instants.
+func TestParseEventTime(t *testing.T) {
+	day, allDay, err := parseEventTime("2026-12-25")
+	require.NoError(t, err)
+	assert.True(t, allDay)
+	assert.Equal(t, time.Date(2026, 12, 25, 0, 0, 0, 0, time.Local), day)
+
+	at, allDay, err := parseEventTime("2026-12-25T09:30:00+01:00")
+	require.NoError(t, err)
+	assert.False(t, allDay)
+	assert.True(t, at.Equal(time.Date(2026, 12, 25, 8, 30, 0, 0, time.UTC)))
+
+	_, _, err = parseEventTime("soon")
+	assert.Error(t, err)
+}
+
+// GetEvents has to wait for the answer, so a calendar built without a way to
+// is refused rather than sent.
+func TestCalendarGetEventsNeedsAWaitingSender(t *testing.T) {
+	_, err := NewCalendar(&recorder{}, nil).GetEvents(context.Background(), "calendar.a", time.Now(), time.Now())
+	assert.Error(t, err)
+}
+
 // Fire produces a FireEventRequest, a different message shape from a service
 // call, carrying the event type and optional data.
 func TestEventFireProducesAFireEventRequest(t *testing.T) {
@@ -188,3 +271,234 @@ func TestNoTargetServiceMethods(t *testing.T) {
 		})
 	}
 }
+
+// fixedLimits stands in for the app's view of Home Assistant.
+type fixedLimits struct {
+	unit     types.TemperatureUnit
+	min, max float32
+}
+
+func (l fixedLimits) TemperatureUnit() (types.TemperatureUnit, error) { return l.unit, nil }
+
+func (l fixedLimits) TemperatureRange(string) (float32, float32, bool) { return l.min, l.max, true }
+
+func TestClimateSetTemperatureChecksLimits(t *testing.T) {
+	r := &recorder{}
+	climate := NewClimate(r, fixedLimits{unit: types.Celsius, min: 7, max: 35})
+
+	// Given in Fahrenheit, sent in the Celsius Home Assistant works in.
+	require.NoError(t, climate.SetTemperature("climate.a", types.SetTemperatureRequest{
+		TargetTempLow:  types.Ptr(float32(66)),
+		TargetTempHigh: types.Ptr(float32(75)),
+		HvacMode:       "heat_cool",
+		Unit:           types.Fahrenheit,
+	}))
+	assert.Equal(t, float32(18.9), r.last.ServiceData["target_temp_low"])
+	assert.Equal(t, float32(23.9), r.last.ServiceData["target_temp_high"])
+	assert.Equal(t, "heat_cool", r.last.ServiceData["hvac_mode"])
+
+	r.last = nil
+	err := climate.SetTemperature("climate.a", types.SetTemperatureRequest{Temperature: types.Ptr(float32(40))})
+	assert.ErrorIs(t, err, types.ErrInvalidTemperature)
+	assert.Nil(t, r.last, "refused before sending")
+}
+
+func TestClimateWithoutLimitsRefusesAUnit(t *testing.T) {
+	err := NewClimate(&recorder{}, nil).SetTemperature("climate.a", types.SetTemperatureRequest{
+		Temperature: types.Ptr(float32(70)),
+		Unit:        types.Fahrenheit,
+	})
+	assert.ErrorIs(t, err, types.ErrInvalidTemperature)
+}
+
+// Room and zone cleaning has no common Home Assistant service, so each
+// integration's encoding is pinned to the payload its robots accept.
+func TestVacuumCleanRoomsAndZones(t *testing.T) {
+	r := &recorder{}
+	vacuum := BuildService[Vacuum](r)
+	kitchen := VacuumZone{X1: 25500, Y1: 25500, X2: 27500, Y2: 27000}
+
+	tests := []struct {
+		name    string
+		call    func() error
+		service string
+		data    map[string]any
+	}{
+		{
+			"roborock rooms",
+			func() error { return vacuum.CleanRooms("vacuum.a", Roborock, 16, 17) },
+			"vacuum.send_command",
+			map[string]any{"command": "app_segment_clean", "params": []int{16, 17}},
+		},
+		{
+			"roborock zone",
+			func() error { return vacuum.CleanZone("vacuum.a", Roborock, kitchen, 2) },
+			"vacuum.send_command",
+			map[string]any{"command": "app_zoned_clean", "params": [][]int{{25500, 25500, 27500, 27000, 2}}},
+		},
+		{
+			"dreame rooms",
+			func() error { return vacuum.CleanRooms("vacuum.a", Dreame, 3) },
+			"dreame_vacuum.vacuum_clean_segment",
+			map[string]any{"segments": []int{3}},
+		},
+		{
+			"dreame zone",
+			func() error { return vacuum.CleanZone("vacuum.a", Dreame, kitchen, 0) },
+			"dreame_vacuum.vacuum_clean_zone",
+			map[string]any{"zone": []int{25500, 25500, 27500, 27000}, "repeats": 1},
+		},
+		{
+			"valetudo rooms",
+			func() error { return vacuum.CleanRooms("vacuum.a", Valetudo, 16, 17) },
+			"vacuum.send_command",
+			map[string]any{
+				"command": "segment_cleanup",
+				"params":  map[string]any{"segment_ids": []string{"16", "17"}, "iterations": 1, "customOrder": true},
+			},
+		},
+		{
+			"valetudo zone",
+			func() error { return vacuum.CleanZone("vacuum.a", Valetudo, VacuumZone{X1: 1, Y1: 2, X2: 3, Y2: 4}, 1) },
+			"vacuum.send_command",
+			map[string]any{
+				"command": "zoned_cleanup",
+				"params": map[string]any{
+					"zones": []any{map[string]any{"points": map[string]any{
+						"pA": map[string]int{"x": 1, "y": 2},
+						"pB": map[string]int{"x": 3, "y": 2},
+						"pC": map[string]int{"x": 3, "y": 4},
+						"pD": map[string]int{"x": 1, "y": 4},
+					}}},
+					"iterations": 1,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.last = nil
+			require.NoError(t, tt.call())
+			require.NotNil(t, r.last)
+			assert.Equal(t, tt.service, r.last.Domain+"."+r.last.Service)
+			assert.Equal(t, tt.data, r.last.ServiceData)
+		})
+	}
+
+	assert.Error(t, vacuum.CleanRooms("vacuum.a", Roborock), "no rooms is nothing to clean")
+	assert.Error(t, vacuum.CleanRooms("vacuum.a", VacuumIntegration(9), 1))
+}
+
+// A Telegram keyboard goes as rows of [text, data] pairs, so button text may
+// hold the colons and commas the string form splits on.
+func TestNotifyTelegramKeyboard(t *testing.T) {
+	r := &recorder{}
+	notify := BuildService[Notify](r)
+
+	require.NoError(t, notify.Telegram(TelegramMessage{
+		Message:   "Garage open",
+		Target:    []int64{12345},
+		ParseMode: "html",
+		Keyboard: [][]TelegramButton{
+			{{Text: "Close it", Data: "/garage_close"}, {Text: "Leave: 1h", Data: "/snooze"}},
+		},
+	}))
+
+	require.NotNil(t, r.last)
+	assert.Equal(t, "telegram_bot", r.last.Domain)
+	assert.Equal(t, "send_message", r.last.Service)
+	assert.Equal(t, []int64{12345}, r.last.ServiceData["target"])
+	assert.Equal(t, [][][2]string{{{"Close it", "/garage_close"}, {"Leave: 1h", "/snooze"}}},
+		r.last.ServiceData["inline_keyboard"])
+	assert.NotContains(t, r.last.ServiceData, "title")
+
+	r.last = nil
+	for name, msg := range map[string]TelegramMessage{
+		"no text":        {Keyboard: [][]TelegramButton{{{Text: "a", Data: "/a"}}}},
+		"bad parse mode": {Message: "hi", ParseMode: "rtf"},
+		"empty row":      {Message: "hi", Keyboard: [][]TelegramButton{{}}},
+		"no data":        {Message: "hi", Keyboard: [][]TelegramButton{{{Text: "a"}}}},
+		"long data":      {Message: "hi", Keyboard: [][]TelegramButton{{{Text: "a", Data: "/" + strings.Repeat("x", 64)}}}},
+	} {
+		assert.Error(t, notify.Telegram(msg), name)
+	}
+	assert.Nil(t, r.last, "a refused message is not sent")
+}
+
+func TestNotifySlackBlocks(t *testing.T) {
+	r := &recorder{}
+	notify := BuildService[Notify](r)
+
+	blocks := []map[string]any{
+		{"type": "header", "text": map[string]any{"type": "plain_text", "text": "Leak"}},
+		{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": "*Kitchen* sensor is wet"}},
+		{"type": "divider"},
+	}
+	require.NoError(t, notify.Slack(SlackMessage{
+		ServiceName: "slack_home",
+		Message:     "Leak in the kitchen",
+		Target:      "#alerts",
+		Blocks:      blocks,
+	}))
+
+	require.NotNil(t, r.last)
+	assert.Equal(t, "notify", r.last.Domain)
+	assert.Equal(t, "slack_home", r.last.Service)
+	assert.Equal(t, []string{"#alerts"}, r.last.ServiceData["target"])
+	assert.Equal(t, map[string]any{"blocks": blocks}, r.last.ServiceData["data"])
+
+	r.last = nil
+	for name, msg := range map[string]SlackMessage{
+		"no service":    {Message: "hi"},
+		"no text":       {ServiceName: "slack_home"},
+		"unknown block": {ServiceName: "slack_home", Message: "hi", Blocks: []map[string]any{{"type": "banner"}}},
+		"empty section": {ServiceName: "slack_home", Message: "hi", Blocks: []map[string]any{{"type": "section"}}},
+		"mrkdwn header": {ServiceName: "slack_home", Message: "hi", Blocks: []map[string]any{
+			{"type": "header", "text": map[string]any{"type": "mrkdwn", "text": "Leak"}},
+		}},
+		"bare actions": {ServiceName: "slack_home", Message: "hi", Blocks: []map[string]any{{"type": "actions"}}},
+		"image sans alt": {ServiceName: "slack_home", Message: "hi", Blocks: []map[string]any{
+			{"type": "image", "image_url": "https://example.com/a.png"},
+		}},
+	} {
+		assert.Error(t, notify.Slack(msg), name)
+	}
+	assert.Nil(t, r.last, "a refused message is not sent")
+}
+
+func TestNotifyDiscordEmbed(t *testing.T) {
+	r := &recorder{}
+	notify := BuildService[Notify](r)
+
+	require.NoError(t, notify.Discord(DiscordMessage{
+		ServiceName: "discord",
+		Message:     "Doorbell",
+		Channels:    []string{"1234567890"},
+		Embed: &DiscordEmbed{
+			Title:  "Front door",
+			Color:  0xFF8800,
+			Fields: []DiscordField{{Name: "Battery", Value: "81%", Inline: true}},
+		},
+	}))
+
+	require.NotNil(t, r.last)
+	assert.Equal(t, "discord", r.last.Service)
+	assert.Equal(t, []string{"1234567890"}, r.last.ServiceData["target"])
+	assert.Equal(t, map[string]any{"embed": map[string]any{
+		"title":  "Front door",
+		"color":  0xFF8800,
+		"fields": []map[string]any{{"name": "Battery", "value": "81%", "inline": true}},
+	}}, r.last.ServiceData["data"])
+
+	r.last = nil
+	for name, msg := range map[string]DiscordMessage{
+		"no channel":  {ServiceName: "discord", Message: "hi"},
+		"nothing":     {ServiceName: "discord", Channels: []string{"1"}},
+		"bad colour":  {ServiceName: "discord", Channels: []string{"1"}, Embed: &DiscordEmbed{Color: 0x1000000}},
+		"empty field": {ServiceName: "discord", Channels: []string{"1"}, Embed: &DiscordEmbed{Fields: []DiscordField{{Name: "a"}}}},
+	} {
+		assert.Error(t, notify.Discord(msg), name)
+	}
+	assert.Nil(t, r.last, "a refused message is not sent")
+}
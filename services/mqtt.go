@@ -0,0 +1,22 @@
+package services
+
+// MQTT publishes through Home Assistant's MQTT integration, on the broker it
+// is connected to.
+type MQTT struct {
+	conn Sender
+}
+
+// Publish sends payload to topic. A retained message is kept by the broker and
+// handed to whoever subscribes to the topic later.
+func (m MQTT) Publish(topic, payload string, retain bool) error {
+	req := NewBaseServiceRequest("")
+	req.Domain = "mqtt"
+	req.Service = "publish"
+	req.ServiceData = map[string]any{
+		"topic":   topic,
+		"payload": payload,
+		"retain":  retain,
+	}
+
+	return m.conn.Send(&req)
+}
@@ -24,3 +24,10 @@ func (ha *Notify) Notify(reqData types.NotifyRequest) error {
 	req.ServiceData = serviceData
 	return ha.conn.Send(&req)
 }
+
+// Persistent shows a notification in Home Assistant's own sidebar, where an
+// automation's errors are seen by whoever next opens it, rather than on a
+// phone. It stays until dismissed.
+func (ha *Notify) Persistent(title, message string) error {
+	return PersistentNotification{conn: ha.conn}.Create(message, map[string]any{"title": title})
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// TelegramButton is one button of an inline keyboard. Pressing it fires a
+// telegram_callback event carrying Data, which by convention starts with a
+// slash, as in "/garage_close".
+type TelegramButton struct {
+	Text string
+	Data string
+}
+
+// TelegramMessage is a message sent through the telegram_bot integration.
+type TelegramMessage struct {
+	Message string
+	Title   string
+
+	// Target lists the chat ids to send to. Empty sends to the bot's default
+	// chat.
+	Target []int64
+
+	// ParseMode is one of "markdown", "markdownv2", "html" or "plain_text".
+	// Empty leaves the integration's default.
+	ParseMode string
+
+	DisableNotification bool
+
+	// Keyboard lays out inline buttons, one slice per row.
+	Keyboard [][]TelegramButton
+}
+
+// telegramCallbackLimit is the most bytes Telegram carries as a button's
+// callback data. Longer data is refused by Telegram, not by Home Assistant,
+// so the call would otherwise appear to succeed.
+const telegramCallbackLimit = 64
+
+var telegramParseModes = []string{"markdown", "markdownv2", "html", "plain_text"}
+
+// Telegram sends a message with telegram_bot.send_message, which unlike the
+// telegram notify platform can carry an inline keyboard.
+func (ha *Notify) Telegram(msg TelegramMessage) error {
+	if msg.Message == "" {
+		return errors.New("a Telegram message needs text")
+	}
+	if msg.ParseMode != "" && !slices.Contains(telegramParseModes, msg.ParseMode) {
+		return fmt.Errorf("unknown Telegram parse mode %q", msg.ParseMode)
+	}
+
+	req := NewBaseServiceRequest("")
+	req.Domain = "telegram_bot"
+	req.Service = "send_message"
+
+	serviceData := map[string]any{"message": msg.Message}
+	if msg.Title != "" {
+		serviceData["title"] = msg.Title
+	}
+	if len(msg.Target) > 0 {
+		serviceData["target"] = msg.Target
+	}
+	if msg.ParseMode != "" {
+		serviceData["parse_mode"] = msg.ParseMode
+	}
+	if msg.DisableNotification {
+		serviceData["disable_notification"] = true
+	}
+
+	if len(msg.Keyboard) > 0 {
+		// Rows of [text, data] pairs, the form that lets text hold a colon or
+		// comma the string form would split on.
+		rows := make([][][2]string, 0, len(msg.Keyboard))
+		for i, row := range msg.Keyboard {
+			if len(row) == 0 {
+				return fmt.Errorf("Telegram keyboard row %d is empty", i)
+			}
+			buttons := make([][2]string, 0, len(row))
+			for _, b := range row {
+				if b.Text == "" || b.Data == "" {
+					return fmt.Errorf("Telegram button in row %d needs text and data", i)
+				}
+				if len(b.Data) > telegramCallbackLimit {
+					return fmt.Errorf("Telegram button %q carries %d bytes of data, over the limit of %d", b.Text, len(b.Data), telegramCallbackLimit)
+				}
+				buttons = append(buttons, [2]string{b.Text, b.Data})
+			}
+			rows = append(rows, buttons)
+		}
+		serviceData["inline_keyboard"] = rows
+	}
+
+	req.ServiceData = serviceData
+	return ha.conn.Send(&req)
+}
+
+// SlackMessage is a message sent through a Slack notify service.
+type SlackMessage struct {
+	// ServiceName is the notify service the Slack integration set up, such as
+	// slack_home.
+	ServiceName string
+
+	// Message is the text shown in notifications, and in the channel when
+	// there are no blocks.
+	Message string
+	Title   string
+
+	// Target is the channel to post to, such as "#alerts". Empty posts to the
+	// integration's default channel.
+	Target string
+
+	Username string
+	IconURL  string
+
+	// Blocks is a Block Kit payload, each block a JSON object as Slack's
+	// documentation gives it.
+	Blocks []map[string]any
+}
+
+// slackBlockLimit is the most blocks Slack accepts in one message.
+const slackBlockLimit = 50
+
+var slackBlockTypes = []string{
+	"actions", "context", "divider", "file", "header", "image", "input", "rich_text", "section", "video",
+}
+
+// Slack posts a message through a Slack notify service. Blocks are checked for
+// the shape Slack requires, since Slack refuses a malformed payload after Home
+// Assistant has reported the call a success.
+func (ha *Notify) Slack(msg SlackMessage) error {
+	if msg.ServiceName == "" {
+		return errors.New("a Slack message needs the notify service to send through")
+	}
+	if msg.Message == "" {
+		return errors.New("a Slack message needs text")
+	}
+	if err := validateSlackBlocks(msg.Blocks); err != nil {
+		return err
+	}
+
+	req := NewBaseServiceRequest("")
+	req.Domain = "notify"
+	req.Service = msg.ServiceName
+
+	serviceData := map[string]any{"message": msg.Message}
+	if msg.Title != "" {
+		serviceData["title"] = msg.Title
+	}
+	if msg.Target != "" {
+		serviceData["target"] = []string{msg.Target}
+	}
+
+	data := map[string]any{}
+	if msg.Username != "" {
+		data["username"] = msg.Username
+	}
+	if msg.IconURL != "" {
+		data["icon"] = msg.IconURL
+	}
+	if len(msg.Blocks) > 0 {
+		data["blocks"] = msg.Blocks
+	}
+	if len(data) > 0 {
+		serviceData["data"] = data
+	}
+
+	req.ServiceData = serviceData
+	return ha.conn.Send(&req)
+}
+
+func validateSlackBlocks(blocks []map[string]any) error {
+	if len(blocks) > slackBlockLimit {
+		return fmt.Errorf("a Slack message takes at most %d blocks, not %d", slackBlockLimit, len(blocks))
+	}
+	for i, block := range blocks {
+		kind, _ := block["type"].(string)
+		if !slices.Contains(slackBlockTypes, kind) {
+			return fmt.Errorf("Slack block %d has unknown type %q", i, kind)
+		}
+		switch kind {
+		case "section":
+			if block["text"] == nil && block["fields"] == nil {
+				return fmt.Errorf("Slack section block %d needs text or fields", i)
+			}
+		case "header":
+			text, _ := block["text"].(map[string]any)
+			if t, _ := text["type"].(string); t != "plain_text" {
+				return fmt.Errorf("Slack header block %d needs plain_text text", i)
+			}
+			if s, _ := text["text"].(string); s == "" || utf8.RuneCountInString(s) > 150 {
+				return fmt.Errorf("Slack header block %d needs text of 1 to 150 characters", i)
+			}
+		case "actions", "context":
+			if !nonEmptyList(block["elements"]) {
+				return fmt.Errorf("Slack %s block %d needs elements", kind, i)
+			}
+		case "image":
+			if url, _ := block["image_url"].(string); url == "" {
+				return fmt.Errorf("Slack image block %d needs an image_url", i)
+			}
+			if alt, _ := block["alt_text"].(string); alt == "" {
+				return fmt.Errorf("Slack image block %d needs alt_text", i)
+			}
+		}
+	}
+	return nil
+}
+
+// nonEmptyList reports a non-empty list, in either form a caller builds one:
+// decoded from JSON or written out as maps.
+func nonEmptyList(v any) bool {
+	switch list := v.(type) {
+	case []any:
+		return len(list) > 0
+	case []map[string]any:
+		return len(list) > 0
+	}
+	return false
+}
+
+// DiscordField is one name and value pair of an embed.
+type DiscordField struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+// DiscordEmbed is the rich card Discord shows beneath a message.
+type DiscordEmbed struct {
+	Title       string
+	Description string
+	URL         string
+	// Color is the bar down the card's edge, as 0xRRGGBB.
+	Color  int
+	Fields []DiscordField
+}
+
+// DiscordMessage is a message sent through a Discord notify service.
+type DiscordMessage struct {
+	// ServiceName is the notify service the Discord integration set up, such
+	// as discord.
+	ServiceName string
+
+	Message string
+
+	// Channels lists the channel or user ids to send to. Discord has no
+	// default, so at least one is needed.
+	Channels []string
+
+	Embed *DiscordEmbed
+}
+
+// Discord sends a message through a Discord notify service, checking the
+// embed against the limits Discord enforces.
+func (ha *Notify) Discord(msg DiscordMessage) error {
+	if msg.ServiceName == "" {
+		return errors.New("a Discord message needs the notify service to send through")
+	}
+	if msg.Message == "" && msg.Embed == nil {
+		return errors.New("a Discord message needs text or an embed")
+	}
+	if len(msg.Channels) == 0 {
+		return errors.New("a Discord message needs a channel to send to")
+	}
+
+	req := NewBaseServiceRequest("")
+	req.Domain = "notify"
+	req.Service = msg.ServiceName
+	serviceData := map[string]any{"message": msg.Message, "target": msg.Channels}
+
+	if e := msg.Embed; e != nil {
+		if err := e.validate(); err != nil {
+			return err
+		}
+		embed := map[string]any{}
+		if e.Title != "" {
+			embed["title"] = e.Title
+		}
+		if e.Description != "" {
+			embed["description"] = e.Description
+		}
+		if e.URL != "" {
+			embed["url"] = e.URL
+		}
+		if e.Color != 0 {
+			embed["color"] = e.Color
+		}
+		if len(e.Fields) > 0 {
+			fields := make([]map[string]any, len(e.Fields))
+			for i, f := range e.Fields {
+				fields[i] = map[string]any{"name": f.Name, "value": f.Value, "inline": f.Inline}
+			}
+			embed["fields"] = fields
+		}
+		serviceData["data"] = map[string]any{"embed": embed}
+	}
+
+	req.ServiceData = serviceData
+	return ha.conn.Send(&req)
+}
+
+func (e *DiscordEmbed) validate() error {
+	switch {
+	case utf8.RuneCountInString(e.Title) > 256:
+		return errors.New("a Discord embed title is at most 256 characters")
+	case utf8.RuneCountInString(e.Description) > 4096:
+		return errors.New("a Discord embed description is at most 4096 characters")
+	case len(e.Fields) > 25:
+		return fmt.Errorf("a Discord embed takes at most 25 fields, not %d", len(e.Fields))
+	case e.Color < 0 || e.Color > 0xFFFFFF:
+		return fmt.Errorf("Discord embed colour %#x is not 0xRRGGBB", e.Color)
+	}
+	for i, f := range e.Fields {
+		if f.Name == "" || f.Value == "" {
+			return fmt.Errorf("Discord embed field %d needs a name and a value", i)
+		}
+		if utf8.RuneCountInString(f.Name) > 256 || utf8.RuneCountInString(f.Value) > 1024 {
+			return fmt.Errorf("Discord embed field %q is over Discord's length limits", f.Name)
+		}
+	}
+	return nil
+}
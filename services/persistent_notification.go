@@ -0,0 +1,43 @@
+package services
+
+import "maps"
+
+type PersistentNotification struct {
+	conn Sender
+}
+
+// Create shows a notification in Home Assistant's sidebar until someone
+// dismisses it. Takes an optional map that is translated into service_data,
+// for a title or a notification_id that a later Create replaces and Dismiss
+// removes.
+func (p PersistentNotification) Create(message string, serviceData ...map[string]any) error {
+	req := NewBaseServiceRequest("")
+	req.Domain = "persistent_notification"
+	req.Service = "create"
+	req.ServiceData = map[string]any{}
+	if len(serviceData) != 0 {
+		maps.Copy(req.ServiceData, serviceData[0])
+	}
+	req.ServiceData["message"] = message
+
+	return p.conn.Send(&req)
+}
+
+// Dismiss removes the notification created with the given notification_id.
+func (p PersistentNotification) Dismiss(notificationId string) error {
+	req := NewBaseServiceRequest("")
+	req.Domain = "persistent_notification"
+	req.Service = "dismiss"
+	req.ServiceData = map[string]any{"notification_id": notificationId}
+
+	return p.conn.Send(&req)
+}
+
+// DismissAll removes every persistent notification.
+func (p PersistentNotification) DismissAll() error {
+	req := NewBaseServiceRequest("")
+	req.Domain = "persistent_notification"
+	req.Service = "dismiss_all"
+
+	return p.conn.Send(&req)
+}
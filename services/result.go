@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Xevion/go-ha/types"
+)
+
+// ResultSender is a Sender that can also wait for Home Assistant's answer to a
+// call, returning the result it carries or the error it reports.
+type ResultSender interface {
+	Sender
+	SendForResult(ctx context.Context, req types.Request) (json.RawMessage, error)
+}
+
+// ServiceResult is Home Assistant's answer to a service call.
+type ServiceResult struct {
+	// Context identifies the call in Home Assistant, where it appears as the
+	// cause of the state changes it made.
+	Context ServiceContext `json:"context"`
+
+	// Response is what the service returned. Only services that respond fill
+	// it, and only when asked with CallForResponse.
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// ServiceContext is the context Home Assistant assigned a call.
+type ServiceContext struct {
+	ID       string `json:"id"`
+	ParentID string `json:"parent_id"`
+	UserID   string `json:"user_id"`
+}
+
+// CallWithResult invokes any service, as Call does, and waits for Home
+// Assistant to confirm it. A call Home Assistant refuses, such as one against
+// an entity that does not exist, comes back as an error.
+func CallWithResult(ctx context.Context, sender ResultSender, domain, service string, entityID EntityID, data map[string]any) (ServiceResult, error) {
+	req := NewBaseServiceRequest(string(entityID))
+	req.Domain = domain
+	req.Service = service
+	req.ServiceData = data
+	return sendForResult(ctx, sender, &req)
+}
+
+// CallForResponse invokes a service that returns data, such as
+// weather.get_forecasts or calendar.get_events, and waits for it. Home
+// Assistant refuses the call if the service does not respond.
+func CallForResponse(ctx context.Context, sender ResultSender, domain, service string, entityID EntityID, data map[string]any) (ServiceResult, error) {
+	req := NewBaseServiceRequest(string(entityID))
+	req.Domain = domain
+	req.Service = service
+	req.ServiceData = data
+	req.ReturnResponse = true
+	return sendForResult(ctx, sender, &req)
+}
+
+func sendForResult(ctx context.Context, sender ResultSender, req *BaseServiceRequest) (ServiceResult, error) {
+	raw, err := sender.SendForResult(ctx, req)
+	if err != nil {
+		return ServiceResult{}, fmt.Errorf("calling %s.%s: %w", req.Domain, req.Service, err)
+	}
+
+	var result ServiceResult
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return ServiceResult{}, fmt.Errorf("decoding %s.%s result: %w", req.Domain, req.Service, err)
+		}
+	}
+	return result, nil
+}
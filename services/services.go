@@ -1,6 +1,10 @@
 package services
 
-import "github.com/Xevion/go-ha/types"
+import (
+	"log/slog"
+
+	"github.com/Xevion/go-ha/types"
+)
 
 // Sender delivers a service call to Home Assistant. The client satisfies it;
 // it is an interface here so that building a service does not require naming
@@ -9,15 +13,49 @@ type Sender interface {
 	Send(req types.Request) error
 }
 
+// LoggingSender is a Sender that also names the logger the services built on
+// it write to. Services built on a plain Sender log to slog's default logger.
+type LoggingSender interface {
+	Sender
+	Logger() *slog.Logger
+}
+
+// StoppingSender is a Sender that also says when the app it sends for stops,
+// so what a service leaves running, such as a colour loop, stops with it.
+type StoppingSender interface {
+	Sender
+	Done() <-chan struct{}
+}
+
+// doneOf is closed when the app sending through conn stops. It is nil, and
+// never closes, for a plain Sender.
+func doneOf(conn Sender) <-chan struct{} {
+	if s, ok := conn.(StoppingSender); ok {
+		return s.Done()
+	}
+	return nil
+}
+
+// loggerOf is the logger for services sending through conn.
+func loggerOf(conn Sender) *slog.Logger {
+	if l, ok := conn.(LoggingSender); ok {
+		return l.Logger()
+	}
+	return slog.Default()
+}
+
+// BuildService builds a domain's services around conn. Climate, which checks
+// its requests before sending, is built with NewClimate instead.
 func BuildService[
 	T AdaptiveLighting |
 		AlarmControlPanel |
-		Climate |
 		Cover |
 		Light |
 		HomeAssistant |
 		Lock |
+		Logbook |
 		MediaPlayer |
+		MQTT |
 		Switch |
 		InputBoolean |
 		InputButton |
@@ -27,6 +65,7 @@ func BuildService[
 		Event |
 		Notify |
 		Number |
+		PersistentNotification |
 		Scene |
 		Script |
 		TTS |
@@ -37,9 +76,13 @@ func BuildService[
 	return &T{conn: conn}
 }
 
-// ServiceTarget names what a service call acts on.
+// ServiceTarget names what a service call acts on: entities, whole areas,
+// devices, or any mix of them, as Home Assistant's target schema allows.
 type ServiceTarget struct {
-	EntityId string `json:"entity_id,omitempty"`
+	// EntityId is one entity, or several separated by commas.
+	EntityId string   `json:"entity_id,omitempty"`
+	AreaId   []string `json:"area_id,omitempty"`
+	DeviceId []string `json:"device_id,omitempty"`
 }
 
 type BaseServiceRequest struct {
@@ -52,6 +95,9 @@ type BaseServiceRequest struct {
 	// struct value, so this used to send "target":{} on every call that names
 	// no entity.
 	Target *ServiceTarget `json:"target,omitempty"`
+
+	// ReturnResponse asks a service that returns data to send it back.
+	ReturnResponse bool `json:"return_response,omitempty"`
 }
 
 // SetID stamps the request with a connection-scoped id. The client calls this
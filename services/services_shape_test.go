@@ -126,9 +126,15 @@ func TestServiceMethodsAddressTheRightService(t *testing.T) {
 		{"timer cancel", func() error { return BuildService[Timer](r).Cancel("timer.a") }, "timer", "cancel", "timer.a"},
 		{"timer finish", func() error { return BuildService[Timer](r).Finish("timer.a") }, "timer", "finish", "timer.a"},
 
-		{"climate set fan mode", func() error { return BuildService[Climate](r).SetFanMode("climate.a", "auto") }, "climate", "set_fan_mode", "climate.a"},
+		{"calendar create event", func() error {
+			return NewCalendar(r, nil).CreateEvent("calendar.a", "x", time.Unix(0, 0), time.Unix(60, 0))
+		}, "calendar", "create_event", "calendar.a"},
+
+		{"climate set fan mode", func() error { return NewClimate(r, nil).SetFanMode("climate.a", "auto") }, "climate", "set_fan_mode", "climate.a"},
+		{"climate set hvac mode", func() error { return NewClimate(r, nil).SetHvacMode("climate.a", "off") }, "climate", "set_hvac_mode", "climate.a"},
+		{"climate set preset mode", func() error { return NewClimate(r, nil).SetPresetMode("climate.a", "eco") }, "climate", "set_preset_mode", "climate.a"},
 		{"climate set temperature", func() error {
-			return BuildService[Climate](r).SetTemperature("climate.a", types.SetTemperatureRequest{Temperature: types.Ptr(float32(21))})
+			return NewClimate(r, nil).SetTemperature("climate.a", types.SetTemperatureRequest{Temperature: types.Ptr(float32(21))})
 		}, "climate", "set_temperature", "climate.a"},
 
 		{"tts cloud say", func() error { return BuildService[TTS](r).CloudSay("media_player.a") }, "tts", "cloud_say", "media_player.a"},
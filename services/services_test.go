@@ -99,3 +99,24 @@ func TestFireEventRequestStampsItsId(t *testing.T) {
 	assert.Equal(t, "fire_event", got["type"])
 	assert.Equal(t, "custom_event", got["event_type"])
 }
+
+func TestTargetApplyKeepsTheNamedEntity(t *testing.T) {
+	req := NewBaseServiceRequest("light.hall")
+	InArea("living_room").Apply(&req)
+	Entities("light.porch").Apply(&req)
+	OnDevice("abc123").Apply(&req)
+
+	raw, err := json.Marshal(req.Target)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"entity_id":"light.hall, light.porch","area_id":["living_room"],"device_id":["abc123"]}`, string(raw))
+	assert.Equal(t, "light.hall, light.porch, area living_room, device abc123", req.Target.String())
+}
+
+func TestTargetApplyWithoutAnEntity(t *testing.T) {
+	req := NewBaseServiceRequest("")
+	InArea("kitchen", "hall").Apply(&req)
+
+	raw, err := json.Marshal(req.Target)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"area_id":["kitchen","hall"]}`, string(raw), "no entity_id is sent when none is named")
+}
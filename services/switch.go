@@ -30,3 +30,16 @@ func (s Switch) TurnOff(entityId SwitchID) error {
 
 	return s.conn.Send(&req)
 }
+
+// TurnOffAll turns off every switch but those given.
+func (s Switch) TurnOffAll(except ...SwitchID) error {
+	target, err := allExcept(s.conn, "switch", except)
+	if err != nil || target == "" {
+		return err
+	}
+	req := NewBaseServiceRequest(target)
+	req.Domain = "switch"
+	req.Service = "turn_off"
+
+	return s.conn.Send(&req)
+}
@@ -0,0 +1,66 @@
+package services
+
+import "strings"
+
+// Target widens a service call beyond the entity it names, to whole areas,
+// devices, or more entities. Home Assistant resolves areas and devices to
+// their entities when the call arrives, so a lamp added to a room later is
+// included without changing the automation.
+//
+// Combine targets with Service.Targeting, which applies them to every call
+// made through it.
+type Target struct {
+	Entities []string
+	Areas    []string
+	Devices  []string
+}
+
+// InArea targets every entity in the given areas, by area id.
+func InArea(areaIDs ...string) Target { return Target{Areas: areaIDs} }
+
+// OnDevice targets every entity of the given devices, by device id.
+func OnDevice(deviceIDs ...string) Target { return Target{Devices: deviceIDs} }
+
+// Entities targets several entities at once.
+func Entities(entityIDs ...string) Target { return Target{Entities: entityIDs} }
+
+// Apply adds the target to a request's own, keeping whatever entity the
+// request already names.
+func (t Target) Apply(req *BaseServiceRequest) {
+	if len(t.Entities) == 0 && len(t.Areas) == 0 && len(t.Devices) == 0 {
+		return
+	}
+	merged := ServiceTarget{}
+	if req.Target != nil {
+		merged = *req.Target
+	}
+
+	entities := t.Entities
+	if merged.EntityId != "" {
+		entities = append([]string{merged.EntityId}, entities...)
+	}
+	merged.EntityId = strings.Join(entities, ", ")
+	// Copied, so requests built from one target never share its slices.
+	merged.AreaId = append(append([]string(nil), merged.AreaId...), t.Areas...)
+	merged.DeviceId = append(append([]string(nil), merged.DeviceId...), t.Devices...)
+	req.Target = &merged
+}
+
+// String describes the target for logs: its entities, then any areas and
+// devices.
+func (t *ServiceTarget) String() string {
+	if t == nil {
+		return ""
+	}
+	var parts []string
+	if t.EntityId != "" {
+		parts = append(parts, t.EntityId)
+	}
+	for _, id := range t.AreaId {
+		parts = append(parts, "area "+id)
+	}
+	for _, id := range t.DeviceId {
+		parts = append(parts, "device "+id)
+	}
+	return strings.Join(parts, ", ")
+}
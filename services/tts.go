@@ -36,3 +36,21 @@ func (tts TTS) GoogleTranslateSay(entityId EntityID, serviceData ...map[string]a
 
 	return tts.conn.Send(&req)
 }
+
+// Say message on a media player through a tts entity, such as
+// tts.google_en_com. An empty language leaves the engine's own.
+// See https://www.home-assistant.io/integrations/tts/#action-ttsspeak
+func (tts TTS) Speak(entityId EntityID, mediaPlayer MediaPlayerID, message, language string) error {
+	req := NewBaseServiceRequest(string(entityId))
+	req.Domain = "tts"
+	req.Service = "speak"
+	req.ServiceData = map[string]any{
+		"media_player_entity_id": mediaPlayer,
+		"message":                message,
+	}
+	if language != "" {
+		req.ServiceData["language"] = language
+	}
+
+	return tts.conn.Send(&req)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// VacuumIntegration picks how room and zone cleaning is encoded. Home Assistant
+// has no common service for either; each integration takes its own command,
+// with its own shape of parameters.
+type VacuumIntegration int
+
+const (
+	// Roborock is the core Roborock integration, driven through
+	// vacuum.send_command with the app's own segment and zone commands.
+	Roborock VacuumIntegration = iota
+
+	// Dreame is the dreame_vacuum custom integration, which registers
+	// services of its own.
+	Dreame
+
+	// Valetudo is a Valetudo robot exposed over MQTT, driven through
+	// vacuum.send_command.
+	Valetudo
+)
+
+func (i VacuumIntegration) String() string {
+	switch i {
+	case Roborock:
+		return "roborock"
+	case Dreame:
+		return "dreame"
+	case Valetudo:
+		return "valetudo"
+	default:
+		return "VacuumIntegration(" + strconv.Itoa(int(i)) + ")"
+	}
+}
+
+// VacuumZone is a rectangle on the robot's map, in the map's own coordinates,
+// which are whatever the integration's map card shows.
+type VacuumZone struct {
+	X1, Y1, X2, Y2 int
+}
+
+// CleanRooms cleans the given rooms, by the ids the integration numbers its map
+// segments with, in one pass. The integration decides how the request is
+// encoded.
+func (v Vacuum) CleanRooms(entityId VacuumID, integration VacuumIntegration, roomIDs ...int) error {
+	if len(roomIDs) == 0 {
+		return errors.New("CleanRooms needs at least one room")
+	}
+
+	req := NewBaseServiceRequest(string(entityId))
+	switch integration {
+	case Roborock:
+		req.Domain, req.Service = "vacuum", "send_command"
+		req.ServiceData = map[string]any{"command": "app_segment_clean", "params": roomIDs}
+	case Dreame:
+		req.Domain, req.Service = "dreame_vacuum", "vacuum_clean_segment"
+		req.ServiceData = map[string]any{"segments": roomIDs}
+	case Valetudo:
+		// Valetudo names segments with strings, though they are numbers.
+		ids := make([]string, len(roomIDs))
+		for i, id := range roomIDs {
+			ids[i] = strconv.Itoa(id)
+		}
+		req.Domain, req.Service = "vacuum", "send_command"
+		req.ServiceData = map[string]any{
+			"command": "segment_cleanup",
+			"params":  map[string]any{"segment_ids": ids, "iterations": 1, "customOrder": true},
+		}
+	default:
+		return fmt.Errorf("no room cleaning for %s", integration)
+	}
+
+	return v.conn.Send(&req)
+}
+
+// CleanZone cleans a rectangle of the map, going over it the given number of
+// times. Fewer than one pass is taken as one.
+func (v Vacuum) CleanZone(entityId VacuumID, integration VacuumIntegration, zone VacuumZone, passes int) error {
+	passes = max(passes, 1)
+
+	req := NewBaseServiceRequest(string(entityId))
+	switch integration {
+	case Roborock:
+		req.Domain, req.Service = "vacuum", "send_command"
+		req.ServiceData = map[string]any{
+			"command": "app_zoned_clean",
+			"params":  [][]int{{zone.X1, zone.Y1, zone.X2, zone.Y2, passes}},
+		}
+	case Dreame:
+		req.Domain, req.Service = "dreame_vacuum", "vacuum_clean_zone"
+		req.ServiceData = map[string]any{
+			"zone":    []int{zone.X1, zone.Y1, zone.X2, zone.Y2},
+			"repeats": passes,
+		}
+	case Valetudo:
+		// Valetudo wants the four corners, clockwise from the first.
+		corner := func(x, y int) map[string]int { return map[string]int{"x": x, "y": y} }
+		req.Domain, req.Service = "vacuum", "send_command"
+		req.ServiceData = map[string]any{
+			"command": "zoned_cleanup",
+			"params": map[string]any{
+				"zones": []any{map[string]any{"points": map[string]any{
+					"pA": corner(zone.X1, zone.Y1),
+					"pB": corner(zone.X2, zone.Y1),
+					"pC": corner(zone.X2, zone.Y2),
+					"pD": corner(zone.X1, zone.Y2),
+				}}},
+				"iterations": passes,
+			},
+		}
+	default:
+		return fmt.Errorf("no zone cleaning for %s", integration)
+	}
+
+	return v.conn.Send(&req)
+}
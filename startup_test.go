@@ -0,0 +1,58 @@
+package ha_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+func TestStartupRunsAreStaggered(t *testing.T) {
+	server := hatest.New(t)
+	app, err := ha.NewApp(types.NewAppRequest{
+		URL:            server.URL(),
+		HAAuthToken:    hatest.Token,
+		StartupStagger: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = app.Close() })
+
+	var mu sync.Mutex
+	ran := map[string]time.Time{}
+	for _, name := range []string{"heating", "lights", "blinds"} {
+		trig := ha.AtStartup()
+		if name == "blinds" {
+			trig = trig.Order(-1)
+		}
+		require.NoError(t, app.RegisterAutomations(
+			ha.NewAutomation(name).
+				On(trig).
+				Do(func(context.Context, ha.Run) error {
+					mu.Lock()
+					ran[name] = time.Now()
+					mu.Unlock()
+					return nil
+				}).
+				MustBuild(),
+		))
+	}
+	start(t, app)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ran) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Less(t, ran["blinds"], ran["heating"], "ordered first")
+	assert.GreaterOrEqual(t, ran["lights"].Sub(ran["heating"]), 80*time.Millisecond, "spaced apart")
+}
@@ -0,0 +1,58 @@
+package ha_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestStatisticsReadsMeasurementsAndTotals(t *testing.T) {
+	server := hatest.New(t)
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	server.AddStatistic("sensor.lounge_temperature", day, map[string]float64{"mean": 19.5, "min": 17, "max": 21.2})
+	server.AddStatistic("sensor.lounge_temperature", day.AddDate(0, 0, 1), map[string]float64{"mean": 20, "min": 18, "max": 22})
+	server.AddStatistic("sensor.energy_import", day, map[string]float64{"state": 1204.5, "sum": 880.5, "change": 9.25})
+	server.AddStatistic("sensor.energy_import", day.AddDate(0, 0, -1), map[string]float64{"state": 1195.25, "sum": 871.25, "change": 8})
+
+	app := newApp(t, server)
+	start(t, app)
+
+	stats, err := app.Statistics([]string{"sensor.lounge_temperature", "sensor.energy_import", "sensor.no_class"},
+		day, day.AddDate(0, 0, 2), ha.PeriodDay)
+	require.NoError(t, err)
+	assert.NotContains(t, stats, "sensor.no_class", "an entity without statistics is left out")
+
+	temps := stats["sensor.lounge_temperature"]
+	require.Len(t, temps, 2)
+	assert.True(t, temps[0].Start.Equal(day))
+	assert.True(t, temps[0].End.Equal(day.AddDate(0, 0, 1)))
+	require.NotNil(t, temps[0].Mean)
+	assert.InDelta(t, 19.5, *temps[0].Mean, 1e-9)
+	assert.InDelta(t, 22, *temps[1].Max, 1e-9)
+	assert.Nil(t, temps[0].Sum, "a measurement has no total")
+
+	energy := stats["sensor.energy_import"]
+	require.Len(t, energy, 1, "the day before the span is not in it")
+	require.NotNil(t, energy[0].Change)
+	assert.InDelta(t, 9.25, *energy[0].Change, 1e-9)
+	assert.Nil(t, energy[0].Mean)
+}
+
+func TestStatisticsRefusesABadRequest(t *testing.T) {
+	app := newApp(t, hatest.New(t))
+	now := time.Now()
+
+	_, err := app.Statistics(nil, now.Add(-time.Hour), now, ha.PeriodHour)
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "no entities")
+
+	_, err = app.Statistics([]string{"sensor.a"}, now, now.Add(-time.Hour), ha.PeriodHour)
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "end before start")
+
+	_, err = app.Statistics([]string{"sensor.a"}, now.Add(-time.Hour), now, "fortnight")
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "unknown period")
+}
@@ -0,0 +1,115 @@
+package ha_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestSubAppStopsAndStartsOnItsOwn(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.kitchen_motion", "off")
+	server.SetState("binary_sensor.hall_motion", "off")
+	app := newApp(t, server)
+
+	kitchen, err := app.SubApp("kitchen")
+	require.NoError(t, err)
+
+	cancelled := make(chan struct{}, 2)
+	require.NoError(t, kitchen.RegisterAutomations(
+		ha.NewAutomation("lights").
+			On(ha.StateChanged("binary_sensor.kitchen_motion").To("on")).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn("light.kitchen")
+			}).
+			MustBuild(),
+		ha.NewAutomation("slow").
+			On(ha.StateChanged("binary_sensor.kitchen_motion").To("off")).
+			Do(func(ctx context.Context, _ ha.Run) error {
+				<-ctx.Done()
+				cancelled <- struct{}{}
+				return ctx.Err()
+			}).
+			MustBuild(),
+	))
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("hall").
+			On(ha.StateChanged("binary_sensor.hall_motion").To("on")).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn("light.hall")
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.ChangeState("binary_sensor.kitchen_motion", "on")
+	server.WaitForCalls(1)
+	server.ChangeState("binary_sensor.kitchen_motion", "off")
+	time.Sleep(50 * time.Millisecond)
+
+	kitchen.Stop()
+	assert.False(t, kitchen.Running())
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("stopping did not cancel the run under way")
+	}
+
+	server.ChangeState("binary_sensor.kitchen_motion", "on")
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	calls := server.WaitForCalls(2)
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, server.Calls(), 2, "the stopped kitchen does nothing")
+	assert.Equal(t, "light.hall", calls[1].EntityID, "the rest of the app carries on")
+
+	kitchen.Start()
+	assert.True(t, kitchen.Running())
+	server.ChangeState("binary_sensor.kitchen_motion", "off")
+	server.ChangeState("binary_sensor.kitchen_motion", "on")
+	calls = server.WaitForCalls(3)
+	assert.Equal(t, "light.kitchen", calls[2].EntityID)
+}
+
+func TestSubAppNeedsAName(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	_, err := app.SubApp("")
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+	_, err = app.SubApp("a/b")
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
+
+// A refused registration leaves nothing behind: an automation the app then
+// takes directly is not stopped with the sub-app that failed to take it.
+func TestSubAppRegistrationFailureLeavesNothingBehind(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	app := newApp(t, server)
+
+	kitchen, err := app.SubApp("kitchen")
+	require.NoError(t, err)
+
+	hall := ha.NewAutomation("hall").
+		On(ha.StateChanged("binary_sensor.hall_motion").To("on")).
+		Do(func(_ context.Context, run ha.Run) error {
+			return run.Services.Light.TurnOn("light.hall")
+		}).
+		MustBuild()
+	unbuilt := ha.Automation{}
+	assert.ErrorIs(t, kitchen.RegisterAutomations(hall, unbuilt), ha.ErrInvalidAutomation)
+
+	require.NoError(t, app.RegisterAutomations(hall))
+	start(t, app)
+	kitchen.Stop()
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	calls := server.WaitForCalls(1)
+	assert.Equal(t, "light.hall", calls[0].EntityID)
+}
@@ -0,0 +1,40 @@
+package ha_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/types"
+)
+
+// The Supervisor serves Home Assistant under /core, so both the REST API and
+// the websocket have to be found beneath the URL's path rather than at the
+// root.
+func TestAppReachesHomeAssistantBehindAPathPrefix(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("light.hall", "on")
+
+	target, err := url.Parse(server.URL())
+	require.NoError(t, err)
+	proxy := httptest.NewServer(http.StripPrefix("/core", httputil.NewSingleHostReverseProxy(target)))
+	t.Cleanup(proxy.Close)
+
+	app, err := ha.NewApp(types.NewAppRequest{URL: proxy.URL + "/core", HAAuthToken: hatest.Token})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = app.Close() })
+
+	st, err := app.State().Get("light.hall")
+	require.NoError(t, err)
+	assert.Equal(t, "on", st.State)
+
+	require.NoError(t, app.Services().Light.TurnOff("light.hall"))
+	assert.Equal(t, "turn_off", server.WaitForCalls(1)[0].Service)
+}
@@ -0,0 +1,91 @@
+package ha_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+// warmer renders the one template these tests use from the server's state, the
+// way Home Assistant would.
+func warmer(server *hatest.Server) hatest.TemplateRenderer {
+	return func(template string) (any, error) {
+		if !strings.Contains(template, "sensor.temp") {
+			return nil, errors.New("TemplateSyntaxError: unexpected '}'")
+		}
+		state, _, _ := server.State("sensor.temp")
+		return state > "20", nil
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("sensor.temp", "23")
+	server.HandleTemplate(warmer(server))
+	app := newApp(t, server)
+
+	result, err := app.Services().Template.Render("{{ states('sensor.temp') | float > 20 }}")
+	require.NoError(t, err)
+	assert.Equal(t, "true", result)
+
+	_, err = app.Services().Template.Render("{{ oops }")
+	assert.ErrorIs(t, err, ha.ErrCallFailed)
+
+	_, err = app.Services().Template.Render("")
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
+
+// Plain text renders as itself, and strings come back without JSON quoting.
+func TestTemplateRenderPlainText(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	result, err := app.Services().WithResult().Template.Render("hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result)
+}
+
+func TestTemplateWatchCallsBackOnChange(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("sensor.temp", "18")
+	server.HandleTemplate(warmer(server))
+	app := newApp(t, server)
+
+	got := make(chan string, 8)
+	sub, err := app.Services().Template.Watch("{{ states('sensor.temp') | float > 20 }}",
+		func(result string) { got <- result })
+	require.NoError(t, err)
+
+	next := func() string {
+		t.Helper()
+		select {
+		case r := <-got:
+			return r
+		case <-time.After(2 * time.Second):
+			t.Fatal("no rendering arrived")
+			return ""
+		}
+	}
+
+	assert.Equal(t, "false", next(), "the first rendering is passed on")
+
+	server.ChangeState("sensor.temp", "19")
+	server.ChangeState("sensor.temp", "22")
+	assert.Equal(t, "true", next(), "only the change is passed on")
+
+	require.NoError(t, sub.Cancel())
+	server.ChangeState("sensor.temp", "17")
+
+	select {
+	case r := <-got:
+		t.Fatalf("called after cancelling: %s", r)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,120 @@
+package ha_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+// traceServer answers the trace commands with two runs of a script, and
+// reports the commands it was sent.
+func traceServer(t *testing.T) (*hatest.Server, func() []map[string]any) {
+	var mu sync.Mutex
+	var asked []map[string]any
+	record := func(msg map[string]any) {
+		mu.Lock()
+		defer mu.Unlock()
+		asked = append(asked, msg)
+	}
+	server := hatest.New(t)
+	server.HandleCommand("trace/list", func(msg map[string]any) (any, error) {
+		record(msg)
+		return []map[string]any{
+			{
+				"run_id": "older", "domain": "script", "item_id": "good_night",
+				"state": "stopped", "script_execution": "finished", "last_step": "sequence/1",
+				"timestamp": map[string]any{"start": "2026-10-16T22:00:00+00:00", "finish": "2026-10-16T22:00:02+00:00"},
+			},
+			{
+				"run_id": "newer", "domain": "script", "item_id": "good_night",
+				"state": "stopped", "script_execution": "error", "last_step": "sequence/0",
+				"error":     "Entity not found: lock.back_door",
+				"timestamp": map[string]any{"start": "2026-10-17T22:00:00+00:00", "finish": "2026-10-17T22:00:01+00:00"},
+			},
+		}, nil
+	})
+	server.HandleCommand("trace/get", func(msg map[string]any) (any, error) {
+		record(msg)
+		if msg["run_id"] != "newer" {
+			return nil, errors.New("no such run")
+		}
+		return map[string]any{
+			"run_id": "newer", "domain": "script", "item_id": "good_night",
+			"state": "stopped", "script_execution": "error", "last_step": "sequence/0",
+			"error":     "Entity not found: lock.back_door",
+			"timestamp": map[string]any{"start": "2026-10-17T22:00:00+00:00", "finish": nil},
+			"config":    map[string]any{"alias": "Good night"},
+			"trace": map[string]any{
+				"sequence/0": []map[string]any{{
+					"path": "sequence/0", "timestamp": "2026-10-17T22:00:00.5+00:00",
+					"error": "Entity not found: lock.back_door",
+				}},
+				"trigger": []map[string]any{{
+					"path": "trigger", "timestamp": "2026-10-17T22:00:00+00:00",
+					"changed_variables": map[string]any{"this": "script.good_night"},
+				}},
+			},
+		}, nil
+	})
+	return server, func() []map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return asked
+	}
+}
+
+func TestTracesListRunsNewestFirst(t *testing.T) {
+	server, asked := traceServer(t)
+	app := newApp(t, server)
+
+	runs, err := app.Traces("script.good_night")
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "newer", runs[0].RunID)
+	assert.True(t, runs[0].Failed())
+	assert.False(t, runs[1].Failed())
+	assert.Equal(t, 2, runs[1].Finish.Second())
+
+	assert.Equal(t, "script", asked()[0]["domain"])
+	assert.Equal(t, "good_night", asked()[0]["item_id"])
+}
+
+func TestTraceReadsTheLatestRunStepByStep(t *testing.T) {
+	server, _ := traceServer(t)
+	app := newApp(t, server)
+
+	trace, err := app.Trace("script.good_night", "")
+	require.NoError(t, err)
+	assert.Equal(t, "Entity not found: lock.back_door", trace.Error)
+	assert.True(t, trace.Finish.IsZero(), "a null finish reads as zero")
+	assert.Equal(t, "Good night", trace.Config["alias"])
+
+	require.Len(t, trace.Steps, 2)
+	assert.Equal(t, "trigger", trace.Steps[0].Path, "steps are in the order taken")
+	assert.Equal(t, "script.good_night", trace.Steps[0].Variables["this"])
+	assert.Equal(t, "Entity not found: lock.back_door", trace.Steps[1].Error)
+}
+
+func TestTraceFindsAnAutomationByItsConfigID(t *testing.T) {
+	server, asked := traceServer(t)
+	server.SetState("automation.porch_lights", "on", map[string]any{"id": "1700000000000"})
+	server.SetState("automation.from_yaml", "on")
+	app := newApp(t, server)
+
+	_, err := app.Traces("automation.porch_lights")
+	require.NoError(t, err)
+	assert.Equal(t, "automation", asked()[0]["domain"])
+	assert.Equal(t, "1700000000000", asked()[0]["item_id"])
+
+	_, err = app.Traces("automation.from_yaml")
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs, "an automation without an id is not traced")
+
+	_, err = app.Traces("light.kitchen")
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
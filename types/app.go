@@ -1,10 +1,18 @@
 package types
 
+import (
+	"log/slog"
+	"time"
+)
+
 // NewAppRequest contains the configuration for creating a new App instance.
 type NewAppRequest struct {
 	// Required
 	// URL of your Home Assistant instance, e.g. "http://localhost:8123".
-	// The scheme decides whether the connection is plain or TLS.
+	// The scheme decides whether the connection is plain or TLS. A path, as in
+	// "https://example.com/homeassistant", is kept as the API's prefix.
+	// Running as an add-on, leave it and HAAuthToken empty to have both taken
+	// from the add-on's environment.
 	URL string
 
 	// Required
@@ -20,4 +28,114 @@ type NewAppRequest struct {
 	// Connection tunes the websocket connection. The zero value uses defaults
 	// suitable for a typical Home Assistant instance.
 	Connection ConnectionOptions
+
+	// Optional
+	// Audit records every service call the app makes where the household can
+	// see it. The zero value records nothing.
+	Audit AuditOptions
+
+	// Optional
+	// ServiceTimeout bounds how long a service call waits for Home Assistant
+	// to answer, for calls that wait: those made through Service.WithResult
+	// and Service.CallWithResult, and for App.Command. Ordinary calls return
+	// once sent. Defaults to 10 seconds.
+	ServiceTimeout time.Duration
+
+	// Optional
+	// StartupStagger spreads out the automations that run at startup, one
+	// every StartupStagger, so dozens of them do not hit Home Assistant in the
+	// same instant. AtStartup().Order decides who goes first. Zero runs them
+	// all at once.
+	StartupStagger time.Duration
+
+	// Optional
+	// ReadOnly lets the app subscribe and read state as usual but refuses
+	// every service call, event fire and SetState with ErrReadOnly, logging
+	// what it would have done, as well as the raw websocket commands sent with
+	// Command that write, such as calendar/event/delete. It is for running
+	// changed automations against a production Home Assistant before trusting
+	// them with it.
+	ReadOnly bool
+
+	// Optional
+	// DryRun lets the app subscribe, read state and run its automations as
+	// usual, but skips every service call, event fire and SetState, logging
+	// what it would have done. Unlike ReadOnly, the skipped calls report
+	// success, so an automation carries on past them as it would in
+	// production. App.DryRunCalls lists what was skipped. It is for deploying
+	// new logic without touching real devices. Of the raw websocket commands
+	// sent with Command, those that write are skipped too and the rest pass.
+	// It cannot be combined with ReadOnly.
+	DryRun bool
+
+	// Optional
+	// CheckEntities makes RegisterAutomations refuse an automation whose
+	// triggers or conditions name an entity Home Assistant does not have,
+	// with ErrUnknownEntity, rather than registering one that never fires.
+	// Patterns are not checked, since they may match entities added later.
+	CheckEntities bool
+
+	// Optional
+	// OnUndecodable is given every message or event that could not be
+	// decoded, with the reason, instead of it being dispatched. It is a
+	// dead-letter hook for debugging a change in Home Assistant's protocol:
+	// keep the frames it is handed and compare them with what was expected.
+	// It runs on the connection's reader and must not block. Failures are
+	// counted in ConnectionStats.Undecodable whether or not it is set.
+	OnUndecodable func(raw []byte, err error)
+
+	// Optional
+	// Logger receives everything the app logs: the connection, the schedules,
+	// the services and each automation, whose lines carry its name. Defaults
+	// to slog's default logger as it is when NewApp is called. To silence the
+	// app, pass a logger whose handler discards, such as
+	// slog.New(slog.DiscardHandler).
+	Logger *slog.Logger
+
+	// Optional
+	// StreamEntities keeps the state cache from Home Assistant's
+	// subscribe_entities stream rather than a REST snapshot of every entity
+	// fetched on each connection. The stream sends the full set once over the
+	// websocket and then only what changed, compressed, which on a large
+	// install is far less than the snapshot after every reconnect. Automations
+	// still trigger on state_changed events. Needs Home Assistant 2022.4 or
+	// later: an older one refuses the command and the app never becomes ready.
+	StreamEntities bool
+
+	// Optional
+	// DeferredCallsFile is where the calls queued with Service.At are kept,
+	// so those still due when the app stops are made after it restarts. The
+	// file is created as needed, and its directory must exist. Without it the
+	// queue is held in memory and lost with the app. It is ignored in a dry
+	// run, whose calls are never made.
+	DeferredCallsFile string
+
+	// Optional
+	// Timezone names the zone, such as "America/Chicago", that schedules and
+	// conditions read the clock in: daily triggers, cron expressions,
+	// TimeBetween and the weekday and date conditions all resolve wall-clock
+	// times there. HomeTimezone takes the zone configured in Home Assistant.
+	// Empty keeps the clock's own, which for the system clock is the process's
+	// local time, and in most containers UTC.
+	Timezone string
+}
+
+// HomeTimezone, as NewAppRequest.Timezone, reads the clock in the time zone
+// Home Assistant is configured with.
+const HomeTimezone = "home"
+
+// DefaultAuditSensor is the conventional entity for AuditOptions.Sensor.
+const DefaultAuditSensor = "sensor.go_ha_last_action"
+
+// AuditOptions decides where the app's service calls are recorded, so someone
+// wondering why the lights went off can find out what did it.
+type AuditOptions struct {
+	// Sensor, if set, is an entity kept pointing at the latest call: its state
+	// is domain.service, with the domain, service, target and time as
+	// attributes. DefaultAuditSensor is the usual choice.
+	Sensor string
+
+	// Logbook writes a logbook entry for each call, which keeps a history
+	// rather than only the latest.
+	Logbook bool
 }
@@ -7,3 +7,17 @@ import "time"
 type Clock interface {
 	Now() time.Time
 }
+
+// TimerClock is a Clock that can also wake a sleeper. When the Clock given to
+// an App implements it, the schedulers wait on its timers rather than on real
+// ones, so a test clock that releases its waiters as it is advanced fires
+// schedules with no real time elapsed. A Clock without it is waited on with
+// real timers, for the gap its own Now reports.
+type TimerClock interface {
+	Clock
+
+	// Timer delivers the clock's time on c once d has passed on this clock.
+	// A duration of zero or less delivers at once. Calling stop abandons the
+	// timer; it is safe to call after delivery.
+	Timer(d time.Duration) (c <-chan time.Time, stop func())
+}
@@ -28,4 +28,21 @@ type ConnectionOptions struct {
 	// PingInterval is how often an idle connection is checked for liveness.
 	// Defaults to 30 seconds.
 	PingInterval time.Duration
+
+	// PingTimeout is how long a ping may go unanswered before the connection
+	// is presumed dead and re-established. Behind a NAT or proxy that has
+	// silently dropped the connection, this is what notices. Defaults to 10
+	// seconds.
+	PingTimeout time.Duration
+
+	// WriteTimeout bounds how long one outgoing message may take to reach the
+	// socket. A write that overruns it means the connection has stopped
+	// draining, so it is dropped and re-established rather than left to hold
+	// up every service call behind it. Defaults to 10 seconds.
+	WriteTimeout time.Duration
+
+	// ReadTimeout is how long the connection may stay silent before it is
+	// presumed dead. Defaults to PingInterval plus PingTimeout, since
+	// pings keep a live connection talking.
+	ReadTimeout time.Duration
 }
@@ -1,5 +1,11 @@
 package types
 
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
 type NotifyRequest struct {
 	// Which notify service to call, such as mobile_app_sams_iphone
 	ServiceName string
@@ -8,7 +14,39 @@ type NotifyRequest struct {
 	Data        map[string]any
 }
 
-// SetTemperatureRequest describes a climate.set_temperature call.
+// ErrInvalidArgs reports arguments a call cannot act on. The app reports the
+// same error, as ha.ErrInvalidArgs.
+var ErrInvalidArgs = errors.New("invalid arguments provided")
+
+// ErrInvalidTemperature reports a set_temperature request Home Assistant would
+// refuse, or one outside what the entity accepts.
+var ErrInvalidTemperature = errors.New("invalid temperature request")
+
+// TemperatureUnit is a unit temperatures are given in.
+type TemperatureUnit string
+
+const (
+	Celsius    TemperatureUnit = "°C"
+	Fahrenheit TemperatureUnit = "°F"
+)
+
+// Convert expresses v, given in u, in the other unit. It is rounded to a tenth
+// of a degree, finer than any thermostat steps.
+func (u TemperatureUnit) Convert(v float32, to TemperatureUnit) float32 {
+	switch {
+	case u == Fahrenheit && to == Celsius:
+		v = (v - 32) * 5 / 9
+	case u == Celsius && to == Fahrenheit:
+		v = v*9/5 + 32
+	default:
+		return v
+	}
+	return float32(math.Round(float64(v)*10) / 10)
+}
+
+// SetTemperatureRequest describes a climate.set_temperature call: a single
+// setpoint, or a TargetTempLow to TargetTempHigh range for an entity in
+// heat_cool, with HvacMode switching the mode in the same call.
 //
 // The temperatures are pointers so that an unset field is distinguishable from
 // a deliberate zero. Zero degrees is an ordinary setpoint in Celsius, and
@@ -18,6 +56,60 @@ type SetTemperatureRequest struct {
 	TargetTempHigh *float32
 	TargetTempLow  *float32
 	HvacMode       string
+
+	// Unit is the unit the temperatures are given in. They are converted to
+	// Home Assistant's own before sending. Empty means they already are.
+	Unit TemperatureUnit
+}
+
+// Validate checks the request is one Home Assistant would accept: a setpoint
+// or a range but not both, a range with both ends and the low end below the
+// high.
+func (r SetTemperatureRequest) Validate() error {
+	ranged := r.TargetTempHigh != nil || r.TargetTempLow != nil
+	switch {
+	case r.Temperature == nil && !ranged:
+		return fmt.Errorf("%w: no temperature given", ErrInvalidTemperature)
+	case r.Temperature != nil && ranged:
+		return fmt.Errorf("%w: a setpoint and a range are exclusive", ErrInvalidTemperature)
+	case ranged && (r.TargetTempHigh == nil || r.TargetTempLow == nil):
+		return fmt.Errorf("%w: a range needs both its high and low", ErrInvalidTemperature)
+	case ranged && *r.TargetTempLow > *r.TargetTempHigh:
+		return fmt.Errorf("%w: range low %v is above its high %v", ErrInvalidTemperature, *r.TargetTempLow, *r.TargetTempHigh)
+	}
+	switch r.Unit {
+	case "", Celsius, Fahrenheit:
+	default:
+		return fmt.Errorf("%w: unknown unit %q", ErrInvalidTemperature, r.Unit)
+	}
+	return nil
+}
+
+// InUnit returns the request with its temperatures expressed in the given
+// unit. A request without a unit is taken to be in it already.
+func (r SetTemperatureRequest) InUnit(to TemperatureUnit) SetTemperatureRequest {
+	if r.Unit == "" || r.Unit == to {
+		r.Unit = to
+		return r
+	}
+	for _, t := range []**float32{&r.Temperature, &r.TargetTempHigh, &r.TargetTempLow} {
+		if *t != nil {
+			*t = Ptr(r.Unit.Convert(**t, to))
+		}
+	}
+	r.Unit = to
+	return r
+}
+
+// CheckLimits reports a setpoint outside the entity's min_temp to max_temp,
+// which are in the same unit as the request.
+func (r SetTemperatureRequest) CheckLimits(minTemp, maxTemp float32) error {
+	for _, t := range []*float32{r.Temperature, r.TargetTempHigh, r.TargetTempLow} {
+		if t != nil && (*t < minTemp || *t > maxTemp) {
+			return fmt.Errorf("%w: %v%s is outside %v to %v", ErrInvalidTemperature, *t, r.Unit, minTemp, maxTemp)
+		}
+	}
+	return nil
 }
 
 func (r *SetTemperatureRequest) ToJSON() map[string]any {
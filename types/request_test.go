@@ -71,3 +71,45 @@ func TestPtr(t *testing.T) {
 	a, b := Ptr("x"), Ptr("y")
 	assert.NotSame(t, a, b)
 }
+
+func TestSetTemperatureValidate(t *testing.T) {
+	valid := []SetTemperatureRequest{
+		{Temperature: Ptr(float32(0))},
+		{Temperature: Ptr(float32(20)), HvacMode: "heat"},
+		{TargetTempLow: Ptr(float32(19)), TargetTempHigh: Ptr(float32(24)), Unit: Celsius},
+	}
+	for _, req := range valid {
+		assert.NoError(t, req.Validate())
+	}
+
+	invalid := map[string]SetTemperatureRequest{
+		"nothing to set":         {HvacMode: "heat"},
+		"setpoint and range":     {Temperature: Ptr(float32(20)), TargetTempLow: Ptr(float32(19)), TargetTempHigh: Ptr(float32(24))},
+		"half a range":           {TargetTempLow: Ptr(float32(19))},
+		"range upside down":      {TargetTempLow: Ptr(float32(24)), TargetTempHigh: Ptr(float32(19))},
+		"unit nobody recognises": {Temperature: Ptr(float32(20)), Unit: "K"},
+	}
+	for name, req := range invalid {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, req.Validate(), ErrInvalidTemperature)
+		})
+	}
+}
+
+func TestSetTemperatureInUnit(t *testing.T) {
+	req := SetTemperatureRequest{Temperature: Ptr(float32(70)), Unit: Fahrenheit}
+
+	converted := req.InUnit(Celsius)
+	assert.Equal(t, float32(21.1), *converted.Temperature)
+	assert.Equal(t, Celsius, converted.Unit)
+	assert.Equal(t, float32(70), *req.Temperature, "the original is left alone")
+
+	unitless := SetTemperatureRequest{Temperature: Ptr(float32(21))}.InUnit(Fahrenheit)
+	assert.Equal(t, float32(21), *unitless.Temperature, "no unit means already in the target's")
+}
+
+func TestSetTemperatureCheckLimits(t *testing.T) {
+	req := SetTemperatureRequest{TargetTempLow: Ptr(float32(5)), TargetTempHigh: Ptr(float32(24))}
+	assert.ErrorIs(t, req.CheckLimits(7, 35), ErrInvalidTemperature)
+	assert.NoError(t, req.CheckLimits(5, 35))
+}
@@ -0,0 +1,122 @@
+// Package units converts sensor readings between the units Home Assistant
+// reports them in, so an automation written against Celsius keeps working on
+// an instance set to Fahrenheit, or on a sensor that reports in kW where its
+// neighbour reports in W.
+//
+// Units are named as Home Assistant writes them in unit_of_measurement: "°C",
+// "km", "kW", "lx" and so on.
+package units
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnknownUnit reports a unit this package does not know.
+	ErrUnknownUnit = errors.New("unknown unit")
+
+	// ErrIncompatibleUnits reports a conversion between units that measure
+	// different things, such as °C to km.
+	ErrIncompatibleUnits = errors.New("incompatible units")
+)
+
+// Home Assistant's names for the units converted here.
+const (
+	Celsius    = "°C"
+	Fahrenheit = "°F"
+	Kelvin     = "K"
+
+	Millimetres = "mm"
+	Centimetres = "cm"
+	Metres      = "m"
+	Kilometres  = "km"
+	Inches      = "in"
+	Feet        = "ft"
+	Yards       = "yd"
+	Miles       = "mi"
+
+	Watts     = "W"
+	Kilowatts = "kW"
+	Megawatts = "MW"
+
+	WattHours     = "Wh"
+	KilowattHours = "kWh"
+	MegawattHours = "MWh"
+
+	Lux         = "lx"
+	FootCandles = "fc"
+)
+
+// quantity is what a unit measures. Conversion is only defined within one.
+type quantity string
+
+const (
+	temperature quantity = "temperature"
+	distance    quantity = "distance"
+	power       quantity = "power"
+	energy      quantity = "energy"
+	illuminance quantity = "illuminance"
+)
+
+// unit converts to and from its quantity's base unit: base = value*scale +
+// offset. Only temperatures need the offset.
+type unit struct {
+	quantity quantity
+	scale    float64
+	offset   float64
+}
+
+var known = map[string]unit{
+	Celsius:    {temperature, 1, 0},
+	Fahrenheit: {temperature, 5.0 / 9, -32 * 5.0 / 9},
+	Kelvin:     {temperature, 1, -273.15},
+
+	Millimetres: {distance, 0.001, 0},
+	Centimetres: {distance, 0.01, 0},
+	Metres:      {distance, 1, 0},
+	Kilometres:  {distance, 1000, 0},
+	Inches:      {distance, 0.0254, 0},
+	Feet:        {distance, 0.3048, 0},
+	Yards:       {distance, 0.9144, 0},
+	Miles:       {distance, 1609.344, 0},
+
+	Watts:     {power, 1, 0},
+	Kilowatts: {power, 1e3, 0},
+	Megawatts: {power, 1e6, 0},
+
+	WattHours:     {energy, 1, 0},
+	KilowattHours: {energy, 1e3, 0},
+	MegawattHours: {energy, 1e6, 0},
+
+	Lux:         {illuminance, 1, 0},
+	FootCandles: {illuminance, 10.763910416709722, 0},
+}
+
+// Convert converts value from one unit to another.
+func Convert(value float64, from, to string) (float64, error) {
+	if from == to {
+		return value, nil
+	}
+	f, ok := known[from]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownUnit, from)
+	}
+	t, ok := known[to]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownUnit, to)
+	}
+	if f.quantity != t.quantity {
+		return 0, fmt.Errorf("%w: %s is %s, %s is %s", ErrIncompatibleUnits, from, f.quantity, to, t.quantity)
+	}
+	base := value*f.scale + f.offset
+	return (base - t.offset) / t.scale, nil
+}
+
+// Compatible reports whether a reading in one unit can be converted to the
+// other.
+func Compatible(from, to string) bool {
+	f, okFrom := known[from]
+	t, okTo := known[to]
+	return okFrom && okTo && f.quantity == t.quantity
+}
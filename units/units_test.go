@@ -0,0 +1,50 @@
+package units
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{100, Celsius, Fahrenheit, 212},
+		{32, Fahrenheit, Celsius, 0},
+		{0, Celsius, Kelvin, 273.15},
+		{68, Fahrenheit, Kelvin, 293.15},
+		{10, Kilometres, Miles, 6.2137119},
+		{1, Miles, Feet, 5280},
+		{1500, Watts, Kilowatts, 1.5},
+		{2.5, KilowattHours, WattHours, 2500},
+		{10, FootCandles, Lux, 107.6391},
+		{21, Celsius, Celsius, 21},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.value, tt.from, tt.to)
+		require.NoError(t, err, "%v %s to %s", tt.value, tt.from, tt.to)
+		assert.InDelta(t, tt.want, got, 1e-4, "%v %s to %s", tt.value, tt.from, tt.to)
+	}
+}
+
+func TestConvertRejectsMismatchedUnits(t *testing.T) {
+	_, err := Convert(20, Celsius, Kilometres)
+	assert.ErrorIs(t, err, ErrIncompatibleUnits)
+
+	_, err = Convert(20, "furlongs", Miles)
+	assert.ErrorIs(t, err, ErrUnknownUnit)
+
+	_, err = Convert(20, Watts, "hp")
+	assert.ErrorIs(t, err, ErrUnknownUnit)
+}
+
+func TestCompatible(t *testing.T) {
+	assert.True(t, Compatible(Watts, Megawatts))
+	assert.True(t, Compatible(Lux, FootCandles))
+	assert.False(t, Compatible(Watts, WattHours))
+	assert.False(t, Compatible(Celsius, "furlongs"))
+}
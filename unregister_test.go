@@ -0,0 +1,60 @@
+package ha_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestUnregisteredAutomationStopsFiring(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	app := newApp(t, server)
+
+	held := ha.NewAutomation("hall held").
+		On(ha.StateChanged("binary_sensor.hall_motion").To("on").For(100 * time.Millisecond)).
+		Do(func(_ context.Context, run ha.Run) error {
+			return run.Services.Light.TurnOn("light.held")
+		}).
+		MustBuild()
+	nightly := ha.NewAutomation("nightly").
+		On(ha.Daily(ha.TimeOfDay(3, 0))).
+		Do(func(context.Context, ha.Run) error { return nil }).
+		MustBuild()
+	// Shares a name with the one unregistered, which must not take it along.
+	kept := ha.NewAutomation("hall held").
+		On(ha.StateChanged("binary_sensor.hall_motion").To("on")).
+		Do(func(_ context.Context, run ha.Run) error {
+			return run.Services.Light.TurnOn("light.kept")
+		}).
+		MustBuild()
+	require.NoError(t, app.RegisterAutomations(held, nightly, kept))
+	start(t, app)
+
+	// The held one is mid-wait when it goes.
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	server.AssertServiceCalled("light", "turn_on", "light.kept")
+	require.NoError(t, app.UnregisterAutomations(held, nightly))
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, server.AssertServiceNotCalled("light", "turn_on", "light.held"))
+
+	server.ResetCalls()
+	server.ChangeState("binary_sensor.hall_motion", "off")
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	server.AssertServiceCalled("light", "turn_on", "light.kept")
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, server.AssertServiceNotCalled("light", "turn_on", "light.held"))
+
+	inspected := app.Inspect()
+	assert.Empty(t, inspected.Schedules)
+	require.Len(t, inspected.Automations, 1)
+	assert.Equal(t, "hall held", inspected.Automations[0].Name)
+
+	assert.ErrorIs(t, app.UnregisterAutomations(held), ha.ErrUnknownAutomation)
+}
@@ -0,0 +1,88 @@
+package ha_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestWaitUntilSequencesAnAction(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.garage_door", "off")
+	app := newApp(t, server)
+
+	done := make(chan error, 1)
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("garage light follows the door").
+			On(ha.StateChanged("binary_sensor.garage_door").To("on")).
+			Do(func(ctx context.Context, run ha.Run) error {
+				if err := run.Services.Light.TurnOn("light.garage"); err != nil {
+					return err
+				}
+				err := ha.WaitUntil(ctx, run.State, "binary_sensor.garage_door", "off", 2*time.Second)
+				if err == nil {
+					err = run.Services.Light.TurnOff("light.garage")
+				}
+				done <- err
+				return err
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.ChangeState("binary_sensor.garage_door", "on")
+	server.WaitForCalls(1)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, server.Calls(), 1, "still waiting for the door")
+
+	server.ChangeState("binary_sensor.garage_door", "off")
+	require.NoError(t, <-done)
+	calls := server.WaitForCalls(2)
+	assert.Equal(t, "turn_off", calls[1].Service)
+}
+
+func TestWaitForEventFiltersAndTimesOut(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+	start(t, app)
+
+	ctx := context.Background()
+	got := make(chan ha.Event, 1)
+	go func() {
+		ev, err := app.WaitForEvent(ctx, "doorbell_pressed", func(ev ha.Event) bool {
+			return contains(ev.Raw, `"front"`)
+		}, 2*time.Second)
+		if err == nil {
+			got <- ev
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	server.Fire("doorbell_pressed", map[string]any{"door": "back"})
+	server.Fire("doorbell_pressed", map[string]any{"door": "front"})
+	select {
+	case ev := <-got:
+		assert.True(t, contains(ev.Raw, "front"))
+	case <-time.After(time.Second):
+		t.Fatal("the front doorbell was not seen")
+	}
+
+	_, err := app.WaitForEvent(ctx, "garage_opened", nil, 20*time.Millisecond)
+	assert.ErrorIs(t, err, ha.ErrWaitTimeout)
+}
+
+func TestWaitingOutsideARunNeedsTheApp(t *testing.T) {
+	_, err := ha.WaitForEvent(context.Background(), "doorbell_pressed", nil, time.Second)
+	assert.ErrorIs(t, err, ha.ErrInvalidArgs)
+}
+
+func contains(raw []byte, s string) bool {
+	return strings.Contains(string(raw), s)
+}
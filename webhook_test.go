@@ -0,0 +1,51 @@
+package ha_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+)
+
+func TestRegisterWebhookRunsTheHandler(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	got := make(chan ha.WebhookRequest, 1)
+	require.NoError(t, app.RegisterWebhook("ci-finished", func(_ context.Context, req ha.WebhookRequest) error {
+		got <- req
+		return nil
+	}))
+	start(t, app)
+
+	assert.False(t, server.CallWebhook("someone-else", nil))
+	require.True(t, server.CallWebhook("ci-finished", map[string]any{"status": "failed"}))
+
+	select {
+	case req := <-got:
+		var body struct{ Status string }
+		require.NoError(t, json.Unmarshal(req.JSON, &body))
+		assert.Equal(t, "failed", body.Status)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the handler never ran")
+	}
+	assert.Zero(t, app.ConnectionStats().Undecodable)
+}
+
+func TestRunAutomationRefusesAWebhookAutomation(t *testing.T) {
+	server := hatest.New(t)
+	app := newApp(t, server)
+
+	require.NoError(t, app.RegisterWebhook("ci-finished", func(context.Context, ha.WebhookRequest) error {
+		return nil
+	}))
+	start(t, app)
+
+	assert.ErrorIs(t, app.RunAutomation("webhook ci-finished"), ha.ErrInvalidArgs)
+}
@@ -0,0 +1,76 @@
+package ha_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ha "github.com/Xevion/go-ha"
+	"github.com/Xevion/go-ha/hatest"
+	"github.com/Xevion/go-ha/services"
+)
+
+func TestWhenAllRunsWhenMotionMeetsTheDark(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	server.SetState("sensor.hall_lux", "45")
+
+	app := newApp(t, server)
+	require.NoError(t, app.RegisterAutomations(
+		ha.NewAutomation("hall light").
+			On(ha.WhenAll(
+				ha.Require("binary_sensor.hall_motion").To("on"),
+				ha.Require("sensor.hall_lux").Below(20),
+			)).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn("light.hall")
+			}).
+			MustBuild(),
+	))
+	start(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, server.Calls(), "motion alone, in daylight")
+
+	server.ChangeState("sensor.hall_lux", "12")
+	server.WaitForCalls(1)
+
+	server.ChangeState("sensor.hall_lux", "10")
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, server.Calls(), 1, "still together, so it does not fire again")
+}
+
+func TestWhenAllSharedBetweenAutomationsFiresEach(t *testing.T) {
+	server := hatest.New(t)
+	server.SetState("binary_sensor.hall_motion", "off")
+	server.SetState("sensor.hall_lux", "12")
+
+	dark := ha.WhenAll(
+		ha.Require("binary_sensor.hall_motion").To("on"),
+		ha.Require("sensor.hall_lux").Below(20),
+	)
+	app := newApp(t, server)
+	for _, light := range []services.LightID{"light.hall", "light.stairs"} {
+		a, err := ha.NewAutomation(string(light)).
+			On(dark).
+			Do(func(_ context.Context, run ha.Run) error {
+				return run.Services.Light.TurnOn(light)
+			}).
+			Build()
+		require.NoError(t, err)
+		require.NoError(t, app.RegisterAutomations(a))
+	}
+	start(t, app)
+
+	server.ChangeState("binary_sensor.hall_motion", "on")
+	server.WaitForCalls(2)
+	var lit []string
+	for _, c := range server.Calls() {
+		lit = append(lit, c.EntityID)
+	}
+	assert.ElementsMatch(t, []string{"light.hall", "light.stairs"}, lit)
+}